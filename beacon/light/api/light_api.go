@@ -0,0 +1,474 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package api implements a client of the light client related endpoints of the
+// beacon node REST API.
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	ctypes "github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	ErrNotFound = errors.New("404 Not Found")
+	ErrInternal = errors.New("500 Internal Server Error")
+)
+
+// requestTimeout is the timeout of a single API request.
+const requestTimeout = 10 * time.Second
+
+// BeaconLightApi requests light client information from a beacon node REST API.
+type BeaconLightApi struct {
+	url           string
+	client        *http.Client
+	customHeaders map[string]string
+}
+
+// NewBeaconLightApi creates a client of the beacon node REST API at the given
+// URL. The custom headers are added to every request, e.g. for authentication.
+func NewBeaconLightApi(url string, customHeaders map[string]string) *BeaconLightApi {
+	return &BeaconLightApi{
+		url:           url,
+		client:        &http.Client{Timeout: requestTimeout},
+		customHeaders: customHeaders,
+	}
+}
+
+func (api *BeaconLightApi) httpGet(path string) ([]byte, error) {
+	req, err := http.NewRequest("GET", api.url+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	for k, v := range api.customHeaders {
+		req.Header.Set(k, v)
+	}
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		return io.ReadAll(resp.Body)
+	case 404:
+		return nil, ErrNotFound
+	case 500:
+		return nil, ErrInternal
+	default:
+		return nil, fmt.Errorf("unexpected error from API endpoint \"%s\": status code %d", path, resp.StatusCode)
+	}
+}
+
+// GetBestUpdatesAndCommittees fetches and validates LightClientUpdate for given
+// period and full serialized committee for the next period (committee root hash
+// equals update.NextSyncCommitteeRoot).
+// Note that the results are validated but the update signature should be verified
+// by the caller as its validity depends on the update chain.
+func (api *BeaconLightApi) GetBestUpdatesAndCommittees(firstPeriod, count uint64) ([]*types.LightClientUpdate, []*types.SerializedSyncCommittee, error) {
+	resp, err := api.httpGet("/eth/v1/beacon/light_client/updates?start_period=" + strconv.FormatUint(firstPeriod, 10) + "&count=" + strconv.FormatUint(count, 10))
+	if err != nil {
+		return nil, nil, err
+	}
+	var data []jsonUpdate
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, nil, err
+	}
+	if len(data) != int(count) {
+		return nil, nil, errors.New("invalid number of committee updates")
+	}
+	updates := make([]*types.LightClientUpdate, int(count))
+	committees := make([]*types.SerializedSyncCommittee, int(count))
+	for i, d := range data {
+		if d.Data.AttestedHeader.Beacon.SyncPeriod() != firstPeriod+uint64(i) {
+			return nil, nil, errors.New("wrong committee update header period")
+		}
+		if d.Data.NextSyncCommittee == nil {
+			return nil, nil, errors.New("sync committee is missing")
+		}
+		update := &types.LightClientUpdate{
+			AttestedHeader: types.SignedHeader{
+				Header:        d.Data.AttestedHeader.Beacon,
+				Signature:     d.Data.SyncAggregate,
+				SignatureSlot: uint64(d.Data.SignatureSlot),
+			},
+			NextSyncCommitteeRoot:   d.Data.NextSyncCommittee.Root(),
+			NextSyncCommitteeBranch: d.Data.NextSyncCommitteeBranch,
+			FinalityBranch:          d.Data.FinalityBranch,
+		}
+		// Updates without finality have a zero finalized header.
+		if d.Data.FinalizedHeader != nil && d.Data.FinalizedHeader.Beacon != (types.Header{}) {
+			update.FinalizedHeader = &d.Data.FinalizedHeader.Beacon
+		}
+		if err := update.Validate(); err != nil {
+			return nil, nil, err
+		}
+		updates[i] = update
+		committees[i] = d.Data.NextSyncCommittee
+	}
+	return updates, committees, nil
+}
+
+// GetOptimisticUpdate fetches the latest available optimistic update.
+// Note that the signature should be verified by the caller as its validity
+// depends on the update chain.
+func (api *BeaconLightApi) GetOptimisticUpdate() (*types.OptimisticUpdate, error) {
+	resp, err := api.httpGet("/eth/v1/beacon/light_client/optimistic_update")
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Data struct {
+			AttestedHeader jsonHeaderWithExecProof `json:"attested_header"`
+			SyncAggregate  types.SyncAggregate     `json:"sync_aggregate"`
+			SignatureSlot  common.Decimal          `json:"signature_slot"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, err
+	}
+	attested, err := data.Data.AttestedHeader.header()
+	if err != nil {
+		return nil, err
+	}
+	update := &types.OptimisticUpdate{
+		Attested:      attested,
+		Signature:     data.Data.SyncAggregate,
+		SignatureSlot: uint64(data.Data.SignatureSlot),
+	}
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// GetFinalityUpdate fetches the latest available finality update.
+// Note that the signature should be verified by the caller as its validity
+// depends on the update chain.
+func (api *BeaconLightApi) GetFinalityUpdate() (*types.FinalityUpdate, error) {
+	resp, err := api.httpGet("/eth/v1/beacon/light_client/finality_update")
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Data struct {
+			AttestedHeader  jsonHeaderWithExecProof `json:"attested_header"`
+			FinalizedHeader jsonHeaderWithExecProof `json:"finalized_header"`
+			FinalityBranch  merkle.Values           `json:"finality_branch"`
+			SyncAggregate   types.SyncAggregate     `json:"sync_aggregate"`
+			SignatureSlot   common.Decimal          `json:"signature_slot"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, err
+	}
+	attested, err := data.Data.AttestedHeader.header()
+	if err != nil {
+		return nil, err
+	}
+	finalized, err := data.Data.FinalizedHeader.header()
+	if err != nil {
+		return nil, err
+	}
+	update := &types.FinalityUpdate{
+		Attested:       attested,
+		Finalized:      finalized,
+		FinalityBranch: data.Data.FinalityBranch,
+		Signature:      data.Data.SyncAggregate,
+		SignatureSlot:  uint64(data.Data.SignatureSlot),
+	}
+	if err := update.Validate(); err != nil {
+		return nil, err
+	}
+	return update, nil
+}
+
+// GetCheckpointData fetches and validates bootstrap data belonging to the given
+// checkpoint (beacon block root).
+func (api *BeaconLightApi) GetCheckpointData(checkpointHash common.Hash) (*types.BootstrapData, error) {
+	resp, err := api.httpGet("/eth/v1/beacon/light_client/bootstrap/" + checkpointHash.String())
+	if err != nil {
+		return nil, err
+	}
+	var data struct {
+		Data struct {
+			Header          jsonHeaderWithExecProof        `json:"header"`
+			Committee       *types.SerializedSyncCommittee `json:"current_sync_committee"`
+			CommitteeBranch merkle.Values                  `json:"current_sync_committee_branch"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, err
+	}
+	if data.Data.Committee == nil {
+		return nil, errors.New("sync committee is missing")
+	}
+	header := data.Data.Header.Beacon
+	if header.Hash() != checkpointHash {
+		return nil, fmt.Errorf("invalid checkpoint block header, have %v want %v", header.Hash(), checkpointHash)
+	}
+	checkpoint := &types.BootstrapData{
+		Header:          header,
+		CommitteeBranch: data.Data.CommitteeBranch,
+		CommitteeRoot:   data.Data.Committee.Root(),
+		Committee:       data.Data.Committee,
+	}
+	if err := checkpoint.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	return checkpoint, nil
+}
+
+// GetExecutionPayload fetches the execution payload of the given beacon block,
+// along with the versioned hashes of its blob commitments. The payload is not
+// verified, it should be checked against a proven execution block hash.
+func (api *BeaconLightApi) GetExecutionPayload(blockRoot common.Hash) (*engine.ExecutableData, []common.Hash, error) {
+	resp, err := api.httpGet("/eth/v2/beacon/blocks/" + blockRoot.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	var data struct {
+		Data struct {
+			Message struct {
+				Body struct {
+					ExecutionPayload   *jsonExecutionPayload `json:"execution_payload"`
+					BlobKzgCommitments []hexutil.Bytes       `json:"blob_kzg_commitments"`
+				} `json:"body"`
+			} `json:"message"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(resp, &data); err != nil {
+		return nil, nil, err
+	}
+	body := data.Data.Message.Body
+	if body.ExecutionPayload == nil {
+		return nil, nil, errors.New("block has no execution payload")
+	}
+	payload, err := body.ExecutionPayload.executableData()
+	if err != nil {
+		return nil, nil, err
+	}
+	var (
+		hasher = sha256.New()
+		hashes = make([]common.Hash, len(body.BlobKzgCommitments))
+	)
+	for i, commitment := range body.BlobKzgCommitments {
+		hasher.Reset()
+		hasher.Write(commitment)
+		hasher.Sum(hashes[i][:0])
+		hashes[i][0] = params.BlobTxHashVersion
+	}
+	return payload, hashes, nil
+}
+
+// jsonUpdate is the JSON representation of a LightClientUpdate.
+type jsonUpdate struct {
+	Data struct {
+		AttestedHeader          jsonHeaderWithExecProof        `json:"attested_header"`
+		NextSyncCommittee       *types.SerializedSyncCommittee `json:"next_sync_committee"`
+		NextSyncCommitteeBranch merkle.Values                  `json:"next_sync_committee_branch"`
+		FinalizedHeader         *jsonHeaderWithExecProof       `json:"finalized_header,omitempty"`
+		FinalityBranch          merkle.Values                  `json:"finality_branch,omitempty"`
+		SyncAggregate           types.SyncAggregate            `json:"sync_aggregate"`
+		SignatureSlot           common.Decimal                 `json:"signature_slot"`
+	} `json:"data"`
+}
+
+// jsonHeaderWithExecProof is the JSON representation of a light client header.
+// The execution payload header and its proof are only present after Capella.
+type jsonHeaderWithExecProof struct {
+	Beacon          types.Header         `json:"beacon"`
+	Execution       *jsonExecutionHeader `json:"execution,omitempty"`
+	ExecutionBranch merkle.Values        `json:"execution_branch,omitempty"`
+}
+
+func (h *jsonHeaderWithExecProof) header() (types.HeaderWithExecProof, error) {
+	if h.Execution == nil {
+		return types.HeaderWithExecProof{}, errors.New("execution payload header is missing")
+	}
+	payload, err := h.Execution.executionHeader()
+	if err != nil {
+		return types.HeaderWithExecProof{}, err
+	}
+	return types.HeaderWithExecProof{
+		Header:        h.Beacon,
+		PayloadHeader: payload,
+		PayloadBranch: h.ExecutionBranch,
+	}, nil
+}
+
+// jsonExecutionFields are the fields shared by the execution payload and the
+// execution payload header.
+type jsonExecutionFields struct {
+	ParentHash    common.Hash     `json:"parent_hash"`
+	FeeRecipient  common.Address  `json:"fee_recipient"`
+	StateRoot     common.Hash     `json:"state_root"`
+	ReceiptsRoot  common.Hash     `json:"receipts_root"`
+	LogsBloom     hexutil.Bytes   `json:"logs_bloom"`
+	PrevRandao    common.Hash     `json:"prev_randao"`
+	BlockNumber   common.Decimal  `json:"block_number"`
+	GasLimit      common.Decimal  `json:"gas_limit"`
+	GasUsed       common.Decimal  `json:"gas_used"`
+	Timestamp     common.Decimal  `json:"timestamp"`
+	ExtraData     hexutil.Bytes   `json:"extra_data"`
+	BaseFeePerGas string          `json:"base_fee_per_gas"`
+	BlockHash     common.Hash     `json:"block_hash"`
+	BlobGasUsed   *common.Decimal `json:"blob_gas_used,omitempty"`
+	ExcessBlobGas *common.Decimal `json:"excess_blob_gas,omitempty"`
+}
+
+func (f *jsonExecutionFields) validate() (*big.Int, error) {
+	if len(f.LogsBloom) != ctypes.BloomByteLength {
+		return nil, fmt.Errorf("invalid logs bloom length %d", len(f.LogsBloom))
+	}
+	if len(f.ExtraData) > 32 {
+		return nil, fmt.Errorf("invalid extra data length %d", len(f.ExtraData))
+	}
+	if (f.BlobGasUsed == nil) != (f.ExcessBlobGas == nil) {
+		return nil, errors.New("incomplete blob gas fields")
+	}
+	baseFee, ok := new(big.Int).SetString(f.BaseFeePerGas, 10)
+	if !ok || baseFee.Sign() < 0 || baseFee.BitLen() > 256 {
+		return nil, fmt.Errorf("invalid base fee %q", f.BaseFeePerGas)
+	}
+	return baseFee, nil
+}
+
+func (f *jsonExecutionFields) blobGas() (used, excess *uint64) {
+	if f.BlobGasUsed != nil {
+		used, excess = new(uint64), new(uint64)
+		*used, *excess = uint64(*f.BlobGasUsed), uint64(*f.ExcessBlobGas)
+	}
+	return used, excess
+}
+
+// jsonExecutionHeader is the JSON representation of an execution payload header.
+type jsonExecutionHeader struct {
+	jsonExecutionFields
+	TransactionsRoot common.Hash `json:"transactions_root"`
+	WithdrawalsRoot  common.Hash `json:"withdrawals_root"`
+}
+
+func (h *jsonExecutionHeader) executionHeader() (*types.ExecutionHeader, error) {
+	baseFee, err := h.validate()
+	if err != nil {
+		return nil, err
+	}
+	header := &types.ExecutionHeader{
+		ParentHash:       h.ParentHash,
+		FeeRecipient:     h.FeeRecipient,
+		StateRoot:        h.StateRoot,
+		ReceiptsRoot:     h.ReceiptsRoot,
+		PrevRandao:       h.PrevRandao,
+		BlockNumber:      uint64(h.BlockNumber),
+		GasLimit:         uint64(h.GasLimit),
+		GasUsed:          uint64(h.GasUsed),
+		Timestamp:        uint64(h.Timestamp),
+		ExtraData:        h.ExtraData,
+		BaseFeePerGas:    baseFee,
+		BlockHash:        h.BlockHash,
+		TransactionsRoot: h.TransactionsRoot,
+		WithdrawalsRoot:  h.WithdrawalsRoot,
+	}
+	copy(header.LogsBloom[:], h.LogsBloom)
+	header.DataGasUsed, header.ExcessDataGas = h.blobGas()
+	return header, nil
+}
+
+// jsonExecutionPayload is the JSON representation of an execution payload.
+type jsonExecutionPayload struct {
+	jsonExecutionFields
+	Transactions []hexutil.Bytes   `json:"transactions"`
+	Withdrawals  []*jsonWithdrawal `json:"withdrawals"`
+}
+
+type jsonWithdrawal struct {
+	Index          common.Decimal `json:"index"`
+	ValidatorIndex common.Decimal `json:"validator_index"`
+	Address        common.Address `json:"address"`
+	Amount         common.Decimal `json:"amount"`
+}
+
+func (p *jsonExecutionPayload) executableData() (*engine.ExecutableData, error) {
+	baseFee, err := p.validate()
+	if err != nil {
+		return nil, err
+	}
+	data := &engine.ExecutableData{
+		ParentHash:    p.ParentHash,
+		FeeRecipient:  p.FeeRecipient,
+		StateRoot:     p.StateRoot,
+		ReceiptsRoot:  p.ReceiptsRoot,
+		LogsBloom:     p.LogsBloom,
+		Random:        p.PrevRandao,
+		Number:        uint64(p.BlockNumber),
+		GasLimit:      uint64(p.GasLimit),
+		GasUsed:       uint64(p.GasUsed),
+		Timestamp:     uint64(p.Timestamp),
+		ExtraData:     p.ExtraData,
+		BaseFeePerGas: baseFee,
+		BlockHash:     p.BlockHash,
+		Transactions:  make([][]byte, len(p.Transactions)),
+	}
+	for i, tx := range p.Transactions {
+		data.Transactions[i] = tx
+	}
+	// Withdrawals are absent before Capella, but an empty list is valid after.
+	if p.Withdrawals != nil {
+		data.Withdrawals = make([]*ctypes.Withdrawal, len(p.Withdrawals))
+		for i, w := range p.Withdrawals {
+			data.Withdrawals[i] = &ctypes.Withdrawal{
+				Index:     uint64(w.Index),
+				Validator: uint64(w.ValidatorIndex),
+				Address:   w.Address,
+				Amount:    uint64(w.Amount),
+			}
+		}
+	}
+	data.DataGasUsed, data.ExcessDataGas = p.blobGas()
+	return data, nil
+}
+
+// ParseCustomHeaders parses a list of "key:value" HTTP header specifications.
+func ParseCustomHeaders(headers []string) (map[string]string, error) {
+	custom := make(map[string]string)
+	for _, s := range headers {
+		k, v, ok := strings.Cut(s, ":")
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid custom API header %q", s)
+		}
+		custom[k] = strings.TrimSpace(v)
+	}
+	return custom, nil
+}
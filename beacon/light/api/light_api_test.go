@@ -0,0 +1,189 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+func testBlock(t *testing.T) *types.Block {
+	key, _ := crypto.GenerateKey()
+	signer := types.LatestSignerForChainID(big.NewInt(1))
+	tx, err := types.SignNewTx(key, signer, &types.DynamicFeeTx{
+		ChainID:   big.NewInt(1),
+		Nonce:     1,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Gas:       21000,
+		To:        &common.Address{1},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withdrawals := []*types.Withdrawal{{Index: 5, Validator: 6, Address: common.Address{7}, Amount: 8}}
+	header := &types.Header{
+		ParentHash: common.Hash{1},
+		Number:     big.NewInt(100),
+		GasLimit:   30000000,
+		GasUsed:    21000,
+		Time:       1000,
+		Extra:      []byte("test"),
+		BaseFee:    big.NewInt(7),
+		Difficulty: new(big.Int),
+	}
+	return types.NewBlockWithWithdrawals(header, []*types.Transaction{tx}, nil, nil, withdrawals, trie.NewStackTrie(nil))
+}
+
+// beaconPayload converts a block to the beacon API JSON representation of an
+// execution payload.
+func beaconPayload(block *types.Block) map[string]interface{} {
+	data := engine.BlockToExecutableData(block, nil, nil, nil, nil).ExecutionPayload
+	var txs []hexutil.Bytes
+	for _, tx := range data.Transactions {
+		txs = append(txs, tx)
+	}
+	var withdrawals []map[string]interface{}
+	for _, w := range data.Withdrawals {
+		withdrawals = append(withdrawals, map[string]interface{}{
+			"index":           strconv.FormatUint(w.Index, 10),
+			"validator_index": strconv.FormatUint(w.Validator, 10),
+			"address":         w.Address,
+			"amount":          strconv.FormatUint(w.Amount, 10),
+		})
+	}
+	return map[string]interface{}{
+		"parent_hash":      data.ParentHash,
+		"fee_recipient":    data.FeeRecipient,
+		"state_root":       data.StateRoot,
+		"receipts_root":    data.ReceiptsRoot,
+		"logs_bloom":       hexutil.Bytes(data.LogsBloom),
+		"prev_randao":      data.Random,
+		"block_number":     strconv.FormatUint(data.Number, 10),
+		"gas_limit":        strconv.FormatUint(data.GasLimit, 10),
+		"gas_used":         strconv.FormatUint(data.GasUsed, 10),
+		"timestamp":        strconv.FormatUint(data.Timestamp, 10),
+		"extra_data":       hexutil.Bytes(data.ExtraData),
+		"base_fee_per_gas": data.BaseFeePerGas.String(),
+		"block_hash":       data.BlockHash,
+		"transactions":     txs,
+		"withdrawals":      withdrawals,
+	}
+}
+
+func TestGetExecutionPayload(t *testing.T) {
+	var (
+		block = testBlock(t)
+		root  = common.Hash{0xbb}
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Test") != "1" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/eth/v2/beacon/blocks/"+root.String() {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"version": "capella",
+			"data": map[string]interface{}{
+				"message": map[string]interface{}{
+					"slot": "1",
+					"body": map[string]interface{}{"execution_payload": beaconPayload(block)},
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	headers, err := ParseCustomHeaders([]string{"X-Test: 1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	api := NewBeaconLightApi(srv.URL, headers)
+	payload, hashes, err := api.GetExecutionPayload(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Rebuilding the block from the payload checks its hash.
+	if _, err := engine.ExecutableDataToBlock(*payload, hashes); err != nil {
+		t.Fatal("invalid payload:", err)
+	}
+	if payload.BlockHash != block.Hash() {
+		t.Fatalf("wrong block hash %v, want %v", payload.BlockHash, block.Hash())
+	}
+	if _, _, err := api.GetExecutionPayload(common.Hash{1}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("wrong error for unknown block: %v", err)
+	}
+}
+
+func TestParseCustomHeaders(t *testing.T) {
+	headers, err := ParseCustomHeaders([]string{"Authorization: Bearer x", "A:b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if headers["Authorization"] != "Bearer x" || headers["A"] != "b" {
+		t.Fatalf("wrong headers %v", headers)
+	}
+	for _, invalid := range []string{"foo", ":bar"} {
+		if _, err := ParseCustomHeaders([]string{invalid}); err == nil {
+			t.Errorf("invalid header %q accepted", invalid)
+		}
+	}
+}
+
+func TestGetBestUpdatesMissingCommittee(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]interface{}{
+			map[string]interface{}{
+				"data": map[string]interface{}{
+					"attested_header": map[string]interface{}{
+						"beacon": map[string]interface{}{
+							"slot":           "0",
+							"proposer_index": "0",
+							"parent_root":    common.Hash{},
+							"state_root":     common.Hash{},
+							"body_root":      common.Hash{},
+						},
+					},
+					"sync_aggregate": btypes.SyncAggregate{},
+					"signature_slot": "1",
+				},
+			},
+		})
+	}))
+	defer srv.Close()
+
+	api := NewBeaconLightApi(srv.URL, nil)
+	if _, _, err := api.GetBestUpdatesAndCommittees(0, 1); err == nil {
+		t.Fatal("update without sync committee accepted")
+	}
+}
@@ -0,0 +1,285 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package light implements a beacon chain light client which follows the chain
+// head based on sync committee signatures.
+package light
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	headPollInterval  = 4 * time.Second  // interval of polling the latest updates
+	retryInterval     = 12 * time.Second // delay after a failed bootstrap attempt
+	maxUpdatesRequest = 128              // maximum number of committee updates requested at once
+)
+
+// Backend is the source of light client data, typically a beacon node REST API.
+// The returned data is expected to be validated, but the signatures are checked
+// by the client.
+type Backend interface {
+	GetCheckpointData(checkpoint common.Hash) (*types.BootstrapData, error)
+	GetBestUpdatesAndCommittees(firstPeriod, count uint64) ([]*types.LightClientUpdate, []*types.SerializedSyncCommittee, error)
+	GetOptimisticUpdate() (*types.OptimisticUpdate, error)
+	GetFinalityUpdate() (*types.FinalityUpdate, error)
+}
+
+// Config contains the settings of the light client.
+type Config struct {
+	ChainConfig     *types.ChainConfig
+	Checkpoint      common.Hash // Trusted beacon block root to start syncing from
+	SignerThreshold int         // Minimum number of signers of accepted headers, zero means supermajority
+}
+
+// HeadEvent is sent when a new beacon chain head has been verified.
+type HeadEvent struct {
+	Beacon    types.Header           // Signed beacon header
+	Payload   *types.ExecutionHeader // Proven execution payload header of the beacon block
+	Finalized *types.ExecutionHeader // Execution payload header of the latest finalized block, nil if unknown
+}
+
+// Client follows the beacon chain head. It bootstraps from a trusted checkpoint,
+// keeps the sync committee chain up to date and verifies the signatures of the
+// latest headers.
+type Client struct {
+	backend    Backend
+	chain      *CommitteeChain
+	config     *types.ChainConfig
+	checkpoint common.Hash
+	clock      mclock.Clock
+
+	headFeed event.Feed
+
+	lock      sync.Mutex
+	head      *types.HeaderWithExecProof
+	finalized *types.HeaderWithExecProof
+	announced *types.HeaderWithExecProof
+
+	closeCh chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewClient creates a light client using the given backend.
+func NewClient(backend Backend, config Config) *Client {
+	threshold := config.SignerThreshold
+	if threshold == 0 {
+		threshold = params.SyncCommitteeSupermajority
+	}
+	return &Client{
+		backend:    backend,
+		chain:      NewCommitteeChain(config.ChainConfig, threshold),
+		config:     config.ChainConfig,
+		checkpoint: config.Checkpoint,
+		clock:      mclock.System{},
+		closeCh:    make(chan struct{}),
+	}
+}
+
+// Start launches the sync loop.
+func (c *Client) Start() {
+	c.wg.Add(1)
+	go c.loop()
+}
+
+// Stop terminates the sync loop.
+func (c *Client) Stop() {
+	close(c.closeCh)
+	c.wg.Wait()
+}
+
+// SubscribeHeads subscribes to verified head events.
+func (c *Client) SubscribeHeads(ch chan<- HeadEvent) event.Subscription {
+	return c.headFeed.Subscribe(ch)
+}
+
+// Head returns the latest verified head, or nil if none is known yet.
+func (c *Client) Head() *types.HeaderWithExecProof {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.head
+}
+
+// Finalized returns the latest verified finalized header, or nil if none is
+// known yet.
+func (c *Client) Finalized() *types.HeaderWithExecProof {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	return c.finalized
+}
+
+func (c *Client) loop() {
+	defer c.wg.Done()
+
+	for {
+		err := c.bootstrap()
+		if err == nil {
+			break
+		}
+		log.Warn("Beacon light client bootstrap failed", "checkpoint", c.checkpoint, "err", err)
+		if !c.sleep(retryInterval) {
+			return
+		}
+	}
+	for {
+		c.update()
+		if !c.sleep(headPollInterval) {
+			return
+		}
+	}
+}
+
+// sleep waits for the given duration, returning false if the client is stopped.
+func (c *Client) sleep(d time.Duration) bool {
+	timer := c.clock.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return true
+	case <-c.closeCh:
+		return false
+	}
+}
+
+// bootstrap initializes the committee chain from the checkpoint.
+func (c *Client) bootstrap() error {
+	bootstrap, err := c.backend.GetCheckpointData(c.checkpoint)
+	if err != nil {
+		return err
+	}
+	if err := c.chain.Init(c.checkpoint, bootstrap); err != nil {
+		return err
+	}
+	log.Info("Beacon light client bootstrapped", "checkpoint", c.checkpoint, "slot", bootstrap.Header.Slot, "period", bootstrap.Header.SyncPeriod())
+	return nil
+}
+
+// update fetches and verifies the latest finality and optimistic updates, and
+// announces a new head if there is one.
+func (c *Client) update() {
+	if update, err := c.backend.GetFinalityUpdate(); err != nil {
+		log.Debug("Failed to retrieve finality update", "err", err)
+	} else if err := c.verify(update.SignedHeader()); err != nil {
+		log.Debug("Invalid finality update", "slot", update.Attested.Slot, "err", err)
+	} else {
+		c.lock.Lock()
+		if c.finalized == nil || update.Finalized.Slot > c.finalized.Slot {
+			c.finalized = &update.Finalized
+		}
+		// The attested header of a finality update is also a head candidate.
+		if c.head == nil || update.Attested.Slot > c.head.Slot {
+			c.head = &update.Attested
+		}
+		c.lock.Unlock()
+	}
+	if update, err := c.backend.GetOptimisticUpdate(); err != nil {
+		log.Debug("Failed to retrieve optimistic update", "err", err)
+	} else if err := c.verify(update.SignedHeader()); err != nil {
+		log.Debug("Invalid optimistic update", "slot", update.Attested.Slot, "err", err)
+	} else {
+		c.lock.Lock()
+		if c.head == nil || update.Attested.Slot > c.head.Slot {
+			c.head = &update.Attested
+		}
+		c.lock.Unlock()
+	}
+	c.announce()
+}
+
+var errStopped = errors.New("light client stopped")
+
+// announce sends a head event if the head has changed since the last call.
+func (c *Client) announce() {
+	c.lock.Lock()
+	head, finalized := c.head, c.finalized
+	if head == nil || head == c.announced {
+		c.lock.Unlock()
+		return
+	}
+	c.announced = head
+	c.lock.Unlock()
+
+	ev := HeadEvent{Beacon: head.Header, Payload: head.PayloadHeader}
+	if finalized != nil {
+		ev.Finalized = finalized.PayloadHeader
+	}
+	log.Debug("New beacon light client head", "slot", head.Slot, "number", head.PayloadHeader.BlockNumber, "hash", head.PayloadHeader.BlockHash)
+	c.headFeed.Send(ev)
+}
+
+// verify checks the signature of a header, syncing the committee chain up to
+// the signature period if necessary.
+func (c *Client) verify(head types.SignedHeader) error {
+	err := c.chain.VerifySignedHeader(head)
+	if !errors.Is(err, ErrUnknownCommittee) {
+		return err
+	}
+	if err := c.syncCommittees(types.SyncPeriod(head.SignatureSlot)); err != nil {
+		return err
+	}
+	return c.chain.VerifySignedHeader(head)
+}
+
+// syncCommittees requests committee updates until the committee of the given
+// period is known.
+func (c *Client) syncCommittees(period uint64) error {
+	for {
+		next := c.chain.NextPeriod()
+		if next == 0 {
+			return ErrNotInitialized
+		}
+		if next > period {
+			// The committee of an older period has been pruned.
+			return ErrUnknownCommittee
+		}
+		count := period + 1 - next
+		if count > maxUpdatesRequest {
+			count = maxUpdatesRequest
+		}
+		updates, committees, err := c.backend.GetBestUpdatesAndCommittees(next-1, count)
+		if err != nil {
+			return err
+		}
+		if len(updates) == 0 {
+			return errors.New("no committee updates available")
+		}
+		for i, update := range updates {
+			if err := c.chain.InsertUpdate(update, committees[i]); err != nil {
+				return err
+			}
+		}
+		if c.chain.NextPeriod() > period {
+			return nil
+		}
+		select {
+		case <-c.closeCh:
+			return errStopped
+		default:
+		}
+	}
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testBackend serves light client data of a test chain.
+type testBackend struct {
+	bootstrap  *types.BootstrapData
+	updates    map[uint64]*types.LightClientUpdate
+	committees map[uint64]*types.SerializedSyncCommittee
+	optimistic *types.OptimisticUpdate
+	finality   *types.FinalityUpdate
+}
+
+func (b *testBackend) GetCheckpointData(checkpoint common.Hash) (*types.BootstrapData, error) {
+	return b.bootstrap, nil
+}
+
+func (b *testBackend) GetBestUpdatesAndCommittees(first, count uint64) ([]*types.LightClientUpdate, []*types.SerializedSyncCommittee, error) {
+	var (
+		updates    []*types.LightClientUpdate
+		committees []*types.SerializedSyncCommittee
+	)
+	for period := first; period < first+count; period++ {
+		if b.updates[period] == nil {
+			return nil, nil, errors.New("not found")
+		}
+		updates = append(updates, b.updates[period])
+		committees = append(committees, b.committees[period])
+	}
+	return updates, committees, nil
+}
+
+func (b *testBackend) GetOptimisticUpdate() (*types.OptimisticUpdate, error) {
+	return b.optimistic, nil
+}
+
+func (b *testBackend) GetFinalityUpdate() (*types.FinalityUpdate, error) {
+	return b.finality, nil
+}
+
+// makeExecHeader creates a beacon header of the given slot whose body contains
+// an execution payload of the given number.
+func makeExecHeader(slot, number uint64, state common.Hash) types.HeaderWithExecProof {
+	payload := &types.ExecutionHeader{
+		BlockNumber:   number,
+		BlockHash:     common.BigToHash(new(big.Int).SetUint64(number)),
+		BaseFeePerGas: big.NewInt(1),
+	}
+	body := newTestTree(4, map[uint64]merkle.Value{params.BodyIndexExecPayload: merkle.Value(payload.Root())})
+	return types.HeaderWithExecProof{
+		Header:        types.Header{Slot: slot, StateRoot: state, BodyRoot: body.root()},
+		PayloadHeader: payload,
+		PayloadBranch: body.branch(params.BodyIndexExecPayload),
+	}
+}
+
+func TestClient(t *testing.T) {
+	var (
+		c1      = newTestCommittee(t, 1)
+		c2      = newTestCommittee(t, 2)
+		c3      = newTestCommittee(t, 3)
+		backend = &testBackend{
+			bootstrap:  makeBootstrap(c1, 1),
+			updates:    map[uint64]*types.LightClientUpdate{1: makeUpdate(t, c1, 1, c2), 2: makeUpdate(t, c2, 2, c3)},
+			committees: map[uint64]*types.SerializedSyncCommittee{1: c2.serialized, 2: c3.serialized},
+		}
+	)
+	// The finalized header is in period 2, the head in period 3 so the client
+	// has to sync two committee updates.
+	finalized := makeExecHeader(types.SyncPeriodStart(2)+10, 100, common.Hash{})
+	state := newTestTree(7, map[uint64]merkle.Value{params.StateIndexFinalBlock: merkle.Value(finalized.Hash())})
+	attested := makeExecHeader(types.SyncPeriodStart(2)+80, 110, state.root())
+	signed := c2.sign(t, attested.Header, testSigners)
+	backend.finality = &types.FinalityUpdate{
+		Attested:       attested,
+		Finalized:      finalized,
+		FinalityBranch: state.branch(params.StateIndexFinalBlock),
+		Signature:      signed.Signature,
+		SignatureSlot:  signed.SignatureSlot,
+	}
+	head := makeExecHeader(types.SyncPeriodStart(3)+5, 120, common.Hash{})
+	signed = c3.sign(t, head.Header, testSigners)
+	backend.optimistic = &types.OptimisticUpdate{
+		Attested:      head,
+		Signature:     signed.Signature,
+		SignatureSlot: signed.SignatureSlot,
+	}
+	if err := backend.finality.Validate(); err != nil {
+		t.Fatal("invalid test finality update:", err)
+	}
+	client := NewClient(backend, Config{
+		ChainConfig:     testConfig,
+		Checkpoint:      backend.bootstrap.Header.Hash(),
+		SignerThreshold: testThreshold,
+	})
+	heads := make(chan HeadEvent, 1)
+	sub := client.SubscribeHeads(heads)
+	defer sub.Unsubscribe()
+	client.Start()
+	defer client.Stop()
+
+	select {
+	case ev := <-heads:
+		if ev.Beacon != head.Header {
+			t.Errorf("wrong head slot %d, want %d", ev.Beacon.Slot, head.Slot)
+		}
+		if ev.Payload.BlockHash != head.PayloadHeader.BlockHash {
+			t.Errorf("wrong head payload %v", ev.Payload.BlockHash)
+		}
+		if ev.Finalized == nil || ev.Finalized.BlockHash != finalized.PayloadHeader.BlockHash {
+			t.Errorf("wrong finalized payload %v", ev.Finalized)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("no head event")
+	}
+	if next := client.chain.NextPeriod(); next != 4 {
+		t.Errorf("wrong next committee period %d", next)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+var (
+	ErrNotInitialized     = errors.New("committee chain not initialized")
+	ErrUnknownCommittee   = errors.New("sync committee of the signature period is unknown")
+	ErrWrongPeriod        = errors.New("update is not for the next unknown period")
+	ErrInsufficientSigner = errors.New("insufficient number of signers")
+	ErrInvalidSignature   = errors.New("invalid sync committee signature")
+	ErrWrongCommitteeRoot = errors.New("wrong next sync committee root")
+)
+
+// keptCommittees is the number of most recent sync committees kept in memory.
+const keptCommittees = 4
+
+// CommitteeChain is a passive data structure that verifies the chain of sync
+// committees, starting from the committee of a trusted checkpoint. Each new
+// committee is accepted if the update proving it is signed by the committee
+// of the previous period.
+//
+// Headers signed by any of the known committees can be verified against it.
+type CommitteeChain struct {
+	lock            sync.RWMutex
+	config          *types.ChainConfig
+	signerThreshold int
+	committees      map[uint64]*types.SyncCommittee
+	first, next     uint64 // committees of periods [first, next) are known
+}
+
+// NewCommitteeChain creates a new CommitteeChain. Signatures are only accepted
+// if they have at least signerThreshold signers.
+func NewCommitteeChain(config *types.ChainConfig, signerThreshold int) *CommitteeChain {
+	return &CommitteeChain{
+		config:          config,
+		signerThreshold: signerThreshold,
+		committees:      make(map[uint64]*types.SyncCommittee),
+	}
+}
+
+// Init resets the chain to the committee proven by the given bootstrap data,
+// which must belong to the trusted checkpoint.
+func (s *CommitteeChain) Init(checkpoint common.Hash, bootstrap *types.BootstrapData) error {
+	if hash := bootstrap.Header.Hash(); hash != checkpoint {
+		return fmt.Errorf("bootstrap header %v does not match checkpoint %v", hash, checkpoint)
+	}
+	if bootstrap.Committee == nil {
+		return errors.New("bootstrap committee missing")
+	}
+	if err := bootstrap.Validate(); err != nil {
+		return err
+	}
+	committee, err := bootstrap.Committee.Deserialize()
+	if err != nil {
+		return fmt.Errorf("invalid bootstrap committee: %w", err)
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	period := bootstrap.Header.SyncPeriod()
+	s.committees = map[uint64]*types.SyncCommittee{period: committee}
+	s.first, s.next = period, period+1
+	return nil
+}
+
+// NextPeriod returns the first period whose committee is not known yet, which is
+// proven by the update of the previous period. It returns zero if the chain is
+// not initialized.
+func (s *CommitteeChain) NextPeriod() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.next
+}
+
+// InsertUpdate verifies an update of the last known period and adds the next
+// sync committee proven by it.
+func (s *CommitteeChain) InsertUpdate(update *types.LightClientUpdate, nextCommittee *types.SerializedSyncCommittee) error {
+	if err := update.Validate(); err != nil {
+		return err
+	}
+	if nextCommittee.Root() != update.NextSyncCommitteeRoot {
+		return ErrWrongCommitteeRoot
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.next == 0 {
+		return ErrNotInitialized
+	}
+	period := update.AttestedHeader.Header.SyncPeriod()
+	if period != s.next-1 {
+		return ErrWrongPeriod
+	}
+	if err := s.verifySignedHeader(update.AttestedHeader); err != nil {
+		return err
+	}
+	committee, err := nextCommittee.Deserialize()
+	if err != nil {
+		return fmt.Errorf("invalid next sync committee: %w", err)
+	}
+	s.committees[s.next] = committee
+	s.next++
+	for s.next-s.first > keptCommittees {
+		delete(s.committees, s.first)
+		s.first++
+	}
+	log.Debug("Added sync committee", "period", period+1)
+	return nil
+}
+
+// VerifySignedHeader checks whether the given header is signed by the sync
+// committee of the signature period with enough signers.
+func (s *CommitteeChain) VerifySignedHeader(head types.SignedHeader) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.verifySignedHeader(head)
+}
+
+func (s *CommitteeChain) verifySignedHeader(head types.SignedHeader) error {
+	if s.next == 0 {
+		return ErrNotInitialized
+	}
+	if head.SignatureSlot <= head.Header.Slot {
+		return errors.New("signature slot is not after the signed header")
+	}
+	committee := s.committees[types.SyncPeriod(head.SignatureSlot)]
+	if committee == nil {
+		return ErrUnknownCommittee
+	}
+	if head.Signature.SignerCount() < s.signerThreshold {
+		return ErrInsufficientSigner
+	}
+	signingRoot, err := s.config.Forks.SigningRoot(head.Header)
+	if err != nil {
+		return err
+	}
+	if !committee.VerifySignature(signingRoot, &head.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
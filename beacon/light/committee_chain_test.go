@@ -0,0 +1,207 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+const (
+	testSigners   = 20 // number of committee members signing test headers
+	testThreshold = 10
+)
+
+var testConfig = (&types.ChainConfig{GenesisValidatorsRoot: common.Hash{1}}).AddFork("GENESIS", 0, []byte{1, 0, 0, 0})
+
+// testCommittee is a sync committee with known secret keys.
+type testCommittee struct {
+	keys       []*bls.SecretKey
+	serialized *types.SerializedSyncCommittee
+}
+
+func newTestCommittee(t *testing.T, seed uint64) *testCommittee {
+	c := &testCommittee{serialized: new(types.SerializedSyncCommittee)}
+	pubs := make([]*bls.Pubkey, params.SyncCommitteeSize)
+	for i := range pubs {
+		var enc [32]byte
+		binary.BigEndian.PutUint64(enc[16:], seed)
+		binary.BigEndian.PutUint64(enc[24:], uint64(i+1))
+		key := new(bls.SecretKey)
+		if err := key.Deserialize(&enc); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := bls.SkToPk(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.keys, pubs[i] = append(c.keys, key), pub
+		pk := pub.Serialize()
+		copy(c.serialized[i*params.BLSPubkeySize:], pk[:])
+	}
+	agg, err := bls.AggregatePubkeys(pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pk := agg.Serialize()
+	copy(c.serialized[params.SyncCommitteeSize*params.BLSPubkeySize:], pk[:])
+	return c
+}
+
+// sign creates a signed header with the given number of signers.
+func (c *testCommittee) sign(t *testing.T, header types.Header, signers int) types.SignedHeader {
+	root, err := testConfig.Forks.SigningRoot(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := types.SignedHeader{Header: header, SignatureSlot: header.Slot + 1}
+	var sigs []*bls.Signature
+	for i := 0; i < signers; i++ {
+		signed.Signature.Signers[i/8] |= byte(1) << (i % 8)
+		sigs = append(sigs, bls.Sign(c.keys[i], root[:]))
+	}
+	sig, err := bls.Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed.Signature.Signature = sig.Serialize()
+	return signed
+}
+
+// testTree is a sparse binary merkle tree addressed by generalized indices.
+type testTree struct {
+	nodes map[uint64]merkle.Value
+	depth int
+}
+
+func newTestTree(depth int, values map[uint64]merkle.Value) *testTree {
+	tree := &testTree{nodes: make(map[uint64]merkle.Value), depth: depth}
+	for index, v := range values {
+		tree.nodes[index] = v
+	}
+	for index := uint64(1)<<depth - 1; index > 0; index-- {
+		if _, ok := tree.nodes[index]; ok {
+			continue
+		}
+		var (
+			left, right = tree.nodes[index*2], tree.nodes[index*2+1]
+			v           merkle.Value
+			hasher      = sha256.New()
+		)
+		hasher.Write(left[:])
+		hasher.Write(right[:])
+		hasher.Sum(v[:0])
+		tree.nodes[index] = v
+	}
+	return tree
+}
+
+func (tree *testTree) root() common.Hash {
+	return common.Hash(tree.nodes[1])
+}
+
+func (tree *testTree) branch(index uint64) merkle.Values {
+	var branch merkle.Values
+	for ; index > 1; index /= 2 {
+		branch = append(branch, tree.nodes[index^1])
+	}
+	return branch
+}
+
+// makeUpdate creates an update of the given period proving the next committee.
+func makeUpdate(t *testing.T, signer *testCommittee, period uint64, next *testCommittee) *types.LightClientUpdate {
+	nextRoot := next.serialized.Root()
+	state := newTestTree(6, map[uint64]merkle.Value{params.StateIndexNextSyncCommittee: merkle.Value(nextRoot)})
+	header := types.Header{Slot: types.SyncPeriodStart(period) + 100, StateRoot: state.root()}
+	return &types.LightClientUpdate{
+		AttestedHeader:          signer.sign(t, header, testSigners),
+		NextSyncCommitteeRoot:   nextRoot,
+		NextSyncCommitteeBranch: state.branch(params.StateIndexNextSyncCommittee),
+	}
+}
+
+// makeBootstrap creates bootstrap data of the given period.
+func makeBootstrap(committee *testCommittee, period uint64) *types.BootstrapData {
+	root := committee.serialized.Root()
+	state := newTestTree(6, map[uint64]merkle.Value{params.StateIndexSyncCommittee: merkle.Value(root)})
+	return &types.BootstrapData{
+		Header:          types.Header{Slot: types.SyncPeriodStart(period) + 10, StateRoot: state.root()},
+		CommitteeRoot:   root,
+		Committee:       committee.serialized,
+		CommitteeBranch: state.branch(params.StateIndexSyncCommittee),
+	}
+}
+
+func TestCommitteeChain(t *testing.T) {
+	var (
+		c1        = newTestCommittee(t, 1)
+		c2        = newTestCommittee(t, 2)
+		bootstrap = makeBootstrap(c1, 1)
+		chain     = NewCommitteeChain(testConfig, testThreshold)
+	)
+	head1 := c1.sign(t, types.Header{Slot: types.SyncPeriodStart(1) + 50}, testSigners)
+	if err := chain.VerifySignedHeader(head1); !errors.Is(err, ErrNotInitialized) {
+		t.Fatalf("wrong error before init: %v", err)
+	}
+	if err := chain.Init(common.Hash{1}, bootstrap); err == nil {
+		t.Fatal("bootstrap accepted for wrong checkpoint")
+	}
+	if err := chain.Init(bootstrap.Header.Hash(), bootstrap); err != nil {
+		t.Fatal("bootstrap failed:", err)
+	}
+	if next := chain.NextPeriod(); next != 2 {
+		t.Fatalf("wrong next period %d", next)
+	}
+	// Check header signatures of the bootstrapped period.
+	if err := chain.VerifySignedHeader(head1); err != nil {
+		t.Fatal("valid header rejected:", err)
+	}
+	if err := chain.VerifySignedHeader(c2.sign(t, head1.Header, testSigners)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong error for header signed by wrong committee: %v", err)
+	}
+	if err := chain.VerifySignedHeader(c1.sign(t, head1.Header, testThreshold-1)); !errors.Is(err, ErrInsufficientSigner) {
+		t.Fatalf("wrong error for header with too few signers: %v", err)
+	}
+	head2 := c2.sign(t, types.Header{Slot: types.SyncPeriodStart(2) + 50}, testSigners)
+	if err := chain.VerifySignedHeader(head2); !errors.Is(err, ErrUnknownCommittee) {
+		t.Fatalf("wrong error for header of unknown period: %v", err)
+	}
+	// Add the next committee.
+	if err := chain.InsertUpdate(makeUpdate(t, c1, 2, c2), c2.serialized); !errors.Is(err, ErrWrongPeriod) {
+		t.Fatalf("wrong error for update of wrong period: %v", err)
+	}
+	if err := chain.InsertUpdate(makeUpdate(t, c2, 1, c2), c2.serialized); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("wrong error for update signed by wrong committee: %v", err)
+	}
+	if err := chain.InsertUpdate(makeUpdate(t, c1, 1, c2), c1.serialized); !errors.Is(err, ErrWrongCommitteeRoot) {
+		t.Fatalf("wrong error for update with wrong committee: %v", err)
+	}
+	if err := chain.InsertUpdate(makeUpdate(t, c1, 1, c2), c2.serialized); err != nil {
+		t.Fatal("valid update rejected:", err)
+	}
+	if err := chain.VerifySignedHeader(head2); err != nil {
+		t.Fatal("valid header of next period rejected:", err)
+	}
+}
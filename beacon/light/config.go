@@ -0,0 +1,52 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package light
+
+import (
+	"github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Beacon chain configurations of the public networks.
+var (
+	MainnetConfig = (&types.ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0x4b363db94e286120d76eb905340fdd4e54bfe9f06bf33ff6cf5ad27f511bfe95"),
+		GenesisTime:           1606824023,
+	}).
+		AddFork("GENESIS", 0, []byte{0, 0, 0, 0}).
+		AddFork("ALTAIR", 74240, []byte{1, 0, 0, 0}).
+		AddFork("BELLATRIX", 144896, []byte{2, 0, 0, 0}).
+		AddFork("CAPELLA", 194048, []byte{3, 0, 0, 0})
+
+	SepoliaConfig = (&types.ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0xd8ea171f3c94aea21ebc42a1ed61052acf3f9209c00e4efbaaddac09ed9b8078"),
+		GenesisTime:           1655733600,
+	}).
+		AddFork("GENESIS", 0, []byte{0x90, 0x00, 0x00, 0x69}).
+		AddFork("ALTAIR", 50, []byte{0x90, 0x00, 0x00, 0x70}).
+		AddFork("BELLATRIX", 100, []byte{0x90, 0x00, 0x00, 0x71}).
+		AddFork("CAPELLA", 56832, []byte{0x90, 0x00, 0x00, 0x72})
+
+	GoerliConfig = (&types.ChainConfig{
+		GenesisValidatorsRoot: common.HexToHash("0x043db0d9a83813551ee2f33450d23797757d430911a9320530ad8a0eabc43efb"),
+		GenesisTime:           1616508000,
+	}).
+		AddFork("GENESIS", 0, []byte{0x00, 0x00, 0x10, 0x20}).
+		AddFork("ALTAIR", 36660, []byte{0x01, 0x00, 0x10, 0x20}).
+		AddFork("BELLATRIX", 112260, []byte{0x02, 0x00, 0x10, 0x20}).
+		AddFork("CAPELLA", 162304, []byte{0x03, 0x00, 0x10, 0x20})
+)
@@ -25,9 +25,13 @@ import (
 	"github.com/ethereum/go-ethereum/beacon/params"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	bls "github.com/protolambda/bls12-381-util"
+	"github.com/ethereum/go-ethereum/crypto/bls12381"
 )
 
+// signatureDST is the domain separation tag of the proof of possession BLS
+// signature scheme used by the beacon chain.
+var signatureDST = []byte("BLS_SIG_BLS12381G2_XMD:SHA-256_SSWU_RO_POP_")
+
 // SerializedSyncCommitteeSize is the size of the sync committee plus the
 // aggregate public key.
 const SerializedSyncCommitteeSize = (params.SyncCommitteeSize + 1) * params.BLSPubkeySize
@@ -118,15 +122,17 @@ func (s *SerializedSyncCommittee) Root() common.Hash {
 
 // Deserialize splits open the pubkeys into proper BLS key types.
 func (s *SerializedSyncCommittee) Deserialize() (*SyncCommittee, error) {
-	sc := new(SyncCommittee)
+	var (
+		sc = new(SyncCommittee)
+		g1 = bls12381.NewG1()
+	)
 	for i := 0; i <= params.SyncCommitteeSize; i++ {
-		key := new(bls.Pubkey)
-
-		var bytes [params.BLSPubkeySize]byte
-		copy(bytes[:], s[i*params.BLSPubkeySize:(i+1)*params.BLSPubkeySize])
-
-		if err := key.Deserialize(&bytes); err != nil {
-			return nil, err
+		key, err := g1.FromCompressed(s[i*params.BLSPubkeySize : (i+1)*params.BLSPubkeySize])
+		if err != nil {
+			return nil, fmt.Errorf("invalid pubkey %d: %v", i, err)
+		}
+		if g1.IsZero(key) {
+			return nil, fmt.Errorf("pubkey %d is the point at infinity", i)
 		}
 		if i < params.SyncCommitteeSize {
 			sc.keys[i] = key
@@ -142,26 +148,52 @@ func (s *SerializedSyncCommittee) Deserialize() (*SyncCommittee, error) {
 // See data structure definition here:
 // https://github.com/ethereum/consensus-specs/blob/dev/specs/altair/beacon-chain.md#syncaggregate
 type SyncCommittee struct {
-	keys      [params.SyncCommitteeSize]*bls.Pubkey
-	aggregate *bls.Pubkey
+	keys      [params.SyncCommitteeSize]*bls12381.PointG1
+	aggregate *bls12381.PointG1
 }
 
 // VerifySignature returns true if the given sync aggregate is a valid signature
 // or the given hash.
 func (sc *SyncCommittee) VerifySignature(signingRoot common.Hash, signature *SyncAggregate) bool {
 	var (
-		sig  bls.Signature
-		keys = make([]*bls.Pubkey, 0, params.SyncCommitteeSize)
+		g1      = bls12381.NewG1()
+		g2      = bls12381.NewG2()
+		signers = signature.SignerCount()
 	)
-	if err := sig.Deserialize(&signature.Signature); err != nil {
+	if signers == 0 {
 		return false
 	}
-	for i, key := range sc.keys {
-		if signature.Signers[i/8]&(byte(1)<<(i%8)) != 0 {
-			keys = append(keys, key)
+	sig, err := g2.FromCompressed(signature.Signature[:])
+	if err != nil {
+		return false
+	}
+	// Aggregate the signer keys. If most of the committee has signed, it is
+	// cheaper to subtract the non-signers from the committee aggregate.
+	var key *bls12381.PointG1
+	if signers > params.SyncCommitteeSize/2 {
+		key = g1.New().Set(sc.aggregate)
+		for i, k := range sc.keys {
+			if signature.Signers[i/8]&(byte(1)<<(i%8)) == 0 {
+				g1.Sub(key, key, k)
+			}
+		}
+	} else {
+		key = g1.Zero()
+		for i, k := range sc.keys {
+			if signature.Signers[i/8]&(byte(1)<<(i%8)) != 0 {
+				g1.Add(key, key, k)
+			}
 		}
 	}
-	return bls.FastAggregateVerify(keys, signingRoot[:], &sig)
+	msg, err := g2.HashToCurve(signingRoot[:], signatureDST)
+	if err != nil {
+		return false
+	}
+	// Check e(key, H(msg)) == e(g1, sig).
+	engine := bls12381.NewPairingEngine()
+	engine.AddPair(key, msg)
+	engine.AddPairInv(g1.One(), sig)
+	return engine.Check()
 }
 
 //go:generate go run github.com/fjl/gencodec -type SyncAggregate -field-override syncAggregateMarshaling -out gen_syncaggregate_json.go
@@ -0,0 +1,105 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/common"
+	bls "github.com/protolambda/bls12-381-util"
+)
+
+// testCommittee is a sync committee with known secret keys.
+type testCommittee struct {
+	keys       [params.SyncCommitteeSize]*bls.SecretKey
+	serialized SerializedSyncCommittee
+}
+
+func newTestCommittee(t *testing.T) *testCommittee {
+	var (
+		c    = new(testCommittee)
+		pubs = make([]*bls.Pubkey, params.SyncCommitteeSize)
+	)
+	for i := range c.keys {
+		var enc [32]byte
+		binary.BigEndian.PutUint64(enc[24:], uint64(i+1))
+		c.keys[i] = new(bls.SecretKey)
+		if err := c.keys[i].Deserialize(&enc); err != nil {
+			t.Fatal(err)
+		}
+		pub, err := bls.SkToPk(c.keys[i])
+		if err != nil {
+			t.Fatal(err)
+		}
+		pubs[i] = pub
+		enc48 := pub.Serialize()
+		copy(c.serialized[i*params.BLSPubkeySize:], enc48[:])
+	}
+	agg, err := bls.AggregatePubkeys(pubs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc48 := agg.Serialize()
+	copy(c.serialized[params.SyncCommitteeSize*params.BLSPubkeySize:], enc48[:])
+	return c
+}
+
+// sign creates a sync aggregate over root by the first n members.
+func (c *testCommittee) sign(t *testing.T, root common.Hash, n int) *SyncAggregate {
+	var (
+		agg  SyncAggregate
+		sigs []*bls.Signature
+	)
+	for i := 0; i < n; i++ {
+		agg.Signers[i/8] |= byte(1) << (i % 8)
+		sigs = append(sigs, bls.Sign(c.keys[i], root[:]))
+	}
+	sig, err := bls.Aggregate(sigs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agg.Signature = sig.Serialize()
+	return &agg
+}
+
+func TestSyncCommitteeSignature(t *testing.T) {
+	tc := newTestCommittee(t)
+	committee, err := tc.serialized.Deserialize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := common.Hash{1, 2, 3}
+	for _, n := range []int{1, 100, params.SyncCommitteeSupermajority, params.SyncCommitteeSize} {
+		sig := tc.sign(t, root, n)
+		if !committee.VerifySignature(root, sig) {
+			t.Errorf("valid signature by %d signers rejected", n)
+		}
+		if committee.VerifySignature(common.Hash{4}, sig) {
+			t.Errorf("signature by %d signers accepted for wrong root", n)
+		}
+		// Dropping a signer from the bitmask must invalidate the signature.
+		sig.Signers[0] &^= 1
+		if committee.VerifySignature(root, sig) {
+			t.Errorf("signature by %d signers accepted with wrong signer set", n)
+		}
+	}
+	if committee.VerifySignature(root, &SyncAggregate{}) {
+		t.Error("empty signature accepted")
+	}
+}
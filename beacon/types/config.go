@@ -17,6 +17,7 @@
 package types
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"fmt"
 	"os"
@@ -99,9 +100,15 @@ func (f Forks) SigningRoot(header Header) (common.Hash, error) {
 	return signingRoot, nil
 }
 
-func (f Forks) Len() int           { return len(f) }
-func (f Forks) Swap(i, j int)      { f[i], f[j] = f[j], f[i] }
-func (f Forks) Less(i, j int) bool { return f[i].Epoch < f[j].Epoch }
+func (f Forks) Len() int      { return len(f) }
+func (f Forks) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
+func (f Forks) Less(i, j int) bool {
+	// Forks scheduled at the same epoch are ordered by their version.
+	if f[i].Epoch == f[j].Epoch {
+		return bytes.Compare(f[i].Version, f[j].Version) < 0
+	}
+	return f[i].Epoch < f[j].Epoch
+}
 
 // ChainConfig contains the beacon chain configuration.
 type ChainConfig struct {
@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/beacon/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ExecutionHeader is the header of the execution payload included in a beacon
+// block body. The data gas fields are only present after the Deneb fork.
+//
+// See data structure definition here:
+// https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/beacon-chain.md#executionpayloadheader
+type ExecutionHeader struct {
+	ParentHash       common.Hash
+	FeeRecipient     common.Address
+	StateRoot        common.Hash
+	ReceiptsRoot     common.Hash
+	LogsBloom        [256]byte
+	PrevRandao       common.Hash
+	BlockNumber      uint64
+	GasLimit         uint64
+	GasUsed          uint64
+	Timestamp        uint64
+	ExtraData        []byte
+	BaseFeePerGas    *big.Int
+	BlockHash        common.Hash
+	TransactionsRoot common.Hash
+	WithdrawalsRoot  common.Hash
+	DataGasUsed      *uint64
+	ExcessDataGas    *uint64
+}
+
+// Root calculates the SSZ hash tree root of the header.
+func (h *ExecutionHeader) Root() common.Hash {
+	var (
+		fields []merkle.Value
+		v      merkle.Value
+	)
+	uint64Value := func(n uint64) merkle.Value {
+		var v merkle.Value
+		binary.LittleEndian.PutUint64(v[:8], n)
+		return v
+	}
+	fields = append(fields, merkle.Value(h.ParentHash))
+	copy(v[:], h.FeeRecipient[:])
+	fields = append(fields, v, merkle.Value(h.StateRoot), merkle.Value(h.ReceiptsRoot))
+
+	bloom := make([]merkle.Value, len(h.LogsBloom)/32)
+	for i := range bloom {
+		copy(bloom[i][:], h.LogsBloom[i*32:])
+	}
+	fields = append(fields, merkleize(bloom), merkle.Value(h.PrevRandao))
+	fields = append(fields, uint64Value(h.BlockNumber), uint64Value(h.GasLimit), uint64Value(h.GasUsed), uint64Value(h.Timestamp))
+
+	// The extra data is a byte list with a limit of a single chunk.
+	v = merkle.Value{}
+	copy(v[:], h.ExtraData)
+	fields = append(fields, hashPair(v, uint64Value(uint64(len(h.ExtraData)))))
+
+	v = merkle.Value{}
+	if h.BaseFeePerGas != nil {
+		h.BaseFeePerGas.FillBytes(v[:])
+		for i := 0; i < 16; i++ {
+			v[i], v[31-i] = v[31-i], v[i]
+		}
+	}
+	fields = append(fields, v, merkle.Value(h.BlockHash), merkle.Value(h.TransactionsRoot), merkle.Value(h.WithdrawalsRoot))
+
+	if h.DataGasUsed != nil && h.ExcessDataGas != nil {
+		fields = append(fields, uint64Value(*h.DataGasUsed), uint64Value(*h.ExcessDataGas))
+	}
+	return common.Hash(merkleize(fields))
+}
+
+// merkleize computes the root of a binary merkle tree with the given leaves,
+// padded with zero values to the next power of two.
+func merkleize(values []merkle.Value) merkle.Value {
+	size := 1
+	for size < len(values) {
+		size *= 2
+	}
+	layer := make([]merkle.Value, size)
+	copy(layer, values)
+	for len(layer) > 1 {
+		for i := 0; i < len(layer)/2; i++ {
+			layer[i] = hashPair(layer[i*2], layer[i*2+1])
+		}
+		layer = layer[:len(layer)/2]
+	}
+	return layer[0]
+}
+
+func hashPair(a, b merkle.Value) (r merkle.Value) {
+	hasher := sha256.New()
+	hasher.Write(a[:])
+	hasher.Write(b[:])
+	hasher.Sum(r[:0])
+	return r
+}
+
+// HeaderWithExecProof is a beacon header along with the execution payload header
+// of the block and a proof of its inclusion in the block body.
+//
+// See data structure definition here:
+// https://github.com/ethereum/consensus-specs/blob/dev/specs/capella/light-client/sync-protocol.md#modified-lightclientheader
+type HeaderWithExecProof struct {
+	Header
+	PayloadHeader *ExecutionHeader
+	PayloadBranch merkle.Values
+}
+
+// Validate verifies the execution payload header proof.
+func (h *HeaderWithExecProof) Validate() error {
+	if h.PayloadHeader == nil {
+		return errors.New("missing execution payload header")
+	}
+	if err := merkle.VerifyProof(h.BodyRoot, params.BodyIndexExecPayload, h.PayloadBranch, merkle.Value(h.PayloadHeader.Root())); err != nil {
+		return fmt.Errorf("invalid execution payload proof: %w", err)
+	}
+	return nil
+}
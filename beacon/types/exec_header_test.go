@@ -0,0 +1,110 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/merkle"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func testExecutionHeader() *ExecutionHeader {
+	return &ExecutionHeader{
+		ParentHash:       common.Hash{1},
+		FeeRecipient:     common.Address{2},
+		StateRoot:        common.Hash{3},
+		ReceiptsRoot:     common.Hash{4},
+		LogsBloom:        [256]byte{5},
+		PrevRandao:       common.Hash{6},
+		BlockNumber:      7,
+		GasLimit:         8,
+		GasUsed:          9,
+		Timestamp:        10,
+		ExtraData:        []byte{11},
+		BaseFeePerGas:    big.NewInt(12),
+		BlockHash:        common.Hash{13},
+		TransactionsRoot: common.Hash{14},
+		WithdrawalsRoot:  common.Hash{15},
+	}
+}
+
+func TestExecutionHeaderRoot(t *testing.T) {
+	base := testExecutionHeader().Root()
+
+	// Every field must contribute to the root.
+	mutations := []func(h *ExecutionHeader){
+		func(h *ExecutionHeader) { h.ParentHash[31] = 1 },
+		func(h *ExecutionHeader) { h.FeeRecipient[19] = 1 },
+		func(h *ExecutionHeader) { h.StateRoot[31] = 1 },
+		func(h *ExecutionHeader) { h.ReceiptsRoot[31] = 1 },
+		func(h *ExecutionHeader) { h.LogsBloom[255] = 1 },
+		func(h *ExecutionHeader) { h.PrevRandao[31] = 1 },
+		func(h *ExecutionHeader) { h.BlockNumber++ },
+		func(h *ExecutionHeader) { h.GasLimit++ },
+		func(h *ExecutionHeader) { h.GasUsed++ },
+		func(h *ExecutionHeader) { h.Timestamp++ },
+		func(h *ExecutionHeader) { h.ExtraData = append(h.ExtraData, 0) },
+		func(h *ExecutionHeader) { h.BaseFeePerGas = new(big.Int).Lsh(h.BaseFeePerGas, 200) },
+		func(h *ExecutionHeader) { h.BlockHash[31] = 1 },
+		func(h *ExecutionHeader) { h.TransactionsRoot[31] = 1 },
+		func(h *ExecutionHeader) { h.WithdrawalsRoot[31] = 1 },
+		func(h *ExecutionHeader) { h.DataGasUsed, h.ExcessDataGas = new(uint64), new(uint64) },
+	}
+	for i, mutate := range mutations {
+		h := testExecutionHeader()
+		mutate(h)
+		if h.Root() == base {
+			t.Errorf("mutation %d did not change the root", i)
+		}
+	}
+}
+
+func TestHeaderWithExecProof(t *testing.T) {
+	payload := testExecutionHeader()
+
+	// Build a block body tree with the payload root at its position.
+	body := make([]merkle.Value, 16)
+	for i := range body {
+		body[i] = merkle.Value{byte(i + 100)}
+	}
+	body[9] = merkle.Value(payload.Root())
+
+	var branch merkle.Values
+	layer := body
+	for index := 9; len(layer) > 1; index /= 2 {
+		branch = append(branch, layer[index^1])
+		next := make([]merkle.Value, len(layer)/2)
+		for i := range next {
+			next[i] = hashPair(layer[i*2], layer[i*2+1])
+		}
+		layer = next
+	}
+	header := &HeaderWithExecProof{
+		Header:        Header{Slot: 1, BodyRoot: common.Hash(layer[0])},
+		PayloadHeader: payload,
+		PayloadBranch: branch,
+	}
+	if err := header.Validate(); err != nil {
+		t.Fatalf("valid proof rejected: %v", err)
+	}
+	payload.BlockNumber++
+	if err := header.Validate(); err == nil {
+		t.Fatal("proof of modified payload accepted")
+	}
+}
@@ -0,0 +1,192 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/eth/protocols/eth"
+	"github.com/ethereum/go-ethereum/eth/protocols/snap"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/capture"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	captureDumpCommand = &cli.Command{
+		Name:      "capture-dump",
+		Usage:     "Prints the messages of an RLPx capture file as JSON",
+		ArgsUsage: "<capture.rlpxcap>",
+		Action:    captureDump,
+		Flags: []cli.Flag{
+			captureRawFlag,
+		},
+	}
+	captureRawFlag = &cli.BoolFlag{
+		Name:  "raw",
+		Usage: "Print message payloads as hex instead of decoding them",
+	}
+)
+
+// capturedMessage is the JSON form of a captured message.
+type capturedMessage struct {
+	Time     time.Time       `json:"time"`
+	Egress   bool            `json:"egress"`
+	Protocol string          `json:"protocol"`
+	Code     uint64          `json:"code"`
+	Name     string          `json:"name,omitempty"`
+	Size     int             `json:"size"`
+	Message  interface{}     `json:"message,omitempty"`
+	Payload  hexutil.Bytes   `json:"payload,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Header   *capture.Header `json:"header,omitempty"`
+}
+
+// capturedPacket describes how to decode a message of a known protocol.
+type capturedPacket struct {
+	name string
+	new  func(version uint) interface{}
+}
+
+func packet(name string, new func() interface{}) capturedPacket {
+	return capturedPacket{name: name, new: func(uint) interface{} { return new() }}
+}
+
+// capturedPackets are the decodable messages, keyed by protocol and code.
+var capturedPackets = map[string]map[uint64]capturedPacket{
+	"p2p": {
+		0x01: packet("Disconnect", func() interface{} { return new([]p2p.DiscReason) }),
+		0x02: packet("Ping", func() interface{} { return new([]interface{}) }),
+		0x03: packet("Pong", func() interface{} { return new([]interface{}) }),
+	},
+	"eth": {
+		eth.StatusMsg: {name: "Status", new: func(version uint) interface{} {
+			if version >= eth.ETH69 {
+				return new(eth.StatusPacket69)
+			}
+			return new(eth.StatusPacket)
+		}},
+		eth.NewBlockHashesMsg:  packet("NewBlockHashes", func() interface{} { return new(eth.NewBlockHashesPacket) }),
+		eth.TransactionsMsg:    packet("Transactions", func() interface{} { return new(eth.TransactionsPacket) }),
+		eth.GetBlockHeadersMsg: packet("GetBlockHeaders", func() interface{} { return new(eth.GetBlockHeadersPacket66) }),
+		eth.BlockHeadersMsg:    packet("BlockHeaders", func() interface{} { return new(eth.BlockHeadersPacket66) }),
+		eth.GetBlockBodiesMsg:  packet("GetBlockBodies", func() interface{} { return new(eth.GetBlockBodiesPacket66) }),
+		eth.BlockBodiesMsg:     packet("BlockBodies", func() interface{} { return new(eth.BlockBodiesPacket66) }),
+		eth.NewBlockMsg:        packet("NewBlock", func() interface{} { return new(eth.NewBlockPacket) }),
+		eth.NewPooledTransactionHashesMsg: {name: "NewPooledTransactionHashes", new: func(version uint) interface{} {
+			if version >= eth.ETH68 {
+				return new(eth.NewPooledTransactionHashesPacket68)
+			}
+			return new(eth.NewPooledTransactionHashesPacket66)
+		}},
+		eth.GetPooledTransactionsMsg: packet("GetPooledTransactions", func() interface{} { return new(eth.GetPooledTransactionsPacket66) }),
+		eth.PooledTransactionsMsg:    packet("PooledTransactions", func() interface{} { return new(eth.PooledTransactionsPacket66) }),
+		eth.GetNodeDataMsg:           packet("GetNodeData", func() interface{} { return new(eth.GetNodeDataPacket66) }),
+		eth.NodeDataMsg:              packet("NodeData", func() interface{} { return new(eth.NodeDataPacket66) }),
+		eth.GetReceiptsMsg:           packet("GetReceipts", func() interface{} { return new(eth.GetReceiptsPacket66) }),
+		eth.ReceiptsMsg: {name: "Receipts", new: func(version uint) interface{} {
+			if version >= eth.ETH69 {
+				return new(eth.ReceiptsPacket69)
+			}
+			return new(eth.ReceiptsPacket66)
+		}},
+		eth.BlockRangeUpdateMsg: packet("BlockRangeUpdate", func() interface{} { return new(eth.BlockRangeUpdatePacket) }),
+	},
+	"snap": {
+		snap.GetAccountRangeMsg:  packet("GetAccountRange", func() interface{} { return new(snap.GetAccountRangePacket) }),
+		snap.AccountRangeMsg:     packet("AccountRange", func() interface{} { return new(snap.AccountRangePacket) }),
+		snap.GetStorageRangesMsg: packet("GetStorageRanges", func() interface{} { return new(snap.GetStorageRangesPacket) }),
+		snap.StorageRangesMsg:    packet("StorageRanges", func() interface{} { return new(snap.StorageRangesPacket) }),
+		snap.GetByteCodesMsg:     packet("GetByteCodes", func() interface{} { return new(snap.GetByteCodesPacket) }),
+		snap.ByteCodesMsg:        packet("ByteCodes", func() interface{} { return new(snap.ByteCodesPacket) }),
+		snap.GetTrieNodesMsg:     packet("GetTrieNodes", func() interface{} { return new(snap.GetTrieNodesPacket) }),
+		snap.TrieNodesMsg:        packet("TrieNodes", func() interface{} { return new(snap.TrieNodesPacket) }),
+	},
+}
+
+// captureDump prints the header of a capture file followed by its messages,
+// one JSON object per line.
+func captureDump(ctx *cli.Context) error {
+	if ctx.NArg() != 1 {
+		return fmt.Errorf("need capture file as argument")
+	}
+	r, err := capture.Open(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	out := json.NewEncoder(os.Stdout)
+	if err := out.Encode(&capturedMessage{Time: time.Unix(0, int64(r.Header.Start)), Header: &r.Header}); err != nil {
+		return err
+	}
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := out.Encode(decodeFrame(&r.Header, frame, ctx.Bool(captureRawFlag.Name))); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeFrame converts a captured message into its JSON form, decoding the
+// payload if the message is known.
+func decodeFrame(header *capture.Header, frame *capture.Frame, raw bool) *capturedMessage {
+	var (
+		proto, code = header.Resolve(frame.Code)
+		name        = "p2p"
+		version     uint
+	)
+	if proto != nil {
+		name, version = proto.Name, proto.Version
+	}
+	msg := &capturedMessage{
+		Time:     frame.Time,
+		Egress:   frame.Egress,
+		Protocol: name,
+		Code:     code,
+		Size:     len(frame.Payload),
+	}
+	if proto != nil {
+		msg.Protocol = proto.String()
+	}
+	packet, ok := capturedPackets[name][code]
+	if !ok || raw {
+		msg.Payload = frame.Payload
+		return msg
+	}
+	msg.Name = packet.name
+	decoded := packet.new(version)
+	if err := rlp.DecodeBytes(frame.Payload, decoded); err != nil {
+		msg.Payload = frame.Payload
+		msg.Error = err.Error()
+		return msg
+	}
+	msg.Message = decoded
+	return msg
+}
@@ -36,6 +36,8 @@ type crawler struct {
 
 	// settings
 	revalidateInterval time.Duration
+	history            *crawlHistory   // records all checks if set
+	quit               <-chan struct{} // stops the crawl when closed, if set
 	mu                 sync.RWMutex
 }
 
@@ -136,6 +138,8 @@ loop:
 			}
 		case <-timeoutCh:
 			break loop
+		case <-c.quit:
+			break loop
 		case <-statusTicker.C:
 			log.Info("Crawling in progress",
 				"added", atomic.LoadUint64(&added),
@@ -183,7 +187,11 @@ func (c *crawler) updateNode(n *enode.Node) int {
 	// Request the node record.
 	status := nodeUpdated
 	node.LastCheck = truncNow()
-	if nn, err := c.disc.RequestENR(n); err != nil {
+	nn, err := c.disc.RequestENR(n)
+	if c.history != nil {
+		c.history.record(n, nn, err)
+	}
+	if err != nil {
 		if node.Score == 0 {
 			// Node doesn't implement EIP-868.
 			log.Debug("Skipping node", "id", n.ID())
@@ -0,0 +1,267 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/internal/flags"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	crawlerHelloInterval = 12 * time.Hour   // how often the client name of a node is refreshed
+	crawlerHelloTimeout  = 10 * time.Second // timeout of the RLPx handshake
+	crawlerRecheckWindow = 7 * 24 * time.Hour
+)
+
+var (
+	crawlerCommand = &cli.Command{
+		Name:      "crawler",
+		Usage:     "Continuously crawls the DHT, keeping the history of all nodes found",
+		ArgsUsage: "<history database directory>",
+		Action:    crawlerRun,
+		Flags: flags.Merge(discoveryNodeFlags, []cli.Flag{
+			crawlerV5Flag,
+			crawlParallelismFlag,
+			crawlerRevalidateFlag,
+			crawlerWindowFlag,
+			crawlerHTTPFlag,
+			crawlerNetworkFlag,
+			crawlerDNSTreeFlag,
+			crawlerDNSKeyFlag,
+			crawlerDNSFilterFlag,
+			crawlerDNSIntervalFlag,
+		}),
+	}
+	crawlerV5Flag = &cli.BoolFlag{
+		Name:  "v5",
+		Usage: "Crawl using discovery v5 instead of v4",
+	}
+	crawlerRevalidateFlag = &cli.DurationFlag{
+		Name:  "revalidate",
+		Usage: "Minimum time between two checks of a node",
+		Value: 10 * time.Minute,
+	}
+	crawlerWindowFlag = &cli.DurationFlag{
+		Name:  "window",
+		Usage: "Nodes which responded within this time are considered live",
+		Value: 24 * time.Hour,
+	}
+	crawlerHTTPFlag = &cli.StringFlag{
+		Name:  "http",
+		Usage: "Listening address of the HTTP stats API",
+	}
+	crawlerNetworkFlag = &cli.StringFlag{
+		Name:  "network",
+		Usage: "Network to report fork readiness for (mainnet, goerli, sepolia)",
+	}
+	crawlerDNSTreeFlag = &cli.StringFlag{
+		Name:  "dns.tree",
+		Usage: "DNS tree definition directory to update with live nodes",
+	}
+	crawlerDNSKeyFlag = &cli.StringFlag{
+		Name:  "dns.key",
+		Usage: "Key file used to sign the DNS tree after updating it",
+	}
+	crawlerDNSFilterFlag = &cli.StringFlag{
+		Name:  "dns.filter",
+		Usage: "Node set filters applied to the DNS tree nodes, e.g. \"-eth-network mainnet -limit 3000\"",
+	}
+	crawlerDNSIntervalFlag = &cli.DurationFlag{
+		Name:  "dns.interval",
+		Usage: "Time between DNS tree updates",
+		Value: time.Hour,
+	}
+)
+
+func crawlerRun(ctx *cli.Context) error {
+	if ctx.NArg() < 1 {
+		return errors.New("need history database directory as argument")
+	}
+	var (
+		window  = ctx.Duration(crawlerWindowFlag.Name)
+		network = ctx.String(crawlerNetworkFlag.Name)
+	)
+	if network != "" {
+		if _, _, err := ethNetwork(network); err != nil {
+			return err
+		}
+	}
+	history, err := openCrawlHistory(ctx.Args().First())
+	if err != nil {
+		return err
+	}
+	defer history.close()
+
+	key, _ := crypto.GenerateKey()
+	history.helloInterval = crawlerHelloInterval
+	history.hello = func(n *enode.Node) (string, error) {
+		h, err := rlpxHello(n, key, crawlerHelloTimeout)
+		if err != nil {
+			return "", err
+		}
+		return h.Name, nil
+	}
+
+	// Set up DNS tree updates.
+	var feed *crawlerDNSFeed
+	if ctx.IsSet(crawlerDNSTreeFlag.Name) {
+		feed = &crawlerDNSFeed{
+			dir:      ctx.String(crawlerDNSTreeFlag.Name),
+			filter:   strings.Fields(ctx.String(crawlerDNSFilterFlag.Name)),
+			history:  history,
+			window:   window,
+			interval: ctx.Duration(crawlerDNSIntervalFlag.Name),
+		}
+		if _, err := andFilter(feed.filter); err != nil {
+			return err
+		}
+		if ctx.IsSet(crawlerDNSKeyFlag.Name) {
+			feed.key = loadSigningKey(ctx.String(crawlerDNSKeyFlag.Name))
+		}
+	}
+
+	// Start the stats API.
+	if addr := ctx.String(crawlerHTTPFlag.Name); addr != "" {
+		listener, err := net.Listen("tcp", addr)
+		if err != nil {
+			return err
+		}
+		srv := &http.Server{
+			Handler:           newCrawlStatsHandler(history, window, network),
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+		go srv.Serve(listener)
+		defer srv.Close()
+		log.Info("Crawler stats API started", "addr", listener.Addr())
+	}
+
+	// Start discovery.
+	var (
+		disc   resolver
+		random enode.Iterator
+	)
+	if ctx.Bool(crawlerV5Flag.Name) {
+		d := startV5(ctx)
+		defer d.Close()
+		disc, random = d, d.RandomNodes()
+	} else {
+		d := startV4(ctx)
+		defer d.Close()
+		disc, random = d, d.RandomNodes()
+	}
+
+	quit := make(chan struct{})
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigc)
+	go func() {
+		<-sigc
+		log.Info("Got interrupt, shutting down...")
+		close(quit)
+	}()
+
+	var wg sync.WaitGroup
+	if feed != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			feed.loop(quit)
+		}()
+	}
+
+	// Run the crawler. Known nodes are rechecked regularly, even when they
+	// can't be found in the DHT anymore.
+	revalidate := ctx.Duration(crawlerRevalidateFlag.Name)
+	c := newCrawler(history.nodeSet(crawlerRecheckWindow), disc, random, newHistoryIterator(history, crawlerRecheckWindow, revalidate))
+	c.revalidateInterval = revalidate
+	c.history = history
+	c.quit = quit
+	c.run(0, ctx.Int(crawlParallelismFlag.Name))
+	wg.Wait()
+	return nil
+}
+
+// crawlerDNSFeed periodically writes the live nodes of the crawler history into
+// a DNS tree definition directory, signing the tree if a key is configured. The
+// tree can then be deployed with the 'dns to-*' commands.
+type crawlerDNSFeed struct {
+	dir      string
+	filter   []string
+	key      *ecdsa.PrivateKey
+	history  *crawlHistory
+	window   time.Duration
+	interval time.Duration
+}
+
+func (f *crawlerDNSFeed) loop(quit <-chan struct{}) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := f.update(); err != nil {
+				log.Warn("Failed to update DNS tree", "dir", f.dir, "err", err)
+			}
+		case <-quit:
+			return
+		}
+	}
+}
+
+// update writes the current node set into the tree definition.
+func (f *crawlerDNSFeed) update() error {
+	ns, err := filterNodeSet(f.history.nodeSet(f.window), f.filter)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(f.dir, 0755); err != nil {
+		return err
+	}
+	_, nodesFile := treeDefinitionFiles(f.dir)
+	writeNodesJSON(nodesFile, ns)
+	if f.key == nil {
+		log.Info("Updated DNS tree nodes", "dir", f.dir, "nodes", len(ns))
+		return nil
+	}
+
+	def := loadTreeDefinition(f.dir)
+	domain, err := treeDomain(f.dir, def)
+	if err != nil {
+		return err
+	}
+	def.Meta.Seq++
+	if def, err = signTreeDefinition(def, domain, f.key); err != nil {
+		return err
+	}
+	writeTreeMetadata(f.dir, def)
+	log.Info("Updated DNS tree", "dir", f.dir, "nodes", len(ns), "seq", def.Meta.Seq, "url", def.Meta.URL)
+	return nil
+}
@@ -0,0 +1,308 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// historyPrefix is the database key prefix of node histories.
+var historyPrefix = []byte("n")
+
+// nodeHistory is everything the crawler learned about a node.
+type nodeHistory struct {
+	N *enode.Node
+
+	FirstSeen time.Time // first response
+	LastSeen  time.Time // last response
+	LastCheck time.Time // last attempt to contact the node
+	Checks    uint64    // number of attempts
+	Responses uint64    // number of successful attempts
+
+	Client        string    // client name from the RLPx handshake
+	ClientChecked time.Time // time of the last RLPx handshake attempt
+}
+
+// Uptime is the fraction of checks the node responded to.
+func (h *nodeHistory) Uptime() float64 {
+	if h.Checks == 0 {
+		return 0
+	}
+	return float64(h.Responses) / float64(h.Checks)
+}
+
+// storedHistory is the RLP encoding of nodeHistory.
+type storedHistory struct {
+	Record        *enr.Record
+	FirstSeen     uint64
+	LastSeen      uint64
+	LastCheck     uint64
+	Checks        uint64
+	Responses     uint64
+	Client        string
+	ClientChecked uint64
+}
+
+// crawlHistory is a database of all nodes seen by the crawler.
+type crawlHistory struct {
+	db  ethdb.KeyValueStore
+	now func() time.Time
+	mu  sync.Mutex
+
+	// hello retrieves the client name of a node. It is called for responding
+	// nodes with a TCP endpoint at most once per helloInterval.
+	hello         func(*enode.Node) (string, error)
+	helloInterval time.Duration
+}
+
+// openCrawlHistory opens the history database at the given path.
+func openCrawlHistory(path string) (*crawlHistory, error) {
+	db, err := leveldb.New(path, 16, 16, "", false)
+	if err != nil {
+		return nil, err
+	}
+	return newCrawlHistory(db), nil
+}
+
+func newCrawlHistory(db ethdb.KeyValueStore) *crawlHistory {
+	return &crawlHistory{db: db, now: truncNow}
+}
+
+func (c *crawlHistory) close() error {
+	return c.db.Close()
+}
+
+// get retrieves the history of a node.
+func (c *crawlHistory) get(id enode.ID) *nodeHistory {
+	blob, err := c.db.Get(historyKey(id))
+	if err != nil {
+		return nil
+	}
+	return decodeHistory(blob)
+}
+
+func (c *crawlHistory) put(h *nodeHistory) {
+	id := h.N.ID()
+	blob, err := rlp.EncodeToBytes(&storedHistory{
+		Record:        h.N.Record(),
+		FirstSeen:     unixTime(h.FirstSeen),
+		LastSeen:      unixTime(h.LastSeen),
+		LastCheck:     unixTime(h.LastCheck),
+		Checks:        h.Checks,
+		Responses:     h.Responses,
+		Client:        h.Client,
+		ClientChecked: unixTime(h.ClientChecked),
+	})
+	if err != nil {
+		log.Error("Failed to encode node history", "id", id, "err", err)
+		return
+	}
+	if err := c.db.Put(historyKey(id), blob); err != nil {
+		log.Error("Failed to store node history", "id", id, "err", err)
+	}
+}
+
+func historyKey(id enode.ID) []byte {
+	key := make([]byte, 0, len(historyPrefix)+len(id))
+	return append(append(key, historyPrefix...), id[:]...)
+}
+
+func decodeHistory(blob []byte) *nodeHistory {
+	var stored storedHistory
+	if err := rlp.DecodeBytes(blob, &stored); err != nil {
+		return nil
+	}
+	n, err := enode.New(enode.ValidSchemes, stored.Record)
+	if err != nil {
+		return nil
+	}
+	return &nodeHistory{
+		N:             n,
+		FirstSeen:     fromUnixTime(stored.FirstSeen),
+		LastSeen:      fromUnixTime(stored.LastSeen),
+		LastCheck:     fromUnixTime(stored.LastCheck),
+		Checks:        stored.Checks,
+		Responses:     stored.Responses,
+		Client:        stored.Client,
+		ClientChecked: fromUnixTime(stored.ClientChecked),
+	}
+}
+
+// record stores the result of a check. The response is the record returned by
+// the node, or nil if it didn't respond. Nodes are only added to the history
+// when they respond.
+func (c *crawlHistory) record(n *enode.Node, response *enode.Node, err error) {
+	c.mu.Lock()
+	h := c.get(n.ID())
+	if h == nil {
+		if err != nil {
+			c.mu.Unlock()
+			return
+		}
+		h = &nodeHistory{N: response}
+	}
+	now := c.now()
+	h.LastCheck = now
+	h.Checks++
+	if err == nil {
+		if response.Seq() >= h.N.Seq() {
+			h.N = response
+		}
+		if h.FirstSeen.IsZero() {
+			h.FirstSeen = now
+		}
+		h.LastSeen = now
+		h.Responses++
+	}
+	needHello := err == nil && c.hello != nil && h.N.TCP() != 0 && now.Sub(h.ClientChecked) >= c.helloInterval
+	if needHello {
+		h.ClientChecked = now
+	}
+	c.put(h)
+	c.mu.Unlock()
+
+	if needHello {
+		c.updateClient(h.N)
+	}
+}
+
+// updateClient performs the RLPx handshake with a node and stores its client name.
+func (c *crawlHistory) updateClient(n *enode.Node) {
+	name, err := c.hello(n)
+	if err != nil {
+		log.Debug("RLPx handshake failed", "id", n.ID(), "err", err)
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if h := c.get(n.ID()); h != nil {
+		h.Client = name
+		c.put(h)
+	}
+}
+
+// forEach calls fn for all nodes in the history.
+func (c *crawlHistory) forEach(fn func(*nodeHistory)) {
+	it := c.db.NewIterator(historyPrefix, nil)
+	defer it.Release()
+	for it.Next() {
+		if h := decodeHistory(it.Value()); h != nil {
+			fn(h)
+		}
+	}
+}
+
+// nodeSet returns the nodes which responded within the given time window, in
+// nodes.json format.
+func (c *crawlHistory) nodeSet(window time.Duration) nodeSet {
+	var (
+		ns     = make(nodeSet)
+		cutoff = c.now().Add(-window)
+	)
+	c.forEach(func(h *nodeHistory) {
+		if h.LastSeen.Before(cutoff) {
+			return
+		}
+		ns[h.N.ID()] = nodeJSON{
+			Seq:           h.N.Seq(),
+			N:             h.N,
+			Score:         int(h.Responses),
+			FirstResponse: h.FirstSeen,
+			LastResponse:  h.LastSeen,
+			LastCheck:     h.LastCheck,
+		}
+	})
+	return ns
+}
+
+// historyIterator returns the nodes of the history again and again, so nodes are
+// rechecked even if they're not found in the DHT anymore. The first pass starts
+// after one interval, since the crawler input covers the nodes known at startup.
+type historyIterator struct {
+	history  *crawlHistory
+	window   time.Duration // nodes which didn't respond for longer are skipped
+	interval time.Duration // minimum time between two passes over the history
+
+	nodes     []*enode.Node
+	cur       *enode.Node
+	lastPass  time.Time
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newHistoryIterator(h *crawlHistory, window, interval time.Duration) *historyIterator {
+	return &historyIterator{
+		history:  h,
+		window:   window,
+		interval: interval,
+		lastPass: time.Now(),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (it *historyIterator) Next() bool {
+	for len(it.nodes) == 0 {
+		if wait := time.Until(it.lastPass.Add(it.interval)); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-it.closed:
+				timer.Stop()
+				return false
+			}
+		}
+		it.lastPass = time.Now()
+		it.nodes = it.history.nodeSet(it.window).nodes()
+	}
+	select {
+	case <-it.closed:
+		return false
+	default:
+	}
+	it.cur, it.nodes = it.nodes[0], it.nodes[1:]
+	return true
+}
+
+func (it *historyIterator) Node() *enode.Node {
+	return it.cur
+}
+
+func (it *historyIterator) Close() {
+	it.closeOnce.Do(func() { close(it.closed) })
+}
+
+func unixTime(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+	return uint64(t.Unix())
+}
+
+func fromUnixTime(t uint64) time.Time {
+	if t == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(t), 0).UTC()
+}
@@ -0,0 +1,165 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb/memorydb"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func newTestCrawlNode(t *testing.T, ip net.IP, seq uint64, fork *forkid.ID) *enode.Node {
+	t.Helper()
+	key, _ := crypto.GenerateKey()
+	return signTestCrawlNode(t, key, ip, seq, fork)
+}
+
+func signTestCrawlNode(t *testing.T, key *ecdsa.PrivateKey, ip net.IP, seq uint64, fork *forkid.ID) *enode.Node {
+	t.Helper()
+	var r enr.Record
+	r.SetSeq(seq)
+	r.Set(enr.IP(ip))
+	r.Set(enr.TCP(30303))
+	r.Set(enr.UDP(30303))
+	if fork != nil {
+		r.Set(enr.WithEntry("eth", struct {
+			ForkID forkid.ID
+			Tail   []rlp.RawValue `rlp:"tail"`
+		}{ForkID: *fork}))
+	}
+	if err := enode.SignV4(&r, key); err != nil {
+		t.Fatal(err)
+	}
+	n, err := enode.New(enode.ValidSchemes, &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestCrawlHistoryRecord(t *testing.T) {
+	var (
+		now     = time.Unix(1700000000, 0).UTC()
+		history = newCrawlHistory(memorydb.New())
+		hellos  int
+	)
+	history.now = func() time.Time { return now }
+	history.helloInterval = time.Hour
+	history.hello = func(n *enode.Node) (string, error) {
+		hellos++
+		return "Geth/v1.12.0-stable/linux-amd64/go1.20.3", nil
+	}
+	key, _ := crypto.GenerateKey()
+	n := signTestCrawlNode(t, key, net.IP{10, 0, 0, 1}, 1, nil)
+
+	// Unresponsive nodes are not added.
+	history.record(n, nil, errors.New("timeout"))
+	if history.get(n.ID()) != nil {
+		t.Fatal("unresponsive node added to history")
+	}
+
+	history.record(n, n, nil)
+	now = now.Add(10 * time.Minute)
+	history.record(n, nil, errors.New("timeout"))
+	now = now.Add(10 * time.Minute)
+	updated := signTestCrawlNode(t, key, net.IP{10, 0, 0, 1}, 2, nil)
+	history.record(n, updated, nil)
+
+	h := history.get(n.ID())
+	if h == nil {
+		t.Fatal("node not in history")
+	}
+	if h.Checks != 3 || h.Responses != 2 {
+		t.Errorf("wrong checks/responses: %d/%d", h.Checks, h.Responses)
+	}
+	if h.Uptime() != 2.0/3.0 {
+		t.Errorf("wrong uptime %f", h.Uptime())
+	}
+	if !h.FirstSeen.Equal(now.Add(-20*time.Minute)) || !h.LastSeen.Equal(now) {
+		t.Errorf("wrong first/last seen: %v %v", h.FirstSeen, h.LastSeen)
+	}
+	if h.N.Seq() != 2 {
+		t.Errorf("record not updated, seq %d", h.N.Seq())
+	}
+	if h.Client != "Geth/v1.12.0-stable/linux-amd64/go1.20.3" {
+		t.Errorf("wrong client %q", h.Client)
+	}
+	if hellos != 1 {
+		t.Errorf("wrong number of RLPx handshakes %d, want 1", hellos)
+	}
+
+	// Nodes drop out of the node set when they're not seen within the window.
+	if ns := history.nodeSet(time.Hour); len(ns) != 1 || ns[n.ID()].Score != 2 {
+		t.Errorf("wrong node set %v", ns)
+	}
+	now = now.Add(2 * time.Hour)
+	if ns := history.nodeSet(time.Hour); len(ns) != 0 {
+		t.Errorf("stale node in node set")
+	}
+}
+
+func TestCrawlStats(t *testing.T) {
+	var (
+		history = newCrawlHistory(memorydb.New())
+		config  = params.MainnetChainConfig
+		genesis = params.MainnetGenesisHash
+		now     = history.now()
+		current = forkid.NewID(config, genesis, math.MaxUint64, uint64(now.Unix()))
+		stale   = forkid.NewID(config, genesis, config.LondonBlock.Uint64(), 0)
+		clients = []string{"Geth/v1.12.0", "Geth/v1.11.6", "Nethermind/v1.19.3", ""}
+		nodes   = []*enode.Node{
+			newTestCrawlNode(t, net.IP{10, 0, 0, 1}, 1, &current),
+			newTestCrawlNode(t, net.IP{10, 0, 1, 1}, 1, &current),
+			newTestCrawlNode(t, net.IP{10, 1, 0, 1}, 1, &stale),
+			newTestCrawlNode(t, net.IP{10, 0, 0, 2}, 1, nil),
+		}
+	)
+	for i, n := range nodes {
+		history.put(&nodeHistory{N: n, FirstSeen: now, LastSeen: now, LastCheck: now, Checks: 1, Responses: 1, Client: clients[i]})
+	}
+
+	stats, err := computeCrawlStats(history, time.Hour, "mainnet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Nodes != 4 {
+		t.Errorf("wrong node count %d", stats.Nodes)
+	}
+	if stats.Clients["Geth"] != 2 || stats.Clients["Nethermind"] != 1 || stats.Clients["unknown"] != 1 {
+		t.Errorf("wrong client distribution %v", stats.Clients)
+	}
+	if stats.Forks[forkIDString(current)] != 2 || stats.Forks[forkIDString(stale)] != 1 {
+		t.Errorf("wrong fork distribution %v", stats.Forks)
+	}
+	if stats.Network.Compatible != 3 || stats.Network.Ready != 2 {
+		t.Errorf("wrong fork readiness %+v", stats.Network)
+	}
+	if len(stats.Prefixes) != 2 || stats.Prefixes[0] != (prefixCount{"10.0.0.0/16", 3}) {
+		t.Errorf("wrong prefixes %v", stats.Prefixes)
+	}
+}
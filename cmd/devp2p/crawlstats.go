@@ -0,0 +1,195 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/log"
+	"golang.org/x/exp/slices"
+)
+
+// maxStatsPrefixes is the number of IP prefixes reported by the stats API.
+const maxStatsPrefixes = 100
+
+// crawlStats is a summary of the nodes which responded to the crawler recently.
+type crawlStats struct {
+	Time     time.Time      `json:"time"`
+	Window   string         `json:"window"`
+	Nodes    int            `json:"nodes"`
+	Clients  map[string]int `json:"clients"`
+	Forks    map[string]int `json:"forks"`
+	Network  *forkStats     `json:"network,omitempty"`
+	Prefixes []prefixCount  `json:"prefixes"`
+}
+
+// forkStats counts the nodes of a network by their readiness for its next fork.
+type forkStats struct {
+	Name       string `json:"name"`
+	ForkID     string `json:"forkID"` // fork ID of the network at the current time
+	Compatible int    `json:"compatible"`
+	Ready      int    `json:"ready"` // compatible nodes announcing the next fork
+}
+
+// prefixCount is the number of nodes in an IP prefix.
+type prefixCount struct {
+	Prefix string `json:"prefix"`
+	Nodes  int    `json:"nodes"`
+}
+
+// forkIDString formats a fork ID as hash/next.
+func forkIDString(id forkid.ID) string {
+	return fmt.Sprintf("%s/%d", hexutil.Encode(id.Hash[:]), id.Next)
+}
+
+// clientName returns the name of the client implementation from the client
+// string advertised in the RLPx handshake, e.g. "Geth" for "Geth/v1.12.0/linux-amd64/go1.20".
+func clientName(client string) string {
+	if client == "" {
+		return "unknown"
+	}
+	return strings.SplitN(client, "/", 2)[0]
+}
+
+// ipPrefix returns the /16 prefix of IPv4 addresses and the /32 prefix of IPv6
+// addresses, which roughly correspond to networks of a single provider.
+func ipPrefix(ip net.IP) string {
+	if ip4 := ip.To4(); ip4 != nil {
+		return (&net.IPNet{IP: ip4.Mask(net.CIDRMask(16, 32)), Mask: net.CIDRMask(16, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(32, 128)), Mask: net.CIDRMask(32, 128)}).String()
+}
+
+// computeCrawlStats summarizes the nodes which responded within the window. If
+// network is not empty, fork readiness is computed for the given network.
+func computeCrawlStats(history *crawlHistory, window time.Duration, network string) (*crawlStats, error) {
+	var (
+		now    = history.now()
+		cutoff = now.Add(-window)
+		stats  = &crawlStats{
+			Time:     now,
+			Window:   window.String(),
+			Clients:  make(map[string]int),
+			Forks:    make(map[string]int),
+			Prefixes: []prefixCount{},
+		}
+		prefixes = make(map[string]int)
+		filter   forkid.Filter
+		current  forkid.ID
+	)
+	if network != "" {
+		config, genesis, err := ethNetwork(network)
+		if err != nil {
+			return nil, err
+		}
+		// All supported networks are past their last block number based fork,
+		// so only the time matters.
+		filter = forkid.NewStaticFilter(config, genesis)
+		current = forkid.NewID(config, genesis, math.MaxUint64, uint64(now.Unix()))
+		stats.Network = &forkStats{Name: network, ForkID: forkIDString(current)}
+	}
+	history.forEach(func(h *nodeHistory) {
+		if h.LastSeen.Before(cutoff) {
+			return
+		}
+		stats.Nodes++
+		stats.Clients[clientName(h.Client)]++
+		if ip := h.N.IP(); ip != nil {
+			prefixes[ipPrefix(ip)]++
+		}
+		id, ok := ethForkID(h.N)
+		if !ok {
+			return
+		}
+		stats.Forks[forkIDString(id)]++
+		if filter != nil && filter(id) == nil {
+			stats.Network.Compatible++
+			if id == current {
+				stats.Network.Ready++
+			}
+		}
+	})
+	for prefix, n := range prefixes {
+		stats.Prefixes = append(stats.Prefixes, prefixCount{Prefix: prefix, Nodes: n})
+	}
+	slices.SortFunc(stats.Prefixes, func(a, b prefixCount) bool {
+		if a.Nodes != b.Nodes {
+			return a.Nodes > b.Nodes
+		}
+		return a.Prefix < b.Prefix
+	})
+	if len(stats.Prefixes) > maxStatsPrefixes {
+		stats.Prefixes = stats.Prefixes[:maxStatsPrefixes]
+	}
+	return stats, nil
+}
+
+// crawlStatsHandler serves the crawler statistics over HTTP.
+type crawlStatsHandler struct {
+	history *crawlHistory
+	window  time.Duration
+	network string
+}
+
+func newCrawlStatsHandler(history *crawlHistory, window time.Duration, network string) http.Handler {
+	h := &crawlStatsHandler{history: history, window: window, network: network}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/stats", h.serveStats(func(s *crawlStats) interface{} { return s }))
+	mux.HandleFunc("/stats/clients", h.serveStats(func(s *crawlStats) interface{} { return s.Clients }))
+	mux.HandleFunc("/stats/forks", h.serveStats(func(s *crawlStats) interface{} {
+		return struct {
+			Forks   map[string]int `json:"forks"`
+			Network *forkStats     `json:"network,omitempty"`
+		}{s.Forks, s.Network}
+	}))
+	mux.HandleFunc("/stats/geo", h.serveStats(func(s *crawlStats) interface{} { return s.Prefixes }))
+	mux.HandleFunc("/nodes", h.serveNodes)
+	return mux
+}
+
+func (h *crawlStatsHandler) serveStats(pick func(*crawlStats) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats, err := computeCrawlStats(h.history, h.window, h.network)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSONResponse(w, pick(stats))
+	}
+}
+
+// serveNodes serves the recently seen nodes in nodes.json format.
+func (h *crawlStatsHandler) serveNodes(w http.ResponseWriter, r *http.Request) {
+	writeJSONResponse(w, h.history.nodeSet(h.window))
+}
+
+func writeJSONResponse(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", jsonIndent)
+	if err := enc.Encode(v); err != nil {
+		log.Debug("Failed to write HTTP response", "err", err)
+	}
+}
@@ -154,14 +154,10 @@ func dnsSign(ctx *cli.Context) error {
 		defdir  = ctx.Args().Get(0)
 		keyfile = ctx.Args().Get(1)
 		def     = loadTreeDefinition(defdir)
-		domain  = directoryName(defdir)
 	)
-	if def.Meta.URL != "" {
-		d, _, err := dnsdisc.ParseURL(def.Meta.URL)
-		if err != nil {
-			return fmt.Errorf("invalid 'url' field: %v", err)
-		}
-		domain = d
+	domain, err := treeDomain(defdir, def)
+	if err != nil {
+		return err
 	}
 	if ctx.IsSet(dnsDomainFlag.Name) {
 		domain = ctx.String(dnsDomainFlag.Name)
@@ -171,21 +167,40 @@ func dnsSign(ctx *cli.Context) error {
 	} else {
 		def.Meta.Seq++ // Auto-bump sequence number if not supplied via flag.
 	}
-	t, err := dnsdisc.MakeTree(def.Meta.Seq, def.Nodes, def.Meta.Links)
+	def, err = signTreeDefinition(def, domain, loadSigningKey(keyfile))
 	if err != nil {
 		return err
 	}
+	writeTreeMetadata(defdir, def)
+	return nil
+}
 
-	key := loadSigningKey(keyfile)
-	url, err := t.Sign(key, domain)
+// treeDomain returns the domain of a tree definition, which is taken from its
+// URL or from the name of its directory.
+func treeDomain(defdir string, def *dnsDefinition) (string, error) {
+	if def.Meta.URL == "" {
+		return directoryName(defdir), nil
+	}
+	domain, _, err := dnsdisc.ParseURL(def.Meta.URL)
 	if err != nil {
-		return fmt.Errorf("can't sign: %v", err)
+		return "", fmt.Errorf("invalid 'url' field: %v", err)
 	}
+	return domain, nil
+}
 
+// signTreeDefinition creates the tree of a definition and signs it.
+func signTreeDefinition(def *dnsDefinition, domain string, key *ecdsa.PrivateKey) (*dnsDefinition, error) {
+	t, err := dnsdisc.MakeTree(def.Meta.Seq, def.Nodes, def.Meta.Links)
+	if err != nil {
+		return nil, err
+	}
+	url, err := t.Sign(key, domain)
+	if err != nil {
+		return nil, fmt.Errorf("can't sign: %v", err)
+	}
 	def = treeToDefinition(url, t)
 	def.Meta.LastModified = time.Now()
-	writeTreeMetadata(defdir, def)
-	return nil
+	return def, nil
 }
 
 // directoryName returns the directory name of the given path.
@@ -52,6 +52,8 @@ func init() {
 		dnsCommand,
 		nodesetCommand,
 		rlpxCommand,
+		captureDumpCommand,
+		crawlerCommand,
 	}
 }
 
@@ -25,7 +25,9 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/forkid"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/enr"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
@@ -100,19 +102,28 @@ func nodesetFilter(ctx *cli.Context) error {
 	if ctx.NArg() < 1 {
 		return errors.New("need nodes file as argument")
 	}
-	// Parse -limit.
-	limit, err := parseFilterLimit(ctx.Args().Tail())
+	result, err := filterNodeSet(loadNodesJSON(ctx.Args().First()), ctx.Args().Tail())
 	if err != nil {
 		return err
 	}
+	writeNodesJSON("-", result)
+	return nil
+}
+
+// filterNodeSet applies the filters and -limit option in args to a node set.
+func filterNodeSet(ns nodeSet, args []string) (nodeSet, error) {
+	// Parse -limit.
+	limit, err := parseFilterLimit(args)
+	if err != nil {
+		return nil, err
+	}
 	// Parse the filters.
-	filter, err := andFilter(ctx.Args().Tail())
+	filter, err := andFilter(args)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Load nodes and apply filters.
-	ns := loadNodesJSON(ctx.Args().First())
+	// Apply filters.
 	result := make(nodeSet)
 	for id, n := range ns {
 		if filter(n) {
@@ -122,8 +133,7 @@ func nodesetFilter(ctx *cli.Context) error {
 	if limit >= 0 {
 		result = result.topN(limit)
 	}
-	writeNodesJSON("-", result)
-	return nil
+	return result, nil
 }
 
 type nodeFilter func(nodeJSON) bool
@@ -224,32 +234,45 @@ func minAgeFilter(args []string) (nodeFilter, error) {
 	return f, nil
 }
 
-func ethFilter(args []string) (nodeFilter, error) {
-	var filter forkid.Filter
-	switch args[0] {
+// ethNetwork returns the chain config and genesis hash of a known network.
+func ethNetwork(name string) (*params.ChainConfig, common.Hash, error) {
+	switch name {
 	case "mainnet":
-		filter = forkid.NewStaticFilter(params.MainnetChainConfig, params.MainnetGenesisHash)
+		return params.MainnetChainConfig, params.MainnetGenesisHash, nil
 	case "goerli":
-		filter = forkid.NewStaticFilter(params.GoerliChainConfig, params.GoerliGenesisHash)
+		return params.GoerliChainConfig, params.GoerliGenesisHash, nil
 	case "sepolia":
-		filter = forkid.NewStaticFilter(params.SepoliaChainConfig, params.SepoliaGenesisHash)
+		return params.SepoliaChainConfig, params.SepoliaGenesisHash, nil
 	default:
-		return nil, fmt.Errorf("unknown network %q", args[0])
+		return nil, common.Hash{}, fmt.Errorf("unknown network %q", name)
 	}
+}
 
+func ethFilter(args []string) (nodeFilter, error) {
+	config, genesis, err := ethNetwork(args[0])
+	if err != nil {
+		return nil, err
+	}
+	filter := forkid.NewStaticFilter(config, genesis)
 	f := func(n nodeJSON) bool {
-		var eth struct {
-			ForkID forkid.ID
-			Tail   []rlp.RawValue `rlp:"tail"`
-		}
-		if n.N.Load(enr.WithEntry("eth", &eth)) != nil {
-			return false
-		}
-		return filter(eth.ForkID) == nil
+		id, ok := ethForkID(n.N)
+		return ok && filter(id) == nil
 	}
 	return f, nil
 }
 
+// ethForkID returns the fork ID announced in the "eth" entry of a node record.
+func ethForkID(n *enode.Node) (forkid.ID, bool) {
+	var eth struct {
+		ForkID forkid.ID
+		Tail   []rlp.RawValue `rlp:"tail"`
+	}
+	if n.Load(enr.WithEntry("eth", &eth)) != nil {
+		return forkid.ID{}, false
+	}
+	return eth.ForkID, true
+}
+
 func lesFilter(args []string) (nodeFilter, error) {
 	f := func(n nodeJSON) bool {
 		var les struct {
@@ -17,13 +17,16 @@
 package main
 
 import (
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/devp2p/internal/ethtest"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
 	"github.com/ethereum/go-ethereum/p2p/rlpx"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/urfave/cli/v2"
@@ -68,37 +71,50 @@ var (
 
 func rlpxPing(ctx *cli.Context) error {
 	n := getNodeArg(ctx)
-	fd, err := net.Dial("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()))
+	ourKey, _ := crypto.GenerateKey()
+	h, err := rlpxHello(n, ourKey, 0)
 	if err != nil {
 		return err
 	}
-	conn := rlpx.NewConn(fd, n.Pubkey())
-	ourKey, _ := crypto.GenerateKey()
-	_, err = conn.Handshake(ourKey)
+	fmt.Printf("%+v\n", h)
+	return nil
+}
+
+// rlpxHello performs the RLPx handshake with the node and returns the protocol
+// handshake it sends. A zero timeout means no timeout.
+func rlpxHello(n *enode.Node, key *ecdsa.PrivateKey, timeout time.Duration) (*ethtest.Hello, error) {
+	fd, err := net.DialTimeout("tcp", fmt.Sprintf("%v:%d", n.IP(), n.TCP()), timeout)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	conn := rlpx.NewConn(fd, n.Pubkey())
+	defer conn.Close()
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if _, err = conn.Handshake(key); err != nil {
+		return nil, err
 	}
 	code, data, _, err := conn.Read()
 	if err != nil {
-		return err
+		return nil, err
 	}
 	switch code {
 	case 0:
 		var h ethtest.Hello
 		if err := rlp.DecodeBytes(data, &h); err != nil {
-			return fmt.Errorf("invalid handshake: %v", err)
+			return nil, fmt.Errorf("invalid handshake: %v", err)
 		}
-		fmt.Printf("%+v\n", h)
+		return &h, nil
 	case 1:
 		var msg []p2p.DiscReason
 		if rlp.DecodeBytes(data, &msg); len(msg) == 0 {
-			return errors.New("invalid disconnect message")
+			return nil, errors.New("invalid disconnect message")
 		}
-		return fmt.Errorf("received disconnect message: %v", msg[0])
+		return nil, fmt.Errorf("received disconnect message: %v", msg[0])
 	default:
-		return fmt.Errorf("invalid message code %d, expected handshake (code zero)", code)
+		return nil, fmt.Errorf("invalid message code %d, expected handshake (code zero)", code)
 	}
-	return nil
 }
 
 // rlpxEthTest runs the eth protocol test suite.
@@ -0,0 +1,166 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/urfave/cli/v2"
+)
+
+var HexFlag = &cli.StringFlag{
+	Name:  "hex",
+	Usage: "single container data parse and validation",
+}
+
+var RefTestFlag = &cli.StringFlag{
+	Name:  "test",
+	Usage: "path to EOF validation reference test file or directory",
+}
+
+var eofParseCommand = &cli.Command{
+	Name:      "eofparse",
+	Aliases:   []string{"eof"},
+	Usage:     "parses and validates EOF containers",
+	ArgsUsage: "[<file>]",
+	Action:    eofParseCmd,
+	Flags: []cli.Flag{
+		HexFlag,
+		RefTestFlag,
+	},
+	Description: `
+The eofparse command validates hex encoded EOF v1 containers, one per line,
+read from the given file or standard input. For each container, it prints
+either 'OK' followed by the code section sizes, or the validation error.
+With --test, the EOF validation reference tests in the given file or directory
+are checked instead.`,
+}
+
+func eofParseCmd(ctx *cli.Context) error {
+	if ctx.IsSet(RefTestFlag.Name) {
+		return eofRefTests(ctx.String(RefTestFlag.Name))
+	}
+	if ctx.IsSet(HexFlag.Name) {
+		fmt.Println(parseEOF(ctx.String(HexFlag.Name)))
+		return nil
+	}
+	var in io.Reader = os.Stdin
+	if fn := ctx.Args().First(); len(fn) > 0 {
+		f, err := os.Open(fn)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 1024*1024), 10*1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Println(parseEOF(line))
+	}
+	return scanner.Err()
+}
+
+// parseEOF validates a hex encoded EOF container, returning the outcome.
+func parseEOF(input string) string {
+	code, err := hex.DecodeString(strings.TrimPrefix(input, "0x"))
+	if err != nil {
+		return fmt.Sprintf("err: unable to decode data: %v", err)
+	}
+	c, err := vm.ValidateEOF(code)
+	if err != nil {
+		return fmt.Sprintf("err: %v", err)
+	}
+	sizes := make([]string, len(c.Code))
+	for i, section := range c.Code {
+		sizes[i] = fmt.Sprintf("%d", len(section))
+	}
+	return fmt.Sprintf("OK %s", strings.Join(sizes, ","))
+}
+
+// eofTest is a single EOF validation reference test.
+type eofTest struct {
+	Vectors map[string]struct {
+		Code    string `json:"code"`
+		Results map[string]struct {
+			Result    bool   `json:"result"`
+			Exception string `json:"exception,omitempty"`
+		} `json:"results"`
+	} `json:"vectors"`
+}
+
+// eofRefTests runs the EOF validation reference tests in the given file or
+// directory, checking the validity of each vector against the Prague results.
+func eofRefTests(path string) error {
+	var passed, failed int
+	err := filepath.WalkDir(path, func(fn string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(fn) != ".json" {
+			return nil
+		}
+		data, err := os.ReadFile(fn)
+		if err != nil {
+			return err
+		}
+		var tests map[string]eofTest
+		if err := json.Unmarshal(data, &tests); err != nil {
+			return fmt.Errorf("%s: %v", fn, err)
+		}
+		for name, test := range tests {
+			for id, vector := range test.Vectors {
+				want, ok := vector.Results["Prague"]
+				if !ok {
+					continue
+				}
+				code, err := hex.DecodeString(strings.TrimPrefix(vector.Code, "0x"))
+				if err == nil {
+					_, err = vm.ValidateEOF(code)
+				}
+				if (err == nil) != want.Result {
+					fmt.Fprintf(os.Stderr, "%s: %s/%s: have err %v, want valid %v (%s)\n", fn, name, id, err, want.Result, want.Exception)
+					failed++
+					continue
+				}
+				passed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d tests passed, %d tests failed\n", passed, failed)
+	if failed > 0 {
+		return fmt.Errorf("%d tests failed", failed)
+	}
+	return nil
+}
@@ -0,0 +1,290 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// command is a debugger command. The run function returns whether execution
+// should be resumed after the command completes.
+type command struct {
+	names []string // Name of the command, followed by its aliases
+	args  string
+	help  string
+	run   func(d *Debugger, args []string) (bool, error)
+}
+
+var (
+	commandList []*command          // Commands in the order they are listed in the help
+	commands    map[string]*command // Commands indexed by name and alias
+)
+
+func init() {
+	commandList = []*command{
+		{[]string{"step", "s"}, "", "execute the next instruction, stepping into calls", cmdStep},
+		{[]string{"next", "n"}, "", "execute the next instruction, stepping over calls", cmdNext},
+		{[]string{"finish", "f"}, "", "run until the current call frame returns", cmdFinish},
+		{[]string{"continue", "c"}, "", "run until the next breakpoint", cmdContinue},
+		{[]string{"quit", "q"}, "", "disable the debugger and run to completion", cmdQuit},
+		{[]string{"break", "b"}, "pc <n> [address] | op <name> | depth <n> | slot <word>", "set a breakpoint", cmdBreak},
+		{[]string{"delete", "d"}, "<id>", "delete a breakpoint", cmdDelete},
+		{[]string{"breakpoints"}, "", "list the breakpoints", cmdBreakpoints},
+		{[]string{"where", "w"}, "", "print the current instruction", cmdWhere},
+		{[]string{"stack", "st"}, "", "print the stack, top first", cmdStack},
+		{[]string{"memory", "mem"}, "[offset [size]]", "print the memory", cmdMemory},
+		{[]string{"storage", "sto"}, "[slot]", "print a storage slot, or all the slots accessed so far", cmdStorage},
+		{[]string{"returndata", "rd"}, "", "print the return data of the last call", cmdReturnData},
+		{[]string{"source", "l"}, "[lines]", "print the source code around the current line", cmdSource},
+		{[]string{"help", "h"}, "", "print this help", cmdHelp},
+	}
+	commands = make(map[string]*command)
+	for _, cmd := range commandList {
+		for _, name := range cmd.names {
+			commands[name] = cmd
+		}
+	}
+}
+
+func cmdHelp(d *Debugger, args []string) (bool, error) {
+	for _, cmd := range commandList {
+		usage := strings.Join(cmd.names, ", ")
+		if cmd.args != "" {
+			usage += " " + cmd.args
+		}
+		fmt.Fprintf(d.out, "  %-58s %s\n", usage, cmd.help)
+	}
+	return false, nil
+}
+
+func cmdStep(d *Debugger, args []string) (bool, error) {
+	d.mode = modeStep
+	return true, nil
+}
+
+func cmdNext(d *Debugger, args []string) (bool, error) {
+	d.mode, d.target = modeNext, d.cur.depth
+	return true, nil
+}
+
+func cmdFinish(d *Debugger, args []string) (bool, error) {
+	d.mode, d.target = modeFinish, d.cur.depth
+	return true, nil
+}
+
+func cmdContinue(d *Debugger, args []string) (bool, error) {
+	d.mode = modeContinue
+	return true, nil
+}
+
+func cmdQuit(d *Debugger, args []string) (bool, error) {
+	d.mode = modeDetach
+	return true, nil
+}
+
+func cmdBreak(d *Debugger, args []string) (bool, error) {
+	if len(args) < 2 {
+		return false, errors.New("usage: break pc <n> [address] | op <name> | depth <n> | slot <word>")
+	}
+	b := &breakpoint{id: d.nextID}
+	switch args[0] {
+	case "pc":
+		pc, err := strconv.ParseUint(args[1], 0, 64)
+		if err != nil {
+			return false, fmt.Errorf("invalid pc: %v", err)
+		}
+		b.kind, b.pc = breakPC, pc
+		if len(args) > 2 {
+			if !common.IsHexAddress(args[2]) {
+				return false, fmt.Errorf("invalid address %q", args[2])
+			}
+			addr := common.HexToAddress(args[2])
+			b.address = &addr
+		}
+	case "op":
+		op := vm.StringToOp(strings.ToUpper(args[1]))
+		if op.String() != strings.ToUpper(args[1]) {
+			return false, fmt.Errorf("unknown opcode %q", args[1])
+		}
+		b.kind, b.op = breakOp, op
+	case "depth":
+		depth, err := strconv.Atoi(args[1])
+		if err != nil {
+			return false, fmt.Errorf("invalid depth: %v", err)
+		}
+		b.kind, b.depth = breakDepth, depth
+	case "slot":
+		slot, err := parseWord(args[1])
+		if err != nil {
+			return false, err
+		}
+		b.kind, b.slot = breakSlot, slot
+	default:
+		return false, fmt.Errorf("unknown breakpoint type %q", args[0])
+	}
+	d.nextID++
+	d.breakpoints = append(d.breakpoints, b)
+	fmt.Fprintf(d.out, "breakpoint %v set\n", b)
+	return false, nil
+}
+
+func cmdDelete(d *Debugger, args []string) (bool, error) {
+	if len(args) != 1 {
+		return false, errors.New("usage: delete <id>")
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[0], "#"))
+	if err != nil {
+		return false, fmt.Errorf("invalid breakpoint id: %v", err)
+	}
+	for i, b := range d.breakpoints {
+		if b.id == id {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return false, nil
+		}
+	}
+	return false, fmt.Errorf("no breakpoint #%d", id)
+}
+
+func cmdBreakpoints(d *Debugger, args []string) (bool, error) {
+	if len(d.breakpoints) == 0 {
+		fmt.Fprintln(d.out, "no breakpoints")
+	}
+	for _, b := range d.breakpoints {
+		fmt.Fprintln(d.out, b)
+	}
+	return false, nil
+}
+
+func cmdWhere(d *Debugger, args []string) (bool, error) {
+	d.printLocation()
+	return false, nil
+}
+
+func cmdStack(d *Debugger, args []string) (bool, error) {
+	stack := d.cur.scope.Stack.Data()
+	if len(stack) == 0 {
+		fmt.Fprintln(d.out, "stack is empty")
+	}
+	for i := len(stack) - 1; i >= 0; i-- {
+		fmt.Fprintf(d.out, "%4d: %#064x\n", len(stack)-1-i, stack[i].Bytes32())
+	}
+	return false, nil
+}
+
+func cmdMemory(d *Debugger, args []string) (bool, error) {
+	mem := d.cur.scope.Memory
+	offset, size := uint64(0), uint64(mem.Len())
+	if len(args) > 0 {
+		var err error
+		if offset, err = strconv.ParseUint(args[0], 0, 64); err != nil {
+			return false, fmt.Errorf("invalid offset: %v", err)
+		}
+		size = 32
+		if len(args) > 1 {
+			if size, err = strconv.ParseUint(args[1], 0, 64); err != nil {
+				return false, fmt.Errorf("invalid size: %v", err)
+			}
+		}
+	}
+	if offset >= uint64(mem.Len()) {
+		fmt.Fprintf(d.out, "memory size is %d\n", mem.Len())
+		return false, nil
+	}
+	if size > uint64(mem.Len())-offset {
+		size = uint64(mem.Len()) - offset
+	}
+	data := mem.GetPtr(int64(offset), int64(size))
+	for i := 0; i < len(data); i += 32 {
+		end := i + 32
+		if end > len(data) {
+			end = len(data)
+		}
+		fmt.Fprintf(d.out, "%#06x: %x\n", offset+uint64(i), data[i:end])
+	}
+	return false, nil
+}
+
+func cmdStorage(d *Debugger, args []string) (bool, error) {
+	addr := d.cur.scope.Contract.Address()
+	if len(args) > 0 {
+		slot, err := parseWord(args[0])
+		if err != nil {
+			return false, err
+		}
+		fmt.Fprintf(d.out, "%#x: %#x\n", slot, d.env.StateDB.GetState(addr, slot))
+		return false, nil
+	}
+	slots := make([]common.Hash, 0, len(d.touched[addr]))
+	for slot := range d.touched[addr] {
+		slots = append(slots, slot)
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Big().Cmp(slots[j].Big()) < 0 })
+	if len(slots) == 0 {
+		fmt.Fprintf(d.out, "no storage slots of %#x accessed yet\n", addr)
+	}
+	for _, slot := range slots {
+		fmt.Fprintf(d.out, "%#x: %#x\n", slot, d.env.StateDB.GetState(addr, slot))
+	}
+	return false, nil
+}
+
+func cmdReturnData(d *Debugger, args []string) (bool, error) {
+	fmt.Fprintf(d.out, "%#x\n", d.cur.rData)
+	return false, nil
+}
+
+func cmdSource(d *Debugger, args []string) (bool, error) {
+	context := 3
+	if len(args) > 0 {
+		var err error
+		if context, err = strconv.Atoi(args[0]); err != nil {
+			return false, fmt.Errorf("invalid line count: %v", err)
+		}
+	}
+	_, file, line, _, ok := d.position()
+	if !ok {
+		return false, errors.New("no source available for the current instruction")
+	}
+	fmt.Fprintf(d.out, "%s:\n", file)
+	for _, l := range d.sources.lines(file, line, context) {
+		fmt.Fprintln(d.out, l)
+	}
+	return false, nil
+}
+
+// parseWord parses a 32 byte word given in hex or decimal notation.
+func parseWord(s string) (common.Hash, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		if len(s) > 66 {
+			return common.Hash{}, fmt.Errorf("word %q too long", s)
+		}
+		return common.HexToHash(s), nil
+	}
+	n, ok := new(big.Int).SetString(s, 10)
+	if !ok || n.Sign() < 0 || n.BitLen() > 256 {
+		return common.Hash{}, fmt.Errorf("invalid word %q", s)
+	}
+	return common.BigToHash(n), nil
+}
@@ -0,0 +1,276 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+// Package debugger implements an interactive step debugger for the EVM.
+package debugger
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// mode is the execution mode the debugger resumes in.
+type mode int
+
+const (
+	modeStep     mode = iota // Stop at the next instruction, at any depth
+	modeNext                 // Stop at the next instruction at the same or a lower depth
+	modeFinish               // Stop once the current call frame returns
+	modeContinue             // Stop only at breakpoints
+	modeDetach               // Never stop again
+)
+
+// breakpointKind enumerates the conditions a breakpoint can trigger on.
+type breakpointKind int
+
+const (
+	breakPC breakpointKind = iota
+	breakOp
+	breakDepth
+	breakSlot
+)
+
+// breakpoint is a condition upon which execution is suspended.
+type breakpoint struct {
+	id      int
+	kind    breakpointKind
+	pc      uint64
+	address *common.Address // Restricts a pc breakpoint to a contract, if set
+	op      vm.OpCode
+	depth   int
+	slot    common.Hash
+}
+
+func (b *breakpoint) String() string {
+	switch b.kind {
+	case breakPC:
+		if b.address != nil {
+			return fmt.Sprintf("#%d pc %d in %#x", b.id, b.pc, *b.address)
+		}
+		return fmt.Sprintf("#%d pc %d", b.id, b.pc)
+	case breakOp:
+		return fmt.Sprintf("#%d op %v", b.id, b.op)
+	case breakDepth:
+		return fmt.Sprintf("#%d depth %d", b.id, b.depth)
+	default:
+		return fmt.Sprintf("#%d slot %#x", b.id, b.slot)
+	}
+}
+
+// matches checks whether the breakpoint triggers on the current instruction.
+func (b *breakpoint) matches(pc uint64, op vm.OpCode, depth int, scope *vm.ScopeContext) bool {
+	switch b.kind {
+	case breakPC:
+		return b.pc == pc && (b.address == nil || *b.address == scope.Contract.Address())
+	case breakOp:
+		return b.op == op
+	case breakDepth:
+		return b.depth == depth
+	default:
+		if op != vm.SLOAD && op != vm.SSTORE {
+			return false
+		}
+		stack := scope.Stack.Data()
+		return len(stack) > 0 && common.Hash(stack[len(stack)-1].Bytes32()) == b.slot
+	}
+}
+
+// vmState is the VM state at the instruction the debugger is suspended on.
+type vmState struct {
+	pc    uint64
+	op    vm.OpCode
+	gas   uint64
+	cost  uint64
+	depth int
+	scope *vm.ScopeContext
+	rData []byte
+}
+
+// Debugger is an EVM logger that suspends execution on breakpoints or while
+// stepping, and reads commands to inspect the VM state from its input.
+type Debugger struct {
+	in      *bufio.Scanner
+	out     io.Writer
+	sources *Sources
+
+	env         *vm.EVM
+	mode        mode
+	target      int // Depth relative to which next and finish resume
+	breakpoints []*breakpoint
+	nextID      int
+	touched     map[common.Address]map[common.Hash]struct{} // Storage slots accessed per contract
+	cur         *vmState
+}
+
+// New creates a debugger reading commands from in and writing to out. The
+// optional sources are used to display the Solidity lines being executed.
+func New(in io.Reader, out io.Writer, sources *Sources) *Debugger {
+	return &Debugger{
+		in:      bufio.NewScanner(in),
+		out:     out,
+		sources: sources,
+		mode:    modeStep,
+		nextID:  1,
+		touched: make(map[common.Address]map[common.Hash]struct{}),
+	}
+}
+
+// CaptureTxStart implements the EVMLogger interface.
+func (d *Debugger) CaptureTxStart(gasLimit uint64) {}
+
+// CaptureTxEnd implements the EVMLogger interface.
+func (d *Debugger) CaptureTxEnd(restGas uint64) {}
+
+// CaptureStart implements the EVMLogger interface to initialize the debugging session.
+func (d *Debugger) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	d.env = env
+	if create {
+		fmt.Fprintf(d.out, "creating contract %#x from %#x, gas %d\n", to, from, gas)
+	} else {
+		fmt.Fprintf(d.out, "calling %#x from %#x, gas %d, input %#x\n", to, from, gas, input)
+	}
+}
+
+// CaptureEnd implements the EVMLogger interface to report the execution result.
+func (d *Debugger) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if err != nil {
+		fmt.Fprintf(d.out, "execution failed: %v, gas used %d, output %#x\n", err, gasUsed, output)
+	} else {
+		fmt.Fprintf(d.out, "execution finished, gas used %d, output %#x\n", gasUsed, output)
+	}
+}
+
+// CaptureEnter implements the EVMLogger interface to report entered call frames.
+func (d *Debugger) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if d.mode == modeDetach {
+		return
+	}
+	fmt.Fprintf(d.out, "-> %v %#x, gas %d, input %#x\n", typ, to, gas, input)
+}
+
+// CaptureExit implements the EVMLogger interface to report exited call frames.
+func (d *Debugger) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if d.mode == modeDetach {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(d.out, "<- failed: %v, gas used %d, output %#x\n", err, gasUsed, output)
+	} else {
+		fmt.Fprintf(d.out, "<- returned, gas used %d, output %#x\n", gasUsed, output)
+	}
+}
+
+// CaptureFault implements the EVMLogger interface to report execution faults.
+func (d *Debugger) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if d.mode == modeDetach {
+		return
+	}
+	fmt.Fprintf(d.out, "fault at pc %d (%v), depth %d: %v\n", pc, op, depth, err)
+}
+
+// CaptureState implements the EVMLogger interface, suspending execution and
+// running the command loop if a breakpoint hits or a step completes.
+func (d *Debugger) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op == vm.SLOAD || op == vm.SSTORE {
+		if stack := scope.Stack.Data(); len(stack) > 0 {
+			addr := scope.Contract.Address()
+			if d.touched[addr] == nil {
+				d.touched[addr] = make(map[common.Hash]struct{})
+			}
+			d.touched[addr][common.Hash(stack[len(stack)-1].Bytes32())] = struct{}{}
+		}
+	}
+	var stop bool
+	switch d.mode {
+	case modeDetach:
+		return
+	case modeStep:
+		stop = true
+	case modeNext:
+		stop = depth <= d.target
+	case modeFinish:
+		stop = depth < d.target
+	}
+	for _, b := range d.breakpoints {
+		if b.matches(pc, op, depth, scope) {
+			fmt.Fprintf(d.out, "breakpoint %v hit\n", b)
+			stop = true
+			break
+		}
+	}
+	if !stop {
+		return
+	}
+	d.cur = &vmState{pc: pc, op: op, gas: gas, cost: cost, depth: depth, scope: scope, rData: rData}
+	if err != nil {
+		fmt.Fprintf(d.out, "error: %v\n", err)
+	}
+	d.printLocation()
+	d.repl()
+	d.cur = nil
+}
+
+// printLocation prints the instruction the debugger is suspended on, along with
+// the source line it was compiled from, if known.
+func (d *Debugger) printLocation() {
+	s := d.cur
+	fmt.Fprintf(d.out, "[%d] %#x pc %d: %v  gas %d cost %d\n", s.depth, s.scope.Contract.Address(), s.pc, s.op, s.gas, s.cost)
+	if _, file, line, text, ok := d.position(); ok {
+		fmt.Fprintf(d.out, "     %s:%d  %s\n", file, line, strings.TrimSpace(text))
+	}
+}
+
+// position resolves the source position of the current instruction.
+func (d *Debugger) position() (contract, file string, line int, text string, ok bool) {
+	c := d.cur.scope.Contract
+	return d.sources.Position(c.CodeHash, c.Code, d.cur.pc)
+}
+
+// repl reads and executes commands until one of them resumes execution. If the
+// input is exhausted, the debugger detaches and lets the execution run to the end.
+func (d *Debugger) repl() {
+	for {
+		fmt.Fprint(d.out, "(evm) ")
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			d.mode = modeDetach
+			return
+		}
+		fields := strings.Fields(d.in.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		cmd, ok := commands[fields[0]]
+		if !ok {
+			fmt.Fprintf(d.out, "unknown command %q, type 'help' for a list of commands\n", fields[0])
+			continue
+		}
+		resume, err := cmd.run(d, fields[1:])
+		if err != nil {
+			fmt.Fprintf(d.out, "error: %v\n", err)
+			continue
+		}
+		if resume {
+			return
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/core/vm/runtime"
+)
+
+func TestParseSourceMap(t *testing.T) {
+	locs, err := ParseSourceMap("1:2:0:-;:9;2:1:1:i;;:::o")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SourceLocation{
+		{Start: 1, Length: 2, File: 0, Jump: "-"},
+		{Start: 1, Length: 9, File: 0, Jump: "-"},
+		{Start: 2, Length: 1, File: 1, Jump: "i"},
+		{Start: 2, Length: 1, File: 1, Jump: "i"},
+		{Start: 2, Length: 1, File: 1, Jump: "o"},
+	}
+	if !reflect.DeepEqual(locs, want) {
+		t.Fatalf("source map mismatch:\nhave %+v\nwant %+v", locs, want)
+	}
+	if _, err := ParseSourceMap("1:x"); err == nil {
+		t.Fatal("expected error for malformed source map")
+	}
+}
+
+// Tests a scripted debugging session, stepping over calls, hitting breakpoints
+// and resolving source lines.
+func TestDebuggerSession(t *testing.T) {
+	var (
+		callee = common.HexToAddress("0xbb")
+		// PUSH1 1 PUSH1 0 SSTORE STOP
+		calleeCode = []byte{byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP)}
+		// CALL(gas, callee, 0, 0, 0, 0, 0) PUSH1 7 STOP
+		code = append([]byte{
+			byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+			byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL),
+		}, byte(vm.PUSH1), 7, byte(vm.STOP))
+	)
+	// Create a fake artifact mapping the callee's instructions to a source file
+	dir := t.TempDir()
+	source := "contract C {\n  function f() {\n    x = 1;\n  }\n}\n"
+	if err := os.WriteFile(filepath.Join(dir, "c.sol"), []byte(source), 0644); err != nil {
+		t.Fatal(err)
+	}
+	artifact := `{"contracts":{"c.sol:C":{"bin":"","bin-runtime":"6001600055","srcmap":"","srcmap-runtime":"32:5:0:-;;36:1:0","abi":[]}},"sourceList":["c.sol"],"version":"0.8.0"}`
+	if err := os.WriteFile(filepath.Join(dir, "combined.json"), []byte(artifact), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sources, err := LoadCombinedJSON(filepath.Join(dir, "combined.json"))
+	if err != nil {
+		t.Fatalf("failed to load artifact: %v", err)
+	}
+	script := strings.Join([]string{
+		"b op CALL",
+		"b slot 0",
+		"c",      // runs to the CALL
+		"next",   // steps over it, but the slot breakpoint hits inside
+		"source", // show the solidity source
+		"stack",
+		"finish", // back to the caller, after the call
+		"storage",
+		"d 2",
+		"c",
+	}, "\n")
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(common.HexToAddress("0xaa"), code)
+	statedb.SetCode(callee, calleeCode)
+
+	var out bytes.Buffer
+	cfg := &runtime.Config{
+		State:     statedb,
+		EVMConfig: vm.Config{Tracer: New(strings.NewReader(script), &out, sources)},
+	}
+	if _, _, err := runtime.Call(common.HexToAddress("0xaa"), nil, cfg); err != nil {
+		t.Fatal(err)
+	}
+	output := out.String()
+	for _, want := range []string{
+		"breakpoint #1 op CALL hit\n[1] 0x00000000000000000000000000000000000000aa pc 13: CALL",
+		"breakpoint #2 slot 0x0000000000000000000000000000000000000000000000000000000000000000 hit\n[2] 0x00000000000000000000000000000000000000bb pc 4: SSTORE",
+		"c.sol:3  x = 1;",
+		"=>    3      x = 1;",
+		"   0: 0x0000000000000000000000000000000000000000000000000000000000000000\n   1: 0x0000000000000000000000000000000000000000000000000000000000000001\n",
+		"<- returned, gas used",
+		"[1] 0x00000000000000000000000000000000000000aa pc 14: PUSH1",
+		"no storage slots of 0x00000000000000000000000000000000000000aa accessed yet",
+		"execution finished",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in debugger output:\n%s", want, output)
+		}
+	}
+}
+
+func TestDebuggerMemory(t *testing.T) {
+	// PUSH1 0x42 PUSH1 0 MSTORE STOP
+	code := []byte{byte(vm.PUSH1), 0x42, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.STOP)}
+	script := strings.Join([]string{
+		"b op STOP",
+		"c",
+		"memory 31 0xffffffffffffffff", // size overflows offset+size
+		"memory 64",
+		"c",
+	}, "\n")
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	statedb.SetCode(common.HexToAddress("0xaa"), code)
+
+	var out bytes.Buffer
+	cfg := &runtime.Config{
+		State:     statedb,
+		EVMConfig: vm.Config{Tracer: New(strings.NewReader(script), &out, nil)},
+	}
+	if _, _, err := runtime.Call(common.HexToAddress("0xaa"), nil, cfg); err != nil {
+		t.Fatal(err)
+	}
+	output := out.String()
+	for _, want := range []string{"0x00001f: 42\n", "memory size is 32\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in debugger output:\n%s", want, output)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package debugger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/compiler"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// SourceLocation is a decoded entry of a solc source map.
+type SourceLocation struct {
+	Start  int    // Byte offset of the range in the source file
+	Length int    // Length of the source range in bytes
+	File   int    // Index of the source file in the source list, -1 if none
+	Jump   string // Jump type: "i" into a function, "o" out of it, "-" regular
+}
+
+// ParseSourceMap decodes a compressed solc source map (s:l:f:j;s:l:f:j;...)
+// into one location per instruction.
+func ParseSourceMap(srcmap string) ([]SourceLocation, error) {
+	if srcmap == "" {
+		return nil, nil
+	}
+	var (
+		entries = strings.Split(srcmap, ";")
+		locs    = make([]SourceLocation, len(entries))
+		prev    = SourceLocation{File: -1, Jump: "-"}
+	)
+	for i, entry := range entries {
+		loc := prev
+		for j, field := range strings.Split(entry, ":") {
+			if field == "" {
+				continue
+			}
+			switch j {
+			case 0, 1, 2:
+				n, err := strconv.Atoi(field)
+				if err != nil {
+					return nil, fmt.Errorf("invalid source map entry %d: %v", i, err)
+				}
+				switch j {
+				case 0:
+					loc.Start = n
+				case 1:
+					loc.Length = n
+				case 2:
+					loc.File = n
+				}
+			case 3:
+				loc.Jump = field
+			}
+		}
+		locs[i], prev = loc, loc
+	}
+	return locs, nil
+}
+
+// instructionOffsets returns the program counter of every instruction in code.
+func instructionOffsets(code []byte) []uint64 {
+	var pcs []uint64
+	for pc := uint64(0); pc < uint64(len(code)); pc++ {
+		pcs = append(pcs, pc)
+		if op := vm.OpCode(code[pc]); op.IsPush() {
+			pc += uint64(op - vm.PUSH1 + 1)
+		}
+	}
+	return pcs
+}
+
+// sourceFile is a source file referenced by a source map.
+type sourceFile struct {
+	name  string
+	src   []byte
+	lines []int // Byte offset of the start of every line
+}
+
+// newSourceFile indexes the lines of a source file.
+func newSourceFile(name string, src []byte) *sourceFile {
+	lines := []int{0}
+	for i, b := range src {
+		if b == '\n' {
+			lines = append(lines, i+1)
+		}
+	}
+	return &sourceFile{name: name, src: src, lines: lines}
+}
+
+// line returns the 1-based line number containing the given byte offset.
+func (f *sourceFile) line(offset int) int {
+	lo, hi := 0, len(f.lines)
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		if f.lines[mid] <= offset {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo + 1
+}
+
+// text returns the content of the given 1-based line.
+func (f *sourceFile) text(line int) string {
+	if line < 1 || line > len(f.lines) {
+		return ""
+	}
+	start, end := f.lines[line-1], len(f.src)
+	if line < len(f.lines) {
+		end = f.lines[line]
+	}
+	return strings.TrimRight(string(f.src[start:end]), "\r\n")
+}
+
+// sourceMap maps the program counters of a single piece of bytecode to the
+// source locations they were compiled from.
+type sourceMap struct {
+	name string
+	code []byte
+	locs map[uint64]SourceLocation
+}
+
+// newSourceMap maps the instructions of code to the given source map.
+func newSourceMap(name string, code []byte, srcmap string) (*sourceMap, error) {
+	locs, err := ParseSourceMap(srcmap)
+	if err != nil {
+		return nil, err
+	}
+	sm := &sourceMap{name: name, code: code, locs: make(map[uint64]SourceLocation)}
+	for i, pc := range instructionOffsets(code) {
+		if i >= len(locs) {
+			break
+		}
+		sm.locs[pc] = locs[i]
+	}
+	return sm, nil
+}
+
+// Sources holds the source maps and files of a solc combined-json artifact.
+type Sources struct {
+	maps  []*sourceMap
+	files []*sourceFile
+	cache map[common.Hash]*sourceMap
+}
+
+// LoadCombinedJSON reads a solc --combined-json artifact, which must contain the
+// bin, bin-runtime, srcmap and srcmap-runtime outputs. Source files in the
+// artifact's source list are looked up relative to the artifact's directory and
+// the current directory.
+func LoadCombinedJSON(path string) (*Sources, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	contracts, err := compiler.ParseCombinedJSON(blob, "", "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	var meta struct {
+		SourceList []string `json:"sourceList"`
+	}
+	if err := json.Unmarshal(blob, &meta); err != nil {
+		return nil, err
+	}
+	s := &Sources{cache: make(map[common.Hash]*sourceMap)}
+	for _, name := range meta.SourceList {
+		var src []byte
+		for _, dir := range []string{filepath.Dir(path), "."} {
+			if src, err = os.ReadFile(filepath.Join(dir, name)); err == nil {
+				break
+			}
+		}
+		s.files = append(s.files, newSourceFile(name, src))
+	}
+	for name, contract := range contracts {
+		if srcmap, ok := contract.Info.SrcMap.(string); ok {
+			sm, err := newSourceMap(name, common.FromHex(contract.Code), srcmap)
+			if err != nil {
+				return nil, fmt.Errorf("contract %s: %v", name, err)
+			}
+			s.maps = append(s.maps, sm)
+		}
+		sm, err := newSourceMap(name, common.FromHex(contract.RuntimeCode), contract.Info.SrcMapRuntime)
+		if err != nil {
+			return nil, fmt.Errorf("contract %s: %v", name, err)
+		}
+		s.maps = append(s.maps, sm)
+	}
+	return s, nil
+}
+
+// lookup returns the source map matching the given code. Init code is matched
+// by prefix, since constructor arguments are appended to it.
+func (s *Sources) lookup(hash common.Hash, code []byte) *sourceMap {
+	if sm, ok := s.cache[hash]; ok {
+		return sm
+	}
+	var match *sourceMap
+	for _, sm := range s.maps {
+		if len(sm.code) > 0 && bytes.HasPrefix(code, sm.code) {
+			if match == nil || len(sm.code) > len(match.code) {
+				match = sm
+			}
+		}
+	}
+	s.cache[hash] = match
+	return match
+}
+
+// Position resolves the source position of a program counter within the given
+// code, returning the contract name, file, line number and line text.
+func (s *Sources) Position(hash common.Hash, code []byte, pc uint64) (contract string, file string, line int, text string, ok bool) {
+	if s == nil {
+		return "", "", 0, "", false
+	}
+	sm := s.lookup(hash, code)
+	if sm == nil {
+		return "", "", 0, "", false
+	}
+	loc, ok := sm.locs[pc]
+	if !ok || loc.File < 0 || loc.File >= len(s.files) {
+		return sm.name, "", 0, "", false
+	}
+	f := s.files[loc.File]
+	if f.src == nil {
+		return sm.name, f.name, 0, "", false
+	}
+	line = f.line(loc.Start)
+	return sm.name, f.name, line, f.text(line), true
+}
+
+// lines returns the source lines around the given line of a file.
+func (s *Sources) lines(file string, line, context int) []string {
+	for _, f := range s.files {
+		if f.name != file {
+			continue
+		}
+		var out []string
+		for l := line - context; l <= line+context; l++ {
+			if l < 1 || l > len(f.lines) {
+				continue
+			}
+			marker := "  "
+			if l == line {
+				marker = "=>"
+			}
+			out = append(out, fmt.Sprintf("%s %4d  %s", marker, l, f.text(l)))
+		}
+		return out
+	}
+	return nil
+}
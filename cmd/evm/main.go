@@ -32,6 +32,14 @@ var (
 		Name:  "debug",
 		Usage: "output full trace logs",
 	}
+	InteractiveFlag = &cli.BoolFlag{
+		Name:  "interactive",
+		Usage: "run the code in an interactive step debugger",
+	}
+	CombinedJSONFlag = &cli.StringFlag{
+		Name:  "combinedjson",
+		Usage: "solc --combined-json artifact providing source maps to the interactive debugger",
+	}
 	MemProfileFlag = &cli.StringFlag{
 		Name:  "memprofile",
 		Usage: "creates a memory profile at the given path",
@@ -40,6 +48,10 @@ var (
 		Name:  "cpuprofile",
 		Usage: "creates a CPU profile at the given path",
 	}
+	ProfileFlag = &cli.StringFlag{
+		Name:  "profile",
+		Usage: "writes a gas profile in collapsed-stack (flamegraph) format to the given path",
+	}
 	StatDumpFlag = &cli.BoolFlag{
 		Name:  "statdump",
 		Usage: "displays stack and heap memory information",
@@ -190,6 +202,8 @@ func init() {
 		BenchFlag,
 		CreateFlag,
 		DebugFlag,
+		InteractiveFlag,
+		CombinedJSONFlag,
 		VerbosityFlag,
 		CodeFlag,
 		CodeFileFlag,
@@ -201,6 +215,7 @@ func init() {
 		InputFileFlag,
 		MemProfileFlag,
 		CPUProfileFlag,
+		ProfileFlag,
 		StatDumpFlag,
 		GenesisFlag,
 		MachineFlag,
@@ -214,6 +229,7 @@ func init() {
 	app.Commands = []*cli.Command{
 		compileCommand,
 		disasmCommand,
+		eofParseCommand,
 		runCommand,
 		blockTestCommand,
 		stateTestCommand,
@@ -29,6 +29,7 @@ import (
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/evm/internal/compiler"
+	"github.com/ethereum/go-ethereum/cmd/evm/internal/debugger"
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
@@ -37,7 +38,9 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/core/vm/runtime"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+	_ "github.com/ethereum/go-ethereum/eth/tracers/native"
 	"github.com/ethereum/go-ethereum/internal/flags"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
@@ -107,6 +110,34 @@ func timedExec(bench bool, execFunc func() ([]byte, uint64, error)) (output []by
 	return output, gasLeft, stats, err
 }
 
+// writeProfile writes the collapsed stacks gathered by the profiler to the given
+// path and dumps the JSON summary of the profile to stderr.
+func writeProfile(path string, profiler tracers.Tracer) error {
+	res, err := profiler.GetResult()
+	if err != nil {
+		return err
+	}
+	var summary map[string]json.RawMessage
+	if err := json.Unmarshal(res, &summary); err != nil {
+		return err
+	}
+	var collapsed string
+	if err := json.Unmarshal(summary["collapsed"], &collapsed); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(collapsed), 0644); err != nil {
+		return fmt.Errorf("could not write profile: %v", err)
+	}
+	delete(summary, "collapsed")
+	out, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "#### PROFILE ####")
+	fmt.Fprintln(os.Stderr, string(out))
+	return nil
+}
+
 func runCmd(ctx *cli.Context) error {
 	glogger := log.NewGlogHandler(log.StreamHandler(os.Stderr, log.TerminalFormat(false)))
 	glogger.Verbosity(log.Lvl(ctx.Int(VerbosityFlag.Name)))
@@ -122,6 +153,7 @@ func runCmd(ctx *cli.Context) error {
 	var (
 		tracer        vm.EVMLogger
 		debugLogger   *logger.StructLogger
+		profiler      tracers.Tracer
 		statedb       *state.StateDB
 		chainConfig   *params.ChainConfig
 		sender        = common.BytesToAddress([]byte("sender"))
@@ -130,7 +162,16 @@ func runCmd(ctx *cli.Context) error {
 		preimages     = ctx.Bool(DumpFlag.Name)
 		blobHashes    []common.Hash // TODO (MariusVanDerWijden) implement blob hashes in state tests
 	)
-	if ctx.Bool(MachineFlag.Name) {
+	if ctx.Bool(InteractiveFlag.Name) {
+		var sources *debugger.Sources
+		if path := ctx.String(CombinedJSONFlag.Name); path != "" {
+			var err error
+			if sources, err = debugger.LoadCombinedJSON(path); err != nil {
+				return fmt.Errorf("could not load source maps: %v", err)
+			}
+		}
+		tracer = debugger.New(os.Stdin, os.Stdout, sources)
+	} else if ctx.Bool(MachineFlag.Name) {
 		tracer = logger.NewJSONLogger(logconfig, os.Stdout)
 	} else if ctx.Bool(DebugFlag.Name) {
 		debugLogger = logger.NewStructLogger(logconfig)
@@ -138,6 +179,17 @@ func runCmd(ctx *cli.Context) error {
 	} else {
 		debugLogger = logger.NewStructLogger(logconfig)
 	}
+	printOutput := tracer == nil
+	if ctx.String(ProfileFlag.Name) != "" {
+		if tracer != nil {
+			utils.Fatalf("--%s cannot be combined with --%s, --%s or --%s", ProfileFlag.Name, InteractiveFlag.Name, MachineFlag.Name, DebugFlag.Name)
+		}
+		var err error
+		if profiler, err = tracers.DefaultDirectory.New("profilerTracer", new(tracers.Context), nil); err != nil {
+			return err
+		}
+		tracer = profiler
+	}
 	if ctx.String(GenesisFlag.Name) != "" {
 		gen := readGenesis(ctx.String(GenesisFlag.Name))
 		genesisConfig = gen
@@ -307,6 +359,12 @@ func runCmd(ctx *cli.Context) error {
 		logger.WriteLogs(os.Stderr, statedb.Logs())
 	}
 
+	if profiler != nil {
+		if err := writeProfile(ctx.String(ProfileFlag.Name), profiler); err != nil {
+			return err
+		}
+	}
+
 	if bench || ctx.Bool(StatDumpFlag.Name) {
 		fmt.Fprintf(os.Stderr, `EVM gas used:    %d
 execution time:  %v
@@ -314,7 +372,7 @@ allocations:     %d
 allocated bytes: %d
 `, initialGas-leftOverGas, stats.time, stats.allocs, stats.bytesAllocated)
 	}
-	if tracer == nil {
+	if printOutput {
 		fmt.Printf("%#x\n", output)
 		if err != nil {
 			fmt.Printf(" error: %v\n", err)
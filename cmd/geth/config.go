@@ -196,8 +196,9 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		utils.RegisterFullSyncTester(stack, eth, ctx.Path(utils.SyncTargetFlag.Name))
 	}
 
-	// Start the dev mode if requested, or launch the engine API for
-	// interacting with external consensus client.
+	// Start the dev mode if requested, follow the beacon chain with the light
+	// client, or launch the engine API for interacting with external consensus
+	// client.
 	if ctx.IsSet(utils.DeveloperFlag.Name) {
 		simBeacon, err := catalyst.NewSimulatedBeacon(ctx.Uint64(utils.DeveloperPeriodFlag.Name), eth)
 		if err != nil {
@@ -205,6 +206,11 @@ func makeFullNode(ctx *cli.Context) (*node.Node, ethapi.Backend) {
 		}
 		catalyst.RegisterSimulatedBeaconAPIs(stack, simBeacon)
 		stack.RegisterLifecycle(simBeacon)
+	} else if ctx.IsSet(utils.BeaconApiFlag.Name) {
+		if cfg.Eth.SyncMode == downloader.LightSync {
+			utils.Fatalf("The beacon light client is not supported in light sync mode")
+		}
+		utils.RegisterLightBeacon(ctx, stack, eth)
 	} else if cfg.Eth.SyncMode != downloader.LightSync {
 		err := ethcatalyst.Register(stack, eth)
 		if err != nil {
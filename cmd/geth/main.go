@@ -74,6 +74,7 @@ var (
 		utils.TxPoolNoLocalsFlag,
 		utils.TxPoolJournalFlag,
 		utils.TxPoolRejournalFlag,
+		utils.TxPoolSnapshotFlag,
 		utils.TxPoolPriceLimitFlag,
 		utils.TxPoolPriceBumpFlag,
 		utils.TxPoolAccountSlotsFlag,
@@ -81,6 +82,14 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolOriginRateFlag,
+		utils.TxPoolQueueCoverageFlag,
+		utils.TxPoolSpamPenaltyFlag,
+		utils.TxPoolPrivateLifetimeFlag,
+		utils.TxPoolPrivateAccountSlotsFlag,
+		utils.TxPoolPrivateGlobalSlotsFlag,
+		utils.TxPoolUserOpEntryPointFlag,
+		utils.TxPoolUserOpBundlerFlag,
 		utils.SyncModeFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
@@ -119,16 +128,29 @@ var (
 		utils.MinerEtherbaseFlag,
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
+		utils.MinerOrderingFlag,
 		utils.MinerNewPayloadTimeout,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV4Flag,
 		utils.DiscoveryV5Flag,
+		utils.PortalHistoryFlag,
+		utils.PortalBootnodesFlag,
+		utils.PortalStorageFlag,
 		utils.LegacyDiscoveryV5Flag,
 		utils.NetrestrictFlag,
+		utils.MaxEgressFlag,
+		utils.MaxIngressFlag,
+		utils.CaptureDirFlag,
 		utils.NodeKeyFileFlag,
 		utils.NodeKeyHexFlag,
 		utils.DNSDiscoveryFlag,
+		utils.BeaconApiFlag,
+		utils.BeaconApiHeaderFlag,
+		utils.BeaconCheckpointFlag,
+		utils.BeaconThresholdFlag,
+		utils.BeaconConfigFlag,
+		utils.BeaconGenesisRootFlag,
 		utils.DeveloperFlag,
 		utils.DeveloperGasLimitFlag,
 		utils.DeveloperPeriodFlag,
@@ -37,12 +37,18 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/beacon/light"
+	lightapi "github.com/ethereum/go-ethereum/beacon/light/api"
+	bparams "github.com/ethereum/go-ethereum/beacon/params"
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/fdlimit"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
+	"github.com/ethereum/go-ethereum/core/txpool/userpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -322,10 +328,16 @@ var (
 	}
 	TxPoolRejournalFlag = &cli.DurationFlag{
 		Name:     "txpool.rejournal",
-		Usage:    "Time interval to regenerate the local transaction journal",
+		Usage:    "Time interval to regenerate the local transaction journal and the pool snapshot",
 		Value:    ethconfig.Defaults.TxPool.Rejournal,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolSnapshotFlag = &cli.StringFlag{
+		Name:     "txpool.snapshot",
+		Usage:    "Disk snapshot of all pending and queued remote transactions to survive node restarts (disabled if empty)",
+		Value:    ethconfig.Defaults.TxPool.Snapshot,
+		Category: flags.TxPoolCategory,
+	}
 	TxPoolPriceLimitFlag = &cli.Uint64Flag{
 		Name:     "txpool.pricelimit",
 		Usage:    "Minimum gas price tip to enforce for acceptance into the pool",
@@ -368,6 +380,52 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolOriginRateFlag = &cli.Uint64Flag{
+		Name:     "txpool.originrate",
+		Usage:    "Maximum number of transactions accepted per minute from a single RPC client subnet, /24 for IPv4 and /64 for IPv6 (0 = unlimited)",
+		Value:    ethconfig.Defaults.TxPool.OriginRate,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolQueueCoverageFlag = &cli.Uint64Flag{
+		Name:     "txpool.queuecoverage",
+		Usage:    "Minimum percentage of the pooled transaction costs a sender's balance must cover to queue gapped transactions (0 = disabled)",
+		Value:    ethconfig.Defaults.TxPool.QueueCoverage,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolSpamPenaltyFlag = &cli.Uint64Flag{
+		Name:     "txpool.spampenalty",
+		Usage:    "Decaying count of underpriced transactions and replacements at which a sender is throttled (0 = disabled)",
+		Value:    ethconfig.Defaults.TxPool.SpamPenalty,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivateLifetimeFlag = &cli.Uint64Flag{
+		Name:     "txpool.privatelifetime",
+		Usage:    "Number of blocks privately submitted transactions are kept for inclusion",
+		Value:    ethconfig.Defaults.PrivatePool.Lifetime,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivateAccountSlotsFlag = &cli.Uint64Flag{
+		Name:     "txpool.privateaccountslots",
+		Usage:    "Maximum number of privately submitted transactions per account",
+		Value:    ethconfig.Defaults.PrivatePool.AccountSlots,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolPrivateGlobalSlotsFlag = &cli.Uint64Flag{
+		Name:     "txpool.privateglobalslots",
+		Usage:    "Maximum number of privately submitted transactions for all accounts",
+		Value:    ethconfig.Defaults.PrivatePool.GlobalSlots,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolUserOpEntryPointFlag = &cli.StringFlag{
+		Name:     "txpool.userops.entrypoint",
+		Usage:    "ERC-4337 EntryPoint contract to accept user operations for (disabled if unset)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolUserOpBundlerFlag = &cli.StringFlag{
+		Name:     "txpool.userops.bundler",
+		Usage:    "Unlocked account signing the user operation bundles and receiving their fees",
+		Category: flags.TxPoolCategory,
+	}
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
 		Name:     "cache",
@@ -461,6 +519,12 @@ var (
 		Value:    ethconfig.Defaults.Miner.Recommit,
 		Category: flags.MinerCategory,
 	}
+	MinerOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    "Transaction ordering policy for built blocks (price, fifo, roundrobin)",
+		Value:    miner.OrderingPrice,
+		Category: flags.MinerCategory,
+	}
 	MinerNewPayloadTimeout = &cli.DurationFlag{
 		Name:     "miner.newpayload-timeout",
 		Usage:    "Specify the maximum time allowance for creating a new payload",
@@ -564,6 +628,40 @@ var (
 		Category:  flags.MiscCategory,
 	}
 
+	// Beacon chain light client settings
+	BeaconApiFlag = &cli.StringFlag{
+		Name:     "beacon.api",
+		Usage:    "Beacon node REST API URL to follow the chain head with a light client instead of an external consensus client (RPC-only use)",
+		Category: flags.BeaconCategory,
+	}
+	BeaconApiHeaderFlag = &cli.StringSliceFlag{
+		Name:     "beacon.api.header",
+		Usage:    "Custom HTTP header of beacon API requests (format: \"key:value\")",
+		Category: flags.BeaconCategory,
+	}
+	BeaconCheckpointFlag = &cli.StringFlag{
+		Name:     "beacon.checkpoint",
+		Usage:    "Trusted beacon block root to start the light client from",
+		Category: flags.BeaconCategory,
+	}
+	BeaconThresholdFlag = &cli.IntFlag{
+		Name:     "beacon.threshold",
+		Usage:    "Minimum number of sync committee signers required to accept a beacon head",
+		Value:    bparams.SyncCommitteeSupermajority,
+		Category: flags.BeaconCategory,
+	}
+	BeaconConfigFlag = &cli.PathFlag{
+		Name:      "beacon.config",
+		Usage:     "Beacon chain config YAML file (required for custom networks)",
+		TakesFile: true,
+		Category:  flags.BeaconCategory,
+	}
+	BeaconGenesisRootFlag = &cli.StringFlag{
+		Name:     "beacon.genesis.gvroot",
+		Usage:    "Beacon chain genesis validators root (required for custom networks)",
+		Category: flags.BeaconCategory,
+	}
+
 	// RPC settings
 	IPCDisabledFlag = &cli.BoolFlag{
 		Name:     "ipcdisable",
@@ -738,7 +836,7 @@ var (
 	}
 	NATFlag = &cli.StringFlag{
 		Name:     "nat",
-		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|extip:<IP>)",
+		Usage:    "NAT port mapping mechanism (any|none|upnp|pmp|pmp:<IP>|pcp|pcp:<IP>|extip:<IP>)",
 		Value:    "any",
 		Category: flags.NetworkingCategory,
 	}
@@ -760,11 +858,42 @@ var (
 		Usage:    "Enables the experimental RLPx V5 (Topic Discovery) mechanism",
 		Category: flags.NetworkingCategory,
 	}
+	PortalHistoryFlag = &cli.BoolFlag{
+		Name:     "portal.history",
+		Usage:    "Retrieves block bodies and receipts missing from the database from the portal history network (enables discovery v5)",
+		Category: flags.NetworkingCategory,
+	}
+	PortalBootnodesFlag = &cli.StringFlag{
+		Name:     "portal.bootnodes",
+		Usage:    "Comma separated enode URLs of portal history network bootstrap nodes",
+		Category: flags.NetworkingCategory,
+	}
+	PortalStorageFlag = &cli.Uint64Flag{
+		Name:     "portal.storage",
+		Usage:    "Maximum size of portal history network content stored for other nodes in megabytes (0 = unlimited)",
+		Value:    ethconfig.Defaults.PortalHistoryStorage / 1024 / 1024,
+		Category: flags.NetworkingCategory,
+	}
 	NetrestrictFlag = &cli.StringFlag{
 		Name:     "netrestrict",
 		Usage:    "Restricts network communication to the given IP networks (CIDR masks)",
 		Category: flags.NetworkingCategory,
 	}
+	MaxEgressFlag = &cli.Uint64Flag{
+		Name:     "bandwidth.egress",
+		Usage:    "Maximum total network egress in KiB/s, bulk sync traffic is throttled first (0 = unlimited)",
+		Category: flags.NetworkingCategory,
+	}
+	MaxIngressFlag = &cli.Uint64Flag{
+		Name:     "bandwidth.ingress",
+		Usage:    "Maximum total network ingress in KiB/s, bulk sync traffic is throttled first (0 = unlimited)",
+		Category: flags.NetworkingCategory,
+	}
+	CaptureDirFlag = &cli.StringFlag{
+		Name:     "capturedir",
+		Usage:    "Directory to record the decrypted devp2p messages of each peer into (for debugging)",
+		Category: flags.NetworkingCategory,
+	}
 	DNSDiscoveryFlag = &cli.StringFlag{
 		Name:     "discovery.dns",
 		Usage:    "Sets DNS discovery entry points (use \"\" to disable DNS)",
@@ -1201,6 +1330,19 @@ func setLes(ctx *cli.Context, cfg *ethconfig.Config) {
 	}
 }
 
+// setPortal configures the portal history network from the command line flags.
+func setPortal(ctx *cli.Context, cfg *ethconfig.Config) {
+	if ctx.IsSet(PortalHistoryFlag.Name) {
+		cfg.PortalHistory = ctx.Bool(PortalHistoryFlag.Name)
+	}
+	if ctx.IsSet(PortalBootnodesFlag.Name) {
+		cfg.PortalHistoryBootnodes = SplitAndTrim(ctx.String(PortalBootnodesFlag.Name))
+	}
+	if ctx.IsSet(PortalStorageFlag.Name) {
+		cfg.PortalHistoryStorage = ctx.Uint64(PortalStorageFlag.Name) * 1024 * 1024
+	}
+}
+
 // MakeDatabaseHandles raises out the number of allowed file handles per process
 // for Geth and returns half of the allowance to assign to the database.
 func MakeDatabaseHandles(max int) int {
@@ -1339,11 +1481,13 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 	}
 	CheckExclusive(ctx, DiscoveryV4Flag, NoDiscoverFlag)
 	CheckExclusive(ctx, DiscoveryV5Flag, NoDiscoverFlag)
+	CheckExclusive(ctx, PortalHistoryFlag, NoDiscoverFlag)
 	cfg.DiscoveryV4 = ctx.Bool(DiscoveryV4Flag.Name)
 	cfg.DiscoveryV5 = ctx.Bool(DiscoveryV5Flag.Name)
 
 	// If we're running a light client or server, force enable the v5 peer discovery.
-	if lightClient || lightServer {
+	// The portal history network runs on top of discovery v5 as well.
+	if lightClient || lightServer || ctx.Bool(PortalHistoryFlag.Name) {
 		cfg.DiscoveryV5 = true
 	}
 
@@ -1354,6 +1498,15 @@ func SetP2PConfig(ctx *cli.Context, cfg *p2p.Config) {
 		}
 		cfg.NetRestrict = list
 	}
+	if ctx.IsSet(MaxEgressFlag.Name) {
+		cfg.MaxEgress = ctx.Uint64(MaxEgressFlag.Name) * 1024
+	}
+	if ctx.IsSet(MaxIngressFlag.Name) {
+		cfg.MaxIngress = ctx.Uint64(MaxIngressFlag.Name) * 1024
+	}
+	if ctx.IsSet(CaptureDirFlag.Name) {
+		cfg.CaptureDir = ctx.String(CaptureDirFlag.Name)
+	}
 
 	if ctx.Bool(DeveloperFlag.Name) {
 		// --dev mode can't use p2p networking.
@@ -1489,6 +1642,9 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolRejournalFlag.Name) {
 		cfg.Rejournal = ctx.Duration(TxPoolRejournalFlag.Name)
 	}
+	if ctx.IsSet(TxPoolSnapshotFlag.Name) {
+		cfg.Snapshot = ctx.String(TxPoolSnapshotFlag.Name)
+	}
 	if ctx.IsSet(TxPoolPriceLimitFlag.Name) {
 		cfg.PriceLimit = ctx.Uint64(TxPoolPriceLimitFlag.Name)
 	}
@@ -1510,6 +1666,47 @@ func setTxPool(ctx *cli.Context, cfg *legacypool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolOriginRateFlag.Name) {
+		cfg.OriginRate = ctx.Uint64(TxPoolOriginRateFlag.Name)
+	}
+	if ctx.IsSet(TxPoolQueueCoverageFlag.Name) {
+		cfg.QueueCoverage = ctx.Uint64(TxPoolQueueCoverageFlag.Name)
+	}
+	if ctx.IsSet(TxPoolSpamPenaltyFlag.Name) {
+		cfg.SpamPenalty = ctx.Uint64(TxPoolSpamPenaltyFlag.Name)
+	}
+}
+
+func setPrivatePool(ctx *cli.Context, cfg *privatepool.Config) {
+	if ctx.IsSet(TxPoolPrivateLifetimeFlag.Name) {
+		cfg.Lifetime = ctx.Uint64(TxPoolPrivateLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPrivateAccountSlotsFlag.Name) {
+		cfg.AccountSlots = ctx.Uint64(TxPoolPrivateAccountSlotsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolPrivateGlobalSlotsFlag.Name) {
+		cfg.GlobalSlots = ctx.Uint64(TxPoolPrivateGlobalSlotsFlag.Name)
+	}
+}
+
+func setUserPool(ctx *cli.Context, cfg *userpool.Config) {
+	if ctx.IsSet(TxPoolUserOpEntryPointFlag.Name) {
+		addr := ctx.String(TxPoolUserOpEntryPointFlag.Name)
+		if !common.IsHexAddress(addr) {
+			Fatalf("Invalid --%s: %q", TxPoolUserOpEntryPointFlag.Name, addr)
+		}
+		cfg.EntryPoint = common.HexToAddress(addr)
+	}
+	if ctx.IsSet(TxPoolUserOpBundlerFlag.Name) {
+		addr := ctx.String(TxPoolUserOpBundlerFlag.Name)
+		if !common.IsHexAddress(addr) {
+			Fatalf("Invalid --%s: %q", TxPoolUserOpBundlerFlag.Name, addr)
+		}
+		cfg.Bundler = common.HexToAddress(addr)
+	}
+	if cfg.EntryPoint != (common.Address{}) && cfg.Bundler == (common.Address{}) {
+		Fatalf("--%s requires --%s", TxPoolUserOpEntryPointFlag.Name, TxPoolUserOpBundlerFlag.Name)
+	}
 }
 
 func setMiner(ctx *cli.Context, cfg *miner.Config) {
@@ -1525,6 +1722,13 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerRecommitIntervalFlag.Name) {
 		cfg.Recommit = ctx.Duration(MinerRecommitIntervalFlag.Name)
 	}
+	if ctx.IsSet(MinerOrderingFlag.Name) {
+		ordering := ctx.String(MinerOrderingFlag.Name)
+		if _, err := miner.NewOrderingPolicy(ordering); err != nil {
+			Fatalf("Invalid --%s: %v", MinerOrderingFlag.Name, err)
+		}
+		cfg.Ordering = ordering
+	}
 	if ctx.IsSet(MinerNewPayloadTimeout.Name) {
 		cfg.NewPayloadTimeout = ctx.Duration(MinerNewPayloadTimeout.Name)
 	}
@@ -1605,6 +1809,7 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	CheckExclusive(ctx, MainnetFlag, DeveloperFlag, GoerliFlag, SepoliaFlag)
 	CheckExclusive(ctx, LightServeFlag, SyncModeFlag, "light")
 	CheckExclusive(ctx, DeveloperFlag, ExternalSignerFlag) // Can't use both ephemeral unlocked and external signer
+	CheckExclusive(ctx, DeveloperFlag, BeaconApiFlag)
 	if ctx.String(GCModeFlag.Name) == "archive" && ctx.Uint64(TxLookupLimitFlag.Name) != 0 {
 		ctx.Set(TxLookupLimitFlag.Name, "0")
 		log.Warn("Disable transaction unindexing for archive node")
@@ -1615,9 +1820,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
 	setTxPool(ctx, &cfg.TxPool)
+	setPrivatePool(ctx, &cfg.PrivatePool)
+	setUserPool(ctx, &cfg.UserPool)
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
+	setPortal(ctx, cfg)
 
 	// Cap the cache allowance and tune the garbage collector
 	mem, err := gopsutil.VirtualMemory()
@@ -1915,6 +2123,53 @@ func RegisterFullSyncTester(stack *node.Node, eth *eth.Ethereum, path string) {
 	log.Info("Registered full-sync tester", "number", block.NumberU64(), "hash", block.Hash())
 }
 
+// RegisterLightBeacon adds the beacon chain light client into node, which drives
+// the chain head instead of an external consensus client.
+func RegisterLightBeacon(ctx *cli.Context, stack *node.Node, eth *eth.Ethereum) {
+	var chainConfig *btypes.ChainConfig
+	switch {
+	case ctx.IsSet(BeaconConfigFlag.Name):
+		root, err := hexutil.Decode(ctx.String(BeaconGenesisRootFlag.Name))
+		if err != nil || len(root) != common.HashLength {
+			Fatalf("Invalid or missing beacon chain genesis validators root (--%s)", BeaconGenesisRootFlag.Name)
+		}
+		chainConfig = &btypes.ChainConfig{GenesisValidatorsRoot: common.BytesToHash(root)}
+		if err := chainConfig.LoadForks(ctx.Path(BeaconConfigFlag.Name)); err != nil {
+			Fatalf("Failed to load beacon chain config: %v", err)
+		}
+	case ctx.Bool(MainnetFlag.Name):
+		chainConfig = light.MainnetConfig
+	case ctx.Bool(SepoliaFlag.Name):
+		chainConfig = light.SepoliaConfig
+	case ctx.Bool(GoerliFlag.Name):
+		chainConfig = light.GoerliConfig
+	case ctx.Bool(DeveloperFlag.Name):
+		Fatalf("The beacon light client is not supported in developer mode")
+	default:
+		if ctx.IsSet(NetworkIdFlag.Name) {
+			Fatalf("Beacon chain config is required for custom networks (--%s)", BeaconConfigFlag.Name)
+		}
+		chainConfig = light.MainnetConfig
+	}
+	checkpoint, err := hexutil.Decode(ctx.String(BeaconCheckpointFlag.Name))
+	if err != nil || len(checkpoint) != common.HashLength {
+		Fatalf("Invalid or missing beacon chain checkpoint (--%s)", BeaconCheckpointFlag.Name)
+	}
+	headers, err := lightapi.ParseCustomHeaders(ctx.StringSlice(BeaconApiHeaderFlag.Name))
+	if err != nil {
+		Fatalf("%v", err)
+	}
+	config := light.Config{
+		ChainConfig:     chainConfig,
+		Checkpoint:      common.BytesToHash(checkpoint),
+		SignerThreshold: ctx.Int(BeaconThresholdFlag.Name),
+	}
+	if _, err := ethcatalyst.RegisterLightBeacon(stack, eth, ctx.String(BeaconApiFlag.Name), headers, config); err != nil {
+		Fatalf("Failed to register beacon light client: %v", err)
+	}
+	log.Info("Registered beacon light client", "api", ctx.String(BeaconApiFlag.Name), "checkpoint", config.Checkpoint)
+}
+
 func SetupMetrics(ctx *cli.Context) {
 	if metrics.Enabled {
 		log.Info("Enabling metrics collection")
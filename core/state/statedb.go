@@ -397,6 +397,24 @@ func (s *StateDB) StorageTrie(addr common.Address) (Trie, error) {
 	return cpy.getTrie(s.db)
 }
 
+// GetStorageRoot returns the storage root of an account, including the storage
+// changes of all the transactions finalised since the last commit. The root of
+// non-existent accounts is the empty root hash.
+func (s *StateDB) GetStorageRoot(addr common.Address) (common.Hash, error) {
+	stateObject := s.getStateObject(addr)
+	if stateObject == nil {
+		return types.EmptyRootHash, nil
+	}
+	if len(stateObject.pendingStorage) == 0 {
+		return stateObject.data.Root, nil
+	}
+	tr, err := s.StorageTrie(addr)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return tr.Hash(), nil
+}
+
 func (s *StateDB) HasSelfDestructed(addr common.Address) bool {
 	stateObject := s.getStateObject(addr)
 	if stateObject != nil {
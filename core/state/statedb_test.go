@@ -1070,3 +1070,30 @@ func TestResetObject(t *testing.T) {
 		t.Fatalf("Unexpected storage slot value %v", slot)
 	}
 }
+
+// Tests that the storage root of an account reflects the finalised but not yet
+// hashed storage changes.
+func TestGetStorageRoot(t *testing.T) {
+	var (
+		state, _ = New(types.EmptyRootHash, NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		addr     = common.HexToAddress("0x1")
+	)
+	if root, _ := state.GetStorageRoot(addr); root != types.EmptyRootHash {
+		t.Fatalf("missing account root mismatch: have %x, want %x", root, types.EmptyRootHash)
+	}
+	state.SetBalance(addr, big.NewInt(1))
+	state.SetState(addr, common.HexToHash("0x1"), common.HexToHash("0x2"))
+	state.Finalise(true)
+
+	pending, err := state.GetStorageRoot(addr)
+	if err != nil {
+		t.Fatalf("failed to retrieve pending storage root: %v", err)
+	}
+	state.IntermediateRoot(true)
+	if root, _ := state.GetStorageRoot(addr); root != pending {
+		t.Fatalf("storage root mismatch: have %x, want %x", pending, root)
+	}
+	if pending == types.EmptyRootHash {
+		t.Fatal("storage root not updated")
+	}
+}
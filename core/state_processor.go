@@ -160,3 +160,14 @@ func ApplyTransaction(config *params.ChainConfig, bc ChainContext, author *commo
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{BlobHashes: tx.BlobHashes()}, statedb, config, cfg)
 	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, vmenv)
 }
+
+// ApplyTransactionWithEVM attempts to apply a transaction to the given state
+// database using an existing EVM, whose block context must match the header.
+// Reusing the EVM allows the caller to cancel execution through it.
+func ApplyTransactionWithEVM(config *params.ChainConfig, gp *GasPool, statedb *state.StateDB, header *types.Header, tx *types.Transaction, usedGas *uint64, evm *vm.EVM) (*types.Receipt, error) {
+	msg, err := TransactionToMessage(tx, types.MakeSigner(config, header.Number, header.Time), header.BaseFee)
+	if err != nil {
+		return nil, err
+	}
+	return applyTransaction(msg, config, gp, statedb, header.Number, header.Hash(), tx, usedGas, evm)
+}
@@ -0,0 +1,46 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+// AdmissionStats summarizes the admission policies enforced by a subpool beyond
+// the validity rules, and how many transactions they refused.
+type AdmissionStats struct {
+	OriginRate    uint64 // Transactions accepted per minute from a single RPC origin (0 = unlimited)
+	QueueCoverage uint64 // Percentage of the queued cost the sender's balance must cover (0 = disabled)
+	SpamPenalty   uint64 // Spam penalty at which a sender is throttled (0 = disabled)
+
+	LimitedOrigins   int // Origins currently exceeding their submission rate
+	PenalizedSenders int // Senders with an outstanding spam penalty
+	ThrottledSenders int // Senders currently throttled for spam
+
+	RateLimited uint64 // Transactions refused for their origin's submission rate
+	Uncovered   uint64 // Queued transactions refused for insufficient balance coverage
+	Throttled   uint64 // Transactions refused for their sender's spam penalty
+}
+
+// AdmissionSubPool is a subpool enforcing admission policies on the submitted
+// transactions.
+type AdmissionSubPool interface {
+	SubPool
+
+	// Admission returns the current admission policy statistics of the subpool.
+	Admission() AdmissionStats
+
+	// AllowOrigin records a submission from the given RPC client address made
+	// to another subpool, reporting whether it fits into the origin's rate.
+	AllowOrigin(origin string) bool
+}
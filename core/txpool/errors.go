@@ -50,4 +50,16 @@ var (
 	// ErrFutureReplacePending is returned if a future transaction replaces a pending
 	// transaction. Future transactions should only be able to replace other future transactions.
 	ErrFutureReplacePending = errors.New("future transaction tries to replace pending")
+
+	// ErrPrivateUnsupported is returned if a private transaction is submitted to
+	// a pool without a private subpool.
+	ErrPrivateUnsupported = errors.New("private transactions not supported")
+
+	// ErrOriginRateLimited is returned if the network origin submitting a
+	// transaction exceeded its permitted submission rate.
+	ErrOriginRateLimited = errors.New("origin submission rate exceeded")
+
+	// ErrSenderThrottled is returned if the sender of a transaction accumulated
+	// too much spam penalty from underpriced transactions and replacements.
+	ErrSenderThrottled = errors.New("sender throttled for spam")
 )
@@ -0,0 +1,237 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+)
+
+const (
+	// originRateWindow is the time window over which the submission rate of
+	// an origin is measured.
+	originRateWindow = time.Minute
+
+	// spamHalfLife is the time it takes for the spam penalty of a sender to
+	// decay to half of its value.
+	spamHalfLife = 10 * time.Minute
+
+	// spamForgetPenalty is the decayed penalty below which a sender is forgotten.
+	spamForgetPenalty = 0.01
+
+	// maxSpamSenders is the number of penalized senders tracked, the least
+	// penalized ones are forgotten beyond it.
+	maxSpamSenders = 4096
+
+	// originSubnetV4 and originSubnetV6 are the prefix lengths of the subnets
+	// whose submissions are accounted as a single origin.
+	originSubnetV4 = 24
+	originSubnetV6 = 64
+)
+
+// originWindow counts the submissions of an origin within a rate window.
+type originWindow struct {
+	start time.Time
+	count uint64
+}
+
+// spamScore is the decaying spam penalty of a sender.
+type spamScore struct {
+	penalty float64
+	updated time.Time
+}
+
+// decayed returns the penalty of the sender at the given time.
+func (s *spamScore) decayed(now time.Time) float64 {
+	return s.penalty * math.Exp2(-float64(now.Sub(s.updated))/float64(spamHalfLife))
+}
+
+// admission enforces the admission policies of the pool beyond the validity
+// rules: the submission rate of RPC origins and the spam penalty of senders.
+// The balance coverage of queued transactions is checked by the pool itself,
+// only being accounted for here.
+//
+// Origin rates are checked before obtaining the pool lock, so admission does
+// its own locking.
+type admission struct {
+	originRate  uint64 // Transactions accepted per rate window from a single origin
+	spamPenalty uint64 // Spam penalty at which a sender is throttled
+
+	origins map[string]*originWindow      // Submission windows of the recent origins
+	senders map[common.Address]*spamScore // Spam penalties of the penalized senders
+	stats   txpool.AdmissionStats         // Counters of the refused transactions
+	lock    sync.Mutex
+}
+
+func newAdmission(config *Config) *admission {
+	return &admission{
+		originRate:  config.OriginRate,
+		spamPenalty: config.SpamPenalty,
+		origins:     make(map[string]*originWindow),
+		senders:     make(map[common.Address]*spamScore),
+		stats: txpool.AdmissionStats{
+			OriginRate:    config.OriginRate,
+			QueueCoverage: config.QueueCoverage,
+			SpamPenalty:   config.SpamPenalty,
+		},
+	}
+}
+
+// originIP returns the subnet of an RPC client address, or an empty string if
+// the address carries no IP (e.g. IPC connections). Clients are grouped by /24
+// for IPv4 and by /64 for IPv6, as a single host can easily obtain many of the
+// addresses of its subnet.
+func originIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return ""
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(originSubnetV4, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(originSubnetV6, 128)).String()
+}
+
+// allow records a submission from the given origin, reporting whether it fits
+// into the origin's submission rate.
+func (a *admission) allow(origin string, now time.Time) bool {
+	if a.originRate == 0 || origin == "" {
+		return true
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	window := a.origins[origin]
+	if window == nil || now.Sub(window.start) >= originRateWindow {
+		window = &originWindow{start: now}
+		a.origins[origin] = window
+	}
+	window.count++
+	if window.count > a.originRate {
+		a.stats.RateLimited++
+		return false
+	}
+	return true
+}
+
+// throttled reports whether the sender accumulated enough spam penalty for its
+// transactions to be refused.
+func (a *admission) throttled(sender common.Address, now time.Time) bool {
+	if a.spamPenalty == 0 {
+		return false
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	score, ok := a.senders[sender]
+	if !ok || score.decayed(now) < float64(a.spamPenalty) {
+		return false
+	}
+	a.stats.Throttled++
+	return true
+}
+
+// penalize increases the spam penalty of a sender for an underpriced transaction
+// or replacement. Once the tracked senders are at capacity, the least penalized
+// one makes room for a new sender, unless all carry more penalty than it would.
+func (a *admission) penalize(sender common.Address, now time.Time) {
+	if a.spamPenalty == 0 {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	score, ok := a.senders[sender]
+	if !ok {
+		if len(a.senders) >= maxSpamSenders {
+			var (
+				lowest  common.Address
+				penalty = math.Inf(1)
+			)
+			for addr, score := range a.senders {
+				if decayed := score.decayed(now); decayed < penalty {
+					lowest, penalty = addr, decayed
+				}
+			}
+			if penalty > 1 {
+				return
+			}
+			delete(a.senders, lowest)
+		}
+		score = new(spamScore)
+		a.senders[sender] = score
+	}
+	score.penalty, score.updated = score.decayed(now)+1, now
+}
+
+// uncovered records a queued transaction refused for insufficient balance.
+func (a *admission) uncovered() {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.stats.Uncovered++
+}
+
+// expire forgets the origins whose rate window passed and the senders whose
+// penalty decayed away.
+func (a *admission) expire(now time.Time) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	for origin, window := range a.origins {
+		if now.Sub(window.start) >= originRateWindow {
+			delete(a.origins, origin)
+		}
+	}
+	for sender, score := range a.senders {
+		if score.decayed(now) < spamForgetPenalty {
+			delete(a.senders, sender)
+		}
+	}
+}
+
+// status returns the current admission statistics.
+func (a *admission) status(now time.Time) txpool.AdmissionStats {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	stats := a.stats
+	for _, window := range a.origins {
+		if now.Sub(window.start) < originRateWindow && window.count > a.originRate {
+			stats.LimitedOrigins++
+		}
+	}
+	for _, score := range a.senders {
+		penalty := score.decayed(now)
+		if penalty < spamForgetPenalty {
+			continue
+		}
+		stats.PenalizedSenders++
+		if penalty >= float64(a.spamPenalty) {
+			stats.ThrottledSenders++
+		}
+	}
+	return stats
+}
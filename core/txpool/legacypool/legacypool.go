@@ -19,6 +19,7 @@ package legacypool
 
 import (
 	"errors"
+	"fmt"
 	"math"
 	"math/big"
 	"sort"
@@ -82,6 +83,10 @@ var (
 	queuedNofundsMeter   = metrics.NewRegisteredMeter("txpool/queued/nofunds", nil)   // Dropped due to out-of-funds
 	queuedEvictionMeter  = metrics.NewRegisteredMeter("txpool/queued/eviction", nil)  // Dropped due to lifetime
 
+	// conditionalDropMeter counts the conditional transactions dropped due to
+	// their preconditions becoming unsatisfiable
+	conditionalDropMeter = metrics.NewRegisteredMeter("txpool/conditional/drop", nil)
+
 	// General tx metrics
 	knownTxMeter       = metrics.NewRegisteredMeter("txpool/known", nil)
 	validTxMeter       = metrics.NewRegisteredMeter("txpool/valid", nil)
@@ -92,6 +97,11 @@ var (
 	// throttleTxMeter counts how many transactions are rejected due to too-many-changes between
 	// txpool reorgs.
 	throttleTxMeter = metrics.NewRegisteredMeter("txpool/throttle", nil)
+
+	// Metrics for the transactions refused by the admission policies
+	originLimitMeter     = metrics.NewRegisteredMeter("txpool/admission/ratelimit", nil)
+	uncoveredTxMeter     = metrics.NewRegisteredMeter("txpool/admission/uncovered", nil)
+	throttledSenderMeter = metrics.NewRegisteredMeter("txpool/admission/throttled", nil)
 	// reorgDurationTimer measures how long time a txpool reorg takes.
 	reorgDurationTimer = metrics.NewRegisteredTimer("txpool/reorgtime", nil)
 	// dropBetweenReorgHistogram counts how many drops we experience between two reorg runs. It is expected
@@ -127,7 +137,8 @@ type Config struct {
 	Locals    []common.Address // Addresses that should be treated by default as local
 	NoLocals  bool             // Whether local transaction handling should be disabled
 	Journal   string           // Journal of local transactions to survive node restarts
-	Rejournal time.Duration    // Time interval to regenerate the local transaction journal
+	Rejournal time.Duration    // Time interval to regenerate the local transaction journal and the pool snapshot
+	Snapshot  string           // Snapshot of all remote transactions to survive node restarts (disabled if empty)
 
 	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
 	PriceBump  uint64 // Minimum price bump percentage to replace an already existing transaction (nonce)
@@ -138,6 +149,10 @@ type Config struct {
 	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
+
+	OriginRate    uint64 // Maximum number of transactions accepted per minute from a single RPC origin subnet, /24 for IPv4 and /64 for IPv6 (0 = unlimited)
+	QueueCoverage uint64 // Minimum percentage of the pooled cost a sender's balance must cover to queue gapped transactions (0 = disabled)
+	SpamPenalty   uint64 // Decaying penalty from underpriced transactions and replacements at which a sender is throttled (0 = disabled)
 }
 
 // DefaultConfig contains the default configurations for the transaction pool.
@@ -208,6 +223,7 @@ type LegacyPool struct {
 	chain       BlockChain
 	gasTip      atomic.Pointer[big.Int]
 	txFeed      event.Feed
+	dropFeed    event.Feed
 	scope       event.SubscriptionScope
 	signer      types.Signer
 	mu          sync.RWMutex
@@ -216,8 +232,9 @@ type LegacyPool struct {
 	currentState  *state.StateDB               // Current state in the blockchain head
 	pendingNonces *noncer                      // Pending state tracking virtual nonces
 
-	locals  *accountSet // Set of local transaction to exempt from eviction rules
-	journal *journal    // Journal of local transaction to back up to disk
+	locals   *accountSet // Set of local transaction to exempt from eviction rules
+	journal  *journal    // Journal of local transaction to back up to disk
+	snapshot *snapshot   // Snapshot of remote transactions to back up to disk
 
 	pending map[common.Address]*list     // All currently processable transactions
 	queue   map[common.Address]*list     // Queued but non-processable transactions
@@ -225,6 +242,11 @@ type LegacyPool struct {
 	all     *lookup                      // All transactions to allow lookups
 	priced  *pricedList                  // All transactions sorted by price
 
+	lifecycle *lifecycle               // Recent lifecycle history of the pooled transactions
+	admission *admission               // Admission policies beyond the validity rules
+	drops     []txpool.DroppedTx       // Dropped transactions waiting to be announced
+	included  map[common.Hash]struct{} // Transactions included by the blocks of the running reset
+
 	reqResetCh      chan *txpoolResetRequest
 	reqPromoteCh    chan *accountSet
 	queueTxEventCh  chan *types.Transaction
@@ -256,6 +278,8 @@ func New(config Config, chain BlockChain) *LegacyPool {
 		queue:           make(map[common.Address]*list),
 		beats:           make(map[common.Address]time.Time),
 		all:             newLookup(),
+		lifecycle:       newLifecycle(),
+		admission:       newAdmission(&config),
 		reqResetCh:      make(chan *txpoolResetRequest),
 		reqPromoteCh:    make(chan *accountSet),
 		queueTxEventCh:  make(chan *types.Transaction),
@@ -273,6 +297,9 @@ func New(config Config, chain BlockChain) *LegacyPool {
 	if !config.NoLocals && config.Journal != "" {
 		pool.journal = newTxJournal(config.Journal)
 	}
+	if config.Snapshot != "" {
+		pool.snapshot = newTxSnapshot(config.Snapshot)
+	}
 	return pool
 }
 
@@ -310,6 +337,24 @@ func (pool *LegacyPool) Init(gasTip *big.Int, head *types.Header) error {
 			log.Warn("Failed to rotate transaction journal", "err", err)
 		}
 	}
+	// If remote transaction snapshotting is enabled, revalidate the previously
+	// pooled transactions against the current head and resume their lifetime.
+	if pool.snapshot != nil {
+		add := func(txs []*txpool.Transaction) []error {
+			return pool.Add(txs, false, true)
+		}
+		beats, err := pool.snapshot.load(pool.signer, add)
+		if err != nil {
+			log.Warn("Failed to load transaction pool snapshot", "err", err)
+		}
+		pool.mu.Lock()
+		for addr, beat := range beats {
+			if _, ok := pool.beats[addr]; ok {
+				pool.beats[addr] = beat
+			}
+		}
+		pool.mu.Unlock()
+	}
 	pool.wg.Add(1)
 	go pool.loop()
 	return nil
@@ -365,12 +410,15 @@ func (pool *LegacyPool) loop() {
 				if time.Since(pool.beats[addr]) > pool.config.Lifetime {
 					list := pool.queue[addr].Flatten()
 					for _, tx := range list {
+						pool.drop(tx, txpool.DropExpired, common.Hash{})
 						pool.removeTx(tx.Hash(), true)
 					}
 					queuedEvictionMeter.Mark(int64(len(list)))
 				}
 			}
 			pool.mu.Unlock()
+			pool.announceDrops()
+			pool.admission.expire(time.Now())
 
 		// Handle local transaction journal rotation
 		case <-journal.C:
@@ -381,6 +429,9 @@ func (pool *LegacyPool) loop() {
 				}
 				pool.mu.Unlock()
 			}
+			if pool.snapshot != nil {
+				pool.writeSnapshot()
+			}
 		}
 	}
 }
@@ -397,6 +448,9 @@ func (pool *LegacyPool) Close() error {
 	if pool.journal != nil {
 		pool.journal.close()
 	}
+	if pool.snapshot != nil {
+		pool.writeSnapshot()
+	}
 	log.Info("Transaction pool stopped")
 	return nil
 }
@@ -414,10 +468,27 @@ func (pool *LegacyPool) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.
 	return pool.scope.Track(pool.txFeed.Subscribe(ch))
 }
 
+// SubscribeDropped registers a subscription of DroppedTxsEvent and starts sending
+// event to the given channel.
+func (pool *LegacyPool) SubscribeDropped(ch chan<- txpool.DroppedTxsEvent) event.Subscription {
+	return pool.scope.Track(pool.dropFeed.Subscribe(ch))
+}
+
+// Lifecycle returns the recorded lifecycle history of a transaction, oldest event
+// first, or nil if the transaction is not tracked.
+func (pool *LegacyPool) Lifecycle(hash common.Hash) []txpool.TxEvent {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	return pool.lifecycle.get(hash)
+}
+
 // SetGasTip updates the minimum gas tip required by the transaction pool for a
-// new transaction, and drops all transactions below this threshold.
+// new transaction, and drops all transactions below this threshold. The dropped
+// transactions are announced right away.
 func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 	pool.mu.Lock()
+	defer pool.announceDrops()
 	defer pool.mu.Unlock()
 
 	old := pool.gasTip.Load()
@@ -428,6 +499,7 @@ func (pool *LegacyPool) SetGasTip(tip *big.Int) {
 		// pool.priced is sorted by GasFeeCap, so we have to iterate through pool.all instead
 		drop := pool.all.RemotesBelowTip(tip)
 		for _, tx := range drop {
+			pool.drop(tx, txpool.DropUnderpriced, common.Hash{})
 			pool.removeTx(tx.Hash(), false)
 		}
 		pool.priced.Removed(len(drop))
@@ -556,6 +628,50 @@ func (pool *LegacyPool) local() map[common.Address]types.Transactions {
 	return txs
 }
 
+// remote retrieves all currently known remote transactions, grouped by origin
+// account and sorted by nonce, along with the heartbeats of their accounts. The
+// returned sets are copies and can be freely modified by calling code.
+//
+// Conditional transactions are left out, since their conditions are not persisted
+// and they must not be reloaded as unconditional ones.
+func (pool *LegacyPool) remote() (map[common.Address]types.Transactions, map[common.Address]time.Time) {
+	var (
+		txs   = make(map[common.Address]types.Transactions)
+		beats = make(map[common.Address]time.Time)
+	)
+	collect := func(accounts map[common.Address]*list) {
+		for addr, list := range accounts {
+			if pool.locals.contains(addr) {
+				continue
+			}
+			for _, tx := range list.Flatten() {
+				if tx.Conditional() == nil {
+					txs[addr] = append(txs[addr], tx)
+				}
+			}
+			if beat, ok := pool.beats[addr]; ok {
+				beats[addr] = beat
+			} else {
+				beats[addr] = time.Now()
+			}
+		}
+	}
+	collect(pool.pending)
+	collect(pool.queue)
+	return txs, beats
+}
+
+// writeSnapshot regenerates the snapshot of remote transactions on disk.
+func (pool *LegacyPool) writeSnapshot() {
+	pool.mu.RLock()
+	txs, beats := pool.remote()
+	pool.mu.RUnlock()
+
+	if err := pool.snapshot.write(txs, beats); err != nil {
+		log.Warn("Failed to write transaction pool snapshot", "err", err)
+	}
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -604,9 +720,29 @@ func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 	if err := txpool.ValidateTransactionWithState(tx, pool.signer, opts); err != nil {
 		return err
 	}
+	if cond := tx.Conditional(); cond != nil {
+		if err := checkConditional(cond, pool.currentHead.Load(), pool.currentState, time.Now()); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// checkConditional verifies that the preconditions of a conditional transaction
+// can still be met by a block built on top of the given head and state.
+func checkConditional(cond *types.TransactionConditional, head *types.Header, statedb *state.StateDB, now time.Time) error {
+	if cond.Expired(now) {
+		return types.ErrConditionalExpired
+	}
+	if cond.BlockNumberMax != nil && head.Number.Uint64() >= uint64(*cond.BlockNumberMax) {
+		return fmt.Errorf("%w: head %d, max %d", types.ErrConditionalBlockNumber, head.Number, *cond.BlockNumberMax)
+	}
+	if cond.TimestampMax != nil && head.Time >= uint64(*cond.TimestampMax) {
+		return fmt.Errorf("%w: head %d, max %d", types.ErrConditionalTimestamp, head.Time, *cond.TimestampMax)
+	}
+	return cond.CheckState(statedb)
+}
+
 // add validates a transaction and inserts it into the non-executable queue for later
 // pending promotion and execution. If the transaction is a replacement for an already
 // pending or queued one, it overwrites the previous transaction if its price is higher.
@@ -614,7 +750,10 @@ func (pool *LegacyPool) validateTx(tx *types.Transaction, local bool) error {
 // If a newly added transaction is marked as local, its sending account will be
 // be added to the allowlist, preventing any associated transaction from being dropped
 // out of the pool due to pricing constraints.
-func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, err error) {
+//
+// If the transaction is to be screened, the admission policies are enforced on it:
+// spam throttling of the sender and balance coverage of gapped transactions.
+func (pool *LegacyPool) add(tx *types.Transaction, local bool, screen bool) (replaced bool, err error) {
 	// If the transaction is already known, discard it
 	hash := tx.Hash()
 	if pool.all.Get(hash) != nil {
@@ -636,6 +775,28 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	// already validated by this point
 	from, _ := types.Sender(pool.signer, tx)
 
+	// Enforce the admission policies, penalizing underpriced transactions and
+	// replacements of screened senders
+	if screen {
+		defer func() {
+			if errors.Is(err, txpool.ErrUnderpriced) || errors.Is(err, txpool.ErrReplaceUnderpriced) {
+				pool.admission.penalize(from, time.Now())
+			}
+		}()
+		if pool.admission.throttled(from, time.Now()) {
+			log.Trace("Discarding transaction of throttled sender", "hash", hash, "from", from)
+			throttledSenderMeter.Mark(1)
+			return false, txpool.ErrSenderThrottled
+		}
+		if pool.config.QueueCoverage > 0 && pool.isGapped(from, tx) {
+			if err := pool.coverQueued(from, tx); err != nil {
+				log.Trace("Discarding uncovered future transaction", "hash", hash, "from", from, "err", err)
+				uncoveredTxMeter.Mark(1)
+				pool.admission.uncovered()
+				return false, err
+			}
+		}
+	}
 	// If the transaction pool is full, discard underpriced transactions
 	if uint64(pool.all.Slots()+numSlots(tx)) > pool.config.GlobalSlots+pool.config.GlobalQueue {
 		// If the new transaction is underpriced, don't accept it
@@ -690,6 +851,7 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 		for _, tx := range drop {
 			log.Trace("Discarding freshly underpriced transaction", "hash", tx.Hash(), "gasTipCap", tx.GasTipCap(), "gasFeeCap", tx.GasFeeCap())
 			underpricedTxMeter.Mark(1)
+			pool.drop(tx, txpool.DropUnderpriced, common.Hash{})
 			dropped := pool.removeTx(tx.Hash(), false)
 			pool.changesSinceReorg += dropped
 		}
@@ -708,9 +870,11 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 			pool.all.Remove(old.Hash())
 			pool.priced.Removed(1)
 			pendingReplaceMeter.Mark(1)
+			pool.drop(old, txpool.DropReplaced, hash)
 		}
 		pool.all.Add(tx, isLocal)
 		pool.priced.Put(tx, isLocal)
+		pool.track(hash, txpool.TxStagePending)
 		pool.journalTx(from, tx)
 		pool.queueTxEvent(tx)
 		log.Trace("Pooled new executable transaction", "hash", hash, "from", from, "to", tx.To())
@@ -739,6 +903,28 @@ func (pool *LegacyPool) add(tx *types.Transaction, local bool) (replaced bool, e
 	return replaced, nil
 }
 
+// coverQueued checks whether the balance of a sender covers the configured share
+// of the cost of all its pooled transactions, the given one included.
+func (pool *LegacyPool) coverQueued(from common.Address, tx *types.Transaction) error {
+	cost := new(big.Int).Set(tx.Cost())
+	if list := pool.pending[from]; list != nil {
+		cost.Add(cost, list.totalcost)
+	}
+	if list := pool.queue[from]; list != nil {
+		cost.Add(cost, list.totalcost)
+		if old := list.txs.Get(tx.Nonce()); old != nil {
+			cost.Sub(cost, old.Cost())
+		}
+	}
+	need := cost.Mul(cost, new(big.Int).SetUint64(pool.config.QueueCoverage))
+	need.Div(need, big.NewInt(100))
+
+	if balance := pool.currentState.GetBalance(from); balance.Cmp(need) < 0 {
+		return fmt.Errorf("%w: balance %v, queued coverage %v", core.ErrInsufficientFunds, balance, need)
+	}
+	return nil
+}
+
 // isGapped reports whether the given transaction is immediately executable.
 func (pool *LegacyPool) isGapped(from common.Address, tx *types.Transaction) bool {
 	// Short circuit if transaction falls within the scope of the pending list
@@ -783,6 +969,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		queuedReplaceMeter.Mark(1)
+		pool.drop(old, txpool.DropReplaced, hash)
 	} else {
 		// Nothing was replaced, bump the queued counter
 		queuedGauge.Inc(1)
@@ -796,6 +983,7 @@ func (pool *LegacyPool) enqueueTx(hash common.Hash, tx *types.Transaction, local
 		pool.all.Add(tx, local)
 		pool.priced.Put(tx, local)
 	}
+	pool.track(hash, txpool.TxStageQueued)
 	// If we never record the heartbeat, do it right now.
 	if _, exist := pool.beats[from]; !exist {
 		pool.beats[from] = time.Now()
@@ -810,6 +998,10 @@ func (pool *LegacyPool) journalTx(from common.Address, tx *types.Transaction) {
 	if pool.journal == nil || !pool.locals.contains(from) {
 		return
 	}
+	// Conditions are not persisted, skip conditional transactions
+	if tx.Conditional() != nil {
+		return
+	}
 	if err := pool.journal.insert(tx); err != nil {
 		log.Warn("Failed to journal local transaction", "err", err)
 	}
@@ -832,6 +1024,7 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(hash)
 		pool.priced.Removed(1)
 		pendingDiscardMeter.Mark(1)
+		pool.drop(tx, txpool.DropUnderpriced, common.Hash{})
 		return false
 	}
 	// Otherwise discard any previous transaction and mark this
@@ -839,10 +1032,13 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 		pool.all.Remove(old.Hash())
 		pool.priced.Removed(1)
 		pendingReplaceMeter.Mark(1)
+		pool.drop(old, txpool.DropReplaced, hash)
 	} else {
 		// Nothing was replaced, bump the pending counter
 		pendingGauge.Inc(1)
 	}
+	pool.track(hash, txpool.TxStagePending)
+
 	// Set the potentially new pending nonce and notify any subsystems of the new tx
 	pool.pendingNonces.set(addr, tx.Nonce()+1)
 
@@ -857,11 +1053,14 @@ func (pool *LegacyPool) promoteTx(addr common.Address, hash common.Hash, tx *typ
 // If sync is set, the method will block until all internal maintenance related
 // to the add is finished. Only use this during tests for determinism!
 func (pool *LegacyPool) Add(txs []*txpool.Transaction, local bool, sync bool) []error {
-	unwrapped := make([]*types.Transaction, len(txs))
+	var (
+		unwrapped = make([]*types.Transaction, len(txs))
+		origins   = make([]string, len(txs))
+	)
 	for i, tx := range txs {
-		unwrapped[i] = tx.Tx
+		unwrapped[i], origins[i] = tx.Tx, originIP(tx.Origin)
 	}
-	return pool.addTxs(unwrapped, local, sync)
+	return pool.addTxs(unwrapped, origins, local, sync)
 }
 
 // addLocals enqueues a batch of transactions into the pool if they are valid, marking the
@@ -870,7 +1069,7 @@ func (pool *LegacyPool) Add(txs []*txpool.Transaction, local bool, sync bool) []
 // This method is used to add transactions from the RPC API and performs synchronous pool
 // reorganization and event propagation.
 func (pool *LegacyPool) addLocals(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, !pool.config.NoLocals, true)
+	return pool.addTxs(txs, nil, !pool.config.NoLocals, true)
 }
 
 // addLocal enqueues a single local transaction into the pool if it is valid. This is
@@ -886,7 +1085,7 @@ func (pool *LegacyPool) addLocal(tx *types.Transaction) error {
 // This method is used to add transactions from the p2p network and does not wait for pool
 // reorganization and internal event propagation.
 func (pool *LegacyPool) addRemotes(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, false)
+	return pool.addTxs(txs, nil, false, false)
 }
 
 // addRemote enqueues a single transaction into the pool if it is valid. This is a convenience
@@ -898,20 +1097,23 @@ func (pool *LegacyPool) addRemote(tx *types.Transaction) error {
 
 // addRemotesSync is like addRemotes, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemotesSync(txs []*types.Transaction) []error {
-	return pool.addTxs(txs, false, true)
+	return pool.addTxs(txs, nil, false, true)
 }
 
 // This is like addRemotes with a single transaction, but waits for pool reorganization. Tests use this method.
 func (pool *LegacyPool) addRemoteSync(tx *types.Transaction) error {
-	return pool.addTxs([]*types.Transaction{tx}, false, true)[0]
+	return pool.addTxs([]*types.Transaction{tx}, nil, false, true)[0]
 }
 
-// addTxs attempts to queue a batch of transactions if they are valid.
-func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync bool) []error {
+// addTxs attempts to queue a batch of transactions if they are valid. The origins,
+// if given, are the IP addresses of the RPC clients submitting the transactions.
+func (pool *LegacyPool) addTxs(txs []*types.Transaction, origins []string, local, sync bool) []error {
 	// Filter out known ones without obtaining the pool lock or recovering signatures
 	var (
-		errs = make([]error, len(txs))
-		news = make([]*types.Transaction, 0, len(txs))
+		errs    = make([]error, len(txs))
+		news    = make([]*types.Transaction, 0, len(txs))
+		screens = make([]bool, 0, len(txs))
+		now     = time.Now()
 	)
 	for i, tx := range txs {
 		// If the transaction is known, pre-set the error slot
@@ -920,16 +1122,34 @@ func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync bool) []err
 			knownTxMeter.Mark(1)
 			continue
 		}
+		// Refuse transactions from origins flooding the pool over RPC
+		var origin string
+		if origins != nil {
+			origin = origins[i]
+		}
+		if !pool.admission.allow(origin, now) {
+			errs[i] = txpool.ErrOriginRateLimited
+			originLimitMeter.Mark(1)
+			continue
+		}
 		// Exclude transactions with basic errors, e.g invalid signatures and
 		// insufficient intrinsic gas as soon as possible and cache senders
 		// in transactions before obtaining lock
 		if err := pool.validateTxBasics(tx, local); err != nil {
 			errs[i] = err
 			invalidTxMeter.Mark(1)
+
+			// Underpriced transactions count as spam, the sender is already cached
+			if (!local || origin != "") && errors.Is(err, txpool.ErrUnderpriced) {
+				if from, err := types.Sender(pool.signer, tx); err == nil {
+					pool.admission.penalize(from, now)
+				}
+			}
 			continue
 		}
 		// Accumulate all unknown transactions for deeper processing
 		news = append(news, tx)
+		screens = append(screens, !local || origin != "")
 	}
 	if len(news) == 0 {
 		return errs
@@ -937,7 +1157,7 @@ func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync bool) []err
 
 	// Process all the new transaction and merge any errors into the original slice
 	pool.mu.Lock()
-	newErrs, dirtyAddrs := pool.addTxsLocked(news, local)
+	newErrs, dirtyAddrs := pool.addTxsLocked(news, screens, local)
 	pool.mu.Unlock()
 
 	var nilSlot = 0
@@ -956,13 +1176,14 @@ func (pool *LegacyPool) addTxs(txs []*types.Transaction, local, sync bool) []err
 	return errs
 }
 
-// addTxsLocked attempts to queue a batch of transactions if they are valid.
+// addTxsLocked attempts to queue a batch of transactions if they are valid. The
+// admission policies are enforced on the transactions flagged in screens, if any.
 // The transaction pool lock must be held.
-func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]error, *accountSet) {
+func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, screens []bool, local bool) ([]error, *accountSet) {
 	dirty := newAccountSet(pool.signer)
 	errs := make([]error, len(txs))
 	for i, tx := range txs {
-		replaced, err := pool.add(tx, local)
+		replaced, err := pool.add(tx, local, screens != nil && screens[i])
 		errs[i] = err
 		if err == nil && !replaced {
 			dirty.addTx(tx)
@@ -972,6 +1193,21 @@ func (pool *LegacyPool) addTxsLocked(txs []*types.Transaction, local bool) ([]er
 	return errs, dirty
 }
 
+// AllowOrigin records a submission from the given RPC client address made to
+// another subpool, reporting whether it fits into the origin's submission rate.
+func (pool *LegacyPool) AllowOrigin(origin string) bool {
+	if !pool.admission.allow(originIP(origin), time.Now()) {
+		originLimitMeter.Mark(1)
+		return false
+	}
+	return true
+}
+
+// Admission returns the current admission policy statistics of the pool.
+func (pool *LegacyPool) Admission() txpool.AdmissionStats {
+	return pool.admission.status(time.Now())
+}
+
 // Status returns the status (unknown/pending/queued) of a batch of transactions
 // identified by their hashes.
 func (pool *LegacyPool) Status(hash common.Hash) txpool.TxStatus {
@@ -1064,6 +1300,46 @@ func (pool *LegacyPool) removeTx(hash common.Hash, outofbound bool) int {
 	return 0
 }
 
+// track records a lifecycle stage of a transaction.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) track(hash common.Hash, stage txpool.TxStage) {
+	pool.lifecycle.record(hash, txpool.TxEvent{Stage: stage})
+}
+
+// drop records a transaction being removed from the pool without being included,
+// and queues it up for announcement.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) drop(tx *types.Transaction, reason txpool.DropReason, replacement common.Hash) {
+	pool.lifecycle.record(tx.Hash(), txpool.TxEvent{Stage: txpool.TxStageDropped, Reason: reason, Replacement: replacement})
+	pool.drops = append(pool.drops, txpool.DroppedTx{Tx: tx, Reason: reason, Replacement: replacement})
+}
+
+// forward records a transaction being removed from the pool due to its nonce
+// becoming stale, either by its own inclusion or by a conflicting transaction's.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) forward(tx *types.Transaction) {
+	if _, ok := pool.included[tx.Hash()]; ok {
+		pool.track(tx.Hash(), txpool.TxStageIncluded)
+		return
+	}
+	pool.drop(tx, txpool.DropNonceTooLow, common.Hash{})
+}
+
+// announceDrops sends out the transactions dropped since the last announcement.
+func (pool *LegacyPool) announceDrops() {
+	pool.mu.Lock()
+	drops := pool.drops
+	pool.drops = nil
+	pool.mu.Unlock()
+
+	if len(drops) > 0 {
+		pool.dropFeed.Send(txpool.DroppedTxsEvent{Txs: drops})
+	}
+}
+
 // requestReset requests a pool reset to the new head block.
 // The returned channel is closed when the reset has occurred.
 func (pool *LegacyPool) requestReset(oldHead *types.Header, newHead *types.Header) chan struct{} {
@@ -1205,6 +1481,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 	// remove any transaction that has been included in the block or was invalidated
 	// because of another transaction (e.g. higher gas price).
 	if reset != nil {
+		pool.dropStaleConditionals()
 		pool.demoteUnexecutables()
 		if reset.newHead != nil {
 			if pool.chainconfig.IsLondon(new(big.Int).Add(reset.newHead.Number, big.NewInt(1))) {
@@ -1228,8 +1505,12 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 
 	dropBetweenReorgHistogram.Update(int64(pool.changesSinceReorg))
 	pool.changesSinceReorg = 0 // Reset change counter
+	pool.included = nil
 	pool.mu.Unlock()
 
+	// Notify subsystems for transactions dropped along the way
+	pool.announceDrops()
+
 	// Notify subsystems for newly added transactions
 	for _, tx := range promoted {
 		addr, _ := types.Sender(pool.signer, tx)
@@ -1251,7 +1532,7 @@ func (pool *LegacyPool) runReorg(done chan struct{}, reset *txpoolResetRequest,
 // of the transaction pool is valid with regard to the chain state.
 func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	// If we're reorging an old state, reinject all dropped transactions
-	var reinject types.Transactions
+	var reinject, included types.Transactions
 
 	if oldHead != nil && oldHead.Hash() != newHead.ParentHash {
 		// If the reorg is too deep, avoid doing it (will happen during fast sync)
@@ -1290,7 +1571,7 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 					log.Warn("New head missing in txpool reset", "number", newHead.Number, "hash", newHead.Hash())
 					return
 				}
-				var discarded, included types.Transactions
+				var discarded types.Transactions
 				for rem.NumberU64() > add.NumberU64() {
 					discarded = append(discarded, rem.Transactions()...)
 					if rem = pool.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
@@ -1320,6 +1601,12 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 				reinject = types.TxDifference(discarded, included)
 			}
 		}
+	} else if oldHead != nil {
+		// Gather the transactions of the new head to tell the included ones apart
+		// from the ones invalidated by conflicting transactions
+		if block := pool.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64()); block != nil {
+			included = block.Transactions()
+		}
 	}
 	// Initialize the internal state to the current head
 	if newHead == nil {
@@ -1334,10 +1621,14 @@ func (pool *LegacyPool) reset(oldHead, newHead *types.Header) {
 	pool.currentState = statedb
 	pool.pendingNonces = newNoncer(statedb)
 
+	pool.included = make(map[common.Hash]struct{}, len(included))
+	for _, tx := range included {
+		pool.included[tx.Hash()] = struct{}{}
+	}
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	core.SenderCacher.Recover(pool.signer, reinject)
-	pool.addTxsLocked(reinject, false)
+	pool.addTxsLocked(reinject, nil, false)
 }
 
 // promoteExecutables moves transactions that have become processable from the
@@ -1359,6 +1650,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range forwards {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.forward(tx)
 		}
 		log.Trace("Removed old queued transactions", "count", len(forwards))
 		// Drop all transactions that are too costly (low balance or out of gas)
@@ -1366,6 +1658,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 		for _, tx := range drops {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.drop(tx, txpool.DropUnexecutable, common.Hash{})
 		}
 		log.Trace("Removed unpayable queued transactions", "count", len(drops))
 		queuedNofundsMeter.Mark(int64(len(drops)))
@@ -1388,6 +1681,7 @@ func (pool *LegacyPool) promoteExecutables(accounts []common.Address) []*types.T
 			for _, tx := range caps {
 				hash := tx.Hash()
 				pool.all.Remove(hash)
+				pool.drop(tx, txpool.DropEvicted, common.Hash{})
 				log.Trace("Removed cap-exceeding queued transaction", "hash", hash)
 			}
 			queuedRateLimitMeter.Mark(int64(len(caps)))
@@ -1450,6 +1744,7 @@ func (pool *LegacyPool) truncatePending() {
 						// Drop the transaction from the global pools too
 						hash := tx.Hash()
 						pool.all.Remove(hash)
+						pool.drop(tx, txpool.DropEvicted, common.Hash{})
 
 						// Update the account nonce to the dropped transaction
 						pool.pendingNonces.setIfLower(offenders[i], tx.Nonce())
@@ -1477,6 +1772,7 @@ func (pool *LegacyPool) truncatePending() {
 					// Drop the transaction from the global pools too
 					hash := tx.Hash()
 					pool.all.Remove(hash)
+					pool.drop(tx, txpool.DropEvicted, common.Hash{})
 
 					// Update the account nonce to the dropped transaction
 					pool.pendingNonces.setIfLower(addr, tx.Nonce())
@@ -1523,6 +1819,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Drop all transactions if they are less than the overflow
 		if size := uint64(list.Len()); size <= drop {
 			for _, tx := range list.Flatten() {
+				pool.drop(tx, txpool.DropEvicted, common.Hash{})
 				pool.removeTx(tx.Hash(), true)
 			}
 			drop -= size
@@ -1532,6 +1829,7 @@ func (pool *LegacyPool) truncateQueue() {
 		// Otherwise drop only last few transactions
 		txs := list.Flatten()
 		for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+			pool.drop(txs[i], txpool.DropEvicted, common.Hash{})
 			pool.removeTx(txs[i].Hash(), true)
 			drop--
 			queuedRateLimitMeter.Mark(1)
@@ -1539,6 +1837,33 @@ func (pool *LegacyPool) truncateQueue() {
 	}
 }
 
+// dropStaleConditionals removes all the conditional transactions from the pool
+// whose preconditions can no longer be met on top of the current head.
+//
+// Note, this method assumes the pool lock is held!
+func (pool *LegacyPool) dropStaleConditionals() {
+	var (
+		head  = pool.currentHead.Load()
+		now   = time.Now()
+		stale []*types.Transaction
+	)
+	pool.all.Range(func(hash common.Hash, tx *types.Transaction, local bool) bool {
+		if cond := tx.Conditional(); cond != nil {
+			if err := checkConditional(cond, head, pool.currentState, now); err != nil {
+				log.Trace("Dropping stale conditional transaction", "hash", hash, "err", err)
+				stale = append(stale, tx)
+			}
+		}
+		return true
+	}, true, true)
+
+	for _, tx := range stale {
+		pool.drop(tx, txpool.DropConditional, common.Hash{})
+		pool.removeTx(tx.Hash(), true)
+	}
+	conditionalDropMeter.Mark(int64(len(stale)))
+}
+
 // demoteUnexecutables removes invalid and processed transactions from the pools
 // executable/pending queue and any subsequent transactions that become unexecutable
 // are moved back into the future queue.
@@ -1557,6 +1882,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 		for _, tx := range olds {
 			hash := tx.Hash()
 			pool.all.Remove(hash)
+			pool.forward(tx)
 			log.Trace("Removed old pending transaction", "hash", hash)
 		}
 		// Drop all transactions that are too costly (low balance or out of gas), and queue any invalids back for later
@@ -1565,6 +1891,7 @@ func (pool *LegacyPool) demoteUnexecutables() {
 			hash := tx.Hash()
 			log.Trace("Removed unpayable pending transaction", "hash", hash)
 			pool.all.Remove(hash)
+			pool.drop(tx, txpool.DropUnexecutable, common.Hash{})
 		}
 		pendingNofundsMeter.Mark(int64(len(drops)))
 
@@ -24,11 +24,13 @@ import (
 	"math/big"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -452,14 +454,14 @@ func TestChainFork(t *testing.T) {
 	resetState()
 
 	tx := transaction(0, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	pool.removeTx(tx.Hash(), true)
 
 	// reset the pool's internal state
 	resetState()
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 }
@@ -486,10 +488,10 @@ func TestDoubleNonce(t *testing.T) {
 	tx3, _ := types.SignTx(types.NewTransaction(0, common.Address{}, big.NewInt(100), 1000000, big.NewInt(1), nil), signer, key)
 
 	// Add the first two transaction, ensure higher priced stays only
-	if replace, err := pool.add(tx1, false); err != nil || replace {
+	if replace, err := pool.add(tx1, false, false); err != nil || replace {
 		t.Errorf("first transaction insert failed (%v) or reported replacement (%v)", err, replace)
 	}
-	if replace, err := pool.add(tx2, false); err != nil || !replace {
+	if replace, err := pool.add(tx2, false, false); err != nil || !replace {
 		t.Errorf("second transaction insert failed (%v) or not reported replacement (%v)", err, replace)
 	}
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
@@ -501,7 +503,7 @@ func TestDoubleNonce(t *testing.T) {
 	}
 
 	// Add the third transaction and ensure it's not saved (smaller price)
-	pool.add(tx3, false)
+	pool.add(tx3, false, false)
 	<-pool.requestPromoteExecutables(newAccountSet(signer, addr))
 	if pool.pending[addr].Len() != 1 {
 		t.Error("expected 1 pending transactions, got", pool.pending[addr].Len())
@@ -524,7 +526,7 @@ func TestMissingNonce(t *testing.T) {
 	addr := crypto.PubkeyToAddress(key.PublicKey)
 	testAddBalance(pool, addr, big.NewInt(100000000000000))
 	tx := transaction(1, 100000, key)
-	if _, err := pool.add(tx, false); err != nil {
+	if _, err := pool.add(tx, false, false); err != nil {
 		t.Error("didn't expect error", err)
 	}
 	if len(pool.pending) != 0 {
@@ -2389,6 +2391,206 @@ func testJournaling(t *testing.T, nolocals bool) {
 	pool.Close()
 }
 
+// Tests that the remote transactions of the pool are snapshotted on shutdown and
+// reloaded on startup, revalidated against the new head and with their age kept.
+func TestSnapshotting(t *testing.T) {
+	t.Parallel()
+
+	snapshot := filepath.Join(t.TempDir(), "transactions.snapshot.rlp")
+
+	// Create the original pool to inject transactions into the snapshot
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	// Add two pending and a queued remote transaction, with an old arrival time
+	var (
+		seen = time.Now().Add(-time.Hour).Round(0)
+		txs  = []*types.Transaction{
+			pricedTransaction(0, 100000, big.NewInt(1), key),
+			pricedTransaction(1, 100000, big.NewInt(1), key),
+			pricedTransaction(3, 100000, big.NewInt(1), key),
+		}
+	)
+	for _, tx := range txs {
+		tx.SetTime(seen)
+	}
+	for i, err := range pool.addRemotesSync(txs) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool content mismatch: have %d/%d pending/queued, want 2/1", pending, queued)
+	}
+	pool.mu.Lock()
+	beat := time.Now().Add(-time.Minute).Round(0)
+	pool.beats[addr] = beat
+	pool.mu.Unlock()
+
+	// Terminate the old pool, include the first transaction, restart and ensure
+	// the remaining transactions survive
+	pool.Close()
+	statedb.SetNonce(addr, 1)
+	blockchain = newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	if pending, queued := pool.Stats(); pending != 1 || queued != 1 {
+		t.Fatalf("reloaded pool content mismatch: have %d/%d pending/queued, want 1/1", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+	for _, tx := range txs[1:] {
+		pooled := pool.Get(tx.Hash())
+		if pooled == nil {
+			t.Fatalf("transaction %x missing after reload", tx.Hash())
+		}
+		if !pooled.Tx.Time().Equal(seen) {
+			t.Errorf("transaction %x age mismatch: have %v, want %v", tx.Hash(), pooled.Tx.Time(), seen)
+		}
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	if !pool.beats[addr].Equal(beat) {
+		t.Errorf("account heartbeat mismatch: have %v, want %v", pool.beats[addr], beat)
+	}
+}
+
+// Tests that conditional transactions are not snapshotted, as their conditions
+// would be lost on reload.
+func TestSnapshottingConditional(t *testing.T) {
+	t.Parallel()
+
+	snapshot := filepath.Join(t.TempDir(), "transactions.snapshot.rlp")
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.Snapshot = snapshot
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	tx0 := pricedTransaction(0, 100000, big.NewInt(1), key)
+	tx0.SetConditional(&types.TransactionConditional{})
+	tx1 := pricedTransaction(1, 100000, big.NewInt(1), key)
+	for i, err := range pool.addRemotesSync([]*types.Transaction{tx0, tx1}) {
+		if err != nil {
+			t.Fatalf("failed to add remote transaction %d: %v", i, err)
+		}
+	}
+	pool.Close()
+
+	pool = New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	if pool.Get(tx0.Hash()) != nil {
+		t.Error("conditional transaction reloaded from snapshot")
+	}
+	if pool.Get(tx1.Hash()) == nil {
+		t.Error("unconditional transaction missing after reload")
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Fatalf("reloaded pool content mismatch: have %d/%d pending/queued, want 0/1", pending, queued)
+	}
+}
+
+// Tests that conditional transactions are only accepted if their preconditions
+// hold, and that they are dropped as soon as the preconditions break.
+func TestConditionalTransactions(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	pool := New(testTxPoolConfig, blockchain)
+	if err := pool.Init(new(big.Int).SetUint64(testTxPoolConfig.PriceLimit), blockchain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	defer pool.Close()
+
+	var (
+		key, _ = crypto.GenerateKey()
+		addr   = crypto.PubkeyToAddress(key.PublicKey)
+		oracle = common.Address{0xaa}
+		slot   = common.Hash{0x01}
+		past   = hexutil.Uint64(time.Now().Add(-time.Hour).Unix())
+		zero   = hexutil.Uint64(0)
+	)
+	testAddBalance(pool, addr, big.NewInt(1000000))
+
+	pool.mu.Lock()
+	pool.currentState.SetState(oracle, slot, common.Hash{0x01})
+	pool.mu.Unlock()
+
+	conditional := func(nonce uint64, cond *types.TransactionConditional) *types.Transaction {
+		tx := transaction(nonce, 100000, key)
+		tx.SetConditional(cond)
+		return tx
+	}
+	// Ensure that transactions with unsatisfiable preconditions are rejected
+	rejects := []struct {
+		cond *types.TransactionConditional
+		want error
+	}{
+		{&types.TransactionConditional{Expiry: &past}, types.ErrConditionalExpired},
+		{&types.TransactionConditional{BlockNumberMax: &zero}, types.ErrConditionalBlockNumber},
+		{&types.TransactionConditional{KnownAccounts: map[common.Address]types.KnownAccount{
+			oracle: {StorageSlots: map[common.Hash]common.Hash{slot: {0x02}}},
+		}}, types.ErrConditionalStorage},
+	}
+	for i, tt := range rejects {
+		if err := pool.addRemoteSync(conditional(0, tt.cond)); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// Add a conditional transaction followed by an unconditional one
+	tx0 := conditional(0, &types.TransactionConditional{KnownAccounts: map[common.Address]types.KnownAccount{
+		oracle: {StorageSlots: map[common.Hash]common.Hash{slot: {0x01}}},
+	}})
+	tx1 := transaction(1, 100000, key)
+	for _, tx := range []*types.Transaction{tx0, tx1} {
+		if err := pool.addRemoteSync(tx); err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 2/0", pending, queued)
+	}
+	// Break the precondition and ensure the transaction is dropped on reset
+	statedb.SetState(oracle, slot, common.Hash{0x02})
+	<-pool.requestReset(nil, nil)
+
+	if pool.Get(tx0.Hash()) != nil {
+		t.Errorf("stale conditional transaction not dropped")
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Errorf("pool stats mismatch: have %d/%d, want 0/1", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
 // TestStatusCheck tests that the pool can correctly retrieve the
 // pending status of individual transactions.
 func TestStatusCheck(t *testing.T) {
@@ -2602,3 +2804,286 @@ func BenchmarkMultiAccountBatchInsert(b *testing.B) {
 		pool.addRemotesSync([]*types.Transaction{tx})
 	}
 }
+
+// Tests that the lifecycle of transactions is recorded, and that the dropped
+// transactions are announced along with the reason.
+func TestTransactionLifecycle(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	drops := make(chan txpool.DroppedTxsEvent, 4)
+	sub := pool.SubscribeDropped(drops)
+	defer sub.Unsubscribe()
+
+	checkStages := func(tx *types.Transaction, want ...txpool.TxStage) []txpool.TxEvent {
+		t.Helper()
+
+		events := pool.Lifecycle(tx.Hash())
+		if len(events) != len(want) {
+			t.Fatalf("lifecycle length mismatch: have %d, want %d", len(events), len(want))
+		}
+		for i, event := range events {
+			if event.Stage != want[i] {
+				t.Errorf("event %d: stage mismatch: have %v, want %v", i, event.Stage, want[i])
+			}
+		}
+		return events
+	}
+	checkDrop := func(tx *types.Transaction, reason txpool.DropReason, replacement common.Hash) {
+		t.Helper()
+
+		select {
+		case ev := <-drops:
+			if len(ev.Txs) != 1 {
+				t.Fatalf("dropped transaction count mismatch: have %d, want 1", len(ev.Txs))
+			}
+			if drop := ev.Txs[0]; drop.Tx.Hash() != tx.Hash() || drop.Reason != reason || drop.Replacement != replacement {
+				t.Errorf("dropped transaction mismatch: have %x/%v/%x, want %x/%v/%x", drop.Tx.Hash(), drop.Reason, drop.Replacement, tx.Hash(), reason, replacement)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("dropped transaction not announced")
+		}
+	}
+	// Add an executable and a gapped transaction
+	tx0, tx2 := transaction(0, 100000, key), transaction(2, 100000, key)
+	for _, err := range pool.addRemotesSync([]*types.Transaction{tx0, tx2}) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	checkStages(tx0, txpool.TxStageQueued, txpool.TxStagePending)
+	checkStages(tx2, txpool.TxStageQueued)
+
+	// Replace the executable transaction and ensure the drop is recorded
+	replacement := pricedTransaction(0, 100000, big.NewInt(2), key)
+	if err := pool.addRemoteSync(replacement); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	checkDrop(tx0, txpool.DropReplaced, replacement.Hash())
+	if events := checkStages(tx0, txpool.TxStageQueued, txpool.TxStagePending, txpool.TxStageDropped); events[2].Replacement != replacement.Hash() {
+		t.Errorf("replacement mismatch: have %x, want %x", events[2].Replacement, replacement.Hash())
+	}
+	// Consume the nonce outside of the pool and ensure the stale transaction is dropped
+	testSetNonce(pool, addr, 1)
+	<-pool.requestReset(nil, nil)
+
+	checkDrop(replacement, txpool.DropNonceTooLow, common.Hash{})
+	if events := checkStages(replacement, txpool.TxStagePending, txpool.TxStageDropped); events[1].Reason != txpool.DropNonceTooLow {
+		t.Errorf("drop reason mismatch: have %v, want %v", events[1].Reason, txpool.DropNonceTooLow)
+	}
+	if events := pool.Lifecycle(common.Hash{}); events != nil {
+		t.Errorf("unknown transaction has lifecycle: %v", events)
+	}
+	// Raise the gas tip and ensure the underpriced transaction is announced
+	pool.SetGasTip(big.NewInt(2))
+	checkDrop(tx2, txpool.DropUnderpriced, common.Hash{})
+}
+
+// Tests that transactions submitted over RPC are rate limited per origin subnet,
+// while ones without an origin are unaffected.
+func TestOriginRateLimiting(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.OriginRate = 2
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	submit := func(nonce uint64, origin string) error {
+		return pool.Add([]*txpool.Transaction{{Tx: transaction(nonce, 100000, key), Origin: origin}}, true, true)[0]
+	}
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := submit(nonce, "10.0.0.1:30000"); err != nil {
+			t.Fatalf("transaction %d within rate refused: %v", nonce, err)
+		}
+	}
+	if err := submit(2, "10.0.0.2:30001"); !errors.Is(err, txpool.ErrOriginRateLimited) {
+		t.Fatalf("transaction over rate error mismatch: have %v, want %v", err, txpool.ErrOriginRateLimited)
+	}
+	if err := submit(2, "10.0.1.1:30000"); err != nil {
+		t.Fatalf("transaction from other origin refused: %v", err)
+	}
+	if err := submit(3, ""); err != nil {
+		t.Fatalf("transaction without origin refused: %v", err)
+	}
+	stats := pool.Admission()
+	if stats.RateLimited != 1 || stats.LimitedOrigins != 1 {
+		t.Fatalf("admission stats mismatch: have %d/%d refused/origins, want 1/1", stats.RateLimited, stats.LimitedOrigins)
+	}
+	// Once the rate window passes, the origin may submit again
+	pool.admission.expire(time.Now().Add(originRateWindow))
+	if err := submit(4, "10.0.0.1:30000"); err != nil {
+		t.Fatalf("transaction after rate window refused: %v", err)
+	}
+}
+
+// Tests that RPC origins are grouped by their /24 IPv4 or /64 IPv6 subnet.
+func TestOriginSubnet(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		addr, origin string
+	}{
+		{"", ""},
+		{"/tmp/geth.ipc", ""},
+		{"10.0.0.1:30000", "10.0.0.0"},
+		{"10.0.0.255", "10.0.0.0"},
+		{"[::ffff:10.0.0.1]:30000", "10.0.0.0"},
+		{"[2001:db8::1]:30000", "2001:db8::"},
+		{"[2001:db8::ffff:1]:30000", "2001:db8::"},
+		{"[2001:db8:0:1::1]:30000", "2001:db8:0:1::"},
+	}
+	for _, tt := range tests {
+		if origin := originIP(tt.addr); origin != tt.origin {
+			t.Errorf("origin of %q mismatch: have %q, want %q", tt.addr, origin, tt.origin)
+		}
+	}
+}
+
+// Tests that gapped transactions are only queued if the sender's balance covers
+// the configured share of all its pooled transactions.
+func TestQueueCoverage(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.QueueCoverage = 100
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(250000))
+
+	// Each transaction costs 100000, the balance covers two of them
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add executable transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(2, 100000, key)); err != nil {
+		t.Fatalf("failed to add covered gapped transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(transaction(3, 100000, key)); !errors.Is(err, core.ErrInsufficientFunds) {
+		t.Fatalf("uncovered gapped transaction error mismatch: have %v, want %v", err, core.ErrInsufficientFunds)
+	}
+	// Local transactions submitted without an origin are exempt
+	if err := pool.addLocal(transaction(3, 100000, key)); err != nil {
+		t.Fatalf("failed to add local gapped transaction: %v", err)
+	}
+	// Executable transactions are not subject to the coverage
+	if err := pool.addRemoteSync(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add executable transaction: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 4 || queued != 0 {
+		t.Fatalf("pool content mismatch: have %d/%d pending/queued, want 4/0", pending, queued)
+	}
+	if stats := pool.Admission(); stats.Uncovered != 1 {
+		t.Fatalf("uncovered counter mismatch: have %d, want 1", stats.Uncovered)
+	}
+}
+
+// Tests that senders spamming underpriced transactions or replacements get
+// throttled, and recover once their penalty decays. Successful replacements
+// are not penalized.
+func TestSpamThrottling(t *testing.T) {
+	t.Parallel()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := newTestBlockChain(params.TestChainConfig, 1000000, statedb, new(event.Feed))
+
+	config := testTxPoolConfig
+	config.SpamPenalty = 2
+
+	pool := New(config, blockchain)
+	pool.Init(new(big.Int).SetUint64(config.PriceLimit), blockchain.CurrentBlock())
+	defer pool.Close()
+
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, addr, big.NewInt(1000000000))
+
+	// Replace a transaction, then fail to replace it twice and send an underpriced
+	// one, accumulating a penalty of 3
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if stats := pool.Admission(); stats.PenalizedSenders != 0 {
+		t.Fatalf("sender penalized for a replacement: %d penalized senders", stats.PenalizedSenders)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(0, 100000, big.NewInt(2), key)); !errors.Is(err, ErrAlreadyKnown) {
+		t.Fatalf("known transaction error mismatch: have %v, want %v", err, ErrAlreadyKnown)
+	}
+	for gas := uint64(100001); gas <= 100002; gas++ {
+		if err := pool.addRemoteSync(pricedTransaction(0, gas, big.NewInt(2), key)); !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+			t.Fatalf("underpriced replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+		}
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(0), key)); !errors.Is(err, txpool.ErrUnderpriced) {
+		t.Fatalf("underpriced transaction error mismatch: have %v, want %v", err, txpool.ErrUnderpriced)
+	}
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), key)); !errors.Is(err, txpool.ErrSenderThrottled) {
+		t.Fatalf("throttled sender error mismatch: have %v, want %v", err, txpool.ErrSenderThrottled)
+	}
+	if stats := pool.Admission(); stats.Throttled != 1 || stats.PenalizedSenders != 1 || stats.ThrottledSenders != 1 {
+		t.Fatalf("admission stats mismatch: have %d/%d/%d refused/penalized/throttled, want 1/1/1", stats.Throttled, stats.PenalizedSenders, stats.ThrottledSenders)
+	}
+	// Age the penalty by a half-life to 1.5, the sender should be admitted again
+	pool.admission.lock.Lock()
+	score := pool.admission.senders[addr]
+	score.updated = score.updated.Add(-spamHalfLife)
+	pool.admission.lock.Unlock()
+
+	if err := pool.addRemoteSync(pricedTransaction(1, 100000, big.NewInt(1), key)); err != nil {
+		t.Fatalf("failed to add transaction after penalty decay: %v", err)
+	}
+}
+
+// Tests that the number of tracked spam senders is bounded, forgetting the least
+// penalized ones, so cycling through new senders can't evict a heavy spammer.
+func TestSpamSendersLimit(t *testing.T) {
+	t.Parallel()
+
+	var (
+		a       = newAdmission(&Config{SpamPenalty: 2})
+		now     = time.Now()
+		spammer = common.Address{0xff}
+	)
+	a.penalize(spammer, now)
+	a.penalize(spammer, now)
+	for i := 0; i < 2*maxSpamSenders; i++ {
+		a.penalize(common.BigToAddress(big.NewInt(int64(i))), now)
+	}
+	if n := len(a.senders); n != maxSpamSenders {
+		t.Fatalf("tracked sender count mismatch: have %d, want %d", n, maxSpamSenders)
+	}
+	if !a.throttled(spammer, now) {
+		t.Errorf("most penalized sender not throttled")
+	}
+	// Once the tracked penalties decayed, new senders displace the old ones
+	later := now.Add(spamHalfLife)
+	a.penalize(common.Address{0xee}, later)
+	if _, ok := a.senders[common.Address{0xee}]; !ok {
+		t.Errorf("new sender not tracked over decayed ones")
+	}
+	if n := len(a.senders); n != maxSpamSenders {
+		t.Fatalf("tracked sender count mismatch: have %d, want %d", n, maxSpamSenders)
+	}
+}
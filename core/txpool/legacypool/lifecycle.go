@@ -0,0 +1,80 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+)
+
+const (
+	// lifecycleTxs is the maximum number of transactions whose lifecycle is
+	// tracked. Beyond it, the histories of the earliest seen ones are forgotten.
+	lifecycleTxs = 16384
+
+	// lifecycleEvents is the maximum number of events retained for a single
+	// transaction. Beyond it, the oldest events are forgotten.
+	lifecycleEvents = 16
+)
+
+// lifecycle is a bounded record of the recent lifecycle events of transactions
+// seen by the pool.
+//
+// Note, lifecycle is not thread safe, its users must hold the pool lock.
+type lifecycle struct {
+	events map[common.Hash][]txpool.TxEvent // Event history of the tracked transactions
+	order  []common.Hash                    // Tracked transactions in insertion order
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{events: make(map[common.Hash][]txpool.TxEvent)}
+}
+
+// record appends an event to the history of a transaction, starting to track it
+// if it's not yet tracked.
+func (l *lifecycle) record(hash common.Hash, event txpool.TxEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	events, ok := l.events[hash]
+	if !ok {
+		if len(l.order) >= lifecycleTxs {
+			delete(l.events, l.order[0])
+			l.order = l.order[1:]
+		}
+		l.order = append(l.order, hash)
+	}
+	// Skip recording repeated stages (e.g. a pending transaction shuffled around)
+	if n := len(events); n > 0 && events[n-1].Stage == event.Stage && event.Stage != txpool.TxStageDropped {
+		return
+	}
+	if len(events) >= lifecycleEvents {
+		events = append(events[:0], events[1:]...)
+	}
+	l.events[hash] = append(events, event)
+}
+
+// get returns a copy of the recorded history of a transaction.
+func (l *lifecycle) get(hash common.Hash) []txpool.TxEvent {
+	events := l.events[hash]
+	if len(events) == 0 {
+		return nil
+	}
+	return append([]txpool.TxEvent(nil), events...)
+}
@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package legacypool
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// snapshotEntry is a transaction stored in a pool snapshot, along with the
+// timestamps needed to restore its age and the lifetime of its sender.
+type snapshotEntry struct {
+	Tx   *types.Transaction
+	Time uint64 // Time the transaction was first seen, in unix nanoseconds
+	Beat uint64 // Last heartbeat of the sender account, in unix nanoseconds
+}
+
+// snapshot is a dump of all the remote transactions of the pool, pending and
+// queued ones alike, with the aim of allowing them to survive node restarts.
+// Contrary to the journal, which is appended to whenever a local transaction
+// arrives, the snapshot is regenerated in full periodically and on shutdown.
+type snapshot struct {
+	path string // Filesystem path to store the transactions at
+}
+
+// newTxSnapshot creates a new transaction pool snapshot at the given path.
+func newTxSnapshot(path string) *snapshot {
+	return &snapshot{
+		path: path,
+	}
+}
+
+// load parses a pool snapshot from disk, injecting its contents into the pool
+// via the given add method. The original arrival time of the transactions is
+// restored before insertion, and the heartbeats of the senders are returned so
+// the pool can resume their queue lifetime.
+func (s *snapshot) load(signer types.Signer, add func([]*txpool.Transaction) []error) (map[common.Address]time.Time, error) {
+	input, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the snapshot file doesn't exist at all
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	var (
+		stream = rlp.NewStream(bufio.NewReader(input), 0)
+		beats  = make(map[common.Address]time.Time)
+
+		total, dropped int
+		failure        error
+		batch          []*txpool.Transaction
+		senders        []common.Address
+		stamps         []time.Time
+	)
+	// Create a method to load a limited batch of transactions and bump the
+	// appropriate progress counters.
+	loadBatch := func() {
+		for i, err := range add(batch) {
+			if err != nil {
+				log.Debug("Failed to add snapshotted transaction", "err", err)
+				dropped++
+				continue
+			}
+			if stamps[i].After(beats[senders[i]]) {
+				beats[senders[i]] = stamps[i]
+			}
+		}
+		batch, senders, stamps = batch[:0], senders[:0], stamps[:0]
+	}
+	for {
+		var entry snapshotEntry
+		if err = stream.Decode(&entry); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			break
+		}
+		total++
+
+		// Restore the age of the transaction and track its sender's heartbeat.
+		// Transactions with invalid signatures are rejected by the pool anyway.
+		entry.Tx.SetTime(time.Unix(0, int64(entry.Time)))
+		from, _ := types.Sender(signer, entry.Tx)
+
+		batch = append(batch, &txpool.Transaction{Tx: entry.Tx})
+		senders = append(senders, from)
+		stamps = append(stamps, time.Unix(0, int64(entry.Beat)))
+
+		if len(batch) >= 1024 {
+			loadBatch()
+		}
+	}
+	if len(batch) > 0 {
+		loadBatch()
+	}
+	log.Info("Loaded transaction pool snapshot", "transactions", total, "dropped", dropped)
+	return beats, failure
+}
+
+// write regenerates the snapshot from the given transactions, grouped by sender
+// account, and the heartbeats of the senders.
+func (s *snapshot) write(all map[common.Address]types.Transactions, beats map[common.Address]time.Time) error {
+	// Generate the new snapshot next to the live one, and swap it in when done
+	replacement, err := os.OpenFile(s.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	var (
+		buffered = bufio.NewWriter(replacement)
+		count    int
+	)
+	for addr, txs := range all {
+		beat := uint64(beats[addr].UnixNano())
+		for _, tx := range txs {
+			entry := &snapshotEntry{Tx: tx, Time: uint64(tx.Time().UnixNano()), Beat: beat}
+			if err = rlp.Encode(buffered, entry); err != nil {
+				replacement.Close()
+				return err
+			}
+		}
+		count += len(txs)
+	}
+	if err = buffered.Flush(); err != nil {
+		replacement.Close()
+		return err
+	}
+	if err = replacement.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(s.path+".new", s.path); err != nil {
+		return err
+	}
+	log.Info("Regenerated transaction pool snapshot", "transactions", count, "accounts", len(all))
+	return nil
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package txpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// TxStage is a step in the lifecycle of a pooled transaction.
+type TxStage uint8
+
+const (
+	TxStageQueued   TxStage = iota + 1 // Waiting for a nonce gap to be filled or funds to arrive
+	TxStagePending                     // Executable on top of the current head
+	TxStageIncluded                    // Included in the canonical chain
+	TxStageDropped                     // Removed from the pool without being included
+)
+
+// String implements fmt.Stringer.
+func (s TxStage) String() string {
+	switch s {
+	case TxStageQueued:
+		return "queued"
+	case TxStagePending:
+		return "pending"
+	case TxStageIncluded:
+		return "included"
+	case TxStageDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// DropReason describes why a transaction was removed from the pool without being
+// included in the chain.
+type DropReason uint8
+
+const (
+	DropUnderpriced  DropReason = iota + 1 // Outbid by better paying transactions, or below the minimum tip
+	DropReplaced                           // Replaced by a transaction with the same nonce
+	DropNonceTooLow                        // Nonce consumed by a conflicting transaction, e.g. after a reorg
+	DropUnexecutable                       // Not enough funds or over the block gas limit
+	DropEvicted                            // Evicted to keep the pool within its size limits
+	DropExpired                            // Queued for longer than the allowed lifetime
+	DropConditional                        // Preconditions can no longer be met
+)
+
+// String implements fmt.Stringer.
+func (r DropReason) String() string {
+	switch r {
+	case DropUnderpriced:
+		return "underpriced"
+	case DropReplaced:
+		return "replaced"
+	case DropNonceTooLow:
+		return "nonce too low"
+	case DropUnexecutable:
+		return "unexecutable"
+	case DropEvicted:
+		return "evicted"
+	case DropExpired:
+		return "expired"
+	case DropConditional:
+		return "conditional"
+	default:
+		return "unknown"
+	}
+}
+
+// TxEvent is a single entry in the lifecycle history of a transaction.
+type TxEvent struct {
+	Time        time.Time
+	Stage       TxStage
+	Reason      DropReason  // Reason of the drop, only set for dropped transactions
+	Replacement common.Hash // Hash of the replacing transaction, only set for replaced ones
+}
+
+// DroppedTx is a transaction removed from the pool without being included.
+type DroppedTx struct {
+	Tx          *types.Transaction
+	Reason      DropReason
+	Replacement common.Hash // Hash of the replacing transaction, only set for replaced ones
+}
+
+// DroppedTxsEvent is posted when a batch of transactions leave the transaction
+// pool without being included in the chain.
+type DroppedTxsEvent struct{ Txs []DroppedTx }
+
+// TrackingSubPool is a subpool recording the lifecycle of its transactions and
+// announcing the ones it drops.
+type TrackingSubPool interface {
+	SubPool
+
+	// Lifecycle returns the recorded lifecycle history of a transaction, oldest
+	// event first, or nil if the transaction is not tracked.
+	Lifecycle(hash common.Hash) []TxEvent
+
+	// SubscribeDropped subscribes to the transactions dropped by the subpool.
+	SubscribeDropped(ch chan<- DroppedTxsEvent) event.Subscription
+}
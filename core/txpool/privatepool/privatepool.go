@@ -0,0 +1,578 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package privatepool implements a transaction pool for privately submitted
+// transactions, which are only used for local block production.
+package privatepool
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// txMaxSize is the maximum size a single private transaction can have, in
+	// line with the limit of the legacy pool.
+	txMaxSize = 4 * 32 * 1024
+
+	// maxResetDepth is the maximum number of blocks walked back on a pool reset
+	// to look for included private transactions.
+	maxResetDepth = 64
+)
+
+var (
+	// ErrAccountLimitExceeded is returned if a sender attempts to pool more
+	// private transactions than its account slots allow.
+	ErrAccountLimitExceeded = errors.New("private account limit exceeded")
+
+	// ErrPoolFull is returned if the private pool is full and the transaction
+	// doesn't pay more than the cheapest one which could be evicted.
+	ErrPoolFull = errors.New("private pool is full")
+)
+
+// BlockChain defines the minimal set of methods needed to back a private pool
+// with a chain. Exists to allow mocking the live chain out of tests.
+type BlockChain interface {
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// GetBlock retrieves a specific block, used during pool resets.
+	GetBlock(hash common.Hash, number uint64) *types.Block
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// Config are the configuration parameters of the private transaction pool.
+type Config struct {
+	Lifetime     uint64 // Number of blocks a private transaction is kept for inclusion
+	History      uint64 // Number of finished private transactions to retain the status of
+	AccountSlots uint64 // Maximum number of private transactions per account
+	GlobalSlots  uint64 // Maximum number of private transactions for all accounts
+}
+
+// DefaultConfig contains the default configurations for the private pool.
+var DefaultConfig = Config{
+	Lifetime:     25,
+	History:      4096,
+	AccountSlots: 16,
+	GlobalSlots:  1024,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.Lifetime < 1 {
+		log.Warn("Sanitizing invalid privatepool lifetime", "provided", conf.Lifetime, "updated", DefaultConfig.Lifetime)
+		conf.Lifetime = DefaultConfig.Lifetime
+	}
+	if conf.AccountSlots < 1 {
+		log.Warn("Sanitizing invalid privatepool account slots", "provided", conf.AccountSlots, "updated", DefaultConfig.AccountSlots)
+		conf.AccountSlots = DefaultConfig.AccountSlots
+	}
+	if conf.GlobalSlots < 1 {
+		log.Warn("Sanitizing invalid privatepool global slots", "provided", conf.GlobalSlots, "updated", DefaultConfig.GlobalSlots)
+		conf.GlobalSlots = DefaultConfig.GlobalSlots
+	}
+	return conf
+}
+
+// TxStatus is the lifecycle status of a private transaction.
+type TxStatus uint
+
+const (
+	TxStatusUnknown  TxStatus = iota // Never seen, or forgotten since
+	TxStatusPending                  // Executable, awaiting inclusion
+	TxStatusQueued                   // Waiting for a nonce gap to be filled
+	TxStatusIncluded                 // Included in a block
+	TxStatusExpired                  // Not included within its lifetime
+	TxStatusDropped                  // Invalidated, e.g. by a conflicting transaction
+)
+
+// String implements fmt.Stringer.
+func (s TxStatus) String() string {
+	switch s {
+	case TxStatusPending:
+		return "pending"
+	case TxStatusQueued:
+		return "queued"
+	case TxStatusIncluded:
+		return "included"
+	case TxStatusExpired:
+		return "expired"
+	case TxStatusDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// Record is the tracked lifecycle of a private transaction.
+type Record struct {
+	Status   TxStatus
+	Deadline uint64 // Last block number the transaction may be included in
+	Block    uint64 // Block number the transaction was included in, if any
+}
+
+// privateTx is a transaction tracked by the private pool.
+type privateTx struct {
+	tx       *types.Transaction
+	from     common.Address
+	deadline uint64
+}
+
+// PrivatePool is a transaction pool holding privately submitted transactions.
+// These are never announced to the network: the pool does not emit transaction
+// events and is hidden from remote peers. The transactions are kept for a limited
+// number of blocks, during which they are available for local block production.
+type PrivatePool struct {
+	config Config
+	chain  BlockChain
+	signer types.Signer
+	gasTip *big.Int
+
+	head  *types.Header  // Current head of the chain
+	state *state.StateDB // Current state at the head of the chain
+
+	all      map[common.Hash]*privateTx               // All tracked transactions by hash
+	accounts map[common.Address]map[uint64]*privateTx // Tracked transactions by sender and nonce
+	history  map[common.Hash]Record                   // Records of the finished transactions
+	finished []common.Hash                            // Finished transactions in order, for history eviction
+	txFeed   event.Feed                               // Transaction feed, never fired to avoid propagation
+
+	// lock protects the pool contents and the state. It's not a read-write lock,
+	// since state reads fill the caches of the StateDB.
+	lock sync.Mutex
+}
+
+// New creates a new private transaction pool.
+func New(config Config, chain BlockChain) *PrivatePool {
+	config = (&config).sanitize()
+
+	return &PrivatePool{
+		config:   config,
+		chain:    chain,
+		signer:   types.LatestSigner(chain.Config()),
+		all:      make(map[common.Hash]*privateTx),
+		accounts: make(map[common.Address]map[uint64]*privateTx),
+		history:  make(map[common.Hash]Record),
+	}
+}
+
+// Filter returns whether the given transaction can be consumed by the private
+// pool. Private transactions are only accepted through TxPool.AddPrivate, so no
+// transaction is accepted through the generic path.
+func (p *PrivatePool) Filter(tx *types.Transaction) bool {
+	return false
+}
+
+// Private marks the pool as a private subpool.
+func (p *PrivatePool) Private() {}
+
+// Init sets the gas price needed to keep a transaction in the pool and the chain
+// head to allow balance / nonce checks.
+func (p *PrivatePool) Init(gasTip *big.Int, head *types.Header) error {
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		return err
+	}
+	p.head, p.state = head, statedb
+	p.gasTip = new(big.Int).Set(gasTip)
+	return nil
+}
+
+// Close terminates the private pool.
+func (p *PrivatePool) Close() error {
+	return nil
+}
+
+// Reset moves the pool to a new chain head, finishing the transactions that got
+// included, invalidated or expired.
+func (p *PrivatePool) Reset(oldHead, newHead *types.Header) {
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		log.Error("Failed to reset private pool state", "err", err)
+		return
+	}
+	// Gather the transactions included in the new blocks
+	included := make(map[common.Hash]uint64)
+
+	block := p.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
+	for depth := 0; block != nil && depth < maxResetDepth; depth++ {
+		if oldHead != nil && block.Hash() == oldHead.Hash() {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			included[tx.Hash()] = block.NumberU64()
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = p.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.head, p.state = newHead, statedb
+
+	for hash, ptx := range p.all {
+		switch {
+		case included[hash] != 0:
+			p.finish(ptx, Record{Status: TxStatusIncluded, Deadline: ptx.deadline, Block: included[hash]})
+		case ptx.tx.Nonce() < statedb.GetNonce(ptx.from):
+			p.finish(ptx, Record{Status: TxStatusDropped, Deadline: ptx.deadline})
+		case newHead.Number.Uint64() >= ptx.deadline:
+			p.finish(ptx, Record{Status: TxStatusExpired, Deadline: ptx.deadline})
+		}
+	}
+}
+
+// finish removes a transaction from the pool, retaining its final record.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) finish(ptx *privateTx, record Record) {
+	hash := ptx.tx.Hash()
+	log.Debug("Private transaction finished", "hash", hash, "status", record.Status)
+
+	p.remove(ptx)
+	p.history[hash] = record
+	p.finished = append(p.finished, hash)
+
+	for uint64(len(p.finished)) > p.config.History {
+		delete(p.history, p.finished[0])
+		p.finished = p.finished[1:]
+	}
+}
+
+// remove deletes a transaction from the pool's indices.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) remove(ptx *privateTx) {
+	delete(p.all, ptx.tx.Hash())
+
+	txs := p.accounts[ptx.from]
+	delete(txs, ptx.tx.Nonce())
+	if len(txs) == 0 {
+		delete(p.accounts, ptx.from)
+	}
+}
+
+// SetGasTip updates the minimum gas tip required by the miner to include a
+// private transaction. Since private transactions are explicitly submitted by
+// the node operator, none of them are dropped.
+func (p *PrivatePool) SetGasTip(tip *big.Int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.gasTip = new(big.Int).Set(tip)
+}
+
+// Has returns an indicator whether the pool has a transaction cached with the
+// given hash.
+func (p *PrivatePool) Has(hash common.Hash) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.all[hash] != nil
+}
+
+// Get returns a transaction if it is contained in the pool, or nil otherwise.
+func (p *PrivatePool) Get(hash common.Hash) *txpool.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if ptx := p.all[hash]; ptx != nil {
+		return &txpool.Transaction{Tx: ptx.tx}
+	}
+	return nil
+}
+
+// Add inserts a batch of private transactions into the pool. The local and
+// sync flags are ignored, all private transactions being local by definition.
+func (p *PrivatePool) Add(txs []*txpool.Transaction, local bool, sync bool) []error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = p.add(tx.Tx)
+	}
+	return errs
+}
+
+// add validates and inserts a single private transaction into the pool. A
+// transaction with the same sender and nonce as an existing one replaces it. If
+// the pool is full, the cheapest evictable transaction makes room for a new one
+// paying a higher tip.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) add(tx *types.Transaction) error {
+	hash := tx.Hash()
+	if p.all[hash] != nil {
+		return txpool.ErrAlreadyKnown
+	}
+	opts := &txpool.ValidationOptions{
+		Config: p.chain.Config(),
+		Accept: 0 |
+			1<<types.LegacyTxType |
+			1<<types.AccessListTxType |
+			1<<types.DynamicFeeTxType,
+		MaxSize: txMaxSize,
+		MinTip:  new(big.Int),
+	}
+	if err := txpool.ValidateTransaction(tx, nil, nil, nil, p.head, p.signer, opts); err != nil {
+		return err
+	}
+	from, _ := types.Sender(p.signer, tx) // already validated above
+
+	stateOpts := &txpool.ValidationOptionsWithState{
+		State: p.state,
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			spent := new(big.Int)
+			for _, ptx := range p.accounts[addr] {
+				spent.Add(spent, ptx.tx.Cost())
+			}
+			return spent
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			if ptx := p.accounts[addr][nonce]; ptx != nil {
+				return ptx.tx.Cost()
+			}
+			return nil
+		},
+	}
+	if err := txpool.ValidateTransactionWithState(tx, p.signer, stateOpts); err != nil {
+		return err
+	}
+	if old := p.accounts[from][tx.Nonce()]; old != nil {
+		p.finish(old, Record{Status: TxStatusDropped, Deadline: old.deadline})
+	} else {
+		if uint64(len(p.accounts[from])) >= p.config.AccountSlots {
+			return ErrAccountLimitExceeded
+		}
+		if uint64(len(p.all)) >= p.config.GlobalSlots {
+			victim := p.cheapest()
+			if victim == nil || tx.EffectiveGasTipCmp(victim.tx, p.head.BaseFee) <= 0 {
+				return ErrPoolFull
+			}
+			log.Debug("Evicting private transaction", "hash", victim.tx.Hash(), "from", victim.from, "nonce", victim.tx.Nonce())
+			p.finish(victim, Record{Status: TxStatusDropped, Deadline: victim.deadline})
+		}
+	}
+	ptx := &privateTx{
+		tx:       tx,
+		from:     from,
+		deadline: p.head.Number.Uint64() + p.config.Lifetime,
+	}
+	p.all[hash] = ptx
+	if p.accounts[from] == nil {
+		p.accounts[from] = make(map[uint64]*privateTx)
+	}
+	p.accounts[from][tx.Nonce()] = ptx
+
+	log.Debug("Pooled private transaction", "hash", hash, "from", from, "nonce", tx.Nonce(), "deadline", ptx.deadline)
+	return nil
+}
+
+// cheapest returns the transaction paying the lowest tip among the highest nonce
+// transactions of each account, which can be evicted without creating a nonce
+// gap.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) cheapest() *privateTx {
+	var victim *privateTx
+	for _, txs := range p.accounts {
+		var last *privateTx
+		for _, ptx := range txs {
+			if last == nil || ptx.tx.Nonce() > last.tx.Nonce() {
+				last = ptx
+			}
+		}
+		if victim == nil || last.tx.EffectiveGasTipCmp(victim.tx, p.head.BaseFee) < 0 {
+			victim = last
+		}
+	}
+	return victim
+}
+
+// executables returns the transactions of an account executable on top of the
+// current state, sorted by nonce.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) executables(addr common.Address) []*types.Transaction {
+	var (
+		txs   = p.accounts[addr]
+		nonce = p.state.GetNonce(addr)
+		run   []*types.Transaction
+	)
+	for ptx := txs[nonce]; ptx != nil; ptx = txs[nonce] {
+		run = append(run, ptx.tx)
+		nonce++
+	}
+	return run
+}
+
+// queued returns the transactions of an account that are not executable on top
+// of the current state, sorted by nonce.
+//
+// Note, this method assumes the pool lock is held!
+func (p *PrivatePool) queued(addr common.Address) []*types.Transaction {
+	var (
+		txs   = p.accounts[addr]
+		next  = p.state.GetNonce(addr) + uint64(len(p.executables(addr)))
+		block []*types.Transaction
+	)
+	for nonce, ptx := range txs {
+		if nonce > next {
+			block = append(block, ptx.tx)
+		}
+	}
+	sort.Sort(types.TxByNonce(block))
+	return block
+}
+
+// Pending retrieves all currently executable private transactions, grouped by
+// origin account and sorted by nonce. If tips are enforced, the transactions of
+// an account are truncated at the first one paying less than the gas tip.
+func (p *PrivatePool) Pending(enforceTips bool) map[common.Address][]*types.Transaction {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pending := make(map[common.Address][]*types.Transaction)
+	for addr := range p.accounts {
+		txs := p.executables(addr)
+		if enforceTips {
+			for i, tx := range txs {
+				if tx.EffectiveGasTipIntCmp(p.gasTip, p.head.BaseFee) < 0 {
+					txs = txs[:i]
+					break
+				}
+			}
+		}
+		if len(txs) > 0 {
+			pending[addr] = txs
+		}
+	}
+	return pending
+}
+
+// SubscribeTransactions registers a subscription of NewTxsEvent. Since private
+// transactions must never be propagated to the network, no events are sent.
+func (p *PrivatePool) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.txFeed.Subscribe(ch)
+}
+
+// Nonce returns the next nonce of an account, with all executable private
+// transactions applied on top.
+func (p *PrivatePool) Nonce(addr common.Address) uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.state.GetNonce(addr) + uint64(len(p.executables(addr)))
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (p *PrivatePool) Stats() (int, int) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var pending int
+	for addr := range p.accounts {
+		pending += len(p.executables(addr))
+	}
+	return pending, len(p.all) - pending
+}
+
+// Content retrieves the data content of the private pool, returning all the
+// pending as well as queued transactions, grouped by account and sorted by nonce.
+func (p *PrivatePool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var (
+		pending = make(map[common.Address][]*types.Transaction)
+		queued  = make(map[common.Address][]*types.Transaction)
+	)
+	for addr := range p.accounts {
+		if txs := p.executables(addr); len(txs) > 0 {
+			pending[addr] = txs
+		}
+		if txs := p.queued(addr); len(txs) > 0 {
+			queued[addr] = txs
+		}
+	}
+	return pending, queued
+}
+
+// ContentFrom retrieves the data content of the private pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+func (p *PrivatePool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	return p.executables(addr), p.queued(addr)
+}
+
+// Locals retrieves the accounts currently considered local by the pool. Private
+// transactions are not subject to local account tracking.
+func (p *PrivatePool) Locals() []common.Address {
+	return []common.Address{}
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by its hash.
+func (p *PrivatePool) Status(hash common.Hash) txpool.TxStatus {
+	switch p.Record(hash).Status {
+	case TxStatusPending:
+		return txpool.TxStatusPending
+	case TxStatusQueued:
+		return txpool.TxStatusQueued
+	default:
+		return txpool.TxStatusUnknown
+	}
+}
+
+// Record returns the lifecycle record of a private transaction, be it still in
+// the pool or recently finished.
+func (p *PrivatePool) Record(hash common.Hash) Record {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if ptx := p.all[hash]; ptx != nil {
+		status := TxStatusQueued
+		if ptx.tx.Nonce() < p.state.GetNonce(ptx.from)+uint64(len(p.executables(ptx.from))) {
+			status = TxStatusPending
+		}
+		return Record{Status: status, Deadline: ptx.deadline}
+	}
+	return p.history[hash]
+}
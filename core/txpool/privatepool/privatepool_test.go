@@ -0,0 +1,323 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package privatepool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testBlockChain is a mock of the live chain for testing the pool.
+type testBlockChain struct {
+	config  *params.ChainConfig
+	statedb *state.StateDB
+	blocks  map[common.Hash]*types.Block
+	head    *types.Header
+
+	chainHeadFeed event.Feed
+}
+
+func newTestBlockChain() *testBlockChain {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	genesis := types.NewBlockWithHeader(&types.Header{Number: new(big.Int), GasLimit: 10_000_000})
+
+	return &testBlockChain{
+		config:  params.TestChainConfig,
+		statedb: statedb,
+		blocks:  map[common.Hash]*types.Block{genesis.Hash(): genesis},
+		head:    genesis.Header(),
+	}
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig { return bc.config }
+func (bc *testBlockChain) CurrentBlock() *types.Header { return bc.head }
+
+func (bc *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.blocks[hash]
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+func (bc *testBlockChain) SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return bc.chainHeadFeed.Subscribe(ch)
+}
+
+// mine appends a new block with the given transactions to the chain, returning
+// the old and new heads.
+func (bc *testBlockChain) mine(txs ...*types.Transaction) (*types.Header, *types.Header) {
+	for _, tx := range txs {
+		from, _ := types.Sender(types.HomesteadSigner{}, tx)
+		bc.statedb.SetNonce(from, tx.Nonce()+1)
+	}
+	header := &types.Header{
+		ParentHash: bc.head.Hash(),
+		Number:     new(big.Int).Add(bc.head.Number, common.Big1),
+		GasLimit:   bc.head.GasLimit,
+	}
+	block := types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))
+	bc.blocks[block.Hash()] = block
+
+	old := bc.head
+	bc.head = block.Header()
+	return old, bc.head
+}
+
+func transaction(nonce uint64, price int64, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(100), 100000, big.NewInt(price), nil), types.HomesteadSigner{}, key)
+	return tx
+}
+
+func setupPool(t *testing.T) (*PrivatePool, *testBlockChain, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	chain := newTestBlockChain()
+	key, _ := crypto.GenerateKey()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1_000_000_000))
+
+	pool := New(Config{Lifetime: 2, History: 16}, chain)
+	if err := pool.Init(big.NewInt(1), chain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	return pool, chain, key
+}
+
+func add(pool *PrivatePool, txs ...*types.Transaction) []error {
+	wrapped := make([]*txpool.Transaction, len(txs))
+	for i, tx := range txs {
+		wrapped[i] = &txpool.Transaction{Tx: tx}
+	}
+	return pool.Add(wrapped, true, true)
+}
+
+// Tests that private transactions are tracked through their lifecycle, from
+// submission to inclusion or expiry.
+func TestPrivateLifecycle(t *testing.T) {
+	pool, chain, key := setupPool(t)
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx0, tx1, tx3 := transaction(0, 1, key), transaction(1, 1, key), transaction(3, 1, key)
+	for i, err := range add(pool, tx0, tx1, tx3) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if pool.Filter(tx0) {
+		t.Errorf("private pool accepts transactions through the generic path")
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 2/1", pending, queued)
+	}
+	if nonce := pool.Nonce(addr); nonce != 2 {
+		t.Errorf("pool nonce mismatch: have %d, want 2", nonce)
+	}
+	if record := pool.Record(tx3.Hash()); record.Status != TxStatusQueued || record.Deadline != 2 {
+		t.Errorf("queued record mismatch: have %+v", record)
+	}
+	// Include the first transaction and ensure it's finished
+	pool.Reset(chain.mine(tx0))
+
+	if record := pool.Record(tx0.Hash()); record.Status != TxStatusIncluded || record.Block != 1 {
+		t.Errorf("included record mismatch: have %+v", record)
+	}
+	if pending := pool.Pending(true); len(pending[addr]) != 1 || pending[addr][0] != tx1 {
+		t.Errorf("pending transactions mismatch: have %v", pending[addr])
+	}
+	// Mine an empty block reaching the deadline and ensure the rest expire
+	pool.Reset(chain.mine())
+
+	for _, tx := range []*types.Transaction{tx1, tx3} {
+		if record := pool.Record(tx.Hash()); record.Status != TxStatusExpired {
+			t.Errorf("expired record mismatch: have %+v", record)
+		}
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 0/0", pending, queued)
+	}
+}
+
+// Tests that replaced and invalidated private transactions are dropped, and that
+// the finished records are only retained up to the configured limit.
+func TestPrivateDropping(t *testing.T) {
+	pool, chain, key := setupPool(t)
+
+	tx, replacement := transaction(0, 1, key), transaction(0, 2, key)
+	add(pool, tx)
+	if err := add(pool, replacement)[0]; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if record := pool.Record(tx.Hash()); record.Status != TxStatusDropped {
+		t.Errorf("replaced record mismatch: have %+v", record)
+	}
+	// Include a conflicting transaction and ensure the private one is dropped
+	pool.Reset(chain.mine(transaction(0, 3, key)))
+
+	if record := pool.Record(replacement.Hash()); record.Status != TxStatusDropped {
+		t.Errorf("invalidated record mismatch: have %+v", record)
+	}
+	// Overflow the history and ensure the oldest records are forgotten
+	for i := uint64(1); i <= pool.config.History; i++ {
+		add(pool, transaction(i, 1, key))
+		pool.Reset(chain.mine(transaction(i, 2, key)))
+	}
+	if record := pool.Record(tx.Hash()); record.Status != TxStatusUnknown {
+		t.Errorf("evicted record mismatch: have %+v", record)
+	}
+}
+
+// Tests that the number of private transactions is limited per account and in
+// total, evicting the cheapest transaction if a new one pays more.
+func TestPrivateLimits(t *testing.T) {
+	pool, chain, key := setupPool(t)
+	pool.config.AccountSlots, pool.config.GlobalSlots = 2, 3
+
+	other, _ := crypto.GenerateKey()
+	third, _ := crypto.GenerateKey()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1_000_000_000))
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(third.PublicKey), big.NewInt(1_000_000_000))
+
+	for i, err := range add(pool, transaction(0, 3, key), transaction(5, 3, key)) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if err := add(pool, transaction(1, 3, key))[0]; err != ErrAccountLimitExceeded {
+		t.Fatalf("account limit error mismatch: have %v, want %v", err, ErrAccountLimitExceeded)
+	}
+	if err := add(pool, transaction(5, 4, key))[0]; err != nil {
+		t.Fatalf("failed to replace transaction at account limit: %v", err)
+	}
+	cheap := transaction(0, 1, other)
+	if err := add(pool, cheap)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if err := add(pool, transaction(0, 1, third))[0]; err != ErrPoolFull {
+		t.Fatalf("pool full error mismatch: have %v, want %v", err, ErrPoolFull)
+	}
+	if err := add(pool, transaction(0, 2, third))[0]; err != nil {
+		t.Fatalf("failed to add transaction evicting a cheaper one: %v", err)
+	}
+	if record := pool.Record(cheap.Hash()); record.Status != TxStatusDropped {
+		t.Errorf("evicted record mismatch: have %+v", record)
+	}
+	if pending, queued := pool.Stats(); pending != 2 || queued != 1 {
+		t.Fatalf("pool stats mismatch: have %d/%d, want 2/1", pending, queued)
+	}
+}
+
+// Tests that private transactions are only available for block production when
+// added through the primary pool, and are marked as private there.
+func TestPrivateTxPool(t *testing.T) {
+	chain := newTestBlockChain()
+	key, _ := crypto.GenerateKey()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1_000_000_000))
+
+	pool, err := txpool.New(big.NewInt(1), chain, []txpool.SubPool{New(DefaultConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	tx := transaction(0, 1, key)
+	if err := pool.Add([]*txpool.Transaction{{Tx: tx}}, true, true)[0]; err == nil {
+		t.Fatalf("private subpool accepted public transaction")
+	}
+	if err := pool.AddPrivate([]*txpool.Transaction{{Tx: tx}}, true)[0]; err != nil {
+		t.Fatalf("failed to add private transaction: %v", err)
+	}
+	if !pool.Private(tx.Hash()) {
+		t.Errorf("private transaction not marked private")
+	}
+	if pending := pool.Pending(false); len(pending) != 1 {
+		t.Errorf("private transaction not pending")
+	}
+	if pending := pool.PublicPending(false); len(pending) != 0 {
+		t.Errorf("private transaction publicly pending")
+	}
+}
+
+// Tests that private submissions count against the submission rate of their
+// origin, shared with the public transactions.
+func TestPrivateOriginRate(t *testing.T) {
+	chain := newTestBlockChain()
+	key, _ := crypto.GenerateKey()
+	chain.statedb.AddBalance(crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1_000_000_000))
+
+	config := legacypool.DefaultConfig
+	config.OriginRate = 2
+
+	pool, err := txpool.New(big.NewInt(1), chain, []txpool.SubPool{legacypool.New(config, chain), New(DefaultConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	submit := func(nonce uint64, origin string) error {
+		return pool.AddPrivate([]*txpool.Transaction{{Tx: transaction(nonce, 1, key), Origin: origin}}, true)[0]
+	}
+	for nonce := uint64(0); nonce < 2; nonce++ {
+		if err := submit(nonce, "10.0.0.1:30000"); err != nil {
+			t.Fatalf("transaction %d within rate refused: %v", nonce, err)
+		}
+	}
+	if err := submit(2, "10.0.0.1:30001"); err != txpool.ErrOriginRateLimited {
+		t.Fatalf("transaction over rate error mismatch: have %v, want %v", err, txpool.ErrOriginRateLimited)
+	}
+	if err := submit(2, ""); err != nil {
+		t.Fatalf("transaction without origin refused: %v", err)
+	}
+	if stats := pool.Admission(); stats.RateLimited != 1 {
+		t.Fatalf("rate limited counter mismatch: have %d, want 1", stats.RateLimited)
+	}
+}
+
+// Tests that concurrent readers don't race on the pool state. State reads
+// populate the caches of the StateDB, so this only fails with -race.
+func TestPrivateConcurrentReads(t *testing.T) {
+	pool, _, key := setupPool(t)
+	add(pool, transaction(0, 1, key), transaction(2, 1, key))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				pool.Nonce(common.Address{byte(j)})
+				pool.Pending(false)
+				pool.Stats()
+				pool.Content()
+			}
+		}()
+	}
+	wg.Wait()
+}
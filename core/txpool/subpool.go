@@ -34,6 +34,8 @@ type Transaction struct {
 	BlobTxBlobs   []kzg4844.Blob       // Blobs needed by the blob pool
 	BlobTxCommits []kzg4844.Commitment // Commitments needed by the blob pool
 	BlobTxProofs  []kzg4844.Proof      // Proofs needed by the blob pool
+
+	Origin string // Network address of the RPC client submitting the transaction, if any
 }
 
 // SubPool represents a specialized transaction pool that lives on its own (e.g.
@@ -109,3 +111,14 @@ type SubPool interface {
 	// identified by their hashes.
 	Status(hash common.Hash) TxStatus
 }
+
+// PrivateSubPool is a subpool holding transactions which must never be propagated
+// to the network, being only available for local block production. Its Filter
+// must reject all transactions, private ones being added through AddPrivate on
+// the primary pool instead.
+type PrivateSubPool interface {
+	SubPool
+
+	// Private is a marker method distinguishing private subpools.
+	Private()
+}
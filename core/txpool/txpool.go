@@ -240,18 +240,112 @@ func (p *TxPool) Add(txs []*Transaction, local bool, sync bool) []error {
 	return errs
 }
 
+// AddPrivate enqueues a batch of transactions into the private subpool. These
+// are only used for local block production and never propagated to the network.
+// The submissions count against the rate of their origins, as enforced by the
+// admission subpools.
+func (p *TxPool) AddPrivate(txs []*Transaction, sync bool) []error {
+	errs := make([]error, len(txs))
+	for _, subpool := range p.subpools {
+		if _, ok := subpool.(PrivateSubPool); !ok {
+			continue
+		}
+		admitted := make([]*Transaction, 0, len(txs))
+		for i, tx := range txs {
+			if !p.allowOrigin(tx.Origin) {
+				errs[i] = ErrOriginRateLimited
+				continue
+			}
+			admitted = append(admitted, tx)
+		}
+		added := subpool.Add(admitted, true, sync)
+		for i := range errs {
+			if errs[i] == nil {
+				errs[i], added = added[0], added[1:]
+			}
+		}
+		return errs
+	}
+	for i := range txs {
+		errs[i] = ErrPrivateUnsupported
+	}
+	return errs
+}
+
+// allowOrigin records a submission from the given origin in the admission
+// subpools, reporting whether it fits into the origin's submission rate.
+func (p *TxPool) allowOrigin(origin string) bool {
+	if origin == "" {
+		return true
+	}
+	for _, subpool := range p.subpools {
+		if admitter, ok := subpool.(AdmissionSubPool); ok && !admitter.AllowOrigin(origin) {
+			return false
+		}
+	}
+	return true
+}
+
+// Private returns whether the transaction with the given hash is held by a
+// private subpool, and must thus not be propagated to the network.
+func (p *TxPool) Private(hash common.Hash) bool {
+	for _, subpool := range p.subpools {
+		if _, ok := subpool.(PrivateSubPool); ok && subpool.Has(hash) {
+			return true
+		}
+	}
+	return false
+}
+
 // Pending retrieves all currently processable transactions, grouped by origin
 // account and sorted by nonce.
 func (p *TxPool) Pending(enforceTips bool) map[common.Address][]*types.Transaction {
 	txs := make(map[common.Address][]*types.Transaction)
 	for _, subpool := range p.subpools {
 		for addr, set := range subpool.Pending(enforceTips) {
-			txs[addr] = set
+			txs[addr] = mergeByNonce(txs[addr], set)
 		}
 	}
 	return txs
 }
 
+// PublicPending retrieves the processable transactions announced to the network,
+// leaving out those of the private subpools.
+func (p *TxPool) PublicPending(enforceTips bool) map[common.Address][]*types.Transaction {
+	txs := make(map[common.Address][]*types.Transaction)
+	for _, subpool := range p.subpools {
+		if _, ok := subpool.(PrivateSubPool); ok {
+			continue
+		}
+		for addr, set := range subpool.Pending(enforceTips) {
+			txs[addr] = mergeByNonce(txs[addr], set)
+		}
+	}
+	return txs
+}
+
+// mergeByNonce merges two nonce sorted transaction lists of the same account.
+// Accounts are not exclusive to the private subpool, so the same account may
+// have transactions in multiple subpools, in which case those of the subpool
+// queried first take precedence on nonce collisions.
+func mergeByNonce(have, add []*types.Transaction) []*types.Transaction {
+	if len(have) == 0 {
+		return add
+	}
+	merged := make([]*types.Transaction, 0, len(have)+len(add))
+	for len(have) > 0 || len(add) > 0 {
+		switch {
+		case len(add) == 0 || (len(have) > 0 && have[0].Nonce() < add[0].Nonce()):
+			merged, have = append(merged, have[0]), have[1:]
+		case len(have) == 0 || add[0].Nonce() < have[0].Nonce():
+			merged, add = append(merged, add[0]), add[1:]
+		default:
+			merged, have, add = append(merged, have[0]), have[1:], add[1:]
+		}
+	}
+	return merged
+}
+
 // SubscribeNewTxsEvent registers a subscription of NewTxsEvent and starts sending
 // events to the given channel.
 func (p *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
@@ -262,6 +356,58 @@ func (p *TxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscrip
 	return p.subs.Track(event.JoinSubscriptions(subs...))
 }
 
+// SubscribeDroppedTxsEvent registers a subscription of DroppedTxsEvent and starts
+// sending event to the given channel.
+func (p *TxPool) SubscribeDroppedTxsEvent(ch chan<- DroppedTxsEvent) event.Subscription {
+	var subs []event.Subscription
+	for _, subpool := range p.subpools {
+		if tracker, ok := subpool.(TrackingSubPool); ok {
+			subs = append(subs, tracker.SubscribeDropped(ch))
+		}
+	}
+	return p.subs.Track(event.JoinSubscriptions(subs...))
+}
+
+// Lifecycle returns the recorded lifecycle history of a transaction, oldest event
+// first, or nil if none of the subpools track it.
+func (p *TxPool) Lifecycle(hash common.Hash) []TxEvent {
+	for _, subpool := range p.subpools {
+		if tracker, ok := subpool.(TrackingSubPool); ok {
+			if events := tracker.Lifecycle(hash); len(events) > 0 {
+				return events
+			}
+		}
+	}
+	return nil
+}
+
+// Admission returns the admission policy statistics of the pool. The policies
+// are reported from the first subpool enforcing them, the counters are summed.
+func (p *TxPool) Admission() AdmissionStats {
+	var (
+		stats AdmissionStats
+		found bool
+	)
+	for _, subpool := range p.subpools {
+		admitter, ok := subpool.(AdmissionSubPool)
+		if !ok {
+			continue
+		}
+		sub := admitter.Admission()
+		if !found {
+			stats.OriginRate, stats.QueueCoverage, stats.SpamPenalty = sub.OriginRate, sub.QueueCoverage, sub.SpamPenalty
+			found = true
+		}
+		stats.LimitedOrigins += sub.LimitedOrigins
+		stats.PenalizedSenders += sub.PenalizedSenders
+		stats.ThrottledSenders += sub.ThrottledSenders
+		stats.RateLimited += sub.RateLimited
+		stats.Uncovered += sub.Uncovered
+		stats.Throttled += sub.Throttled
+	}
+	return stats
+}
+
 // Nonce returns the next nonce of an account, with all transactions executable
 // by the pool already applied on top.
 func (p *TxPool) Nonce(addr common.Address) uint64 {
@@ -301,10 +447,10 @@ func (p *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.
 		run, block := subpool.Content()
 
 		for addr, txs := range run {
-			runnable[addr] = txs
+			runnable[addr] = mergeByNonce(runnable[addr], txs)
 		}
 		for addr, txs := range block {
-			blocked[addr] = txs
+			blocked[addr] = mergeByNonce(blocked[addr], txs)
 		}
 	}
 	return runnable, blocked
@@ -0,0 +1,213 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package userpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// entryPointABI is the subset of the v0.6 EntryPoint interface used by the pool.
+const entryPointABI = `[
+	{"type":"function","name":"handleOps","inputs":[{"name":"ops","type":"tuple[]","components":[
+		{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},
+		{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},
+		{"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},
+		{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]},
+		{"name":"beneficiary","type":"address"}],"outputs":[]},
+	{"type":"function","name":"simulateValidation","inputs":[{"name":"userOp","type":"tuple","components":[
+		{"name":"sender","type":"address"},{"name":"nonce","type":"uint256"},{"name":"initCode","type":"bytes"},
+		{"name":"callData","type":"bytes"},{"name":"callGasLimit","type":"uint256"},{"name":"verificationGasLimit","type":"uint256"},
+		{"name":"preVerificationGas","type":"uint256"},{"name":"maxFeePerGas","type":"uint256"},{"name":"maxPriorityFeePerGas","type":"uint256"},
+		{"name":"paymasterAndData","type":"bytes"},{"name":"signature","type":"bytes"}]}],"outputs":[]},
+	{"type":"error","name":"ValidationResult","inputs":[
+		{"name":"returnInfo","type":"tuple","components":[{"name":"preOpGas","type":"uint256"},{"name":"prefund","type":"uint256"},
+			{"name":"sigFailed","type":"bool"},{"name":"validAfter","type":"uint48"},{"name":"validUntil","type":"uint48"},
+			{"name":"paymasterContext","type":"bytes"}]},
+		{"name":"senderInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"factoryInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]},
+		{"name":"paymasterInfo","type":"tuple","components":[{"name":"stake","type":"uint256"},{"name":"unstakeDelaySec","type":"uint256"}]}]},
+	{"type":"error","name":"FailedOp","inputs":[{"name":"opIndex","type":"uint256"},{"name":"reason","type":"string"}]},
+	{"type":"event","name":"UserOperationEvent","anonymous":false,"inputs":[
+		{"name":"userOpHash","type":"bytes32","indexed":true},{"name":"sender","type":"address","indexed":true},
+		{"name":"paymaster","type":"address","indexed":true},{"name":"nonce","type":"uint256","indexed":false},
+		{"name":"success","type":"bool","indexed":false},{"name":"actualGasCost","type":"uint256","indexed":false},
+		{"name":"actualGasUsed","type":"uint256","indexed":false}]}
+]`
+
+// minUnstakeDelay is the minimum unstake delay of an entity to be considered
+// staked, allowing it to access its own storage during validation.
+const minUnstakeDelay = 86400
+
+var (
+	entryPoint, _ = abi.JSON(strings.NewReader(entryPointABI))
+
+	// UserOperationEventID is the topic of the event emitted by the EntryPoint
+	// for every executed user operation.
+	UserOperationEventID = entryPoint.Events["UserOperationEvent"].ID
+
+	errUnexpectedResult = errors.New("unexpected simulation result")
+)
+
+// entryPointOp is the ABI representation of a user operation.
+type entryPointOp struct {
+	Sender               common.Address
+	Nonce                *big.Int
+	InitCode             []byte
+	CallData             []byte
+	CallGasLimit         *big.Int
+	VerificationGasLimit *big.Int
+	PreVerificationGas   *big.Int
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+	PaymasterAndData     []byte
+	Signature            []byte
+}
+
+func newEntryPointOp(op *UserOperation) entryPointOp {
+	return entryPointOp{
+		Sender:               op.Sender,
+		Nonce:                bigOrZero(op.Nonce),
+		InitCode:             op.InitCode,
+		CallData:             op.CallData,
+		CallGasLimit:         bigOrZero(op.CallGasLimit),
+		VerificationGasLimit: bigOrZero(op.VerificationGasLimit),
+		PreVerificationGas:   bigOrZero(op.PreVerificationGas),
+		MaxFeePerGas:         bigOrZero(op.MaxFeePerGas),
+		MaxPriorityFeePerGas: bigOrZero(op.MaxPriorityFeePerGas),
+		PaymasterAndData:     op.PaymasterAndData,
+		Signature:            op.Signature,
+	}
+}
+
+// packUserOp returns the ABI encoding of an operation, as it appears in the
+// calldata of a bundle.
+func packUserOp(op *UserOperation) ([]byte, error) {
+	return entryPoint.Methods["simulateValidation"].Inputs.Pack(newEntryPointOp(op))
+}
+
+// packSimulateValidation returns the calldata of a simulateValidation call.
+func packSimulateValidation(op *UserOperation) ([]byte, error) {
+	return entryPoint.Pack("simulateValidation", newEntryPointOp(op))
+}
+
+// packHandleOps returns the calldata of a handleOps call bundling the given
+// operations.
+func packHandleOps(ops []*UserOperation, beneficiary common.Address) ([]byte, error) {
+	packed := make([]entryPointOp, len(ops))
+	for i, op := range ops {
+		packed[i] = newEntryPointOp(op)
+	}
+	return entryPoint.Pack("handleOps", packed, beneficiary)
+}
+
+// stakeInfo is the stake of an entity participating in an operation.
+type stakeInfo struct {
+	Stake           *big.Int
+	UnstakeDelaySec *big.Int
+}
+
+// staked returns whether the entity is staked enough to be trusted with access
+// to its own storage.
+func (s stakeInfo) staked() bool {
+	return s.Stake != nil && s.Stake.Sign() > 0 &&
+		s.UnstakeDelaySec != nil && s.UnstakeDelaySec.Cmp(big.NewInt(minUnstakeDelay)) >= 0
+}
+
+// validationResult is the outcome of a successful simulateValidation call.
+type validationResult struct {
+	ReturnInfo struct {
+		PreOpGas         *big.Int
+		Prefund          *big.Int
+		SigFailed        bool
+		ValidAfter       *big.Int
+		ValidUntil       *big.Int
+		PaymasterContext []byte
+	}
+	SenderInfo    stakeInfo
+	FactoryInfo   stakeInfo
+	PaymasterInfo stakeInfo
+}
+
+// unpackValidationResult decodes the revert data of a simulateValidation call,
+// which always reverts: with ValidationResult on success, or FailedOp otherwise.
+func unpackValidationResult(data []byte) (*validationResult, error) {
+	if len(data) < 4 {
+		return nil, errUnexpectedResult
+	}
+	var id [4]byte
+	copy(id[:], data)
+
+	abiErr, err := entryPoint.ErrorByID(id)
+	if err != nil {
+		if reason, err := abi.UnpackRevert(data); err == nil {
+			return nil, fmt.Errorf("validation reverted: %s", reason)
+		}
+		return nil, errUnexpectedResult
+	}
+	values, err := abiErr.Inputs.Unpack(data[4:])
+	if err != nil {
+		return nil, err
+	}
+	switch abiErr.Name {
+	case "ValidationResult":
+		result := new(validationResult)
+		if err := abiErr.Inputs.Copy(result, values); err != nil {
+			return nil, err
+		}
+		return result, nil
+
+	case "FailedOp":
+		return nil, fmt.Errorf("validation failed: %v", values[1])
+
+	default:
+		return nil, errUnexpectedResult
+	}
+}
+
+// UserOperationEvent is the outcome of an executed user operation.
+type UserOperationEvent struct {
+	UserOpHash    common.Hash
+	Sender        common.Address
+	Paymaster     common.Address
+	Nonce         *big.Int
+	Success       bool
+	ActualGasCost *big.Int
+	ActualGasUsed *big.Int
+}
+
+// UnpackUserOperationEvent decodes a UserOperationEvent log.
+func UnpackUserOperationEvent(log *types.Log) (*UserOperationEvent, error) {
+	if len(log.Topics) != 4 || log.Topics[0] != UserOperationEventID {
+		return nil, errors.New("not a user operation event")
+	}
+	event := &UserOperationEvent{
+		UserOpHash: log.Topics[1],
+		Sender:     common.BytesToAddress(log.Topics[2][:]),
+		Paymaster:  common.BytesToAddress(log.Topics[3][:]),
+	}
+	if err := entryPoint.UnpackIntoInterface(event, "UserOperationEvent", log.Data); err != nil {
+		return nil, err
+	}
+	return event, nil
+}
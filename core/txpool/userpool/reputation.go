@@ -0,0 +1,131 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package userpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Reputation parameters of ERC-7562, limiting the operations accepted from
+// factories and paymasters whose operations fail to get included.
+const (
+	minInclusionRateDenominator = 10        // Expected ratio of seen to included operations
+	throttlingSlack             = 10        // Excess seen operations before throttling
+	banSlack                    = 50        // Excess seen operations before banning
+	throttledEntityOps          = 4         // Operations allowed in the pool for a throttled entity
+	reputationDecayInterval     = time.Hour // Interval of the reputation decay
+	reputationDecayNumerator    = 23        // Fraction of the counters retained per interval
+	reputationDecayDenominator  = 24
+)
+
+// ReputationStatus is the standing of an entity in the pool.
+type ReputationStatus uint8
+
+const (
+	ReputationOK        ReputationStatus = iota // Operations accepted without limits
+	ReputationThrottled                         // Few operations accepted at a time
+	ReputationBanned                            // No operations accepted
+)
+
+// String implements fmt.Stringer.
+func (s ReputationStatus) String() string {
+	switch s {
+	case ReputationOK:
+		return "ok"
+	case ReputationThrottled:
+		return "throttled"
+	case ReputationBanned:
+		return "banned"
+	default:
+		return "unknown"
+	}
+}
+
+// reputationEntry counts the operations of an entity.
+type reputationEntry struct {
+	seen     uint64 // Operations seen by the pool
+	included uint64 // Operations included on chain
+}
+
+// reputation tracks the standing of the factories and paymasters, based on how
+// many of their operations made it on chain.
+//
+// Note, reputation is not thread safe, its users must hold the pool lock.
+type reputation struct {
+	entries map[common.Address]*reputationEntry
+	decayed time.Time // Time of the last decay
+}
+
+func newReputation() *reputation {
+	return &reputation{
+		entries: make(map[common.Address]*reputationEntry),
+		decayed: time.Now(),
+	}
+}
+
+func (r *reputation) entry(addr common.Address) *reputationEntry {
+	entry, ok := r.entries[addr]
+	if !ok {
+		entry = new(reputationEntry)
+		r.entries[addr] = entry
+	}
+	return entry
+}
+
+// seen records an operation of the entity entering the pool.
+func (r *reputation) seen(addr common.Address) {
+	r.entry(addr).seen++
+}
+
+// included records an operation of the entity being included on chain.
+func (r *reputation) included(addr common.Address) {
+	r.entry(addr).included++
+}
+
+// status returns the current standing of an entity.
+func (r *reputation) status(addr common.Address) ReputationStatus {
+	entry, ok := r.entries[addr]
+	if !ok {
+		return ReputationOK
+	}
+	maxSeen := entry.seen / minInclusionRateDenominator
+	switch {
+	case maxSeen > entry.included+banSlack:
+		return ReputationBanned
+	case maxSeen > entry.included+throttlingSlack:
+		return ReputationThrottled
+	default:
+		return ReputationOK
+	}
+}
+
+// decay reduces the counters of all entities for every decay interval passed,
+// forgetting the ones dropping to zero.
+func (r *reputation) decay(now time.Time) {
+	for now.Sub(r.decayed) >= reputationDecayInterval {
+		r.decayed = r.decayed.Add(reputationDecayInterval)
+		for addr, entry := range r.entries {
+			entry.seen = entry.seen * reputationDecayNumerator / reputationDecayDenominator
+			entry.included = entry.included * reputationDecayNumerator / reputationDecayDenominator
+			if entry.seen == 0 && entry.included == 0 {
+				delete(r.entries, addr)
+			}
+		}
+	}
+}
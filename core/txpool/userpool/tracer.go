@@ -0,0 +1,230 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package userpool
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// associatedSlots is the number of consecutive storage slots following a hash
+// of the sender, which are considered associated with the sender.
+const associatedSlots = 128
+
+// bannedOpcodes are the opcodes entities may not use during validation, as
+// their results may differ between simulation and inclusion (ERC-7562 OP-011).
+var bannedOpcodes = map[vm.OpCode]bool{
+	vm.GASPRICE:     true,
+	vm.GASLIMIT:     true,
+	vm.DIFFICULTY:   true,
+	vm.TIMESTAMP:    true,
+	vm.BASEFEE:      true,
+	vm.BLOCKHASH:    true,
+	vm.NUMBER:       true,
+	vm.SELFBALANCE:  true,
+	vm.BALANCE:      true,
+	vm.ORIGIN:       true,
+	vm.CREATE:       true,
+	vm.COINBASE:     true,
+	vm.SELFDESTRUCT: true,
+	vm.BLOBHASH:     true,
+}
+
+// storageAccess is a storage slot accessed by an entity during validation.
+type storageAccess struct {
+	entity  common.Address // Entity in whose scope the access happened
+	address common.Address // Contract whose storage was accessed
+	slot    common.Hash
+}
+
+// validationTracer enforces the ERC-7562 validation rules on the entities of a
+// user operation while simulating its validation. Opcode rules are checked as
+// the execution progresses, whereas storage accesses are collected and checked
+// afterwards, as they depend on the stake of the entities.
+type validationTracer struct {
+	sender   common.Address
+	entities map[common.Address]bool // Factory, sender and paymaster of the operation
+
+	frames   []common.Address                   // Entity in scope of each call frame, zero if none
+	lastOp   vm.OpCode                          // Previous opcode executed in an entity's scope
+	creates  map[common.Address]int             // Number of CREATE2 executed by each entity
+	hashes   map[common.Hash]bool               // Hashes of preimages starting with the sender
+	accesses []storageAccess                    // Storage accessed by the entities
+	errs     []error                            // Opcode rule violations
+	seen     map[common.Address]map[string]bool // Violations already reported, to avoid duplicates
+}
+
+func newValidationTracer(op *UserOperation) *validationTracer {
+	t := &validationTracer{
+		sender:   op.Sender,
+		entities: map[common.Address]bool{op.Sender: true},
+		creates:  make(map[common.Address]int),
+		hashes:   make(map[common.Hash]bool),
+		seen:     make(map[common.Address]map[string]bool),
+	}
+	if factory := op.Factory(); factory != (common.Address{}) {
+		t.entities[factory] = true
+	}
+	if paymaster := op.Paymaster(); paymaster != (common.Address{}) {
+		t.entities[paymaster] = true
+	}
+	return t
+}
+
+// violation records a rule violation of an entity, once per rule.
+func (t *validationTracer) violation(entity common.Address, rule string, args ...interface{}) {
+	if t.seen[entity] == nil {
+		t.seen[entity] = make(map[string]bool)
+	}
+	msg := fmt.Sprintf(rule, args...)
+	if t.seen[entity][msg] {
+		return
+	}
+	t.seen[entity][msg] = true
+	t.errs = append(t.errs, fmt.Errorf("entity %v: %s", entity, msg))
+}
+
+// scope returns the entity in whose scope the current frame executes.
+func (t *validationTracer) scope() common.Address {
+	if len(t.frames) == 0 {
+		return common.Address{}
+	}
+	return t.frames[len(t.frames)-1]
+}
+
+// enter pushes a new call frame, which stays in the scope of the caller unless
+// the callee is an entity itself.
+func (t *validationTracer) enter(to common.Address) {
+	if t.entities[to] {
+		t.frames = append(t.frames, to)
+		return
+	}
+	t.frames = append(t.frames, t.scope())
+}
+
+func (t *validationTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (t *validationTracer) CaptureTxEnd(restGas uint64) {}
+
+func (t *validationTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.enter(to)
+}
+
+func (t *validationTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (t *validationTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	// Delegate calls execute in the caller's context, keep its scope
+	if typ == vm.DELEGATECALL || typ == vm.CALLCODE {
+		t.frames = append(t.frames, t.scope())
+		return
+	}
+	t.enter(to)
+}
+
+func (t *validationTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	t.frames = t.frames[:len(t.frames)-1]
+}
+
+func (t *validationTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	entity := t.scope()
+	if entity == (common.Address{}) {
+		return // EntryPoint code is trusted
+	}
+	defer func() { t.lastOp = op }()
+
+	// GAS is only allowed right before a call (OP-012)
+	if t.lastOp == vm.GAS {
+		switch op {
+		case vm.CALL, vm.DELEGATECALL, vm.CALLCODE, vm.STATICCALL:
+		default:
+			t.violation(entity, "opcode GAS not followed by a call")
+		}
+	}
+	if bannedOpcodes[op] {
+		t.violation(entity, "banned opcode %v", op)
+		return
+	}
+	stack := scope.Stack
+	switch op {
+	case vm.CREATE2:
+		// Only the factory may deploy, and only the sender (OP-031)
+		if t.creates[entity]++; entity == t.sender || t.creates[entity] > 1 {
+			t.violation(entity, "opcode CREATE2 used more than once or outside the factory")
+		}
+
+	case vm.SLOAD, vm.SSTORE:
+		slot := common.Hash(stack.Back(0).Bytes32())
+		t.accesses = append(t.accesses, storageAccess{entity: entity, address: scope.Contract.Address(), slot: slot})
+
+	case vm.KECCAK256:
+		// Track the hashes of the sender address, to recognize associated slots.
+		// The memory isn't expanded yet, skip hashes reaching past its end.
+		offset, size := stack.Back(0), stack.Back(1)
+		if !offset.IsUint64() || !size.IsUint64() || size.Uint64() < 32 {
+			return
+		}
+		if length := uint64(scope.Memory.Len()); offset.Uint64() > length || size.Uint64() > length-offset.Uint64() {
+			return
+		}
+		data := scope.Memory.GetCopy(int64(offset.Uint64()), int64(size.Uint64()))
+		if bytes.Equal(data[:32], common.LeftPadBytes(t.sender[:], 32)) {
+			t.hashes[crypto.Keccak256Hash(data)] = true
+		}
+	}
+}
+
+func (t *validationTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// associated returns whether a storage slot is associated with the sender, that
+// is, whether it is the sender address itself or within a short range after a
+// hash of a preimage starting with it.
+func (t *validationTracer) associated(slot common.Hash) bool {
+	if bytes.Equal(slot[:], common.LeftPadBytes(t.sender[:], 32)) {
+		return true
+	}
+	value := slot.Big()
+	for hash := range t.hashes {
+		if diff := new(big.Int).Sub(value, hash.Big()); diff.Sign() >= 0 && diff.Cmp(big.NewInt(associatedSlots)) < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns the rule violations of the simulated validation, given the
+// stake of the entities reported by the EntryPoint.
+func (t *validationTracer) check(staked map[common.Address]bool) []error {
+	for _, access := range t.accesses {
+		switch {
+		case access.address == t.sender:
+			// The account may access its own storage (STO-010)
+		case t.associated(access.slot):
+			// Any entity may access the storage associated with the sender (STO-021)
+		case access.address == access.entity && staked[access.entity]:
+			// Staked entities may access their own storage (STO-031)
+		default:
+			t.violation(access.entity, "access to storage of %v at slot %v", access.address, access.slot)
+		}
+	}
+	return t.errs
+}
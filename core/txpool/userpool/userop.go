@@ -0,0 +1,179 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package userpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Gas overheads of a user operation in a bundle, used to compute the minimum
+// pre-verification gas. The values follow the reference bundler, assuming each
+// operation is bundled on its own.
+const (
+	preVerificationFixed   = 21000 // Intrinsic cost of the bundle transaction
+	preVerificationPerOp   = 18300 // Overhead of processing an operation in handleOps
+	preVerificationPerWord = 4     // Cost of copying a word of the operation
+	preVerificationZero    = 4     // Calldata cost of a zero byte
+	preVerificationNonZero = 16    // Calldata cost of a non-zero byte
+)
+
+var (
+	errUserOpSender      = errors.New("user operation without sender")
+	errUserOpFees        = errors.New("max priority fee per gas higher than max fee per gas")
+	errUserOpInitCode    = errors.New("invalid init code")
+	errUserOpPaymaster   = errors.New("invalid paymaster")
+	errUserOpPreVerifGas = errors.New("pre-verification gas too low")
+	errUserOpGasLimit    = errors.New("user operation exceeds block gas limit")
+)
+
+// UserOperation is an ERC-4337 user operation, as defined by the v0.6 EntryPoint.
+type UserOperation struct {
+	Sender               common.Address `json:"sender"`
+	Nonce                *hexutil.Big   `json:"nonce"`
+	InitCode             hexutil.Bytes  `json:"initCode"`
+	CallData             hexutil.Bytes  `json:"callData"`
+	CallGasLimit         *hexutil.Big   `json:"callGasLimit"`
+	VerificationGasLimit *hexutil.Big   `json:"verificationGasLimit"`
+	PreVerificationGas   *hexutil.Big   `json:"preVerificationGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	PaymasterAndData     hexutil.Bytes  `json:"paymasterAndData"`
+	Signature            hexutil.Bytes  `json:"signature"`
+}
+
+// bigOrZero returns the value of a numeric field, treating missing ones as zero.
+func bigOrZero(v *hexutil.Big) *big.Int {
+	if v == nil {
+		return new(big.Int)
+	}
+	return v.ToInt()
+}
+
+// word left pads a big integer into a 32 byte ABI word.
+func word(v *big.Int) []byte {
+	return common.LeftPadBytes(v.Bytes(), 32)
+}
+
+// Hash returns the hash identifying the operation on the given entry point and
+// chain, as computed by EntryPoint.getUserOpHash.
+func (op *UserOperation) Hash(entryPoint common.Address, chainID *big.Int) common.Hash {
+	packed := make([]byte, 0, 10*32)
+	packed = append(packed, common.LeftPadBytes(op.Sender[:], 32)...)
+	packed = append(packed, word(bigOrZero(op.Nonce))...)
+	packed = append(packed, crypto.Keccak256(op.InitCode)...)
+	packed = append(packed, crypto.Keccak256(op.CallData)...)
+	packed = append(packed, word(bigOrZero(op.CallGasLimit))...)
+	packed = append(packed, word(bigOrZero(op.VerificationGasLimit))...)
+	packed = append(packed, word(bigOrZero(op.PreVerificationGas))...)
+	packed = append(packed, word(bigOrZero(op.MaxFeePerGas))...)
+	packed = append(packed, word(bigOrZero(op.MaxPriorityFeePerGas))...)
+	packed = append(packed, crypto.Keccak256(op.PaymasterAndData)...)
+
+	return crypto.Keccak256Hash(
+		crypto.Keccak256(packed),
+		common.LeftPadBytes(entryPoint[:], 32),
+		word(chainID),
+	)
+}
+
+// Factory returns the factory deploying the sender, if any.
+func (op *UserOperation) Factory() common.Address {
+	if len(op.InitCode) < common.AddressLength {
+		return common.Address{}
+	}
+	return common.BytesToAddress(op.InitCode[:common.AddressLength])
+}
+
+// Paymaster returns the paymaster sponsoring the operation, if any.
+func (op *UserOperation) Paymaster() common.Address {
+	if len(op.PaymasterAndData) < common.AddressLength {
+		return common.Address{}
+	}
+	return common.BytesToAddress(op.PaymasterAndData[:common.AddressLength])
+}
+
+// gas returns the maximum amount of gas the operation may consume in a bundle.
+func (op *UserOperation) gas() uint64 {
+	verification := bigOrZero(op.VerificationGasLimit).Uint64()
+	if op.Paymaster() != (common.Address{}) {
+		verification *= 3 // validation, postOp and postOp revert
+	}
+	return bigOrZero(op.PreVerificationGas).Uint64() + verification + bigOrZero(op.CallGasLimit).Uint64()
+}
+
+// minPreVerificationGas calculates the gas needed to cover the bundle overhead
+// of an operation, which is not measured on chain.
+func (op *UserOperation) minPreVerificationGas() (uint64, error) {
+	packed, err := packUserOp(op)
+	if err != nil {
+		return 0, err
+	}
+	gas := uint64(preVerificationFixed + preVerificationPerOp)
+	gas += uint64(len(packed)+31) / 32 * preVerificationPerWord
+	for _, b := range packed {
+		if b == 0 {
+			gas += preVerificationZero
+		} else {
+			gas += preVerificationNonZero
+		}
+	}
+	return gas, nil
+}
+
+// validateBasics checks the statically verifiable fields of an operation, given
+// the gas limit of the current block.
+func (op *UserOperation) validateBasics(gasLimit uint64) error {
+	if op.Sender == (common.Address{}) {
+		return errUserOpSender
+	}
+	for _, v := range []*hexutil.Big{op.Nonce, op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas, op.MaxFeePerGas, op.MaxPriorityFeePerGas} {
+		if v != nil && (v.ToInt().Sign() < 0 || v.ToInt().BitLen() > 256) {
+			return fmt.Errorf("invalid numeric field %v", v)
+		}
+	}
+	for _, v := range []*hexutil.Big{op.CallGasLimit, op.VerificationGasLimit, op.PreVerificationGas} {
+		if gas := bigOrZero(v); !gas.IsUint64() || gas.Uint64() > gasLimit {
+			return errUserOpGasLimit
+		}
+	}
+	if bigOrZero(op.MaxPriorityFeePerGas).Cmp(bigOrZero(op.MaxFeePerGas)) > 0 {
+		return errUserOpFees
+	}
+	if len(op.InitCode) != 0 && len(op.InitCode) < common.AddressLength {
+		return errUserOpInitCode
+	}
+	if len(op.PaymasterAndData) != 0 && len(op.PaymasterAndData) < common.AddressLength {
+		return errUserOpPaymaster
+	}
+	if op.gas() > gasLimit {
+		return fmt.Errorf("%w: have %d, limit %d", errUserOpGasLimit, op.gas(), gasLimit)
+	}
+	min, err := op.minPreVerificationGas()
+	if err != nil {
+		return err
+	}
+	if have := bigOrZero(op.PreVerificationGas).Uint64(); have < min {
+		return fmt.Errorf("%w: have %d, want %d", errUserOpPreVerifGas, have, min)
+	}
+	return nil
+}
@@ -0,0 +1,756 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package userpool implements a pool of ERC-4337 user operations, which are
+// validated by simulation and bundled into EntryPoint transactions.
+package userpool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	// maxResetDepth is the maximum number of blocks walked back on a pool reset
+	// to look for included bundles.
+	maxResetDepth = 64
+
+	// historyLimit is the number of included operations whose bundle transaction
+	// is remembered, to serve receipt lookups.
+	historyLimit = 4096
+
+	// validityMargin is the minimum number of seconds an operation must remain
+	// valid for after the current head to be accepted.
+	validityMargin = 30
+)
+
+var (
+	// ErrUserOpKnown is returned if an operation is already contained in the pool.
+	ErrUserOpKnown = errors.New("user operation already known")
+
+	// ErrUserOpUnderpriced is returned if an operation replacing another one with
+	// the same sender and nonce doesn't bump its fees enough.
+	ErrUserOpUnderpriced = errors.New("replacement user operation underpriced")
+
+	// ErrUserOpPoolFull is returned if the pool cannot accept more operations.
+	ErrUserOpPoolFull = errors.New("user operation pool is full")
+
+	// ErrUserOpSignature is returned if the account or paymaster rejected the
+	// signature of an operation.
+	ErrUserOpSignature = errors.New("invalid user operation signature")
+
+	// ErrUserOpExpired is returned if an operation is not valid long enough to
+	// be included.
+	ErrUserOpExpired = errors.New("user operation expired or expiring soon")
+
+	// ErrUserOpRules is returned if the validation of an operation violates the
+	// ERC-7562 rules.
+	ErrUserOpRules = errors.New("user operation violates validation rules")
+
+	// ErrEntityBanned is returned if the factory or paymaster of an operation is
+	// banned due to its operations failing to get included.
+	ErrEntityBanned = errors.New("entity banned")
+
+	// ErrEntityThrottled is returned if the factory or paymaster of an operation
+	// is throttled and already has the allowed number of operations pooled.
+	ErrEntityThrottled = errors.New("entity throttled")
+
+	errTxUnsupported = errors.New("user operation pool does not accept transactions")
+)
+
+// BlockChain defines the minimal set of methods needed to back a user operation
+// pool with a chain. Exists to allow mocking the live chain out of tests.
+type BlockChain interface {
+	core.ChainContext
+
+	// Config retrieves the chain's fork configuration.
+	Config() *params.ChainConfig
+
+	// CurrentBlock returns the current head of the chain.
+	CurrentBlock() *types.Header
+
+	// GetBlock retrieves a specific block, used during pool resets.
+	GetBlock(hash common.Hash, number uint64) *types.Block
+
+	// StateAt returns a state database for a given root hash (generally the head).
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// Config are the configuration parameters of the user operation pool.
+type Config struct {
+	EntryPoint common.Address // EntryPoint contract the operations are submitted to
+	Bundler    common.Address // Account signing the bundles and receiving their fees
+	MaxOps     uint64         // Maximum number of operations kept in the pool
+	BundleSize uint64         // Maximum number of operations in a single bundle
+	PriceBump  uint64         // Minimum fee bump percentage to replace an operation
+}
+
+// DefaultConfig contains the default configurations for the user operation pool.
+var DefaultConfig = Config{
+	MaxOps:     4096,
+	BundleSize: 16,
+	PriceBump:  10,
+}
+
+// sanitize checks the provided user configurations and changes anything that's
+// unreasonable or unworkable.
+func (config *Config) sanitize() Config {
+	conf := *config
+	if conf.MaxOps < 1 {
+		log.Warn("Sanitizing invalid userpool capacity", "provided", conf.MaxOps, "updated", DefaultConfig.MaxOps)
+		conf.MaxOps = DefaultConfig.MaxOps
+	}
+	if conf.BundleSize < 1 {
+		log.Warn("Sanitizing invalid userpool bundle size", "provided", conf.BundleSize, "updated", DefaultConfig.BundleSize)
+		conf.BundleSize = DefaultConfig.BundleSize
+	}
+	if conf.PriceBump < 1 {
+		log.Warn("Sanitizing invalid userpool price bump", "provided", conf.PriceBump, "updated", DefaultConfig.PriceBump)
+		conf.PriceBump = DefaultConfig.PriceBump
+	}
+	return conf
+}
+
+// SignerFn signs a bundle transaction with the bundler account.
+type SignerFn func(tx *types.Transaction) (*types.Transaction, error)
+
+// GasEstimate is the gas needed by the different phases of a user operation.
+type GasEstimate struct {
+	PreVerificationGas   uint64
+	VerificationGasLimit uint64
+	CallGasLimit         uint64
+}
+
+// opKey identifies the operations replacing each other.
+type opKey struct {
+	sender common.Address
+	nonce  common.Hash
+}
+
+// userOp is an operation tracked by the pool.
+type userOp struct {
+	op        *UserOperation
+	hash      common.Hash
+	entities  []common.Address // Factory and paymaster of the operation, if any
+	validated common.Hash      // Head the operation was last validated on top of
+}
+
+func (uop *userOp) key() opKey {
+	return opKey{sender: uop.op.Sender, nonce: common.BigToHash(bigOrZero(uop.op.Nonce))}
+}
+
+// UserPool is a pool of ERC-4337 user operations. Operations are submitted via
+// AddUserOp and validated by simulating them against the EntryPoint, enforcing
+// the ERC-7562 rules. Valid operations are bundled into a single handleOps call
+// signed by the bundler, which is offered to block production as the only pending
+// transaction of the pool. Regular transactions are never accepted.
+type UserPool struct {
+	config  Config
+	chain   BlockChain
+	sign    SignerFn
+	chainID *big.Int
+
+	head  *types.Header  // Current head of the chain
+	state *state.StateDB // Current state at the head of the chain
+
+	ops        map[common.Hash]*userOp     // All pooled operations by hash
+	keys       map[opKey]*userOp           // Pooled operations by sender and nonce
+	reputation *reputation                 // Standing of the factories and paymasters
+	bundle     *types.Transaction          // Current bundle of the pooled operations
+	bundled    map[common.Hash]*userOp     // Operations of the current bundle by hash
+	included   map[common.Hash]common.Hash // Bundle transactions of recently included operations
+	history    []common.Hash               // Included operations in order, for history eviction
+
+	txFeed event.Feed
+	lock   sync.RWMutex // Write lock required for state access, as reads fill the StateDB caches
+}
+
+// New creates a new user operation pool, signing its bundles with the given
+// function.
+func New(config Config, chain BlockChain, sign SignerFn) *UserPool {
+	config = (&config).sanitize()
+
+	return &UserPool{
+		config:     config,
+		chain:      chain,
+		sign:       sign,
+		chainID:    chain.Config().ChainID,
+		ops:        make(map[common.Hash]*userOp),
+		keys:       make(map[opKey]*userOp),
+		reputation: newReputation(),
+		bundled:    make(map[common.Hash]*userOp),
+		included:   make(map[common.Hash]common.Hash),
+	}
+}
+
+// EntryPoint returns the address of the EntryPoint contract supported by the pool.
+func (p *UserPool) EntryPoint() common.Address {
+	return p.config.EntryPoint
+}
+
+// Filter returns whether the given transaction can be consumed by the pool. The
+// pool only holds user operations, so no transaction is ever accepted.
+func (p *UserPool) Filter(tx *types.Transaction) bool {
+	return false
+}
+
+// Init sets the chain head to validate the operations against.
+func (p *UserPool) Init(gasTip *big.Int, head *types.Header) error {
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		return err
+	}
+	p.head, p.state = head, statedb
+	return nil
+}
+
+// Close terminates the user operation pool.
+func (p *UserPool) Close() error {
+	return nil
+}
+
+// Reset moves the pool to a new chain head, finishing the operations that got
+// included and revalidating the remaining ones into a new bundle. The simulations
+// run without holding the pool lock, as they are expensive.
+func (p *UserPool) Reset(oldHead, newHead *types.Header) {
+	statedb, err := p.chain.StateAt(newHead.Root)
+	if err != nil {
+		log.Error("Failed to reset user operation pool state", "err", err)
+		return
+	}
+	// Gather the transactions included in the new blocks
+	included := make(map[common.Hash]bool)
+
+	block := p.chain.GetBlock(newHead.Hash(), newHead.Number.Uint64())
+	for depth := 0; block != nil && depth < maxResetDepth; depth++ {
+		if oldHead != nil && block.Hash() == oldHead.Hash() {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			included[tx.Hash()] = true
+		}
+		if block.NumberU64() == 0 {
+			break
+		}
+		block = p.chain.GetBlock(block.ParentHash(), block.NumberU64()-1)
+	}
+	p.lock.Lock()
+
+	p.head, p.state = newHead, statedb
+	if p.bundle != nil && included[p.bundle.Hash()] {
+		for hash, uop := range p.bundled {
+			p.finish(uop, p.bundle.Hash())
+			log.Debug("User operation included", "hash", hash, "bundle", p.bundle.Hash())
+		}
+	}
+	p.reputation.decay(time.Now())
+
+	var (
+		pending = make([]*userOp, 0, len(p.ops))
+		base    = statedb.Copy()
+	)
+	for _, uop := range p.ops {
+		pending = append(pending, uop)
+	}
+	p.lock.Unlock()
+
+	invalid := make(map[*userOp]error)
+	for _, uop := range pending {
+		if err := p.validate(uop.op, newHead, base.Copy()); err != nil {
+			invalid[uop] = err
+		}
+	}
+	p.lock.Lock()
+	for _, uop := range pending {
+		// Skip the operations replaced or finished in the meantime
+		if p.ops[uop.hash] != uop {
+			continue
+		}
+		if err := invalid[uop]; err != nil {
+			log.Debug("Dropping invalidated user operation", "hash", uop.hash, "err", err)
+			p.remove(uop)
+			continue
+		}
+		uop.validated = newHead.Hash()
+	}
+	bundle := p.rebuild()
+	p.lock.Unlock()
+
+	if bundle != nil {
+		p.txFeed.Send(core.NewTxsEvent{Txs: types.Transactions{bundle}})
+	}
+}
+
+// finish removes an included operation from the pool, crediting its entities
+// and remembering the bundle it was included in.
+//
+// Note, this method assumes the pool lock is held!
+func (p *UserPool) finish(uop *userOp, tx common.Hash) {
+	p.remove(uop)
+	for _, entity := range uop.entities {
+		p.reputation.included(entity)
+	}
+	p.included[uop.hash] = tx
+	p.history = append(p.history, uop.hash)
+
+	for len(p.history) > historyLimit {
+		delete(p.included, p.history[0])
+		p.history = p.history[1:]
+	}
+}
+
+// remove deletes an operation from the pool's indices.
+//
+// Note, this method assumes the pool lock is held!
+func (p *UserPool) remove(uop *userOp) {
+	delete(p.ops, uop.hash)
+	delete(p.keys, uop.key())
+}
+
+// SetGasTip is a no-op, the fees of the bundles being set by the operations.
+func (p *UserPool) SetGasTip(tip *big.Int) {}
+
+// Has returns an indicator whether the pool has a transaction cached with the
+// given hash, that is, whether it is the current bundle.
+func (p *UserPool) Has(hash common.Hash) bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.bundle != nil && p.bundle.Hash() == hash
+}
+
+// Get returns the current bundle if it has the given hash, or nil otherwise.
+func (p *UserPool) Get(hash common.Hash) *txpool.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.bundle != nil && p.bundle.Hash() == hash {
+		return &txpool.Transaction{Tx: p.bundle}
+	}
+	return nil
+}
+
+// Add rejects all transactions, user operations being submitted via AddUserOp.
+func (p *UserPool) Add(txs []*txpool.Transaction, local bool, sync bool) []error {
+	errs := make([]error, len(txs))
+	for i := range txs {
+		errs[i] = errTxUnsupported
+	}
+	return errs
+}
+
+// AddUserOp validates an operation by simulating it on top of the current head
+// and inserts it into the pool, returning its hash.
+func (p *UserPool) AddUserOp(op *UserOperation) (common.Hash, error) {
+	p.lock.Lock()
+	hash, err := p.add(op)
+	var bundle *types.Transaction
+	if err == nil {
+		bundle = p.rebuild()
+	}
+	p.lock.Unlock()
+
+	if bundle != nil {
+		p.txFeed.Send(core.NewTxsEvent{Txs: types.Transactions{bundle}})
+	}
+	return hash, err
+}
+
+// add validates and inserts a single operation into the pool. An operation with
+// the same sender and nonce as an existing one replaces it if it bumps the fees.
+//
+// Note, this method assumes the pool lock is held!
+func (p *UserPool) add(op *UserOperation) (common.Hash, error) {
+	hash := op.Hash(p.config.EntryPoint, p.chainID)
+	if p.ops[hash] != nil {
+		return hash, ErrUserOpKnown
+	}
+	if err := op.validateBasics(p.head.GasLimit); err != nil {
+		return hash, err
+	}
+	deployed := len(p.state.GetCode(op.Sender)) > 0
+	if deployed == (len(op.InitCode) > 0) {
+		if deployed {
+			return hash, fmt.Errorf("%w: sender already deployed", errUserOpInitCode)
+		}
+		return hash, fmt.Errorf("%w: sender not deployed", errUserOpInitCode)
+	}
+	uop := &userOp{op: op, hash: hash}
+	for _, entity := range []common.Address{op.Factory(), op.Paymaster()} {
+		if entity == (common.Address{}) {
+			continue
+		}
+		switch p.reputation.status(entity) {
+		case ReputationBanned:
+			return hash, fmt.Errorf("%w: %v", ErrEntityBanned, entity)
+		case ReputationThrottled:
+			if p.pooled(entity) >= throttledEntityOps {
+				return hash, fmt.Errorf("%w: %v", ErrEntityThrottled, entity)
+			}
+		}
+		uop.entities = append(uop.entities, entity)
+	}
+	old := p.keys[uop.key()]
+	if old != nil {
+		if !bumped(old.op.MaxFeePerGas, op.MaxFeePerGas, p.config.PriceBump) ||
+			!bumped(old.op.MaxPriorityFeePerGas, op.MaxPriorityFeePerGas, p.config.PriceBump) {
+			return hash, ErrUserOpUnderpriced
+		}
+	} else if uint64(len(p.ops)) >= p.config.MaxOps {
+		return hash, ErrUserOpPoolFull
+	}
+	if err := p.validate(op, p.head, p.state.Copy()); err != nil {
+		return hash, err
+	}
+	uop.validated = p.head.Hash()
+	if old != nil {
+		p.remove(old)
+	}
+	p.ops[hash] = uop
+	p.keys[uop.key()] = uop
+	for _, entity := range uop.entities {
+		p.reputation.seen(entity)
+	}
+	log.Debug("Pooled user operation", "hash", hash, "sender", op.Sender, "nonce", bigOrZero(op.Nonce))
+	return hash, nil
+}
+
+// pooled returns the number of pooled operations involving an entity.
+//
+// Note, this method assumes the pool lock is held!
+func (p *UserPool) pooled(entity common.Address) int {
+	var count int
+	for _, uop := range p.ops {
+		for _, e := range uop.entities {
+			if e == entity {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// bumped returns whether a fee is at least the given percentage above the old one.
+func bumped(old, fee *hexutil.Big, bump uint64) bool {
+	threshold := new(big.Int).Mul(bigOrZero(old), big.NewInt(int64(100+bump)))
+	threshold.Div(threshold, big.NewInt(100))
+	return bigOrZero(fee).Cmp(threshold) >= 0
+}
+
+// newEVM creates an EVM on top of the given head and state for simulating
+// operations.
+func (p *UserPool) newEVM(head *types.Header, statedb *state.StateDB, tracer vm.EVMLogger) *vm.EVM {
+	blockCtx := core.NewEVMBlockContext(head, p.chain, &p.config.Bundler)
+	return vm.NewEVM(blockCtx, vm.TxContext{GasPrice: new(big.Int)}, statedb, p.chain.Config(), vm.Config{Tracer: tracer, NoBaseFee: true})
+}
+
+// simulate runs the validation of an operation on top of the given head and
+// state, enforcing the ERC-7562 rules on its entities. The state is modified, so
+// it must be a copy owned by the caller.
+func (p *UserPool) simulate(op *UserOperation, head *types.Header, statedb *state.StateDB) (*validationResult, error) {
+	data, err := packSimulateValidation(op)
+	if err != nil {
+		return nil, err
+	}
+	tracer := newValidationTracer(op)
+	evm := p.newEVM(head, statedb, tracer)
+
+	ret, _, err := evm.Call(vm.AccountRef(common.Address{}), p.config.EntryPoint, data, head.GasLimit, new(big.Int))
+	if !errors.Is(err, vm.ErrExecutionReverted) {
+		if err == nil {
+			err = errUnexpectedResult
+		}
+		return nil, fmt.Errorf("validation simulation failed: %w", err)
+	}
+	result, err := unpackValidationResult(ret)
+	if err != nil {
+		return nil, err
+	}
+	staked := map[common.Address]bool{
+		op.Sender:      result.SenderInfo.staked(),
+		op.Factory():   result.FactoryInfo.staked(),
+		op.Paymaster(): result.PaymasterInfo.staked(),
+	}
+	if errs := tracer.check(staked); len(errs) > 0 {
+		return nil, fmt.Errorf("%w: %v", ErrUserOpRules, errs[0])
+	}
+	return result, nil
+}
+
+// validate simulates the validation of an operation, additionally checking the
+// signature and validity period reported by the EntryPoint. The state must be a
+// copy owned by the caller.
+func (p *UserPool) validate(op *UserOperation, head *types.Header, statedb *state.StateDB) error {
+	result, err := p.simulate(op, head, statedb)
+	if err != nil {
+		return err
+	}
+	if result.ReturnInfo.SigFailed {
+		return ErrUserOpSignature
+	}
+	if after := result.ReturnInfo.ValidAfter; after != nil && after.Cmp(new(big.Int).SetUint64(head.Time)) > 0 {
+		return fmt.Errorf("%w: valid after %v", ErrUserOpExpired, after)
+	}
+	if until := result.ReturnInfo.ValidUntil; until != nil && until.Sign() > 0 && until.Cmp(new(big.Int).SetUint64(head.Time+validityMargin)) < 0 {
+		return fmt.Errorf("%w: valid until %v", ErrUserOpExpired, until)
+	}
+	return nil
+}
+
+// rebuild bundles the best paying pooled operations into a new handleOps
+// transaction, returning it if it changed. Operations not yet revalidated on top
+// of the current head are left out until a pool reset is done with them.
+//
+// Note, this method assumes the pool lock is held!
+func (p *UserPool) rebuild() *types.Transaction {
+	var old common.Hash
+	if p.bundle != nil {
+		old = p.bundle.Hash()
+	}
+	p.bundle, p.bundled = nil, make(map[common.Hash]*userOp)
+
+	candidates := make([]*userOp, 0, len(p.ops))
+	for _, uop := range p.ops {
+		candidates = append(candidates, uop)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if cmp := bigOrZero(candidates[i].op.MaxPriorityFeePerGas).Cmp(bigOrZero(candidates[j].op.MaxPriorityFeePerGas)); cmp != 0 {
+			return cmp > 0
+		}
+		return bytes.Compare(candidates[i].hash[:], candidates[j].hash[:]) < 0
+	})
+	var (
+		ops     []*UserOperation
+		gas     uint64
+		senders = make(map[common.Address]bool)
+		tipCap  = new(big.Int).SetUint64(math.MaxUint64)
+		feeCap  = new(big.Int).SetUint64(math.MaxUint64)
+	)
+	for _, uop := range candidates {
+		if uint64(len(ops)) >= p.config.BundleSize {
+			break
+		}
+		// A bundle may contain a single operation per sender
+		if senders[uop.op.Sender] || gas+uop.op.gas() > p.head.GasLimit {
+			continue
+		}
+		if uop.validated != p.head.Hash() {
+			continue
+		}
+		ops = append(ops, uop.op)
+		gas += uop.op.gas()
+		senders[uop.op.Sender] = true
+		p.bundled[uop.hash] = uop
+
+		if tip := bigOrZero(uop.op.MaxPriorityFeePerGas); tip.Cmp(tipCap) < 0 {
+			tipCap = tip
+		}
+		if fee := bigOrZero(uop.op.MaxFeePerGas); fee.Cmp(feeCap) < 0 {
+			feeCap = fee
+		}
+	}
+	if len(ops) == 0 || p.sign == nil {
+		return nil
+	}
+	data, err := packHandleOps(ops, p.config.Bundler)
+	if err != nil {
+		log.Error("Failed to pack user operation bundle", "err", err)
+		return nil
+	}
+	var (
+		nonce = p.state.GetNonce(p.config.Bundler)
+		to    = p.config.EntryPoint
+		tx    *types.Transaction
+	)
+	if p.head.BaseFee != nil {
+		tx = types.NewTx(&types.DynamicFeeTx{ChainID: p.chainID, Nonce: nonce, GasTipCap: tipCap, GasFeeCap: feeCap, Gas: gas, To: &to, Data: data})
+	} else {
+		tx = types.NewTx(&types.LegacyTx{Nonce: nonce, GasPrice: feeCap, Gas: gas, To: &to, Data: data})
+	}
+	signed, err := p.sign(tx)
+	if err != nil {
+		log.Error("Failed to sign user operation bundle", "err", err)
+		return nil
+	}
+	p.bundle = signed
+	if signed.Hash() == old {
+		return nil
+	}
+	log.Debug("Bundled user operations", "hash", signed.Hash(), "ops", len(ops), "gas", gas)
+	return signed
+}
+
+// Pending retrieves the current bundle, the only transaction offered by the pool.
+func (p *UserPool) Pending(enforceTips bool) map[common.Address][]*types.Transaction {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.bundle == nil {
+		return map[common.Address][]*types.Transaction{}
+	}
+	return map[common.Address][]*types.Transaction{p.config.Bundler: {p.bundle}}
+}
+
+// SubscribeTransactions registers a subscription of NewTxsEvent, fired for every
+// new bundle.
+func (p *UserPool) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription {
+	return p.txFeed.Subscribe(ch)
+}
+
+// Nonce returns the next nonce of an account, with the current bundle applied
+// on top for the bundler.
+func (p *UserPool) Nonce(addr common.Address) uint64 {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	nonce := p.state.GetNonce(addr)
+	if p.bundle != nil && addr == p.config.Bundler {
+		nonce++
+	}
+	return nonce
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions.
+func (p *UserPool) Stats() (int, int) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.bundle == nil {
+		return 0, 0
+	}
+	return 1, 0
+}
+
+// Content retrieves the data content of the pool, namely the current bundle.
+func (p *UserPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
+	return p.Pending(false), map[common.Address][]*types.Transaction{}
+}
+
+// ContentFrom retrieves the data content of the pool for an address, namely the
+// current bundle if the address is the bundler.
+func (p *UserPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	return p.Pending(false)[addr], []*types.Transaction{}
+}
+
+// Locals retrieves the accounts currently considered local by the pool.
+func (p *UserPool) Locals() []common.Address {
+	return []common.Address{}
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by its hash.
+func (p *UserPool) Status(hash common.Hash) txpool.TxStatus {
+	if p.Has(hash) {
+		return txpool.TxStatusPending
+	}
+	return txpool.TxStatusUnknown
+}
+
+// UserOp returns a pooled operation by hash, or nil if unknown.
+func (p *UserPool) UserOp(hash common.Hash) *UserOperation {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if uop := p.ops[hash]; uop != nil {
+		return uop.op
+	}
+	return nil
+}
+
+// Included returns the hash of the bundle transaction a recently included
+// operation was part of.
+func (p *UserPool) Included(hash common.Hash) (common.Hash, bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	tx, ok := p.included[hash]
+	return tx, ok
+}
+
+// Reputation returns the standing of a factory or paymaster.
+func (p *UserPool) Reputation(entity common.Address) ReputationStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.reputation.status(entity)
+}
+
+// EstimateGas estimates the gas limits of an operation. The pre-verification
+// gas is derived from the size of the operation, the verification gas limit
+// from a simulated validation with fees zeroed, and the call gas limit from
+// executing the call data from the EntryPoint.
+func (p *UserPool) EstimateGas(op *UserOperation) (*GasEstimate, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	pvg, err := op.minPreVerificationGas()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		sim   = *op
+		limit = (*hexutil.Big)(new(big.Int).SetUint64(p.head.GasLimit / 2))
+		zero  = new(hexutil.Big)
+	)
+	sim.VerificationGasLimit, sim.CallGasLimit, sim.PreVerificationGas = limit, zero, zero
+	sim.MaxFeePerGas, sim.MaxPriorityFeePerGas = zero, zero
+
+	// Signature failures are ignored, allowing estimation with dummy signatures
+	result, err := p.simulate(&sim, p.head, p.state.Copy())
+	if err != nil {
+		return nil, err
+	}
+	verification := result.ReturnInfo.PreOpGas.Uint64()
+
+	// Deploy the sender if needed, then execute the call from the EntryPoint
+	statedb := p.state.Copy()
+	evm := p.newEVM(p.head, statedb, nil)
+	if factory := op.Factory(); factory != (common.Address{}) && len(statedb.GetCode(op.Sender)) == 0 {
+		if _, _, err := evm.Call(vm.AccountRef(p.config.EntryPoint), factory, op.InitCode[common.AddressLength:], p.head.GasLimit, new(big.Int)); err != nil {
+			return nil, fmt.Errorf("sender deployment failed: %w", err)
+		}
+	}
+	_, left, err := evm.Call(vm.AccountRef(p.config.EntryPoint), op.Sender, op.CallData, p.head.GasLimit, new(big.Int))
+	if err != nil {
+		return nil, fmt.Errorf("call execution failed: %w", err)
+	}
+	call := p.head.GasLimit - left
+
+	return &GasEstimate{
+		PreVerificationGas:   pvg,
+		VerificationGasLimit: verification + verification/10,
+		CallGasLimit:         call + call/10,
+	}, nil
+}
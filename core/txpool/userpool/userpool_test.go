@@ -0,0 +1,384 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package userpool
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// testBlockChain is a mock of the live chain for testing the pool.
+type testBlockChain struct {
+	config  *params.ChainConfig
+	statedb *state.StateDB
+	blocks  map[common.Hash]*types.Block
+	head    *types.Header
+}
+
+func newTestBlockChain() *testBlockChain {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	genesis := types.NewBlockWithHeader(&types.Header{Number: new(big.Int), GasLimit: 30_000_000, Difficulty: new(big.Int)})
+
+	return &testBlockChain{
+		config:  params.TestChainConfig,
+		statedb: statedb,
+		blocks:  map[common.Hash]*types.Block{genesis.Hash(): genesis},
+		head:    genesis.Header(),
+	}
+}
+
+func (bc *testBlockChain) Config() *params.ChainConfig { return bc.config }
+func (bc *testBlockChain) CurrentBlock() *types.Header { return bc.head }
+func (bc *testBlockChain) Engine() consensus.Engine    { return nil }
+
+func (bc *testBlockChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := bc.blocks[hash]; block != nil {
+		return block.Header()
+	}
+	return nil
+}
+
+func (bc *testBlockChain) GetBlock(hash common.Hash, number uint64) *types.Block {
+	return bc.blocks[hash]
+}
+
+func (bc *testBlockChain) StateAt(common.Hash) (*state.StateDB, error) {
+	return bc.statedb, nil
+}
+
+// mine appends a new block with the given transactions to the chain, returning
+// the old and new heads.
+func (bc *testBlockChain) mine(txs ...*types.Transaction) (*types.Header, *types.Header) {
+	header := &types.Header{
+		ParentHash: bc.head.Hash(),
+		Number:     new(big.Int).Add(bc.head.Number, common.Big1),
+		GasLimit:   bc.head.GasLimit,
+		Difficulty: new(big.Int),
+	}
+	block := types.NewBlock(header, txs, nil, nil, trie.NewStackTrie(nil))
+	bc.blocks[block.Hash()] = block
+
+	old := bc.head
+	bc.head = block.Header()
+	return old, bc.head
+}
+
+// revertCode returns the code of a contract reverting with the given data on
+// every call.
+func revertCode(data []byte) []byte {
+	size := []byte{byte(len(data) >> 8), byte(len(data))}
+	code := []byte{byte(vm.PUSH2), size[0], size[1], byte(vm.PUSH1), 14, byte(vm.PUSH1), 0, byte(vm.CODECOPY)}
+	code = append(code, byte(vm.PUSH2), size[0], size[1], byte(vm.PUSH1), 0, byte(vm.REVERT))
+	return append(code, data...)
+}
+
+// validationResultData returns the revert data of a successful simulateValidation.
+func validationResultData(t *testing.T, sigFailed bool) []byte {
+	t.Helper()
+
+	abiErr := entryPoint.Errors["ValidationResult"]
+	returnInfo := struct {
+		PreOpGas         *big.Int
+		Prefund          *big.Int
+		SigFailed        bool
+		ValidAfter       *big.Int
+		ValidUntil       *big.Int
+		PaymasterContext []byte
+	}{big.NewInt(50000), big.NewInt(1), sigFailed, new(big.Int), new(big.Int), nil}
+	stake := stakeInfo{new(big.Int), new(big.Int)}
+
+	data, err := abiErr.Inputs.Pack(returnInfo, stake, stake, stake)
+	if err != nil {
+		t.Fatalf("failed to pack validation result: %v", err)
+	}
+	return append(append([]byte{}, abiErr.ID[:4]...), data...)
+}
+
+func testUserOp(t *testing.T, sender common.Address, nonce int64, tip int64) *UserOperation {
+	t.Helper()
+
+	op := &UserOperation{
+		Sender:               sender,
+		Nonce:                (*hexutil.Big)(big.NewInt(nonce)),
+		CallData:             []byte{0x01},
+		CallGasLimit:         (*hexutil.Big)(big.NewInt(50000)),
+		VerificationGasLimit: (*hexutil.Big)(big.NewInt(100000)),
+		PreVerificationGas:   new(hexutil.Big),
+		MaxFeePerGas:         (*hexutil.Big)(big.NewInt(100)),
+		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(tip)),
+		Signature:            []byte{0x02},
+	}
+	// Pad the pre-verification gas, its own encoding may grow the requirement
+	pvg, err := op.minPreVerificationGas()
+	if err != nil {
+		t.Fatalf("failed to compute pre-verification gas: %v", err)
+	}
+	op.PreVerificationGas = (*hexutil.Big)(new(big.Int).SetUint64(pvg + 100))
+	return op
+}
+
+// Tests that user operations are validated, bundled and tracked until inclusion.
+func TestUserOpLifecycle(t *testing.T) {
+	var (
+		chain      = newTestBlockChain()
+		key, _     = crypto.GenerateKey()
+		bundler    = crypto.PubkeyToAddress(key.PublicKey)
+		entrypoint = common.Address{0xe0}
+		sender     = common.Address{0xaa}
+		signer     = types.LatestSigner(chain.config)
+	)
+	chain.statedb.SetCode(entrypoint, revertCode(validationResultData(t, false)))
+	chain.statedb.SetCode(sender, []byte{0x00})
+
+	sign := func(tx *types.Transaction) (*types.Transaction, error) {
+		return types.SignTx(tx, signer, key)
+	}
+	pool := New(Config{EntryPoint: entrypoint, Bundler: bundler}, chain, sign)
+	if err := pool.Init(new(big.Int), chain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	// Ensure malformed operations are rejected without simulation
+	if _, err := pool.AddUserOp(&UserOperation{}); !errors.Is(err, errUserOpSender) {
+		t.Errorf("senderless operation error mismatch: have %v, want %v", err, errUserOpSender)
+	}
+	undeployed := testUserOp(t, common.Address{0xbb}, 0, 1)
+	if _, err := pool.AddUserOp(undeployed); !errors.Is(err, errUserOpInitCode) {
+		t.Errorf("undeployed sender error mismatch: have %v, want %v", err, errUserOpInitCode)
+	}
+	// Add an operation, replace it and ensure a bundle is built
+	op := testUserOp(t, sender, 0, 1)
+	if _, err := pool.AddUserOp(op); err != nil {
+		t.Fatalf("failed to add operation: %v", err)
+	}
+	if _, err := pool.AddUserOp(testUserOp(t, sender, 0, 2)); !errors.Is(err, ErrUserOpUnderpriced) {
+		t.Errorf("underpriced replacement error mismatch: have %v, want %v", err, ErrUserOpUnderpriced)
+	}
+	replacement := testUserOp(t, sender, 0, 1)
+	replacement.MaxFeePerGas = (*hexutil.Big)(big.NewInt(200))
+	replacement.MaxPriorityFeePerGas = (*hexutil.Big)(big.NewInt(10))
+	hash, err := pool.AddUserOp(replacement)
+	if err != nil {
+		t.Fatalf("failed to replace operation: %v", err)
+	}
+	if pool.UserOp(op.Hash(entrypoint, chain.config.ChainID)) != nil {
+		t.Errorf("replaced operation still pooled")
+	}
+	pending := pool.Pending(true)[bundler]
+	if len(pending) != 1 {
+		t.Fatalf("bundle count mismatch: have %d, want 1", len(pending))
+	}
+	bundle := pending[0]
+	if to := bundle.To(); to == nil || *to != entrypoint {
+		t.Errorf("bundle recipient mismatch: have %v, want %v", to, entrypoint)
+	}
+	if from, _ := types.Sender(signer, bundle); from != bundler {
+		t.Errorf("bundle signer mismatch: have %v, want %v", from, bundler)
+	}
+	if !pool.Has(bundle.Hash()) || pool.Nonce(bundler) != 1 {
+		t.Errorf("bundle not tracked by the pool")
+	}
+	// Include the bundle and ensure the operation is finished
+	pool.Reset(chain.mine(bundle))
+
+	if pool.UserOp(hash) != nil {
+		t.Errorf("included operation still pooled")
+	}
+	if tx, ok := pool.Included(hash); !ok || tx != bundle.Hash() {
+		t.Errorf("included bundle mismatch: have %v, want %v", tx, bundle.Hash())
+	}
+	if pending := pool.Pending(true); len(pending) != 0 {
+		t.Errorf("bundle still pending after inclusion")
+	}
+}
+
+// Tests that operations rejected by the account are not accepted.
+func TestUserOpSignatureFailure(t *testing.T) {
+	var (
+		chain      = newTestBlockChain()
+		entrypoint = common.Address{0xe0}
+		sender     = common.Address{0xaa}
+	)
+	chain.statedb.SetCode(entrypoint, revertCode(validationResultData(t, true)))
+	chain.statedb.SetCode(sender, []byte{0x00})
+
+	pool := New(Config{EntryPoint: entrypoint}, chain, nil)
+	if err := pool.Init(new(big.Int), chain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	if _, err := pool.AddUserOp(testUserOp(t, sender, 0, 1)); !errors.Is(err, ErrUserOpSignature) {
+		t.Errorf("error mismatch: have %v, want %v", err, ErrUserOpSignature)
+	}
+}
+
+// Tests that only new operations are validated on insertion, the pooled ones
+// being revalidated once per new head.
+func TestUserOpRevalidation(t *testing.T) {
+	var (
+		chain      = newTestBlockChain()
+		key, _     = crypto.GenerateKey()
+		bundler    = crypto.PubkeyToAddress(key.PublicKey)
+		entrypoint = common.Address{0xe0}
+		signer     = types.LatestSigner(chain.config)
+	)
+	chain.statedb.SetCode(entrypoint, revertCode(validationResultData(t, false)))
+	chain.statedb.SetCode(common.Address{0xaa}, []byte{0x00})
+	chain.statedb.SetCode(common.Address{0xbb}, []byte{0x00})
+
+	sign := func(tx *types.Transaction) (*types.Transaction, error) {
+		return types.SignTx(tx, signer, key)
+	}
+	pool := New(Config{EntryPoint: entrypoint, Bundler: bundler}, chain, sign)
+	if err := pool.Init(new(big.Int), chain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	hash, err := pool.AddUserOp(testUserOp(t, common.Address{0xaa}, 0, 1))
+	if err != nil {
+		t.Fatalf("failed to add operation: %v", err)
+	}
+	// Invalidate all operations and ensure the pooled one is kept until a reset
+	chain.statedb.SetCode(entrypoint, revertCode(validationResultData(t, true)))
+	if _, err := pool.AddUserOp(testUserOp(t, common.Address{0xbb}, 0, 2)); !errors.Is(err, ErrUserOpSignature) {
+		t.Fatalf("error mismatch: have %v, want %v", err, ErrUserOpSignature)
+	}
+	if pool.UserOp(hash) == nil || len(pool.Pending(true)[bundler]) != 1 {
+		t.Fatalf("pooled operation revalidated on insertion")
+	}
+	pool.Reset(chain.mine())
+
+	if pool.UserOp(hash) != nil {
+		t.Errorf("invalidated operation still pooled")
+	}
+	if pending := pool.Pending(true); len(pending) != 0 {
+		t.Errorf("bundle still pending after invalidation")
+	}
+}
+
+// Tests that the state backed queries of the pool can be used concurrently.
+func TestUserOpConcurrentQueries(t *testing.T) {
+	var (
+		chain      = newTestBlockChain()
+		entrypoint = common.Address{0xe0}
+		sender     = common.Address{0xaa}
+	)
+	chain.statedb.SetCode(entrypoint, revertCode(validationResultData(t, false)))
+	chain.statedb.SetCode(sender, []byte{0x00})
+
+	pool := New(Config{EntryPoint: entrypoint}, chain, nil)
+	if err := pool.Init(new(big.Int), chain.CurrentBlock()); err != nil {
+		t.Fatalf("failed to init pool: %v", err)
+	}
+	op := testUserOp(t, sender, 0, 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			pool.Nonce(common.Address{byte(i)})
+		}(i)
+		go func() {
+			defer wg.Done()
+			pool.EstimateGas(op)
+		}()
+	}
+	wg.Wait()
+}
+
+// Tests that the validation tracer enforces the opcode and storage rules.
+func TestValidationTracer(t *testing.T) {
+	var (
+		sender = common.Address{0xaa}
+		other  = common.Address{0xbb}
+	)
+	tests := []struct {
+		code []byte
+		fail bool
+	}{
+		// Reading own storage is fine
+		{[]byte{byte(vm.PUSH1), 0, byte(vm.SLOAD), byte(vm.STOP)}, false},
+		// Banned opcodes are not
+		{[]byte{byte(vm.TIMESTAMP), byte(vm.STOP)}, true},
+		// GAS must be followed by a call
+		{[]byte{byte(vm.GAS), byte(vm.POP), byte(vm.STOP)}, true},
+		// Hashing memory which isn't expanded yet is fine
+		{[]byte{byte(vm.PUSH1), 64, byte(vm.PUSH1), 0, byte(vm.KECCAK256), byte(vm.POP), byte(vm.STOP)}, false},
+		{[]byte{byte(vm.PUSH1), 64, byte(vm.PUSH1), 0, byte(vm.MSTORE), byte(vm.PUSH1), 64, byte(vm.PUSH1), 16, byte(vm.KECCAK256), byte(vm.POP), byte(vm.STOP)}, false},
+		// Reading unassociated storage of another contract is not allowed
+		{[]byte{
+			byte(vm.PUSH1), 0, byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1), byte(vm.DUP1),
+			byte(vm.PUSH20), 0xbb, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0,
+			byte(vm.PUSH2), 0xff, 0xff, byte(vm.CALL), byte(vm.STOP),
+		}, true},
+	}
+	for i, tt := range tests {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+		statedb.SetCode(sender, tt.code)
+		statedb.SetCode(other, []byte{byte(vm.PUSH1), 1, byte(vm.SLOAD), byte(vm.STOP)})
+
+		tracer := newValidationTracer(&UserOperation{Sender: sender})
+		evm := vm.NewEVM(vm.BlockContext{CanTransfer: core.CanTransfer, Transfer: core.Transfer, BlockNumber: new(big.Int), Difficulty: new(big.Int)}, vm.TxContext{}, statedb, params.TestChainConfig, vm.Config{Tracer: tracer})
+		if _, _, err := evm.Call(vm.AccountRef(common.Address{}), sender, nil, 1_000_000, new(big.Int)); err != nil {
+			t.Fatalf("test %d: execution failed: %v", i, err)
+		}
+		if errs := tracer.check(nil); (len(errs) > 0) != tt.fail {
+			t.Errorf("test %d: violations mismatch: have %v, want failure %v", i, errs, tt.fail)
+		}
+	}
+}
+
+// Tests the reputation thresholds and their decay over time.
+func TestReputation(t *testing.T) {
+	var (
+		rep    = newReputation()
+		entity = common.Address{0xaa}
+	)
+	for i := 0; i < (throttlingSlack+1)*minInclusionRateDenominator; i++ {
+		rep.seen(entity)
+	}
+	if status := rep.status(entity); status != ReputationThrottled {
+		t.Errorf("status mismatch: have %v, want %v", status, ReputationThrottled)
+	}
+	for i := 0; i < (banSlack-throttlingSlack)*minInclusionRateDenominator; i++ {
+		rep.seen(entity)
+	}
+	if status := rep.status(entity); status != ReputationBanned {
+		t.Errorf("status mismatch: have %v, want %v", status, ReputationBanned)
+	}
+	rep.decay(rep.decayed.Add(48 * reputationDecayInterval))
+	if status := rep.status(entity); status != ReputationOK {
+		t.Errorf("decayed status mismatch: have %v, want %v", status, ReputationOK)
+	}
+	rep.decay(rep.decayed.Add(1000 * time.Hour))
+	if len(rep.entries) != 0 {
+		t.Errorf("decayed entries not forgotten: %d left", len(rep.entries))
+	}
+}
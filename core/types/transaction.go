@@ -53,6 +53,8 @@ type Transaction struct {
 	inner TxData    // Consensus contents of a transaction
 	time  time.Time // Time first seen locally (spam avoidance)
 
+	conditional *TransactionConditional // Local inclusion preconditions, if any
+
 	// caches
 	hash atomic.Value
 	size atomic.Value
@@ -394,6 +396,31 @@ func (tx *Transaction) BlobGasFeeCapIntCmp(other *big.Int) int {
 	return tx.inner.blobGasFeeCap().Cmp(other)
 }
 
+// Time returns the time when the transaction was first seen on the network. It
+// is a heuristic to prefer mining older txs vs new all other things equal.
+func (tx *Transaction) Time() time.Time {
+	return tx.time
+}
+
+// SetTime sets the decoding time of a transaction. This is used by tests to set
+// arbitrary times and by persistent transaction pools when loading old txs from
+// disk.
+func (tx *Transaction) SetTime(t time.Time) {
+	tx.time = t
+}
+
+// Conditional returns the inclusion preconditions of the transaction, or nil if
+// it was submitted unconditionally.
+func (tx *Transaction) Conditional() *TransactionConditional {
+	return tx.conditional
+}
+
+// SetConditional attaches inclusion preconditions to the transaction. They are
+// local metadata and are not part of the transaction's encoding.
+func (tx *Transaction) SetConditional(cond *TransactionConditional) {
+	tx.conditional = cond
+}
+
 // Hash returns the transaction hash.
 func (tx *Transaction) Hash() common.Hash {
 	if hash := tx.hash.Load(); hash != nil {
@@ -0,0 +1,189 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var (
+	// ErrConditionalBlockNumber is returned if the block number a conditional
+	// transaction is checked against is outside of its allowed range.
+	ErrConditionalBlockNumber = errors.New("block number out of conditional range")
+
+	// ErrConditionalTimestamp is returned if the block timestamp a conditional
+	// transaction is checked against is outside of its allowed range.
+	ErrConditionalTimestamp = errors.New("timestamp out of conditional range")
+
+	// ErrConditionalExpired is returned if a conditional transaction is past its
+	// expiry time.
+	ErrConditionalExpired = errors.New("conditional transaction expired")
+
+	// ErrConditionalStorage is returned if the storage of one of the known accounts
+	// of a conditional transaction doesn't match the expected one.
+	ErrConditionalStorage = errors.New("known account storage mismatch")
+)
+
+// KnownAccount is the expected storage of an account, specified either by the
+// root of its storage trie or by the values of individual storage slots.
+type KnownAccount struct {
+	StorageRoot  *common.Hash
+	StorageSlots map[common.Hash]common.Hash
+}
+
+// MarshalJSON encodes a known account as either a storage root hash or a map of
+// storage slots to their values.
+func (a KnownAccount) MarshalJSON() ([]byte, error) {
+	if a.StorageRoot != nil {
+		return json.Marshal(a.StorageRoot)
+	}
+	return json.Marshal(a.StorageSlots)
+}
+
+// UnmarshalJSON decodes a known account from either a storage root hash or a
+// map of storage slots to their values.
+func (a *KnownAccount) UnmarshalJSON(input []byte) error {
+	if len(input) > 0 && input[0] == '"' {
+		root := new(common.Hash)
+		if err := json.Unmarshal(input, root); err != nil {
+			return err
+		}
+		a.StorageRoot, a.StorageSlots = root, nil
+		return nil
+	}
+	var slots map[common.Hash]common.Hash
+	if err := json.Unmarshal(input, &slots); err != nil {
+		return err
+	}
+	a.StorageRoot, a.StorageSlots = nil, slots
+	return nil
+}
+
+// ConditionalState is the subset of the state needed to check the known accounts
+// of a conditional transaction.
+type ConditionalState interface {
+	GetState(addr common.Address, key common.Hash) common.Hash
+	GetStorageRoot(addr common.Address) (common.Hash, error)
+}
+
+// TransactionConditional is a set of preconditions attached to a transaction at
+// submission time. The transaction may only be included in a block satisfying
+// all of them; it is dropped as soon as they can no longer be met.
+//
+// Conditions are local metadata of the transaction, they are not part of its
+// consensus encoding and are thus not propagated to other nodes.
+type TransactionConditional struct {
+	KnownAccounts  map[common.Address]KnownAccount `json:"knownAccounts,omitempty"`
+	BlockNumberMin *hexutil.Uint64                 `json:"blockNumberMin,omitempty"`
+	BlockNumberMax *hexutil.Uint64                 `json:"blockNumberMax,omitempty"`
+	TimestampMin   *hexutil.Uint64                 `json:"timestampMin,omitempty"`
+	TimestampMax   *hexutil.Uint64                 `json:"timestampMax,omitempty"`
+	Expiry         *hexutil.Uint64                 `json:"expiry,omitempty"` // Unix time after which the transaction is dropped
+}
+
+// Cost returns the number of state lookups needed to check the conditions.
+func (c *TransactionConditional) Cost() int {
+	var cost int
+	for _, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			cost++
+		} else {
+			cost += len(account.StorageSlots)
+		}
+	}
+	return cost
+}
+
+// Validate checks that the conditions are consistent, i.e. that they can be
+// satisfied by some block.
+func (c *TransactionConditional) Validate() error {
+	if c.BlockNumberMin != nil && c.BlockNumberMax != nil && *c.BlockNumberMin > *c.BlockNumberMax {
+		return fmt.Errorf("%w: min %d > max %d", ErrConditionalBlockNumber, *c.BlockNumberMin, *c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && c.TimestampMax != nil && *c.TimestampMin > *c.TimestampMax {
+		return fmt.Errorf("%w: min %d > max %d", ErrConditionalTimestamp, *c.TimestampMin, *c.TimestampMax)
+	}
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot == nil && len(account.StorageSlots) == 0 {
+			return fmt.Errorf("%w: no storage specified for %v", ErrConditionalStorage, addr)
+		}
+	}
+	return nil
+}
+
+// Expired reports whether the expiry of the conditions has passed.
+func (c *TransactionConditional) Expired(now time.Time) bool {
+	return c.Expiry != nil && uint64(now.Unix()) > uint64(*c.Expiry)
+}
+
+// CheckBlock checks the block number and timestamp window of the conditions
+// against the given block.
+func (c *TransactionConditional) CheckBlock(number, timestamp uint64) error {
+	if c.BlockNumberMin != nil && number < uint64(*c.BlockNumberMin) {
+		return fmt.Errorf("%w: have %d, min %d", ErrConditionalBlockNumber, number, *c.BlockNumberMin)
+	}
+	if c.BlockNumberMax != nil && number > uint64(*c.BlockNumberMax) {
+		return fmt.Errorf("%w: have %d, max %d", ErrConditionalBlockNumber, number, *c.BlockNumberMax)
+	}
+	if c.TimestampMin != nil && timestamp < uint64(*c.TimestampMin) {
+		return fmt.Errorf("%w: have %d, min %d", ErrConditionalTimestamp, timestamp, *c.TimestampMin)
+	}
+	if c.TimestampMax != nil && timestamp > uint64(*c.TimestampMax) {
+		return fmt.Errorf("%w: have %d, max %d", ErrConditionalTimestamp, timestamp, *c.TimestampMax)
+	}
+	return nil
+}
+
+// CheckState checks the known accounts of the conditions against the given state.
+func (c *TransactionConditional) CheckState(state ConditionalState) error {
+	for addr, account := range c.KnownAccounts {
+		if account.StorageRoot != nil {
+			root, err := state.GetStorageRoot(addr)
+			if err != nil {
+				return err
+			}
+			if root != *account.StorageRoot {
+				return fmt.Errorf("%w: %v root have %v, want %v", ErrConditionalStorage, addr, root, *account.StorageRoot)
+			}
+			continue
+		}
+		for key, want := range account.StorageSlots {
+			if have := state.GetState(addr, key); have != want {
+				return fmt.Errorf("%w: %v slot %v have %v, want %v", ErrConditionalStorage, addr, key, have, want)
+			}
+		}
+	}
+	return nil
+}
+
+// Check verifies all the conditions against a block with the given number and
+// timestamp built on top of the given state, at the given wall clock time.
+func (c *TransactionConditional) Check(number, timestamp uint64, now time.Time, state ConditionalState) error {
+	if c.Expired(now) {
+		return ErrConditionalExpired
+	}
+	if err := c.CheckBlock(number, timestamp); err != nil {
+		return err
+	}
+	return c.CheckState(state)
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// conditionalState is a fake state to check known accounts against.
+type conditionalState struct {
+	roots map[common.Address]common.Hash
+	slots map[common.Address]map[common.Hash]common.Hash
+}
+
+func (s *conditionalState) GetState(addr common.Address, key common.Hash) common.Hash {
+	return s.slots[addr][key]
+}
+
+func (s *conditionalState) GetStorageRoot(addr common.Address) (common.Hash, error) {
+	if root, ok := s.roots[addr]; ok {
+		return root, nil
+	}
+	return EmptyRootHash, nil
+}
+
+func TestTransactionConditionalJSON(t *testing.T) {
+	input := `{
+		"knownAccounts": {
+			"0x00000000000000000000000000000000000000aa": "0x0100000000000000000000000000000000000000000000000000000000000000",
+			"0x00000000000000000000000000000000000000bb": {
+				"0x0000000000000000000000000000000000000000000000000000000000000001": "0x0000000000000000000000000000000000000000000000000000000000000002"
+			}
+		},
+		"blockNumberMin": "0x1",
+		"timestampMax": "0x64"
+	}`
+	var cond TransactionConditional
+	if err := json.Unmarshal([]byte(input), &cond); err != nil {
+		t.Fatalf("failed to decode conditional: %v", err)
+	}
+	root := common.Hash{0x01}
+	want := TransactionConditional{
+		KnownAccounts: map[common.Address]KnownAccount{
+			common.HexToAddress("0xaa"): {StorageRoot: &root},
+			common.HexToAddress("0xbb"): {StorageSlots: map[common.Hash]common.Hash{
+				common.HexToHash("0x01"): common.HexToHash("0x02"),
+			}},
+		},
+		BlockNumberMin: new(hexutil.Uint64),
+		TimestampMax:   new(hexutil.Uint64),
+	}
+	*want.BlockNumberMin, *want.TimestampMax = 1, 100
+
+	if !reflect.DeepEqual(cond, want) {
+		t.Fatalf("decoded conditional mismatch: have %+v, want %+v", cond, want)
+	}
+	blob, err := json.Marshal(&cond)
+	if err != nil {
+		t.Fatalf("failed to encode conditional: %v", err)
+	}
+	var dec TransactionConditional
+	if err := json.Unmarshal(blob, &dec); err != nil {
+		t.Fatalf("failed to decode encoded conditional: %v", err)
+	}
+	if !reflect.DeepEqual(dec, want) {
+		t.Fatalf("round trip mismatch: have %+v, want %+v", dec, want)
+	}
+	if cost := cond.Cost(); cost != 2 {
+		t.Errorf("cost mismatch: have %d, want 2", cost)
+	}
+}
+
+func TestTransactionConditionalCheck(t *testing.T) {
+	var (
+		oracle = common.Address{0xaa}
+		root   = common.Hash{0x01}
+		now    = time.Unix(1000, 0)
+		state  = &conditionalState{
+			roots: map[common.Address]common.Hash{oracle: root},
+			slots: map[common.Address]map[common.Hash]common.Hash{oracle: {{0x01}: {0x02}}},
+		}
+	)
+	u64 := func(n uint64) *hexutil.Uint64 { return (*hexutil.Uint64)(&n) }
+
+	tests := []struct {
+		cond   TransactionConditional
+		number uint64
+		time   uint64
+		want   error
+	}{
+		{TransactionConditional{}, 1, 1, nil},
+		{TransactionConditional{BlockNumberMin: u64(2)}, 1, 1, ErrConditionalBlockNumber},
+		{TransactionConditional{BlockNumberMax: u64(2)}, 3, 1, ErrConditionalBlockNumber},
+		{TransactionConditional{TimestampMin: u64(10)}, 1, 9, ErrConditionalTimestamp},
+		{TransactionConditional{TimestampMax: u64(10)}, 1, 11, ErrConditionalTimestamp},
+		{TransactionConditional{Expiry: u64(999)}, 1, 1, ErrConditionalExpired},
+		{TransactionConditional{Expiry: u64(1000)}, 1, 1, nil},
+		{TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{oracle: {StorageRoot: &root}}}, 1, 1, nil},
+		{TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{{0xbb}: {StorageRoot: &root}}}, 1, 1, ErrConditionalStorage},
+		{TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{oracle: {StorageSlots: map[common.Hash]common.Hash{{0x01}: {0x02}}}}}, 1, 1, nil},
+		{TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{oracle: {StorageSlots: map[common.Hash]common.Hash{{0x01}: {0x03}}}}}, 1, 1, ErrConditionalStorage},
+	}
+	for i, tt := range tests {
+		if err := tt.cond.Check(tt.number, tt.time, now, state); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// Ensure inconsistent conditions are rejected upfront
+	if err := (&TransactionConditional{BlockNumberMin: u64(2), BlockNumberMax: u64(1)}).Validate(); !errors.Is(err, ErrConditionalBlockNumber) {
+		t.Errorf("inverted block range error mismatch: have %v, want %v", err, ErrConditionalBlockNumber)
+	}
+	if err := (&TransactionConditional{KnownAccounts: map[common.Address]KnownAccount{oracle: {}}}).Validate(); !errors.Is(err, ErrConditionalStorage) {
+		t.Errorf("empty known account error mismatch: have %v, want %v", err, ErrConditionalStorage)
+	}
+}
@@ -60,6 +60,9 @@ type Contract struct {
 
 	Gas   uint64
 	value *big.Int
+
+	container   *Container // Parsed EOF container, if the code is EOF formatted
+	returnStack []uint64   // Return program counters of the active EOF CALLF frames
 }
 
 // NewContract returns a new contract environment for the execution of EVM.
@@ -134,9 +137,10 @@ func (c *Contract) isCode(udest uint64) bool {
 func (c *Contract) AsDelegate() *Contract {
 	// NOTE: caller must, at all times be a contract. It should never happen
 	// that caller is something other than a Contract.
-	parent := c.caller.(*Contract)
-	c.CallerAddress = parent.CallerAddress
-	c.value = parent.value
+	if parent, ok := c.caller.(*Contract); ok {
+		c.CallerAddress = parent.CallerAddress
+		c.value = parent.value
+	}
 
 	return c
 }
@@ -0,0 +1,157 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// StatefulPrecompiledContract is a precompiled contract which, besides its input,
+// has access to the state and the call context it is executed in. When invoked,
+// RunStateful is called instead of Run.
+type StatefulPrecompiledContract interface {
+	PrecompiledContract
+	RunStateful(env *PrecompileEnvironment, input []byte) ([]byte, error)
+}
+
+// PrecompileEnvironment is the execution context of a stateful precompiled
+// contract.
+type PrecompileEnvironment struct {
+	StateDB  StateDB
+	Context  BlockContext
+	Caller   common.Address // Account invoking the precompile
+	Address  common.Address // Account the precompile executes as (the caller's for CALLCODE and DELEGATECALL)
+	Value    *big.Int       // Value transferred along with the call, if any
+	ReadOnly bool           // Whether state modifications are forbidden (static context)
+}
+
+var (
+	registryLock sync.RWMutex
+	registry     = make(map[string]PrecompiledContract)
+)
+
+// RegisterPrecompile makes a custom precompiled contract implementation available
+// under the given name. Registered contracts are bound to addresses and activated
+// by the Precompiles section of the chain configuration, typically in the genesis
+// of a private or development network. It panics if the name is already taken.
+func RegisterPrecompile(name string, p PrecompiledContract) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	if p == nil {
+		panic("vm: RegisterPrecompile contract is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("vm: RegisterPrecompile called twice for " + name)
+	}
+	registry[name] = p
+}
+
+// RegisteredPrecompile returns the custom precompiled contract registered under
+// the given name.
+func RegisteredPrecompile(name string) (PrecompiledContract, bool) {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	p, ok := registry[name]
+	return p, ok
+}
+
+// CheckPrecompileConfig verifies that all the custom precompiles of the chain
+// configuration are registered, scheduled on a known fork and don't shadow any
+// of the standard precompiled contracts.
+func CheckPrecompileConfig(config *params.ChainConfig) error {
+	for addr, cfg := range config.Precompiles {
+		if err := cfg.Validate(); err != nil {
+			return fmt.Errorf("precompile %v: %w", addr, err)
+		}
+		if _, ok := RegisteredPrecompile(cfg.Name); !ok {
+			return fmt.Errorf("precompile %v: unknown contract %q", addr, cfg.Name)
+		}
+		if _, ok := PrecompiledContractsCancun[addr]; ok {
+			return fmt.Errorf("precompile %v: address taken by standard precompile", addr)
+		}
+		if _, ok := PrecompiledContractsBLS[addr]; ok {
+			return fmt.Errorf("precompile %v: address taken by standard precompile", addr)
+		}
+	}
+	return nil
+}
+
+// customPrecompiles returns the custom precompiled contracts of the chain
+// configuration which are active under the given rules.
+func customPrecompiles(config *params.ChainConfig, rules params.Rules) map[common.Address]PrecompiledContract {
+	if len(config.Precompiles) == 0 {
+		return nil
+	}
+	active := make(map[common.Address]PrecompiledContract)
+	for addr, cfg := range config.Precompiles {
+		if !cfg.Active(rules) {
+			continue
+		}
+		if p, ok := RegisteredPrecompile(cfg.Name); ok {
+			active[addr] = p
+		}
+	}
+	return active
+}
+
+// ActiveChainPrecompiles returns the precompiles enabled with the current
+// configuration, including the custom ones of the chain.
+func ActiveChainPrecompiles(config *params.ChainConfig, rules params.Rules) []common.Address {
+	precompiles := ActivePrecompiles(rules)
+
+	custom := customPrecompiles(config, rules)
+	if len(custom) == 0 {
+		return precompiles
+	}
+	addrs := make([]common.Address, 0, len(precompiles)+len(custom))
+	addrs = append(addrs, precompiles...)
+	for addr := range custom {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
+
+// runPrecompiledContract runs a precompiled contract, providing the execution
+// context to stateful ones. The caller and self accounts are the ones the call
+// frame executes with.
+func (evm *EVM) runPrecompiledContract(p PrecompiledContract, caller, self common.Address, input []byte, gas uint64, value *big.Int, readOnly bool) ([]byte, uint64, error) {
+	sp, ok := p.(StatefulPrecompiledContract)
+	if !ok {
+		return RunPrecompiledContract(p, input, gas)
+	}
+	gasCost := sp.RequiredGas(input)
+	if gas < gasCost {
+		return nil, 0, ErrOutOfGas
+	}
+	env := &PrecompileEnvironment{
+		StateDB:  evm.StateDB,
+		Context:  evm.Context,
+		Caller:   caller,
+		Address:  self,
+		Value:    value,
+		ReadOnly: readOnly,
+	}
+	output, err := sp.RunStateful(env, input)
+	return output, gas - gasCost, err
+}
@@ -0,0 +1,194 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testKeccak is a stateless custom precompile hashing its input.
+type testKeccak struct{}
+
+func (c *testKeccak) RequiredGas(input []byte) uint64  { return 100 }
+func (c *testKeccak) Run(input []byte) ([]byte, error) { return crypto.Keccak256(input), nil }
+
+// testOracle is a stateful custom precompile, which stores the input in the
+// slot 0 of its own account and returns the previously stored value.
+type testOracle struct{}
+
+func (c *testOracle) RequiredGas(input []byte) uint64  { return 200 }
+func (c *testOracle) Run(input []byte) ([]byte, error) { panic("stateless run of stateful precompile") }
+
+func (c *testOracle) RunStateful(env *PrecompileEnvironment, input []byte) ([]byte, error) {
+	prev := env.StateDB.GetState(env.Address, common.Hash{})
+	if len(input) > 0 {
+		if env.ReadOnly {
+			return nil, ErrWriteProtection
+		}
+		env.StateDB.SetState(env.Address, common.Hash{}, common.BytesToHash(input))
+	}
+	return prev.Bytes(), nil
+}
+
+func init() {
+	RegisterPrecompile("test-keccak", new(testKeccak))
+	RegisterPrecompile("test-oracle", new(testOracle))
+}
+
+var (
+	testKeccakAddr = common.HexToAddress("0x0000000000000000000000000000000000000100")
+	testOracleAddr = common.HexToAddress("0x0000000000000000000000000000000000000101")
+)
+
+func newPrecompileTestEVM(precompiles map[common.Address]*params.PrecompileConfig) *EVM {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	config := *params.AllEthashProtocolChanges
+	config.Precompiles = precompiles
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+	}
+	return NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+}
+
+func TestCustomPrecompiles(t *testing.T) {
+	evm := newPrecompileTestEVM(map[common.Address]*params.PrecompileConfig{
+		testKeccakAddr: {Name: "test-keccak"},
+		testOracleAddr: {Name: "test-oracle", Fork: "berlin"},
+	})
+	caller := AccountRef(common.Address{0x01})
+
+	// Stateless precompiles are run with the standard gas accounting.
+	ret, gas, err := evm.Call(caller, testKeccakAddr, []byte("hello"), 1000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to call keccak precompile: %v", err)
+	}
+	if want := crypto.Keccak256([]byte("hello")); !bytes.Equal(ret, want) {
+		t.Errorf("keccak output mismatch: have %x, want %x", ret, want)
+	}
+	if gas != 900 {
+		t.Errorf("keccak gas mismatch: have %d, want %d", gas, 900)
+	}
+	// Stateful precompiles have access to the state of the account they run as.
+	if _, _, err := evm.Call(caller, testOracleAddr, []byte{0x2a}, 1000, new(big.Int)); err != nil {
+		t.Fatalf("failed to update oracle: %v", err)
+	}
+	ret, _, err = evm.StaticCall(caller, testOracleAddr, nil, 1000)
+	if err != nil {
+		t.Fatalf("failed to query oracle: %v", err)
+	}
+	if want := common.BytesToHash([]byte{0x2a}); !bytes.Equal(ret, want[:]) {
+		t.Errorf("oracle output mismatch: have %x, want %x", ret, want)
+	}
+	if _, _, err := evm.StaticCall(caller, testOracleAddr, []byte{0x01}, 1000); err != ErrWriteProtection {
+		t.Errorf("static oracle update error mismatch: have %v, want %v", err, ErrWriteProtection)
+	}
+	// Custom precompiles are reported along with the standard ones.
+	var found int
+	for _, addr := range evm.ActivePrecompiles() {
+		if addr == testKeccakAddr || addr == testOracleAddr {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf("active precompiles mismatch: have %d custom, want 2", found)
+	}
+}
+
+func TestCustomPrecompileActivation(t *testing.T) {
+	evm := newPrecompileTestEVM(map[common.Address]*params.PrecompileConfig{
+		testKeccakAddr: {Name: "test-keccak", Fork: "prague"},
+	})
+	if _, ok := evm.precompile(testKeccakAddr); ok {
+		t.Fatal("precompile active before its fork")
+	}
+	for _, addr := range evm.ActivePrecompiles() {
+		if addr == testKeccakAddr {
+			t.Fatal("inactive precompile reported as active")
+		}
+	}
+}
+
+func TestCheckPrecompileConfig(t *testing.T) {
+	tests := []struct {
+		precompiles map[common.Address]*params.PrecompileConfig
+		fail        bool
+	}{
+		{map[common.Address]*params.PrecompileConfig{testKeccakAddr: {Name: "test-keccak", Fork: "cancun"}}, false},
+		{map[common.Address]*params.PrecompileConfig{testKeccakAddr: {Name: "unknown"}}, true},
+		{map[common.Address]*params.PrecompileConfig{testKeccakAddr: {Name: "test-keccak", Fork: "unknown"}}, true},
+		{map[common.Address]*params.PrecompileConfig{common.BytesToAddress([]byte{1}): {Name: "test-keccak"}}, true},
+	}
+	for i, tt := range tests {
+		config := *params.AllEthashProtocolChanges
+		config.Precompiles = tt.precompiles
+		if err := CheckPrecompileConfig(&config); (err != nil) != tt.fail {
+			t.Errorf("test %d: error mismatch: have %v, want failure %v", i, err, tt.fail)
+		}
+	}
+}
+
+// enterTracer records the value of entered call frames.
+type enterTracer struct{ values []*big.Int }
+
+func (t *enterTracer) CaptureTxStart(uint64) {}
+func (t *enterTracer) CaptureTxEnd(uint64)   {}
+func (t *enterTracer) CaptureStart(*EVM, common.Address, common.Address, bool, []byte, uint64, *big.Int) {
+}
+func (t *enterTracer) CaptureEnd([]byte, uint64, error) {}
+func (t *enterTracer) CaptureEnter(typ OpCode, from, to common.Address, input []byte, gas uint64, value *big.Int) {
+	t.values = append(t.values, value)
+}
+func (t *enterTracer) CaptureExit([]byte, uint64, error) {}
+func (t *enterTracer) CaptureState(uint64, OpCode, uint64, uint64, *ScopeContext, []byte, int, error) {
+}
+func (t *enterTracer) CaptureFault(uint64, OpCode, uint64, uint64, *ScopeContext, int, error) {}
+
+// Tests that DELEGATECALL doesn't crash when the caller is not a contract.
+func TestDelegateCallNonContractCaller(t *testing.T) {
+	evm := newPrecompileTestEVM(map[common.Address]*params.PrecompileConfig{
+		testKeccakAddr: {Name: "test-keccak"},
+	})
+	tracer := new(enterTracer)
+	evm.Config.Tracer = tracer
+	caller := AccountRef(common.Address{0x01})
+
+	ret, _, err := evm.DelegateCall(caller, testKeccakAddr, []byte("hello"), 1000)
+	if err != nil {
+		t.Fatalf("failed to delegate to precompile: %v", err)
+	}
+	if want := crypto.Keccak256([]byte("hello")); !bytes.Equal(ret, want) {
+		t.Errorf("keccak output mismatch: have %x, want %x", ret, want)
+	}
+	if _, _, err := evm.DelegateCall(caller, common.Address{0x02}, nil, 1000); err != nil {
+		t.Fatalf("failed to delegate to account: %v", err)
+	}
+	if len(tracer.values) != 2 || tracer.values[0] == nil || tracer.values[0].Sign() != 0 {
+		t.Errorf("wrong traced call values: %v", tracer.values)
+	}
+}
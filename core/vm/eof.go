@@ -0,0 +1,326 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	eofFormatByte = 0xef
+	eof1Version   = 1
+
+	kindTypes = 1
+	kindCode  = 2
+	kindData  = 3
+
+	eofMagicLen    = 2
+	eofVersionLen  = 1
+	sectionKindLen = 1
+	sectionSizeLen = 2
+	typeEntryLen   = 4
+
+	maxCodeSections  = 1024
+	maxInputItems    = 127
+	maxOutputItems   = 127
+	maxStackHeight   = 1023
+	returnStackLimit = 1024
+)
+
+var (
+	eofMagic = []byte{eofFormatByte, 0x00}
+
+	errInvalidMagic              = errors.New("invalid magic")
+	errUnsupportedVersion        = errors.New("unsupported version")
+	errTooShort                  = errors.New("container too short")
+	errTruncatedHeader           = errors.New("truncated header")
+	errMissingTypeHeader         = errors.New("missing type header")
+	errInvalidTypeSize           = errors.New("invalid type section size")
+	errMissingCodeHeader         = errors.New("missing code header")
+	errInvalidCodeSize           = errors.New("invalid code size")
+	errMissingDataHeader         = errors.New("missing data header")
+	errMissingTerminator         = errors.New("missing header terminator")
+	errTooManyInputs             = errors.New("invalid type content, too many inputs")
+	errTooManyOutputs            = errors.New("invalid type content, too many outputs")
+	errInvalidSection0Type       = errors.New("invalid section 0 type, input and output should be zero")
+	errTooLargeMaxStackHeight    = errors.New("invalid type content, max stack height exceeds limit")
+	errInvalidContainerSize      = errors.New("invalid container size")
+	errUndefinedInstruction      = errors.New("undefined instruction")
+	errTruncatedImmediate        = errors.New("truncated immediate")
+	errInvalidSectionArgument    = errors.New("invalid section argument")
+	errInvalidJumpDest           = errors.New("invalid jump destination")
+	errInvalidBranchCount        = errors.New("invalid number of branches in jump table")
+	errInvalidCodeTermination    = errors.New("invalid code termination")
+	errUnreachableCode           = errors.New("unreachable code")
+	errConflictingStack          = errors.New("conflicting stack height")
+	errStackUnderflow            = errors.New("stack underflow")
+	errStackOverflow             = errors.New("stack overflow")
+	errInvalidOutputs            = errors.New("invalid number of outputs")
+	errInvalidMaxStackHeight     = errors.New("invalid max stack height")
+	errReturnStackExceeded       = errors.New("return stack limit reached")
+	errInvalidEOFRuntimeCodeMode = errors.New("eof initcode must deploy eof code")
+)
+
+// hasEOFMagic returns true if code starts with the EOF magic prefix.
+func hasEOFMagic(code []byte) bool {
+	return len(code) >= eofMagicLen && bytes.Equal(eofMagic, code[:eofMagicLen])
+}
+
+// isEOFVersion1 returns true if the code's version byte equals EOF version 1.
+func isEOFVersion1(code []byte) bool {
+	return hasEOFMagic(code) && len(code) > eofMagicLen && code[eofMagicLen] == eof1Version
+}
+
+// FunctionMetadata is an EOF type section entry, describing the stack
+// behaviour of a code section.
+type FunctionMetadata struct {
+	Input          uint8
+	Output         uint8
+	MaxStackHeight uint16
+}
+
+// Container is an EOF version 1 container, as defined by EIP-3540.
+type Container struct {
+	Types []*FunctionMetadata
+	Code  [][]byte
+	Data  []byte
+
+	codeOffsets []int // Offset of each code section within the serialized container
+}
+
+// CodeOffset returns the offset of the given code section within the serialized
+// container. Execution of EOF code uses these offsets as program counters.
+func (c *Container) CodeOffset(section int) int {
+	return c.codeOffsets[section]
+}
+
+// MarshalBinary encodes an EOF container into binary format.
+func (c *Container) MarshalBinary() []byte {
+	b := make([]byte, 0, eofMagicLen+eofVersionLen)
+	b = append(b, eofMagic...)
+	b = append(b, eof1Version)
+
+	// Write section headers.
+	b = append(b, kindTypes)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(c.Types)*typeEntryLen))
+	b = append(b, kindCode)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(c.Code)))
+	for _, code := range c.Code {
+		b = binary.BigEndian.AppendUint16(b, uint16(len(code)))
+	}
+	b = append(b, kindData)
+	b = binary.BigEndian.AppendUint16(b, uint16(len(c.Data)))
+	b = append(b, 0) // terminator
+
+	// Write section contents.
+	for _, ty := range c.Types {
+		b = append(b, ty.Input, ty.Output)
+		b = binary.BigEndian.AppendUint16(b, ty.MaxStackHeight)
+	}
+	for _, code := range c.Code {
+		b = append(b, code...)
+	}
+	b = append(b, c.Data...)
+	return b
+}
+
+// UnmarshalBinary decodes an EOF container, checking its header and type
+// section but not the validity of its code.
+func (c *Container) UnmarshalBinary(b []byte) error {
+	if !hasEOFMagic(b) {
+		return fmt.Errorf("%w: want %x", errInvalidMagic, eofMagic)
+	}
+	if len(b) < 14 {
+		return errTooShort
+	}
+	if !isEOFVersion1(b) {
+		return fmt.Errorf("%w: have %d, want %d", errUnsupportedVersion, b[2], eof1Version)
+	}
+	var (
+		kind, typesSize, dataSize int
+		codeSizes                 []int
+		err                       error
+	)
+	// Parse type section header.
+	kind, typesSize, err = parseSection(b, 3)
+	if err != nil {
+		return err
+	}
+	if kind != kindTypes {
+		return fmt.Errorf("%w: found section kind %x instead", errMissingTypeHeader, kind)
+	}
+	if typesSize < typeEntryLen || typesSize%typeEntryLen != 0 {
+		return fmt.Errorf("%w: type section size must be divisible by 4, have %d", errInvalidTypeSize, typesSize)
+	}
+	if typesSize/typeEntryLen > maxCodeSections {
+		return fmt.Errorf("%w: type section must not exceed 4*1024, have %d", errInvalidTypeSize, typesSize)
+	}
+	// Parse code section header.
+	kind, codeSizes, err = parseSectionList(b, 6)
+	if err != nil {
+		return err
+	}
+	if kind != kindCode {
+		return fmt.Errorf("%w: found section kind %x instead", errMissingCodeHeader, kind)
+	}
+	if len(codeSizes) != typesSize/typeEntryLen {
+		return fmt.Errorf("%w: mismatch of code sections and types, have %d, want %d", errInvalidCodeSize, len(codeSizes), typesSize/typeEntryLen)
+	}
+	// Parse data section header.
+	offset := 6 + sectionKindLen + sectionSizeLen + len(codeSizes)*sectionSizeLen
+	kind, dataSize, err = parseSection(b, offset)
+	if err != nil {
+		return err
+	}
+	if kind != kindData {
+		return fmt.Errorf("%w: found section kind %x instead", errMissingDataHeader, kind)
+	}
+	// Check for terminator.
+	offset += sectionKindLen + sectionSizeLen
+	if offset >= len(b) {
+		return fmt.Errorf("%w: invalid offset terminator", errMissingTerminator)
+	}
+	if b[offset] != 0 {
+		return fmt.Errorf("%w: have %x", errMissingTerminator, b[offset])
+	}
+	offset++
+
+	// Verify overall container size.
+	expectedSize := offset + typesSize + dataSize
+	for _, size := range codeSizes {
+		expectedSize += size
+	}
+	if len(b) != expectedSize {
+		return fmt.Errorf("%w: have %d, want %d", errInvalidContainerSize, len(b), expectedSize)
+	}
+	// Parse types section.
+	types := make([]*FunctionMetadata, 0, len(codeSizes))
+	for i := 0; i < typesSize/typeEntryLen; i++ {
+		sig := &FunctionMetadata{
+			Input:          b[offset+i*typeEntryLen],
+			Output:         b[offset+i*typeEntryLen+1],
+			MaxStackHeight: binary.BigEndian.Uint16(b[offset+i*typeEntryLen+2:]),
+		}
+		if sig.Input > maxInputItems {
+			return fmt.Errorf("%w for section %d: have %d", errTooManyInputs, i, sig.Input)
+		}
+		if sig.Output > maxOutputItems {
+			return fmt.Errorf("%w for section %d: have %d", errTooManyOutputs, i, sig.Output)
+		}
+		if sig.MaxStackHeight > maxStackHeight {
+			return fmt.Errorf("%w for section %d, have %d", errTooLargeMaxStackHeight, i, sig.MaxStackHeight)
+		}
+		types = append(types, sig)
+	}
+	if types[0].Input != 0 || types[0].Output != 0 {
+		return fmt.Errorf("%w: have %d, %d", errInvalidSection0Type, types[0].Input, types[0].Output)
+	}
+	c.Types = types
+	offset += typesSize
+
+	// Parse code sections.
+	code := make([][]byte, len(codeSizes))
+	codeOffsets := make([]int, len(codeSizes))
+	for i, size := range codeSizes {
+		if size == 0 {
+			return fmt.Errorf("%w for section %d: size must not be 0", errInvalidCodeSize, i)
+		}
+		code[i] = b[offset : offset+size]
+		codeOffsets[i] = offset
+		offset += size
+	}
+	c.Code = code
+	c.codeOffsets = codeOffsets
+
+	// Parse data section.
+	c.Data = b[offset : offset+dataSize]
+	return nil
+}
+
+// ValidateCode validates each code section of the container against the EOF
+// v1 rule set, using the given jump table to look up instruction definitions.
+func (c *Container) ValidateCode(jt *JumpTable) error {
+	for i, code := range c.Code {
+		if err := validateCode(code, i, c.Types, jt); err != nil {
+			return fmt.Errorf("section %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// ValidateEOF parses and fully validates an EOF container using the EOF
+// instruction set.
+func ValidateEOF(code []byte) (*Container, error) {
+	return validateEOF(code, &eofInstructionSet)
+}
+
+// validateEOF parses and fully validates an EOF container using the given
+// instruction set.
+func validateEOF(code []byte, jt *JumpTable) (*Container, error) {
+	c := new(Container)
+	if err := c.UnmarshalBinary(code); err != nil {
+		return nil, err
+	}
+	if err := c.ValidateCode(jt); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// parseSection decodes a (kind, size) pair from an EOF header.
+func parseSection(b []byte, idx int) (kind, size int, err error) {
+	if idx+3 > len(b) {
+		return 0, 0, errTruncatedHeader
+	}
+	return int(b[idx]), int(binary.BigEndian.Uint16(b[idx+1 : idx+3])), nil
+}
+
+// parseSectionList decodes a (kind, len, []codeSize) section list from an EOF
+// header.
+func parseSectionList(b []byte, idx int) (kind int, list []int, err error) {
+	if idx >= len(b) {
+		return 0, nil, errTruncatedHeader
+	}
+	kind = int(b[idx])
+	list, err = parseList(b, idx+1)
+	if err != nil {
+		return 0, nil, err
+	}
+	return kind, list, nil
+}
+
+// parseList decodes a list of uint16 sizes, prefixed by their count.
+func parseList(b []byte, idx int) ([]int, error) {
+	if len(b) < idx+2 {
+		return nil, errTruncatedHeader
+	}
+	count := binary.BigEndian.Uint16(b[idx:])
+	if count == 0 || count > maxCodeSections {
+		return nil, fmt.Errorf("%w: have %d", errInvalidCodeSize, count)
+	}
+	if len(b) <= idx+2+int(count)*2 {
+		return nil, errTruncatedHeader
+	}
+	list := make([]int, count)
+	for i := 0; i < int(count); i++ {
+		list[i] = int(binary.BigEndian.Uint16(b[idx+2+2*i:]))
+	}
+	return list, nil
+}
@@ -0,0 +1,157 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// enableEOF applies the EOF v1 instruction changes to the jump table:
+//   - EIP-4200: static relative jumps (RJUMP, RJUMPI, RJUMPV)
+//   - EIP-4750: functions (CALLF, RETF), deprecating JUMP, JUMPI and PC
+//   - EIP-3670: code validation, deprecating CALLCODE and SELFDESTRUCT
+func enableEOF(jt *JumpTable) {
+	undefined := &operation{
+		execute:   opUndefined,
+		maxStack:  maxStack(0, 0),
+		undefined: true,
+	}
+	jt[JUMP] = undefined
+	jt[JUMPI] = undefined
+	jt[PC] = undefined
+	jt[CALLCODE] = undefined
+	jt[SELFDESTRUCT] = undefined
+
+	// The designated invalid instruction is allowed to appear in EOF code.
+	jt[INVALID] = &operation{
+		execute:  opUndefined,
+		maxStack: maxStack(0, 0),
+	}
+
+	jt[RJUMP] = &operation{
+		execute:     opRjump,
+		constantGas: GasQuickStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+	jt[RJUMPI] = &operation{
+		execute:     opRjumpi,
+		constantGas: params.RjumpiGas,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+	}
+	jt[RJUMPV] = &operation{
+		execute:     opRjumpv,
+		constantGas: params.RjumpiGas,
+		minStack:    minStack(1, 0),
+		maxStack:    maxStack(1, 0),
+	}
+	jt[CALLF] = &operation{
+		execute:     opCallf,
+		constantGas: GasFastStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+	jt[RETF] = &operation{
+		execute:     opRetf,
+		constantGas: GasFastestStep,
+		minStack:    minStack(0, 0),
+		maxStack:    maxStack(0, 0),
+	}
+}
+
+// relativeOffset parses the signed 16 bit immediate at the given position.
+func relativeOffset(code []byte, pos uint64) int64 {
+	return int64(int16(binary.BigEndian.Uint16(code[pos:])))
+}
+
+// opRjump implements the RJUMP opcode.
+func opRjump(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if interpreter.evm.abort.Load() {
+		return nil, errStopToken
+	}
+	offset := relativeOffset(scope.Contract.Code, *pc+1)
+	*pc = uint64(int64(*pc) + 3 + offset - 1) // pc will be increased by the interpreter loop
+	return nil, nil
+}
+
+// opRjumpi implements the RJUMPI opcode.
+func opRjumpi(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if interpreter.evm.abort.Load() {
+		return nil, errStopToken
+	}
+	cond := scope.Stack.pop()
+	if cond.IsZero() {
+		*pc += 2 // skip the immediate
+		return nil, nil
+	}
+	offset := relativeOffset(scope.Contract.Code, *pc+1)
+	*pc = uint64(int64(*pc) + 3 + offset - 1)
+	return nil, nil
+}
+
+// opRjumpv implements the RJUMPV opcode.
+func opRjumpv(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if interpreter.evm.abort.Load() {
+		return nil, errStopToken
+	}
+	var (
+		code  = scope.Contract.Code
+		count = uint64(code[*pc+1])
+		idx   = scope.Stack.pop()
+	)
+	if !idx.IsUint64() || idx.Uint64() >= count {
+		// Out-of-bounds index falls through to the next instruction.
+		*pc += 1 + count*2
+		return nil, nil
+	}
+	offset := relativeOffset(code, *pc+2+idx.Uint64()*2)
+	*pc = uint64(int64(*pc) + 2 + int64(count)*2 + offset - 1)
+	return nil, nil
+}
+
+// opCallf implements the CALLF opcode.
+func opCallf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	var (
+		container = scope.Contract.container
+		idx       = int(binary.BigEndian.Uint16(scope.Contract.Code[*pc+1:]))
+		typ       = container.Types[idx]
+	)
+	if len(scope.Contract.returnStack) >= returnStackLimit {
+		return nil, errReturnStackExceeded
+	}
+	if limit := int(params.StackLimit); scope.Stack.len()+int(typ.MaxStackHeight)-int(typ.Input) > limit {
+		return nil, &ErrStackOverflow{stackLen: scope.Stack.len(), limit: limit - int(typ.MaxStackHeight) + int(typ.Input)}
+	}
+	scope.Contract.returnStack = append(scope.Contract.returnStack, *pc+3)
+	*pc = uint64(container.CodeOffset(idx)) - 1
+	return nil, nil
+}
+
+// opRetf implements the RETF opcode. Returning from the first code section,
+// which is not entered via CALLF, halts execution like STOP.
+func opRetf(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext) ([]byte, error) {
+	if len(scope.Contract.returnStack) == 0 {
+		return nil, errStopToken
+	}
+	last := len(scope.Contract.returnStack) - 1
+	*pc = scope.Contract.returnStack[last] - 1
+	scope.Contract.returnStack = scope.Contract.returnStack[:last]
+	return nil, nil
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testEOFContainer returns a container whose first section calls a function
+// returning 0xbb through a conditional relative jump, and returns the result.
+func testEOFContainer() *Container {
+	return &Container{
+		Types: []*FunctionMetadata{
+			{Input: 0, Output: 0, MaxStackHeight: 2},
+			{Input: 0, Output: 1, MaxStackHeight: 1},
+		},
+		Code: [][]byte{
+			{
+				byte(CALLF), 0x00, 0x01,
+				byte(PUSH1), 0x00,
+				byte(MSTORE),
+				byte(PUSH1), 0x20,
+				byte(PUSH1), 0x00,
+				byte(RETURN),
+			},
+			{
+				byte(PUSH1), 0x01,
+				byte(RJUMPI), 0x00, 0x03,
+				byte(PUSH1), 0xaa,
+				byte(RETF),
+				byte(PUSH1), 0xbb,
+				byte(RETF),
+			},
+		},
+		Data: []byte{0x01, 0x02},
+	}
+}
+
+func TestEOFMarshaling(t *testing.T) {
+	for i, want := range []*Container{
+		testEOFContainer(),
+		{
+			Types: []*FunctionMetadata{{Input: 0, Output: 0, MaxStackHeight: 0}},
+			Code:  [][]byte{{byte(STOP)}},
+			Data:  []byte{},
+		},
+	} {
+		var (
+			b   = want.MarshalBinary()
+			got Container
+		)
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("test %d: failed to unmarshal: %v", i, err)
+		}
+		if !bytes.Equal(got.MarshalBinary(), b) {
+			t.Fatalf("test %d: round trip mismatch: have %x, want %x", i, got.MarshalBinary(), b)
+		}
+		if err := got.ValidateCode(&eofInstructionSet); err != nil {
+			t.Fatalf("test %d: failed to validate: %v", i, err)
+		}
+	}
+}
+
+func TestEOFUnmarshalErrors(t *testing.T) {
+	valid := testEOFContainer().MarshalBinary()
+	for i, tt := range []struct {
+		mutate func([]byte) []byte
+		want   error
+	}{
+		{func(b []byte) []byte { b[1] = 0x01; return b }, errInvalidMagic},
+		{func(b []byte) []byte { b[2] = 0x02; return b }, errUnsupportedVersion},
+		{func(b []byte) []byte { return append(b, 0x00) }, errInvalidContainerSize},
+		{func(b []byte) []byte { return b[:len(b)-1] }, errInvalidContainerSize},
+		{func(b []byte) []byte { b[3] = kindCode; return b }, errMissingTypeHeader},
+		{func(b []byte) []byte { b[16] = 0x01; return b }, errMissingTerminator},
+		{func(b []byte) []byte { b[17] = 0x01; return b }, errInvalidSection0Type},
+	} {
+		b := tt.mutate(append([]byte{}, valid...))
+		var c Container
+		if err := c.UnmarshalBinary(b); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+func TestEOFValidation(t *testing.T) {
+	for i, tt := range []struct {
+		code      []byte
+		maxHeight uint16
+		want      error
+	}{
+		{[]byte{byte(STOP), byte(STOP)}, 0, errUnreachableCode},
+		{[]byte{byte(PUSH0)}, 1, errInvalidCodeTermination},
+		{[]byte{byte(PC), byte(STOP)}, 1, errUndefinedInstruction},
+		{[]byte{byte(PUSH2), 0x00}, 1, errTruncatedImmediate},
+		{[]byte{byte(RJUMP), 0xff, 0xff}, 0, errInvalidJumpDest},
+		{[]byte{byte(RJUMP), 0x00, 0x10}, 0, errInvalidJumpDest},
+		{[]byte{byte(ADD), byte(STOP)}, 0, errStackUnderflow},
+		{[]byte{byte(PUSH0), byte(STOP)}, 0, errInvalidMaxStackHeight},
+		{[]byte{byte(CALLF), 0x00, 0x01, byte(STOP)}, 0, errInvalidSectionArgument},
+		{[]byte{byte(PUSH0), byte(RJUMPV), 0x00, byte(STOP)}, 1, errInvalidBranchCount},
+		{[]byte{byte(PUSH0), byte(RJUMPI), 0x00, 0x01, byte(PUSH0), byte(STOP)}, 1, errConflictingStack},
+		{[]byte{byte(PUSH0), byte(RJUMPI), 0x00, 0x00, byte(STOP)}, 1, nil},
+		{[]byte{byte(INVALID)}, 0, nil},
+	} {
+		c := &Container{
+			Types: []*FunctionMetadata{{MaxStackHeight: tt.maxHeight}},
+			Code:  [][]byte{tt.code},
+		}
+		if err := c.ValidateCode(&eofInstructionSet); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+}
+
+// newEOFTestEVM creates an EVM with the Prague fork, and thus EOF, enabled.
+func newEOFTestEVM(t *testing.T) *EVM {
+	t.Helper()
+
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	config := *params.AllDevChainProtocolChanges
+	config.CancunTime = new(uint64)
+	config.PragueTime = new(uint64)
+
+	vmctx := BlockContext{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		BlockNumber: new(big.Int),
+		Random:      &common.Hash{},
+	}
+	return NewEVM(vmctx, TxContext{}, statedb, &config, Config{})
+}
+
+// deployer returns EOF initcode deploying the given code from its data section.
+func deployer(code []byte) []byte {
+	c := &Container{
+		Types: []*FunctionMetadata{{MaxStackHeight: 3}},
+		Code: [][]byte{{
+			byte(PUSH1), byte(len(code)),
+			byte(PUSH1), 0x00, // patched below
+			byte(PUSH0),
+			byte(CODECOPY),
+			byte(PUSH1), byte(len(code)),
+			byte(PUSH0),
+			byte(RETURN),
+		}},
+		Data: code,
+	}
+	b := c.MarshalBinary()
+	b[len(b)-len(code)-7] = byte(len(b) - len(code))
+	return b
+}
+
+func TestEOFExecution(t *testing.T) {
+	var (
+		evm     = newEOFTestEVM(t)
+		caller  = AccountRef(common.Address{0x01})
+		runtime = testEOFContainer().MarshalBinary()
+	)
+	_, addr, _, err := evm.Create(caller, deployer(runtime), 1_000_000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to deploy eof contract: %v", err)
+	}
+	if code := evm.StateDB.GetCode(addr); !bytes.Equal(code, runtime) {
+		t.Fatalf("deployed code mismatch: have %x, want %x", code, runtime)
+	}
+	ret, _, err := evm.Call(caller, addr, nil, 1_000_000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to call eof contract: %v", err)
+	}
+	if want := common.LeftPadBytes([]byte{0xbb}, 32); !bytes.Equal(ret, want) {
+		t.Fatalf("return mismatch: have %x, want %x", ret, want)
+	}
+}
+
+func TestEOFCreationErrors(t *testing.T) {
+	var (
+		evm    = newEOFTestEVM(t)
+		caller = AccountRef(common.Address{0x01})
+	)
+	// EOF initcode may not deploy legacy code.
+	if _, _, gas, err := evm.Create(caller, deployer([]byte{byte(STOP)}), 100_000, new(big.Int)); !errors.Is(err, errInvalidEOFRuntimeCodeMode) {
+		t.Errorf("legacy deployment error mismatch: have %v, want %v", err, errInvalidEOFRuntimeCodeMode)
+	} else if gas != 0 {
+		t.Errorf("legacy deployment left gas: have %d, want 0", gas)
+	}
+	// Invalid EOF initcode is rejected before execution.
+	initcode := deployer(testEOFContainer().MarshalBinary())
+	if _, _, _, err := evm.Create(caller, initcode[:len(initcode)-1], 100_000, new(big.Int)); !errors.Is(err, ErrInvalidEOFInitcode) {
+		t.Errorf("invalid initcode error mismatch: have %v, want %v", err, ErrInvalidEOFInitcode)
+	}
+}
+
+func FuzzEOFParsing(f *testing.F) {
+	f.Add(testEOFContainer().MarshalBinary())
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var c Container
+		if err := c.UnmarshalBinary(data); err != nil {
+			return
+		}
+		if have := c.MarshalBinary(); !bytes.Equal(have, data) {
+			t.Fatalf("round trip mismatch: have %x, want %x", have, data)
+		}
+		c.ValidateCode(&eofInstructionSet)
+	})
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// validateCode validates the code parameter against the EOF v1 validity
+// requirements: EIP-3670 (code validation), EIP-4200 (static relative jumps),
+// EIP-4750 (functions) and EIP-5450 (stack validation).
+func validateCode(code []byte, section int, metadata []*FunctionMetadata, jt *JumpTable) error {
+	var (
+		i        = 0
+		count    = 0 // Number of instructions, used to detect immediates
+		op       OpCode
+		analysis bitvec // Marks the immediate bytes of instructions
+	)
+	analysis = make(bitvec, len(code)/8+1+4)
+	for i < len(code) {
+		count++
+		op = OpCode(code[i])
+		if jt[op].undefined {
+			return fmt.Errorf("%w: op %s, pos %d", errUndefinedInstruction, op, i)
+		}
+		switch {
+		case op >= PUSH1 && op <= PUSH32:
+			size := int(op - PUSH0)
+			if len(code) <= i+size {
+				return fmt.Errorf("%w: op %s, pos %d", errTruncatedImmediate, op, i)
+			}
+			for j := 1; j <= size; j++ {
+				analysis.set1(uint64(i + j))
+			}
+			i += size
+		case op == RJUMP || op == RJUMPI:
+			if len(code) <= i+2 {
+				return fmt.Errorf("%w: op %s, pos %d", errTruncatedImmediate, op, i)
+			}
+			analysis.set1(uint64(i + 1))
+			analysis.set1(uint64(i + 2))
+			i += 2
+		case op == RJUMPV:
+			if len(code) <= i+1 {
+				return fmt.Errorf("%w: jump table size missing, op %s, pos %d", errTruncatedImmediate, op, i)
+			}
+			n := int(code[i+1])
+			if n == 0 {
+				return fmt.Errorf("%w: pos %d", errInvalidBranchCount, i)
+			}
+			if len(code) <= i+1+n*2 {
+				return fmt.Errorf("%w: jump table truncated, op %s, pos %d", errTruncatedImmediate, op, i)
+			}
+			for j := 1; j <= 1+n*2; j++ {
+				analysis.set1(uint64(i + j))
+			}
+			i += 1 + n*2
+		case op == CALLF:
+			if len(code) <= i+2 {
+				return fmt.Errorf("%w: op %s, pos %d", errTruncatedImmediate, op, i)
+			}
+			arg := int(binary.BigEndian.Uint16(code[i+1:]))
+			if arg >= len(metadata) {
+				return fmt.Errorf("%w: arg %d, last %d, pos %d", errInvalidSectionArgument, arg, len(metadata), i)
+			}
+			analysis.set1(uint64(i + 1))
+			analysis.set1(uint64(i + 2))
+			i += 2
+		}
+		i++
+	}
+	// Code sections may not "fall through" and require proper termination.
+	// Therefore, the last instruction must be considered terminal or RJUMP.
+	if !isTerminal(op) && op != RJUMP {
+		return fmt.Errorf("%w: end with %s, pos %d", errInvalidCodeTermination, op, i)
+	}
+	if err := validateJumpDestinations(code, analysis); err != nil {
+		return err
+	}
+	height, err := validateControlFlow(code, section, metadata, jt, count)
+	if err != nil {
+		return err
+	}
+	if height != int(metadata[section].MaxStackHeight) {
+		return fmt.Errorf("%w in code section %d: have %d, want %d", errInvalidMaxStackHeight, section, height, metadata[section].MaxStackHeight)
+	}
+	return nil
+}
+
+// isTerminal returns whether the opcode ends the execution of a code section.
+func isTerminal(op OpCode) bool {
+	switch op {
+	case STOP, RETURN, REVERT, INVALID, RETF:
+		return true
+	}
+	return false
+}
+
+// immediateSize returns the number of immediate bytes following op at pos.
+func immediateSize(code []byte, pos int) int {
+	switch op := OpCode(code[pos]); {
+	case op >= PUSH1 && op <= PUSH32:
+		return int(op - PUSH0)
+	case op == RJUMP || op == RJUMPI || op == CALLF:
+		return 2
+	case op == RJUMPV:
+		return 1 + int(code[pos+1])*2
+	}
+	return 0
+}
+
+// validateJumpDestinations checks that all static relative jumps land on the
+// beginning of an instruction within the code section.
+func validateJumpDestinations(code []byte, analysis bitvec) error {
+	for i := 0; i < len(code); i++ {
+		switch op := OpCode(code[i]); op {
+		case RJUMP, RJUMPI:
+			if err := checkDest(code, analysis, i+1, i+3); err != nil {
+				return err
+			}
+		case RJUMPV:
+			count := int(code[i+1])
+			for j := 0; j < count; j++ {
+				if err := checkDest(code, analysis, i+2+j*2, i+2+count*2); err != nil {
+					return err
+				}
+			}
+		}
+		i += immediateSize(code, i)
+	}
+	return nil
+}
+
+// checkDest parses a relative offset at code[imm:imm+2] and checks that the
+// resulting destination, relative to from, is a valid instruction start.
+func checkDest(code []byte, analysis bitvec, imm, from int) error {
+	offset := int(int16(binary.BigEndian.Uint16(code[imm:])))
+	dest := from + offset
+	if dest < 0 || dest >= len(code) {
+		return fmt.Errorf("%w: out-of-bounds offset: offset %d, dest %d, pos %d", errInvalidJumpDest, offset, dest, imm)
+	}
+	if !analysis.codeSegment(uint64(dest)) {
+		return fmt.Errorf("%w: offset into immediate: offset %d, dest %d, pos %d", errInvalidJumpDest, offset, dest, imm)
+	}
+	return nil
+}
+
+// validateControlFlow iterates through all possible branches of the code
+// section, ensuring stack heights are consistent at every instruction, that no
+// code is unreachable, and that the stack neither underflows nor overflows.
+// It returns the maximum stack height reached.
+func validateControlFlow(code []byte, section int, metadata []*FunctionMetadata, jt *JumpTable, instructions int) (int, error) {
+	type item struct {
+		pos    int
+		height int
+	}
+	var (
+		heights  = make(map[int]int)
+		worklist = []item{{0, int(metadata[section].Input)}}
+		highest  = int(metadata[section].Input)
+	)
+	for len(worklist) > 0 {
+		var (
+			idx    = len(worklist) - 1
+			pos    = worklist[idx].pos
+			height = worklist[idx].height
+		)
+		worklist = worklist[:idx]
+	outer:
+		for pos < len(code) {
+			op := OpCode(code[pos])
+
+			// Check if pos has already been visited; if so, the stack heights should be the same.
+			if want, ok := heights[pos]; ok {
+				if height != want {
+					return 0, fmt.Errorf("%w: have %d, want %d", errConflictingStack, height, want)
+				}
+				// Already visited this path and stack height matches.
+				break
+			}
+			heights[pos] = height
+
+			// Validate height for current op and update as needed.
+			switch op {
+			case CALLF:
+				arg := binary.BigEndian.Uint16(code[pos+1:])
+				callee := metadata[arg]
+				if height < int(callee.Input) {
+					return 0, fmt.Errorf("%w: at pos %d", errStackUnderflow, pos)
+				}
+				if height+int(callee.MaxStackHeight)-int(callee.Input) > int(params.StackLimit) {
+					return 0, fmt.Errorf("%w: at pos %d", errStackOverflow, pos)
+				}
+				height += int(callee.Output) - int(callee.Input)
+			case RETF:
+				if int(metadata[section].Output) != height {
+					return 0, fmt.Errorf("%w: have %d, want %d, at pos %d", errInvalidOutputs, metadata[section].Output, height, pos)
+				}
+				break outer
+			default:
+				pops, pushes := opStackEffect(jt[op])
+				if height < pops {
+					return 0, fmt.Errorf("%w: at pos %d", errStackUnderflow, pos)
+				}
+				height += pushes - pops
+			}
+			if height > highest {
+				highest = height
+			}
+			if highest > maxStackHeight {
+				return 0, fmt.Errorf("%w: at pos %d", errStackOverflow, pos)
+			}
+			switch {
+			case op == RJUMP:
+				offset := int(int16(binary.BigEndian.Uint16(code[pos+1:])))
+				pos += 3 + offset // pc + 1 + 2
+			case op == RJUMPI:
+				offset := int(int16(binary.BigEndian.Uint16(code[pos+1:])))
+				worklist = append(worklist, item{pos: pos + 3 + offset, height: height})
+				pos += 3
+			case op == RJUMPV:
+				count := int(code[pos+1])
+				for i := 0; i < count; i++ {
+					offset := int(int16(binary.BigEndian.Uint16(code[pos+2+2*i:])))
+					worklist = append(worklist, item{pos: pos + 2 + 2*count + offset, height: height})
+				}
+				pos += 2 + 2*count
+			case isTerminal(op):
+				break outer
+			default:
+				pos += 1 + immediateSize(code, pos)
+			}
+		}
+	}
+	if len(heights) != instructions {
+		return 0, fmt.Errorf("%w: reached %d of %d instructions", errUnreachableCode, len(heights), instructions)
+	}
+	return highest, nil
+}
+
+// opStackEffect derives the number of items an operation pops from and pushes
+// to the stack from its stack bounds.
+func opStackEffect(op *operation) (pops, pushes int) {
+	return op.minStack, int(params.StackLimit) + op.minStack - op.maxStack
+}
@@ -17,6 +17,7 @@
 package vm
 
 import (
+	"fmt"
 	"math/big"
 	"sync/atomic"
 
@@ -51,7 +52,10 @@ func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
 	default:
 		precompiles = PrecompiledContractsHomestead
 	}
-	p, ok := precompiles[addr]
+	if p, ok := precompiles[addr]; ok {
+		return p, true
+	}
+	p, ok := evm.precompiles[addr]
 	return p, ok
 }
 
@@ -120,6 +124,9 @@ type EVM struct {
 	// available gas is calculated in gasCall* according to the 63/64 rule and later
 	// applied in opCall*.
 	callGasTemp uint64
+	// precompiles contains the custom precompiled contracts of the chain which
+	// are active for the current epoch.
+	precompiles map[common.Address]PrecompiledContract
 }
 
 // NewEVM returns a new EVM. The returned EVM is not thread safe and should
@@ -133,6 +140,7 @@ func NewEVM(blockCtx BlockContext, txCtx TxContext, statedb StateDB, chainConfig
 		chainConfig: chainConfig,
 		chainRules:  chainConfig.Rules(blockCtx.BlockNumber, blockCtx.Random != nil, blockCtx.Time),
 	}
+	evm.precompiles = customPrecompiles(chainConfig, evm.chainRules)
 	evm.interpreter = NewEVMInterpreter(evm)
 	return evm
 }
@@ -220,7 +228,7 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 	}
 
 	if isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompiledContract(p, caller.Address(), addr, input, gas, value, evm.interpreter.readOnly)
 	} else {
 		// Initialise a new contract and set the code that is to be used by the EVM.
 		// The contract is a scoped environment for this execution context only.
@@ -283,7 +291,7 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompiledContract(p, caller.Address(), caller.Address(), input, gas, value, evm.interpreter.readOnly)
 	} else {
 		addrCopy := addr
 		// Initialise a new contract and set the code that is to be used by the EVM.
@@ -314,13 +322,19 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 	}
 	var snapshot = evm.StateDB.Snapshot()
 
+	// DELEGATECALL inherits the caller and value from the parent call. The caller
+	// should always be a contract, but don't crash if it isn't.
+	var (
+		parentCaller common.Address
+		parentValue  = new(big.Int)
+	)
+	if parent, ok := caller.(*Contract); ok {
+		parentCaller, parentValue = parent.CallerAddress, parent.value
+	}
+
 	// Invoke tracer hooks that signal entering/exiting a call frame
 	if evm.Config.Tracer != nil {
-		// NOTE: caller must, at all times be a contract. It should never happen
-		// that caller is something other than a Contract.
-		parent := caller.(*Contract)
-		// DELEGATECALL inherits value from parent call
-		evm.Config.Tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, parent.value)
+		evm.Config.Tracer.CaptureEnter(DELEGATECALL, caller.Address(), addr, input, gas, parentValue)
 		defer func(startGas uint64) {
 			evm.Config.Tracer.CaptureExit(ret, startGas-gas, err)
 		}(gas)
@@ -328,7 +342,7 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 
 	// It is allowed to call precompiles, even via delegatecall
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompiledContract(p, parentCaller, caller.Address(), input, gas, parentValue, evm.interpreter.readOnly)
 	} else {
 		addrCopy := addr
 		// Initialise a new contract and make initialise the delegate values
@@ -377,7 +391,7 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 	}
 
 	if p, isPrecompile := evm.precompile(addr); isPrecompile {
-		ret, gas, err = RunPrecompiledContract(p, input, gas)
+		ret, gas, err = evm.runPrecompiledContract(p, caller.Address(), addr, input, gas, big0, true)
 	} else {
 		// At this point, we use a copy of address. If we don't, the go compiler will
 		// leak the 'contract' to the outer scope, and make allocation for 'contract'
@@ -459,16 +473,37 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 			evm.Config.Tracer.CaptureEnter(typ, caller.Address(), address, codeAndHash.code, gas, value)
 		}
 	}
-
-	ret, err := evm.interpreter.Run(contract, nil, false)
+	var (
+		ret []byte
+		err error
+	)
+	// EOF initcode is validated before execution, and may only deploy EOF code.
+	isEOF := evm.chainRules.IsPrague && hasEOFMagic(codeAndHash.code)
+	if isEOF {
+		var container *Container
+		if container, err = validateEOF(codeAndHash.code, evm.interpreter.eofTable); err != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidEOFInitcode, err)
+		} else {
+			contract.container = container
+		}
+	}
+	if err == nil {
+		ret, err = evm.interpreter.Run(contract, nil, false)
+	}
+	if err == nil && isEOF {
+		if _, verr := validateEOF(ret, evm.interpreter.eofTable); verr != nil {
+			err = fmt.Errorf("%w: %v", errInvalidEOFRuntimeCodeMode, verr)
+		}
+	}
 
 	// Check whether the max code size has been exceeded, assign err if the case.
 	if err == nil && evm.chainRules.IsEIP158 && len(ret) > params.MaxCodeSize {
 		err = ErrMaxCodeSizeExceeded
 	}
 
-	// Reject code starting with 0xEF if EIP-3541 is enabled.
-	if err == nil && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
+	// Reject code starting with 0xEF if EIP-3541 is enabled, unless it is
+	// valid EOF code deployed by EOF initcode.
+	if err == nil && !isEOF && len(ret) >= 1 && ret[0] == 0xEF && evm.chainRules.IsLondon {
 		err = ErrInvalidCode
 	}
 
@@ -521,5 +556,11 @@ func (evm *EVM) Create2(caller ContractRef, code []byte, gas uint64, endowment *
 	return evm.create(caller, codeAndHash, gas, endowment, contractAddr, CREATE2)
 }
 
+// ActivePrecompiles returns the addresses of the precompiled contracts, standard
+// and custom ones, enabled in the current epoch.
+func (evm *EVM) ActivePrecompiles() []common.Address {
+	return ActiveChainPrecompiles(evm.chainConfig, evm.chainRules)
+}
+
 // ChainConfig returns the environment's chain configuration
 func (evm *EVM) ChainConfig() *params.ChainConfig { return evm.chainConfig }
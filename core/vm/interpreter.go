@@ -17,6 +17,8 @@
 package vm
 
 import (
+	"fmt"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -41,8 +43,9 @@ type ScopeContext struct {
 
 // EVMInterpreter represents an EVM interpreter
 type EVMInterpreter struct {
-	evm   *EVM
-	table *JumpTable
+	evm      *EVM
+	table    *JumpTable
+	eofTable *JumpTable // Instruction set of EOF code, nil before EOF is enabled
 
 	hasher    crypto.KeccakState // Keccak256 hasher instance shared across opcodes
 	hasherBuf common.Hash        // Keccak256 hasher result array shared aross opcodes
@@ -56,6 +59,8 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 	// If jump table was not initialised we set the default one.
 	var table *JumpTable
 	switch {
+	case evm.chainRules.IsPrague:
+		table = &pragueInstructionSet
 	case evm.chainRules.IsCancun:
 		table = &cancunInstructionSet
 	case evm.chainRules.IsShanghai:
@@ -95,7 +100,12 @@ func NewEVMInterpreter(evm *EVM) *EVMInterpreter {
 		}
 	}
 	evm.Config.ExtraEips = extraEips
-	return &EVMInterpreter{evm: evm, table: table}
+
+	var eofTable *JumpTable
+	if evm.chainRules.IsPrague {
+		eofTable = &eofInstructionSet
+	}
+	return &EVMInterpreter{evm: evm, table: table, eofTable: eofTable}
 }
 
 // Run loops and evaluates the contract's code with the given input data and returns
@@ -137,8 +147,9 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// For optimisation reason we're using uint64 as the program counter.
 		// It's theoretically possible to go above 2^64. The YP defines the PC
 		// to be uint256. Practically much less so feasible.
-		pc   = uint64(0) // program counter
-		cost uint64
+		pc    = uint64(0) // program counter
+		table = in.table  // instruction set of the executed code
+		cost  uint64
 		// copies used by tracer
 		pcCopy  uint64 // needed for the deferred EVMLogger
 		gasCopy uint64 // for EVMLogger to log gas remaining before execution
@@ -154,6 +165,20 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 	}()
 	contract.Input = input
 
+	// EOF code is executed with its own instruction set, starting at the first
+	// code section. The code was validated upon deployment, but is parsed again
+	// to locate the sections.
+	if in.eofTable != nil && isEOFVersion1(contract.Code) {
+		if contract.container == nil {
+			container := new(Container)
+			if err := container.UnmarshalBinary(contract.Code); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrInvalidEOF, err)
+			}
+			contract.container = container
+		}
+		table = in.eofTable
+		pc = uint64(contract.container.CodeOffset(0))
+	}
 	if debug {
 		defer func() {
 			if err != nil {
@@ -177,7 +202,7 @@ func (in *EVMInterpreter) Run(contract *Contract, input []byte, readOnly bool) (
 		// Get the operation from the jump table and validate the stack to ensure there are
 		// enough stack items available to perform the operation.
 		op = contract.GetOp(pc)
-		operation := in.table[op]
+		operation := table[op]
 		cost = operation.constantGas // For tracing
 		// Validate stack
 		if sLen := stack.len(); sLen < operation.minStack {
@@ -42,6 +42,9 @@ type operation struct {
 
 	// memorySize returns the memory size required for the operation
 	memorySize memorySizeFunc
+
+	// undefined denotes if the instruction is not officially defined in the jump table
+	undefined bool
 }
 
 var (
@@ -57,6 +60,8 @@ var (
 	mergeInstructionSet            = newMergeInstructionSet()
 	shanghaiInstructionSet         = newShanghaiInstructionSet()
 	cancunInstructionSet           = newCancunInstructionSet()
+	pragueInstructionSet           = newPragueInstructionSet()
+	eofInstructionSet              = newEOFInstructionSet()
 )
 
 // JumpTable contains the EVM opcodes supported at a given fork.
@@ -80,6 +85,22 @@ func validate(jt JumpTable) JumpTable {
 	return jt
 }
 
+// newPragueInstructionSet returns the instruction set used to run legacy code
+// in the Prague fork. The EOF instructions are only available to EOF code,
+// which runs with the instruction set returned by newEOFInstructionSet.
+func newPragueInstructionSet() JumpTable {
+	instructionSet := newCancunInstructionSet()
+	return validate(instructionSet)
+}
+
+// newEOFInstructionSet returns the instruction set used to validate and run
+// EOF v1 code.
+func newEOFInstructionSet() JumpTable {
+	instructionSet := newPragueInstructionSet()
+	enableEOF(&instructionSet) // EIP-3540, EIP-3670, EIP-4200, EIP-4750 and EIP-5450
+	return validate(instructionSet)
+}
+
 func newCancunInstructionSet() JumpTable {
 	instructionSet := newShanghaiInstructionSet()
 	enable4844(&instructionSet) // EIP-4844 (DATAHASH opcode)
@@ -1056,7 +1077,7 @@ func newFrontierInstructionSet() JumpTable {
 	// Fill all unassigned slots with opUndefined.
 	for i, entry := range tbl {
 		if entry == nil {
-			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0)}
+			tbl[i] = &operation{execute: opUndefined, maxStack: maxStack(0, 0), undefined: true}
 		}
 	}
 
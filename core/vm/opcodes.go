@@ -208,6 +208,15 @@ const (
 	LOG4
 )
 
+// 0xe0 range - EOF control flow ops.
+const (
+	RJUMP  OpCode = 0xe0
+	RJUMPI OpCode = 0xe1
+	RJUMPV OpCode = 0xe2
+	CALLF  OpCode = 0xe3
+	RETF   OpCode = 0xe4
+)
+
 // 0xf0 range - closures.
 const (
 	CREATE       OpCode = 0xf0
@@ -383,6 +392,13 @@ var opCodeToString = map[OpCode]string{
 	LOG3: "LOG3",
 	LOG4: "LOG4",
 
+	// 0xe0 range - EOF control flow ops.
+	RJUMP:  "RJUMP",
+	RJUMPI: "RJUMPI",
+	RJUMPV: "RJUMPV",
+	CALLF:  "CALLF",
+	RETF:   "RETF",
+
 	// 0xf0 range - closures.
 	CREATE:       "CREATE",
 	CALL:         "CALL",
@@ -546,6 +562,11 @@ var stringToOp = map[string]OpCode{
 	"LOG2":           LOG2,
 	"LOG3":           LOG3,
 	"LOG4":           LOG4,
+	"RJUMP":          RJUMP,
+	"RJUMPI":         RJUMPI,
+	"RJUMPV":         RJUMPV,
+	"CALLF":          CALLF,
+	"RETF":           RETF,
 	"CREATE":         CREATE,
 	"CREATE2":        CREATE2,
 	"CALL":           CALL,
@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import "errors"
+
+// Compressed points follow the serialization rules of the zcash library:
+// https://github.com/zcash/librustzcash/blob/6e0364cd42a2b3d2b958a54771ef51a8db79dd29/pairing/src/bls12_381/README.md#serialization
+//
+// The three most significant bits of the first byte are flags. The highest bit
+// marks compression, the next one the point at infinity, and the third one is
+// set if the y coordinate is the lexicographically larger of the two roots.
+
+const (
+	flagCompressed = 1 << 7
+	flagInfinity   = 1 << 6
+	flagLargestY   = 1 << 5
+)
+
+// FromCompressed decodes a point in G1 from its 48 byte compressed form. The
+// point is checked to be in the correct subgroup.
+func (g *G1) FromCompressed(compressed []byte) (*PointG1, error) {
+	if len(compressed) != 48 {
+		return nil, errors.New("compressed g1 point must be 48 bytes")
+	}
+	in := make([]byte, 48)
+	copy(in, compressed)
+	largest, infinity, err := decodeFlags(in)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g.Zero(), nil
+	}
+	x, err := fromBytes(in)
+	if err != nil {
+		return nil, err
+	}
+	// Solve the curve equation y^2 = x^3 + b.
+	y := new(fe)
+	square(y, x)
+	mul(y, y, x)
+	add(y, y, b)
+	if !sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if y.largest() != largest {
+		neg(y, y)
+	}
+	p := &PointG1{*x, *y, *new(fe).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not on correct subgroup")
+	}
+	return p, nil
+}
+
+// FromCompressed decodes a point in G2 from its 96 byte compressed form. The
+// point is checked to be in the correct subgroup.
+func (g *G2) FromCompressed(compressed []byte) (*PointG2, error) {
+	if len(compressed) != 96 {
+		return nil, errors.New("compressed g2 point must be 96 bytes")
+	}
+	in := make([]byte, 96)
+	copy(in, compressed)
+	largest, infinity, err := decodeFlags(in)
+	if err != nil {
+		return nil, err
+	}
+	if infinity {
+		return g.Zero(), nil
+	}
+	x, err := g.f.fromBytes(in)
+	if err != nil {
+		return nil, err
+	}
+	// Solve the curve equation y^2 = x^3 + b.
+	y := new(fe2)
+	g.f.square(y, x)
+	g.f.mul(y, y, x)
+	g.f.add(y, y, b2)
+	if !g.f.sqrt(y, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	if y.largest() != largest {
+		g.f.neg(y, y)
+	}
+	p := &PointG2{*x, *y, *new(fe2).one()}
+	if !g.InCorrectSubgroup(p) {
+		return nil, errors.New("point is not on correct subgroup")
+	}
+	return p, nil
+}
+
+// decodeFlags checks and clears the flags of a compressed point.
+func decodeFlags(in []byte) (largest, infinity bool, err error) {
+	if in[0]&flagCompressed == 0 {
+		return false, false, errors.New("compression flag must be set")
+	}
+	if in[0]&flagInfinity != 0 {
+		if in[0] != flagCompressed|flagInfinity {
+			return false, false, errors.New("invalid flags for point at infinity")
+		}
+		for _, v := range in[1:] {
+			if v != 0 {
+				return false, false, errors.New("point at infinity must be zero")
+			}
+		}
+		return false, true, nil
+	}
+	largest = in[0]&flagLargestY != 0
+	in[0] &= 0x1f
+	return largest, false, nil
+}
+
+// largest reports whether the element is greater than (p-1)/2.
+func (e *fe) largest() bool {
+	z, negZ := new(fe), new(fe)
+	fromMont(z, e)
+	neg(negZ, z)
+	return z.cmp(negZ) > 0
+}
+
+// largest reports whether the element is lexicographically larger than its
+// negation, comparing the imaginary parts first.
+func (e *fe2) largest() bool {
+	if !e[1].isZero() {
+		return e[1].largest()
+	}
+	return e[0].largest()
+}
@@ -0,0 +1,97 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// HashToCurve hashes a message to a point in G2, following the
+// BLS12381G2_XMD:SHA-256_SSWU_RO_ suite of RFC 9380.
+func (g *G2) HashToCurve(msg, dst []byte) (*PointG2, error) {
+	u, err := hashToField(msg, dst, 4)
+	if err != nil {
+		return nil, err
+	}
+	u0, u1 := &fe2{*u[0], *u[1]}, &fe2{*u[2], *u[3]}
+	x0, y0 := swuMapG2(g.f, u0)
+	x1, y1 := swuMapG2(g.f, u1)
+	// The isogeny is a group homomorphism, so the points can be added before
+	// mapping them to the curve.
+	p := &PointG2{*x0, *y0, *new(fe2).one()}
+	g.Add(p, p, &PointG2{*x1, *y1, *new(fe2).one()})
+	g.Affine(p)
+	isogenyMapG2(g.f, &p[0], &p[1])
+	g.ClearCofactor(p)
+	return g.Affine(p), nil
+}
+
+// hashToField hashes a message to count field elements using expand_message_xmd
+// with SHA-256.
+func hashToField(msg, dst []byte, count int) ([]*fe, error) {
+	const l = 64 // bytes per element, ceil((ceil(log2(p)) + k) / 8) with k = 128
+	uniform, err := expandMessageXMD(msg, dst, count*l)
+	if err != nil {
+		return nil, err
+	}
+	p := modulus.big()
+	elems := make([]*fe, count)
+	for i := range elems {
+		v := new(big.Int).SetBytes(uniform[i*l : (i+1)*l])
+		if elems[i], err = fromBig(v.Mod(v, p)); err != nil {
+			return nil, err
+		}
+	}
+	return elems, nil
+}
+
+// expandMessageXMD implements expand_message_xmd of RFC 9380 with SHA-256.
+func expandMessageXMD(msg, dst []byte, length int) ([]byte, error) {
+	h := sha256.New()
+	ell := (length + h.Size() - 1) / h.Size()
+	if ell > 255 || length > 65535 || len(dst) > 255 {
+		return nil, errors.New("invalid expand_message_xmd parameters")
+	}
+	dstPrime := append(append([]byte{}, dst...), byte(len(dst)))
+
+	// b_0 = H(Z_pad || msg || I2OSP(len_in_bytes, 2) || I2OSP(0, 1) || DST_prime)
+	h.Write(make([]byte, h.BlockSize()))
+	h.Write(msg)
+	h.Write([]byte{byte(length >> 8), byte(length), 0})
+	h.Write(dstPrime)
+	b0 := h.Sum(nil)
+
+	// b_i = H(strxor(b_0, b_(i-1)) || I2OSP(i, 1) || DST_prime), with b_1 = H(b_0 || 1 || DST_prime)
+	var (
+		out = make([]byte, 0, ell*h.Size())
+		bi  = make([]byte, h.Size())
+	)
+	for i := 1; i <= ell; i++ {
+		for j := range bi {
+			bi[j] ^= b0[j]
+		}
+		h.Reset()
+		h.Write(bi)
+		h.Write([]byte{byte(i)})
+		h.Write(dstPrime)
+		bi = h.Sum(bi[:0])
+		out = append(out, bi...)
+	}
+	return out[:length], nil
+}
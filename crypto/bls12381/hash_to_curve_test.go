@@ -0,0 +1,118 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package bls12381
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestExpandMessageXMD(t *testing.T) {
+	// Test vectors from RFC 9380, appendix K.1.
+	dst := []byte("QUUX-V01-CS02-with-expander-SHA256-128")
+	for i, v := range []struct {
+		msg    string
+		length int
+		want   string
+	}{
+		{"", 0x20, "68a985b87eb6b46952128911f2a4412bbc302a9d759667f87f7a21d803f07235"},
+		{"abc", 0x20, "d8ccab23b5985ccea865c6c97b6e5b8350e794e603b4b97902f53a8a0d605615"},
+	} {
+		out, err := expandMessageXMD([]byte(v.msg), dst, v.length)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hex.EncodeToString(out) != v.want {
+			t.Errorf("vector %d: wrong output %x", i, out)
+		}
+	}
+}
+
+func TestG2HashToCurve(t *testing.T) {
+	dst := []byte("BLS12381G2_XMD:SHA-256_SSWU_RO_TESTGEN")
+	for i, v := range []struct {
+		msg  string
+		want string
+	}{
+		{
+			msg: "",
+			want: "0fbdae26f9f9586a46d4b0b70390d09064ef2afe5c99348438a3c7d9756471e015cb534204c1b6824617a85024c772dc" +
+				"0a650bd36ae7455cb3fe5d8bb1310594551456f5c6593aec9ee0c03d2f6cb693bd2c5e99d4e23cbaec767609314f51d3" +
+				"02e5cf8f9b7348428cc9e66b9a9b36fe45ba0b0a146290c3a68d92895b1af0e1f2d9f889fb412670ae8478d8abd4c5aa" +
+				"0d8d49e7737d8f9fc5cef7c4b8817633103faf2613016cb86a1f3fc29968fe2413e232d9208d2d74a89bf7a48ac36f83",
+		},
+		{
+			msg: "abc",
+			want: "03578447618463deb106b60e609c6f7cc446dc6035f84a72801ba17c94cd800583b493b948eff0033f09086fdd7f6175" +
+				"1953ce6d4267939c7360756d9cca8eb34aac4633ef35369a7dc249445069888e7d1b3f9d2e75fbd468fbcbba7110ea02" +
+				"0184d26779ae9d4670aca9b267dbd4d3b30443ad05b8546d36a195686e1ccc3a59194aea05ed5bce7c3144a29ec047c4" +
+				"0882ab045b8fe4d7d557ebb59a63a35ac9f3d312581b509af0f8eaa2960cbc5e1e36bb969b6e22980b5cbdd0787fcf4e",
+		},
+	} {
+		g := NewG2()
+		p, err := g.HashToCurve([]byte(v.msg), dst)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if have := hex.EncodeToString(g.ToBytes(p)); have != v.want {
+			t.Errorf("vector %d: wrong point\nhave %s\nwant %s", i, have, v.want)
+		}
+	}
+}
+
+func TestFromCompressed(t *testing.T) {
+	g1, g2 := NewG1(), NewG2()
+
+	p1, err := g1.FromCompressed(common.FromHex("97f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g1.ToBytes(p1), g1.ToBytes(g1.One())) {
+		t.Fatal("wrong g1 generator")
+	}
+	p2, err := g2.FromCompressed(common.FromHex("93e02b6052719f607dacd3a088274f65596bd0d09920b61ab5da61bbdc7f5049334cf11213945d57e5ac7d055d042b7e024aa2b2f08f0a91260805272dc51051c6e47ad4fa403b02b4510b647ae3d1770bac0326a805bbefd48056c8c121bdb8"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g2.ToBytes(p2), g2.ToBytes(g2.One())) {
+		t.Fatal("wrong g2 generator")
+	}
+	// The negated generator has the other y flag.
+	neg, err := g1.FromCompressed(common.FromHex("b7f1d3a73197d7942695638c4fa9ac0fc3688c4f9774b905a14e3a3f171bac586c55e83ff97a1aeffb3af00adb22c6bb"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(g1.ToBytes(neg), g1.ToBytes(g1.Neg(g1.New(), g1.One()))) {
+		t.Fatal("wrong negated g1 generator")
+	}
+	// Infinity.
+	inf := make([]byte, 48)
+	inf[0] = 0xc0
+	if p, err := g1.FromCompressed(inf); err != nil || !g1.IsZero(p) {
+		t.Fatal("infinity not decoded:", err)
+	}
+	inf[1] = 1
+	if _, err := g1.FromCompressed(inf); err == nil {
+		t.Fatal("invalid infinity accepted")
+	}
+	if _, err := g1.FromCompressed(make([]byte, 48)); err == nil {
+		t.Fatal("uncompressed point accepted")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"context"
 	"errors"
 	"math/big"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum"
@@ -31,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -48,6 +50,15 @@ type EthAPIBackend struct {
 	allowUnprotectedTxs bool
 	eth                 *Ethereum
 	gpo                 *gasprice.Oracle
+	fees                *feeSample
+}
+
+// feeSample is a snapshot of the public pending transactions, taken once per
+// head block to base the fee estimations on.
+type feeSample struct {
+	head common.Hash
+	txs  []*types.Transaction
+	lock sync.Mutex
 }
 
 // ChainConfig returns the active chain configuration.
@@ -155,11 +166,18 @@ func (b *EthAPIBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumbe
 		}
 		return b.eth.blockchain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 	}
-	return b.eth.blockchain.GetBlockByNumber(uint64(number)), nil
+	if block := b.eth.blockchain.GetBlockByNumber(uint64(number)); block != nil {
+		return block, nil
+	}
+	// The body may be unavailable locally, try the portal history network.
+	return b.eth.portalBlock(ctx, b.eth.blockchain.GetHeaderByNumber(uint64(number))), nil
 }
 
 func (b *EthAPIBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	return b.eth.blockchain.GetBlockByHash(hash), nil
+	if block := b.eth.blockchain.GetBlockByHash(hash); block != nil {
+		return block, nil
+	}
+	return b.eth.portalBlock(ctx, b.eth.blockchain.GetHeaderByHash(hash)), nil
 }
 
 // GetBody returns body of a block. It does not resolve special block numbers.
@@ -170,6 +188,11 @@ func (b *EthAPIBackend) GetBody(ctx context.Context, hash common.Hash, number rp
 	if body := b.eth.blockchain.GetBody(hash); body != nil {
 		return body, nil
 	}
+	if b.eth.blockchain.GetHeaderByHash(hash) != nil {
+		if body := b.eth.portalBody(ctx, hash); body != nil {
+			return body, nil
+		}
+	}
 	return nil, errors.New("block body not found")
 }
 
@@ -186,6 +209,9 @@ func (b *EthAPIBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash r
 			return nil, errors.New("hash is not currently canonical")
 		}
 		block := b.eth.blockchain.GetBlock(hash, header.Number.Uint64())
+		if block == nil {
+			block = b.eth.portalBlock(ctx, header)
+		}
 		if block == nil {
 			return nil, errors.New("header found, but block body is missing")
 		}
@@ -241,7 +267,10 @@ func (b *EthAPIBackend) StateAndHeaderByNumberOrHash(ctx context.Context, blockN
 }
 
 func (b *EthAPIBackend) GetReceipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
-	return b.eth.blockchain.GetReceiptsByHash(hash), nil
+	if receipts := b.eth.blockchain.GetReceiptsByHash(hash); receipts != nil {
+		return receipts, nil
+	}
+	return b.eth.portalReceipts(ctx, hash), nil
 }
 
 func (b *EthAPIBackend) GetLogs(ctx context.Context, hash common.Hash, number uint64) ([][]*types.Log, error) {
@@ -294,7 +323,17 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
-	return b.eth.txPool.Add([]*txpool.Transaction{{Tx: signedTx}}, true, false)[0]
+	tx := &txpool.Transaction{Tx: signedTx, Origin: rpc.PeerInfoFromContext(ctx).RemoteAddr}
+	return b.eth.txPool.Add([]*txpool.Transaction{tx}, true, false)[0]
+}
+
+func (b *EthAPIBackend) SendPrivateTx(ctx context.Context, signedTx *types.Transaction) error {
+	tx := &txpool.Transaction{Tx: signedTx, Origin: rpc.PeerInfoFromContext(ctx).RemoteAddr}
+	return b.eth.txPool.AddPrivate([]*txpool.Transaction{tx}, false)[0]
+}
+
+func (b *EthAPIBackend) GetPrivateTxRecord(txHash common.Hash) (privatepool.Record, error) {
+	return b.eth.privatePool.Record(txHash), nil
 }
 
 func (b *EthAPIBackend) GetPoolTransactions() (types.Transactions, error) {
@@ -342,6 +381,18 @@ func (b *EthAPIBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.S
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
 
+func (b *EthAPIBackend) SubscribeDroppedTxsEvent(ch chan<- txpool.DroppedTxsEvent) event.Subscription {
+	return b.eth.txPool.SubscribeDroppedTxsEvent(ch)
+}
+
+func (b *EthAPIBackend) TxPoolLifecycle(hash common.Hash) []txpool.TxEvent {
+	return b.eth.txPool.Lifecycle(hash)
+}
+
+func (b *EthAPIBackend) TxPoolAdmission() txpool.AdmissionStats {
+	return b.eth.txPool.Admission()
+}
+
 func (b *EthAPIBackend) SyncProgress() ethereum.SyncProgress {
 	return b.eth.Downloader().Progress()
 }
@@ -350,10 +401,34 @@ func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 	return b.gpo.SuggestTipCap(ctx)
 }
 
-func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
+func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, blobBaseFee []*big.Int, blobGasUsedRatio []float64, err error) {
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
 
+// EstimateFees suggests fees for the inclusion targets, outbidding the pending
+// transactions known to the network. Private transactions are left out, so the
+// estimations don't leak them.
+func (b *EthAPIBackend) EstimateFees(ctx context.Context) (*gasprice.FeeEstimate, error) {
+	return b.gpo.EstimateFees(ctx, b.pendingSample())
+}
+
+// pendingSample returns the public pending transactions, gathered at most once
+// per head block.
+func (b *EthAPIBackend) pendingSample() []*types.Transaction {
+	b.fees.lock.Lock()
+	defer b.fees.lock.Unlock()
+
+	head := b.eth.blockchain.CurrentBlock().Hash()
+	if head != b.fees.head {
+		var txs []*types.Transaction
+		for _, list := range b.eth.txPool.PublicPending(false) {
+			txs = append(txs, list...)
+		}
+		b.fees.head, b.fees.txs = head, txs
+	}
+	return b.fees.txs
+}
+
 func (b *EthAPIBackend) ChainDb() ethdb.Database {
 	return b.eth.ChainDb()
 }
@@ -0,0 +1,70 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/miner"
+)
+
+// BundleAPI provides an API to submit transaction bundles to the miner.
+type BundleAPI struct {
+	e *Ethereum
+}
+
+// NewBundleAPI creates a new BundleAPI instance.
+func NewBundleAPI(e *Ethereum) *BundleAPI {
+	return &BundleAPI{e}
+}
+
+// SendBundleArgs represents the arguments for submitting a bundle.
+type SendBundleArgs struct {
+	Txs               []hexutil.Bytes `json:"txs"`
+	BlockNumber       hexutil.Uint64  `json:"blockNumber"`
+	RevertingTxHashes []common.Hash   `json:"revertingTxHashes"`
+}
+
+// SendBundle submits an ordered group of signed transactions to be included
+// atomically at the top of the given block, all of them succeeding except for
+// the ones explicitly allowed to revert. The returned bundle hash is the hash
+// of the concatenated transaction hashes.
+func (api *BundleAPI) SendBundle(ctx context.Context, args SendBundleArgs) (common.Hash, error) {
+	bundle := &miner.Bundle{
+		Txs:         make(types.Transactions, len(args.Txs)),
+		BlockNumber: uint64(args.BlockNumber),
+		Reverting:   args.RevertingTxHashes,
+	}
+	hashes := make([]byte, 0, len(args.Txs)*common.HashLength)
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return common.Hash{}, fmt.Errorf("tx %d: %v", i, err)
+		}
+		bundle.Txs[i] = tx
+		hashes = append(hashes, tx.Hash().Bytes()...)
+	}
+	if err := api.e.Miner().SendBundle(bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(hashes), nil
+}
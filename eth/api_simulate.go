@@ -0,0 +1,106 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/miner"
+)
+
+// SimulationAPI provides an API to build hypothetical blocks on top of the
+// current chain head.
+type SimulationAPI struct {
+	e *Ethereum
+}
+
+// NewSimulationAPI creates a new SimulationAPI instance.
+func NewSimulationAPI(e *Ethereum) *SimulationAPI {
+	return &SimulationAPI{e}
+}
+
+// SimulateBlockArgs represents the arguments for simulating a block.
+type SimulateBlockArgs struct {
+	Txs         []hexutil.Bytes     `json:"txs"`
+	Coinbase    *common.Address     `json:"coinbase"`
+	Timestamp   *hexutil.Uint64     `json:"timestamp"`
+	Withdrawals []*types.Withdrawal `json:"withdrawals"`
+}
+
+// SimulateBlock builds the block which would follow the current head if it
+// contained the given signed transactions, in order. Transactions failing to
+// apply are left out, along with the subsequent ones of the same sender. The
+// block is neither sealed nor broadcast. Like eth_call, the simulation is bound
+// by the RPC gas cap and EVM timeout.
+func (api *SimulationAPI) SimulateBlock(ctx context.Context, args SimulateBlockArgs) (map[string]interface{}, error) {
+	simArgs := &miner.SimulateArgs{
+		Txs:         make(types.Transactions, len(args.Txs)),
+		Withdrawals: args.Withdrawals,
+		GasCap:      api.e.config.RPCGasCap,
+	}
+	for i, input := range args.Txs {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(input); err != nil {
+			return nil, fmt.Errorf("tx %d: %v", i, err)
+		}
+		simArgs.Txs[i] = tx
+	}
+	if args.Coinbase != nil {
+		simArgs.Coinbase = *args.Coinbase
+	}
+	if args.Timestamp != nil {
+		simArgs.Timestamp = uint64(*args.Timestamp)
+	}
+	// Bound the simulation by the EVM timeout, and make sure it's cancelled
+	// once the call has completed.
+	var (
+		cancel  context.CancelFunc
+		timeout = api.e.config.RPCEVMTimeout
+	)
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	sim, err := api.e.Miner().SimulateBlock(ctx, simArgs)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
+	}
+	if err != nil {
+		return nil, err
+	}
+	excluded := sim.Excluded
+	if excluded == nil {
+		excluded = []common.Hash{}
+	}
+	return map[string]interface{}{
+		"block":     ethapi.RPCMarshalBlock(sim.Block, true, false, api.e.BlockChain().Config()),
+		"receipts":  sim.Receipts,
+		"stateRoot": sim.Block.Root(),
+		"gasUsed":   hexutil.Uint64(sim.Block.GasUsed()),
+		"fees":      (*hexutil.Big)(sim.Fees),
+		"excluded":  excluded,
+	}, nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/userpool"
+)
+
+// UserOperationAPI provides the ERC-4337 bundler API on top of the user
+// operation pool.
+type UserOperationAPI struct {
+	e *Ethereum
+}
+
+// NewUserOperationAPI creates a new UserOperationAPI instance.
+func NewUserOperationAPI(e *Ethereum) *UserOperationAPI {
+	return &UserOperationAPI{e}
+}
+
+// checkEntryPoint ensures the requested EntryPoint is the one supported by the pool.
+func (api *UserOperationAPI) checkEntryPoint(entryPoint common.Address) error {
+	if supported := api.e.userPool.EntryPoint(); entryPoint != supported {
+		return fmt.Errorf("unsupported entry point %v, supported %v", entryPoint, supported)
+	}
+	return nil
+}
+
+// SupportedEntryPoints returns the EntryPoint contracts operations are accepted for.
+func (api *UserOperationAPI) SupportedEntryPoints() []common.Address {
+	return []common.Address{api.e.userPool.EntryPoint()}
+}
+
+// SendUserOperation validates a user operation and submits it to the pool,
+// returning its hash.
+func (api *UserOperationAPI) SendUserOperation(ctx context.Context, op userpool.UserOperation, entryPoint common.Address) (common.Hash, error) {
+	if err := api.checkEntryPoint(entryPoint); err != nil {
+		return common.Hash{}, err
+	}
+	return api.e.userPool.AddUserOp(&op)
+}
+
+// EstimateUserOperationGas estimates the gas limits of a user operation. The
+// signature may be a dummy one, as signature failures are ignored.
+func (api *UserOperationAPI) EstimateUserOperationGas(ctx context.Context, op userpool.UserOperation, entryPoint common.Address) (map[string]interface{}, error) {
+	if err := api.checkEntryPoint(entryPoint); err != nil {
+		return nil, err
+	}
+	estimate, err := api.e.userPool.EstimateGas(&op)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"preVerificationGas":   hexutil.Uint64(estimate.PreVerificationGas),
+		"verificationGasLimit": hexutil.Uint64(estimate.VerificationGasLimit),
+		"callGasLimit":         hexutil.Uint64(estimate.CallGasLimit),
+	}, nil
+}
+
+// GetUserOperationReceipt returns the outcome of a recently included user
+// operation, or nil if the operation is unknown or not yet included.
+func (api *UserOperationAPI) GetUserOperationReceipt(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	txHash, ok := api.e.userPool.Included(hash)
+	if !ok {
+		return nil, nil
+	}
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(api.e.chainDb, txHash)
+	if tx == nil {
+		return nil, nil // reorged out, the operation is lost and must be resubmitted
+	}
+	receipts := api.e.blockchain.GetReceiptsByHash(blockHash)
+	if uint64(len(receipts)) <= index {
+		return nil, nil
+	}
+	receipt := receipts[index]
+	for _, log := range receipt.Logs {
+		if log.Address != api.e.userPool.EntryPoint() || len(log.Topics) < 2 || log.Topics[1] != hash {
+			continue
+		}
+		event, err := userpool.UnpackUserOperationEvent(log)
+		if err != nil {
+			continue
+		}
+		return map[string]interface{}{
+			"userOpHash":    hash,
+			"entryPoint":    log.Address,
+			"sender":        event.Sender,
+			"paymaster":     event.Paymaster,
+			"nonce":         (*hexutil.Big)(event.Nonce),
+			"success":       event.Success,
+			"actualGasCost": (*hexutil.Big)(event.ActualGasCost),
+			"actualGasUsed": (*hexutil.Big)(event.ActualGasUsed),
+			"receipt": map[string]interface{}{
+				"transactionHash":   txHash,
+				"blockHash":         blockHash,
+				"blockNumber":       hexutil.Uint64(blockNumber),
+				"transactionIndex":  hexutil.Uint64(index),
+				"status":            hexutil.Uint64(receipt.Status),
+				"gasUsed":           hexutil.Uint64(receipt.GasUsed),
+				"cumulativeGasUsed": hexutil.Uint64(receipt.CumulativeGasUsed),
+			},
+		}, nil
+	}
+	return nil, nil
+}
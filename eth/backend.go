@@ -23,6 +23,7 @@ import (
 	"math/big"
 	"runtime"
 	"sync"
+	"sync/atomic"
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
@@ -36,6 +37,8 @@ import (
 	"github.com/ethereum/go-ethereum/core/state/pruner"
 	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
+	"github.com/ethereum/go-ethereum/core/txpool/userpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/downloader"
@@ -53,6 +56,7 @@ import (
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/p2p/dnsdisc"
 	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/portal"
 	"github.com/ethereum/go-ethereum/params"
 	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -67,7 +71,9 @@ type Ethereum struct {
 	config *ethconfig.Config
 
 	// Handlers
-	txPool *txpool.TxPool
+	txPool      *txpool.TxPool
+	privatePool *privatepool.PrivatePool
+	userPool    *userpool.UserPool // Nil if user operations are not accepted
 
 	blockchain         *core.BlockChain
 	handler            *handler
@@ -95,7 +101,8 @@ type Ethereum struct {
 	networkID     uint64
 	netRPCService *ethapi.NetAPI
 
-	p2pServer *p2p.Server
+	p2pServer     *p2p.Server
+	portalHistory atomic.Pointer[portal.HistoryNetwork] // Nil if the portal history network is disabled
 
 	lock sync.RWMutex // Protects the variadic fields (e.g. gas price and etherbase)
 
@@ -209,9 +216,26 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
+	if config.TxPool.Snapshot != "" {
+		config.TxPool.Snapshot = stack.ResolvePath(config.TxPool.Snapshot)
+	}
 	legacyPool := legacypool.New(config.TxPool, eth.blockchain)
-
-	eth.txPool, err = txpool.New(new(big.Int).SetUint64(config.TxPool.PriceLimit), eth.blockchain, []txpool.SubPool{legacyPool})
+	eth.privatePool = privatepool.New(config.PrivatePool, eth.blockchain)
+
+	subpools := []txpool.SubPool{eth.privatePool, legacyPool}
+	if config.UserPool.EntryPoint != (common.Address{}) {
+		bundler := accounts.Account{Address: config.UserPool.Bundler}
+		sign := func(tx *types.Transaction) (*types.Transaction, error) {
+			wallet, err := eth.accountManager.Find(bundler)
+			if err != nil {
+				return nil, err
+			}
+			return wallet.SignTx(bundler, tx, eth.blockchain.Config().ChainID)
+		}
+		eth.userPool = userpool.New(config.UserPool, eth.blockchain, sign)
+		subpools = append(subpools, eth.userPool)
+	}
+	eth.txPool, err = txpool.New(new(big.Int).SetUint64(config.TxPool.PriceLimit), eth.blockchain, subpools)
 	if err != nil {
 		return nil, err
 	}
@@ -234,7 +258,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 	eth.miner = miner.New(eth, &config.Miner, eth.blockchain.Config(), eth.EventMux(), eth.engine, eth.isLocalBlock)
 	eth.miner.SetExtra(makeExtraData(config.Miner.ExtraData))
 
-	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil}
+	eth.APIBackend = &EthAPIBackend{stack.Config().ExtRPCEnabled(), stack.Config().AllowUnprotectedTxs, eth, nil, new(feeSample)}
 	if eth.APIBackend.allowUnprotectedTxs {
 		log.Info("Unprotected transactions allowed")
 	}
@@ -294,11 +318,21 @@ func (s *Ethereum) APIs() []rpc.API {
 	// Append any APIs exposed explicitly by the consensus engine
 	apis = append(apis, s.engine.APIs(s.BlockChain())...)
 
+	// Append the user operation APIs if enabled
+	if s.userPool != nil {
+		apis = append(apis, rpc.API{Namespace: "eth", Service: NewUserOperationAPI(s)})
+	}
 	// Append all the local APIs and return
 	return append(apis, []rpc.API{
 		{
 			Namespace: "eth",
 			Service:   NewEthereumAPI(s),
+		}, {
+			Namespace: "miner",
+			Service:   NewBundleAPI(s),
+		}, {
+			Namespace: "eth",
+			Service:   NewSimulationAPI(s),
 		}, {
 			Namespace: "miner",
 			Service:   NewMinerAPI(s),
@@ -505,6 +539,12 @@ func (s *Ethereum) Start() error {
 	}
 	// Start the networking layer and the light server if requested
 	s.handler.Start(maxPeers)
+
+	if s.config.PortalHistory {
+		if err := s.startPortalHistory(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -515,6 +555,9 @@ func (s *Ethereum) Stop() error {
 	s.ethDialCandidates.Close()
 	s.snapDialCandidates.Close()
 	s.handler.Stop()
+	if history := s.portalHistory.Load(); history != nil {
+		history.Stop()
+	}
 
 	// Then stop everything else.
 	s.bloomIndexer.Close()
@@ -0,0 +1,140 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/beacon/light"
+	lightapi "github.com/ethereum/go-ethereum/beacon/light/api"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+)
+
+// payloadSource retrieves the execution payloads of beacon blocks.
+type payloadSource interface {
+	GetExecutionPayload(blockRoot common.Hash) (*engine.ExecutableData, []common.Hash, error)
+}
+
+// LightBeacon is an auxiliary service that drives the chain head of Geth by
+// following the beacon chain with a light client, without a consensus client
+// attached. The chain head is only verified by sync committee signatures, so
+// it's meant for RPC-only use.
+type LightBeacon struct {
+	api      *ConsensusAPI
+	client   *light.Client
+	payloads payloadSource
+	closed   chan struct{}
+	wg       sync.WaitGroup
+}
+
+// RegisterLightBeacon registers the light beacon service into the node stack
+// for launching and stopping the service controlled by node. The light client
+// data and execution payloads are retrieved from the beacon node REST API at
+// the given URL.
+func RegisterLightBeacon(stack *node.Node, backend *eth.Ethereum, url string, headers map[string]string, config light.Config) (*LightBeacon, error) {
+	if config.ChainConfig == nil {
+		return nil, errors.New("beacon chain config missing")
+	}
+	if config.Checkpoint == (common.Hash{}) {
+		return nil, errors.New("beacon chain checkpoint missing")
+	}
+	beaconAPI := lightapi.NewBeaconLightApi(url, headers)
+	lb := &LightBeacon{
+		api:      NewConsensusAPI(backend),
+		client:   light.NewClient(beaconAPI, config),
+		payloads: beaconAPI,
+		closed:   make(chan struct{}),
+	}
+	stack.RegisterLifecycle(lb)
+	return lb, nil
+}
+
+// Start launches the light client and the import of verified heads.
+func (lb *LightBeacon) Start() error {
+	heads := make(chan light.HeadEvent, 1)
+	sub := lb.client.SubscribeHeads(heads)
+	lb.client.Start()
+
+	lb.wg.Add(1)
+	go func() {
+		defer lb.wg.Done()
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case head := <-heads:
+				lb.newHead(head)
+			case <-lb.closed:
+				return
+			}
+		}
+	}()
+	log.Warn("Following beacon chain with light client, chain head is not fully validated")
+	return nil
+}
+
+// Stop stops the import of heads and the light client.
+func (lb *LightBeacon) Stop() error {
+	close(lb.closed)
+	lb.wg.Wait()
+	lb.client.Stop()
+	return nil
+}
+
+// newHead retrieves the execution payload of a verified beacon head, checks it
+// against the proven block hash and imports it through the engine API.
+func (lb *LightBeacon) newHead(head light.HeadEvent) {
+	blockRoot := head.Beacon.Hash()
+	payload, versionedHashes, err := lb.payloads.GetExecutionPayload(blockRoot)
+	if err != nil {
+		log.Warn("Failed to retrieve execution payload", "slot", head.Beacon.Slot, "root", blockRoot, "err", err)
+		return
+	}
+	// The payload is hashed when converted into a block, so checking the hash
+	// verifies its contents.
+	if payload.BlockHash != head.Payload.BlockHash {
+		log.Warn("Execution payload does not match verified header", "slot", head.Beacon.Slot, "have", payload.BlockHash, "want", head.Payload.BlockHash)
+		return
+	}
+	var status engine.PayloadStatusV1
+	if payload.ExcessDataGas != nil {
+		status, err = lb.api.NewPayloadV3(*payload, &versionedHashes)
+	} else {
+		status, err = lb.api.NewPayloadV2(*payload)
+	}
+	if err != nil {
+		log.Warn("Failed to import execution payload", "number", payload.Number, "hash", payload.BlockHash, "err", err)
+		return
+	}
+	if status.Status == engine.INVALID {
+		log.Error("Verified execution payload is invalid", "number", payload.Number, "hash", payload.BlockHash, "err", status.ValidationError)
+		return
+	}
+	update := engine.ForkchoiceStateV1{HeadBlockHash: payload.BlockHash}
+	if head.Finalized != nil {
+		update.SafeBlockHash = head.Finalized.BlockHash
+		update.FinalizedBlockHash = head.Finalized.BlockHash
+	}
+	if _, err := lb.api.ForkchoiceUpdatedV2(update, nil); err != nil {
+		log.Warn("Failed to update forkchoice", "head", update.HeadBlockHash, "finalized", update.FinalizedBlockHash, "err", err)
+	}
+}
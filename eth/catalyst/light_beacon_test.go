@@ -0,0 +1,74 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package catalyst
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/beacon/engine"
+	"github.com/ethereum/go-ethereum/beacon/light"
+	btypes "github.com/ethereum/go-ethereum/beacon/types"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// testPayloadSource serves a single execution payload for any beacon block.
+type testPayloadSource struct {
+	payload *engine.ExecutableData
+}
+
+func (s *testPayloadSource) GetExecutionPayload(common.Hash) (*engine.ExecutableData, []common.Hash, error) {
+	if s.payload == nil {
+		return nil, nil, errors.New("not found")
+	}
+	return s.payload, nil, nil
+}
+
+func TestLightBeaconNewHead(t *testing.T) {
+	genesis, preMergeBlocks := generateMergeChain(10, false)
+	n, ethservice := startEthService(t, genesis, preMergeBlocks)
+	defer n.Close()
+
+	var (
+		api     = NewConsensusAPI(ethservice)
+		source  = new(testPayloadSource)
+		lb      = &LightBeacon{api: api, payloads: source}
+		parent  = ethservice.BlockChain().CurrentBlock()
+		payload = getNewPayload(t, api, parent, nil)
+		head    = light.HeadEvent{
+			Payload:   &btypes.ExecutionHeader{BlockHash: payload.BlockHash},
+			Finalized: &btypes.ExecutionHeader{BlockHash: parent.Hash()},
+		}
+	)
+	// A payload not matching the verified header must not be imported.
+	source.payload = getNewPayload(t, api, parent, nil)
+	source.payload.ExtraData = []byte{1}
+	source.payload = setBlockhash(source.payload)
+	lb.newHead(head)
+	if current := ethservice.BlockChain().CurrentBlock(); current.Hash() != parent.Hash() {
+		t.Fatalf("mismatching payload imported, head %d", current.Number)
+	}
+	// The matching payload becomes the new head.
+	source.payload = payload
+	lb.newHead(head)
+	if current := ethservice.BlockChain().CurrentBlock(); current.Hash() != payload.BlockHash {
+		t.Fatalf("wrong head %d %v, want %d %v", current.Number, current.Hash(), payload.Number, payload.BlockHash)
+	}
+	if final := ethservice.BlockChain().CurrentFinalBlock(); final == nil || final.Hash() != parent.Hash() {
+		t.Fatal("finalized block not updated")
+	}
+}
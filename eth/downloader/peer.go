@@ -69,6 +69,12 @@ type Peer interface {
 	RequestReceipts([]common.Hash, chan *eth.Response) (*eth.Request, error)
 }
 
+// rangedPeer is a peer advertising the range of blocks it serves (eth/69 and
+// later). Blocks outside of it are not requested from the peer.
+type rangedPeer interface {
+	BlockRange() (earliest uint64, latest uint64, ok bool)
+}
+
 // lightPeerWrapper wraps a LightPeer struct, stubbing out the Peer-only methods.
 type lightPeerWrapper struct {
 	peer LightPeer
@@ -181,6 +187,20 @@ func (p *peerConnection) Lacks(hash common.Hash) bool {
 	return ok
 }
 
+// Serves retrieves whether the block with the given number is within the range
+// of blocks advertised by the peer. Peers not advertising one serve everything.
+//
+// Only the lower bound is enforced: the history below it was pruned by the peer,
+// whereas the upper bound is only re-announced every few blocks.
+func (p *peerConnection) Serves(number uint64) bool {
+	ranged, ok := p.peer.(rangedPeer)
+	if !ok {
+		return true
+	}
+	earliest, _, ok := ranged.BlockRange()
+	return !ok || number >= earliest
+}
+
 // peeringEvent is sent on the peer event feed when a remote peer connects or
 // disconnects.
 type peeringEvent struct {
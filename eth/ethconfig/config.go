@@ -28,6 +28,8 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/ethash"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
+	"github.com/ethereum/go-ethereum/core/txpool/userpool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -69,10 +71,14 @@ var Defaults = Config{
 	FilterLogCacheSize: 32,
 	Miner:              miner.DefaultConfig,
 	TxPool:             legacypool.DefaultConfig,
+	PrivatePool:        privatepool.DefaultConfig,
+	UserPool:           userpool.DefaultConfig,
 	RPCGasCap:          50000000,
 	RPCEVMTimeout:      5 * time.Second,
 	GPO:                FullNodeGPO,
 	RPCTxFeeCap:        1, // 1 ether
+
+	PortalHistoryStorage: 1024 * 1024 * 1024,
 }
 
 //go:generate go run github.com/fjl/gencodec -type Config -formats toml -out gen_config.go
@@ -110,6 +116,11 @@ type Config struct {
 	LightNoPrune     bool `toml:",omitempty"` // Whether to disable light chain pruning
 	LightNoSyncServe bool `toml:",omitempty"` // Whether to serve light clients before syncing
 
+	// Portal history network options
+	PortalHistory          bool     `toml:",omitempty"` // Whether to retrieve missing block bodies and receipts from the portal history network
+	PortalHistoryBootnodes []string `toml:",omitempty"` // Bootstrap nodes of the portal history network
+	PortalHistoryStorage   uint64   `toml:",omitempty"` // Maximum size of stored portal content in bytes, zero means unlimited
+
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
@@ -129,7 +140,9 @@ type Config struct {
 	Miner miner.Config
 
 	// Transaction pool options
-	TxPool legacypool.Config
+	TxPool      legacypool.Config
+	PrivatePool privatepool.Config
+	UserPool    userpool.Config
 
 	// Gas Price Oracle options
 	GPO gasprice.Config
@@ -8,6 +8,8 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
+	"github.com/ethereum/go-ethereum/core/txpool/userpool"
 	"github.com/ethereum/go-ethereum/eth/downloader"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
 	"github.com/ethereum/go-ethereum/miner"
@@ -31,6 +33,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		LightPeers              int                    `toml:",omitempty"`
 		LightNoPrune            bool                   `toml:",omitempty"`
 		LightNoSyncServe        bool                   `toml:",omitempty"`
+		PortalHistory           bool                   `toml:",omitempty"`
+		PortalHistoryBootnodes  []string               `toml:",omitempty"`
+		PortalHistoryStorage    uint64                 `toml:",omitempty"`
 		SkipBcVersionCheck      bool                   `toml:"-"`
 		DatabaseHandles         int                    `toml:"-"`
 		DatabaseCache           int
@@ -43,6 +48,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		FilterLogCacheSize      int
 		Miner                   miner.Config
 		TxPool                  legacypool.Config
+		PrivatePool             privatepool.Config
+		UserPool                userpool.Config
 		GPO                     gasprice.Config
 		EnablePreimageRecording bool
 		DocRoot                 string `toml:"-"`
@@ -68,6 +75,9 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.LightPeers = c.LightPeers
 	enc.LightNoPrune = c.LightNoPrune
 	enc.LightNoSyncServe = c.LightNoSyncServe
+	enc.PortalHistory = c.PortalHistory
+	enc.PortalHistoryBootnodes = c.PortalHistoryBootnodes
+	enc.PortalHistoryStorage = c.PortalHistoryStorage
 	enc.SkipBcVersionCheck = c.SkipBcVersionCheck
 	enc.DatabaseHandles = c.DatabaseHandles
 	enc.DatabaseCache = c.DatabaseCache
@@ -80,6 +90,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.FilterLogCacheSize = c.FilterLogCacheSize
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
+	enc.PrivatePool = c.PrivatePool
+	enc.UserPool = c.UserPool
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.DocRoot = c.DocRoot
@@ -109,6 +121,9 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		LightPeers              *int                   `toml:",omitempty"`
 		LightNoPrune            *bool                  `toml:",omitempty"`
 		LightNoSyncServe        *bool                  `toml:",omitempty"`
+		PortalHistory           *bool                  `toml:",omitempty"`
+		PortalHistoryBootnodes  []string               `toml:",omitempty"`
+		PortalHistoryStorage    *uint64                `toml:",omitempty"`
 		SkipBcVersionCheck      *bool                  `toml:"-"`
 		DatabaseHandles         *int                   `toml:"-"`
 		DatabaseCache           *int
@@ -121,6 +136,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		FilterLogCacheSize      *int
 		Miner                   *miner.Config
 		TxPool                  *legacypool.Config
+		PrivatePool             *privatepool.Config
+		UserPool                *userpool.Config
 		GPO                     *gasprice.Config
 		EnablePreimageRecording *bool
 		DocRoot                 *string `toml:"-"`
@@ -179,6 +196,15 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.LightNoSyncServe != nil {
 		c.LightNoSyncServe = *dec.LightNoSyncServe
 	}
+	if dec.PortalHistory != nil {
+		c.PortalHistory = *dec.PortalHistory
+	}
+	if dec.PortalHistoryBootnodes != nil {
+		c.PortalHistoryBootnodes = dec.PortalHistoryBootnodes
+	}
+	if dec.PortalHistoryStorage != nil {
+		c.PortalHistoryStorage = *dec.PortalHistoryStorage
+	}
 	if dec.SkipBcVersionCheck != nil {
 		c.SkipBcVersionCheck = *dec.SkipBcVersionCheck
 	}
@@ -215,6 +241,12 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.TxPool != nil {
 		c.TxPool = *dec.TxPool
 	}
+	if dec.PrivatePool != nil {
+		c.PrivatePool = *dec.PrivatePool
+	}
+	if dec.UserPool != nil {
+		c.UserPool = *dec.UserPool
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
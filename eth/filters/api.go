@@ -28,6 +28,7 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
@@ -36,6 +37,7 @@ import (
 var (
 	errInvalidTopic   = errors.New("invalid topic(s)")
 	errFilterNotFound = errors.New("filter not found")
+	errNoDroppedTxs   = errors.New("dropped transactions not supported by backend")
 )
 
 // filter is a helper struct that holds meta information over the filter type
@@ -183,6 +185,59 @@ func (api *FilterAPI) NewPendingTransactions(ctx context.Context, fullTx *bool)
 	return rpcSub, nil
 }
 
+// droppedTransaction is a transaction removed from the pool without being included
+// in the chain, as announced to droppedTransactions subscribers.
+type droppedTransaction struct {
+	Hash        common.Hash  `json:"hash"`
+	Reason      string       `json:"reason"`
+	Replacement *common.Hash `json:"replacement,omitempty"`
+}
+
+// DroppedTransactions creates a subscription that is triggered each time a
+// transaction is removed from the transaction pool without being included in the
+// chain, along with the reason of its removal.
+func (api *FilterAPI) DroppedTransactions(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	backend, ok := api.sys.backend.(droppedTxsBackend)
+	if !ok {
+		return &rpc.Subscription{}, errNoDroppedTxs
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		drops := make(chan txpool.DroppedTxsEvent, 128)
+		dropSub := backend.SubscribeDroppedTxsEvent(drops)
+		defer dropSub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-drops:
+				for _, drop := range ev.Txs {
+					dropped := &droppedTransaction{
+						Hash:   drop.Tx.Hash(),
+						Reason: drop.Reason.String(),
+					}
+					if drop.Replacement != (common.Hash{}) {
+						replacement := drop.Replacement
+						dropped.Replacement = &replacement
+					}
+					notifier.Notify(rpcSub.ID, dropped)
+				}
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // NewBlockFilter creates a filter that fetches blocks that are imported into the chain.
 // It is part of the filter package since polling goes with eth_getFilterChanges.
 func (api *FilterAPI) NewBlockFilter() rpc.ID {
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
@@ -77,6 +78,12 @@ type Backend interface {
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
 }
 
+// droppedTxsBackend is implemented by backends with a local transaction pool,
+// which can report transactions leaving the pool.
+type droppedTxsBackend interface {
+	SubscribeDroppedTxsEvent(chan<- txpool.DroppedTxsEvent) event.Subscription
+}
+
 // FilterSystem holds resources shared by all filters.
 type FilterSystem struct {
 	backend   Backend
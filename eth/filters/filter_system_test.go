@@ -33,6 +33,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -46,6 +47,7 @@ type testBackend struct {
 	db              ethdb.Database
 	sections        uint64
 	txFeed          event.Feed
+	dropFeed        event.Feed
 	logsFeed        event.Feed
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
@@ -133,6 +135,10 @@ func (b *testBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Sub
 	return b.txFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeDroppedTxsEvent(ch chan<- txpool.DroppedTxsEvent) event.Subscription {
+	return b.dropFeed.Subscribe(ch)
+}
+
 func (b *testBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription {
 	return b.rmLogsFeed.Subscribe(ch)
 }
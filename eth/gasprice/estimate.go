@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	// estimateHistory is the number of recent blocks the fee estimation is
+	// based on.
+	estimateHistory = 40
+
+	// inclusionPercentile is the percentile of the block rewards considered the
+	// lowest tip getting a transaction included, filtering out outliers such as
+	// zero tip transactions of the block producer.
+	inclusionPercentile = 5
+)
+
+// feeTiers are the inclusion targets fees are estimated for.
+var feeTiers = []struct {
+	name       string
+	blocks     uint64
+	confidence float64
+}{
+	{"slow", 10, 50},
+	{"normal", 3, 75},
+	{"fast", 1, 95},
+}
+
+// FeeTier is a fee suggestion for a transaction to be included within a number
+// of blocks with a given confidence.
+type FeeTier struct {
+	Name                 string
+	Blocks               uint64   // Number of blocks the transaction is expected to be included within
+	Confidence           float64  // Percentage of recent blocks in which the tip would have sufficed
+	MaxPriorityFeePerGas *big.Int // Suggested tip, or gas price before London
+	MaxFeePerGas         *big.Int // Suggested fee cap covering the projected base fee, nil before London
+	MaxFeePerBlobGas     *big.Int // Suggested blob fee cap, nil before Cancun
+}
+
+// FeeEstimate contains the fee suggestions for the different inclusion targets.
+type FeeEstimate struct {
+	BaseFee     *big.Int // Base fee of the next block, nil before London
+	BlobBaseFee *big.Int // Blob base fee of the next block, nil before Cancun
+	Tiers       []FeeTier
+}
+
+// EstimateFees suggests fees for a transaction to be included within a few
+// blocks, for a number of different targets. Tips are derived from the lowest
+// rewards included by recent blocks, raised to outbid the pending transactions
+// expected to fill the targeted blocks. Fee caps cover the base fees projected
+// for the targeted blocks, assuming all of them are full.
+func (oracle *Oracle) EstimateFees(ctx context.Context, pending []*types.Transaction) (*FeeEstimate, error) {
+	head, err := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	_, rewards, _, _, _, _, err := oracle.FeeHistory(ctx, estimateHistory, rpc.LatestBlockNumber, []float64{inclusionPercentile})
+	if err != nil {
+		return nil, err
+	}
+	minRewards := make([]*big.Int, 0, len(rewards))
+	for _, reward := range rewards {
+		if reward != nil {
+			minRewards = append(minRewards, reward[0])
+		}
+	}
+	var (
+		config  = oracle.backend.ChainConfig()
+		next    = new(big.Int).Add(head.Number, common.Big1)
+		london  = config.IsLondon(next)
+		nextFee *big.Int
+	)
+	if london {
+		nextFee = projectBaseFee(config, head, 1)
+	}
+	estimate := &FeeEstimate{
+		BaseFee:     nextFee,
+		BlobBaseFee: projectBlobFee(head, 1),
+	}
+	for _, t := range feeTiers {
+		tip := windowTip(minRewards, t.blocks, t.confidence)
+		if poolTip := pendingTip(pending, nextFee, head.GasLimit, t.blocks); poolTip.Cmp(tip) > 0 {
+			tip = poolTip
+		}
+		if tip.Cmp(oracle.maxPrice) > 0 {
+			tip = new(big.Int).Set(oracle.maxPrice)
+		}
+		tier := FeeTier{
+			Name:                 t.name,
+			Blocks:               t.blocks,
+			Confidence:           t.confidence,
+			MaxPriorityFeePerGas: tip,
+			MaxFeePerBlobGas:     projectBlobFee(head, t.blocks),
+		}
+		if london {
+			tier.MaxFeePerGas = new(big.Int).Add(projectBaseFee(config, head, t.blocks), tip)
+		}
+		estimate.Tiers = append(estimate.Tiers, tier)
+	}
+	return estimate, nil
+}
+
+// windowTip returns the tip which would have been included within any window of
+// the given number of consecutive blocks, in the given percentage of windows.
+// The rewards are the lowest tips included by each block.
+func windowTip(rewards []*big.Int, blocks uint64, confidence float64) *big.Int {
+	if uint64(len(rewards)) < blocks {
+		blocks = uint64(len(rewards))
+	}
+	if blocks == 0 {
+		return new(big.Int)
+	}
+	var mins []*big.Int
+	for i := 0; i+int(blocks) <= len(rewards); i++ {
+		lowest := rewards[i]
+		for _, reward := range rewards[i+1 : i+int(blocks)] {
+			if reward.Cmp(lowest) < 0 {
+				lowest = reward
+			}
+		}
+		mins = append(mins, lowest)
+	}
+	slices.SortFunc(mins, func(a, b *big.Int) bool { return a.Cmp(b) < 0 })
+
+	index := int(math.Ceil(confidence/100*float64(len(mins)))) - 1
+	if index < 0 {
+		index = 0
+	}
+	return new(big.Int).Set(mins[index])
+}
+
+// pendingTip returns the tip needed to outbid the pending transactions which
+// fill the given number of blocks, or zero if they don't.
+func pendingTip(pending []*types.Transaction, baseFee *big.Int, gasLimit uint64, blocks uint64) *big.Int {
+	type txTip struct {
+		gas uint64
+		tip *big.Int
+	}
+	tips := make([]txTip, 0, len(pending))
+	for _, tx := range pending {
+		tip, err := tx.EffectiveGasTip(baseFee)
+		if err != nil {
+			continue // not includable at the current base fee
+		}
+		tips = append(tips, txTip{gas: tx.Gas(), tip: tip})
+	}
+	slices.SortFunc(tips, func(a, b txTip) bool { return a.tip.Cmp(b.tip) > 0 })
+
+	var (
+		capacity = gasLimit * blocks
+		used     uint64
+	)
+	for _, t := range tips {
+		if used += t.gas; used > capacity {
+			return new(big.Int).Add(t.tip, common.Big1)
+		}
+	}
+	return new(big.Int)
+}
+
+// projectBaseFee returns the highest base fee the given number of blocks after
+// the head may have, assuming all of them are full.
+func projectBaseFee(config *params.ChainConfig, head *types.Header, blocks uint64) *big.Int {
+	var (
+		parent = head
+		fee    *big.Int
+	)
+	for i := uint64(0); i < blocks; i++ {
+		fee = misc.CalcBaseFee(config, parent)
+		parent = &types.Header{
+			Number:   new(big.Int).Add(parent.Number, common.Big1),
+			GasLimit: parent.GasLimit,
+			GasUsed:  parent.GasLimit,
+			BaseFee:  fee,
+		}
+	}
+	return fee
+}
+
+// projectBlobFee returns the highest blob base fee the given number of blocks
+// after the head may have, assuming all of them contain the maximum number of
+// blobs. Nil is returned before Cancun.
+func projectBlobFee(head *types.Header, blocks uint64) *big.Int {
+	if head.ExcessDataGas == nil || head.DataGasUsed == nil {
+		return nil
+	}
+	excess, used := *head.ExcessDataGas, *head.DataGasUsed
+	for i := uint64(0); i < blocks; i++ {
+		excess = misc.CalcExcessDataGas(excess, used)
+		used = params.BlobTxMaxDataGasPerBlock
+	}
+	return misc.CalcBlobFee(excess)
+}
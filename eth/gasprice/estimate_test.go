@@ -0,0 +1,91 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package gasprice
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func TestEstimateFees(t *testing.T) {
+	backend := newTestBackend(t, big.NewInt(0), false)
+	defer backend.teardown()
+
+	oracle := NewOracle(backend, Config{MaxHeaderHistory: 1000, MaxBlockHistory: 1000})
+	head := backend.chain.GetHeaderByNumber(testHead)
+
+	// A huge transaction filling the next block, and a small one pushed out of it
+	pending := []*types.Transaction{
+		types.NewTx(&types.DynamicFeeTx{Gas: head.GasLimit, GasTipCap: big.NewInt(50 * params.GWei), GasFeeCap: big.NewInt(1000 * params.GWei)}),
+		types.NewTx(&types.DynamicFeeTx{Gas: 21000, GasTipCap: big.NewInt(40 * params.GWei), GasFeeCap: big.NewInt(1000 * params.GWei)}),
+	}
+	estimate, err := oracle.EstimateFees(context.Background(), pending)
+	if err != nil {
+		t.Fatalf("failed to estimate fees: %v", err)
+	}
+	nextFee := misc.CalcBaseFee(backend.ChainConfig(), head)
+	if estimate.BaseFee.Cmp(nextFee) != 0 {
+		t.Errorf("base fee mismatch: have %v, want %v", estimate.BaseFee, nextFee)
+	}
+	if estimate.BlobBaseFee != nil {
+		t.Errorf("blob base fee reported before Cancun: %v", estimate.BlobBaseFee)
+	}
+	// Block n includes a single transaction tipping n gwei
+	want := map[string]*big.Int{
+		"slow":   big.NewInt(11 * params.GWei),
+		"normal": big.NewInt(23 * params.GWei),
+		"fast":   big.NewInt(40*params.GWei + 1), // outbidding the pushed out transaction
+	}
+	if len(estimate.Tiers) != len(want) {
+		t.Fatalf("tier count mismatch: have %d, want %d", len(estimate.Tiers), len(want))
+	}
+	for _, tier := range estimate.Tiers {
+		if tier.MaxPriorityFeePerGas.Cmp(want[tier.Name]) != 0 {
+			t.Errorf("%s tier tip mismatch: have %v, want %v", tier.Name, tier.MaxPriorityFeePerGas, want[tier.Name])
+		}
+		wantCap := new(big.Int).Add(projectBaseFee(backend.ChainConfig(), head, tier.Blocks), tier.MaxPriorityFeePerGas)
+		if tier.MaxFeePerGas.Cmp(wantCap) != 0 {
+			t.Errorf("%s tier fee cap mismatch: have %v, want %v", tier.Name, tier.MaxFeePerGas, wantCap)
+		}
+		if tier.MaxFeePerGas.Cmp(new(big.Int).Add(nextFee, tier.MaxPriorityFeePerGas)) < 0 {
+			t.Errorf("%s tier fee cap below next block requirement", tier.Name)
+		}
+	}
+}
+
+func TestProjectBlobFee(t *testing.T) {
+	if fee := projectBlobFee(&types.Header{}, 1); fee != nil {
+		t.Errorf("blob fee projected before Cancun: %v", fee)
+	}
+	var (
+		excess = uint64(10 * params.BlobTxTargetDataGasPerBlock)
+		used   = uint64(0)
+		head   = &types.Header{ExcessDataGas: &excess, DataGasUsed: &used}
+	)
+	// An empty head block lowers the excess, full ones raise it afterwards
+	if have, want := projectBlobFee(head, 1), misc.CalcBlobFee(excess-params.BlobTxTargetDataGasPerBlock); have.Cmp(want) != 0 {
+		t.Errorf("next blob fee mismatch: have %v, want %v", have, want)
+	}
+	if have, want := projectBlobFee(head, 3), misc.CalcBlobFee(excess+params.BlobTxTargetDataGasPerBlock); have.Cmp(want) != 0 {
+		t.Errorf("projected blob fee mismatch: have %v, want %v", have, want)
+	}
+}
@@ -51,6 +51,14 @@ const (
 	// All transactions with a higher size will be announced and need to be fetched
 	// by the peer.
 	txMaxBroadcastSize = 4096
+
+	// blockRangeUpdateInterval is the number of blocks after which the range of
+	// served blocks is re-announced to eth/69 peers.
+	blockRangeUpdateInterval = 32
+
+	// uselessPeerPenalty is the reputation penalty of peers dropped by the
+	// downloader or the fetchers.
+	uselessPeerPenalty = 10
 )
 
 var (
@@ -75,6 +83,10 @@ type txPool interface {
 	// The slice should be modifiable by the caller.
 	Pending(enforceTips bool) map[common.Address][]*types.Transaction
 
+	// Private returns whether the transaction with the given hash was submitted
+	// privately, and must thus never be propagated to the network.
+	Private(hash common.Hash) bool
+
 	// SubscribeNewTxsEvent should return an event subscription of
 	// NewTxsEvent and send events to the given channel.
 	SubscribeNewTxsEvent(chan<- core.NewTxsEvent) event.Subscription
@@ -354,7 +366,7 @@ func (h *handler) runEthPeer(peer *eth.Peer, handler eth.Handler) error {
 		td      = h.chain.GetTd(hash, number)
 	)
 	forkID := forkid.NewID(h.chain.Config(), genesis.Hash(), number, head.Time)
-	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter); err != nil {
+	if err := peer.Handshake(h.networkID, td, hash, genesis.Hash(), forkID, h.forkFilter, h.blockRange(head)); err != nil {
 		peer.Log().Debug("Ethereum handshake failed", "err", err)
 		return err
 	}
@@ -479,10 +491,11 @@ func (h *handler) runSnapExtension(peer *snap.Peer, handler snap.Handler) error
 	return handler(peer)
 }
 
-// removePeer requests disconnection of a peer.
+// removePeer requests disconnection of a peer, lowering its reputation.
 func (h *handler) removePeer(id string) {
 	peer := h.peers.peer(id)
 	if peer != nil {
+		peer.Peer.Penalize(uselessPeerPenalty, "dropped by sync")
 		peer.Peer.Disconnect(p2p.DiscUselessPeer)
 	}
 }
@@ -539,6 +552,10 @@ func (h *handler) Start(maxPeers int) {
 	// start peer handler tracker
 	h.wg.Add(1)
 	go h.protoTracker()
+
+	// announce the range of served blocks
+	h.wg.Add(1)
+	go h.blockRangeLoop()
 }
 
 func (h *handler) Stop() {
@@ -620,6 +637,11 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	)
 	// Broadcast transactions to a batch of peers not knowing about it
 	for _, tx := range txs {
+		// Conditional transactions are not propagated, as their preconditions
+		// are local metadata that would be lost along the way.
+		if tx.Conditional() != nil {
+			continue
+		}
 		peers := h.peers.peersWithoutTransaction(tx.Hash())
 
 		var numDirect int
@@ -662,6 +684,54 @@ func (h *handler) minedBroadcastLoop() {
 	}
 }
 
+// blockRange returns the range of blocks served by the local node, up to the
+// given head. Blocks below the ancient store's tail are pruned.
+func (h *handler) blockRange(head *types.Header) eth.BlockRangeUpdatePacket {
+	earliest, err := h.database.Tail()
+	if err != nil {
+		earliest = 0 // no ancient store, all history retained
+	}
+	if latest := head.Number.Uint64(); earliest > latest {
+		earliest = latest
+	}
+	return eth.BlockRangeUpdatePacket{
+		EarliestBlock:   earliest,
+		LatestBlock:     head.Number.Uint64(),
+		LatestBlockHash: head.Hash(),
+	}
+}
+
+// blockRangeLoop announces the range of served blocks to eth/69 peers, every
+// few blocks as the chain progresses.
+func (h *handler) blockRangeLoop() {
+	defer h.wg.Done()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	sub := h.chain.SubscribeChainHeadEvent(heads)
+	defer sub.Unsubscribe()
+
+	var announced uint64
+	for {
+		select {
+		case ev := <-heads:
+			head := ev.Block.Header()
+			if number := head.Number.Uint64(); number < announced+blockRangeUpdateInterval && number >= announced {
+				continue
+			}
+			blocks := h.blockRange(head)
+			for _, peer := range h.peers.all() {
+				peer.AsyncSendBlockRangeUpdate(blocks)
+			}
+			announced = blocks.LatestBlock
+
+		case <-sub.Err():
+			return
+		case <-h.quitSync:
+			return
+		}
+	}
+}
+
 // txBroadcastLoop announces new transactions to connected peers.
 func (h *handler) txBroadcastLoop() {
 	defer h.wg.Done()
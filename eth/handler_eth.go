@@ -23,6 +23,7 @@ import (
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/p2p/enode"
@@ -33,7 +34,21 @@ import (
 type ethHandler handler
 
 func (h *ethHandler) Chain() *core.BlockChain { return h.chain }
-func (h *ethHandler) TxPool() eth.TxPool      { return h.txpool }
+func (h *ethHandler) TxPool() eth.TxPool      { return &publicTxPool{h.txpool} }
+
+// publicTxPool is a view of the transaction pool served to remote peers, which
+// hides the privately submitted transactions.
+type publicTxPool struct {
+	txPool
+}
+
+// Get retrieves a transaction from the pool, unless it is private.
+func (p *publicTxPool) Get(hash common.Hash) *txpool.Transaction {
+	if p.Private(hash) {
+		return nil
+	}
+	return p.txPool.Get(hash)
+}
 
 // RunPeer is invoked when a peer joins on the `eth` protocol.
 func (h *ethHandler) RunPeer(peer *eth.Peer, hand eth.Handler) error {
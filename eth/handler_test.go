@@ -116,6 +116,12 @@ func (p *testTxPool) Pending(enforceTips bool) map[common.Address][]*types.Trans
 	return batches
 }
 
+// Private returns whether a transaction was submitted privately, which is never
+// the case for the test pool.
+func (p *testTxPool) Private(hash common.Hash) bool {
+	return false
+}
+
 // SubscribeNewTxsEvent should return an event subscription of NewTxsEvent and
 // send events to the given channel.
 func (p *testTxPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
@@ -0,0 +1,130 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/portal"
+)
+
+const (
+	// portalContentPrefix is the database table of the portal history network content.
+	portalContentPrefix = "portal-history-"
+
+	// portalLookupTimeout bounds the portal history network lookups made on
+	// behalf of a single API request.
+	portalLookupTimeout = 5 * time.Second
+)
+
+// startPortalHistory joins the portal history network, which is used to retrieve
+// block bodies and receipts missing from the local database.
+func (s *Ethereum) startPortalHistory() error {
+	disc := s.p2pServer.DiscV5
+	if disc == nil {
+		log.Warn("Portal history network disabled, discovery v5 is not running")
+		return nil
+	}
+	var bootnodes []*enode.Node
+	for _, url := range s.config.PortalHistoryBootnodes {
+		n, err := enode.Parse(enode.ValidSchemes, url)
+		if err != nil {
+			return fmt.Errorf("invalid portal bootnode %q: %v", url, err)
+		}
+		bootnodes = append(bootnodes, n)
+	}
+	history := portal.NewHistoryNetwork(disc, rawdb.NewTable(s.chainDb, portalContentPrefix), portal.Config{
+		Bootnodes:       bootnodes,
+		StorageCapacity: s.config.PortalHistoryStorage,
+		Headers:         s.blockchain.GetHeaderByHash,
+	})
+	history.Start()
+	s.portalHistory.Store(history)
+	log.Info("Started portal history network", "bootnodes", len(bootnodes))
+	return nil
+}
+
+// portalBody retrieves a block body from the portal history network. It returns
+// nil if the network is disabled or the body can't be found.
+func (s *Ethereum) portalBody(ctx context.Context, hash common.Hash) *types.Body {
+	history := s.portalHistory.Load()
+	if history == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, portalLookupTimeout)
+	defer cancel()
+
+	body, err := history.BlockBody(ctx, hash)
+	if err != nil {
+		log.Debug("Block body not found in portal network", "hash", hash, "err", err)
+		return nil
+	}
+	return body
+}
+
+// portalBlock assembles a block from a locally known header and a body from the
+// portal history network.
+func (s *Ethereum) portalBlock(ctx context.Context, header *types.Header) *types.Block {
+	if header == nil {
+		return nil
+	}
+	body := s.portalBody(ctx, header.Hash())
+	if body == nil {
+		return nil
+	}
+	return types.NewBlockWithHeader(header).WithBody(body.Transactions, body.Uncles).WithWithdrawals(body.Withdrawals)
+}
+
+// portalReceipts retrieves the receipts of a locally known block from the portal
+// history network, and derives their non-consensus fields.
+func (s *Ethereum) portalReceipts(ctx context.Context, hash common.Hash) types.Receipts {
+	history := s.portalHistory.Load()
+	if history == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, portalLookupTimeout)
+	defer cancel()
+
+	header := s.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil
+	}
+	body := s.blockchain.GetBody(hash)
+	if body == nil {
+		if body = s.portalBody(ctx, hash); body == nil {
+			return nil
+		}
+	}
+	receipts, err := history.Receipts(ctx, hash)
+	if err != nil {
+		log.Debug("Receipts not found in portal network", "hash", hash, "err", err)
+		return nil
+	}
+	number := header.Number.Uint64()
+	if err := receipts.DeriveFields(s.blockchain.Config(), hash, number, header.Time, header.BaseFee, body.Transactions); err != nil {
+		log.Warn("Failed to derive portal receipt fields", "hash", hash, "number", number, "err", err)
+		return nil
+	}
+	return receipts
+}
@@ -36,9 +36,10 @@ type blockPropagation struct {
 	td    *big.Int
 }
 
-// broadcastBlocks is a write loop that multiplexes blocks and block announcements
-// to the remote peer. The goal is to have an async writer that does not lock up
-// node internals and at the same time rate limits queued data.
+// broadcastBlocks is a write loop that multiplexes blocks, block announcements
+// and served block ranges to the remote peer. The goal is to have an async writer
+// that does not lock up node internals and at the same time rate limits queued
+// data.
 func (p *Peer) broadcastBlocks() {
 	for {
 		select {
@@ -54,6 +55,12 @@ func (p *Peer) broadcastBlocks() {
 			}
 			p.Log().Trace("Announced block", "number", block.Number(), "hash", block.Hash())
 
+		case blocks := <-p.queuedBlockRanges:
+			if err := p.SendBlockRangeUpdate(*blocks); err != nil {
+				return
+			}
+			p.Log().Trace("Announced block range", "earliest", blocks.EarliestBlock, "latest", blocks.LatestBlock)
+
 		case <-p.term:
 			return
 		}
@@ -94,12 +94,20 @@ type TxPool interface {
 }
 
 // MakeProtocols constructs the P2P protocol definitions for `eth`.
+//
+// The eth/69 status carries no total difficulty, so peers speaking it can't be
+// chosen for legacy sync. The version is only advertised if the local chain is
+// already past the merge; chains transitioning at runtime pick it up on restart.
 func MakeProtocols(backend Backend, network uint64, dnsdisc enode.Iterator) []p2p.Protocol {
-	protocols := make([]p2p.Protocol, len(ProtocolVersions))
-	for i, version := range ProtocolVersions {
-		version := version // Closure
+	merged := postMerge(backend.Chain())
 
-		protocols[i] = p2p.Protocol{
+	protocols := make([]p2p.Protocol, 0, len(ProtocolVersions))
+	for _, version := range ProtocolVersions {
+		version := version // Closure
+		if version >= ETH69 && !merged {
+			continue
+		}
+		protocols = append(protocols, p2p.Protocol{
 			Name:    ProtocolName,
 			Version: version,
 			Length:  protocolLengths[version],
@@ -119,11 +127,25 @@ func MakeProtocols(backend Backend, network uint64, dnsdisc enode.Iterator) []p2
 			},
 			Attributes:     []enr.Entry{currentENREntry(backend.Chain())},
 			DialCandidates: dnsdisc,
-		}
+		})
 	}
 	return protocols
 }
 
+// postMerge reports whether the chain reached the terminal total difficulty.
+func postMerge(chain *core.BlockChain) bool {
+	config := chain.Config()
+	if config.TerminalTotalDifficulty == nil {
+		return false
+	}
+	if config.TerminalTotalDifficultyPassed {
+		return true
+	}
+	head := chain.CurrentBlock()
+	td := chain.GetTd(head.Hash(), head.Number.Uint64())
+	return td != nil && td.Cmp(config.TerminalTotalDifficulty) >= 0
+}
+
 // NodeInfo represents a short summary of the `eth` sub-protocol metadata
 // known about the host peer.
 type NodeInfo struct {
@@ -213,6 +235,22 @@ var eth68 = map[uint64]msgHandler{
 	PooledTransactionsMsg:         handlePooledTransactions66,
 }
 
+var eth69 = map[uint64]msgHandler{
+	NewBlockHashesMsg:             handleNewBlockhashes,
+	NewBlockMsg:                   handleNewBlock,
+	TransactionsMsg:               handleTransactions,
+	NewPooledTransactionHashesMsg: handleNewPooledTransactionHashes68,
+	GetBlockHeadersMsg:            handleGetBlockHeaders66,
+	BlockHeadersMsg:               handleBlockHeaders66,
+	GetBlockBodiesMsg:             handleGetBlockBodies66,
+	BlockBodiesMsg:                handleBlockBodies66,
+	GetReceiptsMsg:                handleGetReceipts69,
+	ReceiptsMsg:                   handleReceipts69,
+	GetPooledTransactionsMsg:      handleGetPooledTransactions66,
+	PooledTransactionsMsg:         handlePooledTransactions66,
+	BlockRangeUpdateMsg:           handleBlockRangeUpdate,
+}
+
 // handleMessage is invoked whenever an inbound message is received from a remote
 // peer. The remote connection is torn down upon returning any error.
 func handleMessage(backend Backend, peer *Peer) error {
@@ -230,9 +268,12 @@ func handleMessage(backend Backend, peer *Peer) error {
 	if peer.Version() == ETH67 {
 		handlers = eth67
 	}
-	if peer.Version() >= ETH68 {
+	if peer.Version() == ETH68 {
 		handlers = eth68
 	}
+	if peer.Version() >= ETH69 {
+		handlers = eth69
+	}
 
 	// Track the amount of time it takes to serve the request and run the handler
 	if metrics.Enabled {
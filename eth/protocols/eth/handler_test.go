@@ -17,10 +17,12 @@
 package eth
 
 import (
+	"errors"
 	"math"
 	"math/big"
 	"math/rand"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus"
@@ -555,6 +557,7 @@ func testGetNodeData(t *testing.T, protocol uint, drop bool) {
 func TestGetBlockReceipts66(t *testing.T) { testGetBlockReceipts(t, ETH66) }
 func TestGetBlockReceipts67(t *testing.T) { testGetBlockReceipts(t, ETH67) }
 func TestGetBlockReceipts68(t *testing.T) { testGetBlockReceipts(t, ETH68) }
+func TestGetBlockReceipts69(t *testing.T) { testGetBlockReceipts(t, ETH69) }
 
 func testGetBlockReceipts(t *testing.T, protocol uint) {
 	t.Parallel()
@@ -617,10 +620,65 @@ func testGetBlockReceipts(t *testing.T, protocol uint) {
 		RequestId:         123,
 		GetReceiptsPacket: hashes,
 	})
-	if err := p2p.ExpectMsg(peer.app, ReceiptsMsg, &ReceiptsPacket66{
+	var want interface{} = &ReceiptsPacket66{
 		RequestId:      123,
 		ReceiptsPacket: receipts,
-	}); err != nil {
+	}
+	if protocol >= ETH69 {
+		packet := &ReceiptsPacket69{RequestId: 123, Receipts: make([][]*Receipt69, len(receipts))}
+		for i, block := range receipts {
+			packet.Receipts[i] = make([]*Receipt69, len(block))
+			for j, receipt := range block {
+				packet.Receipts[i][j] = newReceipt69(receipt)
+			}
+		}
+		want = packet
+	}
+	if err := p2p.ExpectMsg(peer.app, ReceiptsMsg, want); err != nil {
 		t.Errorf("receipts mismatch: %v", err)
 	}
 }
+
+// Tests that block range updates are tracked, and invalid ones drop the peer.
+func TestBlockRangeUpdate69(t *testing.T) {
+	t.Parallel()
+
+	backend := newTestBackend(4)
+	defer backend.close()
+
+	peer, errc := newTestPeer("peer", ETH69, backend)
+	defer peer.close()
+
+	update := &BlockRangeUpdatePacket{EarliestBlock: 2, LatestBlock: 4, LatestBlockHash: common.Hash{0x04}}
+	if err := p2p.Send(peer.app, BlockRangeUpdateMsg, update); err != nil {
+		t.Fatalf("failed to send range update: %v", err)
+	}
+	// Messages are handled in order, wait for the update by round-tripping a query
+	p2p.Send(peer.app, GetBlockHeadersMsg, &GetBlockHeadersPacket66{
+		RequestId:             1,
+		GetBlockHeadersPacket: &GetBlockHeadersPacket{Origin: HashOrNumber{Number: 0}, Amount: 1},
+	})
+	if msg, err := peer.app.ReadMsg(); err != nil {
+		t.Fatalf("failed to read header response: %v", err)
+	} else {
+		msg.Discard()
+	}
+	if earliest, latest, ok := peer.BlockRange(); !ok || earliest != 2 || latest != 4 {
+		t.Errorf("block range mismatch: have [%d, %d] (%v), want [2, 4]", earliest, latest, ok)
+	}
+	peer.lock.RLock()
+	if peer.head != update.LatestBlockHash {
+		t.Errorf("head mismatch: have %x, want %x", peer.head, update.LatestBlockHash)
+	}
+	peer.lock.RUnlock()
+	// Send an inverted range and ensure the peer is dropped
+	p2p.Send(peer.app, BlockRangeUpdateMsg, &BlockRangeUpdatePacket{EarliestBlock: 5, LatestBlock: 4, LatestBlockHash: common.Hash{0x04}})
+	select {
+	case err := <-errc:
+		if !errors.Is(err, errInvalidBlockRange) {
+			t.Errorf("drop error mismatch: have %v, want %v", err, errInvalidBlockRange)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("peer not dropped on invalid block range")
+	}
+}
@@ -280,9 +280,33 @@ func handleGetReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 	return peer.ReplyReceiptsRLP(query.RequestId, response)
 }
 
+func handleGetReceipts69(backend Backend, msg Decoder, peer *Peer) error {
+	// Decode the block receipts retrieval message
+	var query GetReceiptsPacket66
+	if err := msg.Decode(&query); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	response := ServiceGetReceiptsQuery69(backend.Chain(), query.GetReceiptsPacket)
+	return peer.ReplyReceiptsRLP(query.RequestId, response)
+}
+
 // ServiceGetReceiptsQuery assembles the response to a receipt query. It is
 // exposed to allow external packages to test protocol behavior.
 func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []rlp.RawValue {
+	return serviceGetReceiptsQuery(chain, query, func(receipts types.Receipts) ([]byte, error) {
+		return rlp.EncodeToBytes(receipts)
+	})
+}
+
+// ServiceGetReceiptsQuery69 assembles the response to a receipt query using the
+// bloom-less eth/69 receipt encoding.
+func ServiceGetReceiptsQuery69(chain *core.BlockChain, query GetReceiptsPacket) []rlp.RawValue {
+	return serviceGetReceiptsQuery(chain, query, encodeReceipts69)
+}
+
+// serviceGetReceiptsQuery assembles the response to a receipt query, encoding
+// the receipts of each block with the given encoder.
+func serviceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket, encode func(types.Receipts) ([]byte, error)) []rlp.RawValue {
 	// Gather state data until the fetch or network limits is reached
 	var (
 		bytes    int
@@ -301,7 +325,7 @@ func ServiceGetReceiptsQuery(chain *core.BlockChain, query GetReceiptsPacket) []
 			}
 		}
 		// If known, encode and queue for response packet
-		if encoded, err := rlp.EncodeToBytes(results); err != nil {
+		if encoded, err := encode(results); err != nil {
 			log.Error("Failed to encode receipt", "err", err)
 		} else {
 			receipts = append(receipts, encoded)
@@ -434,6 +458,52 @@ func handleReceipts66(backend Backend, msg Decoder, peer *Peer) error {
 	}, metadata)
 }
 
+func handleReceipts69(backend Backend, msg Decoder, peer *Peer) error {
+	// A batch of bloom-less receipts arrived to one of our previous requests
+	res := new(ReceiptsPacket69)
+	if err := msg.Decode(res); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	// Restore the consensus receipts, the rest is the same as on older versions
+	receipts := make(ReceiptsPacket, len(res.Receipts))
+	for i, block := range res.Receipts {
+		receipts[i] = make([]*types.Receipt, len(block))
+		for j, receipt := range block {
+			converted, err := receipt.toReceipt()
+			if err != nil {
+				return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+			}
+			receipts[i][j] = converted
+		}
+	}
+	metadata := func() interface{} {
+		hasher := trie.NewStackTrie(nil)
+		hashes := make([]common.Hash, len(receipts))
+		for i, receipt := range receipts {
+			hashes[i] = types.DeriveSha(types.Receipts(receipt), hasher)
+		}
+		return hashes
+	}
+	return peer.dispatchResponse(&Response{
+		id:   res.RequestId,
+		code: ReceiptsMsg,
+		Res:  &receipts,
+	}, metadata)
+}
+
+func handleBlockRangeUpdate(backend Backend, msg Decoder, peer *Peer) error {
+	// The remote peer's range of served blocks changed, track it
+	update := new(BlockRangeUpdatePacket)
+	if err := msg.Decode(update); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	if err := update.validate(); err != nil {
+		return err
+	}
+	peer.setBlockRange(update)
+	return nil
+}
+
 func handleNewPooledTransactionHashes66(backend Backend, msg Decoder, peer *Peer) error {
 	// New transaction announcement arrived, make sure we have
 	// a valid and fresh chain to handle them
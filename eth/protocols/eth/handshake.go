@@ -35,8 +35,12 @@ const (
 )
 
 // Handshake executes the eth protocol handshake, negotiating version number,
-// network IDs, difficulties, head and genesis blocks.
-func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter) error {
+// network IDs, difficulties, head and genesis blocks. From eth/69, the range of
+// served blocks is exchanged instead of the total difficulty.
+func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, blocks BlockRangeUpdatePacket) error {
+	if p.version >= ETH69 {
+		return p.handshake69(network, genesis, forkID, forkFilter, blocks)
+	}
 	// Send out own handshake in a new thread
 	errc := make(chan error, 2)
 
@@ -79,6 +83,51 @@ func (p *Peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis
 	return nil
 }
 
+// handshake69 executes the eth/69 protocol handshake, exchanging the range of
+// served blocks instead of the total difficulty.
+func (p *Peer) handshake69(network uint64, genesis common.Hash, forkID forkid.ID, forkFilter forkid.Filter, blocks BlockRangeUpdatePacket) error {
+	// Send out own handshake in a new thread
+	errc := make(chan error, 2)
+
+	var status StatusPacket69 // safe to read after two values have been received from errc
+
+	go func() {
+		errc <- p2p.Send(p.rw, StatusMsg, &StatusPacket69{
+			ProtocolVersion: uint32(p.version),
+			NetworkID:       network,
+			Genesis:         genesis,
+			ForkID:          forkID,
+			EarliestBlock:   blocks.EarliestBlock,
+			LatestBlock:     blocks.LatestBlock,
+			LatestBlockHash: blocks.LatestBlockHash,
+		})
+	}()
+	go func() {
+		errc <- p.readStatus69(network, &status, genesis, forkFilter)
+	}()
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				markError(p, err)
+				return err
+			}
+		case <-timeout.C:
+			markError(p, p2p.DiscReadTimeout)
+			return p2p.DiscReadTimeout
+		}
+	}
+	p.td = new(big.Int)
+	p.setBlockRange(&BlockRangeUpdatePacket{
+		EarliestBlock:   status.EarliestBlock,
+		LatestBlock:     status.LatestBlock,
+		LatestBlockHash: status.LatestBlockHash,
+	})
+	return nil
+}
+
 // readStatus reads the remote handshake message.
 func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.Hash, forkFilter forkid.Filter) error {
 	msg, err := p.rw.ReadMsg()
@@ -110,6 +159,38 @@ func (p *Peer) readStatus(network uint64, status *StatusPacket, genesis common.H
 	return nil
 }
 
+// readStatus69 reads the remote eth/69 handshake message.
+func (p *Peer) readStatus69(network uint64, status *StatusPacket69, genesis common.Hash, forkFilter forkid.Filter) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Code != StatusMsg {
+		return fmt.Errorf("%w: first msg has code %x (!= %x)", errNoStatusMsg, msg.Code, StatusMsg)
+	}
+	if msg.Size > maxMessageSize {
+		return fmt.Errorf("%w: %v > %v", errMsgTooLarge, msg.Size, maxMessageSize)
+	}
+	// Decode the handshake and make sure everything matches
+	if err := msg.Decode(&status); err != nil {
+		return fmt.Errorf("%w: message %v: %v", errDecode, msg, err)
+	}
+	if status.NetworkID != network {
+		return fmt.Errorf("%w: %d (!= %d)", errNetworkIDMismatch, status.NetworkID, network)
+	}
+	if uint(status.ProtocolVersion) != p.version {
+		return fmt.Errorf("%w: %d (!= %d)", errProtocolVersionMismatch, status.ProtocolVersion, p.version)
+	}
+	if status.Genesis != genesis {
+		return fmt.Errorf("%w: %x (!= %x)", errGenesisMismatch, status.Genesis, genesis)
+	}
+	if err := forkFilter(status.ForkID); err != nil {
+		return fmt.Errorf("%w: %v", errForkIDRejected, err)
+	}
+	blocks := BlockRangeUpdatePacket{status.EarliestBlock, status.LatestBlock, status.LatestBlockHash}
+	return blocks.validate()
+}
+
 // markError registers the error with the corresponding metric.
 func markError(p *Peer, err error) {
 	if !metrics.Enabled {
@@ -80,7 +80,7 @@ func testHandshake(t *testing.T, protocol uint) {
 		// Send the junk test with one peer, check the handshake failure
 		go p2p.Send(app, test.code, test.data)
 
-		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain))
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), BlockRangeUpdatePacket{0, head.Number.Uint64(), head.Hash()})
 		if err == nil {
 			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
 		} else if !errors.Is(err, test.want) {
@@ -88,3 +88,118 @@ func testHandshake(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that eth/69 handshake failures are detected and reported correctly.
+func TestHandshake69(t *testing.T) {
+	t.Parallel()
+
+	// Create a test backend only to have some valid genesis chain
+	backend := newTestBackend(3)
+	defer backend.close()
+
+	var (
+		genesis = backend.chain.Genesis()
+		head    = backend.chain.CurrentBlock()
+		td      = backend.chain.GetTd(head.Hash(), head.Number.Uint64())
+		number  = head.Number.Uint64()
+		forkID  = forkid.NewID(backend.chain.Config(), backend.chain.Genesis().Hash(), backend.chain.CurrentHeader().Number.Uint64(), backend.chain.CurrentHeader().Time)
+	)
+	tests := []struct {
+		code uint64
+		data interface{}
+		want error
+	}{
+		{
+			code: StatusMsg, data: StatusPacket{ETH69, 1, td, head.Hash(), genesis.Hash(), forkID},
+			want: errDecode,
+		},
+		{
+			code: StatusMsg, data: StatusPacket69{ETH68, 1, genesis.Hash(), forkID, 0, number, head.Hash()},
+			want: errProtocolVersionMismatch,
+		},
+		{
+			code: StatusMsg, data: StatusPacket69{ETH69, 999, genesis.Hash(), forkID, 0, number, head.Hash()},
+			want: errNetworkIDMismatch,
+		},
+		{
+			code: StatusMsg, data: StatusPacket69{ETH69, 1, common.Hash{3}, forkID, 0, number, head.Hash()},
+			want: errGenesisMismatch,
+		},
+		{
+			code: StatusMsg, data: StatusPacket69{ETH69, 1, genesis.Hash(), forkid.ID{Hash: [4]byte{0x00, 0x01, 0x02, 0x03}}, 0, number, head.Hash()},
+			want: errForkIDRejected,
+		},
+		{
+			code: StatusMsg, data: StatusPacket69{ETH69, 1, genesis.Hash(), forkID, number + 1, number, head.Hash()},
+			want: errInvalidBlockRange,
+		},
+	}
+	blocks := BlockRangeUpdatePacket{0, number, head.Hash()}
+	for i, test := range tests {
+		// Create the two peers to shake with each other
+		app, net := p2p.MsgPipe()
+		defer app.Close()
+		defer net.Close()
+
+		peer := NewPeer(ETH69, p2p.NewPeer(enode.ID{}, "peer", nil), net, nil)
+		defer peer.Close()
+
+		// Send the junk test with one peer, check the handshake failure
+		go p2p.Send(app, test.code, test.data)
+
+		err := peer.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), blocks)
+		if err == nil {
+			t.Errorf("test %d: protocol returned nil error, want %q", i, test.want)
+		} else if !errors.Is(err, test.want) {
+			t.Errorf("test %d: wrong error: got %q, want %q", i, err, test.want)
+		}
+	}
+	// Run a successful handshake and check the advertised range is tracked
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+	defer net.Close()
+
+	local := NewPeer(ETH69, p2p.NewPeer(enode.ID{1}, "local", nil), app, nil)
+	defer local.Close()
+	remote := NewPeer(ETH69, p2p.NewPeer(enode.ID{2}, "remote", nil), net, nil)
+	defer remote.Close()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- remote.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), BlockRangeUpdatePacket{1, number, head.Hash()})
+	}()
+	if err := local.Handshake(1, td, head.Hash(), genesis.Hash(), forkID, forkid.NewFilter(backend.chain), blocks); err != nil {
+		t.Fatalf("local handshake failed: %v", err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("remote handshake failed: %v", err)
+	}
+	if earliest, latest, ok := local.BlockRange(); !ok || earliest != 1 || latest != number {
+		t.Errorf("advertised range mismatch: have [%d, %d] (%v), want [1, %d]", earliest, latest, ok, number)
+	}
+	if hash, td := local.Head(); hash != head.Hash() || td.Sign() != 0 {
+		t.Errorf("head mismatch: have %x/%v, want %x/0", hash, td, head.Hash())
+	}
+}
+
+// Tests that eth/69, which carries no total difficulty, is only advertised on
+// chains past the merge.
+func TestProtocolsMerge(t *testing.T) {
+	t.Parallel()
+
+	for _, merged := range []bool{false, true} {
+		backend := newTestBackendWithGenerator(0, merged, nil)
+		var versions []uint
+		for _, proto := range MakeProtocols(backend, 1, nil) {
+			versions = append(versions, proto.Version)
+		}
+		backend.close()
+
+		if have := len(versions) > 0 && versions[0] == ETH69; have != merged {
+			t.Errorf("merged %v: eth/69 advertised %v, versions %v", merged, have, versions)
+		}
+		if len(versions) == 0 || versions[len(versions)-1] != ETH66 {
+			t.Errorf("merged %v: legacy versions missing: %v", merged, versions)
+		}
+	}
+}
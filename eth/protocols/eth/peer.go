@@ -54,6 +54,10 @@ const (
 	// dropping broadcasts. Similarly to block propagations, there's no point to queue
 	// above some healthy uncle limit, so use that.
 	maxQueuedBlockAnns = 4
+
+	// maxQueuedBlockRanges is the maximum number of block range announcements to
+	// queue up. Only the latest range matters, stale ones are replaced.
+	maxQueuedBlockRanges = 1
 )
 
 // max is a helper function which returns the larger of the two given integers.
@@ -73,12 +77,16 @@ type Peer struct {
 	version   uint              // Protocol version negotiated
 
 	head common.Hash // Latest advertised head block hash
-	td   *big.Int    // Latest advertised head block total difficulty
+	td   *big.Int    // Latest advertised head block total difficulty (zero on eth/69)
+
+	blocks *BlockRangeUpdatePacket // Latest advertised range of served blocks (nil before eth/69)
 
 	knownBlocks     *knownCache            // Set of block hashes known to be known by this peer
 	queuedBlocks    chan *blockPropagation // Queue of blocks to broadcast to the peer
 	queuedBlockAnns chan *types.Block      // Queue of blocks to announce to the peer
 
+	queuedBlockRanges chan *BlockRangeUpdatePacket // Queue of served block ranges to announce to the peer
+
 	txpool      TxPool             // Transaction pool used by the broadcasters for liveness checks
 	knownTxs    *knownCache        // Set of transaction hashes known to be known by this peer
 	txBroadcast chan []common.Hash // Channel used to queue transaction propagation requests
@@ -96,21 +104,22 @@ type Peer struct {
 // version.
 func NewPeer(version uint, p *p2p.Peer, rw p2p.MsgReadWriter, txpool TxPool) *Peer {
 	peer := &Peer{
-		id:              p.ID().String(),
-		Peer:            p,
-		rw:              rw,
-		version:         version,
-		knownTxs:        newKnownCache(maxKnownTxs),
-		knownBlocks:     newKnownCache(maxKnownBlocks),
-		queuedBlocks:    make(chan *blockPropagation, maxQueuedBlocks),
-		queuedBlockAnns: make(chan *types.Block, maxQueuedBlockAnns),
-		txBroadcast:     make(chan []common.Hash),
-		txAnnounce:      make(chan []common.Hash),
-		reqDispatch:     make(chan *request),
-		reqCancel:       make(chan *cancel),
-		resDispatch:     make(chan *response),
-		txpool:          txpool,
-		term:            make(chan struct{}),
+		id:                p.ID().String(),
+		Peer:              p,
+		rw:                rw,
+		version:           version,
+		knownTxs:          newKnownCache(maxKnownTxs),
+		knownBlocks:       newKnownCache(maxKnownBlocks),
+		queuedBlocks:      make(chan *blockPropagation, maxQueuedBlocks),
+		queuedBlockAnns:   make(chan *types.Block, maxQueuedBlockAnns),
+		queuedBlockRanges: make(chan *BlockRangeUpdatePacket, maxQueuedBlockRanges),
+		txBroadcast:       make(chan []common.Hash),
+		txAnnounce:        make(chan []common.Hash),
+		reqDispatch:       make(chan *request),
+		reqCancel:         make(chan *cancel),
+		resDispatch:       make(chan *response),
+		txpool:            txpool,
+		term:              make(chan struct{}),
 	}
 	// Start up all the broadcasters
 	go peer.broadcastBlocks()
@@ -156,6 +165,61 @@ func (p *Peer) SetHead(hash common.Hash, td *big.Int) {
 	p.td.Set(td)
 }
 
+// BlockRange retrieves the range of blocks served by the peer. The range is only
+// advertised from eth/69, ok is false for older peers.
+func (p *Peer) BlockRange() (earliest uint64, latest uint64, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if p.blocks == nil {
+		return 0, 0, false
+	}
+	return p.blocks.EarliestBlock, p.blocks.LatestBlock, true
+}
+
+// setBlockRange updates the range of blocks served by the peer, along with its
+// head block hash.
+func (p *Peer) setBlockRange(blocks *BlockRangeUpdatePacket) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.blocks = blocks
+	copy(p.head[:], blocks.LatestBlockHash[:])
+}
+
+// SendBlockRangeUpdate announces a change in the range of blocks served by the
+// local node. It's a noop for peers predating eth/69.
+//
+// This method is a helper used by the async block broadcaster. Don't call it
+// directly as a slow peer would hold up the caller.
+func (p *Peer) SendBlockRangeUpdate(blocks BlockRangeUpdatePacket) error {
+	if p.version < ETH69 {
+		return nil
+	}
+	return p2p.Send(p.rw, BlockRangeUpdateMsg, &blocks)
+}
+
+// AsyncSendBlockRangeUpdate queues an announcement of the range of blocks served
+// by the local node, replacing any stale one not yet sent. It's a noop for peers
+// predating eth/69.
+func (p *Peer) AsyncSendBlockRangeUpdate(blocks BlockRangeUpdatePacket) {
+	if p.version < ETH69 {
+		return
+	}
+	for {
+		select {
+		case p.queuedBlockRanges <- &blocks:
+			return
+		default:
+		}
+		select {
+		case stale := <-p.queuedBlockRanges:
+			p.Log().Trace("Replacing stale block range announcement", "latest", stale.LatestBlock)
+		default:
+		}
+	}
+}
+
 // KnownBlock returns whether peer is known to already have a block.
 func (p *Peer) KnownBlock(hash common.Hash) bool {
 	return p.knownBlocks.Contains(hash)
@@ -88,3 +88,41 @@ func TestPeerSet(t *testing.T) {
 		t.Fatalf("bad size")
 	}
 }
+
+// Tests that queued block range announcements don't block the caller, and that
+// stale ones are replaced by the latest range.
+func TestAsyncSendBlockRangeUpdate(t *testing.T) {
+	app, net := p2p.MsgPipe()
+	defer app.Close()
+
+	var id enode.ID
+	rand.Read(id[:])
+	peer := NewPeer(ETH69, p2p.NewPeer(id, "peer", nil), net, nil)
+	defer peer.Close()
+
+	// Nothing reads the pipe yet, so the broadcaster blocks on the first update
+	// it picks up and the later ones replace each other in the queue
+	for latest := uint64(1); latest <= 3; latest++ {
+		peer.AsyncSendBlockRangeUpdate(BlockRangeUpdatePacket{LatestBlock: latest, LatestBlockHash: common.Hash{byte(latest)}})
+	}
+	// The first update may or may not have been picked up before being replaced,
+	// but the latest one must arrive and the middle one must not.
+	for {
+		msg, err := app.ReadMsg()
+		if err != nil {
+			t.Fatalf("failed to read range update: %v", err)
+		}
+		var update BlockRangeUpdatePacket
+		if err := msg.Decode(&update); err != nil {
+			t.Fatalf("failed to decode range update: %v", err)
+		}
+		switch update.LatestBlock {
+		case 1:
+			continue
+		case 3:
+			return
+		default:
+			t.Fatalf("stale range update sent: latest %d", update.LatestBlock)
+		}
+	}
+}
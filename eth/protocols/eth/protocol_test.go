@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
 )
 
 // Tests that the custom union field encoder and decoder works correctly.
@@ -264,3 +265,45 @@ func TestEth66Messages(t *testing.T) {
 		}
 	}
 }
+
+// Tests that receipts survive a roundtrip through the bloom-less eth/69 encoding
+// with their consensus representation intact.
+func TestReceipt69Roundtrip(t *testing.T) {
+	logs := []*types.Log{{
+		Address: common.Address{0x11},
+		Topics:  []common.Hash{{0x22}, {0x33}},
+		Data:    []byte{0x44, 0x55},
+	}}
+	receipts := types.Receipts{
+		{Type: types.LegacyTxType, PostState: common.Hash{0x01}.Bytes(), CumulativeGasUsed: 21000},
+		{Type: types.LegacyTxType, Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 50000, Logs: logs},
+		{Type: types.DynamicFeeTxType, Status: types.ReceiptStatusFailed, CumulativeGasUsed: 80000},
+	}
+	for _, receipt := range receipts {
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	}
+	enc, err := encodeReceipts69(receipts)
+	if err != nil {
+		t.Fatalf("failed to encode receipts: %v", err)
+	}
+	if full, _ := rlp.EncodeToBytes(receipts); len(enc) >= len(full)-len(receipts)*types.BloomByteLength {
+		t.Errorf("encoding not bloom-less: %d bytes, consensus encoding %d bytes", len(enc), len(full))
+	}
+	var decoded []*Receipt69
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatalf("failed to decode receipts: %v", err)
+	}
+	restored := make(types.Receipts, len(decoded))
+	for i, receipt := range decoded {
+		if restored[i], err = receipt.toReceipt(); err != nil {
+			t.Fatalf("failed to restore receipt %d: %v", i, err)
+		}
+	}
+	if have, want := types.DeriveSha(restored, trie.NewStackTrie(nil)), types.DeriveSha(receipts, trie.NewStackTrie(nil)); have != want {
+		t.Errorf("receipt root mismatch: have %x, want %x", have, want)
+	}
+	// Invalid statuses must be rejected
+	if _, err := (&Receipt69{PostStateOrStatus: []byte{0x02}}).toReceipt(); err == nil {
+		t.Errorf("invalid receipt status accepted")
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	receiptStatusFailed     = []byte{}
+	receiptStatusSuccessful = []byte{0x01}
+)
+
+// Receipt69 is the bloom-less receipt encoding of eth/69. The bloom can be
+// recomputed from the logs, so it's not worth the bandwidth. Unlike consensus
+// receipts, typed and legacy receipts share the same list encoding.
+type Receipt69 struct {
+	TxType            byte
+	PostStateOrStatus []byte
+	CumulativeGasUsed uint64
+	Logs              []*types.Log
+}
+
+// newReceipt69 converts a consensus receipt into its eth/69 encoding.
+func newReceipt69(r *types.Receipt) *Receipt69 {
+	receipt := &Receipt69{
+		TxType:            r.Type,
+		PostStateOrStatus: r.PostState,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+	if len(r.PostState) == 0 {
+		receipt.PostStateOrStatus = receiptStatusSuccessful
+		if r.Status == types.ReceiptStatusFailed {
+			receipt.PostStateOrStatus = receiptStatusFailed
+		}
+	}
+	if receipt.Logs == nil {
+		receipt.Logs = []*types.Log{}
+	}
+	return receipt
+}
+
+// toReceipt converts an eth/69 receipt back into its consensus form, deriving
+// the bloom filter from the logs.
+func (r *Receipt69) toReceipt() (*types.Receipt, error) {
+	receipt := &types.Receipt{
+		Type:              r.TxType,
+		CumulativeGasUsed: r.CumulativeGasUsed,
+		Logs:              r.Logs,
+	}
+	switch {
+	case bytes.Equal(r.PostStateOrStatus, receiptStatusSuccessful):
+		receipt.Status = types.ReceiptStatusSuccessful
+	case bytes.Equal(r.PostStateOrStatus, receiptStatusFailed):
+		receipt.Status = types.ReceiptStatusFailed
+	case len(r.PostStateOrStatus) == common.HashLength:
+		receipt.PostState = r.PostStateOrStatus
+	default:
+		return nil, fmt.Errorf("invalid receipt status %x", r.PostStateOrStatus)
+	}
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	return receipt, nil
+}
+
+// encodeReceipts69 encodes the receipts of a block in the eth/69 format.
+func encodeReceipts69(receipts types.Receipts) ([]byte, error) {
+	converted := make([]*Receipt69, len(receipts))
+	for i, receipt := range receipts {
+		converted[i] = newReceipt69(receipt)
+	}
+	return rlp.EncodeToBytes(converted)
+}
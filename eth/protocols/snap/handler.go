@@ -97,6 +97,7 @@ func MakeProtocols(backend Backend, dnsdisc enode.Iterator) []p2p.Protocol {
 			Name:    ProtocolName,
 			Version: version,
 			Length:  protocolLengths[version],
+			Bulk:    true,
 			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
 				return backend.RunPeer(NewPeer(version, p, rw), func(peer *Peer) error {
 					return Handle(backend, peer)
@@ -0,0 +1,172 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+type profileEntry struct {
+	Contract common.Address `json:"contract"`
+	Selector string         `json:"selector"`
+	PC       *uint64        `json:"pc"`
+	Op       string         `json:"op"`
+	Count    uint64         `json:"count"`
+	Gas      uint64         `json:"gas"`
+}
+
+type profileResult struct {
+	GasUsed      uint64         `json:"gasUsed"`
+	IntrinsicGas uint64         `json:"intrinsicGas"`
+	Entries      []profileEntry `json:"entries"`
+	Collapsed    string         `json:"collapsed"`
+}
+
+// Tests that the profiler attributes gas to the frames that consumed it, and
+// that the self costs add up to the gas used by the transaction.
+func TestProfilerTracer(t *testing.T) {
+	var (
+		key, _ = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		caller = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		callee = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		signer = types.LatestSignerForChainID(big.NewInt(1))
+	)
+	// The callee stores 1 into slot 0, the caller invokes the callee with all
+	// its gas and the selector 0x12345678.
+	calleeCode := []byte{
+		byte(vm.PUSH1), 1, byte(vm.PUSH1), 0, byte(vm.SSTORE), byte(vm.STOP),
+	}
+	callerCode := []byte{
+		byte(vm.PUSH4), 0x12, 0x34, 0x56, 0x78, byte(vm.PUSH1), 224, byte(vm.SHL),
+		byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 4, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0,
+		byte(vm.PUSH20),
+	}
+	callerCode = append(callerCode, callee.Bytes()...)
+	callerCode = append(callerCode, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP))
+
+	alloc := core.GenesisAlloc{
+		from:   {Balance: big.NewInt(params.Ether)},
+		caller: {Code: callerCode, Balance: new(big.Int)},
+		callee: {Code: calleeCode, Balance: new(big.Int)},
+	}
+	tx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+		To:       &caller,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, byPC := range []bool{false, true} {
+		_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+		cfg, _ := json.Marshal(map[string]interface{}{"byPC": byPC})
+		tracer, err := tracers.DefaultDirectory.New("profilerTracer", new(tracers.Context), cfg)
+		if err != nil {
+			t.Fatalf("failed to create profiler: %v", err)
+		}
+		context := vm.BlockContext{
+			CanTransfer: core.CanTransfer,
+			Transfer:    core.Transfer,
+			BlockNumber: big.NewInt(1),
+			Difficulty:  big.NewInt(1),
+			GasLimit:    tx.Gas(),
+			BaseFee:     big.NewInt(0),
+		}
+		evm := vm.NewEVM(context, vm.TxContext{Origin: from, GasPrice: tx.GasPrice()}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer})
+		msg, err := core.TransactionToMessage(tx, signer, nil)
+		if err != nil {
+			t.Fatalf("failed to prepare transaction: %v", err)
+		}
+		ret, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+		if err != nil {
+			t.Fatalf("failed to execute transaction: %v", err)
+		}
+		blob, err := tracer.GetResult()
+		if err != nil {
+			t.Fatalf("failed to retrieve profile: %v", err)
+		}
+		var res profileResult
+		if err := json.Unmarshal(blob, &res); err != nil {
+			t.Fatalf("failed to decode profile: %v", err)
+		}
+		if res.IntrinsicGas != params.TxGas {
+			t.Errorf("byPC %v: intrinsic gas mismatch: have %d, want %d", byPC, res.IntrinsicGas, params.TxGas)
+		}
+		if res.GasUsed+res.IntrinsicGas != ret.UsedGas {
+			t.Errorf("byPC %v: gas used mismatch: have %d, want %d", byPC, res.GasUsed+res.IntrinsicGas, ret.UsedGas)
+		}
+		var (
+			total  uint64
+			sstore *profileEntry
+		)
+		for i, entry := range res.Entries {
+			total += entry.Gas
+			if (entry.PC != nil) != byPC {
+				t.Errorf("byPC %v: entry %d has unexpected pc %v", byPC, i, entry.PC)
+			}
+			if entry.Op == "SSTORE" {
+				sstore = &res.Entries[i]
+			}
+		}
+		if total != res.GasUsed {
+			t.Errorf("byPC %v: self gas sum mismatch: have %d, want %d", byPC, total, res.GasUsed)
+		}
+		if sstore == nil {
+			t.Fatalf("byPC %v: missing SSTORE entry", byPC)
+		}
+		if sstore.Contract != callee || sstore.Selector != "0x12345678" || sstore.Count != 1 {
+			t.Errorf("byPC %v: SSTORE attributed wrongly: %+v", byPC, sstore)
+		}
+		if sstore != &res.Entries[0] {
+			t.Errorf("byPC %v: SSTORE is not the most expensive entry", byPC)
+		}
+		// Check the collapsed stacks sum up to the same value
+		var sum uint64
+		for _, line := range strings.Split(strings.TrimSpace(res.Collapsed), "\n") {
+			idx := strings.LastIndexByte(line, ' ')
+			if idx < 0 {
+				t.Fatalf("byPC %v: malformed collapsed line %q", byPC, line)
+			}
+			n, ok := new(big.Int).SetString(line[idx+1:], 10)
+			if !ok {
+				t.Fatalf("byPC %v: malformed collapsed weight %q", byPC, line)
+			}
+			sum += n.Uint64()
+			if strings.Contains(line, ";SSTORE") && !strings.HasPrefix(line, strings.ToLower(caller.Hex())+":fallback;"+strings.ToLower(callee.Hex())+":0x12345678;SSTORE") {
+				t.Errorf("byPC %v: unexpected SSTORE stack %q", byPC, line)
+			}
+		}
+		if sum != res.GasUsed {
+			t.Errorf("byPC %v: collapsed weight mismatch: have %d, want %d", byPC, sum, res.GasUsed)
+		}
+	}
+}
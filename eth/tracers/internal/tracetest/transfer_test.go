@@ -0,0 +1,124 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package tracetest
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/tests"
+)
+
+// Tests that the transfer tracer collects native and token movements in order,
+// flagging the ones made by reverted frames.
+func TestTransferTracer(t *testing.T) {
+	var (
+		key, _   = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		from     = crypto.PubkeyToAddress(key.PublicKey)
+		token    = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		nft      = common.HexToAddress("0x00000000000000000000000000000000000000bb")
+		signer   = types.LatestSignerForChainID(big.NewInt(1))
+		transfer = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	)
+	// The token contract logs an ERC-20 transfer of 5 units from 0x01 to 0x02,
+	// then sends 1 wei to the nft contract.
+	tokenCode := []byte{
+		byte(vm.PUSH1), 5, byte(vm.PUSH1), 0, byte(vm.MSTORE),
+		byte(vm.PUSH1), 0x02, byte(vm.PUSH1), 0x01, byte(vm.PUSH32),
+	}
+	tokenCode = append(tokenCode, transfer.Bytes()...)
+	tokenCode = append(tokenCode,
+		byte(vm.PUSH1), 32, byte(vm.PUSH1), 0, byte(vm.LOG3),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.PUSH1), 1,
+		byte(vm.PUSH1), 0xbb, byte(vm.GAS), byte(vm.CALL), byte(vm.STOP),
+	)
+	// The nft contract logs an ERC-721 transfer of token 7 from 0x03 to 0x04,
+	// then reverts.
+	nftCode := []byte{byte(vm.PUSH1), 7, byte(vm.PUSH1), 0x04, byte(vm.PUSH1), 0x03, byte(vm.PUSH32)}
+	nftCode = append(nftCode, transfer.Bytes()...)
+	nftCode = append(nftCode,
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.LOG4),
+		byte(vm.PUSH1), 0, byte(vm.PUSH1), 0, byte(vm.REVERT),
+	)
+	alloc := core.GenesisAlloc{
+		from:  {Balance: big.NewInt(params.Ether)},
+		token: {Code: tokenCode, Balance: new(big.Int)},
+		nft:   {Code: nftCode, Balance: new(big.Int)},
+	}
+	_, statedb := tests.MakePreState(rawdb.NewMemoryDatabase(), alloc, false)
+
+	tx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+		Gas:      100000,
+		GasPrice: big.NewInt(1),
+		To:       &token,
+		Value:    big.NewInt(10),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tracer, err := tracers.DefaultDirectory.New("transferTracer", new(tracers.Context), nil)
+	if err != nil {
+		t.Fatalf("failed to create transfer tracer: %v", err)
+	}
+	context := vm.BlockContext{
+		CanTransfer: core.CanTransfer,
+		Transfer:    core.Transfer,
+		BlockNumber: big.NewInt(1),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    tx.Gas(),
+		BaseFee:     big.NewInt(0),
+	}
+	evm := vm.NewEVM(context, vm.TxContext{Origin: from, GasPrice: tx.GasPrice()}, statedb, params.AllEthashProtocolChanges, vm.Config{Tracer: tracer})
+	msg, err := core.TransactionToMessage(tx, signer, nil)
+	if err != nil {
+		t.Fatalf("failed to prepare transaction: %v", err)
+	}
+	if _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(tx.Gas())); err != nil {
+		t.Fatalf("failed to execute transaction: %v", err)
+	}
+	res, err := tracer.GetResult()
+	if err != nil {
+		t.Fatalf("failed to retrieve trace result: %v", err)
+	}
+	want := []map[string]interface{}{
+		{"type": "native", "via": "CALL", "from": from, "to": token, "value": "0xa"},
+		{"type": "erc20", "via": "LOG3", "token": token, "from": common.HexToAddress("0x01"), "to": common.HexToAddress("0x02"), "value": "0x5"},
+		{"type": "native", "via": "CALL", "from": token, "to": nft, "value": "0x1", "reverted": true},
+		{"type": "erc721", "via": "LOG4", "token": nft, "from": common.HexToAddress("0x03"), "to": common.HexToAddress("0x04"), "tokenId": "0x7", "reverted": true},
+	}
+	var have, expect interface{}
+	if err := json.Unmarshal(res, &have); err != nil {
+		t.Fatalf("failed to decode result: %v", err)
+	}
+	blob, _ := json.Marshal(want)
+	json.Unmarshal(blob, &expect)
+
+	haveBlob, _ := json.Marshal(have)
+	wantBlob, _ := json.Marshal(expect)
+	if string(haveBlob) != string(wantBlob) {
+		t.Fatalf("transfer mismatch\n have: %s\n want: %s", haveBlob, wantBlob)
+	}
+}
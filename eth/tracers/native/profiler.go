@@ -0,0 +1,397 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("profilerTracer", newProfilerTracer, false)
+}
+
+// precompileOp is the pseudo-opcode name used to attribute gas spent in a frame
+// that executed no bytecode, i.e. a precompiled contract.
+const precompileOp = "PRECOMPILE"
+
+// profileKey identifies a bucket in the aggregated profile.
+type profileKey struct {
+	contract common.Address
+	selector string
+	pc       uint64
+	op       string
+}
+
+// profileEntry is a single aggregated bucket of the profile.
+type profileEntry struct {
+	Contract common.Address `json:"contract"`
+	Selector string         `json:"selector"`
+	PC       *uint64        `json:"pc,omitempty"`
+	Op       string         `json:"op"`
+	Count    uint64         `json:"count"`
+	Gas      uint64         `json:"gas"`
+	Time     uint64         `json:"time"`
+}
+
+// profileWeight is the self cost accumulated by a single collapsed stack.
+type profileWeight struct {
+	gas  uint64
+	time time.Duration
+}
+
+// profileFrame tracks the execution state of a call frame during profiling.
+type profileFrame struct {
+	contract common.Address
+	selector string
+	stack    string    // Collapsed stack of the frame, ';' separated
+	entered  time.Time // Time at which the frame was entered
+
+	pending  bool      // Whether an opcode awaits its cost to be settled
+	pc       uint64    // Program counter of the pending opcode
+	op       string    // Name of the pending opcode
+	gas      uint64    // Gas available before the pending opcode
+	start    time.Time // Time at which the pending opcode started executing
+	childGas uint64    // Gas used by sub-calls of the pending opcode
+	childDur time.Duration
+
+	used uint64 // Gas accounted to opcodes of this frame so far (inclusive)
+}
+
+// profilerTracerConfig contains the user options of the profiler.
+type profilerTracerConfig struct {
+	ByPC   bool   `json:"byPC"`   // If true, buckets are further split by program counter
+	Weight string `json:"weight"` // Weight of the collapsed stacks, "gas" (default) or "time"
+}
+
+// profilerTracer attributes the gas and execution time consumed by a transaction
+// to the (contract, function selector, opcode) that consumed it, aggregating the
+// results across all the calls made. The cost of call and create opcodes does
+// not include the cost of the sub-calls they spawn, which are accounted in their
+// own frames instead.
+//
+// The result contains a JSON summary of the buckets sorted by gas used, and the
+// profile in collapsed-stack format, as consumed by flamegraph.pl or speedscope.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "profilerTracer", tracerConfig: {byPC: true}})
+//	{
+//	  gasUsed: 29132,
+//	  intrinsicGas: 21064,
+//	  duration: 40958,
+//	  entries: [{contract: "0x...", selector: "0xa9059cbb", pc: 512, op: "SSTORE", count: 1, gas: 20000, time: 5103}, ...],
+//	  collapsed: "0x...:0xa9059cbb;SSTORE@512 20000\n..."
+//	}
+type profilerTracer struct {
+	noopTracer
+	config profilerTracerConfig
+
+	frames    []*profileFrame
+	entries   map[profileKey]*profileEntry
+	collapsed map[string]*profileWeight
+
+	gasLimit  uint64 // Gas limit of the transaction, zero if not executed as a transaction
+	startGas  uint64 // Gas available to the top-level call
+	gasUsed   uint64 // Gas used by the top-level call
+	txGasUsed uint64 // Gas used by the transaction, after refunds
+	startTime time.Time
+	duration  time.Duration
+
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newProfilerTracer returns a native go tracer which profiles the gas and time
+// spent per opcode, and implements vm.EVMLogger.
+func newProfilerTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config profilerTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	switch config.Weight {
+	case "":
+		config.Weight = "gas"
+	case "gas", "time":
+	default:
+		return nil, fmt.Errorf("unknown profile weight %q", config.Weight)
+	}
+	return &profilerTracer{
+		config:    config,
+		entries:   make(map[profileKey]*profileEntry),
+		collapsed: make(map[string]*profileWeight),
+	}, nil
+}
+
+// selectorOf returns the label of the function invoked by a frame.
+func selectorOf(create bool, input []byte) string {
+	if create {
+		return "constructor"
+	}
+	if len(input) < 4 {
+		return "fallback"
+	}
+	return bytesToHex(input[:4])
+}
+
+// push starts profiling a new call frame.
+func (t *profilerTracer) push(contract common.Address, create bool, input []byte) {
+	frame := &profileFrame{
+		contract: contract,
+		selector: selectorOf(create, input),
+		entered:  time.Now(),
+	}
+	label := strings.ToLower(contract.Hex()) + ":" + frame.selector
+	if len(t.frames) == 0 {
+		frame.stack = label
+	} else {
+		frame.stack = t.frames[len(t.frames)-1].stack + ";" + label
+	}
+	t.frames = append(t.frames, frame)
+}
+
+// pop finishes profiling the current call frame, attributing any gas not yet
+// accounted for to the last executed opcode and crediting the parent frame's
+// pending opcode with the total gas used by the frame.
+func (t *profilerTracer) pop(gasUsed uint64) {
+	if len(t.frames) == 0 {
+		return
+	}
+	frame := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+
+	now := time.Now()
+	if frame.pending {
+		var rest uint64
+		if gasUsed > frame.used {
+			rest = gasUsed - frame.used
+		}
+		t.settle(frame, rest, now.Sub(frame.start))
+	} else if gasUsed > frame.used {
+		// No bytecode was executed, so this must be a precompile
+		frame.pc, frame.op = 0, precompileOp
+		t.settle(frame, gasUsed-frame.used, now.Sub(frame.entered))
+	}
+	if len(t.frames) > 0 {
+		parent := t.frames[len(t.frames)-1]
+		parent.childGas += gasUsed
+		parent.childDur += now.Sub(frame.entered)
+	}
+}
+
+// settle accounts the inclusive cost of the pending opcode of the given frame,
+// minus any cost already accounted to its sub-calls.
+func (t *profilerTracer) settle(frame *profileFrame, gas uint64, dur time.Duration) {
+	frame.used += gas
+
+	self, selfDur := gas, dur
+	if frame.childGas < self {
+		self -= frame.childGas
+	} else {
+		self = 0
+	}
+	if frame.childDur < selfDur {
+		selfDur -= frame.childDur
+	} else {
+		selfDur = 0
+	}
+	frame.pending, frame.childGas, frame.childDur = false, 0, 0
+
+	key := profileKey{contract: frame.contract, selector: frame.selector, op: frame.op}
+	leaf := frame.op
+	if t.config.ByPC {
+		key.pc = frame.pc
+		leaf += "@" + strconv.FormatUint(frame.pc, 10)
+	}
+	entry, ok := t.entries[key]
+	if !ok {
+		entry = &profileEntry{Contract: key.contract, Selector: key.selector, Op: key.op}
+		if t.config.ByPC {
+			pc := key.pc
+			entry.PC = &pc
+		}
+		t.entries[key] = entry
+	}
+	entry.Count++
+	entry.Gas += self
+	entry.Time += uint64(selfDur)
+
+	stack := frame.stack + ";" + leaf
+	weight, ok := t.collapsed[stack]
+	if !ok {
+		weight = new(profileWeight)
+		t.collapsed[stack] = weight
+	}
+	weight.gas += self
+	weight.time += selfDur
+}
+
+// CaptureTxStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *profilerTracer) CaptureTxStart(gasLimit uint64) {
+	t.gasLimit = gasLimit
+}
+
+// CaptureTxEnd implements the EVMLogger interface to finalize the tracing operation.
+func (t *profilerTracer) CaptureTxEnd(restGas uint64) {
+	t.txGasUsed = t.gasLimit - restGas
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *profilerTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = t.frames[:0]
+	t.startGas = gas
+	t.startTime = time.Now()
+	t.push(to, create, input)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *profilerTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.pop(gasUsed)
+	t.gasUsed += gasUsed
+	t.duration += time.Since(t.startTime)
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *profilerTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if t.interrupt.Load() || len(t.frames) == 0 {
+		return
+	}
+	now := time.Now()
+	frame := t.frames[len(t.frames)-1]
+	if frame.pending {
+		var used uint64
+		if frame.gas > gas {
+			used = frame.gas - gas
+		}
+		t.settle(frame, used, now.Sub(frame.start))
+	}
+	frame.pending = true
+	frame.pc, frame.op, frame.gas, frame.start = pc, op.String(), gas, now
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *profilerTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.push(to, typ == vm.CREATE || typ == vm.CREATE2, input)
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *profilerTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.pop(gasUsed)
+}
+
+// profilerResult is the JSON summary produced by the profiler.
+type profilerResult struct {
+	GasUsed      uint64          `json:"gasUsed"`
+	IntrinsicGas uint64          `json:"intrinsicGas,omitempty"`
+	Refund       uint64          `json:"refund,omitempty"`
+	Duration     uint64          `json:"duration"`
+	Entries      []*profileEntry `json:"entries"`
+	Collapsed    string          `json:"collapsed"`
+}
+
+// GetResult returns the json-encoded profile summary, and any error arising
+// from the encoding or forceful termination (via `Stop`).
+func (t *profilerTracer) GetResult() (json.RawMessage, error) {
+	res := profilerResult{
+		GasUsed:   t.gasUsed,
+		Duration:  uint64(t.duration),
+		Entries:   make([]*profileEntry, 0, len(t.entries)),
+		Collapsed: t.collapsedStacks(),
+	}
+	if t.gasLimit > 0 && t.gasLimit >= t.startGas {
+		res.IntrinsicGas = t.gasLimit - t.startGas
+		if spent := res.IntrinsicGas + t.gasUsed; spent > t.txGasUsed {
+			res.Refund = spent - t.txGasUsed
+		}
+	}
+	for _, entry := range t.entries {
+		res.Entries = append(res.Entries, entry)
+	}
+	sort.Slice(res.Entries, func(i, j int) bool {
+		a, b := res.Entries[i], res.Entries[j]
+		if a.Gas != b.Gas {
+			return a.Gas > b.Gas
+		}
+		if a.Contract != b.Contract {
+			return a.Contract.Hex() < b.Contract.Hex()
+		}
+		if a.Selector != b.Selector {
+			return a.Selector < b.Selector
+		}
+		if a.PC != nil && b.PC != nil && *a.PC != *b.PC {
+			return *a.PC < *b.PC
+		}
+		return a.Op < b.Op
+	})
+	blob, err := json.Marshal(res)
+	if err != nil {
+		return nil, err
+	}
+	return blob, t.reason
+}
+
+// collapsedStacks returns the profile in collapsed-stack format, one stack per line
+// followed by its self weight, sorted lexicographically.
+func (t *profilerTracer) collapsedStacks() string {
+	lines := make([]string, 0, len(t.collapsed))
+	for stack, weight := range t.collapsed {
+		value := weight.gas
+		if t.config.Weight == "time" {
+			value = uint64(weight.time)
+		}
+		if value == 0 {
+			continue
+		}
+		lines = append(lines, stack+" "+strconv.FormatUint(value, 10))
+	}
+	sort.Strings(lines)
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *profilerTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
@@ -0,0 +1,303 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/eth/tracers"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+func init() {
+	tracers.DefaultDirectory.Register("transferTracer", newTransferTracer, false)
+}
+
+var (
+	// transferTopic is the topic of the ERC-20 and ERC-721 Transfer event.
+	transferTopic = common.HexToHash("0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	// transferSingleTopic is the topic of the ERC-1155 TransferSingle event.
+	transferSingleTopic = common.HexToHash("0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62")
+	// transferBatchTopic is the topic of the ERC-1155 TransferBatch event.
+	transferBatchTopic = common.HexToHash("0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526f8f7fb")
+)
+
+// Asset types reported by the transfer tracer.
+const (
+	assetNative  = "native"
+	assetERC20   = "erc20"
+	assetERC721  = "erc721"
+	assetERC1155 = "erc1155"
+)
+
+// assetTransfer is a single movement of assets between two accounts.
+type assetTransfer struct {
+	Type     string          `json:"type"`
+	Via      string          `json:"via"` // Opcode causing the movement
+	Token    *common.Address `json:"token,omitempty"`
+	Operator *common.Address `json:"operator,omitempty"`
+	From     common.Address  `json:"from"`
+	To       common.Address  `json:"to"`
+	TokenID  *hexutil.Big    `json:"tokenId,omitempty"`
+	Value    *hexutil.Big    `json:"value,omitempty"`
+	Reverted bool            `json:"reverted,omitempty"`
+}
+
+// transferTracer collects the native ether and ERC-20/721/1155 token movements
+// of a transaction into a single normalized list, in execution order. Movements
+// made by call frames which were eventually reverted are retained, but flagged.
+//
+// Example:
+//
+//	> debug.traceTransaction("0x...", {tracer: "transferTracer"})
+//	[
+//	  {type: "native", via: "CALL", from: "0x...", to: "0x...", value: "0xde0b6b3a7640000"},
+//	  {type: "erc20", via: "LOG3", token: "0x...", from: "0x...", to: "0x...", value: "0x3e8"},
+//	  {type: "erc721", via: "LOG4", token: "0x...", from: "0x...", to: "0x...", tokenId: "0x7", reverted: true}
+//	]
+type transferTracer struct {
+	noopTracer
+	transfers []assetTransfer
+	frames    []int       // Index of the first transfer made by each active call frame
+	interrupt atomic.Bool // Atomic flag to signal execution interruption
+	reason    error       // Textual reason for the interruption
+}
+
+// newTransferTracer returns a native go tracer which collects the asset
+// movements of a tx, and implements vm.EVMLogger.
+func newTransferTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &transferTracer{transfers: []assetTransfer{}}, nil
+}
+
+// addNative records a native value transfer, if any value is moved.
+func (t *transferTracer) addNative(via vm.OpCode, from, to common.Address, value *big.Int) {
+	if value == nil || value.Sign() == 0 {
+		return
+	}
+	t.transfers = append(t.transfers, assetTransfer{
+		Type:  assetNative,
+		Via:   via.String(),
+		From:  from,
+		To:    to,
+		Value: (*hexutil.Big)(new(big.Int).Set(value)),
+	})
+}
+
+// CaptureStart implements the EVMLogger interface to initialize the tracing operation.
+func (t *transferTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	t.frames = append(t.frames[:0], len(t.transfers))
+	via := vm.CALL
+	if create {
+		via = vm.CREATE
+	}
+	t.addNative(via, from, to, value)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *transferTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	t.exit(err)
+}
+
+// CaptureEnter is called when EVM enters a new scope (via call, create or selfdestruct).
+func (t *transferTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.frames = append(t.frames, len(t.transfers))
+
+	// Delegate calls reuse the value of the parent frame, and code calls send
+	// the value back to the caller itself, neither moves any ether.
+	switch typ {
+	case vm.CALL, vm.CREATE, vm.CREATE2, vm.SELFDESTRUCT:
+		t.addNative(typ, from, to, value)
+	}
+}
+
+// CaptureExit is called when EVM exits a scope, even if the scope didn't
+// execute any code.
+func (t *transferTracer) CaptureExit(output []byte, gasUsed uint64, err error) {
+	if t.interrupt.Load() {
+		return
+	}
+	t.exit(err)
+}
+
+// exit pops the current call frame, flagging all the transfers made within it
+// as reverted if the frame failed.
+func (t *transferTracer) exit(err error) {
+	if len(t.frames) == 0 {
+		return
+	}
+	start := t.frames[len(t.frames)-1]
+	t.frames = t.frames[:len(t.frames)-1]
+	if err == nil {
+		return
+	}
+	for i := start; i < len(t.transfers); i++ {
+		t.transfers[i].Reverted = true
+	}
+}
+
+// CaptureState implements the EVMLogger interface to trace a single step of VM execution.
+func (t *transferTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// skip if the previous op caused an error
+	if err != nil {
+		return
+	}
+	if op != vm.LOG3 && op != vm.LOG4 {
+		return
+	}
+	if t.interrupt.Load() {
+		return
+	}
+	var (
+		stackData = scope.Stack.Data()
+		size      = int(op - vm.LOG0)
+		mStart    = stackData[len(stackData)-1]
+		mSize     = stackData[len(stackData)-2]
+		topics    = make([]common.Hash, size)
+	)
+	for i := 0; i < size; i++ {
+		topics[i] = common.Hash(stackData[len(stackData)-2-(i+1)].Bytes32())
+	}
+	if topics[0] != transferTopic && topics[0] != transferSingleTopic && topics[0] != transferBatchTopic {
+		return
+	}
+	data, err := tracers.GetMemoryCopyPadded(scope.Memory, int64(mStart.Uint64()), int64(mSize.Uint64()))
+	if err != nil {
+		// mSize was unrealistically large
+		log.Warn("failed to copy log data", "err", err, "tracer", "transferTracer", "offset", mStart, "size", mSize)
+		return
+	}
+	t.transfers = append(t.transfers, decodeTransferLog(op, scope.Contract.Address(), topics, data)...)
+}
+
+// decodeTransferLog decodes the token movements of an ERC-20/721/1155 transfer
+// event. Logs not following the token standards are ignored.
+func decodeTransferLog(op vm.OpCode, token common.Address, topics []common.Hash, data []byte) []assetTransfer {
+	var (
+		via      = op.String()
+		transfer = assetTransfer{Via: via, Token: &token}
+	)
+	switch topics[0] {
+	case transferTopic:
+		transfer.From = common.BytesToAddress(topics[1].Bytes())
+		transfer.To = common.BytesToAddress(topics[2].Bytes())
+
+		switch {
+		case len(topics) == 3 && len(data) == 32:
+			transfer.Type = assetERC20
+			transfer.Value = (*hexutil.Big)(new(big.Int).SetBytes(data))
+		case len(topics) == 4 && len(data) == 0:
+			transfer.Type = assetERC721
+			transfer.TokenID = (*hexutil.Big)(topics[3].Big())
+		default:
+			return nil
+		}
+		return []assetTransfer{transfer}
+
+	case transferSingleTopic:
+		if len(topics) != 4 || len(data) != 64 {
+			return nil
+		}
+		operator := common.BytesToAddress(topics[1].Bytes())
+		transfer.Type = assetERC1155
+		transfer.Operator = &operator
+		transfer.From = common.BytesToAddress(topics[2].Bytes())
+		transfer.To = common.BytesToAddress(topics[3].Bytes())
+		transfer.TokenID = (*hexutil.Big)(new(big.Int).SetBytes(data[:32]))
+		transfer.Value = (*hexutil.Big)(new(big.Int).SetBytes(data[32:]))
+		return []assetTransfer{transfer}
+
+	case transferBatchTopic:
+		if len(topics) != 4 {
+			return nil
+		}
+		ids, ok := decodeUintArray(data, 0)
+		if !ok {
+			return nil
+		}
+		values, ok := decodeUintArray(data, 32)
+		if !ok || len(ids) != len(values) {
+			return nil
+		}
+		var (
+			operator  = common.BytesToAddress(topics[1].Bytes())
+			from      = common.BytesToAddress(topics[2].Bytes())
+			to        = common.BytesToAddress(topics[3].Bytes())
+			transfers = make([]assetTransfer, len(ids))
+		)
+		for i := range ids {
+			transfers[i] = assetTransfer{
+				Type:     assetERC1155,
+				Via:      via,
+				Token:    &token,
+				Operator: &operator,
+				From:     from,
+				To:       to,
+				TokenID:  (*hexutil.Big)(ids[i]),
+				Value:    (*hexutil.Big)(values[i]),
+			}
+		}
+		return transfers
+	}
+	return nil
+}
+
+// decodeUintArray decodes an ABI encoded dynamic uint256 array, whose offset
+// is stored in the word at the given position of data.
+func decodeUintArray(data []byte, pos int) ([]*big.Int, bool) {
+	if len(data) < pos+32 {
+		return nil, false
+	}
+	offset := new(big.Int).SetBytes(data[pos : pos+32])
+	if !offset.IsUint64() || offset.Uint64() > uint64(len(data)-32) {
+		return nil, false
+	}
+	start := int(offset.Uint64())
+	length := new(big.Int).SetBytes(data[start : start+32])
+	if !length.IsUint64() || length.Uint64() > uint64((len(data)-start-32)/32) {
+		return nil, false
+	}
+	items := make([]*big.Int, length.Uint64())
+	for i := range items {
+		word := start + 32 + 32*i
+		items[i] = new(big.Int).SetBytes(data[word : word+32])
+	}
+	return items, true
+}
+
+// GetResult returns the json-encoded list of asset movements, and any error
+// arising from the encoding or forceful termination (via `Stop`).
+func (t *transferTracer) GetResult() (json.RawMessage, error) {
+	res, err := json.Marshal(t.transfers)
+	if err != nil {
+		return nil, err
+	}
+	return res, t.reason
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *transferTracer) Stop(err error) {
+	t.reason = err
+	t.interrupt.Store(true)
+}
@@ -37,6 +37,8 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/privatepool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -81,15 +83,17 @@ func (s *EthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, e
 }
 
 type feeHistoryResult struct {
-	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
-	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
-	BaseFee      []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
-	GasUsedRatio []float64        `json:"gasUsedRatio"`
+	OldestBlock      *hexutil.Big     `json:"oldestBlock"`
+	Reward           [][]*hexutil.Big `json:"reward,omitempty"`
+	BaseFee          []*hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	GasUsedRatio     []float64        `json:"gasUsedRatio"`
+	BlobBaseFee      []*hexutil.Big   `json:"baseFeePerBlobGas,omitempty"`
+	BlobGasUsedRatio []float64        `json:"blobGasUsedRatio,omitempty"`
 }
 
 // FeeHistory returns the fee market history.
 func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecimal64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*feeHistoryResult, error) {
-	oldest, reward, baseFee, gasUsed, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
+	oldest, reward, baseFee, gasUsed, blobBaseFee, blobGasUsed, err := s.b.FeeHistory(ctx, uint64(blockCount), lastBlock, rewardPercentiles)
 	if err != nil {
 		return nil, err
 	}
@@ -112,9 +116,58 @@ func (s *EthereumAPI) FeeHistory(ctx context.Context, blockCount math.HexOrDecim
 			results.BaseFee[i] = (*hexutil.Big)(v)
 		}
 	}
+	if blobBaseFee != nil {
+		results.BlobBaseFee = make([]*hexutil.Big, len(blobBaseFee))
+		for i, v := range blobBaseFee {
+			results.BlobBaseFee[i] = (*hexutil.Big)(v)
+		}
+		results.BlobGasUsedRatio = blobGasUsed
+	}
 	return results, nil
 }
 
+// feeTierResult is a fee suggestion for a transaction to be included within a
+// number of blocks.
+type feeTierResult struct {
+	Blocks               hexutil.Uint64 `json:"blocks"`
+	Confidence           float64        `json:"confidence"`
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas"`
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas,omitempty"`
+	MaxFeePerBlobGas     *hexutil.Big   `json:"maxFeePerBlobGas,omitempty"`
+}
+
+type feeEstimateResult struct {
+	BaseFee     *hexutil.Big             `json:"baseFeePerGas,omitempty"`
+	BlobBaseFee *hexutil.Big             `json:"baseFeePerBlobGas,omitempty"`
+	Tiers       map[string]feeTierResult `json:"tiers"`
+}
+
+// EstimateFees returns fee suggestions for a transaction to be included within
+// a number of blocks with a given confidence, for a few tiers (slow, normal and
+// fast). The suggestions are based on recent block rewards, the public pending
+// transactions and the projected base fees.
+func (s *EthereumAPI) EstimateFees(ctx context.Context) (*feeEstimateResult, error) {
+	estimate, err := s.b.EstimateFees(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := &feeEstimateResult{
+		BaseFee:     (*hexutil.Big)(estimate.BaseFee),
+		BlobBaseFee: (*hexutil.Big)(estimate.BlobBaseFee),
+		Tiers:       make(map[string]feeTierResult, len(estimate.Tiers)),
+	}
+	for _, tier := range estimate.Tiers {
+		result.Tiers[tier.Name] = feeTierResult{
+			Blocks:               hexutil.Uint64(tier.Blocks),
+			Confidence:           tier.Confidence,
+			MaxPriorityFeePerGas: (*hexutil.Big)(tier.MaxPriorityFeePerGas),
+			MaxFeePerGas:         (*hexutil.Big)(tier.MaxFeePerGas),
+			MaxFeePerBlobGas:     (*hexutil.Big)(tier.MaxFeePerBlobGas),
+		}
+	}
+	return result, nil
+}
+
 // Syncing returns false in case the node is currently not syncing with the network. It can be up-to-date or has not
 // yet received the latest block headers from its pears. In case it is synchronizing:
 // - startingBlock: block number this node started to synchronize from
@@ -209,13 +262,73 @@ func (s *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCT
 	return content
 }
 
-// Status returns the number of pending and queued transaction in the pool.
-func (s *TxPoolAPI) Status() map[string]hexutil.Uint {
-	pending, queue := s.b.Stats()
-	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(pending),
-		"queued":  hexutil.Uint(queue),
+// txLifecycleEvent is an entry in the lifecycle history of a transaction.
+type txLifecycleEvent struct {
+	Time        hexutil.Uint64 `json:"time"`
+	Stage       string         `json:"stage"`
+	Reason      string         `json:"reason,omitempty"`
+	Replacement *common.Hash   `json:"replacement,omitempty"`
+}
+
+// Status returns the number of pending and queued transaction in the pool. If a
+// transaction hash is given, the status and recorded lifecycle history of that
+// transaction is returned instead.
+func (s *TxPoolAPI) Status(ctx context.Context, hash *common.Hash) (map[string]interface{}, error) {
+	if hash == nil {
+		pending, queue := s.b.Stats()
+		admission := s.b.TxPoolAdmission()
+		return map[string]interface{}{
+			"pending": hexutil.Uint(pending),
+			"queued":  hexutil.Uint(queue),
+			"admission": map[string]interface{}{
+				"originRate":       hexutil.Uint64(admission.OriginRate),
+				"queueCoverage":    hexutil.Uint64(admission.QueueCoverage),
+				"spamPenalty":      hexutil.Uint64(admission.SpamPenalty),
+				"limitedOrigins":   hexutil.Uint(admission.LimitedOrigins),
+				"penalizedSenders": hexutil.Uint(admission.PenalizedSenders),
+				"throttledSenders": hexutil.Uint(admission.ThrottledSenders),
+				"rateLimited":      hexutil.Uint64(admission.RateLimited),
+				"uncovered":        hexutil.Uint64(admission.Uncovered),
+				"throttled":        hexutil.Uint64(admission.Throttled),
+			},
+		}, nil
 	}
+	var (
+		events  = s.b.TxPoolLifecycle(*hash)
+		history = make([]txLifecycleEvent, 0, len(events))
+		status  = "unknown"
+	)
+	for _, event := range events {
+		entry := txLifecycleEvent{
+			Time:  hexutil.Uint64(event.Time.Unix()),
+			Stage: event.Stage.String(),
+		}
+		if event.Stage == txpool.TxStageDropped {
+			entry.Reason = event.Reason.String()
+		}
+		if event.Replacement != (common.Hash{}) {
+			replacement := event.Replacement
+			entry.Replacement = &replacement
+		}
+		history = append(history, entry)
+		status = entry.Stage
+	}
+	result := map[string]interface{}{
+		"hash":    *hash,
+		"status":  status,
+		"history": history,
+	}
+	// The pool only sees stale nonces, check whether the transaction made it
+	tx, blockHash, blockNumber, _, err := s.b.GetTransaction(ctx, *hash)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		result["status"] = txpool.TxStageIncluded.String()
+		result["blockHash"] = blockHash
+		result["blockNumber"] = hexutil.Uint64(blockNumber)
+	}
+	return result, nil
 }
 
 // Inspect retrieves the content of the transaction pool and flattens it into an
@@ -1518,7 +1631,7 @@ func AccessList(ctx context.Context, b Backend, blockNrOrHash rpc.BlockNumberOrH
 	}
 	isPostMerge := header.Difficulty.Cmp(common.Big0) == 0
 	// Retrieve the precompiles since they don't need to be added to the access list
-	precompiles := vm.ActivePrecompiles(b.ChainConfig().Rules(header.Number, isPostMerge, header.Time))
+	precompiles := vm.ActiveChainPrecompiles(b.ChainConfig(), b.ChainConfig().Rules(header.Number, isPostMerge, header.Time))
 
 	// Create an initial tracer
 	prevTracer := logger.NewAccessListTracer(nil, args.from(), to, precompiles)
@@ -1839,6 +1952,69 @@ func (s *TransactionAPI) SendRawTransaction(ctx context.Context, input hexutil.B
 	return SubmitTransaction(ctx, s.b, tx)
 }
 
+// SendPrivateRawTransaction will add the signed transaction to the private pool.
+// Private transactions are only included in locally built blocks and are never
+// propagated to the network. They are dropped if not included within a limited
+// number of blocks, see GetPrivateTransactionStatus.
+func (s *TransactionAPI) SendPrivateRawTransaction(ctx context.Context, input hexutil.Bytes) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := checkTxFee(tx.GasPrice(), tx.Gas(), s.b.RPCTxFeeCap()); err != nil {
+		return common.Hash{}, err
+	}
+	if err := s.b.SendPrivateTx(ctx, tx); err != nil {
+		return common.Hash{}, err
+	}
+	log.Info("Submitted private transaction", "hash", tx.Hash().Hex(), "nonce", tx.Nonce(), "recipient", tx.To(), "value", tx.Value())
+	return tx.Hash(), nil
+}
+
+// GetPrivateTransactionStatus returns the lifecycle status of a privately
+// submitted transaction: pending or queued while awaiting inclusion, included,
+// expired or dropped once finished. Finished transactions are only tracked for
+// a limited time, after which they are reported as unknown.
+func (s *TransactionAPI) GetPrivateTransactionStatus(ctx context.Context, hash common.Hash) (map[string]interface{}, error) {
+	record, err := s.b.GetPrivateTxRecord(hash)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{
+		"status": record.Status.String(),
+	}
+	if record.Status != privatepool.TxStatusUnknown {
+		fields["deadline"] = hexutil.Uint64(record.Deadline)
+	}
+	if record.Status == privatepool.TxStatusIncluded {
+		fields["blockNumber"] = hexutil.Uint64(record.Block)
+	}
+	return fields, nil
+}
+
+// maxConditionalCost is the maximum number of storage lookups the preconditions
+// of a conditional transaction may require.
+const maxConditionalCost = 1000
+
+// SendRawTransactionConditional will add the signed transaction to the transaction
+// pool, along with a set of preconditions restricting its inclusion. The pool drops
+// the transaction once the conditions can no longer be met. Conditional transactions
+// are not propagated to the network.
+func (s *TransactionAPI) SendRawTransactionConditional(ctx context.Context, input hexutil.Bytes, conditional types.TransactionConditional) (common.Hash, error) {
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(input); err != nil {
+		return common.Hash{}, err
+	}
+	if err := conditional.Validate(); err != nil {
+		return common.Hash{}, err
+	}
+	if cost := conditional.Cost(); cost > maxConditionalCost {
+		return common.Hash{}, fmt.Errorf("conditional too expensive: %d storage lookups, max %d", cost, maxConditionalCost)
+	}
+	tx.SetConditional(&conditional)
+	return SubmitTransaction(ctx, s.b, tx)
+}
+
 // Sign calculates an ECDSA signature for:
 // keccak256("\x19Ethereum Signed Message:\n" + len(message) + message).
 //
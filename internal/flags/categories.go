@@ -21,6 +21,7 @@ import "github.com/urfave/cli/v2"
 const (
 	EthCategory        = "ETHEREUM"
 	LightCategory      = "LIGHT CLIENT"
+	BeaconCategory     = "BEACON CHAIN"
 	DevCategory        = "DEVELOPER CHAIN"
 	EthashCategory     = "ETHASH"
 	TxPoolCategory     = "TRANSACTION POOL (EVM)"
@@ -142,6 +142,27 @@ web3._extend({
 			call: 'admin_removeTrustedPeer',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'banPeer',
+			call: 'admin_banPeer',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+		new web3._extend.Method({
+			name: 'unbanPeer',
+			call: 'admin_unbanPeer',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'setMaxBandwidth',
+			call: 'admin_setMaxBandwidth',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'setBandwidthLimit',
+			call: 'admin_setBandwidthLimit',
+			params: 2
+		}),
 		new web3._extend.Method({
 			name: 'exportChain',
 			call: 'admin_exportChain',
@@ -204,6 +225,14 @@ web3._extend({
 			name: 'datadir',
 			getter: 'admin_datadir'
 		}),
+		new web3._extend.Property({
+			name: 'bans',
+			getter: 'admin_bans'
+		}),
+		new web3._extend.Property({
+			name: 'bandwidth',
+			getter: 'admin_bandwidth'
+		}),
 	]
 });
 `
@@ -606,6 +635,15 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'estimateFees',
+			call: 'eth_estimateFees',
+		}),
+		new web3._extend.Method({
+			name: 'simulateBlock',
+			call: 'eth_simulateBlock',
+			params: 1,
+		}),
 		new web3._extend.Method({
 			name: 'getLogs',
 			call: 'eth_getLogs',
@@ -799,6 +837,11 @@ web3._extend({
 			call: 'txpool_contentFrom',
 			params: 1,
 		}),
+		new web3._extend.Method({
+			name: 'transactionStatus',
+			call: 'txpool_status',
+			params: 1,
+		}),
 	]
 });
 `
@@ -0,0 +1,228 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// maxBundlesPerBlock is the maximum number of bundles accepted for a single
+	// target block.
+	maxBundlesPerBlock = 256
+
+	// maxBundles is the maximum number of bundles tracked across all target blocks.
+	maxBundles = 4096
+
+	// maxBundleDistance is how far beyond the current head bundles may target.
+	maxBundleDistance = 32
+)
+
+var (
+	errEmptyBundle      = errors.New("empty bundle")
+	errBundleTooMany    = errors.New("too many bundles for target block")
+	errBundlePoolFull   = errors.New("bundle pool full")
+	errBundleOutdated   = errors.New("bundle targets past block")
+	errBundleTooFar     = errors.New("bundle targets block too far in the future")
+	errBundleReverted   = errors.New("bundle transaction reverted")
+	errBundleUnprofited = errors.New("bundle pays no fees")
+)
+
+// Bundle is an ordered group of transactions to be included atomically in a
+// specific block: either all of them are included, in the given order, or none.
+// Transactions are required to succeed, unless marked as revertible, in which
+// case they are included even if their execution fails.
+type Bundle struct {
+	Txs         types.Transactions
+	BlockNumber uint64        // Number of the block the bundle targets
+	Reverting   []common.Hash // Hashes of the transactions allowed to revert
+}
+
+// revertible returns whether the transaction with the given hash may revert
+// without invalidating the bundle.
+func (b *Bundle) revertible(hash common.Hash) bool {
+	for _, h := range b.Reverting {
+		if h == hash {
+			return true
+		}
+	}
+	return false
+}
+
+// bundlePool tracks the bundles submitted for upcoming blocks.
+type bundlePool struct {
+	bundles map[uint64][]*Bundle // Bundles grouped by target block number
+	count   int                  // Number of bundles across all targets
+	lock    sync.Mutex
+}
+
+func newBundlePool() *bundlePool {
+	return &bundlePool{bundles: make(map[uint64][]*Bundle)}
+}
+
+// add inserts a bundle into the pool, given the current chain head.
+func (p *bundlePool) add(bundle *Bundle, head uint64) error {
+	if len(bundle.Txs) == 0 {
+		return errEmptyBundle
+	}
+	if bundle.BlockNumber <= head {
+		return fmt.Errorf("%w: target %d, head %d", errBundleOutdated, bundle.BlockNumber, head)
+	}
+	if bundle.BlockNumber > head+maxBundleDistance {
+		return fmt.Errorf("%w: target %d, head %d", errBundleTooFar, bundle.BlockNumber, head)
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(head + 1)
+	if len(p.bundles[bundle.BlockNumber]) >= maxBundlesPerBlock {
+		return errBundleTooMany
+	}
+	if p.count >= maxBundles {
+		return errBundlePoolFull
+	}
+	p.bundles[bundle.BlockNumber] = append(p.bundles[bundle.BlockNumber], bundle)
+	p.count++
+	return nil
+}
+
+// pending returns the bundles targeting the given block, dropping the ones
+// targeting earlier blocks.
+func (p *bundlePool) pending(number uint64) []*Bundle {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.prune(number)
+	return append([]*Bundle(nil), p.bundles[number]...)
+}
+
+// prune drops the bundles targeting blocks before the given number.
+// Note, this method assumes the pool lock is held!
+func (p *bundlePool) prune(number uint64) {
+	for target, bundles := range p.bundles {
+		if target < number {
+			p.count -= len(bundles)
+			delete(p.bundles, target)
+		}
+	}
+}
+
+// simulatedBundle is a bundle successfully executed on top of a block being
+// built, along with the resulting environment.
+type simulatedBundle struct {
+	bundle  *Bundle
+	env     *environment // Environment after applying the bundle
+	gasUsed uint64       // Gas used by all the transactions of the bundle
+	payment *big.Int     // Total payment made to the coinbase by the bundle
+}
+
+// score returns the effective gas price paid to the miner by the bundle.
+func (s *simulatedBundle) score() *big.Int {
+	return new(big.Int).Div(s.payment, new(big.Int).SetUint64(s.gasUsed))
+}
+
+// simulateBundle executes a bundle on a copy of the given environment, returning
+// the outcome if all the transactions succeeded or were allowed to revert. The
+// copy is discarded on failure, otherwise it's up to the caller to discard it if
+// the bundle is not committed.
+func (w *worker) simulateBundle(env *environment, bundle *Bundle) (sb *simulatedBundle, err error) {
+	var (
+		sim     = env.copy()
+		gasUsed = sim.header.GasUsed
+		balance = sim.state.GetBalance(sim.coinbase)
+	)
+	defer func() {
+		if err != nil {
+			sim.discard()
+		}
+	}()
+	if sim.gasPool == nil {
+		sim.gasPool = new(core.GasPool).AddGas(sim.header.GasLimit)
+	}
+	for _, tx := range bundle.Txs {
+		sim.state.SetTxContext(tx.Hash(), sim.tcount)
+		if _, err := w.commitTransaction(sim, tx); err != nil {
+			return nil, fmt.Errorf("tx %v: %w", tx.Hash(), err)
+		}
+		if sim.receipts[len(sim.receipts)-1].Status == types.ReceiptStatusFailed && !bundle.revertible(tx.Hash()) {
+			return nil, fmt.Errorf("%w: %v", errBundleReverted, tx.Hash())
+		}
+		sim.tcount++
+	}
+	payment := new(big.Int).Sub(sim.state.GetBalance(sim.coinbase), balance)
+	if payment.Sign() <= 0 {
+		return nil, errBundleUnprofited
+	}
+	return &simulatedBundle{
+		bundle:  bundle,
+		env:     sim,
+		gasUsed: sim.header.GasUsed - gasUsed,
+		payment: payment,
+	}, nil
+}
+
+// commitBundles simulates the given bundles on top of the block being built,
+// and includes the valid ones in the order of their effective payment to the
+// miner. Since each included bundle changes the state the following ones run
+// on, every bundle is re-simulated before inclusion.
+func (w *worker) commitBundles(env *environment, bundles []*Bundle, interrupt *atomic.Int32) error {
+	var sims []*simulatedBundle
+	for _, bundle := range bundles {
+		sim, err := w.simulateBundle(env, bundle)
+		if err != nil {
+			log.Trace("Discarding invalid bundle", "block", bundle.BlockNumber, "txs", len(bundle.Txs), "err", err)
+			continue
+		}
+		sims = append(sims, sim)
+	}
+	sort.SliceStable(sims, func(i, j int) bool {
+		return sims[i].score().Cmp(sims[j].score()) > 0
+	})
+	for i, sim := range sims {
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				for _, rest := range sims[i:] {
+					rest.env.discard()
+				}
+				return signalToErr(signal)
+			}
+		}
+		// The first bundle was simulated on top of the current environment
+		if i > 0 {
+			sim.env.discard()
+			resim, err := w.simulateBundle(env, sim.bundle)
+			if err != nil {
+				log.Trace("Discarding conflicting bundle", "block", sim.bundle.BlockNumber, "err", err)
+				continue
+			}
+			sim = resim
+		}
+		env.discard()
+		*env = *sim.env
+	}
+	return nil
+}
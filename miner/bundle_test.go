@@ -0,0 +1,159 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// bundleTx creates a transaction from the test bank, paying the given multiple
+// of the initial base fee. Transactions without a recipient deploy a contract
+// whose constructor reverts.
+func bundleTx(nonce uint64, to *common.Address, priceMult int64) *types.Transaction {
+	var (
+		price = big.NewInt(priceMult * params.InitialBaseFee)
+		tx    *types.Transaction
+	)
+	if to == nil {
+		tx = types.NewContractCreation(nonce, new(big.Int), 100_000, price, hexutil.MustDecode("0x60006000fd"))
+	} else {
+		tx = types.NewTransaction(nonce, *to, big.NewInt(1000), params.TxGas, price, nil)
+	}
+	tx, _ = types.SignTx(tx, types.HomesteadSigner{}, testBankKey)
+	return tx
+}
+
+func TestBundlePool(t *testing.T) {
+	pool := newBundlePool()
+
+	if err := pool.add(&Bundle{BlockNumber: 2}, 1); !errors.Is(err, errEmptyBundle) {
+		t.Errorf("empty bundle error mismatch: have %v, want %v", err, errEmptyBundle)
+	}
+	txs := types.Transactions{bundleTx(0, &testUserAddress, 2)}
+	if err := pool.add(&Bundle{Txs: txs, BlockNumber: 1}, 1); !errors.Is(err, errBundleOutdated) {
+		t.Errorf("outdated bundle error mismatch: have %v, want %v", err, errBundleOutdated)
+	}
+	if err := pool.add(&Bundle{Txs: txs, BlockNumber: 2 + maxBundleDistance}, 1); !errors.Is(err, errBundleTooFar) {
+		t.Errorf("far bundle error mismatch: have %v, want %v", err, errBundleTooFar)
+	}
+	for number := uint64(2); number <= 3; number++ {
+		if err := pool.add(&Bundle{Txs: txs, BlockNumber: number}, 1); err != nil {
+			t.Fatalf("failed to add bundle for block %d: %v", number, err)
+		}
+	}
+	if bundles := pool.pending(3); len(bundles) != 1 {
+		t.Errorf("pending bundles mismatch: have %d, want 1", len(bundles))
+	}
+	if bundles := pool.pending(2); len(bundles) != 0 {
+		t.Errorf("pruned bundles returned: have %d, want 0", len(bundles))
+	}
+}
+
+func TestBundlePoolLimits(t *testing.T) {
+	pool := newBundlePool()
+	txs := types.Transactions{bundleTx(0, &testUserAddress, 2)}
+
+	// Fill the pool, respecting the per-block limit.
+	var number uint64
+	for i := 0; i < maxBundles; i++ {
+		number = 1 + uint64(i/maxBundlesPerBlock)
+		if err := pool.add(&Bundle{Txs: txs, BlockNumber: number}, 0); err != nil {
+			t.Fatalf("failed to add bundle %d: %v", i, err)
+		}
+	}
+	if err := pool.add(&Bundle{Txs: txs, BlockNumber: number}, 0); !errors.Is(err, errBundleTooMany) {
+		t.Errorf("per-block limit error mismatch: have %v, want %v", err, errBundleTooMany)
+	}
+	if err := pool.add(&Bundle{Txs: txs, BlockNumber: number + 1}, 0); !errors.Is(err, errBundlePoolFull) {
+		t.Errorf("global limit error mismatch: have %v, want %v", err, errBundlePoolFull)
+	}
+	// Advancing the head frees the bundles of past blocks.
+	if err := pool.add(&Bundle{Txs: txs, BlockNumber: number + 1}, 1); err != nil {
+		t.Errorf("failed to add bundle after head advanced: %v", err)
+	}
+}
+
+func TestBundleInclusion(t *testing.T) {
+	var (
+		cheap     = bundleTx(0, &testUserAddress, 5)
+		best      = bundleTx(0, &testUserAddress, 10)
+		expensive = bundleTx(0, &testUserAddress, 20)
+		reverting = bundleTx(1, nil, 20)
+	)
+	tests := []struct {
+		bundles []*Bundle
+		want    []common.Hash
+	}{
+		// The best paying bundle wins, conflicting ones and failing ones are dropped
+		{
+			bundles: []*Bundle{
+				{Txs: types.Transactions{cheap}},
+				{Txs: types.Transactions{best}},
+				{Txs: types.Transactions{expensive, reverting}},
+			},
+			want: []common.Hash{best.Hash()},
+		},
+		// Reverting transactions are included if explicitly allowed
+		{
+			bundles: []*Bundle{
+				{Txs: types.Transactions{best}},
+				{Txs: types.Transactions{expensive, reverting}, Reverting: []common.Hash{reverting.Hash()}},
+			},
+			want: []common.Hash{expensive.Hash(), reverting.Hash()},
+		},
+	}
+	for i, tt := range tests {
+		engine := ethash.NewFaker()
+		w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+
+		for _, bundle := range tt.bundles {
+			bundle.BlockNumber = 1
+			if err := w.bundles.add(bundle, 0); err != nil {
+				t.Fatalf("test %d: failed to add bundle: %v", i, err)
+			}
+		}
+		block, _, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), testUserAddress, common.Hash{}, nil, false)
+		if err != nil {
+			t.Fatalf("test %d: failed to build block: %v", i, err)
+		}
+		// The pool transaction of the bank conflicts with the bundles, and is dropped
+		var have []common.Hash
+		for _, tx := range block.Transactions() {
+			have = append(have, tx.Hash())
+		}
+		if len(have) != len(tt.want) {
+			t.Fatalf("test %d: transaction count mismatch: have %d, want %d", i, len(have), len(tt.want))
+		}
+		for j := range have {
+			if have[j] != tt.want[j] {
+				t.Errorf("test %d: transaction %d mismatch: have %x, want %x", i, j, have[j], tt.want[j])
+			}
+		}
+		w.close()
+		engine.Close()
+	}
+}
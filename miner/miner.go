@@ -18,6 +18,7 @@
 package miner
 
 import (
+	"context"
 	"fmt"
 	"math/big"
 	"sync"
@@ -51,6 +52,7 @@ type Config struct {
 	GasCeil   uint64         // Target gas ceiling for mined blocks.
 	GasPrice  *big.Int       // Minimum gas price for mining a transaction
 	Recommit  time.Duration  // The time interval for miner to re-create mining work.
+	Ordering  string         `toml:",omitempty"` // Transaction ordering policy for built blocks (price, fifo, roundrobin)
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
 }
@@ -234,12 +236,30 @@ func (miner *Miner) SetGasCeil(ceil uint64) {
 	miner.worker.setGasCeil(ceil)
 }
 
+// SetOrdering replaces the ordering policy of pending transactions in built
+// blocks, allowing custom policies beside the built-in ones.
+func (miner *Miner) SetOrdering(policy OrderingPolicy) {
+	miner.worker.setOrdering(policy)
+}
+
 // SubscribePendingLogs starts delivering logs from pending transactions
 // to the given channel.
 func (miner *Miner) SubscribePendingLogs(ch chan<- []*types.Log) event.Subscription {
 	return miner.worker.pendingLogsFeed.Subscribe(ch)
 }
 
+// SendBundle submits a bundle of transactions for inclusion in its target block.
+func (miner *Miner) SendBundle(bundle *Bundle) error {
+	return miner.worker.bundles.add(bundle, miner.worker.chain.CurrentBlock().Number.Uint64())
+}
+
+// SimulateBlock builds a hypothetical block with the given transactions on top
+// of the current head. The block is neither sealed nor announced, and building
+// it is aborted once the context is cancelled.
+func (miner *Miner) SimulateBlock(ctx context.Context, args *SimulateArgs) (*SimulatedBlock, error) {
+	return miner.worker.simulateBlock(ctx, args)
+}
+
 // BuildPayload builds the payload according to the provided parameters.
 func (miner *Miner) BuildPayload(args *BuildPayloadArgs) (*Payload, error) {
 	return miner.worker.buildPayload(args)
@@ -0,0 +1,260 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// Names of the built-in transaction ordering policies.
+const (
+	OrderingPrice      = "price"      // Highest miner tip first, the default
+	OrderingFIFO       = "fifo"       // Earliest arrival first
+	OrderingRoundRobin = "roundrobin" // One transaction per sender in turn
+)
+
+// OrderingPolicy decides the order in which pending transactions are tried for
+// inclusion in a block being built.
+type OrderingPolicy interface {
+	// Order returns an iterator over the given pending transactions, which are
+	// grouped by sender and sorted by nonce. The iterator takes ownership of
+	// the map.
+	Order(block *BuildContext, txs map[common.Address][]*types.Transaction) TransactionOrder
+}
+
+// BuildContext is a read-only view of the block being built, handed to the
+// ordering policy.
+type BuildContext struct {
+	Header  *types.Header // Copy of the header of the block being built
+	Signer  types.Signer  // Signer for the transactions of the block
+	State   StateReader   // State after the transactions included so far
+	GasLeft uint64        // Gas still available in the block
+}
+
+// StateReader provides read access to the state a block is built on.
+type StateReader interface {
+	GetBalance(addr common.Address) *big.Int
+	GetNonce(addr common.Address) uint64
+	GetCode(addr common.Address) []byte
+	GetState(addr common.Address, key common.Hash) common.Hash
+}
+
+// stateReader wraps a StateDB, handing out copies so that the state can't be
+// modified through the returned values.
+type stateReader struct {
+	db *state.StateDB
+}
+
+func (r stateReader) GetBalance(addr common.Address) *big.Int {
+	return new(big.Int).Set(r.db.GetBalance(addr))
+}
+
+func (r stateReader) GetNonce(addr common.Address) uint64 {
+	return r.db.GetNonce(addr)
+}
+
+func (r stateReader) GetCode(addr common.Address) []byte {
+	return common.CopyBytes(r.db.GetCode(addr))
+}
+
+func (r stateReader) GetState(addr common.Address, key common.Hash) common.Hash {
+	return r.db.GetState(addr, key)
+}
+
+// buildContext creates the view of the environment handed to ordering policies.
+func (env *environment) buildContext() *BuildContext {
+	block := &BuildContext{
+		Header: types.CopyHeader(env.header),
+		Signer: env.signer,
+	}
+	if env.state != nil {
+		block.State = stateReader{env.state}
+	}
+	if env.gasPool != nil {
+		block.GasLeft = env.gasPool.Gas()
+	} else {
+		block.GasLeft = env.header.GasLimit
+	}
+	return block
+}
+
+// TransactionOrder iterates over a set of pending transactions, yielding the
+// next one to try. Implementations must honour the nonce ordering of accounts.
+type TransactionOrder interface {
+	// Peek returns the next transaction to try, or nil if none are left.
+	Peek() *types.Transaction
+
+	// Shift replaces the current transaction with the next one of the same sender.
+	Shift()
+
+	// Pop removes the current transaction along with all the subsequent ones of
+	// the same sender, as they can't be executed any more.
+	Pop()
+}
+
+// NewOrderingPolicy returns the built-in ordering policy with the given name.
+// An empty name selects the default price ordering.
+func NewOrderingPolicy(name string) (OrderingPolicy, error) {
+	switch name {
+	case "", OrderingPrice:
+		return priceOrdering{}, nil
+	case OrderingFIFO:
+		return fifoOrdering{}, nil
+	case OrderingRoundRobin:
+		return roundRobinOrdering{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ordering policy %q", name)
+	}
+}
+
+// priceOrdering yields the transactions paying the highest tip to the miner
+// first, preferring the earlier one on ties.
+type priceOrdering struct{}
+
+func (priceOrdering) Order(block *BuildContext, txs map[common.Address][]*types.Transaction) TransactionOrder {
+	return types.NewTransactionsByPriceAndNonce(block.Signer, txs, block.Header.BaseFee)
+}
+
+// fifoOrdering yields the transactions in the order they arrived in the local
+// node, regardless of the fees they pay.
+type fifoOrdering struct{}
+
+func (fifoOrdering) Order(block *BuildContext, txs map[common.Address][]*types.Transaction) TransactionOrder {
+	heads := make(txsByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		heads = append(heads, accTxs[0])
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &fifoOrder{txs: txs, heads: heads, signer: block.Signer}
+}
+
+// txsByTime implements a heap of transactions, the earliest arrival first. Ties
+// are broken by hash to keep the ordering deterministic.
+type txsByTime []*types.Transaction
+
+func (s txsByTime) Len() int { return len(s) }
+func (s txsByTime) Less(i, j int) bool {
+	ti, tj := s[i].Time(), s[j].Time()
+	if ti.Equal(tj) {
+		hi, hj := s[i].Hash(), s[j].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	}
+	return ti.Before(tj)
+}
+func (s txsByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txsByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txsByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*s = old[0 : n-1]
+	return x
+}
+
+// fifoOrder is the iterator of the arrival time ordering.
+type fifoOrder struct {
+	txs    map[common.Address][]*types.Transaction // Per account nonce-sorted list of transactions
+	heads  txsByTime                               // Next transaction for each unique account
+	signer types.Signer                            // Signer for the set of transactions
+}
+
+func (o *fifoOrder) Peek() *types.Transaction {
+	if len(o.heads) == 0 {
+		return nil
+	}
+	return o.heads[0]
+}
+
+func (o *fifoOrder) Shift() {
+	acc, _ := types.Sender(o.signer, o.heads[0])
+	if txs := o.txs[acc]; len(txs) > 0 {
+		o.heads[0], o.txs[acc] = txs[0], txs[1:]
+		heap.Fix(&o.heads, 0)
+		return
+	}
+	heap.Pop(&o.heads)
+}
+
+func (o *fifoOrder) Pop() {
+	heap.Pop(&o.heads)
+}
+
+// roundRobinOrdering cycles through the senders, yielding one transaction from
+// each in turn, so that no account can crowd out the others. Senders are visited
+// in the arrival order of their first pending transaction.
+type roundRobinOrdering struct{}
+
+func (roundRobinOrdering) Order(_ *BuildContext, txs map[common.Address][]*types.Transaction) TransactionOrder {
+	senders := make([]common.Address, 0, len(txs))
+	for from := range txs {
+		senders = append(senders, from)
+	}
+	sort.Slice(senders, func(i, j int) bool {
+		ti, tj := txs[senders[i]][0].Time(), txs[senders[j]][0].Time()
+		if ti.Equal(tj) {
+			return bytes.Compare(senders[i][:], senders[j][:]) < 0
+		}
+		return ti.Before(tj)
+	})
+	return &roundRobinOrder{txs: txs, senders: senders}
+}
+
+// roundRobinOrder is the iterator of the sender-fair ordering.
+type roundRobinOrder struct {
+	txs     map[common.Address][]*types.Transaction // Per account nonce-sorted list of transactions
+	senders []common.Address                        // Accounts with transactions left, in visiting order
+	next    int                                     // Index of the account to yield from
+}
+
+func (o *roundRobinOrder) Peek() *types.Transaction {
+	if len(o.senders) == 0 {
+		return nil
+	}
+	return o.txs[o.senders[o.next]][0]
+}
+
+func (o *roundRobinOrder) Shift() {
+	acc := o.senders[o.next]
+	if o.txs[acc] = o.txs[acc][1:]; len(o.txs[acc]) == 0 {
+		o.Pop()
+		return
+	}
+	o.next = (o.next + 1) % len(o.senders)
+}
+
+func (o *roundRobinOrder) Pop() {
+	delete(o.txs, o.senders[o.next])
+	o.senders = append(o.senders[:o.next], o.senders[o.next+1:]...)
+	if o.next >= len(o.senders) {
+		o.next = 0
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the built-in ordering policies yield the pending transactions in
+// the expected order, honouring account nonces.
+func TestOrderingPolicies(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 3)
+		addrs = make([]common.Address, 3)
+		start = time.Unix(1000, 0)
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	// Each sender has two transactions, the arrival and prices are set so that
+	// every policy yields a different order.
+	//
+	//   sender 0: nonce 0 (price 1, t=0), nonce 1 (price 1, t=3)
+	//   sender 1: nonce 0 (price 3, t=1), nonce 1 (price 3, t=4)
+	//   sender 2: nonce 0 (price 2, t=2), nonce 1 (price 2, t=5)
+	var (
+		prices = []int64{1, 3, 2}
+		txs    = make([][]*types.Transaction, 3)
+	)
+	for i, key := range keys {
+		for nonce := uint64(0); nonce < 2; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, new(big.Int), params.TxGas, big.NewInt(prices[i]), nil), types.HomesteadSigner{}, key)
+			tx.SetTime(start.Add(time.Duration(int(nonce)*len(keys)+i) * time.Second))
+			txs[i] = append(txs[i], tx)
+		}
+	}
+	block := &BuildContext{Header: &types.Header{}, Signer: types.HomesteadSigner{}}
+
+	tests := []struct {
+		policy string
+		want   []*types.Transaction
+	}{
+		{OrderingPrice, []*types.Transaction{txs[1][0], txs[1][1], txs[2][0], txs[2][1], txs[0][0], txs[0][1]}},
+		{OrderingFIFO, []*types.Transaction{txs[0][0], txs[1][0], txs[2][0], txs[0][1], txs[1][1], txs[2][1]}},
+		{OrderingRoundRobin, []*types.Transaction{txs[0][0], txs[1][0], txs[2][0], txs[0][1], txs[1][1], txs[2][1]}},
+	}
+	for _, tt := range tests {
+		policy, err := NewOrderingPolicy(tt.policy)
+		if err != nil {
+			t.Fatalf("%s: failed to create policy: %v", tt.policy, err)
+		}
+		pending := make(map[common.Address][]*types.Transaction)
+		for i, addr := range addrs {
+			pending[addr] = txs[i]
+		}
+		order := policy.Order(block, pending)
+		for i, want := range tt.want {
+			have := order.Peek()
+			if have != want {
+				t.Fatalf("%s: transaction %d mismatch: have %x, want %x", tt.policy, i, have.Hash(), want.Hash())
+			}
+			order.Shift()
+		}
+		if tx := order.Peek(); tx != nil {
+			t.Errorf("%s: unexpected leftover transaction %x", tt.policy, tx.Hash())
+		}
+	}
+	if _, err := NewOrderingPolicy("random"); err == nil {
+		t.Errorf("unknown ordering policy accepted")
+	}
+}
+
+// Tests that dropping a sender in the round robin ordering skips all its
+// remaining transactions while keeping the rotation of the others.
+func TestRoundRobinPop(t *testing.T) {
+	var (
+		keys  = make([]*ecdsa.PrivateKey, 2)
+		txs   = make(map[common.Address][]*types.Transaction)
+		order []common.Address
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addr := crypto.PubkeyToAddress(keys[i].PublicKey)
+		for nonce := uint64(0); nonce < 3; nonce++ {
+			tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, new(big.Int), params.TxGas, big.NewInt(1), nil), types.HomesteadSigner{}, keys[i])
+			tx.SetTime(time.Unix(int64(i), 0))
+			txs[addr] = append(txs[addr], tx)
+		}
+		order = append(order, addr)
+	}
+	block := &BuildContext{Header: &types.Header{}, Signer: types.HomesteadSigner{}}
+	set := roundRobinOrdering{}.Order(block, txs)
+
+	set.Pop() // drop the first sender entirely
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		tx := set.Peek()
+		if tx == nil {
+			t.Fatalf("missing transaction with nonce %d", nonce)
+		}
+		if from, _ := types.Sender(block.Signer, tx); from != order[1] || tx.Nonce() != nonce {
+			t.Fatalf("transaction mismatch: have %x/%d, want %x/%d", from, tx.Nonce(), order[1], nonce)
+		}
+		set.Shift()
+	}
+	if tx := set.Peek(); tx != nil {
+		t.Errorf("unexpected leftover transaction %x", tx.Hash())
+	}
+}
+
+// recordingOrdering is a custom ordering policy recording the blocks it orders.
+type recordingOrdering struct {
+	blocks []*BuildContext
+}
+
+func (o *recordingOrdering) Order(block *BuildContext, txs map[common.Address][]*types.Transaction) TransactionOrder {
+	o.blocks = append(o.blocks, block)
+	return priceOrdering{}.Order(block, txs)
+}
+
+// Tests that a custom ordering policy can be plugged into the miner, and that
+// it's given a view of the block being built.
+func TestCustomOrdering(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	policy := new(recordingOrdering)
+	w.setOrdering(policy)
+
+	// Wait for the pool to promote the pending transactions.
+	for pending, _ := b.txPool.Stats(); pending == 0; pending, _ = b.txPool.Stats() {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	block, _, err := w.getSealingBlock(b.chain.Genesis().Hash(), uint64(time.Now().Unix()), testUserAddress, common.Hash{}, nil, false)
+	if err != nil {
+		t.Fatalf("failed to build block: %v", err)
+	}
+	if len(block.Transactions()) == 0 {
+		t.Fatal("no transactions included")
+	}
+	if len(policy.blocks) == 0 {
+		t.Fatal("custom ordering policy not used")
+	}
+	view := policy.blocks[0]
+	if view.Header.Number.Uint64() != 1 || view.GasLeft != block.GasLimit() {
+		t.Errorf("view mismatch: have number %d gas %d, want 1 and %d", view.Header.Number, view.GasLeft, block.GasLimit())
+	}
+	// The state can't be modified through the view.
+	balance := view.State.GetBalance(testBankAddress)
+	want := new(big.Int).Set(balance)
+	balance.SetUint64(0)
+	if have := view.State.GetBalance(testBankAddress); have.Cmp(want) != 0 {
+		t.Errorf("balance modified through view: have %v, want %v", have, want)
+	}
+}
@@ -0,0 +1,197 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SimulateArgs are the parameters of a hypothetical block built on top of the
+// current chain head.
+type SimulateArgs struct {
+	Txs         types.Transactions // Transactions to include, in order
+	Coinbase    common.Address     // Fee recipient, the etherbase if unset
+	Timestamp   uint64             // Block timestamp, the current time if unset
+	Withdrawals types.Withdrawals  // Withdrawals to process after the transactions
+	GasCap      uint64             // Cap on the total gas of the transactions, 0 for none
+}
+
+// errSimulationGasCap is returned if the transactions of a simulated block
+// request more gas than allowed.
+var errSimulationGasCap = errors.New("simulation gas cap exceeded")
+
+// SimulatedBlock is the outcome of building a hypothetical block.
+type SimulatedBlock struct {
+	Block    *types.Block
+	Receipts types.Receipts
+	Fees     *big.Int      // Total fees paid to the coinbase
+	Excluded []common.Hash // Transactions which could not be included
+}
+
+// listOrder yields a list of transactions in their given order. Once one of
+// them fails, the subsequent transactions of the same sender are skipped.
+type listOrder struct {
+	txs     types.Transactions
+	signer  types.Signer
+	skipped map[common.Address]bool
+}
+
+func newListOrder(txs types.Transactions, signer types.Signer) *listOrder {
+	return &listOrder{txs: txs, signer: signer, skipped: make(map[common.Address]bool)}
+}
+
+func (o *listOrder) Peek() *types.Transaction {
+	for len(o.txs) > 0 {
+		if from, err := types.Sender(o.signer, o.txs[0]); err != nil || !o.skipped[from] {
+			return o.txs[0]
+		}
+		o.txs = o.txs[1:]
+	}
+	return nil
+}
+
+func (o *listOrder) Shift() {
+	o.txs = o.txs[1:]
+}
+
+func (o *listOrder) Pop() {
+	if from, err := types.Sender(o.signer, o.txs[0]); err == nil {
+		o.skipped[from] = true
+	}
+	o.txs = o.txs[1:]
+}
+
+// simulateBlock builds a block containing the given transactions on top of the
+// current head, without sealing or announcing it in any way. The block is built
+// on its own state, apart from the sealing work of the worker, and execution is
+// aborted once the context is cancelled.
+func (w *worker) simulateBlock(ctx context.Context, args *SimulateArgs) (*SimulatedBlock, error) {
+	if args.GasCap != 0 {
+		var gas uint64
+		for _, tx := range args.Txs {
+			if gas += tx.Gas(); gas > args.GasCap || gas < tx.Gas() {
+				return nil, fmt.Errorf("%w: transactions exceed gas cap %d", errSimulationGasCap, args.GasCap)
+			}
+		}
+	}
+	genParams := &generateParams{
+		timestamp:   args.Timestamp,
+		forceTime:   args.Timestamp != 0,
+		coinbase:    args.Coinbase,
+		withdrawals: args.Withdrawals,
+	}
+	if genParams.timestamp == 0 {
+		genParams.timestamp = uint64(time.Now().Unix())
+	}
+	if genParams.coinbase == (common.Address{}) {
+		genParams.coinbase = w.etherbase()
+	}
+	parent, header, err := w.prepareHeader(genParams)
+	if err != nil {
+		return nil, err
+	}
+	statedb, err := w.chain.StateAt(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		signer   = types.MakeSigner(w.chainConfig, header.Number, header.Time)
+		gasPool  = new(core.GasPool).AddGas(header.GasLimit)
+		evm      = vm.NewEVM(core.NewEVMBlockContext(header, w.chain, &genParams.coinbase), vm.TxContext{}, statedb, w.chainConfig, *w.chain.GetVMConfig())
+		order    = newListOrder(args.Txs, signer)
+		txs      types.Transactions
+		receipts types.Receipts
+	)
+	// Cancel the EVM once the context is done. Cancelling after the simulation
+	// has finished is harmless.
+	go func() {
+		<-ctx.Done()
+		evm.Cancel()
+	}()
+	for tx := order.Peek(); tx != nil && gasPool.Gas() >= params.TxGas; tx = order.Peek() {
+		if tx.Protected() && !w.chainConfig.IsEIP155(header.Number) {
+			order.Pop()
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		statedb.SetTxContext(tx.Hash(), len(txs))
+
+		var (
+			snap = statedb.Snapshot()
+			gas  = gasPool.Gas()
+		)
+		receipt, err := core.ApplyTransactionWithEVM(w.chainConfig, gasPool, statedb, header, tx, &header.GasUsed, evm)
+		if evm.Cancelled() {
+			return nil, ctx.Err()
+		}
+		switch {
+		case errors.Is(err, core.ErrNonceTooLow):
+			statedb.RevertToSnapshot(snap)
+			gasPool.SetGas(gas)
+			order.Shift()
+
+		case err != nil:
+			statedb.RevertToSnapshot(snap)
+			gasPool.SetGas(gas)
+			order.Pop()
+
+		default:
+			txs = append(txs, tx)
+			receipts = append(receipts, receipt)
+			order.Shift()
+		}
+	}
+	block, err := w.engine.FinalizeAndAssemble(w.chain, header, statedb, txs, nil, receipts, genParams.withdrawals)
+	if err != nil {
+		return nil, err
+	}
+	// The receipts were derived before the block hash was known, fix them up
+	for i, receipt := range receipts {
+		receipt.BlockHash, receipt.BlockNumber, receipt.TransactionIndex = block.Hash(), block.Number(), uint(i)
+		for _, log := range receipt.Logs {
+			log.BlockHash = block.Hash()
+		}
+	}
+	included := make(map[common.Hash]bool, len(txs))
+	for _, tx := range txs {
+		included[tx.Hash()] = true
+	}
+	var excluded []common.Hash
+	for _, tx := range args.Txs {
+		if !included[tx.Hash()] {
+			excluded = append(excluded, tx.Hash())
+		}
+	}
+	return &SimulatedBlock{
+		Block:    block,
+		Receipts: receipts,
+		Fees:     totalFees(block, receipts),
+		Excluded: excluded,
+	}, nil
+}
@@ -0,0 +1,102 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+func TestSimulateBlock(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, b := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		coinbase = common.Address{0xc0}
+		transfer = bundleTx(0, &testUserAddress, 2)
+		reverted = bundleTx(1, nil, 2)
+		gapped   = bundleTx(5, &testUserAddress, 2)
+		head     = b.chain.CurrentBlock()
+	)
+	sim, err := w.simulateBlock(context.Background(), &SimulateArgs{
+		Txs:       types.Transactions{transfer, reverted, gapped},
+		Coinbase:  coinbase,
+		Timestamp: head.Time + 12,
+	})
+	if err != nil {
+		t.Fatalf("failed to simulate block: %v", err)
+	}
+	block := sim.Block
+	if block.ParentHash() != head.Hash() || block.Time() != head.Time+12 || block.Coinbase() != coinbase {
+		t.Errorf("block header mismatch: parent %x, time %d, coinbase %v", block.ParentHash(), block.Time(), block.Coinbase())
+	}
+	// The reverting deployment is included, the transaction with a nonce gap isn't
+	if txs := block.Transactions(); len(txs) != 2 || txs[0].Hash() != transfer.Hash() || txs[1].Hash() != reverted.Hash() {
+		t.Fatalf("included transactions mismatch: have %d", len(txs))
+	}
+	if len(sim.Excluded) != 1 || sim.Excluded[0] != gapped.Hash() {
+		t.Errorf("excluded transactions mismatch: have %v", sim.Excluded)
+	}
+	if len(sim.Receipts) != 2 || sim.Receipts[1].Status != types.ReceiptStatusFailed || sim.Receipts[1].BlockHash != block.Hash() {
+		t.Errorf("receipts mismatch")
+	}
+	if sim.Fees.Cmp(new(big.Int)) <= 0 {
+		t.Errorf("no fees collected: %v", sim.Fees)
+	}
+	// Ensure nothing was written to the chain
+	if current := b.chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Errorf("chain head moved: have %x, want %x", current.Hash(), head.Hash())
+	}
+	if b.chain.GetBlockByHash(block.Hash()) != nil {
+		t.Errorf("simulated block stored in the chain")
+	}
+}
+
+func TestSimulateBlockLimits(t *testing.T) {
+	engine := ethash.NewFaker()
+	defer engine.Close()
+
+	w, _ := newTestWorker(t, ethashChainConfig, engine, rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	txs := types.Transactions{bundleTx(0, &testUserAddress, 2), bundleTx(1, &testUserAddress, 2)}
+
+	// Transactions requesting more gas than the cap are rejected outright
+	_, err := w.simulateBlock(context.Background(), &SimulateArgs{Txs: txs, GasCap: txs[0].Gas()})
+	if !errors.Is(err, errSimulationGasCap) {
+		t.Errorf("gas cap error mismatch: have %v, want %v", err, errSimulationGasCap)
+	}
+	if _, err := w.simulateBlock(context.Background(), &SimulateArgs{Txs: txs, GasCap: txs[0].Gas() + txs[1].Gas()}); err != nil {
+		t.Errorf("failed to simulate block within gas cap: %v", err)
+	}
+	// Simulations are aborted once their context is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := w.simulateBlock(ctx, &SimulateArgs{Txs: txs}); !errors.Is(err, context.Canceled) {
+		t.Errorf("cancellation error mismatch: have %v, want %v", err, context.Canceled)
+	}
+}
@@ -167,6 +167,7 @@ type worker struct {
 	engine      consensus.Engine
 	eth         Backend
 	chain       *core.BlockChain
+	ordering    OrderingPolicy // Ordering of pending transactions in built blocks
 
 	// Feeds
 	pendingLogsFeed event.Feed
@@ -191,8 +192,9 @@ type worker struct {
 	wg sync.WaitGroup
 
 	current *environment // An environment for current running cycle.
+	bundles *bundlePool  // Bundles submitted for upcoming blocks
 
-	mu       sync.RWMutex // The lock used to protect the coinbase and extra fields
+	mu       sync.RWMutex // The lock used to protect the coinbase, extra and ordering fields
 	coinbase common.Address
 	extra    []byte
 
@@ -242,6 +244,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		coinbase:           config.Etherbase,
 		extra:              config.ExtraData,
 		pendingTasks:       make(map[common.Hash]*task),
+		bundles:            newBundlePool(),
 		txsCh:              make(chan core.NewTxsEvent, txChanSize),
 		chainHeadCh:        make(chan core.ChainHeadEvent, chainHeadChanSize),
 		newWorkCh:          make(chan *newWorkReq),
@@ -277,6 +280,14 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 	}
 	worker.newpayloadTimeout = newpayloadTimeout
 
+	// Fall back to the default transaction ordering if the configured one is unknown.
+	ordering, err := NewOrderingPolicy(worker.config.Ordering)
+	if err != nil {
+		log.Warn("Sanitizing miner ordering policy to default", "provided", worker.config.Ordering, "updated", OrderingPrice, "err", err)
+		ordering = priceOrdering{}
+	}
+	worker.ordering = ordering
+
 	worker.wg.Add(4)
 	go worker.mainLoop()
 	go worker.newWorkLoop(recommit)
@@ -317,6 +328,20 @@ func (w *worker) setExtra(extra []byte) {
 	w.extra = extra
 }
 
+// setOrdering sets the ordering of pending transactions in built blocks.
+func (w *worker) setOrdering(policy OrderingPolicy) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.ordering = policy
+}
+
+// orderingPolicy retrieves the ordering of pending transactions in built blocks.
+func (w *worker) orderingPolicy() OrderingPolicy {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.ordering
+}
+
 // setRecommitInterval updates the interval for miner sealing work recommitting.
 func (w *worker) setRecommitInterval(interval time.Duration) {
 	select {
@@ -538,7 +563,7 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
+				txset := w.orderingPolicy().Order(w.current.buildContext(), txs)
 				tcount := w.current.tcount
 				w.commitTransactions(w.current, txset, nil)
 
@@ -744,7 +769,7 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+func (w *worker) commitTransactions(env *environment, txs TransactionOrder, interrupt *atomic.Int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -780,6 +805,15 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			txs.Pop()
 			continue
 		}
+		// Re-check the preconditions of conditional transactions against the
+		// block being built, as they might have been invalidated since pooling.
+		if cond := tx.Conditional(); cond != nil {
+			if err := cond.Check(env.header.Number.Uint64(), env.header.Time, time.Now(), env.state); err != nil {
+				log.Trace("Skipping conditional transaction", "hash", tx.Hash(), "err", err)
+				txs.Pop()
+				continue
+			}
+		}
 		// Start executing the transaction
 		env.state.SetTxContext(tx.Hash(), env.tcount)
 
@@ -836,6 +870,24 @@ type generateParams struct {
 // either based on the last chain head or specified parent. In this function
 // the pending transactions are not filled yet, only the empty task returned.
 func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
+	parent, header, err := w.prepareHeader(genParams)
+	if err != nil {
+		return nil, err
+	}
+	// Could potentially happen if starting to mine in an odd state.
+	// Note genParams.coinbase can be different with header.Coinbase
+	// since clique algorithm can modify the coinbase field in header.
+	env, err := w.makeEnv(parent, header, genParams.coinbase)
+	if err != nil {
+		log.Error("Failed to create sealing context", "err", err)
+		return nil, err
+	}
+	return env, nil
+}
+
+// prepareHeader constructs the header of a new block on top of the requested
+// parent, returning both.
+func (w *worker) prepareHeader(genParams *generateParams) (*types.Header, *types.Header, error) {
 	w.mu.RLock()
 	defer w.mu.RUnlock()
 
@@ -844,7 +896,7 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	if genParams.parentHash != (common.Hash{}) {
 		block := w.chain.GetBlockByHash(genParams.parentHash)
 		if block == nil {
-			return nil, fmt.Errorf("missing parent")
+			return nil, nil, fmt.Errorf("missing parent")
 		}
 		parent = block.Header()
 	}
@@ -853,7 +905,7 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	timestamp := genParams.timestamp
 	if parent.Time >= timestamp {
 		if genParams.forceTime {
-			return nil, fmt.Errorf("invalid timestamp, parent %d given %d", parent.Time, timestamp)
+			return nil, nil, fmt.Errorf("invalid timestamp, parent %d given %d", parent.Time, timestamp)
 		}
 		timestamp = parent.Time + 1
 	}
@@ -884,23 +936,21 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 	// Run the consensus preparation with the default or customized consensus engine.
 	if err := w.engine.Prepare(w.chain, header); err != nil {
 		log.Error("Failed to prepare header for sealing", "err", err)
-		return nil, err
-	}
-	// Could potentially happen if starting to mine in an odd state.
-	// Note genParams.coinbase can be different with header.Coinbase
-	// since clique algorithm can modify the coinbase field in header.
-	env, err := w.makeEnv(parent, header, genParams.coinbase)
-	if err != nil {
-		log.Error("Failed to create sealing context", "err", err)
-		return nil, err
+		return nil, nil, err
 	}
-	return env, nil
+	return parent, header, nil
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
 // into the given sealing block. The transaction selection and ordering strategy can
 // be customized with the plugin in the future.
 func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) error {
+	// Include the bundles targeting this block ahead of the pool transactions
+	if bundles := w.bundles.pending(env.header.Number.Uint64()); len(bundles) > 0 {
+		if err := w.commitBundles(env, bundles, interrupt); err != nil {
+			return err
+		}
+	}
 	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
 	pending := w.eth.TxPool().Pending(true)
@@ -913,13 +963,13 @@ func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) err
 		}
 	}
 	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
+		txs := w.orderingPolicy().Order(env.buildContext(), localTxs)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
 	}
 	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
+		txs := w.orderingPolicy().Order(env.buildContext(), remoteTxs)
 		if err := w.commitTransactions(env, txs, interrupt); err != nil {
 			return err
 		}
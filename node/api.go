@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -116,6 +117,86 @@ func (api *adminAPI) RemoveTrustedPeer(url string) (bool, error) {
 	return true, nil
 }
 
+// BanPeer bans a remote node for the given number of seconds, or the configured
+// ban duration if omitted. The node is disconnected and removed from the static
+// peer set.
+func (api *adminAPI) BanPeer(url string, seconds *uint64) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	node, err := enode.Parse(enode.ValidSchemes, url)
+	if err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	var duration time.Duration
+	if seconds != nil {
+		duration = time.Duration(*seconds) * time.Second
+	}
+	server.BanPeer(node, duration, "banned by admin")
+	return true, nil
+}
+
+// UnbanPeer lifts the ban of a remote node, given by enode URL or node ID, and
+// resets its reputation. It returns whether the node was banned.
+func (api *adminAPI) UnbanPeer(node string) (bool, error) {
+	// Make sure the server is running, fail otherwise
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	id, err := enode.ParseID(node)
+	if err != nil {
+		n, perr := enode.Parse(enode.ValidSchemes, node)
+		if perr != nil {
+			return false, fmt.Errorf("invalid enode: %v", perr)
+		}
+		id = n.ID()
+	}
+	return server.UnbanPeer(id), nil
+}
+
+// Bans retrieves the remote nodes currently banned.
+func (api *adminAPI) Bans() ([]*p2p.BanInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bans(), nil
+}
+
+// SetMaxBandwidth updates the total bandwidth limits of all protocols, in bytes
+// per second. Zero means unlimited.
+func (api *adminAPI) SetMaxBandwidth(egress, ingress uint64) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	server.SetMaxBandwidth(egress, ingress)
+	return true, nil
+}
+
+// SetBandwidthLimit updates the bandwidth limits of a protocol, in bytes per
+// second, applying them to the connected peers as well.
+func (api *adminAPI) SetBandwidthLimit(protocol string, limit p2p.BandwidthLimit) (bool, error) {
+	server := api.node.Server()
+	if server == nil {
+		return false, ErrNodeStopped
+	}
+	server.SetBandwidthLimit(protocol, limit)
+	return true, nil
+}
+
+// Bandwidth retrieves the bandwidth limits in effect.
+func (api *adminAPI) Bandwidth() (*p2p.BandwidthInfo, error) {
+	server := api.node.Server()
+	if server == nil {
+		return nil, ErrNodeStopped
+	}
+	return server.Bandwidth(), nil
+}
+
 // PeerEvents creates an RPC subscription which receives peer events from the
 // node's p2p.Server
 func (api *adminAPI) PeerEvents(ctx context.Context) (*rpc.Subscription, error) {
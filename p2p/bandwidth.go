@@ -0,0 +1,270 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+	"golang.org/x/time/rate"
+)
+
+// bandwidthMeterName is the prefix of the per-protocol traffic shaping metrics.
+const bandwidthMeterName = "p2p/bandwidth"
+
+// Traffic directions, used to index the limiters of a trafficLimiter.
+const (
+	dirEgress = iota
+	dirIngress
+)
+
+var directionNames = [2]string{dirEgress: "egress", dirIngress: "ingress"}
+
+// BandwidthLimit is the bandwidth available to a protocol, in bytes per second.
+// Zero means unlimited.
+type BandwidthLimit struct {
+	Egress      uint64 `json:"egress"`      // Egress over all peers
+	Ingress     uint64 `json:"ingress"`     // Ingress over all peers
+	PeerEgress  uint64 `json:"peerEgress"`  // Egress to a single peer
+	PeerIngress uint64 `json:"peerIngress"` // Ingress from a single peer
+}
+
+// BandwidthInfo describes the bandwidth limits in effect.
+type BandwidthInfo struct {
+	MaxEgress  uint64                    `json:"maxEgress"`
+	MaxIngress uint64                    `json:"maxIngress"`
+	Protocols  map[string]BandwidthLimit `json:"protocols"`
+}
+
+// trafficLimiter limits the egress and ingress traffic of a protocol.
+type trafficLimiter [2]*rate.Limiter
+
+func newTrafficLimiter(egressLimit, ingressLimit uint64) *trafficLimiter {
+	l := &trafficLimiter{rate.NewLimiter(rate.Inf, 0), rate.NewLimiter(rate.Inf, 0)}
+	l.set(egressLimit, ingressLimit)
+	return l
+}
+
+// set updates the limits, allowing bursts of one second worth of traffic.
+func (l *trafficLimiter) set(egressLimit, ingressLimit uint64) {
+	for dir, limit := range [2]uint64{egressLimit, ingressLimit} {
+		if limit == 0 {
+			l[dir].SetLimit(rate.Inf)
+			continue
+		}
+		l[dir].SetBurst(int(limit))
+		l[dir].SetLimit(rate.Limit(limit))
+	}
+}
+
+// waitLimiter blocks until the limiter allows n bytes, or until closed is
+// closed. It returns the time spent waiting.
+func waitLimiter(lim *rate.Limiter, n int, closed <-chan struct{}) (time.Duration, error) {
+	var waited time.Duration
+	for n > 0 {
+		chunk := n
+		if burst := lim.Burst(); lim.Limit() != rate.Inf && chunk > burst {
+			chunk = burst
+		}
+		r := lim.ReserveN(time.Now(), chunk)
+		if !r.OK() {
+			continue // limit changed concurrently, retry with the new burst
+		}
+		if delay := r.Delay(); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-timer.C:
+			case <-closed:
+				timer.Stop()
+				r.Cancel()
+				return waited, ErrShuttingDown
+			}
+			waited += delay
+		}
+		n -= chunk
+	}
+	return waited, nil
+}
+
+// reserveLimiter consumes n bytes from the limiter without waiting, delaying
+// the traffic of others.
+func reserveLimiter(lim *rate.Limiter, n int) {
+	if lim.Limit() == rate.Inf {
+		return
+	}
+	for n > 0 {
+		chunk := n
+		if burst := lim.Burst(); chunk > burst {
+			chunk = burst
+		}
+		if chunk <= 0 {
+			return
+		}
+		lim.ReserveN(time.Now(), chunk)
+		n -= chunk
+	}
+}
+
+// protoLimiter shapes the traffic of a protocol running on a single peer.
+type protoLimiter struct {
+	total *trafficLimiter // all protocols, all peers
+	proto *trafficLimiter // this protocol, all peers
+	peer  *trafficLimiter // this protocol, this peer
+	bulk  bool            // whether the protocol is throttled first
+
+	delayTimers   [2]metrics.Timer
+	trafficMeters [2]metrics.Meter
+}
+
+// wait blocks until the limits allow transferring n bytes in the given
+// direction, or until closed is closed.
+func (l *protoLimiter) wait(dir int, n int, closed <-chan struct{}) error {
+	if l.bulk {
+		// Bulk traffic yields to the rest when the total limit is reached.
+		if _, err := waitLimiter(l.total[dir], n, closed); err != nil {
+			return err
+		}
+	} else {
+		reserveLimiter(l.total[dir], n)
+	}
+	var delay time.Duration
+	for _, lim := range []*rate.Limiter{l.proto[dir], l.peer[dir]} {
+		waited, err := waitLimiter(lim, n, closed)
+		if err != nil {
+			return err
+		}
+		delay += waited
+	}
+	if metrics.Enabled {
+		l.trafficMeters[dir].Mark(int64(n))
+		if delay > 0 {
+			l.delayTimers[dir].Update(delay)
+		}
+	}
+	return nil
+}
+
+// bandwidth tracks the bandwidth limits of the server and the limiters of the
+// running peers, so limits can be adjusted at runtime.
+type bandwidth struct {
+	lock   sync.Mutex
+	total  *trafficLimiter
+	limits map[string]BandwidthLimit
+	protos map[string]*trafficLimiter
+	peers  map[*Peer]map[string]*trafficLimiter
+}
+
+func newBandwidth(maxEgress, maxIngress uint64, limits map[string]BandwidthLimit) *bandwidth {
+	b := &bandwidth{
+		total:  newTrafficLimiter(maxEgress, maxIngress),
+		limits: make(map[string]BandwidthLimit),
+		protos: make(map[string]*trafficLimiter),
+		peers:  make(map[*Peer]map[string]*trafficLimiter),
+	}
+	for name, limit := range limits {
+		b.limits[name] = limit
+		b.protos[name] = newTrafficLimiter(limit.Egress, limit.Ingress)
+	}
+	return b
+}
+
+// attach installs the limiters of all protocols running on the peer.
+func (b *bandwidth) attach(p *Peer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	peers := make(map[string]*trafficLimiter)
+	for _, rw := range p.running {
+		proto, ok := b.protos[rw.Name]
+		if !ok {
+			proto = newTrafficLimiter(0, 0)
+			b.protos[rw.Name] = proto
+		}
+		limit := b.limits[rw.Name]
+		peer := newTrafficLimiter(limit.PeerEgress, limit.PeerIngress)
+		peers[rw.Name] = peer
+
+		rw.limiter = &protoLimiter{total: b.total, proto: proto, peer: peer, bulk: rw.Bulk}
+		if metrics.Enabled {
+			for dir, name := range directionNames {
+				prefix := fmt.Sprintf("%s/%s/%s", bandwidthMeterName, rw.Name, name)
+				rw.limiter.trafficMeters[dir] = metrics.GetOrRegisterMeter(prefix, nil)
+				rw.limiter.delayTimers[dir] = metrics.GetOrRegisterTimer(prefix+"/delay", nil)
+			}
+		}
+	}
+	b.peers[p] = peers
+}
+
+// detach forgets the limiters of a disconnected peer.
+func (b *bandwidth) detach(p *Peer) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.peers, p)
+}
+
+// setMax updates the total bandwidth limits.
+func (b *bandwidth) setMax(maxEgress, maxIngress uint64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.total.set(maxEgress, maxIngress)
+}
+
+// setLimit updates the bandwidth limits of a protocol, including the ones of
+// the peers running it.
+func (b *bandwidth) setLimit(name string, limit BandwidthLimit) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.limits[name] = limit
+	if proto, ok := b.protos[name]; ok {
+		proto.set(limit.Egress, limit.Ingress)
+	} else {
+		b.protos[name] = newTrafficLimiter(limit.Egress, limit.Ingress)
+	}
+	for _, peers := range b.peers {
+		if peer, ok := peers[name]; ok {
+			peer.set(limit.PeerEgress, limit.PeerIngress)
+		}
+	}
+}
+
+// info returns the limits in effect.
+func (b *bandwidth) info() *BandwidthInfo {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	info := &BandwidthInfo{Protocols: make(map[string]BandwidthLimit, len(b.limits))}
+	for dir, lim := range b.total {
+		if lim.Limit() == rate.Inf {
+			continue
+		}
+		if dir == dirEgress {
+			info.MaxEgress = uint64(lim.Limit())
+		} else {
+			info.MaxIngress = uint64(lim.Limit())
+		}
+	}
+	for name, limit := range b.limits {
+		info.Protocols[name] = limit
+	}
+	return info
+}
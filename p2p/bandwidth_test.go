@@ -0,0 +1,109 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"golang.org/x/time/rate"
+)
+
+func TestBandwidthWait(t *testing.T) {
+	lim := newTrafficLimiter(10000, 0)
+
+	// Transfers larger than the burst are split up and delayed.
+	start := time.Now()
+	if _, err := waitLimiter(lim[dirEgress], 15000, nil); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Fatalf("transfer not throttled, took %v", elapsed)
+	}
+	// Unlimited directions never wait.
+	start = time.Now()
+	if _, err := waitLimiter(lim[dirIngress], 1<<30, nil); err != nil {
+		t.Fatalf("wait failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("unlimited transfer throttled, took %v", elapsed)
+	}
+	// Waiting is aborted on shutdown.
+	closed := make(chan struct{})
+	close(closed)
+	if _, err := waitLimiter(lim[dirEgress], 10000, closed); err != ErrShuttingDown {
+		t.Fatalf("wrong error on shutdown: %v", err)
+	}
+}
+
+func TestBandwidthBulkYields(t *testing.T) {
+	var (
+		total = newTrafficLimiter(1000, 0)
+		other = &protoLimiter{total: total, proto: newTrafficLimiter(0, 0), peer: newTrafficLimiter(0, 0)}
+		bulk  = &protoLimiter{total: total, proto: newTrafficLimiter(0, 0), peer: newTrafficLimiter(0, 0), bulk: true}
+	)
+	// Regular traffic exceeding the total limit goes through without waiting.
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := other.wait(dirEgress, 1000, nil); err != nil {
+			t.Fatalf("wait failed: %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("regular traffic throttled, took %v", elapsed)
+	}
+	// Bulk traffic has to wait for the bandwidth used by the regular traffic.
+	closed := make(chan struct{})
+	time.AfterFunc(100*time.Millisecond, func() { close(closed) })
+	if err := bulk.wait(dirEgress, 100, closed); err != ErrShuttingDown {
+		t.Fatalf("bulk traffic not throttled: %v", err)
+	}
+}
+
+func TestBandwidthSetLimit(t *testing.T) {
+	b := newBandwidth(0, 0, map[string]BandwidthLimit{
+		"a": {Egress: 1000, PeerIngress: 500},
+	})
+	p := NewPeer(enode.ID{1}, "test", []Cap{{"a", 1}, {"b", 1}})
+	for _, rw := range p.running {
+		rw.Bulk = rw.Name == "b"
+	}
+	b.attach(p)
+
+	a, bproto := p.running["a"].limiter, p.running["b"].limiter
+	if a.proto[dirEgress].Limit() != 1000 || a.peer[dirIngress].Limit() != 500 || a.peer[dirEgress].Limit() != rate.Inf {
+		t.Fatal("configured limits not applied")
+	}
+	if !bproto.bulk || bproto.proto[dirEgress].Limit() != rate.Inf {
+		t.Fatal("wrong limiter for unconfigured protocol")
+	}
+	// Limit changes apply to the running peers.
+	b.setLimit("a", BandwidthLimit{PeerEgress: 200})
+	if a.proto[dirEgress].Limit() != rate.Inf || a.peer[dirEgress].Limit() != 200 || a.peer[dirIngress].Limit() != rate.Inf {
+		t.Fatal("updated limits not applied")
+	}
+	b.setMax(3000, 0)
+	info := b.info()
+	if info.MaxEgress != 3000 || info.MaxIngress != 0 || info.Protocols["a"].PeerEgress != 200 {
+		t.Fatalf("wrong bandwidth info: %+v", info)
+	}
+	b.detach(p)
+	if len(b.peers) != 0 {
+		t.Fatal("peer not detached")
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/capture"
+	"golang.org/x/exp/slices"
+)
+
+// captureTransport wraps the transport of a peer, recording all messages read
+// and written after the protocol handshake.
+type captureTransport struct {
+	transport
+	w *capture.Writer
+}
+
+func (t *captureTransport) ReadMsg() (Msg, error) {
+	msg, err := t.transport.ReadMsg()
+	if err != nil {
+		return msg, err
+	}
+	data, err := io.ReadAll(msg.Payload)
+	if err != nil {
+		return msg, err
+	}
+	msg.Payload = bytes.NewReader(data)
+	t.w.WriteFrame(&capture.Frame{Time: msg.ReceivedAt, Code: msg.Code, Payload: data})
+	return msg, nil
+}
+
+func (t *captureTransport) WriteMsg(msg Msg) error {
+	data := make([]byte, msg.Size)
+	if _, err := io.ReadFull(msg.Payload, data); err != nil {
+		return err
+	}
+	msg.Payload = bytes.NewReader(data)
+	if err := t.transport.WriteMsg(msg); err != nil {
+		return err
+	}
+	t.w.WriteFrame(&capture.Frame{Time: time.Now(), Egress: true, Code: msg.Code, Payload: data})
+	return nil
+}
+
+func (t *captureTransport) close(err error) {
+	t.transport.close(err)
+	t.w.Close()
+}
+
+// setupCapture starts recording the messages of a peer into a new capture file
+// in the configured capture directory.
+func (srv *Server) setupCapture(p *Peer) {
+	header := capture.Header{
+		Remote:  p.ID(),
+		Name:    p.Fullname(),
+		Inbound: p.Inbound(),
+	}
+	for _, rw := range p.running {
+		header.Protocols = append(header.Protocols, capture.Protocol{
+			Name:    rw.Name,
+			Version: rw.Version,
+			Offset:  rw.offset,
+			Length:  rw.Length,
+		})
+	}
+	slices.SortFunc(header.Protocols, func(a, b capture.Protocol) bool {
+		return a.Offset < b.Offset
+	})
+	if err := os.MkdirAll(srv.CaptureDir, 0755); err != nil {
+		p.log.Warn("Failed to create capture directory", "err", err)
+		return
+	}
+	id := p.ID()
+	name := fmt.Sprintf("%s-%x.rlpxcap", time.Now().UTC().Format("20060102T150405"), id[:8])
+	w, err := capture.Create(filepath.Join(srv.CaptureDir, name), header)
+	if err != nil {
+		p.log.Warn("Failed to create capture file", "err", err)
+		return
+	}
+	p.rw.transport = &captureTransport{transport: p.rw.transport, w: w}
+}
+
+// ReplayCapture writes the messages of the given protocol received from the
+// captured peer to rw, as if the peer sent them again. Message codes are made
+// relative to the protocol, so rw can be the remote end of a protocol handler
+// under test. It returns after all messages were written.
+func ReplayCapture(r *capture.Reader, name string, version uint, rw MsgWriter) error {
+	if r.Header.Protocol(name, version) == nil {
+		return fmt.Errorf("protocol %s/%d not in capture", name, version)
+	}
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if frame.Egress {
+			continue
+		}
+		proto, code := r.Header.Resolve(frame.Code)
+		if proto == nil || proto.Name != name || proto.Version != version {
+			continue
+		}
+		msg := Msg{
+			Code:       code,
+			Size:       uint32(len(frame.Payload)),
+			Payload:    bytes.NewReader(frame.Payload),
+			ReceivedAt: frame.Time,
+		}
+		if err := rw.WriteMsg(msg); err != nil {
+			return err
+		}
+	}
+}
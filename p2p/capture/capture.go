@@ -0,0 +1,237 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package capture implements a compact file format for recording the decrypted
+// devp2p message stream of a peer connection.
+//
+// A capture is a sequence of RLP items: a header describing the connection and
+// the negotiated protocols, followed by one item per message.
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Version is the version of the capture format.
+const Version = 1
+
+var errClosed = errors.New("capture closed")
+
+// Protocol is a subprotocol negotiated on the captured connection.
+type Protocol struct {
+	Name    string
+	Version uint
+	Offset  uint64 // first message code of the protocol on the wire
+	Length  uint64 // number of message codes used by the protocol
+}
+
+// String returns the protocol as name/version.
+func (p *Protocol) String() string {
+	return fmt.Sprintf("%s/%d", p.Name, p.Version)
+}
+
+// Header describes a captured connection.
+type Header struct {
+	Version   uint
+	Remote    enode.ID // node ID of the remote peer
+	Name      string   // client name advertised by the remote peer
+	Inbound   bool     // whether the connection was initiated by the remote peer
+	Start     uint64   // capture start time in unix nanoseconds
+	Protocols []Protocol
+}
+
+// Resolve maps a message code as seen on the wire to the protocol it belongs to
+// and the code relative to that protocol. The protocol is nil for the messages
+// of devp2p itself and for codes not belonging to any negotiated protocol.
+func (h *Header) Resolve(code uint64) (*Protocol, uint64) {
+	for i := range h.Protocols {
+		proto := &h.Protocols[i]
+		if code >= proto.Offset && code < proto.Offset+proto.Length {
+			return proto, code - proto.Offset
+		}
+	}
+	return nil, code
+}
+
+// Protocol retrieves the negotiated protocol with the given name and version.
+func (h *Header) Protocol(name string, version uint) *Protocol {
+	for i := range h.Protocols {
+		if h.Protocols[i].Name == name && h.Protocols[i].Version == version {
+			return &h.Protocols[i]
+		}
+	}
+	return nil
+}
+
+// Frame is a captured message.
+type Frame struct {
+	Time    time.Time // time the message was read or written
+	Egress  bool      // whether the message was sent to the remote peer
+	Code    uint64    // message code as seen on the wire, see Header.Resolve
+	Payload []byte    // decrypted and decompressed message content
+}
+
+// storedFrame is the RLP encoding of a Frame.
+type storedFrame struct {
+	Elapsed uint64 // nanoseconds since the start of the capture
+	Egress  bool
+	Code    uint64
+	Payload []byte
+}
+
+// Writer records the frames of a connection. It is safe for concurrent use.
+type Writer struct {
+	lock  sync.Mutex
+	w     io.WriteCloser
+	start time.Time
+}
+
+// Create creates a capture file at the given path.
+func Create(path string, header Header) (*Writer, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	w, err := NewWriter(f, header)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// NewWriter writes the given header to w and returns a writer appending frames
+// to it. The header version and start time are filled in if unset.
+func NewWriter(w io.WriteCloser, header Header) (*Writer, error) {
+	if header.Version == 0 {
+		header.Version = Version
+	}
+	start := time.Now()
+	if header.Start == 0 {
+		header.Start = uint64(start.UnixNano())
+	} else {
+		start = time.Unix(0, int64(header.Start))
+	}
+	if err := rlp.Encode(w, &header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w, start: start}, nil
+}
+
+// WriteFrame appends a frame to the capture.
+func (w *Writer) WriteFrame(frame *Frame) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.w == nil {
+		return errClosed
+	}
+	var elapsed uint64
+	if d := frame.Time.Sub(w.start); d > 0 {
+		elapsed = uint64(d)
+	}
+	return rlp.Encode(w.w, &storedFrame{
+		Elapsed: elapsed,
+		Egress:  frame.Egress,
+		Code:    frame.Code,
+		Payload: frame.Payload,
+	})
+}
+
+// Close closes the underlying writer. Frames written afterwards are rejected.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.w == nil {
+		return errClosed
+	}
+	err := w.w.Close()
+	w.w = nil
+	return err
+}
+
+// Reader reads the frames of a capture.
+type Reader struct {
+	Header Header
+
+	stream *rlp.Stream
+	start  time.Time
+	closer io.Closer
+}
+
+// Open opens the capture file at the given path.
+func Open(path string) (*Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.closer = f
+	return r, nil
+}
+
+// NewReader reads the header of a capture from r.
+func NewReader(r io.Reader) (*Reader, error) {
+	stream := rlp.NewStream(r, 0)
+
+	var header Header
+	if err := stream.Decode(&header); err != nil {
+		return nil, fmt.Errorf("invalid capture header: %v", err)
+	}
+	if header.Version != Version {
+		return nil, fmt.Errorf("unsupported capture version %d", header.Version)
+	}
+	return &Reader{
+		Header: header,
+		stream: stream,
+		start:  time.Unix(0, int64(header.Start)),
+	}, nil
+}
+
+// Next reads the next frame. It returns io.EOF at the end of the capture.
+func (r *Reader) Next() (*Frame, error) {
+	var stored storedFrame
+	if err := r.stream.Decode(&stored); err != nil {
+		return nil, err
+	}
+	return &Frame{
+		Time:    r.start.Add(time.Duration(stored.Elapsed)),
+		Egress:  stored.Egress,
+		Code:    stored.Code,
+		Payload: stored.Payload,
+	}, nil
+}
+
+// Close closes the capture file if the reader was created by Open.
+func (r *Reader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
@@ -0,0 +1,116 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package capture
+
+import (
+	"io"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestCaptureRoundtrip(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "peer.rlpxcap")
+		start  = time.Unix(1700000000, 0)
+		header = Header{
+			Remote: enode.ID{0x01},
+			Name:   "test/v1.0.0",
+			Start:  uint64(start.UnixNano()),
+			Protocols: []Protocol{
+				{Name: "eth", Version: 68, Offset: 16, Length: 17},
+				{Name: "snap", Version: 1, Offset: 33, Length: 8},
+			},
+		}
+		frames = []*Frame{
+			{Time: start.Add(time.Millisecond), Code: 0x02, Payload: []byte{0xc0}},
+			{Time: start.Add(2 * time.Millisecond), Egress: true, Code: 16, Payload: []byte{0x01, 0x02}},
+			{Time: start.Add(3 * time.Millisecond), Code: 34, Payload: []byte{}},
+		}
+	)
+	w, err := Create(path, header)
+	if err != nil {
+		t.Fatalf("failed to create capture: %v", err)
+	}
+	for _, frame := range frames {
+		if err := w.WriteFrame(frame); err != nil {
+			t.Fatalf("failed to write frame: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close capture: %v", err)
+	}
+	if err := w.WriteFrame(frames[0]); err != errClosed {
+		t.Fatalf("wrong error writing to closed capture: %v", err)
+	}
+
+	r, err := Open(path)
+	if err != nil {
+		t.Fatalf("failed to open capture: %v", err)
+	}
+	defer r.Close()
+
+	header.Version = Version
+	if !reflect.DeepEqual(r.Header, header) {
+		t.Fatalf("header mismatch: have %+v, want %+v", r.Header, header)
+	}
+	for i, want := range frames {
+		have, err := r.Next()
+		if err != nil {
+			t.Fatalf("frame %d: read error: %v", i, err)
+		}
+		if !have.Time.Equal(want.Time) || have.Egress != want.Egress || have.Code != want.Code || string(have.Payload) != string(want.Payload) {
+			t.Fatalf("frame %d mismatch: have %+v, want %+v", i, have, want)
+		}
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func TestHeaderResolve(t *testing.T) {
+	header := Header{
+		Protocols: []Protocol{
+			{Name: "eth", Version: 68, Offset: 16, Length: 17},
+			{Name: "snap", Version: 1, Offset: 33, Length: 8},
+		},
+	}
+	tests := []struct {
+		code  uint64
+		proto string
+		rel   uint64
+	}{
+		{code: 0x02, proto: "", rel: 0x02},
+		{code: 16, proto: "eth/68", rel: 0},
+		{code: 32, proto: "eth/68", rel: 16},
+		{code: 33, proto: "snap/1", rel: 0},
+		{code: 41, proto: "", rel: 41},
+	}
+	for _, tt := range tests {
+		proto, rel := header.Resolve(tt.code)
+		var name string
+		if proto != nil {
+			name = proto.String()
+		}
+		if name != tt.proto || rel != tt.rel {
+			t.Errorf("code %d: have %q/%d, want %q/%d", tt.code, name, rel, tt.proto, tt.rel)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/p2p/capture"
+)
+
+// pipeTransport is a transport over a message pipe, skipping the handshakes.
+type pipeTransport struct {
+	MsgReadWriter
+}
+
+func (pipeTransport) doEncHandshake(prv *ecdsa.PrivateKey) (*ecdsa.PublicKey, error) {
+	return nil, nil
+}
+
+func (pipeTransport) doProtoHandshake(our *protoHandshake) (*protoHandshake, error) {
+	return nil, nil
+}
+
+func (pipeTransport) close(err error) {}
+
+type nopWriteCloser struct{ *bytes.Buffer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+func TestCaptureReplay(t *testing.T) {
+	var (
+		buf    = new(bytes.Buffer)
+		header = capture.Header{
+			Protocols: []capture.Protocol{
+				{Name: "test", Version: 1, Offset: 16, Length: 4},
+				{Name: "other", Version: 1, Offset: 20, Length: 4},
+			},
+		}
+	)
+	w, err := capture.NewWriter(nopWriteCloser{buf}, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	local, remote := MsgPipe()
+	ct := &captureTransport{transport: pipeTransport{local}, w: w}
+
+	// Exchange a few messages across the captured transport.
+	go func() {
+		Send(remote, pingMsg, []uint{})
+		Send(remote, 16+1, []uint{1})
+		Send(remote, 20+2, []uint{2})
+		ExpectMsg(remote, 16+3, []uint{3})
+		Send(remote, 16+2, []uint{4})
+	}()
+	for i := 0; i < 3; i++ {
+		if _, err := ct.ReadMsg(); err != nil {
+			t.Fatalf("read %d failed: %v", i, err)
+		}
+	}
+	if err := Send(ct, 16+3, []uint{3}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	msg, err := ct.ReadMsg()
+	if err != nil {
+		t.Fatalf("read failed: %v", err)
+	}
+	// The captured transport must still deliver the payload.
+	var content []uint
+	if err := msg.Decode(&content); err != nil || len(content) != 1 || content[0] != 4 {
+		t.Fatalf("wrong message content %v: %v", content, err)
+	}
+	ct.close(nil)
+
+	// Replaying must deliver only the ingress messages of the protocol.
+	r, err := capture.NewReader(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, out := MsgPipe()
+	errc := make(chan error, 1)
+	go func() { errc <- ReplayCapture(r, "test", 1, in) }()
+
+	if err := ExpectMsg(out, 1, []uint{1}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExpectMsg(out, 2, []uint{4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+}
@@ -79,6 +79,7 @@ var (
 	errRecentlyDialed   = errors.New("recently dialed")
 	errNetRestrict      = errors.New("not contained in netrestrict list")
 	errNoPort           = errors.New("node does not provide TCP port")
+	errBanned           = errors.New("node is banned")
 )
 
 // dialer creates outbound connections and submits them into Server.
@@ -128,10 +129,11 @@ type dialScheduler struct {
 type dialSetupFunc func(net.Conn, connFlag, *enode.Node) error
 
 type dialConfig struct {
-	self           enode.ID         // our own ID
-	maxDialPeers   int              // maximum number of dialed peers
-	maxActiveDials int              // maximum number of active dials
-	netRestrict    *netutil.Netlist // IP netrestrict list, disabled if nil
+	self           enode.ID            // our own ID
+	maxDialPeers   int                 // maximum number of dialed peers
+	maxActiveDials int                 // maximum number of active dials
+	netRestrict    *netutil.Netlist    // IP netrestrict list, disabled if nil
+	banned         func(enode.ID) bool // reports banned nodes, disabled if nil
 	resolver       nodeResolver
 	dialer         NodeDialer
 	log            log.Logger
@@ -390,6 +392,9 @@ func (d *dialScheduler) checkDial(n *enode.Node) error {
 	if d.netRestrict != nil && !d.netRestrict.Contains(n.IP()) {
 		return errNetRestrict
 	}
+	if _, static := d.static[n.ID()]; !static && d.banned != nil && d.banned(n.ID()) {
+		return errBanned
+	}
 	if d.history.contains(string(n.ID().Bytes())) {
 		return errRecentlyDialed
 	}
@@ -0,0 +1,494 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/netutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	topicAdLifetime       = 15 * time.Minute // how long an ad stays in the cache
+	topicQueueCapacity    = 100              // max ads per topic
+	adCacheCapacity       = 5000             // max ads across all topics
+	topicRegWindow        = 10 * time.Second // tickets can be used this long after the wait time
+	topicQueryResultLimit = 16               // applies in TOPICQUERY handler
+	topicRetryWait        = time.Second      // wait of registrants yielding to a longer waiting one
+
+	// Ads from the same subnet are limited, per topic and in total. LAN addresses
+	// are exempt, as in the node table.
+	topicSubnetLimit = 10
+	adSubnetLimit    = 100
+
+	// Parameters of the wait time formula, see adCache.diversityWait.
+	waitOccupancyPower = 10
+	waitBaseline       = 1e-7
+)
+
+var (
+	errInvalidTicket = errors.New("invalid ticket")
+	errTicketTiming  = errors.New("ticket used outside of registration window")
+)
+
+// Topic identifies a topic. Ads for a topic are placed on the nodes whose IDs are
+// close to the topic identifier.
+type Topic [32]byte
+
+// NewTopic returns the identifier of the named topic.
+func NewTopic(name string) Topic {
+	return sha256.Sum256([]byte(name))
+}
+
+// adCache stores topic ads placed on the local node.
+//
+// Ads expire after a fixed lifetime. Registrants receive a ticket with a wait time,
+// and must return with the ticket once that time has passed. The wait time grows
+// with the occupancy of the cache, the size of the topic queue and the number of ads
+// already placed from the registrant's subnet and node ID. When the queue of a topic,
+// the whole cache or the share of the registrant's subnet is full, the wait lasts
+// until space becomes available. Tickets carry the total time waited, and when space
+// frees up, registrants which waited longest are admitted first.
+//
+// Tickets are opaque to the registrant: they are sealed with a key only known to the
+// local node.
+type adCache struct {
+	clock         mclock.Clock
+	lifetime      time.Duration
+	waitBase      time.Duration // scale of the diversity wait time, zero disables it
+	topicCapacity int
+	totalCapacity int
+	aead          cipher.AEAD
+
+	mu         sync.Mutex
+	topics     map[Topic][]*adEntry
+	all        []*adEntry // all ads, ordered by insertion time
+	subnetAds  map[string]int
+	nodeAds    map[enode.ID]int
+	waiting    map[Topic]map[enode.ID]*waitingReg // registrants holding a ticket
+	numWaiting int
+}
+
+type adEntry struct {
+	topic  Topic
+	node   *enode.Node
+	subnet string // empty for LAN addresses
+	added  mclock.AbsTime
+}
+
+// waitingReg is a registrant holding a ticket.
+type waitingReg struct {
+	cumulative time.Duration
+	start, end mclock.AbsTime // registration window of the ticket
+}
+
+// ticket is the content of a sealed ticket.
+type ticket struct {
+	Topic          Topic
+	Node           enode.ID
+	IP             net.IP
+	Issued         uint64 // mclock.AbsTime
+	WaitTime       uint64 // time.Duration
+	CumulativeWait uint64 // time.Duration
+}
+
+func newAdCache(clock mclock.Clock, lifetime, waitBase time.Duration, topicCapacity, totalCapacity int) *adCache {
+	var key [16]byte
+	crand.Read(key[:])
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		panic("can't create block cipher: " + err.Error())
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		panic("can't create GCM: " + err.Error())
+	}
+	return &adCache{
+		clock:         clock,
+		lifetime:      lifetime,
+		waitBase:      waitBase,
+		topicCapacity: topicCapacity,
+		totalCapacity: totalCapacity,
+		aead:          aead,
+		topics:        make(map[Topic][]*adEntry),
+		subnetAds:     make(map[string]int),
+		nodeAds:       make(map[enode.ID]int),
+		waiting:       make(map[Topic]map[enode.ID]*waitingReg),
+	}
+}
+
+// adSubnet returns the subnet of an IP address, for limiting the ads from it.
+func adSubnet(ip net.IP) string {
+	if netutil.IsLAN(ip) {
+		return ""
+	}
+	bits := 8 * net.IPv6len
+	if v4 := ip.To4(); v4 != nil {
+		ip, bits = v4, 8*net.IPv4len
+	}
+	return ip.Mask(net.CIDRMask(tableSubnet, bits)).String()
+}
+
+// register attempts to place an ad for n. If the ad can't be placed, it returns a
+// ticket and the time after which the registrant should retry with the ticket.
+func (c *adCache) register(topic Topic, n *enode.Node, ip net.IP, ticketData []byte) ([]byte, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.clock.Now()
+	c.expire(now)
+
+	// Tickets carry the time already spent waiting. Invalid tickets are ignored,
+	// which puts the registrant at the end of the line.
+	var cumulative time.Duration
+	if len(ticketData) > 0 {
+		if tk, err := c.openTicket(ticketData, topic, n.ID(), ip, now); err == nil {
+			cumulative = time.Duration(tk.CumulativeWait)
+		}
+	}
+	subnet := adSubnet(ip)
+	wait := c.waitTime(topic, n.ID(), subnet, cumulative, now)
+	if wait == 0 && c.preempted(topic, n.ID(), cumulative, now) {
+		wait = topicRetryWait
+	}
+	if wait == 0 {
+		c.add(topic, n, subnet, now)
+		c.unwait(topic, n.ID())
+		return nil, 0
+	}
+	c.wait(topic, n.ID(), &waitingReg{
+		cumulative: cumulative + wait,
+		start:      now.Add(wait),
+		end:        now.Add(wait + topicRegWindow),
+	})
+	tk := &ticket{
+		Topic:          topic,
+		Node:           n.ID(),
+		IP:             ip,
+		Issued:         uint64(now),
+		WaitTime:       uint64(wait),
+		CumulativeWait: uint64(cumulative + wait),
+	}
+	return c.sealTicket(tk), wait
+}
+
+// waitTime computes how long a registrant, which already waited for the given time,
+// has to wait until its ad can be placed.
+func (c *adCache) waitTime(topic Topic, id enode.ID, subnet string, cumulative time.Duration, now mclock.AbsTime) time.Duration {
+	queue := c.topics[topic]
+	for _, ad := range queue {
+		if ad.node.ID() == id {
+			return c.remaining(ad, now)
+		}
+	}
+	// Wait for space if the registrant can't be admitted right now.
+	var wait time.Duration
+	if len(queue) >= c.topicCapacity {
+		wait = c.remaining(queue[0], now)
+	}
+	if len(c.all) >= c.totalCapacity {
+		if w := c.remaining(c.all[0], now); w > wait {
+			wait = w
+		}
+	}
+	if subnet != "" {
+		if ad := oldestInSubnet(queue, subnet, topicSubnetLimit); ad != nil {
+			if w := c.remaining(ad, now); w > wait {
+				wait = w
+			}
+		}
+		if c.subnetAds[subnet] >= adSubnetLimit {
+			if w := c.remaining(oldestInSubnet(c.all, subnet, adSubnetLimit), now); w > wait {
+				wait = w
+			}
+		}
+	}
+	if wait > 0 {
+		return wait
+	}
+	if w := c.diversityWait(topic, id, subnet); w > cumulative {
+		return w - cumulative
+	}
+	return 0
+}
+
+// oldestInSubnet returns the oldest of the ads from the subnet if there are at
+// least limit of them.
+func oldestInSubnet(ads []*adEntry, subnet string, limit int) *adEntry {
+	var (
+		oldest *adEntry
+		count  int
+	)
+	for _, ad := range ads {
+		if ad.subnet != subnet {
+			continue
+		}
+		if oldest == nil {
+			oldest = ad
+		}
+		if count++; count >= limit {
+			return oldest
+		}
+	}
+	return nil
+}
+
+// diversityWait computes the total time a registrant has to wait, following the
+// formula of the discv5 topic advertisement specification:
+//
+//	wait = base * (c(topic)/C + score(subnet) + score(id) + G) / (1 - c/C)^P
+//
+// where c is the number of ads, C the capacity of the cache and c(topic) the size
+// of the topic queue. The scores are the shares of the cache taken by ads from the
+// subnet and the node ID of the registrant.
+func (c *adCache) diversityWait(topic Topic, id enode.ID, subnet string) time.Duration {
+	if c.waitBase == 0 {
+		return 0
+	}
+	var (
+		capacity  = float64(c.totalCapacity)
+		occupancy = float64(len(c.all)) / capacity
+		score     = float64(len(c.topics[topic]))/capacity + waitBaseline
+	)
+	if occupancy >= 1 {
+		return c.lifetime // unreachable, full caches are handled by waitTime
+	}
+	if len(c.all) > 0 {
+		if subnet != "" {
+			score += float64(c.subnetAds[subnet]) / float64(len(c.all))
+		}
+		score += float64(c.nodeAds[id]) / float64(len(c.all))
+	}
+	return time.Duration(float64(c.waitBase) * score / math.Pow(1-occupancy, waitOccupancyPower))
+}
+
+// preempted reports whether another registrant, which waited longer, can use its
+// ticket right now.
+func (c *adCache) preempted(topic Topic, id enode.ID, cumulative time.Duration, now mclock.AbsTime) bool {
+	for other, reg := range c.waiting[topic] {
+		if other != id && reg.cumulative > cumulative && reg.start <= now && now <= reg.end {
+			return true
+		}
+	}
+	return false
+}
+
+// wait records a registrant holding a ticket. The number of tracked registrants
+// is limited by the cache capacity, registrants beyond it lose their priority.
+func (c *adCache) wait(topic Topic, id enode.ID, reg *waitingReg) {
+	regs := c.waiting[topic]
+	if regs[id] == nil {
+		if c.numWaiting >= c.totalCapacity {
+			return
+		}
+		c.numWaiting++
+	}
+	if regs == nil {
+		regs = make(map[enode.ID]*waitingReg)
+		c.waiting[topic] = regs
+	}
+	regs[id] = reg
+}
+
+// unwait forgets a registrant which placed its ad.
+func (c *adCache) unwait(topic Topic, id enode.ID) {
+	if regs := c.waiting[topic]; regs[id] != nil {
+		delete(regs, id)
+		c.numWaiting--
+		if len(regs) == 0 {
+			delete(c.waiting, topic)
+		}
+	}
+}
+
+// remaining returns the time until ad expires. It is never zero for ads in the cache.
+func (c *adCache) remaining(ad *adEntry, now mclock.AbsTime) time.Duration {
+	if w := time.Duration(ad.added.Add(c.lifetime) - now); w > 0 {
+		return w
+	}
+	return 1
+}
+
+func (c *adCache) add(topic Topic, n *enode.Node, subnet string, now mclock.AbsTime) {
+	ad := &adEntry{topic: topic, node: n, subnet: subnet, added: now}
+	c.topics[topic] = append(c.topics[topic], ad)
+	c.all = append(c.all, ad)
+	if subnet != "" {
+		c.subnetAds[subnet]++
+	}
+	c.nodeAds[n.ID()]++
+}
+
+// expire removes ads which have reached the end of their lifetime, and forgets
+// registrants whose tickets can't be used any more.
+func (c *adCache) expire(now mclock.AbsTime) {
+	for len(c.all) > 0 && now.Sub(c.all[0].added) >= c.lifetime {
+		ad := c.all[0]
+		c.all = c.all[1:]
+		// Ads are appended in insertion order, so the expired ad is also
+		// the first one in its topic queue.
+		if queue := c.topics[ad.topic][1:]; len(queue) > 0 {
+			c.topics[ad.topic] = queue
+		} else {
+			delete(c.topics, ad.topic)
+		}
+		if ad.subnet != "" {
+			if c.subnetAds[ad.subnet]--; c.subnetAds[ad.subnet] == 0 {
+				delete(c.subnetAds, ad.subnet)
+			}
+		}
+		if id := ad.node.ID(); c.nodeAds[id] > 1 {
+			c.nodeAds[id]--
+		} else {
+			delete(c.nodeAds, id)
+		}
+	}
+	for topic, regs := range c.waiting {
+		for id, reg := range regs {
+			if now > reg.end {
+				c.unwait(topic, id)
+			}
+		}
+	}
+}
+
+// nodes returns up to limit nodes advertising the topic, in random order.
+func (c *adCache) nodes(topic Topic, limit int) []*enode.Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(c.clock.Now())
+	queue := c.topics[topic]
+	nodes := make([]*enode.Node, len(queue))
+	for i, ad := range queue {
+		nodes[i] = ad.node
+	}
+	rand.Shuffle(len(nodes), func(i, j int) { nodes[i], nodes[j] = nodes[j], nodes[i] })
+	if len(nodes) > limit {
+		nodes = nodes[:limit]
+	}
+	return nodes
+}
+
+// len returns the number of ads in the cache.
+func (c *adCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.expire(c.clock.Now())
+	return len(c.all)
+}
+
+func (c *adCache) sealTicket(tk *ticket) []byte {
+	enc, err := rlp.EncodeToBytes(tk)
+	if err != nil {
+		panic("can't encode ticket: " + err.Error())
+	}
+	nonce := make([]byte, c.aead.NonceSize())
+	crand.Read(nonce)
+	return c.aead.Seal(nonce, nonce, enc, nil)
+}
+
+// openTicket decrypts a ticket and checks that it was issued to the registrant for
+// the topic, and that it is used within the registration window.
+func (c *adCache) openTicket(data []byte, topic Topic, id enode.ID, ip net.IP, now mclock.AbsTime) (*ticket, error) {
+	ns := c.aead.NonceSize()
+	if len(data) < ns {
+		return nil, errInvalidTicket
+	}
+	enc, err := c.aead.Open(nil, data[:ns], data[ns:], nil)
+	if err != nil {
+		return nil, errInvalidTicket
+	}
+	var tk ticket
+	if err := rlp.DecodeBytes(enc, &tk); err != nil {
+		return nil, errInvalidTicket
+	}
+	if tk.Topic != topic || tk.Node != id || !tk.IP.Equal(ip) {
+		return nil, errInvalidTicket
+	}
+	start := mclock.AbsTime(tk.Issued).Add(time.Duration(tk.WaitTime))
+	if now < start || now > start.Add(topicRegWindow) {
+		return nil, errTicketTiming
+	}
+	return &tk, nil
+}
+
+// handleRegtopic places an ad for the sender, or hands out a ticket.
+func (t *UDPv5) handleRegtopic(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) {
+	n, err := t.checkRegtopicRecord(p, fromID, fromAddr)
+	if err != nil {
+		t.log.Debug("Invalid record in "+p.Name(), "id", fromID, "addr", fromAddr, "err", err)
+		return
+	}
+	tk, wait := t.ads.register(p.Topic, n, fromAddr.IP, p.Ticket)
+	if tk == nil {
+		t.sendResponse(fromID, fromAddr, &v5wire.Regconfirmation{ReqID: p.ReqID, Topic: p.Topic})
+		return
+	}
+	// Round the wait time up, so the registrant doesn't come back too early.
+	secs := uint((wait + time.Second - 1) / time.Second)
+	t.sendResponse(fromID, fromAddr, &v5wire.Ticket{ReqID: p.ReqID, Ticket: tk, WaitTime: secs})
+}
+
+// checkRegtopicRecord verifies that the record in a REGTOPIC request belongs to the
+// sender and points to the endpoint the request was sent from.
+func (t *UDPv5) checkRegtopicRecord(p *v5wire.Regtopic, fromID enode.ID, fromAddr *net.UDPAddr) (*enode.Node, error) {
+	if p.ENR == nil {
+		return nil, errors.New("missing record")
+	}
+	n, err := enode.New(t.validSchemes, p.ENR)
+	if err != nil {
+		return nil, err
+	}
+	if n.ID() != fromID {
+		return nil, errors.New("record of different node")
+	}
+	if !n.IP().Equal(fromAddr.IP) || n.UDP() != fromAddr.Port {
+		return nil, errors.New("record endpoint does not match sender")
+	}
+	if t.netrestrict != nil && !t.netrestrict.Contains(n.IP()) {
+		return nil, errors.New("not contained in netrestrict list")
+	}
+	return n, nil
+}
+
+// handleTopicQuery returns nodes advertising the topic.
+func (t *UDPv5) handleTopicQuery(p *v5wire.TopicQuery, fromID enode.ID, fromAddr *net.UDPAddr) {
+	var nodes []*enode.Node
+	for _, n := range t.ads.nodes(p.Topic, topicQueryResultLimit) {
+		if netutil.CheckRelayIP(fromAddr.IP, n.IP()) == nil {
+			nodes = append(nodes, n)
+		}
+	}
+	for _, resp := range packNodes(p.ReqID, nodes) {
+		t.sendResponse(fromID, fromAddr, resp)
+	}
+}
@@ -0,0 +1,296 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	topicRegistrarsPerBucket = 3                // registrars per log distance to the topic
+	topicRegistrarsRefresh   = 10 * time.Minute // interval between registrar lookups
+	topicMaxTicketWait       = topicAdLifetime  // tickets with longer wait time are discarded
+	topicSearchRoundDelay    = 10 * time.Second // pause between topic search rounds
+)
+
+// RegisterTopic starts advertising the local node for the given topic. Ads are placed
+// on nodes close to the topic in the background until UnregisterTopic is called.
+func (t *UDPv5) RegisterTopic(topic Topic) {
+	t.topicRegMu.Lock()
+	defer t.topicRegMu.Unlock()
+
+	if _, ok := t.topicReg[topic]; ok {
+		return
+	}
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	reg := &topicRegistration{
+		t:          t,
+		topic:      topic,
+		ctx:        ctx,
+		cancel:     cancel,
+		registrars: make(map[enode.ID]*registrarState),
+	}
+	t.topicReg[topic] = reg
+	t.wg.Add(1)
+	go reg.run()
+}
+
+// UnregisterTopic stops advertising the local node for the given topic. Ads which have
+// already been placed remain until they expire.
+func (t *UDPv5) UnregisterTopic(topic Topic) {
+	t.topicRegMu.Lock()
+	defer t.topicRegMu.Unlock()
+
+	if reg, ok := t.topicReg[topic]; ok {
+		reg.cancel()
+		delete(t.topicReg, topic)
+	}
+}
+
+// TopicNodes returns an iterator over nodes advertising the given topic. The iterator
+// searches for registrars close to the topic and asks them for ads. Nodes may be
+// returned more than once.
+func (t *UDPv5) TopicNodes(topic Topic) enode.Iterator {
+	if t.tab.len() == 0 {
+		// All nodes were dropped, refresh. The very first query will hit this
+		// case and run the bootstrapping logic.
+		<-t.tab.refresh()
+	}
+	ctx, cancel := context.WithCancel(t.closeCtx)
+	return &topicIterator{t: t, topic: topic, ctx: ctx, cancel: cancel}
+}
+
+// regtopic calls REGTOPIC on a node. The response is either TICKET or REGCONFIRMATION.
+func (t *UDPv5) regtopic(n *enode.Node, topic Topic, ticket []byte) (v5wire.Packet, error) {
+	req := &v5wire.Regtopic{Topic: topic, ENR: t.localNode.Node().Record(), Ticket: ticket}
+	resp := t.callToNode(n, v5wire.TicketMsg, req)
+	defer t.callDone(resp)
+
+	select {
+	case p := <-resp.ch:
+		return p, nil
+	case err := <-resp.err:
+		return nil, err
+	}
+}
+
+// topicQuery calls TOPICQUERY on a node and waits for responses.
+func (t *UDPv5) topicQuery(n *enode.Node, topic Topic) ([]*enode.Node, error) {
+	resp := t.callToNode(n, v5wire.NodesMsg, &v5wire.TopicQuery{Topic: topic})
+	return t.waitForNodes(resp, nil)
+}
+
+// topicRegistration places ads for a single topic.
+type topicRegistration struct {
+	t           *UDPv5
+	topic       Topic
+	ctx         context.Context
+	cancel      context.CancelFunc
+	registrars  map[enode.ID]*registrarState
+	lastRefresh mclock.AbsTime
+}
+
+// registrarState tracks the ad placement on a single registrar.
+type registrarState struct {
+	node   *enode.Node
+	ticket []byte
+	next   mclock.AbsTime // time of next REGTOPIC
+}
+
+func (reg *topicRegistration) run() {
+	defer reg.t.wg.Done()
+
+	timer := reg.t.clock.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-timer.C():
+		case <-reg.ctx.Done():
+			return
+		}
+		now := reg.t.clock.Now()
+		if len(reg.registrars) == 0 || now.Sub(reg.lastRefresh) >= topicRegistrarsRefresh {
+			reg.refresh()
+			reg.lastRefresh = now
+		}
+		next := reg.lastRefresh.Add(topicRegistrarsRefresh)
+		for _, r := range reg.registrars {
+			if reg.ctx.Err() != nil {
+				return
+			}
+			if r.next <= now {
+				reg.attempt(r)
+			}
+			if _, ok := reg.registrars[r.node.ID()]; ok && r.next < next {
+				next = r.next
+			}
+		}
+		if len(reg.registrars) == 0 {
+			// No registrars were found, retry soon.
+			next = reg.t.clock.Now().Add(topicSearchRoundDelay)
+		}
+		timer.Reset(time.Duration(next - reg.t.clock.Now()))
+	}
+}
+
+// refresh looks up nodes close to the topic and adds them as registrars.
+func (reg *topicRegistration) refresh() {
+	var (
+		target = enode.ID(reg.topic)
+		self   = reg.t.Self().ID()
+		counts = make(map[int]int)
+	)
+	for id := range reg.registrars {
+		counts[enode.LogDist(target, id)]++
+	}
+	nodes := reg.t.newLookup(reg.ctx, target).run()
+	nodes = append(nodes, reg.t.AllNodes()...)
+	for _, n := range nodes {
+		if n.ID() == self || reg.registrars[n.ID()] != nil {
+			continue
+		}
+		d := enode.LogDist(target, n.ID())
+		if counts[d] >= topicRegistrarsPerBucket {
+			continue
+		}
+		counts[d]++
+		reg.registrars[n.ID()] = &registrarState{node: n}
+	}
+}
+
+// attempt sends REGTOPIC to a registrar and schedules the next attempt.
+func (reg *topicRegistration) attempt(r *registrarState) {
+	resp, err := reg.t.regtopic(r.node, reg.topic, r.ticket)
+	if err != nil {
+		reg.t.log.Trace("Topic registration failed", "id", r.node.ID(), "err", err)
+		delete(reg.registrars, r.node.ID())
+		return
+	}
+	now := reg.t.clock.Now()
+	switch resp := resp.(type) {
+	case *v5wire.Ticket:
+		wait := time.Duration(resp.WaitTime) * time.Second
+		if wait > topicMaxTicketWait || len(resp.Ticket) == 0 {
+			delete(reg.registrars, r.node.ID())
+			return
+		}
+		r.ticket = resp.Ticket
+		r.next = now.Add(wait)
+	case *v5wire.Regconfirmation:
+		// Re-register when the ad expires.
+		r.ticket = nil
+		r.next = now.Add(topicAdLifetime)
+		reg.t.log.Trace("Topic ad placed", "id", r.node.ID())
+	}
+}
+
+// topicIterator searches for nodes advertising a topic. Each round performs a lookup
+// toward the topic and sends TOPICQUERY to every node found by the lookup.
+type topicIterator struct {
+	t      *UDPv5
+	topic  Topic
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	lookup *lookup
+	queue  []*enode.Node // registrars to query
+	asked  map[enode.ID]bool
+	seen   map[enode.ID]bool
+	buffer []*enode.Node
+}
+
+// Node returns the current node.
+func (it *topicIterator) Node() *enode.Node {
+	if len(it.buffer) == 0 {
+		return nil
+	}
+	return it.buffer[0]
+}
+
+// Next moves to the next node.
+func (it *topicIterator) Next() bool {
+	// Consume next node in buffer.
+	if len(it.buffer) > 0 {
+		it.buffer = it.buffer[1:]
+	}
+	for len(it.buffer) == 0 {
+		if it.ctx.Err() != nil {
+			it.lookup = nil
+			it.buffer = nil
+			return false
+		}
+		switch {
+		case len(it.queue) > 0:
+			n := it.queue[0]
+			it.queue = it.queue[1:]
+			nodes, _ := it.t.topicQuery(n, it.topic)
+			it.addResults(nodes)
+		case it.lookup == nil:
+			it.startRound()
+		case !it.lookup.advance():
+			it.lookup = nil
+			it.endRound()
+		default:
+			for _, n := range it.lookup.replyBuffer {
+				if !it.asked[n.ID()] {
+					it.asked[n.ID()] = true
+					it.queue = append(it.queue, unwrapNode(n))
+				}
+			}
+		}
+	}
+	return true
+}
+
+// startRound begins a new search round. The local node is a registrar too, so its own
+// ads are returned first.
+func (it *topicIterator) startRound() {
+	it.asked = make(map[enode.ID]bool)
+	it.seen = make(map[enode.ID]bool)
+	it.asked[it.t.Self().ID()] = true
+	it.lookup = it.t.newLookup(it.ctx, enode.ID(it.topic))
+	it.addResults(it.t.ads.nodes(it.topic, topicQueryResultLimit))
+}
+
+// endRound waits before starting the next round.
+func (it *topicIterator) endRound() {
+	timer := it.t.clock.NewTimer(topicSearchRoundDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+	case <-it.ctx.Done():
+	}
+}
+
+func (it *topicIterator) addResults(nodes []*enode.Node) {
+	for _, n := range nodes {
+		if n.ID() != it.t.Self().ID() && !it.seen[n.ID()] {
+			it.seen[n.ID()] = true
+			it.buffer = append(it.buffer, n)
+		}
+	}
+}
+
+// Close ends the iterator.
+func (it *topicIterator) Close() {
+	it.cancel()
+}
@@ -0,0 +1,399 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package discover
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/discover/v5wire"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+func TestAdCache(t *testing.T) {
+	const lifetime = 10 * time.Minute
+	var (
+		clock  = new(mclock.Simulated)
+		cache  = newAdCache(clock, lifetime, 0, 2, 3)
+		topic1 = NewTopic("foo")
+		topic2 = NewTopic("bar")
+		nodes  = make([]*enode.Node, 6)
+	)
+	for i := range nodes {
+		nodes[i] = unwrapNode(nodeAtDistance(enode.ID{}, 256, intIP(i)))
+	}
+	register := func(topic Topic, n *enode.Node, tk []byte) ([]byte, time.Duration) {
+		t.Helper()
+		return cache.register(topic, n, n.IP(), tk)
+	}
+	mustPlace := func(topic Topic, n *enode.Node, tk []byte) {
+		t.Helper()
+		if tk, wait := register(topic, n, tk); tk != nil {
+			t.Fatalf("ad for %v not placed, wait time %v", n.ID().TerminalString(), wait)
+		}
+	}
+	mustWait := func(topic Topic, n *enode.Node, tk []byte, wantWait, wantCumulative time.Duration) []byte {
+		t.Helper()
+		tk, wait := register(topic, n, tk)
+		if tk == nil {
+			t.Fatalf("ad for %v placed, want ticket", n.ID().TerminalString())
+		}
+		if wait != wantWait {
+			t.Fatalf("wrong wait time %v, want %v", wait, wantWait)
+		}
+		if c := openTestTicket(t, cache, tk).CumulativeWait; time.Duration(c) != wantCumulative {
+			t.Fatalf("wrong cumulative wait time %v, want %v", time.Duration(c), wantCumulative)
+		}
+		return tk
+	}
+
+	// Fill the topic queue.
+	mustPlace(topic1, nodes[0], nil)
+	mustPlace(topic1, nodes[1], nil)
+	tk := mustWait(topic1, nodes[2], nil, lifetime, lifetime)
+
+	// Registering again while the ad exists yields a ticket.
+	clock.Run(time.Minute)
+	mustWait(topic1, nodes[0], nil, lifetime-time.Minute, lifetime-time.Minute)
+
+	// Fill the cache. The next registration has to wait for the oldest ad.
+	mustPlace(topic2, nodes[3], nil)
+	mustWait(topic2, nodes[4], nil, lifetime-time.Minute, lifetime-time.Minute)
+
+	// Using the ticket too early doesn't count the time already waited.
+	clock.Run(time.Minute)
+	mustWait(topic1, nodes[2], tk, lifetime-2*time.Minute, lifetime-2*time.Minute)
+
+	// All ads except the one placed later have expired.
+	clock.Run(lifetime - 2*time.Minute)
+	if n := cache.len(); n != 1 {
+		t.Fatalf("wrong cache size %d after expiry, want 1", n)
+	}
+	// The freed space goes to the registrant which waited longest.
+	mustWait(topic1, nodes[0], nil, topicRetryWait, topicRetryWait)
+	mustPlace(topic1, nodes[2], tk)
+	mustPlace(topic1, nodes[1], nil)
+
+	// The ticket can't be used by a different node, but counts the time already
+	// waited for the node it was issued to.
+	mustWait(topic1, nodes[5], tk, lifetime, lifetime)
+	tk = mustWait(topic1, nodes[2], tk, lifetime, 2*lifetime)
+
+	// Ticket is ignored when the registration window has passed.
+	clock.Run(lifetime + topicRegWindow + 1)
+	mustPlace(topic1, nodes[0], nil)
+	mustPlace(topic1, nodes[1], nil)
+	mustWait(topic1, nodes[2], tk, lifetime, lifetime)
+
+	if got := cache.nodes(topic1, 1); len(got) != 1 {
+		t.Fatalf("wrong number of nodes %d, want 1", len(got))
+	}
+	if got := cache.nodes(topic1, 10); len(got) != 2 {
+		t.Fatalf("wrong number of nodes %d, want 2", len(got))
+	}
+	clock.Run(lifetime)
+	if got := cache.nodes(topic1, 10); len(got) != 0 {
+		t.Fatalf("%d nodes returned after expiry", len(got))
+	}
+}
+
+// This test checks that the wait time grows with the ads already placed from the
+// subnet and node ID of the registrant.
+func TestAdCacheDiversity(t *testing.T) {
+	const lifetime = 10 * time.Minute
+	var (
+		clock  = new(mclock.Simulated)
+		cache  = newAdCache(clock, lifetime, lifetime, 100, 1000)
+		topic1 = NewTopic("foo")
+		topic2 = NewTopic("bar")
+		a      = unwrapNode(nodeAtDistance(enode.ID{}, 256, net.IP{1, 2, 3, 4}))
+		b      = unwrapNode(nodeAtDistance(enode.ID{}, 256, net.IP{1, 2, 3, 5}))
+		c      = unwrapNode(nodeAtDistance(enode.ID{}, 256, net.IP{5, 6, 7, 8}))
+	)
+	// Registrants have to wait even on an empty cache, and are admitted when
+	// returning with the ticket.
+	tk, wait := cache.register(topic1, a, a.IP(), nil)
+	if tk == nil || wait <= 0 || wait > time.Millisecond {
+		t.Fatalf("wrong wait time %v on empty cache", wait)
+	}
+	clock.Run(wait)
+	if tk, wait := cache.register(topic1, a, a.IP(), tk); tk != nil {
+		t.Fatalf("ad not placed after waiting, wait time %v", wait)
+	}
+
+	_, waitSubnet := cache.register(topic1, b, b.IP(), nil)
+	_, waitOther := cache.register(topic1, c, c.IP(), nil)
+	_, waitNode := cache.register(topic2, a, a.IP(), nil)
+	if waitSubnet < lifetime || waitNode < lifetime {
+		t.Errorf("wait time too short for same subnet (%v) or node (%v)", waitSubnet, waitNode)
+	}
+	if waitOther >= lifetime/100 {
+		t.Errorf("wait time %v too long for different subnet", waitOther)
+	}
+}
+
+// This test checks that the number of ads from a subnet is limited.
+func TestAdCacheSubnetLimit(t *testing.T) {
+	const lifetime = 10 * time.Minute
+	var (
+		clock = new(mclock.Simulated)
+		cache = newAdCache(clock, lifetime, 0, 3*topicSubnetLimit, adCacheCapacity)
+		topic = NewTopic("foo")
+	)
+	register := func(ip net.IP) time.Duration {
+		n := unwrapNode(nodeAtDistance(enode.ID{}, 256, ip))
+		_, wait := cache.register(topic, n, ip, nil)
+		return wait
+	}
+	for i := 0; i < topicSubnetLimit; i++ {
+		if wait := register(net.IP{1, 2, 3, byte(i)}); wait != 0 {
+			t.Fatalf("ad %d not placed, wait time %v", i, wait)
+		}
+		clock.Run(time.Second)
+	}
+	if wait, want := register(net.IP{1, 2, 3, 100}), lifetime-topicSubnetLimit*time.Second; wait != want {
+		t.Fatalf("wrong wait time %v for limited subnet, want %v", wait, want)
+	}
+	if wait := register(net.IP{1, 2, 4, 100}); wait != 0 {
+		t.Fatalf("ad from other subnet not placed, wait time %v", wait)
+	}
+	// LAN addresses are exempt.
+	for i := 0; i < topicSubnetLimit+1; i++ {
+		if wait := register(net.IP{10, 0, 0, byte(i)}); wait != 0 {
+			t.Fatalf("LAN ad %d not placed, wait time %v", i, wait)
+		}
+	}
+}
+
+// This test checks that the registrants waiting for a full cache are bounded in
+// total, regardless of the number of topics.
+func TestAdCacheWaitingLimit(t *testing.T) {
+	const totalCapacity = 2
+	var (
+		clock = new(mclock.Simulated)
+		cache = newAdCache(clock, 10*time.Minute, 0, totalCapacity, totalCapacity)
+	)
+	for i := 0; i < totalCapacity; i++ {
+		n := unwrapNode(nodeAtDistance(enode.ID{}, 256, intIP(i)))
+		if tk, _ := cache.register(NewTopic("full"), n, n.IP(), nil); tk != nil {
+			t.Fatalf("ad %d not placed", i)
+		}
+	}
+	n := unwrapNode(nodeAtDistance(enode.ID{}, 256, intIP(totalCapacity)))
+	for i := 0; i < 10*totalCapacity; i++ {
+		topic := NewTopic(fmt.Sprint("topic", i))
+		if tk, _ := cache.register(topic, n, n.IP(), nil); tk == nil {
+			t.Fatalf("ad for topic %d placed in full cache", i)
+		}
+	}
+	if len(cache.waiting) > totalCapacity {
+		t.Fatalf("waiting registrants tracked for %d topics, want at most %d", len(cache.waiting), totalCapacity)
+	}
+	if cache.numWaiting != totalCapacity {
+		t.Fatalf("wrong number of waiting registrants %d, want %d", cache.numWaiting, totalCapacity)
+	}
+}
+
+func openTestTicket(t *testing.T, c *adCache, data []byte) *ticket {
+	t.Helper()
+	ns := c.aead.NonceSize()
+	enc, err := c.aead.Open(nil, data[:ns], data[ns:], nil)
+	if err != nil {
+		t.Fatal("can't open ticket:", err)
+	}
+	var tk ticket
+	if err := rlp.DecodeBytes(enc, &tk); err != nil {
+		t.Fatal("can't decode ticket:", err)
+	}
+	return &tk
+}
+
+// This test checks that REGTOPIC and TOPICQUERY are handled correctly.
+func TestUDPv5_regtopicHandling(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+	test.udp.ads = newAdCache(test.udp.clock, topicAdLifetime, 0, 1, adCacheCapacity)
+
+	var (
+		topic      = NewTopic("foo")
+		remote     = test.getNode(test.remotekey, test.remoteaddr).Node()
+		otherkey   = newkey()
+		otheraddr  = &net.UDPAddr{IP: net.IP{10, 0, 1, 100}, Port: 30303}
+		other      = test.getNode(otherkey, otheraddr).Node()
+		wrongkey   = newkey()
+		wrongaddr  = &net.UDPAddr{IP: net.IP{10, 0, 1, 101}, Port: 30303}
+		wrongentry = test.getNode(wrongkey, &net.UDPAddr{IP: net.IP{10, 0, 1, 102}, Port: 30303}).Node()
+	)
+
+	// Record of another node is rejected.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{1}, Topic: topic, ENR: other.Record()})
+	// Record with wrong endpoint is rejected.
+	test.packetInFrom(wrongkey, wrongaddr, &v5wire.Regtopic{ReqID: []byte{2}, Topic: topic, ENR: wrongentry.Record()})
+
+	// Valid registration is confirmed.
+	test.packetIn(&v5wire.Regtopic{ReqID: []byte{3}, Topic: topic, ENR: remote.Record()})
+	test.waitPacketOut(func(p *v5wire.Regconfirmation, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte{3}) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if p.Topic != topic {
+			t.Error("wrong topic in response:", p.Topic)
+		}
+	})
+
+	// The topic queue is full, so the next registration gets a ticket.
+	test.packetInFrom(otherkey, otheraddr, &v5wire.Regtopic{ReqID: []byte{4}, Topic: topic, ENR: other.Record()})
+	test.waitPacketOut(func(p *v5wire.Ticket, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if !bytes.Equal(p.ReqID, []byte{4}) {
+			t.Error("wrong request ID in response:", p.ReqID)
+		}
+		if len(p.Ticket) == 0 {
+			t.Error("empty ticket")
+		}
+		if want := uint(topicAdLifetime / time.Second); p.WaitTime != want {
+			t.Errorf("wrong wait time %d, want %d", p.WaitTime, want)
+		}
+	})
+
+	// Query returns the registered node.
+	test.packetInFrom(otherkey, otheraddr, &v5wire.TopicQuery{ReqID: []byte{5}, Topic: topic})
+	test.expectNodes([]byte{5}, 1, []*enode.Node{remote})
+
+	// Query for unknown topic returns nothing.
+	test.packetIn(&v5wire.TopicQuery{ReqID: []byte{6}, Topic: NewTopic("bar")})
+	test.expectNodes([]byte{6}, 1, nil)
+}
+
+// This test checks that outgoing REGTOPIC calls accept both kinds of responses.
+func TestUDPv5_regtopicCall(t *testing.T) {
+	t.Parallel()
+	test := newUDPV5Test(t)
+	defer test.close()
+
+	var (
+		topic  = NewTopic("foo")
+		remote = test.getNode(test.remotekey, test.remoteaddr).Node()
+		done   = make(chan v5wire.Packet, 1)
+	)
+	call := func(tk []byte) {
+		go func() {
+			resp, err := test.udp.regtopic(remote, topic, tk)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- resp
+		}()
+	}
+
+	call(nil)
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if p.Topic != topic {
+			t.Error("wrong topic in request:", p.Topic)
+		}
+		if len(p.Ticket) != 0 {
+			t.Error("non-empty ticket in request:", p.Ticket)
+		}
+		if n, err := enode.New(enode.ValidSchemesForTesting, p.ENR); err != nil || n.ID() != test.udp.Self().ID() {
+			t.Error("wrong record in request:", err)
+		}
+		test.packetIn(&v5wire.Ticket{ReqID: p.ReqID, Ticket: []byte("ticket"), WaitTime: 5})
+	})
+	if resp, ok := (<-done).(*v5wire.Ticket); !ok || resp.WaitTime != 5 {
+		t.Fatalf("wrong response %v", resp)
+	}
+
+	call([]byte("ticket"))
+	test.waitPacketOut(func(p *v5wire.Regtopic, addr *net.UDPAddr, _ v5wire.Nonce) {
+		if string(p.Ticket) != "ticket" {
+			t.Errorf("wrong ticket in request: %q", p.Ticket)
+		}
+		test.packetIn(&v5wire.Regconfirmation{ReqID: p.ReqID, Topic: topic})
+	})
+	if _, ok := (<-done).(*v5wire.Regconfirmation); !ok {
+		t.Fatal("wrong response type")
+	}
+}
+
+// Real sockets, real crypto: this test checks that registered nodes can be found.
+func TestUDPv5_topicE2E(t *testing.T) {
+	t.Parallel()
+
+	const N = 5
+	var nodes []*UDPv5
+	for i := 0; i < N; i++ {
+		var cfg Config
+		if len(nodes) > 0 {
+			bn := nodes[0].Self()
+			cfg.Bootnodes = []*enode.Node{bn}
+		}
+		node := startLocalhostV5(t, cfg)
+		nodes = append(nodes, node)
+		defer node.Close()
+	}
+
+	// Register two of the nodes and wait until their ads are placed.
+	topic := NewTopic("foo")
+	advertisers := nodes[1:3]
+	for _, n := range advertisers {
+		n.RegisterTopic(topic)
+	}
+	searcher := nodes[N-1]
+	placed := func() bool {
+		for _, adv := range advertisers {
+			found := false
+			for _, n := range nodes {
+				for _, ad := range n.ads.nodes(topic, N) {
+					found = found || ad.ID() == adv.Self().ID()
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+		return true
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !placed() || searcher.tab.len() < N-1 {
+		if time.Now().After(deadline) {
+			t.Fatal("ads not placed")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	for _, n := range advertisers {
+		n.UnregisterTopic(topic)
+	}
+
+	// Search from another node.
+	it := searcher.TopicNodes(topic)
+	timeout := time.AfterFunc(10*time.Second, it.Close)
+	defer timeout.Stop()
+	found := make(map[enode.ID]bool)
+	for len(found) < len(advertisers) && it.Next() {
+		found[it.Node().ID()] = true
+	}
+	for _, n := range advertisers {
+		if !found[n.Self().ID()] {
+			t.Errorf("advertiser %v not found", n.Self().ID().TerminalString())
+		}
+	}
+}
@@ -76,6 +76,11 @@ type UDPv5 struct {
 	// talkreq handler registry
 	talk *talkSystem
 
+	// topic ads placed on this node, and local ad registrations
+	ads        *adCache
+	topicRegMu sync.Mutex
+	topicReg   map[Topic]*topicRegistration
+
 	// channels into dispatch
 	packetInCh    chan ReadPacket
 	readNextCh    chan struct{}
@@ -174,6 +179,8 @@ func newUDPv5(conn UDPConn, ln *enode.LocalNode, cfg Config) (*UDPv5, error) {
 		cancelCloseCtx: cancelCloseCtx,
 	}
 	t.talk = newTalkSystem(t)
+	t.ads = newAdCache(cfg.Clock, topicAdLifetime, topicAdLifetime, topicQueueCapacity, adCacheCapacity)
+	t.topicReg = make(map[Topic]*topicRegistration)
 	tab, err := newMeteredTable(t, t.db, cfg)
 	if err != nil {
 		return nil, err
@@ -730,7 +737,7 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr *net.UDPAddr, p v5w
 		t.log.Debug(fmt.Sprintf("%s from wrong endpoint", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
-	if p.Kind() != ac.responseType {
+	if !matchesResponseType(ac.responseType, p.Kind()) {
 		t.log.Debug(fmt.Sprintf("Wrong discv5 response type %s", p.Name()), "id", fromID, "addr", fromAddr)
 		return false
 	}
@@ -739,6 +746,15 @@ func (t *UDPv5) handleCallResponse(fromID enode.ID, fromAddr *net.UDPAddr, p v5w
 	return true
 }
 
+// matchesResponseType reports whether a packet of the given kind answers a call expecting
+// responseType. REGTOPIC is answered by either TICKET or REGCONFIRMATION.
+func matchesResponseType(responseType, kind byte) bool {
+	if responseType == v5wire.TicketMsg && kind == v5wire.RegconfirmationMsg {
+		return true
+	}
+	return kind == responseType
+}
+
 // getNode looks for a node record in table and database.
 func (t *UDPv5) getNode(id enode.ID) *enode.Node {
 	if n := t.tab.getNode(id); n != nil {
@@ -771,6 +787,14 @@ func (t *UDPv5) handle(p v5wire.Packet, fromID enode.ID, fromAddr *net.UDPAddr)
 		t.talk.handleRequest(fromID, fromAddr, p)
 	case *v5wire.TalkResponse:
 		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regtopic:
+		t.handleRegtopic(p, fromID, fromAddr)
+	case *v5wire.Ticket:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.Regconfirmation:
+		t.handleCallResponse(fromID, fromAddr, p)
+	case *v5wire.TopicQuery:
+		t.handleTopicQuery(p, fromID, fromAddr)
 	}
 }
 
@@ -21,6 +21,7 @@ import (
 	"crypto/rand"
 	"encoding/binary"
 	"fmt"
+	"math"
 	"net"
 	"os"
 	"sync"
@@ -43,6 +44,10 @@ const (
 	dbDiscoverRoot = "v4"
 	dbDiscv5Root   = "v5"
 
+	// Reputation information is keyed by ID only, the full key is "rep:<ID>".
+	// It is kept out of the node entries so that it survives node expiration.
+	dbReputationPrefix = "rep:"
+
 	// These fields are stored per ID and IP, the full key is "n:<ID>:v4:<IP>:findfail".
 	// Use nodeItemKey to create those keys.
 	dbNodeFindFails = "findfail"
@@ -293,6 +298,101 @@ func deleteRange(db *leveldb.DB, prefix []byte) {
 	}
 }
 
+// Reputation is the persisted quality-of-service score of a node, along with
+// any ban in effect for it.
+type Reputation struct {
+	Score       float64   // Score as of Updated, lowered by penalties
+	Updated     time.Time // Time the score was last updated
+	BannedUntil time.Time // End of the ban, zero if the node isn't banned
+	Reason      string    // Reason of the last penalty or ban
+}
+
+// storedReputation is the RLP encoding of a Reputation.
+type storedReputation struct {
+	Score       uint64 // float64 bits
+	Updated     uint64 // unix seconds
+	BannedUntil uint64 // unix seconds
+	Reason      string
+}
+
+// reputationKey returns the database key for the reputation of a node.
+func reputationKey(id ID) []byte {
+	return append([]byte(dbReputationPrefix), id[:]...)
+}
+
+func decodeReputation(blob []byte) (Reputation, error) {
+	var stored storedReputation
+	if err := rlp.DecodeBytes(blob, &stored); err != nil {
+		return Reputation{}, err
+	}
+	rep := Reputation{
+		Score:   math.Float64frombits(stored.Score),
+		Updated: time.Unix(int64(stored.Updated), 0),
+		Reason:  stored.Reason,
+	}
+	if stored.BannedUntil != 0 {
+		rep.BannedUntil = time.Unix(int64(stored.BannedUntil), 0)
+	}
+	return rep, nil
+}
+
+// Reputation retrieves the stored reputation of a node.
+func (db *DB) Reputation(id ID) (Reputation, bool) {
+	blob, err := db.lvl.Get(reputationKey(id), nil)
+	if err != nil {
+		return Reputation{}, false
+	}
+	rep, err := decodeReputation(blob)
+	if err != nil {
+		return Reputation{}, false
+	}
+	return rep, true
+}
+
+// UpdateReputation stores the reputation of a node.
+func (db *DB) UpdateReputation(id ID, rep Reputation) error {
+	stored := storedReputation{
+		Score:   math.Float64bits(rep.Score),
+		Updated: uint64(rep.Updated.Unix()),
+		Reason:  rep.Reason,
+	}
+	if !rep.BannedUntil.IsZero() {
+		stored.BannedUntil = uint64(rep.BannedUntil.Unix())
+	}
+	blob, err := rlp.EncodeToBytes(&stored)
+	if err != nil {
+		return err
+	}
+	return db.lvl.Put(reputationKey(id), blob, nil)
+}
+
+// DeleteReputation removes the stored reputation of a node.
+func (db *DB) DeleteReputation(id ID) error {
+	return db.lvl.Delete(reputationKey(id), nil)
+}
+
+// Reputations retrieves the stored reputations of all nodes.
+func (db *DB) Reputations() map[ID]Reputation {
+	it := db.lvl.NewIterator(util.BytesPrefix([]byte(dbReputationPrefix)), nil)
+	defer it.Release()
+
+	reps := make(map[ID]Reputation)
+	for it.Next() {
+		key := it.Key()[len(dbReputationPrefix):]
+		if len(key) != len(ID{}) {
+			continue
+		}
+		rep, err := decodeReputation(it.Value())
+		if err != nil {
+			continue
+		}
+		var id ID
+		copy(id[:], key)
+		reps[id] = rep
+	}
+	return reps
+}
+
 // ensureExpirer is a small helper method ensuring that the data expiration
 // mechanism is running. If the expiration goroutine is already running, this
 // method simply returns.
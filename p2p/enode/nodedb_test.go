@@ -467,3 +467,52 @@ func TestDBExpireV5(t *testing.T) {
 	db.UpdateFindFailsV5(ID{}, ip, 4)
 	db.expireNodes()
 }
+
+func TestDBReputation(t *testing.T) {
+	root := t.TempDir()
+	db, err := OpenDB(filepath.Join(root, "database"))
+	if err != nil {
+		t.Fatalf("failed to create persistent database: %v", err)
+	}
+	var (
+		id   = ID{0x01}
+		want = Reputation{
+			Score:       -42.5,
+			Updated:     time.Unix(1700000000, 0),
+			BannedUntil: time.Unix(1700003600, 0),
+			Reason:      "breach of protocol",
+		}
+	)
+	if _, ok := db.Reputation(id); ok {
+		t.Fatal("reputation present before store")
+	}
+	if err := db.UpdateReputation(id, want); err != nil {
+		t.Fatalf("failed to store reputation: %v", err)
+	}
+	// Reputations must survive node expiration and reopening the database.
+	db.expireNodes()
+	db.Close()
+
+	db, err = OpenDB(filepath.Join(root, "database"))
+	if err != nil {
+		t.Fatalf("failed to open persistent database: %v", err)
+	}
+	defer db.Close()
+
+	have, ok := db.Reputation(id)
+	if !ok {
+		t.Fatal("reputation missing after reopen")
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Fatalf("reputation mismatch: have %+v, want %+v", have, want)
+	}
+	if all := db.Reputations(); len(all) != 1 || !reflect.DeepEqual(all[id], want) {
+		t.Fatalf("wrong reputation list: %+v", all)
+	}
+	if err := db.DeleteReputation(id); err != nil {
+		t.Fatalf("failed to delete reputation: %v", err)
+	}
+	if _, ok := db.Reputation(id); ok {
+		t.Fatal("reputation present after delete")
+	}
+}
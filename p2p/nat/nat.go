@@ -49,6 +49,20 @@ type Interface interface {
 	String() string
 }
 
+// Endpoint is an Internet-facing address of a port mapping.
+type Endpoint struct {
+	IP   net.IP
+	Port int
+}
+
+// EndpointMapper is implemented by NAT interfaces which learn the external
+// endpoints of their mappings, possibly for both IPv4 and IPv6.
+type EndpointMapper interface {
+	// ExternalEndpoints returns the external endpoints of the mapping for the
+	// given internal port, at most one per IP family.
+	ExternalEndpoints(protocol string, intport int) []Endpoint
+}
+
 // Parse parses a NAT interface description.
 // The following formats are currently accepted.
 // Note that mechanism names are not case-sensitive.
@@ -59,6 +73,8 @@ type Interface interface {
 //	"upnp"               uses the Universal Plug and Play protocol
 //	"pmp"                uses NAT-PMP with an auto-detected gateway address
 //	"pmp:192.168.0.1"    uses NAT-PMP with the given gateway address
+//	"pcp"                uses PCP with auto-detected IPv4 and IPv6 servers
+//	"pcp:192.168.0.1"    uses PCP with the given server address
 func Parse(spec string) (Interface, error) {
 	var (
 		parts = strings.SplitN(spec, ":", 2)
@@ -85,6 +101,8 @@ func Parse(spec string) (Interface, error) {
 		return UPnP(), nil
 	case "pmp", "natpmp", "nat-pmp":
 		return PMP(ip), nil
+	case "pcp":
+		return PCP(ip), nil
 	default:
 		return nil, fmt.Errorf("unknown mechanism %q", parts[0])
 	}
@@ -146,10 +164,11 @@ func (ExtIP) DeleteMapping(string, int, int) error
 func Any() Interface {
 	// TODO: attempt to discover whether the local machine has an
 	// Internet-class address. Return ExtIP in this case.
-	return startautodisc("UPnP or NAT-PMP", func() Interface {
-		found := make(chan Interface, 2)
+	return startautodisc("UPnP, NAT-PMP or PCP", func() Interface {
+		found := make(chan Interface, 3)
 		go func() { found <- discoverUPnP() }()
 		go func() { found <- discoverPMP() }()
+		go func() { found <- discoverPCP() }()
 		for i := 0; i < cap(found); i++ {
 			if c := <-found; c != nil {
 				return c
@@ -217,6 +236,18 @@ func (n *autodisc) ExternalIP() (net.IP, error) {
 	return n.found.ExternalIP()
 }
 
+// ExternalEndpoints implements EndpointMapper. It returns nil if the discovered
+// mechanism doesn't report endpoints.
+func (n *autodisc) ExternalEndpoints(protocol string, intport int) []Endpoint {
+	if err := n.wait(); err != nil {
+		return nil
+	}
+	if m, ok := n.found.(EndpointMapper); ok {
+		return m.ExternalEndpoints(protocol, intport)
+	}
+	return nil
+}
+
 func (n *autodisc) String() string {
 	n.mu.Lock()
 	defer n.mu.Unlock()
@@ -0,0 +1,437 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// This file implements the MAP opcode of the Port Control Protocol (RFC 6887),
+// which is used to open IPv4 port mappings on NATs and IPv6 pinholes on firewalls.
+
+const (
+	pcpPort    = 5351
+	pcpVersion = 2
+
+	pcpOpAnnounce = 0
+	pcpOpMap      = 1
+	pcpResponse   = 0x80
+
+	pcpHeaderSize  = 24
+	pcpMapSize     = 36
+	pcpMaxRespSize = 1100
+
+	pcpResultSuccess = 0
+
+	// pcpEpochTolerance is the clock skew allowed when checking that the server
+	// didn't lose its state (RFC 6887, section 8.5).
+	pcpEpochTolerance = 2 * time.Second
+)
+
+var (
+	// Anycast addresses of PCP servers (RFC 7723).
+	pcpAnycast4 = net.IPv4(192, 0, 0, 9)
+	pcpAnycast6 = net.ParseIP("2001:1::1")
+
+	// pcpTimeouts are the retransmission timeouts of a request.
+	pcpTimeouts = []time.Duration{250 * time.Millisecond, 500 * time.Millisecond, time.Second}
+
+	errPCPNoGateway = errors.New("no PCP gateway")
+	errPCPNoMapping = errors.New("no PCP mapping established")
+)
+
+var pcpResultNames = map[byte]string{
+	1:  "unsupported version",
+	2:  "not authorized",
+	3:  "malformed request",
+	4:  "unsupported opcode",
+	5:  "unsupported option",
+	6:  "malformed option",
+	7:  "network failure",
+	8:  "no resources",
+	9:  "unsupported protocol",
+	10: "user exceeded quota",
+	11: "cannot provide external",
+	12: "address mismatch",
+	13: "excessive remote peers",
+}
+
+// pcpError is a non-success result code returned by a PCP server.
+type pcpError byte
+
+func (e pcpError) Error() string {
+	if name, ok := pcpResultNames[byte(e)]; ok {
+		return "PCP error: " + name
+	}
+	return fmt.Sprintf("PCP error %d", byte(e))
+}
+
+// pcpMapping is a mapping established on a gateway.
+type pcpMapping struct {
+	nonce    [12]byte
+	protocol string
+	intport  int
+	extport  int // suggested external port
+	lifetime time.Duration
+
+	external Endpoint    // endpoint assigned by the gateway
+	renew    *time.Timer // renews the mapping if its lease is shorter than requested
+}
+
+// pcpGateway is a PCP server reachable over one IP family.
+type pcpGateway struct {
+	addr *net.UDPAddr
+
+	// epoch state of the server, used to detect restarts
+	epoch     uint32
+	epochTime time.Time
+	mappings  map[string]*pcpMapping
+}
+
+// pcp implements Interface using PCP. It maps ports on an IPv4 gateway and on
+// an IPv6 gateway, if present.
+type pcp struct {
+	mu       sync.Mutex
+	gateways []*pcpGateway
+}
+
+// PCP returns a port mapper that uses PCP. The provided gateway address should
+// be the IP of your router or firewall. If the given address is nil, PCP will
+// attempt to discover PCP servers for both IPv4 and IPv6.
+func PCP(gateway net.IP) Interface {
+	if gateway != nil {
+		return newPCP(&net.UDPAddr{IP: gateway, Port: pcpPort})
+	}
+	return startautodisc("PCP", discoverPCP)
+}
+
+func newPCP(servers ...*net.UDPAddr) *pcp {
+	n := new(pcp)
+	for _, addr := range servers {
+		n.gateways = append(n.gateways, &pcpGateway{
+			addr:     addr,
+			mappings: make(map[string]*pcpMapping),
+		})
+	}
+	return n
+}
+
+func (n *pcp) String() string {
+	var gws []string
+	for _, gw := range n.gateways {
+		gws = append(gws, gw.addr.String())
+	}
+	return fmt.Sprintf("PCP(%s)", strings.Join(gws, ","))
+}
+
+// ExternalIP returns the external address assigned to the latest mapping,
+// preferring IPv4. PCP has no dedicated request for it, so it fails until
+// a mapping was established.
+func (n *pcp) ExternalIP() (net.IP, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for _, gw := range n.gateways {
+		for _, m := range gw.mappings {
+			if m.external.IP != nil {
+				return m.external.IP, nil
+			}
+		}
+	}
+	return nil, errPCPNoMapping
+}
+
+// ExternalEndpoints implements EndpointMapper.
+func (n *pcp) ExternalEndpoints(protocol string, intport int) []Endpoint {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var eps []Endpoint
+	for _, gw := range n.gateways {
+		if m := gw.mappings[pcpMappingKey(protocol, intport)]; m != nil && m.external.IP != nil {
+			eps = append(eps, m.external)
+		}
+	}
+	return eps
+}
+
+func (n *pcp) AddMapping(protocol string, extport, intport int, name string, lifetime time.Duration) (uint16, error) {
+	if lifetime <= 0 {
+		return 0, fmt.Errorf("lifetime must not be <= 0")
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var (
+		port    uint16
+		lastErr = errPCPNoGateway
+	)
+	for _, gw := range n.gateways {
+		key := pcpMappingKey(protocol, intport)
+		m := gw.mappings[key]
+		if m == nil {
+			m = &pcpMapping{protocol: protocol, intport: intport}
+			rand.Read(m.nonce[:])
+		}
+		m.extport, m.lifetime = extport, lifetime
+		if err := n.mapLocked(gw, m); err != nil {
+			lastErr = err
+			continue
+		}
+		gw.mappings[key] = m
+		if port == 0 {
+			port = uint16(m.external.Port)
+		}
+	}
+	if port == 0 {
+		return 0, lastErr
+	}
+	return port, nil
+}
+
+func (n *pcp) DeleteMapping(protocol string, extport, intport int) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var lastErr error
+	for _, gw := range n.gateways {
+		key := pcpMappingKey(protocol, intport)
+		m := gw.mappings[key]
+		if m == nil {
+			continue
+		}
+		delete(gw.mappings, key)
+		if m.renew != nil {
+			m.renew.Stop()
+		}
+		// A mapping is deleted by requesting a zero lifetime.
+		m.lifetime = 0
+		if err := n.mapLocked(gw, m); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// mapLocked sends a MAP request for the mapping and records the result.
+func (n *pcp) mapLocked(gw *pcpGateway, m *pcpMapping) error {
+	resp, err := pcpRequest(gw.addr, pcpOpMap, m.lifetime, encodePCPMap(m))
+	if err != nil {
+		return err
+	}
+	if len(resp.data) < pcpMapSize || !bytes.Equal(resp.data[:12], m.nonce[:]) {
+		return errors.New("PCP response for wrong mapping")
+	}
+	restarted := n.checkEpochLocked(gw, resp.epoch)
+	if m.lifetime == 0 {
+		return nil
+	}
+	m.external = Endpoint{
+		IP:   decodePCPAddr(resp.data[20:36]),
+		Port: int(binary.BigEndian.Uint16(resp.data[18:20])),
+	}
+	// Renew the mapping at half of the granted lease if it's shorter than
+	// requested, so it lives until the caller refreshes it.
+	if m.renew != nil {
+		m.renew.Stop()
+		m.renew = nil
+	}
+	if granted := time.Duration(resp.lifetime) * time.Second; granted > 0 && granted < m.lifetime {
+		m.renew = time.AfterFunc(granted/2, func() { n.renew(gw, m) })
+	}
+	if restarted {
+		// The server lost its state, recreate the other mappings.
+		for _, other := range gw.mappings {
+			if other != m {
+				if err := n.mapLocked(gw, other); err != nil {
+					log.Debug("Couldn't recreate PCP mapping", "proto", other.protocol, "intport", other.intport, "err", err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// renew refreshes a mapping whose lease ends before its requested lifetime.
+func (n *pcp) renew(gw *pcpGateway, m *pcpMapping) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if gw.mappings[pcpMappingKey(m.protocol, m.intport)] != m {
+		return // deleted in the meantime
+	}
+	if err := n.mapLocked(gw, m); err != nil {
+		log.Debug("Couldn't renew PCP mapping", "proto", m.protocol, "intport", m.intport, "err", err)
+	}
+}
+
+// checkEpochLocked validates the epoch time reported by the server. It returns
+// true if the server restarted and lost its mappings.
+func (n *pcp) checkEpochLocked(gw *pcpGateway, epoch uint32) bool {
+	now := time.Now()
+	defer func() { gw.epoch, gw.epochTime = epoch, now }()
+
+	if gw.epochTime.IsZero() {
+		return false
+	}
+	elapsed := now.Sub(gw.epochTime)
+	expected := time.Duration(gw.epoch)*time.Second + elapsed
+	actual := time.Duration(epoch) * time.Second
+	return epoch < gw.epoch || actual+pcpEpochTolerance < expected-elapsed/8
+}
+
+func pcpMappingKey(protocol string, intport int) string {
+	return fmt.Sprintf("%s:%d", strings.ToUpper(protocol), intport)
+}
+
+// pcpResult is a decoded PCP response.
+type pcpResult struct {
+	lifetime uint32
+	epoch    uint32
+	data     []byte // opcode-specific data
+}
+
+// pcpRequest sends a request to the server and waits for the response.
+func pcpRequest(server *net.UDPAddr, opcode byte, lifetime time.Duration, data []byte) (*pcpResult, error) {
+	conn, err := net.DialUDP("udp", nil, server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	req := make([]byte, pcpHeaderSize, pcpHeaderSize+len(data))
+	req[0] = pcpVersion
+	req[1] = opcode
+	binary.BigEndian.PutUint32(req[4:8], uint32(lifetime/time.Second))
+	copy(req[8:24], conn.LocalAddr().(*net.UDPAddr).IP.To16())
+	req = append(req, data...)
+
+	buf := make([]byte, pcpMaxRespSize)
+	for _, timeout := range pcpTimeouts {
+		if _, err := conn.Write(req); err != nil {
+			return nil, err
+		}
+		conn.SetReadDeadline(time.Now().Add(timeout))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+					break // retransmit
+				}
+				return nil, err
+			}
+			if n < pcpHeaderSize || buf[1] != opcode|pcpResponse {
+				continue // not a response to this request
+			}
+			if buf[0] != pcpVersion {
+				return nil, pcpError(1)
+			}
+			if buf[3] != pcpResultSuccess {
+				return nil, pcpError(buf[3])
+			}
+			return &pcpResult{
+				lifetime: binary.BigEndian.Uint32(buf[4:8]),
+				epoch:    binary.BigEndian.Uint32(buf[8:12]),
+				data:     append([]byte(nil), buf[pcpHeaderSize:n]...),
+			}, nil
+		}
+	}
+	return nil, errors.New("PCP request timed out")
+}
+
+// encodePCPMap encodes the opcode-specific data of a MAP request.
+func encodePCPMap(m *pcpMapping) []byte {
+	data := make([]byte, pcpMapSize)
+	copy(data[:12], m.nonce[:])
+	if strings.EqualFold(m.protocol, "TCP") {
+		data[12] = 6
+	} else {
+		data[12] = 17
+	}
+	binary.BigEndian.PutUint16(data[16:18], uint16(m.intport))
+	binary.BigEndian.PutUint16(data[18:20], uint16(m.extport))
+	// Leaving the suggested external address unspecified lets the server pick it.
+	return data
+}
+
+// decodePCPAddr decodes a 16-byte address field, which holds IPv4 addresses
+// in IPv4-mapped form.
+func decodePCPAddr(b []byte) net.IP {
+	ip := net.IP(append([]byte(nil), b...))
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4
+	}
+	return ip
+}
+
+// discoverPCP looks for PCP servers on the IPv4 gateways and the IPv6 anycast
+// address, returning a mapper using the first responding server of each family.
+func discoverPCP() Interface {
+	var (
+		v4  = append(potentialGateways(), pcpAnycast4)
+		v6  = []net.IP{pcpAnycast6}
+		gws []*net.UDPAddr
+	)
+	for _, candidates := range [][]net.IP{v4, v6} {
+		if gw := discoverPCPGateway(candidates); gw != nil {
+			gws = append(gws, &net.UDPAddr{IP: gw, Port: pcpPort})
+		}
+	}
+	if len(gws) == 0 {
+		return nil
+	}
+	return newPCP(gws...)
+}
+
+// discoverPCPGateway sends an ANNOUNCE request to all candidates and returns
+// the first one responding.
+func discoverPCPGateway(candidates []net.IP) net.IP {
+	found := make(chan net.IP, len(candidates))
+	for i := range candidates {
+		ip := candidates[i]
+		go func() {
+			if _, err := pcpRequest(&net.UDPAddr{IP: ip, Port: pcpPort}, pcpOpAnnounce, 0, nil); err != nil {
+				found <- nil
+			} else {
+				found <- ip
+			}
+		}()
+	}
+	timeout := time.NewTimer(2 * time.Second)
+	defer timeout.Stop()
+	for range candidates {
+		select {
+		case ip := <-found:
+			if ip != nil {
+				return ip
+			}
+		case <-timeout.C:
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package nat
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakePCPRequest is a MAP request received by fakePCP.
+type fakePCPRequest struct {
+	lifetime uint32
+	nonce    string
+	protocol byte
+	intport  uint16
+}
+
+// fakePCP is a PCP server answering MAP requests.
+type fakePCP struct {
+	conn     *net.UDPConn
+	external net.IP
+	requests chan fakePCPRequest
+
+	mu     sync.Mutex
+	lease  uint32 // maximum lifetime granted
+	epoch  uint32
+	result byte
+}
+
+func newFakePCP(t *testing.T, network, addr string, external net.IP) *fakePCP {
+	conn, err := net.ListenUDP(network, &net.UDPAddr{IP: net.ParseIP(addr)})
+	if err != nil {
+		t.Skipf("can't listen on %s: %v", addr, err)
+	}
+	s := &fakePCP{
+		conn:     conn,
+		external: external,
+		requests: make(chan fakePCPRequest, 20),
+		lease:    3600,
+		epoch:    1000,
+	}
+	t.Cleanup(func() { conn.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *fakePCP) addr() *net.UDPAddr {
+	return s.conn.LocalAddr().(*net.UDPAddr)
+}
+
+func (s *fakePCP) set(fn func(*fakePCP)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s)
+}
+
+func (s *fakePCP) serve() {
+	buf := make([]byte, pcpMaxRespSize)
+	for {
+		n, from, err := s.conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		if n != pcpHeaderSize+pcpMapSize || buf[0] != pcpVersion || buf[1] != pcpOpMap {
+			continue
+		}
+		req := buf[:n]
+		data := req[pcpHeaderSize:]
+		r := fakePCPRequest{
+			lifetime: binary.BigEndian.Uint32(req[4:8]),
+			nonce:    string(data[:12]),
+			protocol: data[12],
+			intport:  binary.BigEndian.Uint16(data[16:18]),
+		}
+		s.requests <- r
+
+		s.mu.Lock()
+		lifetime := r.lifetime
+		if lifetime > s.lease {
+			lifetime = s.lease
+		}
+		resp := make([]byte, pcpHeaderSize+pcpMapSize)
+		resp[0] = pcpVersion
+		resp[1] = pcpOpMap | pcpResponse
+		resp[3] = s.result
+		binary.BigEndian.PutUint32(resp[4:8], lifetime)
+		binary.BigEndian.PutUint32(resp[8:12], s.epoch)
+		copy(resp[pcpHeaderSize:], data[:20])
+		binary.BigEndian.PutUint16(resp[pcpHeaderSize+18:], r.intport+1000)
+		copy(resp[pcpHeaderSize+20:], s.external.To16())
+		s.mu.Unlock()
+
+		s.conn.WriteToUDP(resp, from)
+	}
+}
+
+func (s *fakePCP) nextRequest(t *testing.T, timeout time.Duration) fakePCPRequest {
+	t.Helper()
+	select {
+	case r := <-s.requests:
+		return r
+	case <-time.After(timeout):
+		t.Fatal("timed out waiting for PCP request")
+		return fakePCPRequest{}
+	}
+}
+
+func TestPCPMapping(t *testing.T) {
+	var (
+		ext4 = net.IP{203, 0, 113, 5}
+		ext6 = net.ParseIP("2001:db8::5")
+		s4   = newFakePCP(t, "udp4", "127.0.0.1", ext4)
+		s6   = newFakePCP(t, "udp6", "::1", ext6)
+		n    = newPCP(s4.addr(), s6.addr())
+	)
+	if _, err := n.ExternalIP(); err != errPCPNoMapping {
+		t.Fatalf("wrong error before mapping: %v", err)
+	}
+	port, err := n.AddMapping("TCP", 30303, 30303, "test", 10*time.Minute)
+	if err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	if port != 31303 {
+		t.Fatalf("wrong external port %d", port)
+	}
+	req4, req6 := s4.nextRequest(t, time.Second), s6.nextRequest(t, time.Second)
+	for _, req := range []fakePCPRequest{req4, req6} {
+		if req.lifetime != 600 || req.protocol != 6 || req.intport != 30303 {
+			t.Fatalf("wrong MAP request %+v", req)
+		}
+	}
+
+	ip, err := n.ExternalIP()
+	if err != nil || !ip.Equal(ext4) {
+		t.Fatalf("wrong external IP %v, err %v", ip, err)
+	}
+	eps := n.ExternalEndpoints("tcp", 30303)
+	if len(eps) != 2 || !eps[0].IP.Equal(ext4) || !eps[1].IP.Equal(ext6) || eps[1].Port != 31303 {
+		t.Fatalf("wrong external endpoints %v", eps)
+	}
+	if eps := n.ExternalEndpoints("UDP", 30303); len(eps) != 0 {
+		t.Fatalf("unexpected UDP endpoints %v", eps)
+	}
+
+	// Deleting uses the nonce of the mapping and a zero lifetime.
+	if err := n.DeleteMapping("TCP", 30303, 30303); err != nil {
+		t.Fatal("DeleteMapping failed:", err)
+	}
+	del4 := s4.nextRequest(t, time.Second)
+	if del4.lifetime != 0 || del4.nonce != req4.nonce {
+		t.Fatalf("wrong delete request %+v", del4)
+	}
+	if del6 := s6.nextRequest(t, time.Second); del6.lifetime != 0 || del6.nonce != req6.nonce {
+		t.Fatalf("wrong delete request %+v", del6)
+	}
+	if eps := n.ExternalEndpoints("TCP", 30303); len(eps) != 0 {
+		t.Fatalf("endpoints left after delete: %v", eps)
+	}
+}
+
+func TestPCPRenew(t *testing.T) {
+	s := newFakePCP(t, "udp4", "127.0.0.1", net.IP{203, 0, 113, 5})
+	s.set(func(s *fakePCP) { s.lease = 2 })
+	n := newPCP(s.addr())
+	defer n.DeleteMapping("UDP", 30303, 30303)
+
+	if _, err := n.AddMapping("UDP", 30303, 30303, "test", 10*time.Minute); err != nil {
+		t.Fatal("AddMapping failed:", err)
+	}
+	first := s.nextRequest(t, time.Second)
+	if first.protocol != 17 {
+		t.Fatalf("wrong protocol %d", first.protocol)
+	}
+	// The lease is shorter than requested, so the mapping is renewed after half of it.
+	renewed := s.nextRequest(t, 2*time.Second)
+	if renewed.nonce != first.nonce || renewed.lifetime != 600 {
+		t.Fatalf("wrong renewal request %+v", renewed)
+	}
+}
+
+func TestPCPServerRestart(t *testing.T) {
+	s := newFakePCP(t, "udp4", "127.0.0.1", net.IP{203, 0, 113, 5})
+	n := newPCP(s.addr())
+	if _, err := n.AddMapping("TCP", 30303, 30303, "test", 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	tcp := s.nextRequest(t, time.Second)
+
+	// When the server epoch goes backwards, the other mappings are recreated.
+	s.set(func(s *fakePCP) { s.epoch = 1 })
+	if _, err := n.AddMapping("UDP", 30303, 30303, "test", 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	s.nextRequest(t, time.Second)
+	if r := s.nextRequest(t, time.Second); r.nonce != tcp.nonce {
+		t.Fatalf("TCP mapping not recreated, got request %+v", r)
+	}
+}
+
+func TestPCPError(t *testing.T) {
+	s := newFakePCP(t, "udp4", "127.0.0.1", net.IP{203, 0, 113, 5})
+	s.set(func(s *fakePCP) { s.result = 2 })
+	n := newPCP(s.addr())
+
+	_, err := n.AddMapping("TCP", 30303, 30303, "test", 10*time.Minute)
+	var perr pcpError
+	if !errors.As(err, &perr) || perr != 2 {
+		t.Fatalf("wrong error %v", err)
+	}
+	if eps := n.ExternalEndpoints("TCP", 30303); len(eps) != 0 {
+		t.Fatalf("unexpected endpoints %v", eps)
+	}
+}
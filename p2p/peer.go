@@ -114,6 +114,9 @@ type Peer struct {
 	closed   chan struct{}
 	disc     chan DiscReason
 
+	// reputation receives the penalties of the peer if set
+	reputation *reputation
+
 	// events receives message send / receive events if set
 	events   *event.Feed
 	testPipe *MsgPipeRW // for testing
@@ -213,6 +216,19 @@ func (p *Peer) Disconnect(reason DiscReason) {
 	}
 }
 
+// Penalize lowers the reputation of the peer by the given number of points.
+// Peers whose reputation drops below the server's threshold are banned and
+// disconnected. Trusted and static peers are never penalized.
+func (p *Peer) Penalize(points int, reason string) {
+	if p.reputation == nil || p.rw.is(trustedConn|staticDialedConn) {
+		return
+	}
+	if p.reputation.penalize(p.ID(), points, reason) {
+		p.log.Debug("Disconnecting banned peer", "reason", reason)
+		p.Disconnect(DiscUselessPeer)
+	}
+}
+
 // String implements fmt.Stringer.
 func (p *Peer) String() string {
 	id := p.ID()
@@ -443,6 +459,8 @@ type protoRW struct {
 	werr   chan<- error    // for write results
 	offset uint64
 	w      MsgWriter
+
+	limiter *protoLimiter // bandwidth limits, nil if unlimited
 }
 
 func (rw *protoRW) WriteMsg(msg Msg) (err error) {
@@ -454,6 +472,11 @@ func (rw *protoRW) WriteMsg(msg Msg) (err error) {
 
 	msg.Code += rw.offset
 
+	if rw.limiter != nil {
+		if err := rw.limiter.wait(dirEgress, int(msg.Size), rw.closed); err != nil {
+			return err
+		}
+	}
 	select {
 	case <-rw.wstart:
 		err = rw.w.WriteMsg(msg)
@@ -472,6 +495,12 @@ func (rw *protoRW) ReadMsg() (Msg, error) {
 	select {
 	case msg := <-rw.in:
 		msg.Code -= rw.offset
+		if rw.limiter != nil {
+			if err := rw.limiter.wait(dirIngress, int(msg.Size), rw.closed); err != nil {
+				msg.Discard()
+				return Msg{}, io.EOF
+			}
+		}
 		return msg, nil
 	case <-rw.closed:
 		return Msg{}, io.EOF
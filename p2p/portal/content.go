@@ -0,0 +1,242 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// History network content types, the first byte of a content key.
+const (
+	BlockHeaderType = 0x00
+	BlockBodyType   = 0x01
+	ReceiptsType    = 0x02
+)
+
+var (
+	errInvalidContentKey = errors.New("invalid content key")
+	errContentMismatch   = errors.New("content doesn't match its key")
+)
+
+// ContentID is the location of content in the network's keyspace.
+type ContentID [32]byte
+
+// ContentKey is the key of history network content.
+type ContentKey []byte
+
+// HeaderKey returns the content key of a block header.
+func HeaderKey(hash common.Hash) ContentKey {
+	return append(ContentKey{BlockHeaderType}, hash[:]...)
+}
+
+// BodyKey returns the content key of a block body.
+func BodyKey(hash common.Hash) ContentKey {
+	return append(ContentKey{BlockBodyType}, hash[:]...)
+}
+
+// ReceiptsKey returns the content key of the receipts of a block.
+func ReceiptsKey(hash common.Hash) ContentKey {
+	return append(ContentKey{ReceiptsType}, hash[:]...)
+}
+
+// ID returns the content ID of the key.
+func (k ContentKey) ID() ContentID {
+	return sha256.Sum256(k)
+}
+
+// decode returns the content type and block hash of the key.
+func (k ContentKey) decode() (byte, common.Hash, error) {
+	if len(k) != 1+common.HashLength || k[0] > ReceiptsType {
+		return 0, common.Hash{}, errInvalidContentKey
+	}
+	return k[0], common.BytesToHash(k[1:]), nil
+}
+
+func (k ContentKey) String() string {
+	typ, hash, err := k.decode()
+	if err != nil {
+		return fmt.Sprintf("%x", []byte(k))
+	}
+	return fmt.Sprintf("%s(%x)", [...]string{"header", "body", "receipts"}[typ], hash[:8])
+}
+
+// distance returns the XOR distance of a node and a content ID.
+func distance(a, b [32]byte) *uint256.Int {
+	var d [32]byte
+	for i := range d {
+		d[i] = a[i] ^ b[i]
+	}
+	return new(uint256.Int).SetBytes(d[:])
+}
+
+// EncodeHeader encodes a block header as history network content. The header
+// is not accompanied by a proof of canonicality.
+func EncodeHeader(header *types.Header) ([]byte, error) {
+	enc, err := rlp.EncodeToBytes(header)
+	if err != nil {
+		return nil, err
+	}
+	var w sszWriter
+	w.variable(enc)
+	w.variable([]byte{0}) // proof union: None
+	return w.bytes(), nil
+}
+
+// DecodeHeader decodes header content and checks that it has the given hash.
+func DecodeHeader(content []byte, hash common.Hash) (*types.Header, error) {
+	r := newSSZReader(content)
+	r.variable()
+	r.variable()
+	fields, err := r.vars()
+	if err != nil {
+		return nil, err
+	}
+	if crypto.Keccak256Hash(fields[0]) != hash {
+		return nil, errContentMismatch
+	}
+	header := new(types.Header)
+	if err := rlp.DecodeBytes(fields[0], header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// EncodeBody encodes a block body as history network content. The withdrawals
+// are included for blocks after Shanghai.
+func EncodeBody(body *types.Body, shanghai bool) ([]byte, error) {
+	txs := make([][]byte, len(body.Transactions))
+	for i, tx := range body.Transactions {
+		enc, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		txs[i] = enc
+	}
+	uncles, err := rlp.EncodeToBytes(body.Uncles)
+	if err != nil {
+		return nil, err
+	}
+	var w sszWriter
+	w.variable(encodeByteLists(txs))
+	w.variable(uncles)
+	if shanghai {
+		ws := make([][]byte, len(body.Withdrawals))
+		for i, wd := range body.Withdrawals {
+			if ws[i], err = rlp.EncodeToBytes(wd); err != nil {
+				return nil, err
+			}
+		}
+		w.variable(encodeByteLists(ws))
+	}
+	return w.bytes(), nil
+}
+
+// DecodeBody decodes body content and validates it against the header.
+func DecodeBody(content []byte, header *types.Header) (*types.Body, error) {
+	shanghai := header.WithdrawalsHash != nil
+	r := newSSZReader(content)
+	r.variable()
+	r.variable()
+	if shanghai {
+		r.variable()
+	}
+	fields, err := r.vars()
+	if err != nil {
+		return nil, err
+	}
+	txs, err := decodeByteLists(fields[0])
+	if err != nil {
+		return nil, err
+	}
+	body := &types.Body{Transactions: make([]*types.Transaction, len(txs))}
+	for i, enc := range txs {
+		body.Transactions[i] = new(types.Transaction)
+		if err := body.Transactions[i].UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+		}
+	}
+	if err := rlp.DecodeBytes(fields[1], &body.Uncles); err != nil {
+		return nil, fmt.Errorf("invalid uncles: %v", err)
+	}
+	if shanghai {
+		ws, err := decodeByteLists(fields[2])
+		if err != nil {
+			return nil, err
+		}
+		body.Withdrawals = make([]*types.Withdrawal, len(ws))
+		for i, enc := range ws {
+			body.Withdrawals[i] = new(types.Withdrawal)
+			if err := rlp.DecodeBytes(enc, body.Withdrawals[i]); err != nil {
+				return nil, fmt.Errorf("invalid withdrawal %d: %v", i, err)
+			}
+		}
+	}
+	// Validate the body against the header.
+	hasher := trie.NewStackTrie(nil)
+	if types.DeriveSha(types.Transactions(body.Transactions), hasher) != header.TxHash {
+		return nil, errContentMismatch
+	}
+	if types.CalcUncleHash(body.Uncles) != header.UncleHash {
+		return nil, errContentMismatch
+	}
+	if shanghai && types.DeriveSha(types.Withdrawals(body.Withdrawals), trie.NewStackTrie(nil)) != *header.WithdrawalsHash {
+		return nil, errContentMismatch
+	}
+	return body, nil
+}
+
+// EncodeReceipts encodes the receipts of a block as history network content.
+func EncodeReceipts(receipts types.Receipts) ([]byte, error) {
+	encs := make([][]byte, len(receipts))
+	for i, r := range receipts {
+		enc, err := r.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		encs[i] = enc
+	}
+	return encodeByteLists(encs), nil
+}
+
+// DecodeReceipts decodes receipts content and validates it against the header.
+// Only the consensus fields of the receipts are set.
+func DecodeReceipts(content []byte, header *types.Header) (types.Receipts, error) {
+	encs, err := decodeByteLists(content)
+	if err != nil {
+		return nil, err
+	}
+	receipts := make(types.Receipts, len(encs))
+	for i, enc := range encs {
+		receipts[i] = new(types.Receipt)
+		if err := receipts[i].UnmarshalBinary(enc); err != nil {
+			return nil, fmt.Errorf("invalid receipt %d: %v", i, err)
+		}
+	}
+	if types.DeriveSha(receipts, trie.NewStackTrie(nil)) != header.ReceiptHash {
+		return nil, errContentMismatch
+	}
+	return receipts, nil
+}
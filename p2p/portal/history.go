@@ -0,0 +1,659 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package portal implements the history network of the Portal Network, a
+// content-addressed overlay over discv5 TALKREQ which stores block headers,
+// bodies and receipts.
+package portal
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/holiman/uint256"
+	"golang.org/x/exp/slices"
+)
+
+// HistoryProtocol is the talk protocol identifier of the history network.
+const HistoryProtocol = "\x50\x0b"
+
+const (
+	lookupAlpha      = 3  // concurrent requests of a content lookup
+	maxLookupQueries = 32 // nodes asked by a content lookup
+	gossipNodes      = 4  // nodes offered new content
+
+	refreshInterval    = time.Minute
+	revalidateInterval = 10 * time.Second
+)
+
+var (
+	errTimeout         = errors.New("timeout")
+	errContentNotFound = errors.New("content not found")
+	errNoHeader        = errors.New("block header unknown")
+	errUnexpectedResp  = errors.New("unexpected response")
+)
+
+// Transport is the discv5 functionality used by the history network.
+// It is implemented by *discover.UDPv5.
+type Transport interface {
+	Self() *enode.Node
+	RegisterTalkHandler(protocol string, handler discover.TalkRequestHandler)
+	TalkRequest(n *enode.Node, protocol string, req []byte) ([]byte, error)
+	TalkRequestToID(id enode.ID, addr *net.UDPAddr, protocol string, req []byte) ([]byte, error)
+}
+
+// Config contains the settings of the history network.
+type Config struct {
+	// Bootnodes are used to join the network.
+	Bootnodes []*enode.Node
+
+	// StorageCapacity is the maximum size of stored contents in bytes. When the
+	// capacity is reached, the data radius of the node shrinks. Zero means unlimited.
+	StorageCapacity uint64
+
+	// Headers, if set, returns locally known headers. They are used to validate
+	// bodies and receipts without fetching the header from the network.
+	Headers func(hash common.Hash) *types.Header
+}
+
+// HistoryNetwork is a node of the portal history network.
+type HistoryNetwork struct {
+	transport Transport
+	config    Config
+	self      enode.ID
+	table     *table
+	store     *contentStore
+	utp       *utpSocket
+	log       log.Logger
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewHistoryNetwork creates a history network node. Contents are stored in db.
+func NewHistoryNetwork(transport Transport, db ethdb.KeyValueStore, config Config) *HistoryNetwork {
+	self := transport.Self().ID()
+	return &HistoryNetwork{
+		transport: transport,
+		config:    config,
+		self:      self,
+		table:     newTable(self),
+		store:     newContentStore(db, self, config.StorageCapacity),
+		utp:       newUTPSocket(transport),
+		log:       log.New("proto", "portal-history"),
+		quit:      make(chan struct{}),
+	}
+}
+
+// Start registers the protocol handlers and starts joining the network.
+func (h *HistoryNetwork) Start() {
+	h.transport.RegisterTalkHandler(HistoryProtocol, h.handleTalk)
+	h.transport.RegisterTalkHandler(utpProtocol, h.utp.handle)
+	h.wg.Add(1)
+	go h.loop()
+}
+
+// Stop terminates background activity.
+func (h *HistoryNetwork) Stop() {
+	close(h.quit)
+	h.wg.Wait()
+}
+
+// Radius returns the data radius of the local node.
+func (h *HistoryNetwork) Radius() *uint256.Int {
+	return h.store.Radius()
+}
+
+// Nodes returns the nodes in the routing table.
+func (h *HistoryNetwork) Nodes() []*enode.Node {
+	var nodes []*enode.Node
+	for _, n := range h.table.closest(h.self, nBuckets*bucketSize) {
+		nodes = append(nodes, n.Node)
+	}
+	return nodes
+}
+
+func (h *HistoryNetwork) loop() {
+	defer h.wg.Done()
+
+	var (
+		refresh    = time.NewTicker(refreshInterval)
+		revalidate = time.NewTicker(revalidateInterval)
+	)
+	defer refresh.Stop()
+	defer revalidate.Stop()
+
+	h.refresh()
+	for {
+		select {
+		case <-refresh.C:
+			h.refresh()
+		case <-revalidate.C:
+			if n := h.table.oldest(); n != nil {
+				h.Ping(n.Node)
+			}
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// refresh pings the bootnodes if the table is empty, and asks the closest nodes
+// for more nodes.
+func (h *HistoryNetwork) refresh() {
+	if h.table.len() == 0 {
+		for _, n := range h.config.Bootnodes {
+			h.Ping(n)
+		}
+	}
+	for _, n := range h.table.closest(h.self, lookupAlpha) {
+		nodes, err := h.FindNodes(n.Node, []uint16{256, 255, 254, 253})
+		if err != nil {
+			continue
+		}
+		for _, found := range nodes {
+			if found.ID() != h.self && h.table.get(found.ID()) == nil {
+				h.Ping(found)
+			}
+		}
+	}
+}
+
+// request sends a message to n and waits for the response.
+func (h *HistoryNetwork) request(n *enode.Node, msg Message) (Message, error) {
+	resp, err := h.transport.TalkRequest(n, HistoryProtocol, EncodeMessage(msg))
+	if err != nil {
+		h.table.remove(n.ID())
+		return nil, err
+	}
+	return DecodeMessage(resp)
+}
+
+// Ping checks that n is online and learns its data radius.
+func (h *HistoryNetwork) Ping(n *enode.Node) (*Pong, error) {
+	resp, err := h.request(n, &Ping{ENRSeq: h.transport.Self().Seq(), Radius: h.store.Radius()})
+	if err != nil {
+		return nil, err
+	}
+	pong, ok := resp.(*Pong)
+	if !ok {
+		return nil, errUnexpectedResp
+	}
+	if pong.ENRSeq > n.Seq() {
+		// The node has a newer record, fetch it.
+		if nodes, err := h.FindNodes(n, []uint16{0}); err == nil && len(nodes) == 1 {
+			n = nodes[0]
+		}
+	}
+	h.table.add(n, pong.Radius)
+	return pong, nil
+}
+
+// FindNodes asks n for nodes at the given log distances from n.
+func (h *HistoryNetwork) FindNodes(n *enode.Node, distances []uint16) ([]*enode.Node, error) {
+	resp, err := h.request(n, &FindNodes{Distances: distances})
+	if err != nil {
+		return nil, err
+	}
+	nodes, ok := resp.(*Nodes)
+	if !ok {
+		return nil, errUnexpectedResp
+	}
+	return verifyNodes(n.ID(), nodes.ENRs, distances), nil
+}
+
+// verifyNodes filters nodes which are not at the requested distances from the
+// responding node, or lack an endpoint.
+func verifyNodes(src enode.ID, nodes []*enode.Node, distances []uint16) []*enode.Node {
+	var verified []*enode.Node
+	for _, n := range nodes {
+		d := uint16(enode.LogDist(src, n.ID()))
+		if !slices.Contains(distances, d) || n.IP() == nil || n.UDP() == 0 {
+			continue
+		}
+		verified = append(verified, n)
+	}
+	return verified
+}
+
+// FindContent asks n for content. It returns either the content or nodes closer
+// to the content.
+func (h *HistoryNetwork) FindContent(n *enode.Node, key ContentKey) ([]byte, []*enode.Node, error) {
+	resp, err := h.request(n, &FindContent{ContentKey: key})
+	if err != nil {
+		return nil, nil, err
+	}
+	c, ok := resp.(*Content)
+	if !ok {
+		return nil, nil, errUnexpectedResp
+	}
+	switch {
+	case c.ConnectionID != nil:
+		content, err := h.utp.read(n, *c.ConnectionID)
+		return content, nil, err
+	case c.Content != nil:
+		return c.Content, nil, nil
+	default:
+		return nil, c.ENRs, nil
+	}
+}
+
+// Offer offers contents to n and transfers the contents accepted by it. It
+// returns the number of accepted contents.
+func (h *HistoryNetwork) Offer(n *enode.Node, keys []ContentKey, contents [][]byte) (int, error) {
+	if len(keys) != len(contents) {
+		return 0, fmt.Errorf("key/content count mismatch: %d != %d", len(keys), len(contents))
+	}
+	if len(keys) > maxOfferKeys {
+		return 0, fmt.Errorf("too many contents (%d > %d)", len(keys), maxOfferKeys)
+	}
+	req := &Offer{ContentKeys: make([][]byte, len(keys))}
+	for i, k := range keys {
+		req.ContentKeys[i] = k
+	}
+	resp, err := h.request(n, req)
+	if err != nil {
+		return 0, err
+	}
+	accept, ok := resp.(*Accept)
+	if !ok || len(accept.ContentKeys) != len(keys) {
+		return 0, errUnexpectedResp
+	}
+	var payload []byte
+	accepted := 0
+	for i, ok := range accept.ContentKeys {
+		if ok {
+			payload = binary.AppendUvarint(payload, uint64(len(contents[i])))
+			payload = append(payload, contents[i]...)
+			accepted++
+		}
+	}
+	if accepted == 0 {
+		return 0, nil
+	}
+	return accepted, h.utp.write(n, accept.ConnectionID, payload)
+}
+
+// Store adds content to the local store if it is within the data radius. The
+// content is not validated.
+func (h *HistoryNetwork) Store(key ContentKey, content []byte) bool {
+	return h.store.put(key, content)
+}
+
+// Gossip stores content and offers it to the closest nodes interested in it.
+// It returns the number of nodes which accepted the content.
+func (h *HistoryNetwork) Gossip(key ContentKey, content []byte) int {
+	h.store.put(key, content)
+
+	var (
+		id    = key.ID()
+		nodes []*tableNode
+	)
+	for _, n := range h.table.closest(id, bucketSize) {
+		if !distance(n.ID(), id).Gt(n.radius) {
+			nodes = append(nodes, n)
+		}
+		if len(nodes) == gossipNodes {
+			break
+		}
+	}
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		accepted int
+	)
+	for _, n := range nodes {
+		wg.Add(1)
+		go func(n *enode.Node) {
+			defer wg.Done()
+			count, err := h.Offer(n, []ContentKey{key}, [][]byte{content})
+			if err != nil {
+				h.log.Debug("Content offer failed", "id", n.ID(), "key", key, "err", err)
+				return
+			}
+			if count > 0 {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}(n.Node)
+	}
+	wg.Wait()
+	return accepted
+}
+
+// GetContent returns content from the local store or the network. Content from
+// the network is checked by validate and stored if it is within the radius. The
+// network lookup is aborted once the context is cancelled.
+func (h *HistoryNetwork) GetContent(ctx context.Context, key ContentKey, validate func([]byte) error) ([]byte, error) {
+	if content := h.store.get(key); content != nil {
+		return content, nil
+	}
+	content, err := h.lookupContent(ctx, key, validate)
+	if err != nil {
+		return nil, err
+	}
+	h.store.put(key, content)
+	return content, nil
+}
+
+// lookupContent performs an iterative lookup of content.
+func (h *HistoryNetwork) lookupContent(ctx context.Context, key ContentKey, validate func([]byte) error) ([]byte, error) {
+	type result struct {
+		n       *enode.Node
+		content []byte
+		nodes   []*enode.Node
+		err     error
+	}
+	var (
+		id      = key.ID()
+		seen    = map[enode.ID]bool{h.self: true}
+		queue   []*enode.Node
+		results = make(chan result, lookupAlpha)
+		pending int
+		asked   int
+	)
+	add := func(n *enode.Node) {
+		if seen[n.ID()] {
+			return
+		}
+		seen[n.ID()] = true
+		queue = append(queue, n)
+	}
+	for _, n := range h.table.closest(id, bucketSize) {
+		add(n.Node)
+	}
+	for {
+		slices.SortFunc(queue, func(a, b *enode.Node) bool {
+			return enode.DistCmp(enode.ID(id), a.ID(), b.ID()) < 0
+		})
+		for pending < lookupAlpha && len(queue) > 0 && asked < maxLookupQueries {
+			n := queue[0]
+			queue = queue[1:]
+			pending++
+			asked++
+			go func() {
+				content, nodes, err := h.FindContent(n, key)
+				results <- result{n, content, nodes, err}
+			}()
+		}
+		if pending == 0 {
+			return nil, errContentNotFound
+		}
+		select {
+		case r := <-results:
+			pending--
+			switch {
+			case r.err != nil:
+				h.log.Trace("FINDCONTENT failed", "id", r.n.ID(), "key", key, "err", r.err)
+			case r.content != nil:
+				if err := validate(r.content); err != nil {
+					h.log.Debug("Received invalid content", "id", r.n.ID(), "key", key, "err", err)
+					continue
+				}
+				return r.content, nil
+			default:
+				for _, n := range r.nodes {
+					add(n)
+				}
+			}
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-h.quit:
+			return nil, errContentNotFound
+		}
+	}
+}
+
+// localHeader returns a header from the local chain or the local store.
+func (h *HistoryNetwork) localHeader(hash common.Hash) (*types.Header, error) {
+	if h.config.Headers != nil {
+		if header := h.config.Headers(hash); header != nil {
+			return header, nil
+		}
+	}
+	if content := h.store.get(HeaderKey(hash)); content != nil {
+		return DecodeHeader(content, hash)
+	}
+	return nil, errNoHeader
+}
+
+// header returns a header from the local chain, the local store or the network.
+func (h *HistoryNetwork) header(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	if header, err := h.localHeader(hash); err == nil {
+		return header, nil
+	}
+	return h.BlockHeader(ctx, hash)
+}
+
+// BlockHeader retrieves a block header.
+func (h *HistoryNetwork) BlockHeader(ctx context.Context, hash common.Hash) (*types.Header, error) {
+	content, err := h.GetContent(ctx, HeaderKey(hash), func(c []byte) error {
+		_, err := DecodeHeader(c, hash)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeHeader(content, hash)
+}
+
+// BlockBody retrieves the body of a block.
+func (h *HistoryNetwork) BlockBody(ctx context.Context, hash common.Hash) (*types.Body, error) {
+	header, err := h.header(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	content, err := h.GetContent(ctx, BodyKey(hash), func(c []byte) error {
+		_, err := DecodeBody(c, header)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeBody(content, header)
+}
+
+// Receipts retrieves the receipts of a block. Only the consensus fields of the
+// receipts are set.
+func (h *HistoryNetwork) Receipts(ctx context.Context, hash common.Hash) (types.Receipts, error) {
+	header, err := h.header(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	content, err := h.GetContent(ctx, ReceiptsKey(hash), func(c []byte) error {
+		_, err := DecodeReceipts(c, header)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return DecodeReceipts(content, header)
+}
+
+// validate checks offered content. Bodies and receipts can only be validated if
+// the header is known locally.
+func (h *HistoryNetwork) validate(key ContentKey, content []byte) error {
+	typ, hash, err := key.decode()
+	if err != nil {
+		return err
+	}
+	if typ == BlockHeaderType {
+		_, err := DecodeHeader(content, hash)
+		return err
+	}
+	header, err := h.localHeader(hash)
+	if err != nil {
+		return err
+	}
+	if typ == BlockBodyType {
+		_, err = DecodeBody(content, header)
+	} else {
+		_, err = DecodeReceipts(content, header)
+	}
+	return err
+}
+
+// handleTalk is the talk handler of the history protocol.
+func (h *HistoryNetwork) handleTalk(id enode.ID, addr *net.UDPAddr, req []byte) []byte {
+	msg, err := DecodeMessage(req)
+	if err != nil {
+		h.log.Trace("Invalid portal message", "id", id, "addr", addr, "err", err)
+		return nil
+	}
+	var resp Message
+	switch msg := msg.(type) {
+	case *Ping:
+		resp = h.handlePing(id, addr, msg)
+	case *FindNodes:
+		resp = h.handleFindNodes(msg)
+	case *FindContent:
+		resp = h.handleFindContent(id, msg)
+	case *Offer:
+		resp = h.handleOffer(id, msg)
+	default:
+		return nil
+	}
+	return EncodeMessage(resp)
+}
+
+func (h *HistoryNetwork) handlePing(id enode.ID, addr *net.UDPAddr, msg *Ping) Message {
+	if n := h.table.get(id); n != nil && n.Seq() >= msg.ENRSeq {
+		h.table.add(n.Node, msg.Radius)
+	} else {
+		// The record of the node is unknown or outdated. Talk handlers must
+		// respond quickly, so fetch it in the background.
+		go h.learn(id, addr, msg.Radius)
+	}
+	return &Pong{ENRSeq: h.transport.Self().Seq(), Radius: h.store.Radius()}
+}
+
+// learn fetches the record of a node which pinged us and adds it to the table.
+func (h *HistoryNetwork) learn(id enode.ID, addr *net.UDPAddr, radius *uint256.Int) {
+	resp, err := h.transport.TalkRequestToID(id, addr, HistoryProtocol, EncodeMessage(&FindNodes{Distances: []uint16{0}}))
+	if err != nil {
+		return
+	}
+	msg, err := DecodeMessage(resp)
+	if err != nil {
+		return
+	}
+	if nodes, ok := msg.(*Nodes); ok {
+		if nodes := verifyNodes(id, nodes.ENRs, []uint16{0}); len(nodes) == 1 {
+			h.table.add(nodes[0], radius)
+		}
+	}
+}
+
+func (h *HistoryNetwork) handleFindNodes(msg *FindNodes) Message {
+	var nodes []*enode.Node
+	if slices.Contains(msg.Distances, 0) {
+		nodes = append(nodes, h.transport.Self())
+	}
+	nodes = append(nodes, h.table.atDistances(msg.Distances, bucketSize)...)
+	return &Nodes{Total: 1, ENRs: packNodes(nodes)}
+}
+
+func (h *HistoryNetwork) handleFindContent(id enode.ID, msg *FindContent) Message {
+	key := ContentKey(msg.ContentKey)
+	if content := h.store.get(key); content != nil {
+		if len(content) <= maxPayloadSize {
+			return &Content{Content: content}
+		}
+		conn := h.utp.serve(id, content)
+		return &Content{ConnectionID: &conn}
+	}
+	var nodes []*enode.Node
+	for _, n := range h.table.closest(key.ID(), bucketSize) {
+		if n.ID() != id {
+			nodes = append(nodes, n.Node)
+		}
+	}
+	return &Content{ENRs: packNodes(nodes)}
+}
+
+func (h *HistoryNetwork) handleOffer(id enode.ID, msg *Offer) Message {
+	var (
+		accept = &Accept{ContentKeys: make([]bool, len(msg.ContentKeys))}
+		keys   []ContentKey
+	)
+	for i, k := range msg.ContentKeys {
+		key := ContentKey(k)
+		if _, _, err := key.decode(); err != nil {
+			continue
+		}
+		if cid := key.ID(); h.store.inRadius(cid) && !h.store.has(cid) {
+			accept.ContentKeys[i] = true
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) > 0 {
+		conn, in, err := h.utp.accept(id)
+		if err != nil {
+			h.log.Debug("Declining offer", "id", id, "err", err)
+			return &Accept{ContentKeys: make([]bool, len(msg.ContentKeys))}
+		}
+		accept.ConnectionID = conn
+		go h.receiveOffer(id, in, keys)
+	}
+	return accept
+}
+
+// receiveOffer waits for the accepted contents of an offer and stores them.
+func (h *HistoryNetwork) receiveOffer(id enode.ID, in *utpConn, keys []ContentKey) {
+	payload, err := in.wait()
+	if err != nil {
+		h.log.Debug("Offered content not received", "id", id, "err", err)
+		return
+	}
+	for _, key := range keys {
+		size, n := binary.Uvarint(payload)
+		if n <= 0 || uint64(len(payload)-n) < size {
+			h.log.Debug("Invalid offer payload", "id", id)
+			return
+		}
+		content := payload[n : n+int(size)]
+		payload = payload[n+int(size):]
+		if err := h.validate(key, content); err != nil {
+			h.log.Debug("Dropping offered content", "id", id, "key", key, "err", err)
+			continue
+		}
+		h.store.put(key, content)
+	}
+}
+
+// packNodes returns the prefix of nodes whose records fit into a message.
+func packNodes(nodes []*enode.Node) []*enode.Node {
+	size := 0
+	for i, n := range nodes {
+		size += len(encodeENRs([]*enode.Node{n}))
+		if size > maxPayloadSize-16 {
+			return nodes[:i]
+		}
+	}
+	return nodes
+}
@@ -0,0 +1,347 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/big"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/discover"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/trie"
+	"github.com/holiman/uint256"
+)
+
+// testNetwork connects test transports in memory.
+type testNetwork struct {
+	mu    sync.Mutex
+	nodes map[enode.ID]*testTransport
+}
+
+type testTransport struct {
+	net      *testNetwork
+	self     *enode.Node
+	mu       sync.Mutex
+	handlers map[string]discover.TalkRequestHandler
+	drop     func(protocol string, req []byte) bool // drops outgoing requests if set
+}
+
+func newTestNetwork() *testNetwork {
+	return &testNetwork{nodes: make(map[enode.ID]*testTransport)}
+}
+
+func (tn *testNetwork) newTransport(t *testing.T, port int) *testTransport {
+	tt := &testTransport{
+		net:      tn,
+		self:     newTestKeyNode(t, port).Node(),
+		handlers: make(map[string]discover.TalkRequestHandler),
+	}
+	tn.mu.Lock()
+	tn.nodes[tt.self.ID()] = tt
+	tn.mu.Unlock()
+	return tt
+}
+
+func (tt *testTransport) Self() *enode.Node { return tt.self }
+
+func (tt *testTransport) RegisterTalkHandler(protocol string, handler discover.TalkRequestHandler) {
+	tt.mu.Lock()
+	tt.handlers[protocol] = handler
+	tt.mu.Unlock()
+}
+
+func (tt *testTransport) TalkRequest(n *enode.Node, protocol string, req []byte) ([]byte, error) {
+	return tt.TalkRequestToID(n.ID(), &net.UDPAddr{IP: n.IP(), Port: n.UDP()}, protocol, req)
+}
+
+func (tt *testTransport) TalkRequestToID(id enode.ID, addr *net.UDPAddr, protocol string, req []byte) ([]byte, error) {
+	tt.net.mu.Lock()
+	remote := tt.net.nodes[id]
+	tt.net.mu.Unlock()
+	if remote == nil {
+		return nil, errTimeout
+	}
+	tt.mu.Lock()
+	drop := tt.drop
+	tt.mu.Unlock()
+	if drop != nil && drop(protocol, req) {
+		return nil, errTimeout
+	}
+	remote.mu.Lock()
+	handler := remote.handlers[protocol]
+	remote.mu.Unlock()
+	if handler == nil {
+		return nil, nil
+	}
+	from := &net.UDPAddr{IP: tt.self.IP(), Port: tt.self.UDP()}
+	return handler(tt.self.ID(), from, append([]byte{}, req...)), nil
+}
+
+func newTestKeyNode(t *testing.T, port int) *enode.LocalNode {
+	db, err := enode.OpenDB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(db.Close)
+	key, _ := crypto.GenerateKey()
+	ln := enode.NewLocalNode(db, key)
+	ln.Set(enr.IPv4(net.IP{127, 0, 0, 1}))
+	ln.Set(enr.UDP(port))
+	return ln
+}
+
+// newTestHistory creates n history network nodes which know each other.
+func newTestHistory(t *testing.T, n int) []*HistoryNetwork {
+	var (
+		tn    = newTestNetwork()
+		nodes []*HistoryNetwork
+	)
+	for i := 0; i < n; i++ {
+		h := NewHistoryNetwork(tn.newTransport(t, 30000+i), rawdb.NewMemoryDatabase(), Config{})
+		h.transport.RegisterTalkHandler(HistoryProtocol, h.handleTalk)
+		h.transport.RegisterTalkHandler(utpProtocol, h.utp.handle)
+		nodes = append(nodes, h)
+	}
+	// Connect the nodes in a line, so lookups have to traverse the network.
+	for i := 1; i < n; i++ {
+		if _, err := nodes[i].Ping(nodes[i-1].transport.Self()); err != nil {
+			t.Fatal("ping failed:", err)
+		}
+	}
+	waitFor(t, func() bool { return nodes[0].table.len() > 0 })
+	return nodes
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met")
+}
+
+// testBlock creates a block with a body too large for a single message.
+func testBlock(t *testing.T) (*types.Header, *types.Body, types.Receipts) {
+	var (
+		key, _  = crypto.GenerateKey()
+		signer  = types.LatestSigner(params.TestChainConfig)
+		txs     types.Transactions
+		receipt types.Receipts
+	)
+	for i := 0; i < 4; i++ {
+		tx := types.MustSignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    uint64(i),
+			To:       &common.Address{1},
+			Gas:      100000,
+			GasPrice: big.NewInt(1),
+			Data:     bytes.Repeat([]byte{byte(i)}, 1000),
+		})
+		txs = append(txs, tx)
+		receipt = append(receipt, &types.Receipt{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			Logs:              []*types.Log{},
+		})
+	}
+	for _, r := range receipt {
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+	}
+	header := &types.Header{
+		Number:      big.NewInt(100),
+		Difficulty:  big.NewInt(1),
+		GasLimit:    30000000,
+		UncleHash:   types.EmptyUncleHash,
+		TxHash:      types.DeriveSha(txs, trie.NewStackTrie(nil)),
+		ReceiptHash: types.DeriveSha(receipt, trie.NewStackTrie(nil)),
+	}
+	return header, &types.Body{Transactions: txs}, receipt
+}
+
+func TestContentValidation(t *testing.T) {
+	header, body, receipts := testBlock(t)
+
+	enc, err := EncodeHeader(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h, err := DecodeHeader(enc, header.Hash()); err != nil || h.Hash() != header.Hash() {
+		t.Fatalf("header decode failed: %v", err)
+	}
+	if _, err := DecodeHeader(enc, common.Hash{1}); !errors.Is(err, errContentMismatch) {
+		t.Fatalf("wrong error for header mismatch: %v", err)
+	}
+
+	enc, err = EncodeBody(body, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, err := DecodeBody(enc, header); err != nil || len(b.Transactions) != len(body.Transactions) {
+		t.Fatalf("body decode failed: %v", err)
+	}
+	other := types.CopyHeader(header)
+	other.TxHash = common.Hash{1}
+	if _, err := DecodeBody(enc, other); !errors.Is(err, errContentMismatch) {
+		t.Fatalf("wrong error for body mismatch: %v", err)
+	}
+
+	enc, err = EncodeReceipts(receipts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, err := DecodeReceipts(enc, header); err != nil || len(r) != len(receipts) {
+		t.Fatalf("receipts decode failed: %v", err)
+	}
+	other.ReceiptHash = common.Hash{1}
+	if _, err := DecodeReceipts(enc, other); !errors.Is(err, errContentMismatch) {
+		t.Fatal("no error for receipts mismatch")
+	}
+}
+
+func TestHistoryFindContent(t *testing.T) {
+	var (
+		nodes                  = newTestHistory(t, 6)
+		header, body, receipts = testBlock(t)
+		hash                   = header.Hash()
+		source                 = nodes[0]
+		client                 = nodes[len(nodes)-1]
+	)
+	headerEnc, _ := EncodeHeader(header)
+	bodyEnc, _ := EncodeBody(body, false)
+	receiptsEnc, _ := EncodeReceipts(receipts)
+	if len(bodyEnc) <= maxPayloadSize {
+		t.Fatal("body should be streamed")
+	}
+	source.Store(HeaderKey(hash), headerEnc)
+	source.Store(BodyKey(hash), bodyEnc)
+	source.Store(ReceiptsKey(hash), receiptsEnc)
+
+	// The client only knows the next node, so the lookups have to walk the line.
+	h, err := client.BlockHeader(context.Background(), hash)
+	if err != nil {
+		t.Fatal("header lookup failed:", err)
+	}
+	if h.Hash() != hash {
+		t.Fatal("wrong header")
+	}
+	b, err := client.BlockBody(context.Background(), hash)
+	if err != nil {
+		t.Fatal("body lookup failed:", err)
+	}
+	if types.DeriveSha(types.Transactions(b.Transactions), trie.NewStackTrie(nil)) != header.TxHash {
+		t.Fatal("wrong body")
+	}
+	r, err := client.Receipts(context.Background(), hash)
+	if err != nil {
+		t.Fatal("receipts lookup failed:", err)
+	}
+	if len(r) != len(receipts) {
+		t.Fatal("wrong receipts")
+	}
+	// Contents are stored by the client.
+	if client.store.get(BodyKey(hash)) == nil {
+		t.Fatal("body not stored after lookup")
+	}
+	if _, err := client.BlockHeader(context.Background(), common.Hash{1}); err != errContentNotFound {
+		t.Fatal("wrong error for missing content:", err)
+	}
+}
+
+func TestHistoryOffer(t *testing.T) {
+	var (
+		nodes           = newTestHistory(t, 2)
+		header, body, _ = testBlock(t)
+		hash            = header.Hash()
+	)
+	headerEnc, _ := EncodeHeader(header)
+	bodyEnc, _ := EncodeBody(body, false)
+	bad := append([]byte{}, bodyEnc...)
+	bad[len(bad)-1]++
+
+	// The header must be known for the body to be accepted.
+	if n := nodes[1].Gossip(HeaderKey(hash), headerEnc); n != 1 {
+		t.Fatalf("header accepted by %d nodes, want 1", n)
+	}
+	waitFor(t, func() bool { return nodes[0].store.get(HeaderKey(hash)) != nil })
+
+	if _, err := nodes[1].Offer(nodes[0].transport.Self(), []ContentKey{HeaderKey(hash), BodyKey(hash)}, [][]byte{headerEnc}); err == nil {
+		t.Fatal("offer with missing content succeeded")
+	}
+	n, err := nodes[1].Offer(nodes[0].transport.Self(), []ContentKey{HeaderKey(hash), BodyKey(hash)}, [][]byte{headerEnc, bad})
+	if err != nil {
+		t.Fatal("offer failed:", err)
+	}
+	if n != 1 {
+		t.Fatalf("%d contents accepted, want 1", n)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if nodes[0].store.get(BodyKey(hash)) != nil {
+		t.Fatal("invalid body was stored")
+	}
+	if n := nodes[1].Gossip(BodyKey(hash), bodyEnc); n != 1 {
+		t.Fatalf("body accepted by %d nodes, want 1", n)
+	}
+	waitFor(t, func() bool { return bytes.Equal(nodes[0].store.get(BodyKey(hash)), bodyEnc) })
+}
+
+func TestStorePrune(t *testing.T) {
+	var (
+		self  [32]byte
+		store = newContentStore(rawdb.NewMemoryDatabase(), self, 10*1024)
+	)
+	for i := 0; i < 40; i++ {
+		key := HeaderKey(common.Hash{byte(i)})
+		store.put(key, make([]byte, 1000))
+	}
+	if store.size > store.capacity {
+		t.Fatalf("store size %d exceeds capacity %d", store.size, store.capacity)
+	}
+	radius := store.Radius()
+	if radius.Eq(new(uint256.Int).SetAllOne()) {
+		t.Fatal("radius did not shrink")
+	}
+	// All remaining contents are within the radius.
+	it := store.db.NewIterator(contentPrefix, nil)
+	defer it.Release()
+	for it.Next() {
+		var id ContentID
+		copy(id[:], it.Key()[len(contentPrefix):])
+		if distance(self, id).Gt(radius) {
+			t.Fatalf("content %x outside of radius", id)
+		}
+	}
+	// Reopening the store restores size and radius.
+	reopened := newContentStore(store.db, self, store.capacity)
+	if reopened.size != store.size || !reopened.Radius().Eq(radius) {
+		t.Fatal("store state not restored")
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// This file contains the subset of SSZ serialization used by the portal wire
+// protocol: containers of fixed-size integers and variable-size byte lists.
+
+const sszOffsetSize = 4
+
+var errSSZInvalid = errors.New("invalid SSZ encoding")
+
+// sszWriter encodes a container. Fields are added in order, variable-size
+// fields are placed after the fixed-size part.
+type sszWriter struct {
+	fixed   []byte
+	offsets []int // positions of the variable field offsets in fixed
+	vars    [][]byte
+}
+
+func (w *sszWriter) uint8(v uint8) {
+	w.fixed = append(w.fixed, v)
+}
+
+func (w *sszWriter) uint64(v uint64) {
+	w.fixed = binary.LittleEndian.AppendUint64(w.fixed, v)
+}
+
+func (w *sszWriter) fixedBytes(b []byte) {
+	w.fixed = append(w.fixed, b...)
+}
+
+// variable adds a variable-size field with the given encoding.
+func (w *sszWriter) variable(b []byte) {
+	w.offsets = append(w.offsets, len(w.fixed))
+	w.fixed = append(w.fixed, make([]byte, sszOffsetSize)...)
+	w.vars = append(w.vars, b)
+}
+
+func (w *sszWriter) bytes() []byte {
+	out := w.fixed
+	for i, v := range w.vars {
+		binary.LittleEndian.PutUint32(out[w.offsets[i]:], uint32(len(out)))
+		out = append(out, v...)
+	}
+	return out
+}
+
+// sszReader decodes a container. The fixed-size fields must be read in order,
+// variable-size fields are resolved by vars after all fixed fields were read.
+type sszReader struct {
+	b       []byte
+	pos     int
+	offsets []int
+	err     error
+}
+
+func newSSZReader(b []byte) *sszReader {
+	return &sszReader{b: b}
+}
+
+func (r *sszReader) next(n int) []byte {
+	if r.err != nil || len(r.b)-r.pos < n {
+		r.err = errSSZInvalid
+		return make([]byte, n)
+	}
+	b := r.b[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *sszReader) uint8() uint8 {
+	return r.next(1)[0]
+}
+
+func (r *sszReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.next(8))
+}
+
+func (r *sszReader) fixedBytes(n int) []byte {
+	return r.next(n)
+}
+
+// variable reads the offset of a variable-size field.
+func (r *sszReader) variable() {
+	r.offsets = append(r.offsets, int(binary.LittleEndian.Uint32(r.next(sszOffsetSize))))
+}
+
+// vars returns the contents of the variable-size fields.
+func (r *sszReader) vars() ([][]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	if len(r.offsets) == 0 {
+		if r.pos != len(r.b) {
+			return nil, errSSZInvalid
+		}
+		return nil, nil
+	}
+	if r.offsets[0] != r.pos {
+		return nil, errSSZInvalid
+	}
+	return splitOffsets(r.b, r.offsets)
+}
+
+// splitOffsets slices b at the given offsets.
+func splitOffsets(b []byte, offsets []int) ([][]byte, error) {
+	fields := make([][]byte, len(offsets))
+	for i, start := range offsets {
+		end := len(b)
+		if i < len(offsets)-1 {
+			end = offsets[i+1]
+		}
+		if start > end || end > len(b) {
+			return nil, errSSZInvalid
+		}
+		fields[i] = b[start:end]
+	}
+	return fields, nil
+}
+
+// encodeByteLists encodes a list of byte lists.
+func encodeByteLists(items [][]byte) []byte {
+	var w sszWriter
+	for _, item := range items {
+		w.variable(item)
+	}
+	return w.bytes()
+}
+
+// decodeByteLists decodes a list of byte lists.
+func decodeByteLists(b []byte) ([][]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	if len(b) < sszOffsetSize {
+		return nil, errSSZInvalid
+	}
+	first := int(binary.LittleEndian.Uint32(b))
+	if first%sszOffsetSize != 0 || first == 0 || first > len(b) {
+		return nil, errSSZInvalid
+	}
+	r := newSSZReader(b)
+	for i := 0; i < first/sszOffsetSize; i++ {
+		r.variable()
+	}
+	return r.vars()
+}
+
+// encodeBitlist encodes a bitlist, which has a delimiting bit after the last element.
+func encodeBitlist(bits []bool) []byte {
+	out := make([]byte, len(bits)/8+1)
+	for i, bit := range bits {
+		if bit {
+			out[i/8] |= 1 << (i % 8)
+		}
+	}
+	out[len(bits)/8] |= 1 << (len(bits) % 8)
+	return out
+}
+
+// decodeBitlist decodes a bitlist.
+func decodeBitlist(b []byte) ([]bool, error) {
+	if len(b) == 0 || b[len(b)-1] == 0 {
+		return nil, errSSZInvalid
+	}
+	last := b[len(b)-1]
+	n := (len(b) - 1) * 8
+	for last > 1 {
+		last >>= 1
+		n++
+	}
+	bits := make([]bool, n)
+	for i := range bits {
+		bits[i] = b[i/8]&(1<<(i%8)) != 0
+	}
+	return bits, nil
+}
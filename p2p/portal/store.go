@@ -0,0 +1,170 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"encoding/binary"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+	"golang.org/x/exp/slices"
+)
+
+var (
+	contentPrefix = []byte("c")      // contentPrefix + content ID -> key length + key + content
+	radiusKey     = []byte("radius") // radiusKey -> data radius after the last pruning
+)
+
+// pruneRatio is the fraction of the capacity which is kept when pruning.
+const pruneRatio = 0.9
+
+// contentStore keeps the contents within the data radius of the local node.
+// When the store exceeds its capacity, the contents farthest from the local node
+// are deleted and the radius shrinks accordingly.
+type contentStore struct {
+	db       ethdb.KeyValueStore
+	self     [32]byte
+	capacity uint64 // zero means unlimited
+
+	mu     sync.Mutex
+	size   uint64
+	radius *uint256.Int
+}
+
+func newContentStore(db ethdb.KeyValueStore, self [32]byte, capacity uint64) *contentStore {
+	s := &contentStore{
+		db:       db,
+		self:     self,
+		capacity: capacity,
+		radius:   new(uint256.Int).SetAllOne(),
+	}
+	// Compute the size of the stored contents and restore the radius.
+	it := db.NewIterator(contentPrefix, nil)
+	for it.Next() {
+		s.size += uint64(len(it.Value()))
+	}
+	it.Release()
+	if enc, err := db.Get(radiusKey); err == nil && capacity > 0 {
+		s.radius.SetBytes(enc)
+	}
+	if s.capacity > 0 && s.size > s.capacity {
+		s.prune()
+	}
+	return s
+}
+
+func contentDBKey(id ContentID) []byte {
+	return append(append([]byte{}, contentPrefix...), id[:]...)
+}
+
+// Radius returns the current data radius.
+func (s *contentStore) Radius() *uint256.Int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.radius.Clone()
+}
+
+// inRadius reports whether content with the given ID should be stored.
+func (s *contentStore) inRadius(id ContentID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !distance(s.self, id).Gt(s.radius)
+}
+
+// has reports whether the content is stored.
+func (s *contentStore) has(id ContentID) bool {
+	ok, _ := s.db.Has(contentDBKey(id))
+	return ok
+}
+
+// get returns stored content.
+func (s *contentStore) get(key ContentKey) []byte {
+	v, err := s.db.Get(contentDBKey(key.ID()))
+	if err != nil || len(v) < 2 {
+		return nil
+	}
+	n := int(binary.BigEndian.Uint16(v))
+	if len(v) < 2+n {
+		return nil
+	}
+	return v[2+n:]
+}
+
+// put stores content if it is within the radius. It returns false if the
+// content was not stored.
+func (s *contentStore) put(key ContentKey, content []byte) bool {
+	id := key.ID()
+	if !s.inRadius(id) || s.has(id) {
+		return false
+	}
+	v := binary.BigEndian.AppendUint16(nil, uint16(len(key)))
+	v = append(append(v, key...), content...)
+	if err := s.db.Put(contentDBKey(id), v); err != nil {
+		log.Warn("Failed to store portal content", "key", key, "err", err)
+		return false
+	}
+	s.mu.Lock()
+	s.size += uint64(len(v))
+	prune := s.capacity > 0 && s.size > s.capacity
+	s.mu.Unlock()
+
+	if prune {
+		s.prune()
+	}
+	return true
+}
+
+// prune deletes the farthest contents until the store is below its target size,
+// and sets the radius to the distance of the farthest remaining content.
+func (s *contentStore) prune() {
+	type entry struct {
+		id   ContentID
+		dist *uint256.Int
+		size uint64
+	}
+	var entries []entry
+	it := s.db.NewIterator(contentPrefix, nil)
+	for it.Next() {
+		var id ContentID
+		copy(id[:], it.Key()[len(contentPrefix):])
+		entries = append(entries, entry{id, distance(s.self, id), uint64(len(it.Value()))})
+	}
+	it.Release()
+	slices.SortFunc(entries, func(a, b entry) bool { return a.dist.Gt(b.dist) })
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	target := uint64(float64(s.capacity) * pruneRatio)
+	batch := s.db.NewBatch()
+	for len(entries) > 0 && s.size > target {
+		batch.Delete(contentDBKey(entries[0].id))
+		s.size -= entries[0].size
+		s.radius = entries[0].dist
+		entries = entries[1:]
+	}
+	if len(entries) > 0 {
+		s.radius = entries[0].dist
+	}
+	batch.Put(radiusKey, s.radius.Bytes())
+	if err := batch.Write(); err != nil {
+		log.Warn("Failed to prune portal content", "err", err)
+	}
+	log.Debug("Pruned portal content", "size", s.size, "radius", s.radius.Hex())
+}
@@ -0,0 +1,188 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/holiman/uint256"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	bucketSize = 16
+	nBuckets   = 256
+)
+
+// tableNode is a node of the routing table.
+type tableNode struct {
+	*enode.Node
+	radius   *uint256.Int // data radius announced by the node
+	lastSeen time.Time
+}
+
+// table is the routing table of a portal sub-network. Nodes are kept in buckets
+// by their log distance to the local node, like in discovery.
+type table struct {
+	self    enode.ID
+	mu      sync.Mutex
+	buckets [nBuckets][]*tableNode
+}
+
+func newTable(self enode.ID) *table {
+	return &table{self: self}
+}
+
+func (t *table) bucket(id enode.ID) *[]*tableNode {
+	d := enode.LogDist(t.self, id)
+	if d == 0 {
+		return nil
+	}
+	return &t.buckets[d-1]
+}
+
+// add inserts a node or updates its record and radius. It returns false if the
+// bucket of the node is full.
+func (t *table) add(n *enode.Node, radius *uint256.Int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(n.ID())
+	if b == nil {
+		return false
+	}
+	for _, e := range *b {
+		if e.ID() == n.ID() {
+			if n.Seq() >= e.Seq() {
+				e.Node = n
+			}
+			if radius != nil {
+				e.radius = radius
+			}
+			e.lastSeen = time.Now()
+			return true
+		}
+	}
+	if len(*b) >= bucketSize {
+		return false
+	}
+	if radius == nil {
+		radius = new(uint256.Int)
+	}
+	*b = append(*b, &tableNode{Node: n, radius: radius, lastSeen: time.Now()})
+	return true
+}
+
+// remove deletes a node.
+func (t *table) remove(id enode.ID) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := t.bucket(id)
+	if b == nil {
+		return
+	}
+	for i, e := range *b {
+		if e.ID() == id {
+			*b = append((*b)[:i], (*b)[i+1:]...)
+			return
+		}
+	}
+}
+
+// get returns the node with the given ID.
+func (t *table) get(id enode.ID) *tableNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if b := t.bucket(id); b != nil {
+		for _, e := range *b {
+			if e.ID() == id {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+// atDistances returns up to limit nodes at the given log distances.
+func (t *table) atDistances(dists []uint16, limit int) []*enode.Node {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var nodes []*enode.Node
+	for _, d := range dists {
+		if d == 0 || d > nBuckets {
+			continue
+		}
+		for _, e := range t.buckets[d-1] {
+			if len(nodes) >= limit {
+				return nodes
+			}
+			nodes = append(nodes, e.Node)
+		}
+	}
+	return nodes
+}
+
+// closest returns the n nodes closest to the target.
+func (t *table) closest(target [32]byte, n int) []*tableNode {
+	t.mu.Lock()
+	var all []*tableNode
+	for _, b := range t.buckets {
+		all = append(all, b...)
+	}
+	t.mu.Unlock()
+
+	slices.SortFunc(all, func(a, b *tableNode) bool {
+		return enode.DistCmp(target, a.ID(), b.ID()) < 0
+	})
+	if len(all) > n {
+		all = all[:n]
+	}
+	return all
+}
+
+// oldest returns the node which wasn't seen for the longest time.
+func (t *table) oldest() *tableNode {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var oldest *tableNode
+	for _, b := range t.buckets {
+		for _, e := range b {
+			if oldest == nil || e.lastSeen.Before(oldest.lastSeen) {
+				oldest = e
+			}
+		}
+	}
+	return oldest
+}
+
+// len returns the number of nodes in the table.
+func (t *table) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	n := 0
+	for _, b := range t.buckets {
+		n += len(b)
+	}
+	return n
+}
@@ -0,0 +1,711 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+// Contents which don't fit into a single TALKRESP are transferred over uTP (BEP
+// 29), as required by the portal wire protocol. uTP packets are carried in the
+// TALKREQ messages of the "utp" talk protocol, which are answered with an empty
+// TALKRESP.
+//
+// The node handing out the connection ID listens for the connection, the other
+// node initiates it. For FINDCONTENT, the responder listens and writes the
+// content once the requester connected. For OFFER, the acceptor listens and
+// reads the accepted contents written by the offerer.
+//
+// Data only flows in one direction per connection, which is all the transfers
+// need. Lost packets are retransmitted on timeout or duplicate acknowledgements,
+// and selective acknowledgements of the remote node are honoured. The send
+// window is fixed, LEDBAT congestion control isn't implemented.
+
+const (
+	utpProtocol = "utp"
+
+	utpVersion = 1
+
+	// Packet types
+	utpData  = 0
+	utpFin   = 1
+	utpState = 2
+	utpReset = 3
+	utpSyn   = 4
+
+	utpHeaderSize      = 20
+	utpExtSelectiveAck = 1
+
+	utpPayloadSize = 1000 // data bytes per packet
+	utpSendWindow  = 32   // packets in flight
+	utpRecvWindow  = 1024 // packets buffered ahead of the in-order data
+
+	utpInitialRTO = time.Second
+	utpMinRTO     = 500 * time.Millisecond
+	utpMaxRTO     = 4 * time.Second
+	utpTick       = 50 * time.Millisecond
+
+	utpIdleTimeout = 10 * time.Second // abort if the remote node goes silent
+	utpLinger      = 5 * time.Second  // time to complete the close handshake
+
+	streamTimeout = 30 * time.Second // time for the remote node to connect
+	maxStreamSize = 16 * 1024 * 1024
+
+	maxNodeStreams     = 2  // in-flight incoming transfers from a single node
+	maxIncomingStreams = 16 // in-flight incoming transfers in total
+)
+
+var (
+	errStreamInvalid = errors.New("invalid stream packet")
+	errStreamReset   = errors.New("stream reset by remote node")
+	errStreamInUse   = errors.New("stream connection ID in use")
+	errStreamLimit   = errors.New("too many incoming streams")
+)
+
+// utpPacket is a uTP packet.
+type utpPacket struct {
+	typ       byte
+	connID    uint16
+	timestamp uint32 // microseconds
+	tsDiff    uint32 // microseconds
+	wnd       uint32 // bytes
+	seq       uint16
+	ack       uint16
+	sack      []byte // bitmask of packets received after ack+1
+	payload   []byte
+}
+
+func (p *utpPacket) encode() []byte {
+	b := make([]byte, utpHeaderSize, utpHeaderSize+2+len(p.sack)+len(p.payload))
+	b[0] = p.typ<<4 | utpVersion
+	binary.BigEndian.PutUint16(b[2:], p.connID)
+	binary.BigEndian.PutUint32(b[4:], p.timestamp)
+	binary.BigEndian.PutUint32(b[8:], p.tsDiff)
+	binary.BigEndian.PutUint32(b[12:], p.wnd)
+	binary.BigEndian.PutUint16(b[16:], p.seq)
+	binary.BigEndian.PutUint16(b[18:], p.ack)
+	if len(p.sack) > 0 {
+		b[1] = utpExtSelectiveAck
+		b = append(b, 0, byte(len(p.sack)))
+		b = append(b, p.sack...)
+	}
+	return append(b, p.payload...)
+}
+
+func decodeUTPPacket(b []byte) (*utpPacket, error) {
+	if len(b) < utpHeaderSize || b[0]&0x0f != utpVersion || b[0]>>4 > utpSyn {
+		return nil, errStreamInvalid
+	}
+	p := &utpPacket{
+		typ:       b[0] >> 4,
+		connID:    binary.BigEndian.Uint16(b[2:]),
+		timestamp: binary.BigEndian.Uint32(b[4:]),
+		tsDiff:    binary.BigEndian.Uint32(b[8:]),
+		wnd:       binary.BigEndian.Uint32(b[12:]),
+		seq:       binary.BigEndian.Uint16(b[16:]),
+		ack:       binary.BigEndian.Uint16(b[18:]),
+	}
+	ext, rest := b[1], b[utpHeaderSize:]
+	for ext != 0 {
+		if len(rest) < 2 || len(rest) < 2+int(rest[1]) {
+			return nil, errStreamInvalid
+		}
+		next, size := rest[0], int(rest[1])
+		if ext == utpExtSelectiveAck {
+			p.sack = rest[2 : 2+size]
+		}
+		ext, rest = next, rest[2+size:]
+	}
+	p.payload = rest
+	return p, nil
+}
+
+// seqLess reports whether sequence number a precedes b, accounting for wrapping.
+func seqLess(a, b uint16) bool {
+	return int16(a-b) < 0
+}
+
+func randUint16() uint16 {
+	var b [2]byte
+	rand.Read(b[:])
+	return binary.BigEndian.Uint16(b[:])
+}
+
+func utpMicros(t time.Time) uint32 {
+	return uint32(t.UnixMicro())
+}
+
+type utpKey struct {
+	node enode.ID
+	conn uint16
+}
+
+// utpInput is a packet received from the remote node of a connection.
+type utpInput struct {
+	p    *utpPacket
+	addr *net.UDPAddr
+}
+
+// utpSocket multiplexes the uTP connections over the talk protocol.
+type utpSocket struct {
+	transport Transport
+
+	mu      sync.Mutex
+	listens map[utpKey]*utpConn // connections waiting for a SYN, by the handed out ID
+	conns   map[utpKey]*utpConn // connections by receive ID
+}
+
+func newUTPSocket(transport Transport) *utpSocket {
+	return &utpSocket{
+		transport: transport,
+		listens:   make(map[utpKey]*utpConn),
+		conns:     make(map[utpKey]*utpConn),
+	}
+}
+
+// serve registers content to be read by the given node. It returns the ID of
+// the connection the node has to initiate.
+func (s *utpSocket) serve(node enode.ID, data []byte) uint16 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.listen(node)
+	c.data = data
+	go c.run()
+	return c.listenID
+}
+
+// accept registers an incoming transfer from the given node. It fails if too
+// many transfers are in flight, from the node or in total.
+func (s *utpSocket) accept(node enode.ID) (uint16, *utpConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total, fromNode int
+	for k, c := range s.listens {
+		if !c.reading || c.finished() {
+			continue
+		}
+		total++
+		if k.node == node {
+			fromNode++
+		}
+	}
+	if total >= maxIncomingStreams || fromNode >= maxNodeStreams {
+		return 0, nil, errStreamLimit
+	}
+	c := s.listen(node)
+	c.reading = true
+	go c.run()
+	return c.listenID, c, nil
+}
+
+// listen creates a connection waiting for the given node to connect. It must
+// be called with s.mu held.
+func (s *utpSocket) listen(node enode.ID) *utpConn {
+	for {
+		id := randUint16()
+		var (
+			syn  = utpKey{node, id}
+			recv = utpKey{node, id + 1}
+		)
+		if s.listens[syn] != nil || s.conns[recv] != nil {
+			continue
+		}
+		c := newUTPConn(s, node, nil, id+1, id)
+		c.listenID, c.listening = id, true
+		s.listens[syn], s.conns[recv] = c, c
+		return c
+	}
+}
+
+// dial initiates the connection with the given ID handed out by node n.
+func (s *utpSocket) dial(n *enode.Node, id uint16) (*utpConn, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	k := utpKey{n.ID(), id}
+	if s.conns[k] != nil {
+		return nil, errStreamInUse
+	}
+	c := newUTPConn(s, n.ID(), &net.UDPAddr{IP: n.IP(), Port: n.UDP()}, id, id+1)
+	s.conns[k] = c
+	return c, nil
+}
+
+// read reads the content served by node n on the connection with the given ID.
+func (s *utpSocket) read(n *enode.Node, id uint16) ([]byte, error) {
+	c, err := s.dial(n, id)
+	if err != nil {
+		return nil, err
+	}
+	c.reading = true
+	go c.run()
+	return c.wait()
+}
+
+// write writes data to the connection with the given ID accepted by node n.
+func (s *utpSocket) write(n *enode.Node, id uint16, data []byte) error {
+	c, err := s.dial(n, id)
+	if err != nil {
+		return err
+	}
+	c.data = data
+	go c.run()
+	_, err = c.wait()
+	return err
+}
+
+// remove unregisters a terminated connection.
+func (s *utpSocket) remove(c *utpConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if k := (utpKey{c.node, c.listenID}); c.listening && s.listens[k] == c {
+		delete(s.listens, k)
+	}
+	if k := (utpKey{c.node, c.recvID}); s.conns[k] == c {
+		delete(s.conns, k)
+	}
+}
+
+// handle is the talk handler of the uTP protocol.
+func (s *utpSocket) handle(id enode.ID, addr *net.UDPAddr, req []byte) []byte {
+	p, err := decodeUTPPacket(req)
+	if err != nil {
+		return nil
+	}
+	k := utpKey{id, p.connID}
+	s.mu.Lock()
+	c := s.conns[k]
+	if p.typ == utpSyn {
+		c = s.listens[k]
+	}
+	s.mu.Unlock()
+
+	if c != nil {
+		// Talk handlers must not block. If the connection falls behind, the packet
+		// is dropped and retransmitted later.
+		select {
+		case c.incoming <- utpInput{p, addr}:
+		default:
+		}
+	}
+	return nil
+}
+
+// utpOutgoing is a packet sent but not acknowledged yet.
+type utpOutgoing struct {
+	p     *utpPacket
+	sent  time.Time
+	count int  // number of transmissions
+	acked bool // selectively acknowledged
+}
+
+// utpConn is a uTP connection transferring data in a single direction. Apart
+// from its setup, it's only accessed by its own goroutine.
+type utpConn struct {
+	sock     *utpSocket
+	node     enode.ID
+	addr     *net.UDPAddr
+	recvID   uint16
+	sendID   uint16
+	incoming chan utpInput
+
+	listening bool   // whether the remote node initiates the connection
+	listenID  uint16 // connection ID handed out to the remote node
+	reading   bool   // whether data is received rather than sent
+	data      []byte // data to send
+
+	connected  bool
+	early      []utpInput // packets received before the connection was established
+	lastRecv   time.Time
+	replyMicro uint32 // timestamp difference of the last received packet
+	peerWnd    uint32
+
+	// Sender state
+	seq       uint16 // sequence number of the next packet
+	synSeq    uint16 // sequence number of the SYN or the SYN acknowledgement
+	inflight  []*utpOutgoing
+	offset    int // amount of data sent so far
+	finSent   bool
+	lastAck   uint16
+	dupAcks   int
+	rto       time.Duration
+	srtt      time.Duration
+	rttvar    time.Duration
+	remoteFin bool
+
+	// Receiver state
+	ack     uint16                // sequence number of the last packet received in order
+	pending map[uint16]*utpPacket // packets received out of order
+	buf     []byte
+	hasFin  bool
+	finSeq  uint16
+	eof     bool
+
+	done   chan struct{}
+	result []byte
+	err    error
+}
+
+func newUTPConn(s *utpSocket, node enode.ID, addr *net.UDPAddr, recvID, sendID uint16) *utpConn {
+	return &utpConn{
+		sock:     s,
+		node:     node,
+		addr:     addr,
+		recvID:   recvID,
+		sendID:   sendID,
+		incoming: make(chan utpInput, utpRecvWindow),
+		pending:  make(map[uint16]*utpPacket),
+		rto:      utpInitialRTO,
+		peerWnd:  utpPayloadSize,
+		done:     make(chan struct{}),
+	}
+}
+
+// finished reports whether the transfer is over.
+func (c *utpConn) finished() bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// wait blocks until the transfer is over, returning the received data.
+func (c *utpConn) wait() ([]byte, error) {
+	<-c.done
+	return c.result, c.err
+}
+
+func (c *utpConn) finish(result []byte, err error) {
+	if !c.finished() {
+		c.result, c.err = result, err
+		close(c.done)
+	}
+}
+
+// run is the loop of the connection.
+func (c *utpConn) run() {
+	defer c.sock.remove(c)
+
+	c.lastRecv = time.Now()
+	if !c.listening {
+		c.seq = randUint16()
+		c.synSeq = c.seq
+		c.transmit(&utpPacket{typ: utpSyn})
+	}
+	ticker := time.NewTicker(utpTick)
+	defer ticker.Stop()
+
+	var lingerUntil time.Time
+	for {
+		select {
+		case in := <-c.incoming:
+			c.lastRecv = time.Now()
+			if err := c.handle(in); err != nil {
+				c.finish(nil, err)
+				return
+			}
+		case now := <-ticker.C:
+			timeout := utpIdleTimeout
+			if c.listening && !c.connected {
+				timeout = streamTimeout
+			}
+			if now.Sub(c.lastRecv) > timeout {
+				c.finish(nil, errTimeout)
+				return
+			}
+			c.retransmit(now)
+		}
+		if !c.connected {
+			continue
+		}
+		c.fill()
+
+		// Deliver the result once the data is transferred, then give the close
+		// handshake some time to complete.
+		if !c.finished() {
+			switch {
+			case c.reading && c.eof:
+				c.finish(c.buf, nil)
+				c.finSent = true
+				c.transmit(&utpPacket{typ: utpFin})
+			case !c.reading && c.finSent && len(c.inflight) == 0:
+				c.finish(nil, nil)
+			default:
+				continue
+			}
+			lingerUntil = time.Now().Add(utpLinger)
+		}
+		if time.Now().After(lingerUntil) || (c.reading && len(c.inflight) == 0) || (!c.reading && c.remoteFin) {
+			return
+		}
+	}
+}
+
+// handle processes a packet of the remote node.
+func (c *utpConn) handle(in utpInput) error {
+	p := in.p
+	c.replyMicro = utpMicros(time.Now()) - p.timestamp
+	c.peerWnd = p.wnd
+
+	switch {
+	case p.typ == utpReset:
+		return errStreamReset
+
+	case p.typ == utpSyn:
+		if !c.listening {
+			return nil
+		}
+		if !c.connected {
+			c.addr, c.connected = in.addr, true
+			c.ack = p.seq
+			c.seq = randUint16()
+			c.synSeq = c.seq
+		}
+		// Acknowledge the SYN, also if it was retransmitted
+		c.send(&utpPacket{typ: utpState, seq: c.synSeq, ack: p.seq})
+		return nil
+
+	case !c.connected:
+		// Only the acknowledgement of the SYN establishes the connection. Packets
+		// overtaking it are kept until then.
+		if c.listening {
+			return nil
+		}
+		if p.typ != utpState || p.ack != c.synSeq {
+			if len(c.early) < utpRecvWindow {
+				c.early = append(c.early, in)
+			}
+			return nil
+		}
+		c.connected = true
+		c.ack = p.seq - 1
+		c.acked(p)
+		if c.reading {
+			c.sendState()
+		}
+		early := c.early
+		c.early = nil
+		for _, in := range early {
+			if err := c.handle(in); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	c.acked(p)
+
+	switch p.typ {
+	case utpData:
+		if c.reading {
+			return c.receive(p)
+		}
+	case utpFin:
+		if c.reading {
+			return c.receive(p)
+		}
+		// The remote node is done reading, no data is expected from it.
+		c.ack, c.remoteFin = p.seq, true
+		c.sendState()
+	}
+	return nil
+}
+
+// receive processes a data or FIN packet.
+func (c *utpConn) receive(p *utpPacket) error {
+	if !seqLess(c.ack, p.seq) || (c.hasFin && !seqLess(p.seq, c.finSeq+1)) {
+		c.sendState() // duplicate, acknowledge again
+		return nil
+	}
+	if p.seq-c.ack > utpRecvWindow {
+		return nil
+	}
+	if p.typ == utpFin {
+		c.hasFin, c.finSeq = true, p.seq
+	} else {
+		c.pending[p.seq] = p
+	}
+	for !c.eof {
+		next := c.ack + 1
+		if c.hasFin && next == c.finSeq {
+			c.ack, c.eof = next, true
+			c.pending = nil
+			break
+		}
+		q := c.pending[next]
+		if q == nil {
+			break
+		}
+		delete(c.pending, next)
+		if len(c.buf)+len(q.payload) > maxStreamSize {
+			return errStreamInvalid
+		}
+		c.buf = append(c.buf, q.payload...)
+		c.ack = next
+	}
+	c.sendState()
+	return nil
+}
+
+// acked processes the acknowledgements of a packet.
+func (c *utpConn) acked(p *utpPacket) {
+	now := time.Now()
+
+	n := 0
+	for _, out := range c.inflight {
+		if seqLess(p.ack, out.p.seq) {
+			break
+		}
+		if out.count == 1 {
+			c.sampleRTT(now.Sub(out.sent))
+		}
+		n++
+	}
+	switch {
+	case n > 0:
+		c.inflight, c.dupAcks = c.inflight[n:], 0
+	case p.typ == utpState && len(c.inflight) > 0 && p.ack == c.lastAck:
+		// Three duplicate acknowledgements signal the loss of the next packet.
+		if c.dupAcks++; c.dupAcks == 3 {
+			c.resend(c.inflight[0], now)
+		}
+	}
+	c.lastAck = p.ack
+
+	// Bit i of the selective acknowledgement marks packet ack+2+i as received.
+	for i := 0; i < 8*len(p.sack); i++ {
+		if p.sack[i/8]&(1<<(i%8)) == 0 {
+			continue
+		}
+		seq := p.ack + 2 + uint16(i)
+		for _, out := range c.inflight {
+			if out.p.seq == seq {
+				out.acked = true
+			}
+		}
+	}
+}
+
+func (c *utpConn) sampleRTT(rtt time.Duration) {
+	if c.srtt == 0 {
+		c.srtt, c.rttvar = rtt, rtt/2
+	} else {
+		delta := c.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		c.rttvar = (3*c.rttvar + delta) / 4
+		c.srtt = (7*c.srtt + rtt) / 8
+	}
+	c.rto = c.srtt + 4*c.rttvar
+	if c.rto < utpMinRTO {
+		c.rto = utpMinRTO
+	}
+	if c.rto > utpMaxRTO {
+		c.rto = utpMaxRTO
+	}
+}
+
+// fill sends data packets, and the FIN after the data, as far as the window allows.
+func (c *utpConn) fill() {
+	if c.reading || c.finSent {
+		return
+	}
+	window := int(c.peerWnd / utpPayloadSize)
+	if window > utpSendWindow {
+		window = utpSendWindow
+	}
+	if window < 1 {
+		window = 1
+	}
+	for len(c.inflight) < window && !c.finSent {
+		if c.offset < len(c.data) {
+			end := c.offset + utpPayloadSize
+			if end > len(c.data) {
+				end = len(c.data)
+			}
+			c.transmit(&utpPacket{typ: utpData, payload: c.data[c.offset:end]})
+			c.offset = end
+		} else {
+			c.transmit(&utpPacket{typ: utpFin})
+			c.finSent = true
+		}
+	}
+}
+
+// retransmit resends the packets whose acknowledgement timed out.
+func (c *utpConn) retransmit(now time.Time) {
+	lost := false
+	for _, out := range c.inflight {
+		if !out.acked && now.Sub(out.sent) >= c.rto {
+			c.resend(out, now)
+			lost = true
+		}
+	}
+	if lost {
+		if c.rto *= 2; c.rto > utpMaxRTO {
+			c.rto = utpMaxRTO
+		}
+	}
+}
+
+// transmit sends a packet consuming a sequence number.
+func (c *utpConn) transmit(p *utpPacket) {
+	p.seq = c.seq
+	c.seq++
+	out := &utpOutgoing{p: p}
+	c.inflight = append(c.inflight, out)
+	c.resend(out, time.Now())
+}
+
+func (c *utpConn) resend(out *utpOutgoing, now time.Time) {
+	out.sent = now
+	out.count++
+	out.p.ack = c.ack
+	c.send(out.p)
+}
+
+// sendState acknowledges the packets received so far.
+func (c *utpConn) sendState() {
+	c.send(&utpPacket{typ: utpState, seq: c.seq, ack: c.ack})
+}
+
+// send transmits a packet to the remote node. Packets are sent asynchronously,
+// as each of them waits for a TALKRESP.
+func (c *utpConn) send(p *utpPacket) {
+	p.connID = c.sendID
+	if p.typ == utpSyn {
+		p.connID = c.recvID
+	}
+	p.timestamp = utpMicros(time.Now())
+	p.tsDiff = c.replyMicro
+	p.wnd = uint32(utpRecvWindow-len(c.pending)) * utpPayloadSize
+
+	enc := p.encode()
+	go c.sock.transport.TalkRequestToID(c.node, c.addr, utpProtocol, enc)
+}
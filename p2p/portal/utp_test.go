@@ -0,0 +1,177 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestUTPPacketEncoding(t *testing.T) {
+	// A SYN packet from the uTP wire test vectors of the portal specification.
+	syn := &utpPacket{typ: utpSyn, connID: 10049, timestamp: 3384187322, wnd: 1048576, seq: 11884}
+	want := "0x41002741c9b699ba00000000001000002e6c0000"
+	if enc := hexutil.Encode(syn.encode()); enc != want {
+		t.Errorf("wrong SYN encoding\nhave %s\nwant %s", enc, want)
+	}
+	packets := []*utpPacket{
+		syn,
+		{typ: utpState, connID: 1, seq: 2, ack: 3, sack: []byte{1, 0, 0, 128}},
+		{typ: utpData, connID: 0xffff, timestamp: 1, tsDiff: 2, wnd: 3, seq: 0xffff, ack: 0, payload: []byte{1, 2, 3}},
+	}
+	for _, p := range packets {
+		dec, err := decodeUTPPacket(p.encode())
+		if err != nil {
+			t.Fatalf("decode error: %v", err)
+		}
+		if len(dec.payload) == 0 {
+			dec.payload = p.payload
+		}
+		if !reflect.DeepEqual(dec, p) {
+			t.Errorf("roundtrip mismatch:\nhave %+v\nwant %+v", dec, p)
+		}
+	}
+	inputs := []string{
+		"0x4100",
+		"0x42002741c9b699ba00000000001000002e6c0000",           // bad version
+		"0x51002741c9b699ba00000000001000002e6c0000",           // bad type
+		"0x21012741c9b699ba00000000001000002e6c0000",           // truncated extension
+		"0x21012741c9b699ba00000000001000002e6c000000",         // truncated extension
+		"0x21012741c9b699ba00000000001000002e6c00000004010000", // short extension data
+	}
+	for _, input := range inputs {
+		if p, err := decodeUTPPacket(hexutil.MustDecode(input)); err == nil {
+			t.Errorf("input %s: expected error, got %+v", input, p)
+		}
+	}
+}
+
+// newTestUTP creates two uTP sockets which can reach each other.
+func newTestUTP(t *testing.T) (a, b *utpSocket, ta, tb *testTransport) {
+	tn := newTestNetwork()
+	ta, tb = tn.newTransport(t, 30000), tn.newTransport(t, 30001)
+	a, b = newUTPSocket(ta), newUTPSocket(tb)
+	ta.RegisterTalkHandler(utpProtocol, a.handle)
+	tb.RegisterTalkHandler(utpProtocol, b.handle)
+	return a, b, ta, tb
+}
+
+func TestUTPTransfer(t *testing.T) {
+	t.Parallel()
+
+	a, b, ta, tb := newTestUTP(t)
+	for _, size := range []int{0, 1, utpPayloadSize, 100*utpPayloadSize + 1} {
+		data := make([]byte, size)
+		rand.Read(data)
+
+		// The served content is read by the initiator of the connection.
+		id := b.serve(ta.Self().ID(), data)
+		have, err := a.read(tb.Self(), id)
+		if err != nil {
+			t.Fatalf("size %d: read failed: %v", size, err)
+		}
+		if !bytes.Equal(have, data) {
+			t.Fatalf("size %d: read data mismatch", size)
+		}
+		// The accepted transfer is written by the initiator of the connection.
+		id, in, err := b.accept(ta.Self().ID())
+		if err != nil {
+			t.Fatalf("size %d: accept failed: %v", size, err)
+		}
+		if err := a.write(tb.Self(), id, data); err != nil {
+			t.Fatalf("size %d: write failed: %v", size, err)
+		}
+		if have, err := in.wait(); err != nil || !bytes.Equal(have, data) {
+			t.Fatalf("size %d: written data mismatch: %v", size, err)
+		}
+	}
+}
+
+// Tests that transfers complete in spite of lost packets.
+func TestUTPPacketLoss(t *testing.T) {
+	t.Parallel()
+
+	a, b, ta, tb := newTestUTP(t)
+	var sent atomic.Int64
+	lossy := func(protocol string, req []byte) bool {
+		return sent.Add(1)%7 == 0
+	}
+	ta.drop, tb.drop = lossy, lossy
+
+	data := make([]byte, 50*utpPayloadSize)
+	rand.Read(data)
+
+	id := b.serve(ta.Self().ID(), data)
+	if have, err := a.read(tb.Self(), id); err != nil || !bytes.Equal(have, data) {
+		t.Fatalf("read data mismatch: %v", err)
+	}
+	id, in, err := b.accept(ta.Self().ID())
+	if err != nil {
+		t.Fatalf("accept failed: %v", err)
+	}
+	if err := a.write(tb.Self(), id, data); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if have, err := in.wait(); err != nil || !bytes.Equal(have, data) {
+		t.Fatalf("written data mismatch: %v", err)
+	}
+}
+
+// Tests that incoming transfers are limited per node and in total, and that
+// finished transfers don't count against the limits.
+func TestUTPLimits(t *testing.T) {
+	t.Parallel()
+
+	a, b, ta, _ := newTestUTP(t)
+
+	// Fill the per-node limit.
+	node := ta.Self().ID()
+	var ids []uint16
+	for i := 0; i < maxNodeStreams; i++ {
+		id, _, err := b.accept(node)
+		if err != nil {
+			t.Fatalf("failed to accept stream %d: %v", i, err)
+		}
+		ids = append(ids, id)
+	}
+	if _, _, err := b.accept(node); !errors.Is(err, errStreamLimit) {
+		t.Fatalf("per-node limit error mismatch: have %v, want %v", err, errStreamLimit)
+	}
+	// Fill the global limit from other nodes.
+	for i := maxNodeStreams; i < maxIncomingStreams; i++ {
+		if _, _, err := b.accept(enode.ID{2, byte(i)}); err != nil {
+			t.Fatalf("failed to accept stream %d: %v", i, err)
+		}
+	}
+	if _, _, err := b.accept(enode.ID{3}); !errors.Is(err, errStreamLimit) {
+		t.Fatalf("global limit error mismatch: have %v, want %v", err, errStreamLimit)
+	}
+	// Finish a transfer and ensure the node may start another one.
+	if err := a.write(b.transport.Self(), ids[0], []byte{1}); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	if _, _, err := b.accept(node); err != nil {
+		t.Fatalf("failed to accept stream after transfer finished: %v", err)
+	}
+}
@@ -0,0 +1,353 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/holiman/uint256"
+)
+
+// Portal wire protocol message types.
+const (
+	PingMsg        = 0x00
+	PongMsg        = 0x01
+	FindNodesMsg   = 0x02
+	NodesMsg       = 0x03
+	FindContentMsg = 0x04
+	ContentMsg     = 0x05
+	OfferMsg       = 0x06
+	AcceptMsg      = 0x07
+)
+
+// Selectors of the CONTENT message union.
+const (
+	contentConnectionID = 0x00
+	contentPayload      = 0x01
+	contentENRs         = 0x02
+)
+
+const (
+	// maxPayloadSize is the maximum size of a message fitting into a TALKRESP.
+	// Larger contents are transferred over uTP.
+	maxPayloadSize = 1000
+
+	// maxOfferKeys is the maximum number of contents in an OFFER.
+	maxOfferKeys = 64
+)
+
+var errUnknownMessage = errors.New("unknown message type")
+
+// Message is a portal wire protocol message.
+type Message interface {
+	Kind() byte
+	encode() []byte
+}
+
+// Ping checks whether a node is online and exchanges the data radius.
+type Ping struct {
+	ENRSeq uint64
+	Radius *uint256.Int
+}
+
+// Pong is the response to Ping.
+type Pong struct {
+	ENRSeq uint64
+	Radius *uint256.Int
+}
+
+// FindNodes requests nodes at the given log distances.
+type FindNodes struct {
+	Distances []uint16
+}
+
+// Nodes is the response to FindNodes.
+type Nodes struct {
+	Total uint8
+	ENRs  []*enode.Node
+}
+
+// FindContent requests content by its key.
+type FindContent struct {
+	ContentKey []byte
+}
+
+// Content is the response to FindContent. Exactly one of its fields is set: the
+// content itself, the ID of the uTP connection carrying the content, or nodes
+// closer to the content.
+//
+// Connection IDs are encoded in big-endian byte order, like in uTP headers.
+type Content struct {
+	ConnectionID *uint16
+	Content      []byte
+	ENRs         []*enode.Node
+}
+
+// Offer offers contents to a node.
+type Offer struct {
+	ContentKeys [][]byte
+}
+
+// Accept is the response to Offer. It lists the accepted contents, which must be
+// written to the uTP connection with the given ID.
+type Accept struct {
+	ConnectionID uint16
+	ContentKeys  []bool
+}
+
+func (*Ping) Kind() byte        { return PingMsg }
+func (*Pong) Kind() byte        { return PongMsg }
+func (*FindNodes) Kind() byte   { return FindNodesMsg }
+func (*Nodes) Kind() byte       { return NodesMsg }
+func (*FindContent) Kind() byte { return FindContentMsg }
+func (*Content) Kind() byte     { return ContentMsg }
+func (*Offer) Kind() byte       { return OfferMsg }
+func (*Accept) Kind() byte      { return AcceptMsg }
+
+// The custom payload of PING and PONG is the data radius, as little-endian uint256.
+func encodeRadius(r *uint256.Int) []byte {
+	b := r.Bytes32()
+	for i := 0; i < 16; i++ {
+		b[i], b[31-i] = b[31-i], b[i]
+	}
+	return b[:]
+}
+
+func decodeRadius(b []byte) (*uint256.Int, error) {
+	if len(b) != 32 {
+		return nil, errSSZInvalid
+	}
+	var be [32]byte
+	for i := range be {
+		be[i] = b[31-i]
+	}
+	return new(uint256.Int).SetBytes(be[:]), nil
+}
+
+func (p *Ping) encode() []byte {
+	var w sszWriter
+	w.uint64(p.ENRSeq)
+	w.variable(encodeRadius(p.Radius))
+	return w.bytes()
+}
+
+func (p *Pong) encode() []byte {
+	return (*Ping)(p).encode()
+}
+
+func (p *FindNodes) encode() []byte {
+	dists := make([]byte, 0, 2*len(p.Distances))
+	for _, d := range p.Distances {
+		dists = binary.LittleEndian.AppendUint16(dists, d)
+	}
+	var w sszWriter
+	w.variable(dists)
+	return w.bytes()
+}
+
+func (p *Nodes) encode() []byte {
+	var w sszWriter
+	w.uint8(p.Total)
+	w.variable(encodeENRs(p.ENRs))
+	return w.bytes()
+}
+
+func (p *FindContent) encode() []byte {
+	var w sszWriter
+	w.variable(p.ContentKey)
+	return w.bytes()
+}
+
+func (p *Content) encode() []byte {
+	switch {
+	case p.ConnectionID != nil:
+		return binary.BigEndian.AppendUint16([]byte{contentConnectionID}, *p.ConnectionID)
+	case p.Content != nil:
+		return append([]byte{contentPayload}, p.Content...)
+	default:
+		return append([]byte{contentENRs}, encodeENRs(p.ENRs)...)
+	}
+}
+
+func (p *Offer) encode() []byte {
+	var w sszWriter
+	w.variable(encodeByteLists(p.ContentKeys))
+	return w.bytes()
+}
+
+func (p *Accept) encode() []byte {
+	var w sszWriter
+	w.fixedBytes(binary.BigEndian.AppendUint16(nil, p.ConnectionID))
+	w.variable(encodeBitlist(p.ContentKeys))
+	return w.bytes()
+}
+
+func encodeENRs(nodes []*enode.Node) []byte {
+	enrs := make([][]byte, len(nodes))
+	for i, n := range nodes {
+		enrs[i], _ = rlp.EncodeToBytes(n.Record())
+	}
+	return encodeByteLists(enrs)
+}
+
+func decodeENRs(b []byte) ([]*enode.Node, error) {
+	enrs, err := decodeByteLists(b)
+	if err != nil {
+		return nil, err
+	}
+	nodes := make([]*enode.Node, 0, len(enrs))
+	for _, blob := range enrs {
+		var r enr.Record
+		if err := rlp.DecodeBytes(blob, &r); err != nil {
+			return nil, fmt.Errorf("invalid ENR: %v", err)
+		}
+		n, err := enode.New(enode.ValidSchemes, &r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid ENR: %v", err)
+		}
+		nodes = append(nodes, n)
+	}
+	return nodes, nil
+}
+
+// EncodeMessage encodes a message, prefixed by its type.
+func EncodeMessage(msg Message) []byte {
+	return append([]byte{msg.Kind()}, msg.encode()...)
+}
+
+// DecodeMessage decodes a message.
+func DecodeMessage(b []byte) (Message, error) {
+	if len(b) == 0 {
+		return nil, errSSZInvalid
+	}
+	var (
+		kind, body = b[0], b[1:]
+		r          = newSSZReader(body)
+	)
+	switch kind {
+	case PingMsg, PongMsg:
+		seq := r.uint64()
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		radius, err := decodeRadius(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		if kind == PingMsg {
+			return &Ping{ENRSeq: seq, Radius: radius}, nil
+		}
+		return &Pong{ENRSeq: seq, Radius: radius}, nil
+
+	case FindNodesMsg:
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		if len(fields[0])%2 != 0 {
+			return nil, errSSZInvalid
+		}
+		msg := new(FindNodes)
+		for i := 0; i < len(fields[0]); i += 2 {
+			msg.Distances = append(msg.Distances, binary.LittleEndian.Uint16(fields[0][i:]))
+		}
+		return msg, nil
+
+	case NodesMsg:
+		total := r.uint8()
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := decodeENRs(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Nodes{Total: total, ENRs: nodes}, nil
+
+	case FindContentMsg:
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		return &FindContent{ContentKey: fields[0]}, nil
+
+	case ContentMsg:
+		if len(body) == 0 {
+			return nil, errSSZInvalid
+		}
+		switch body[0] {
+		case contentConnectionID:
+			if len(body) != 3 {
+				return nil, errSSZInvalid
+			}
+			id := binary.BigEndian.Uint16(body[1:])
+			return &Content{ConnectionID: &id}, nil
+		case contentPayload:
+			return &Content{Content: append([]byte{}, body[1:]...)}, nil
+		case contentENRs:
+			nodes, err := decodeENRs(body[1:])
+			if err != nil {
+				return nil, err
+			}
+			return &Content{ENRs: nodes}, nil
+		default:
+			return nil, errSSZInvalid
+		}
+
+	case OfferMsg:
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		keys, err := decodeByteLists(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) > maxOfferKeys {
+			return nil, errors.New("too many offered contents")
+		}
+		return &Offer{ContentKeys: keys}, nil
+
+	case AcceptMsg:
+		id := binary.BigEndian.Uint16(r.fixedBytes(2))
+		r.variable()
+		fields, err := r.vars()
+		if err != nil {
+			return nil, err
+		}
+		bits, err := decodeBitlist(fields[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Accept{ConnectionID: id, ContentKeys: bits}, nil
+
+	default:
+		return nil, errUnknownMessage
+	}
+}
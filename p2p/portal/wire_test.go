@@ -0,0 +1,115 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package portal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/holiman/uint256"
+)
+
+func TestMessageRoundtrip(t *testing.T) {
+	var (
+		node   = newTestKeyNode(t, 30303).Node()
+		connID = uint16(0x1234)
+	)
+	msgs := []Message{
+		&Ping{ENRSeq: 5, Radius: uint256.NewInt(0xff)},
+		&Pong{ENRSeq: 1, Radius: new(uint256.Int).SetAllOne()},
+		&FindNodes{Distances: []uint16{256, 255}},
+		&Nodes{Total: 1, ENRs: []*enode.Node{node}},
+		&FindContent{ContentKey: HeaderKey([32]byte{1})},
+		&Content{ConnectionID: &connID},
+		&Content{Content: []byte{1, 2, 3}},
+		&Content{ENRs: []*enode.Node{node}},
+		&Offer{ContentKeys: [][]byte{HeaderKey([32]byte{1}), BodyKey([32]byte{2})}},
+		&Accept{ConnectionID: 7, ContentKeys: []bool{true, false, false, true, true, false, true, false, true}},
+	}
+	for _, msg := range msgs {
+		enc := EncodeMessage(msg)
+		dec, err := DecodeMessage(enc)
+		if err != nil {
+			t.Fatalf("%T: decode error: %v", msg, err)
+		}
+		if !reflect.DeepEqual(EncodeMessage(dec), enc) {
+			t.Fatalf("%T: roundtrip mismatch:\nhave %x\nwant %x", msg, EncodeMessage(dec), enc)
+		}
+	}
+}
+
+// This checks the encoding against the test vectors of the portal wire protocol
+// specification.
+func TestMessageEncoding(t *testing.T) {
+	radius := new(uint256.Int).SetAllOne()
+	radius.Sub(radius, uint256.NewInt(1))
+	tests := []struct {
+		msg  Message
+		want string
+	}{
+		{
+			msg:  &Ping{ENRSeq: 1, Radius: radius},
+			want: "0x0001000000000000000c000000feffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+		},
+		{
+			msg:  &FindNodes{Distances: []uint16{256, 255}},
+			want: "0x02040000000001ff00",
+		},
+		{
+			msg:  &FindContent{ContentKey: hexutil.MustDecode("0x706f7274616c")},
+			want: "0x0404000000706f7274616c",
+		},
+		{
+			msg:  &Content{Content: hexutil.MustDecode("0x7468652063616b652069732061206c6965")},
+			want: "0x05017468652063616b652069732061206c6965",
+		},
+		{
+			msg:  &Offer{ContentKeys: [][]byte{{0x01, 0x01, 0x01}}},
+			want: "0x060400000004000000010101",
+		},
+		{
+			msg:  &Accept{ConnectionID: 0x0102, ContentKeys: []bool{true, false, false, false, false, false, false, false}},
+			want: "0x070102060000000101",
+		},
+	}
+	for _, test := range tests {
+		if enc := hexutil.Encode(EncodeMessage(test.msg)); enc != test.want {
+			t.Errorf("%T: wrong encoding\nhave %s\nwant %s", test.msg, enc, test.want)
+		}
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	inputs := []string{
+		"0x",
+		"0x00",
+		"0x0001000000000000000d000000", // bad offset
+		"0x02050000000001ff00",         // bad offset
+		"0x0203000000",                 // truncated
+		"0x0504",                       // bad union selector
+		"0x0500",                       // truncated connection ID
+		"0x0702010600000000",           // bitlist without delimiter
+		"0x09",                         // unknown message
+	}
+	for _, input := range inputs {
+		if msg, err := DecodeMessage(hexutil.MustDecode(input)); err == nil {
+			t.Errorf("input %s: expected error, got %#v", input, msg)
+		}
+	}
+}
@@ -61,6 +61,10 @@ type Protocol struct {
 
 	// Attributes contains protocol specific information for the node record.
 	Attributes []enr.Entry
+
+	// Bulk marks protocols transferring bulk data, such as state sync. Their
+	// traffic is throttled first when the server's total bandwidth limit is hit.
+	Bulk bool
 }
 
 func (p Protocol) cap() Cap {
@@ -0,0 +1,204 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+const (
+	// Reputation defaults.
+	defaultBanThreshold = -100
+	defaultBanDuration  = 6 * time.Hour
+
+	// scoreHalfLife is the time after which penalties are halved.
+	scoreHalfLife = time.Hour
+
+	// scoreForget is the magnitude below which an unbanned reputation is dropped.
+	scoreForget = 1
+
+	// protocolErrorPenalty is applied when a peer is disconnected for breaching
+	// the devp2p protocol.
+	protocolErrorPenalty = 25
+)
+
+// BanInfo describes a banned node.
+type BanInfo struct {
+	ID     enode.ID  `json:"id"`
+	Score  float64   `json:"score"`
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason"`
+}
+
+// reputation tracks the scores of remote nodes. Protocols lower the score of
+// misbehaving peers, scores recover over time, and nodes whose score drops below
+// the threshold are banned for a while. Scores and bans are persisted in the
+// node database.
+type reputation struct {
+	db        *enode.DB
+	threshold float64
+	duration  time.Duration
+	now       func() time.Time
+	log       log.Logger
+
+	lock sync.Mutex
+	bans map[enode.ID]time.Time // end of the ban for each banned node
+}
+
+func newReputation(db *enode.DB, threshold int, duration time.Duration, logger log.Logger) *reputation {
+	if threshold >= 0 {
+		threshold = defaultBanThreshold
+	}
+	if duration <= 0 {
+		duration = defaultBanDuration
+	}
+	r := &reputation{
+		db:        db,
+		threshold: float64(threshold),
+		duration:  duration,
+		now:       time.Now,
+		log:       logger,
+		bans:      make(map[enode.ID]time.Time),
+	}
+	// Load the bans still in effect and drop the reputations which faded away.
+	now := r.now()
+	for id, rep := range db.Reputations() {
+		if rep.BannedUntil.After(now) {
+			r.bans[id] = rep.BannedUntil
+			continue
+		}
+		if math.Abs(decayScore(rep.Score, now.Sub(rep.Updated))) < scoreForget {
+			db.DeleteReputation(id)
+		}
+	}
+	return r
+}
+
+// decayScore returns the value of score after elapsed time.
+func decayScore(score float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return score
+	}
+	return score * math.Pow(0.5, float64(elapsed)/float64(scoreHalfLife))
+}
+
+// banned reports whether the given node is currently banned.
+func (r *reputation) banned(id enode.ID) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until, ok := r.bans[id]
+	if !ok {
+		return false
+	}
+	if !until.After(r.now()) {
+		delete(r.bans, id)
+		return false
+	}
+	return true
+}
+
+// penalize lowers the score of a node, banning it if the score drops below the
+// threshold. It returns whether the node got banned.
+func (r *reputation) penalize(id enode.ID, points int, reason string) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := r.now()
+	rep, _ := r.db.Reputation(id)
+	rep.Score = decayScore(rep.Score, now.Sub(rep.Updated)) - float64(points)
+	rep.Updated = now
+	rep.Reason = reason
+
+	banned := false
+	if rep.Score <= r.threshold && !rep.BannedUntil.After(now) {
+		rep.BannedUntil = now.Add(r.duration)
+		r.bans[id] = rep.BannedUntil
+		banned = true
+		r.log.Debug("Banned p2p peer", "id", id, "score", rep.Score, "until", rep.BannedUntil, "reason", reason)
+	}
+	if err := r.db.UpdateReputation(id, rep); err != nil {
+		r.log.Warn("Failed to store peer reputation", "id", id, "err", err)
+	}
+	return banned
+}
+
+// ban bans a node for the given duration, regardless of its score.
+func (r *reputation) ban(id enode.ID, duration time.Duration, reason string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if duration <= 0 {
+		duration = r.duration
+	}
+	now := r.now()
+	rep, _ := r.db.Reputation(id)
+	rep.Score = decayScore(rep.Score, now.Sub(rep.Updated))
+	rep.Updated = now
+	rep.BannedUntil = now.Add(duration)
+	rep.Reason = reason
+
+	r.bans[id] = rep.BannedUntil
+	if err := r.db.UpdateReputation(id, rep); err != nil {
+		r.log.Warn("Failed to store peer reputation", "id", id, "err", err)
+	}
+}
+
+// unban lifts the ban of a node and resets its score. It returns whether the
+// node was banned.
+func (r *reputation) unban(id enode.ID) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	until, ok := r.bans[id]
+	delete(r.bans, id)
+	r.db.DeleteReputation(id)
+	return ok && until.After(r.now())
+}
+
+// list returns the bans in effect, ordered by their end.
+func (r *reputation) list() []*BanInfo {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	var (
+		now  = r.now()
+		bans = make([]*BanInfo, 0, len(r.bans))
+	)
+	for id, until := range r.bans {
+		if !until.After(now) {
+			delete(r.bans, id)
+			continue
+		}
+		info := &BanInfo{ID: id, Until: until}
+		if rep, ok := r.db.Reputation(id); ok {
+			info.Score = decayScore(rep.Score, now.Sub(rep.Updated))
+			info.Reason = rep.Reason
+		}
+		bans = append(bans, info)
+	}
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Until.Before(bans[j].Until)
+	})
+	return bans
+}
@@ -0,0 +1,100 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package p2p
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+)
+
+func TestReputationBan(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		now = time.Now().Truncate(time.Second)
+		id  = enode.ID{0x01}
+		rep = newReputation(db, -50, time.Hour, log.Root())
+	)
+	rep.now = func() time.Time { return now }
+
+	// Penalties below the threshold don't ban.
+	if rep.penalize(id, 30, "first") {
+		t.Fatal("banned below the threshold")
+	}
+	// Penalties decay over time, so after a half-life the same penalty won't ban.
+	now = now.Add(scoreHalfLife)
+	if rep.penalize(id, 30, "second") {
+		t.Fatal("banned despite decayed score")
+	}
+	if rep.banned(id) {
+		t.Fatal("node banned")
+	}
+	// Another penalty right away crosses the threshold.
+	if !rep.penalize(id, 30, "third") {
+		t.Fatal("not banned above the threshold")
+	}
+	if !rep.banned(id) {
+		t.Fatal("node not banned")
+	}
+	// The ban must survive a restart.
+	reloaded := newReputation(db, -50, time.Hour, log.Root())
+	reloaded.now = rep.now
+	if !reloaded.banned(id) {
+		t.Fatal("ban lost after reload")
+	}
+	if bans := reloaded.list(); len(bans) != 1 || bans[0].Reason != "third" || bans[0].Until != now.Add(time.Hour) {
+		t.Fatalf("wrong ban list: %v", bans)
+	}
+	// The ban expires after the configured duration.
+	now = now.Add(time.Hour)
+	if reloaded.banned(id) {
+		t.Fatal("ban not expired")
+	}
+}
+
+func TestReputationUnban(t *testing.T) {
+	db, _ := enode.OpenDB("")
+	defer db.Close()
+
+	var (
+		id  = enode.ID{0x01}
+		rep = newReputation(db, 0, 0, log.Root())
+	)
+	if rep.unban(id) {
+		t.Fatal("unbanned node reported as banned")
+	}
+	rep.ban(id, 0, "admin")
+	if !rep.banned(id) {
+		t.Fatal("node not banned")
+	}
+	if bans := rep.list(); len(bans) != 1 || bans[0].Until.Sub(time.Now()) > defaultBanDuration {
+		t.Fatalf("wrong ban list: %v", bans)
+	}
+	if !rep.unban(id) {
+		t.Fatal("banned node not reported as banned")
+	}
+	if rep.banned(id) {
+		t.Fatal("node still banned")
+	}
+	if _, ok := db.Reputation(id); ok {
+		t.Fatal("reputation not reset")
+	}
+}
@@ -129,6 +129,15 @@ type Config struct {
 	// live nodes in the network.
 	NodeDatabase string `toml:",omitempty"`
 
+	// BanThreshold is the reputation score below which a peer gets banned. Peers
+	// start at zero, protocols lower the score of misbehaving peers and it recovers
+	// over time. It must be negative, zero defaults to a preset value.
+	BanThreshold int `toml:",omitempty"`
+
+	// BanDuration is the time a peer stays banned after its reputation dropped
+	// below BanThreshold. Zero defaults to a preset value.
+	BanDuration time.Duration `toml:",omitempty"`
+
 	// Protocols should contain the protocols supported
 	// by the server. Matching protocols are launched for
 	// each peer.
@@ -158,6 +167,20 @@ type Config struct {
 	// If NoDial is true, the server will not dial any peers.
 	NoDial bool `toml:",omitempty"`
 
+	// MaxEgress and MaxIngress limit the total traffic of all protocols, in bytes
+	// per second. When reached, the traffic of bulk protocols is throttled first.
+	// Zero means unlimited.
+	MaxEgress  uint64 `toml:",omitempty"`
+	MaxIngress uint64 `toml:",omitempty"`
+
+	// BandwidthLimits limits the traffic of individual protocols, keyed by
+	// protocol name.
+	BandwidthLimits map[string]BandwidthLimit `toml:",omitempty"`
+
+	// If CaptureDir is set, the decrypted messages exchanged with each peer are
+	// recorded into a capture file in this directory. See package p2p/capture.
+	CaptureDir string `toml:",omitempty"`
+
 	// If EnableMsgEvents is set then the server will emit PeerEvents
 	// whenever a message is sent to or received from a peer
 	EnableMsgEvents bool
@@ -188,12 +211,14 @@ type Server struct {
 	peerFeed     event.Feed
 	log          log.Logger
 
-	nodedb    *enode.DB
-	localnode *enode.LocalNode
-	ntab      *discover.UDPv4
-	DiscV5    *discover.UDPv5
-	discmix   *enode.FairMix
-	dialsched *dialScheduler
+	nodedb     *enode.DB
+	reputation *reputation
+	bandwidth  *bandwidth
+	localnode  *enode.LocalNode
+	ntab       *discover.UDPv4
+	DiscV5     *discover.UDPv5
+	discmix    *enode.FairMix
+	dialsched  *dialScheduler
 
 	// This is read by the NAT port mapping loop.
 	portMappingRegister chan *portMapping
@@ -482,6 +507,7 @@ func (srv *Server) Start() (err error) {
 	srv.removetrusted = make(chan *enode.Node)
 	srv.peerOp = make(chan peerOpFunc)
 	srv.peerOpDone = make(chan struct{})
+	srv.bandwidth = newBandwidth(srv.MaxEgress, srv.MaxIngress, srv.BandwidthLimits)
 
 	if err := srv.setupLocalNode(); err != nil {
 		return err
@@ -518,6 +544,7 @@ func (srv *Server) setupLocalNode() error {
 		return err
 	}
 	srv.nodedb = db
+	srv.reputation = newReputation(db, srv.BanThreshold, srv.BanDuration, srv.log)
 	srv.localnode = enode.NewLocalNode(db, srv.PrivateKey)
 	srv.localnode.SetFallbackIP(net.IP{127, 0, 0, 1})
 	// TODO: check conflicts
@@ -599,6 +626,7 @@ func (srv *Server) setupDialScheduler() {
 		maxActiveDials: srv.MaxPendingPeers,
 		log:            srv.Logger,
 		netRestrict:    srv.NetRestrict,
+		banned:         srv.reputation.banned,
 		dialer:         srv.Dialer,
 		clock:          srv.clock,
 	}
@@ -824,6 +852,8 @@ func (srv *Server) postHandshakeChecks(peers map[enode.ID]*Peer, inboundCount in
 		return DiscAlreadyConnected
 	case c.node.ID() == srv.localnode.ID():
 		return DiscSelf
+	case !c.is(trustedConn|staticDialedConn) && srv.reputation.banned(c.node.ID()):
+		return errBanned
 	default:
 		return nil
 	}
@@ -1027,6 +1057,11 @@ func (srv *Server) checkpoint(c *conn, stage chan<- *conn) error {
 
 func (srv *Server) launchPeer(c *conn) *Peer {
 	p := newPeer(srv.log, c, srv.Protocols)
+	p.reputation = srv.reputation
+	srv.bandwidth.attach(p)
+	if srv.CaptureDir != "" {
+		srv.setupCapture(p)
+	}
 	if srv.EnableMsgEvents {
 		// If message events are enabled, pass the peerFeed
 		// to the peer.
@@ -1055,6 +1090,12 @@ func (srv *Server) runPeer(p *Peer) {
 	// The main loop waits for existing peers to be sent on srv.delpeer
 	// before returning, so this send should not select on srv.quit.
 	srv.delpeer <- peerDrop{p, err, remoteRequested}
+	srv.bandwidth.detach(p)
+
+	// Penalize the peer if it was dropped for breaching the protocol.
+	if !remoteRequested && discReasonForError(err) == DiscProtocolError {
+		p.Penalize(protocolErrorPenalty, err.Error())
+	}
 
 	// Broadcast peer drop to external subscribers. This needs to be
 	// after the send to delpeer so subscribers have a consistent view of
@@ -1069,6 +1110,47 @@ func (srv *Server) runPeer(p *Peer) {
 	})
 }
 
+// BanPeer bans the given node for the given duration, or the configured ban
+// duration if zero. The node is removed from the static node set and it is
+// disconnected if currently connected.
+func (srv *Server) BanPeer(node *enode.Node, duration time.Duration, reason string) {
+	srv.reputation.ban(node.ID(), duration, reason)
+	srv.doPeerOp(func(peers map[enode.ID]*Peer) {
+		srv.dialsched.removeStatic(node)
+		if peer := peers[node.ID()]; peer != nil {
+			peer.Disconnect(DiscUselessPeer)
+		}
+	})
+}
+
+// UnbanPeer lifts the ban of the given node and resets its reputation. It
+// returns whether the node was banned.
+func (srv *Server) UnbanPeer(id enode.ID) bool {
+	return srv.reputation.unban(id)
+}
+
+// Bans returns the bans currently in effect.
+func (srv *Server) Bans() []*BanInfo {
+	return srv.reputation.list()
+}
+
+// SetMaxBandwidth updates the total bandwidth limits of all protocols, in bytes
+// per second. Zero means unlimited.
+func (srv *Server) SetMaxBandwidth(maxEgress, maxIngress uint64) {
+	srv.bandwidth.setMax(maxEgress, maxIngress)
+}
+
+// SetBandwidthLimit updates the bandwidth limits of a protocol, applying them
+// to the connected peers as well.
+func (srv *Server) SetBandwidthLimit(protocol string, limit BandwidthLimit) {
+	srv.bandwidth.setLimit(protocol, limit)
+}
+
+// Bandwidth returns the bandwidth limits in effect.
+func (srv *Server) Bandwidth() *BandwidthInfo {
+	return srv.bandwidth.info()
+}
+
 // NodeInfo represents a short summary of the information known about the host.
 type NodeInfo struct {
 	ID    string `json:"id"`    // Unique node identifier (also the encryption key)
@@ -125,9 +125,9 @@ func (srv *Server) portMappingLoop() {
 			if err != nil {
 				log.Debug("Couldn't get external IP", "err", err, "interface", srv.NAT)
 			} else if !ip.Equal(lastExtIP) {
-				log.Debug("External IP changed", "ip", extip, "interface", srv.NAT)
+				log.Debug("External IP changed", "ip", ip, "interface", srv.NAT)
 			} else {
-				return
+				continue
 			}
 			// Here, we either failed to get the external IP, or it has changed.
 			lastExtIP = ip
@@ -181,7 +181,37 @@ func (srv *Server) portMappingLoop() {
 				case "UDP":
 					srv.localnode.SetFallbackUDP(m.extPort)
 				}
+				srv.setMappedEndpoints(m)
 			}
 		}
 	}
 }
+
+// setMappedEndpoints updates the local node with the external endpoints of a
+// mapping, if the NAT interface reports them. This is how IPv6 endpoints, which
+// may use a different port than IPv4, get into the ENR.
+func (srv *Server) setMappedEndpoints(m *portMapping) {
+	mapper, ok := srv.NAT.(nat.EndpointMapper)
+	if !ok {
+		return
+	}
+	var port6 int
+	for _, ep := range mapper.ExternalEndpoints(m.protocol, m.port) {
+		srv.localnode.SetStaticIP(ep.IP)
+		if ep.IP.To4() == nil {
+			port6 = ep.Port
+		}
+	}
+	switch m.protocol {
+	case "TCP":
+		if port6 != 0 && port6 != m.extPort {
+			srv.localnode.Set(enr.TCP6(port6))
+		} else {
+			srv.localnode.Delete(enr.TCP6(0))
+		}
+	case "UDP":
+		if port6 != 0 {
+			srv.localnode.SetFallbackUDP6(port6)
+		}
+	}
+}
@@ -25,6 +25,9 @@ import (
 	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/internal/testlog"
 	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/p2p/enr"
+	"github.com/ethereum/go-ethereum/p2p/nat"
 )
 
 func TestServerPortMapping(t *testing.T) {
@@ -75,6 +78,56 @@ func TestServerPortMapping(t *testing.T) {
 	}
 }
 
+func TestServerPortMappingEndpoints(t *testing.T) {
+	clock := new(mclock.Simulated)
+	mockNAT := &mockEndpointNAT{mockNAT: mockNAT{mappedPort: 30000}, port6: 30001}
+	srv := Server{
+		Config: Config{
+			PrivateKey: newkey(),
+			NoDial:     true,
+			ListenAddr: ":0",
+			NAT:        mockNAT,
+			Logger:     testlog.Logger(t, log.LvlTrace),
+			clock:      clock,
+		},
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Stop()
+
+	deadline := clock.Now().Add(portMapRefreshInterval)
+	for clock.Now() < deadline && mockNAT.mapRequests.Load() < 2 {
+		time.Sleep(10 * time.Millisecond)
+		clock.Run(1 * time.Second)
+	}
+	// Wait for the loop to apply the endpoints of the last mapping.
+	var (
+		n    *enode.Node
+		ip6  enr.IPv6
+		tcp6 enr.TCP6
+		udp6 enr.UDP6
+	)
+	for i := 0; i < 100; i++ {
+		if n = srv.LocalNode().Node(); n.Load(&udp6) == nil && n.Load(&tcp6) == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n.Load(&ip6) != nil || !net.IP(ip6).Equal(net.ParseIP("2001:db8::1")) {
+		t.Error("wrong IPv6 in ENR:", net.IP(ip6))
+	}
+	if n.Load(&tcp6) != nil || tcp6 != 30001 {
+		t.Error("wrong TCP6 port in ENR:", tcp6)
+	}
+	if n.Load(&udp6) != nil || udp6 != 30001 {
+		t.Error("wrong UDP6 port in ENR:", udp6)
+	}
+	if n.IP().String() != "192.0.2.0" || n.TCP() != 30000 || n.UDP() != 30000 {
+		t.Error("wrong IPv4 endpoint in ENR:", n.IP(), n.TCP(), n.UDP())
+	}
+}
+
 type mockNAT struct {
 	mappedPort    uint16
 	mapRequests   atomic.Int32
@@ -100,3 +153,16 @@ func (m *mockNAT) ExternalIP() (net.IP, error) {
 func (m *mockNAT) String() string {
 	return "mockNAT"
 }
+
+// mockEndpointNAT is a mockNAT which also reports an IPv6 endpoint.
+type mockEndpointNAT struct {
+	mockNAT
+	port6 int
+}
+
+func (m *mockEndpointNAT) ExternalEndpoints(protocol string, intport int) []nat.Endpoint {
+	return []nat.Endpoint{
+		{IP: net.ParseIP("192.0.2.0"), Port: int(m.mappedPort)},
+		{IP: net.ParseIP("2001:db8::1"), Port: m.port6},
+	}
+}
@@ -375,6 +375,61 @@ func TestServerPeerLimits(t *testing.T) {
 	conn.Close()
 }
 
+func TestServerBanPeer(t *testing.T) {
+	srvkey := newkey()
+	clientkey := newkey()
+	clientnode := enode.NewV4(&clientkey.PublicKey, nil, 0, 0)
+
+	var tp = &setupTransport{
+		pubkey: &clientkey.PublicKey,
+		phs: protoHandshake{
+			ID: crypto.FromECDSAPub(&clientkey.PublicKey)[1:],
+			// Force "DiscUselessPeer" due to unmatching caps
+		},
+	}
+	srv := &Server{
+		Config: Config{
+			PrivateKey:  srvkey,
+			MaxPeers:    10,
+			NoDial:      true,
+			NoDiscovery: true,
+			Protocols:   []Protocol{discard},
+			Logger:      testlog.Logger(t, log.LvlTrace),
+		},
+		newTransport: func(fd net.Conn, dialDest *ecdsa.PublicKey) transport { return tp },
+	}
+	if err := srv.Start(); err != nil {
+		t.Fatalf("couldn't start server: %v", err)
+	}
+	defer srv.Stop()
+
+	// Banned nodes are rejected after the encryption handshake.
+	srv.BanPeer(clientnode, time.Hour, "test")
+	if bans := srv.Bans(); len(bans) != 1 || bans[0].ID != clientnode.ID() || bans[0].Reason != "test" {
+		t.Fatalf("wrong bans: %v", bans)
+	}
+	conn, _ := net.Pipe()
+	srv.SetupConn(conn, inboundConn, nil)
+	if tp.closeErr != errBanned {
+		t.Errorf("unexpected close error for banned node: %q", tp.closeErr)
+	}
+	conn.Close()
+
+	// Once unbanned, the node proceeds to the protocol handshake.
+	if !srv.UnbanPeer(clientnode.ID()) {
+		t.Fatal("node not reported as banned")
+	}
+	if bans := srv.Bans(); len(bans) != 0 {
+		t.Fatalf("bans left after unban: %v", bans)
+	}
+	conn, _ = net.Pipe()
+	srv.SetupConn(conn, inboundConn, nil)
+	if tp.closeErr != DiscUselessPeer {
+		t.Errorf("unexpected close error for unbanned node: %q", tp.closeErr)
+	}
+	conn.Close()
+}
+
 func TestServerSetupConn(t *testing.T) {
 	var (
 		clientkey, srvkey = newkey(), newkey()
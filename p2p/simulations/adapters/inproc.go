@@ -17,13 +17,16 @@
 package adapters
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"math"
 	"net"
 	"sync"
 
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/node"
@@ -40,6 +43,8 @@ type SimAdapter struct {
 	pipe       func() (net.Conn, net.Conn, error)
 	mtx        sync.RWMutex
 	nodes      map[enode.ID]*SimNode
+	links      map[[2]enode.ID]*pipes.Link
+	clock      mclock.Clock // clock of the links, nil for the system clock
 	lifecycles LifecycleConstructors
 }
 
@@ -51,6 +56,7 @@ func NewSimAdapter(services LifecycleConstructors) *SimAdapter {
 	return &SimAdapter{
 		pipe:       pipes.NetPipe,
 		nodes:      make(map[enode.ID]*SimNode),
+		links:      make(map[[2]enode.ID]*pipes.Link),
 		lifecycles: services,
 	}
 }
@@ -96,7 +102,7 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 			PrivateKey:      config.PrivateKey,
 			MaxPeers:        math.MaxInt32,
 			NoDiscovery:     true,
-			Dialer:          s,
+			Dialer:          &simNodeDialer{s, id},
 			EnableMsgEvents: config.EnableMsgEvents,
 		},
 		ExternalSigner: config.ExternalSigner,
@@ -120,6 +126,11 @@ func (s *SimAdapter) NewNode(config *NodeConfig) (Node, error) {
 // Dial implements the p2p.NodeDialer interface by connecting to the node using
 // an in-memory net.Pipe
 func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn, err error) {
+	return s.dial(s.pipe, dest)
+}
+
+// dial connects to dest using the given pipe function.
+func (s *SimAdapter) dial(pipe func() (net.Conn, net.Conn, error), dest *enode.Node) (conn net.Conn, err error) {
 	node, ok := s.GetNode(dest.ID())
 	if !ok {
 		return nil, fmt.Errorf("unknown node: %s", dest.ID())
@@ -129,7 +140,7 @@ func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn,
 		return nil, fmt.Errorf("node not running: %s", dest.ID())
 	}
 	// SimAdapter.pipe is net.Pipe (NewSimAdapter)
-	pipe1, pipe2, err := s.pipe()
+	pipe1, pipe2, err := pipe()
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +151,58 @@ func (s *SimAdapter) Dial(ctx context.Context, dest *enode.Node) (conn net.Conn,
 	return pipe2, nil
 }
 
+// SetClock sets the clock which measures the delays of links created afterwards.
+func (s *SimAdapter) SetClock(clock mclock.Clock) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.clock = clock
+}
+
+// Link returns the simulated link between two nodes, creating it if necessary.
+// Once the link exists, connections between the nodes are carried over it and
+// are subject to its conditions.
+func (s *SimAdapter) Link(a, b enode.ID) *pipes.Link {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	key := linkKey(a, b)
+	link, ok := s.links[key]
+	if !ok {
+		seed := int64(binary.BigEndian.Uint64(key[0][:8]) ^ binary.BigEndian.Uint64(key[1][:8]))
+		link = pipes.NewLink(seed, s.clock)
+		s.links[key] = link
+	}
+	return link
+}
+
+func (s *SimAdapter) getLink(a, b enode.ID) *pipes.Link {
+	s.mtx.RLock()
+	defer s.mtx.RUnlock()
+	return s.links[linkKey(a, b)]
+}
+
+func linkKey(a, b enode.ID) [2]enode.ID {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return [2]enode.ID{a, b}
+}
+
+// simNodeDialer is the p2p.NodeDialer of a SimNode. It routes connections through
+// the link between the nodes, if there is one.
+type simNodeDialer struct {
+	adapter *SimAdapter
+	self    enode.ID
+}
+
+func (d *simNodeDialer) Dial(ctx context.Context, dest *enode.Node) (net.Conn, error) {
+	pipe := d.adapter.pipe
+	if link := d.adapter.getLink(d.self, dest.ID()); link != nil {
+		pipe = func() (net.Conn, net.Conn, error) { return link.Pipe(d.adapter.pipe) }
+	}
+	return d.adapter.dial(pipe, dest)
+}
+
 // DialRPC implements the RPCDialer interface by creating an in-memory RPC
 // client of the given node
 func (s *SimAdapter) DialRPC(id enode.ID) (*rpc.Client, error) {
@@ -18,11 +18,14 @@ package adapters
 
 import (
 	"bytes"
+	"context"
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"sync"
 	"testing"
 
+	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
 )
 
@@ -200,3 +203,48 @@ func TestNetPipeBidirections(t *testing.T) {
 		}
 	}
 }
+
+type testLifecycle struct{}
+
+func (testLifecycle) Start() error { return nil }
+func (testLifecycle) Stop() error  { return nil }
+
+func TestSimAdapterLink(t *testing.T) {
+	adapter := NewSimAdapter(LifecycleConstructors{
+		"test": func(ctx *ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+			return testLifecycle{}, nil
+		},
+	})
+	var nodes []*SimNode
+	for i := 0; i < 2; i++ {
+		config := RandomNodeConfig()
+		config.Lifecycles = []string{"test"}
+		n, err := adapter.NewNode(config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := n.Start(nil); err != nil {
+			t.Fatal(err)
+		}
+		defer n.Stop()
+		nodes = append(nodes, n.(*SimNode))
+	}
+
+	link := adapter.Link(nodes[0].ID, nodes[1].ID)
+	if adapter.Link(nodes[1].ID, nodes[0].ID) != link {
+		t.Fatal("link is not symmetric")
+	}
+	dialer := nodes[0].Server().Dialer
+
+	// Dialing fails while the link is down.
+	link.Down()
+	if _, err := dialer.Dial(context.Background(), nodes[1].Node()); !errors.Is(err, pipes.ErrLinkDown) {
+		t.Fatalf("wrong dial error %v, want %v", err, pipes.ErrLinkDown)
+	}
+	link.Up()
+	conn, err := dialer.Dial(context.Background(), nodes[1].Node())
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
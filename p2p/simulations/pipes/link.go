@@ -0,0 +1,289 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pipes
+
+import (
+	"errors"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+const (
+	defaultRetransmitTimeout = 200 * time.Millisecond
+	maxRetransmits           = 6
+	linkQueueSize            = 256 // max writes in flight per direction
+)
+
+// ErrLinkDown is returned when creating a connection over a link which is down.
+var ErrLinkDown = errors.New("link is down")
+
+// Conditions describes the behavior of a simulated link.
+type Conditions struct {
+	// Latency is the one-way delay of each write.
+	Latency time.Duration
+
+	// LossRate is the probability that a write gets lost. Connections are reliable
+	// streams like TCP, so lost writes are retransmitted and only arrive late.
+	LossRate float64
+
+	// RetransmitTimeout is the delay added for each loss of a write. It doubles
+	// for each consecutive loss. The default is 200ms.
+	RetransmitTimeout time.Duration
+}
+
+// Link is a simulated network link. Connections created over a link are subject to its
+// conditions, which can be changed while the connections are active.
+//
+// A link can be taken down to partition the network. While the link is down, writes are
+// held back, as if packets were dropped by the network, and new connections can't be
+// established. The held data is delivered when the link comes back up, unless the
+// connection was closed in the meantime.
+type Link struct {
+	clock mclock.Clock
+
+	mu   sync.Mutex
+	cond Conditions
+	rand *rand.Rand
+	up   chan struct{} // closed while the link is up
+}
+
+// NewLink creates a link. The seed initializes the random source used for simulating
+// packet loss. Write delays are measured with the given clock, the system clock is
+// used if it is nil.
+func NewLink(seed int64, clock mclock.Clock) *Link {
+	if clock == nil {
+		clock = mclock.System{}
+	}
+	up := make(chan struct{})
+	close(up)
+	return &Link{clock: clock, rand: rand.New(rand.NewSource(seed)), up: up}
+}
+
+// SetConditions changes the link conditions.
+func (l *Link) SetConditions(c Conditions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cond = c
+}
+
+// Conditions returns the current link conditions.
+func (l *Link) Conditions() Conditions {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cond
+}
+
+// Down takes the link down.
+func (l *Link) Down() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.isUp() {
+		l.up = make(chan struct{})
+	}
+}
+
+// Up brings the link back up.
+func (l *Link) Up() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.isUp() {
+		close(l.up)
+	}
+}
+
+// IsUp reports whether the link is up.
+func (l *Link) IsUp() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.isUp()
+}
+
+func (l *Link) isUp() bool {
+	select {
+	case <-l.up:
+		return true
+	default:
+		return false
+	}
+}
+
+// waitUp returns a channel which is closed when the link is up.
+func (l *Link) waitUp() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.up
+}
+
+// delay computes the transmission delay of a single write.
+func (l *Link) delay() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	d := l.cond.Latency
+	rto := l.cond.RetransmitTimeout
+	if rto == 0 {
+		rto = defaultRetransmitTimeout
+	}
+	for i := 0; i < maxRetransmits && l.cond.LossRate > 0 && l.rand.Float64() < l.cond.LossRate; i++ {
+		d += rto
+		rto *= 2
+	}
+	return d
+}
+
+// Pipe creates a connection pair using the given pipe function and routes both
+// directions through the link.
+func (l *Link) Pipe(pipe func() (net.Conn, net.Conn, error)) (net.Conn, net.Conn, error) {
+	if !l.IsUp() {
+		return nil, nil, ErrLinkDown
+	}
+	c1, c2, err := pipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	return newLinkConn(c1, l), newLinkConn(c2, l), nil
+}
+
+// linkConn delays writes on a connection according to the link conditions.
+type linkConn struct {
+	net.Conn
+	link *Link
+
+	mu            sync.Mutex
+	lastDelivery  mclock.AbsTime
+	writeDeadline time.Time
+	err           error
+
+	queue     chan linkWrite
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+type linkWrite struct {
+	data []byte
+	at   mclock.AbsTime
+}
+
+func newLinkConn(c net.Conn, l *Link) *linkConn {
+	lc := &linkConn{
+		Conn:   c,
+		link:   l,
+		queue:  make(chan linkWrite, linkQueueSize),
+		closed: make(chan struct{}),
+	}
+	go lc.deliverLoop()
+	return lc
+}
+
+// Write queues b for delivery. It blocks only when too many writes are in flight.
+func (c *linkConn) Write(b []byte) (int, error) {
+	select {
+	case <-c.closed:
+		return 0, net.ErrClosed
+	default:
+	}
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		return 0, err
+	}
+	at := c.link.clock.Now().Add(c.link.delay())
+	if at < c.lastDelivery {
+		at = c.lastDelivery // keep the stream in order
+	}
+	c.lastDelivery = at
+	deadline := c.writeDeadline
+	c.mu.Unlock()
+
+	w := linkWrite{data: append([]byte(nil), b...), at: at}
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case c.queue <- w:
+		return len(b), nil
+	case <-timeout:
+		return 0, os.ErrDeadlineExceeded
+	case <-c.closed:
+		return 0, net.ErrClosed
+	}
+}
+
+func (c *linkConn) deliverLoop() {
+	for {
+		var w linkWrite
+		select {
+		case w = <-c.queue:
+		case <-c.closed:
+			return
+		}
+		if d := time.Duration(w.at - c.link.clock.Now()); d > 0 {
+			timer := c.link.clock.NewTimer(d)
+			select {
+			case <-timer.C():
+			case <-c.closed:
+				timer.Stop()
+				return
+			}
+		}
+		select {
+		case <-c.link.waitUp():
+		case <-c.closed:
+			return
+		}
+		if _, err := c.Conn.Write(w.data); err != nil {
+			c.mu.Lock()
+			c.err = err
+			c.mu.Unlock()
+			c.Close()
+			return
+		}
+	}
+}
+
+// SetDeadline sets the read and write deadlines.
+func (c *linkConn) SetDeadline(t time.Time) error {
+	c.SetWriteDeadline(t)
+	return c.Conn.SetReadDeadline(t)
+}
+
+// SetWriteDeadline sets the deadline for queueing writes.
+func (c *linkConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writeDeadline = t
+	return nil
+}
+
+// Close closes the connection. Writes which haven't been delivered yet are discarded.
+func (c *linkConn) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closed)
+		err = c.Conn.Close()
+	})
+	return err
+}
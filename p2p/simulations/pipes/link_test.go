@@ -0,0 +1,179 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package pipes
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+func TestLinkLatency(t *testing.T) {
+	link := NewLink(1, nil)
+	link.SetConditions(Conditions{Latency: 50 * time.Millisecond})
+	c1, c2, err := link.Pipe(NetPipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c1.Write([]byte{byte(i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	buf := make([]byte, 3)
+	if _, err := io.ReadFull(c2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Fatalf("data arrived after %v, want at least 50ms", elapsed)
+	}
+	if !bytes.Equal(buf, []byte{0, 1, 2}) {
+		t.Fatalf("wrong data %x", buf)
+	}
+}
+
+func TestLinkSimulatedClock(t *testing.T) {
+	clock := new(mclock.Simulated)
+	link := NewLink(1, clock)
+	link.SetConditions(Conditions{Latency: time.Hour})
+	c1, c2, err := link.Pipe(NetPipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	if _, err := c1.Write([]byte{1}); err != nil {
+		t.Fatal(err)
+	}
+	read := make(chan error, 1)
+	go func() {
+		_, err := io.ReadFull(c2, make([]byte, 1))
+		read <- err
+	}()
+	clock.WaitForTimers(1)
+	clock.Run(time.Hour - time.Second)
+	select {
+	case <-read:
+		t.Fatal("data arrived before the latency elapsed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	clock.Run(time.Second)
+	select {
+	case err := <-read:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("data not delivered after the latency elapsed")
+	}
+}
+
+func TestLinkLoss(t *testing.T) {
+	link := NewLink(1, nil)
+	link.SetConditions(Conditions{LossRate: 1, RetransmitTimeout: 10 * time.Millisecond})
+	c1, c2, err := link.Pipe(NetPipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	// Every transmission is lost, so the write arrives after the maximum number of
+	// retransmits: 10ms + 20ms + 40ms + ...
+	start := time.Now()
+	c1.Write([]byte("x"))
+	buf := make([]byte, 1)
+	if _, err := io.ReadFull(c2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed, want := time.Since(start), 630*time.Millisecond; elapsed < want {
+		t.Fatalf("data arrived after %v, want at least %v", elapsed, want)
+	}
+}
+
+func TestLinkDown(t *testing.T) {
+	link := NewLink(1, nil)
+	c1, c2, err := link.Pipe(NetPipe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+	defer c2.Close()
+
+	// New connections can't be created while the link is down.
+	link.Down()
+	if _, _, err := link.Pipe(NetPipe); !errors.Is(err, ErrLinkDown) {
+		t.Fatalf("wrong error %v, want %v", err, ErrLinkDown)
+	}
+
+	// Data written while the link is down is held back.
+	if _, err := c1.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	c2.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	buf := make([]byte, 5)
+	if _, err := c2.Read(buf); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("read while link down returned %v", err)
+	}
+
+	// It arrives when the link comes back.
+	link.Up()
+	c2.SetReadDeadline(time.Time{})
+	if _, err := io.ReadFull(c2, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Fatalf("wrong data %q", buf)
+	}
+}
+
+func TestLinkWriteDeadline(t *testing.T) {
+	link := NewLink(1, nil)
+	link.Down()
+	p1, p2 := net.Pipe()
+	defer p2.Close()
+	c1 := newLinkConn(p1, link)
+	defer c1.Close()
+
+	// Fill the queue, the next write must time out. One write is taken off the
+	// queue by the delivery loop.
+	c1.SetWriteDeadline(time.Now().Add(50 * time.Millisecond))
+	var err error
+	for i := 0; i <= linkQueueSize+1 && err == nil; i++ {
+		_, err = c1.Write([]byte{1})
+	}
+	if !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("wrong error %v, want %v", err, os.ErrDeadlineExceeded)
+	}
+
+	// Writes fail after close.
+	c1.Close()
+	if _, err := c1.Write([]byte{1}); !errors.Is(err, net.ErrClosed) {
+		t.Fatalf("wrong error %v after close", err)
+	}
+}
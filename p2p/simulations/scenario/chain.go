@@ -0,0 +1,185 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/clique"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+const (
+	extraVanity   = 32               // bytes of the block extra-data holding the miner tag
+	blockGasLimit = 30_000_000       // gas limit of all blocks
+	genesisAge    = 24 * time.Hour   // generated blocks are 10s apart, starting at genesis
+	txGasPrice    = 10 * params.GWei // gas price of transactions created by SendTx
+)
+
+// accountFunds is the initial balance of test accounts.
+var accountFunds = new(big.Int).Mul(big.NewInt(1000), big.NewInt(params.Ether))
+
+// chain creates the blocks of a scenario.
+//
+// All nodes run clique with a single signer whose key is held by the chain maker.
+// Blocks are generated on top of the head of the mining node and carry the name of the
+// node in their extra-data, so that competing forks can be told apart. The state of
+// every generated block is kept in a separate database, which allows mining on any
+// node regardless of which node generated its head.
+type chain struct {
+	mu       sync.Mutex
+	genesis  *core.Genesis
+	engine   *clique.Clique
+	db       ethdb.Database
+	signer   *ecdsa.PrivateKey
+	accounts []*ecdsa.PrivateKey
+}
+
+func newChain(accounts int) (*chain, error) {
+	signer, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	c := &chain{signer: signer, db: rawdb.NewMemoryDatabase()}
+
+	config := *params.AllCliqueProtocolChanges
+	config.Clique = &params.CliqueConfig{Period: 0, Epoch: 30000}
+	alloc := make(core.GenesisAlloc)
+	for i := 0; i < accounts; i++ {
+		key, err := crypto.GenerateKey()
+		if err != nil {
+			return nil, err
+		}
+		c.accounts = append(c.accounts, key)
+		alloc[crypto.PubkeyToAddress(key.PublicKey)] = core.GenesisAccount{Balance: accountFunds}
+	}
+	extra := make([]byte, extraVanity+common.AddressLength+crypto.SignatureLength)
+	copy(extra[extraVanity:], crypto.PubkeyToAddress(signer.PublicKey).Bytes())
+	c.genesis = &core.Genesis{
+		Config:     &config,
+		Timestamp:  uint64(time.Now().Add(-genesisAge).Unix()),
+		ExtraData:  extra,
+		GasLimit:   blockGasLimit,
+		Difficulty: big.NewInt(1),
+		Alloc:      alloc,
+	}
+	c.genesis.MustCommit(c.db)
+	c.engine = clique.New(config.Clique, c.db)
+	return c, nil
+}
+
+// account returns the key of a test account.
+func (c *chain) account(i int) (*ecdsa.PrivateKey, error) {
+	if i < 0 || i >= len(c.accounts) {
+		return nil, fmt.Errorf("unknown account %d", i)
+	}
+	return c.accounts[i], nil
+}
+
+// mine creates n blocks on top of the head of e and inserts them into its chain.
+// Executable transactions from the pool of e are included.
+func (c *chain) mine(e *eth.Ethereum, miner string, n int) ([]*types.Block, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var (
+		bc      = e.BlockChain()
+		head    = bc.CurrentBlock()
+		parent  = bc.GetBlock(head.Hash(), head.Number.Uint64())
+		pending = e.TxPool().Pending(false)
+		blocks  = make([]*types.Block, 0, n)
+	)
+	if parent == nil {
+		return nil, fmt.Errorf("head block %d not found", head.Number)
+	}
+	for i := 0; i < n; i++ {
+		gen, _ := core.GenerateChain(c.genesis.Config, parent, c.engine, c.db, 1, func(_ int, b *core.BlockGen) {
+			extra := make([]byte, extraVanity+crypto.SignatureLength)
+			copy(extra, miner)
+			b.SetExtra(extra)
+			// Clique credits fees to the block signer, do the same here. The
+			// coinbase is a vote in clique: voting to add an existing signer
+			// has no effect.
+			b.SetCoinbase(crypto.PubkeyToAddress(c.signer.PublicKey))
+			b.SetNonce(types.EncodeNonce(math.MaxUint64))
+			includeTxs(b, pending)
+		})
+		block := c.seal(gen[0])
+		blocks = append(blocks, block)
+		parent = block
+	}
+	if _, err := bc.InsertChain(blocks); err != nil {
+		return nil, err
+	}
+	for _, block := range blocks {
+		e.EventMux().Post(core.NewMinedBlockEvent{Block: block})
+	}
+	return blocks, nil
+}
+
+// includeTxs adds the transactions which are executable in the block being generated.
+func includeTxs(b *core.BlockGen, pending map[common.Address][]*types.Transaction) {
+	var gas uint64
+	for addr, txs := range pending {
+		for _, tx := range txs {
+			nonce := b.TxNonce(addr)
+			if tx.Nonce() < nonce {
+				continue // included in an earlier block
+			}
+			if tx.Nonce() > nonce || tx.GasFeeCapIntCmp(b.BaseFee()) < 0 || gas+tx.Gas() > blockGasLimit {
+				break
+			}
+			if b.GetBalance(addr).Cmp(tx.Cost()) < 0 {
+				break
+			}
+			b.AddTx(tx)
+			gas += tx.Gas()
+		}
+	}
+}
+
+// seal signs a generated block.
+func (c *chain) seal(block *types.Block) *types.Block {
+	header := block.Header()
+	sig, err := crypto.Sign(clique.SealHash(header).Bytes(), c.signer)
+	if err != nil {
+		panic(err)
+	}
+	copy(header.Extra[len(header.Extra)-crypto.SignatureLength:], sig)
+	return block.WithSeal(header)
+}
+
+// minedBy returns the name of the node which mined the block.
+func minedBy(header *types.Header) string {
+	if len(header.Extra) < extraVanity {
+		return ""
+	}
+	return strings.TrimRight(string(header.Extra[:extraVanity]), "\x00")
+}
@@ -0,0 +1,200 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+)
+
+// Condition is a predicate on the state of the simulated network.
+type Condition interface {
+	// Check returns nil if the condition holds, or an error describing why not.
+	Check(r *Runner) error
+	String() string
+}
+
+type conditionFunc struct {
+	name string
+	fn   func(*Runner) error
+}
+
+func (c *conditionFunc) Check(r *Runner) error { return c.fn(r) }
+func (c *conditionFunc) String() string        { return c.name }
+
+// NewCondition creates a condition which holds when fn returns nil.
+func NewCondition(name string, fn func(r *Runner) error) Condition {
+	return &conditionFunc{name, fn}
+}
+
+// Not negates a condition.
+func Not(c Condition) Condition {
+	return NewCondition("not "+c.String(), func(r *Runner) error {
+		if c.Check(r) == nil {
+			return fmt.Errorf("%v holds", c)
+		}
+		return nil
+	})
+}
+
+// HeadNumber holds when the head block of a node has the given number.
+func HeadNumber(name string, number uint64) Condition {
+	return NewCondition(fmt.Sprintf("head of %s is #%d", name, number), func(r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		if head := e.BlockChain().CurrentBlock(); head.Number.Uint64() != number {
+			return fmt.Errorf("head of %s is #%d, want #%d", name, head.Number, number)
+		}
+		return nil
+	})
+}
+
+// SameHead holds when all given nodes have the same head block. If no nodes are given,
+// it applies to all running nodes.
+func SameHead(names ...string) Condition {
+	desc := "same head on all nodes"
+	if len(names) > 0 {
+		desc = "same head on " + strings.Join(names, ", ")
+	}
+	return NewCondition(desc, func(r *Runner) error {
+		nodes := names
+		if len(nodes) == 0 {
+			for _, name := range r.scenario.Nodes {
+				if n, _ := r.Node(name); n.Up() {
+					nodes = append(nodes, name)
+				}
+			}
+		}
+		var first string
+		for _, name := range nodes {
+			e, err := r.Eth(name)
+			if err != nil {
+				return err
+			}
+			head := e.BlockChain().CurrentBlock()
+			desc := fmt.Sprintf("#%d [%x]", head.Number, head.Hash().Bytes()[:4])
+			if first == "" {
+				first = desc
+			} else if desc != first {
+				return fmt.Errorf("head of %s is %s, want %s", name, desc, first)
+			}
+		}
+		return nil
+	})
+}
+
+// HeadMinedBy holds when the head block of a node was created by Mine on the given
+// miner node. This tells which of several competing forks a node has chosen.
+func HeadMinedBy(name, miner string) Condition {
+	return NewCondition(fmt.Sprintf("head of %s mined by %s", name, miner), func(r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		head := e.BlockChain().CurrentBlock()
+		if m := minedBy(head); m != miner {
+			return fmt.Errorf("head #%d of %s mined by %q, want %q", head.Number, name, m, miner)
+		}
+		return nil
+	})
+}
+
+// PeerCount holds when a node has the given number of peers. Only peers which have
+// completed the eth protocol handshake are counted.
+func PeerCount(name string, peers int) Condition {
+	return NewCondition(fmt.Sprintf("%s has %d peers", name, peers), func(r *Runner) error {
+		n, err := r.Node(name)
+		if err != nil {
+			return err
+		}
+		sn, ok := n.Node.(*adapters.SimNode)
+		if !ok || sn.Server() == nil {
+			return fmt.Errorf("node %q is not running", name)
+		}
+		var count int
+		for _, info := range sn.Server().PeersInfo() {
+			// The protocol info is a placeholder string during the handshake.
+			if _, pending := info.Protocols["eth"].(string); info.Protocols["eth"] != nil && !pending {
+				count++
+			}
+		}
+		if count != peers {
+			return fmt.Errorf("%s has %d peers, want %d", name, count, peers)
+		}
+		return nil
+	})
+}
+
+// TxPoolSize holds when the pool of a node contains the given number of pending and
+// queued transactions.
+func TxPoolSize(name string, pending, queued int) Condition {
+	desc := fmt.Sprintf("pool of %s has %d pending, %d queued txs", name, pending, queued)
+	return NewCondition(desc, func(r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		if p, q := e.TxPool().Stats(); p != pending || q != queued {
+			return fmt.Errorf("pool of %s has %d pending, %d queued txs, want %d, %d", name, p, q, pending, queued)
+		}
+		return nil
+	})
+}
+
+// TxInPool holds when the pool of a node contains the labeled transaction.
+func TxInPool(name, label string) Condition {
+	return NewCondition(fmt.Sprintf("tx %q in pool of %s", label, name), func(r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		tx, err := r.Tx(label)
+		if err != nil {
+			return err
+		}
+		if !e.TxPool().Has(tx.Hash()) {
+			return fmt.Errorf("tx %q not in pool of %s", label, name)
+		}
+		return nil
+	})
+}
+
+// TxIncluded holds when the labeled transaction is included in the canonical chain of
+// a node.
+func TxIncluded(name, label string) Condition {
+	return NewCondition(fmt.Sprintf("tx %q included on %s", label, name), func(r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		tx, err := r.Tx(label)
+		if err != nil {
+			return err
+		}
+		db := e.ChainDb()
+		found, blockHash, number, _ := rawdb.ReadTransaction(db, tx.Hash())
+		if found == nil || rawdb.ReadCanonicalHash(db, number) != blockHash {
+			return fmt.Errorf("tx %q not included on %s", label, name)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,242 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth"
+	"github.com/ethereum/go-ethereum/eth/downloader"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/p2p/simulations"
+	"github.com/ethereum/go-ethereum/p2p/simulations/adapters"
+	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+)
+
+const networkID = 1337
+
+// Runner executes a scenario.
+type Runner struct {
+	scenario *Scenario
+	clock    mclock.Clock
+	adapter  *adapters.SimAdapter
+	net      *simulations.Network
+	chain    *chain
+	nodes    map[string]*simulations.Node
+
+	mu  sync.Mutex
+	txs map[string]*types.Transaction
+}
+
+// Run executes a scenario and shuts down the simulated network when it's done.
+func Run(ctx context.Context, s *Scenario) error {
+	r, err := NewRunner(s)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return r.Run(ctx)
+}
+
+// NewRunner creates the simulated network of a scenario. The nodes are started by Run.
+func NewRunner(s *Scenario) (*Runner, error) {
+	c, err := newChain(s.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	r := &Runner{
+		scenario: s,
+		clock:    s.Clock,
+		chain:    c,
+		nodes:    make(map[string]*simulations.Node),
+		txs:      make(map[string]*types.Transaction),
+	}
+	if r.clock == nil {
+		r.clock = mclock.System{}
+	}
+	r.adapter = adapters.NewSimAdapter(adapters.LifecycleConstructors{"eth": r.newEthService})
+	r.adapter.SetClock(r.clock)
+	r.net = simulations.NewNetwork(r.adapter, &simulations.NetworkConfig{DefaultService: "eth"})
+
+	for _, name := range s.Nodes {
+		if name == "" || len(name) > extraVanity {
+			r.Close()
+			return nil, fmt.Errorf("invalid node name %q", name)
+		}
+		if r.nodes[name] != nil {
+			r.Close()
+			return nil, fmt.Errorf("duplicate node name %q", name)
+		}
+		config := adapters.RandomNodeConfig()
+		config.Name = name
+		config.Lifecycles = []string{"eth"}
+		n, err := r.net.NewNodeWithConfig(config)
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.nodes[name] = n
+	}
+	// Create links between all nodes up front, so all connections are subject
+	// to fault injection.
+	for i, a := range s.Nodes {
+		for _, b := range s.Nodes[i+1:] {
+			r.adapter.Link(r.nodes[a].ID(), r.nodes[b].ID())
+		}
+	}
+	return r, nil
+}
+
+func (r *Runner) newEthService(ctx *adapters.ServiceContext, stack *node.Node) (node.Lifecycle, error) {
+	config := ethconfig.Defaults
+	config.Genesis = r.chain.genesis
+	config.NetworkId = networkID
+	config.SyncMode = downloader.FullSync
+	return eth.New(stack, &config)
+}
+
+// Run starts all nodes and executes the steps of the scenario. It returns the first
+// error encountered by a step.
+func (r *Runner) Run(ctx context.Context) error {
+	if err := r.net.StartAll(); err != nil {
+		return err
+	}
+	// All nodes start from the same genesis block, there is nothing to sync
+	// before transactions can be accepted.
+	for _, name := range r.scenario.Nodes {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		e.SetSynced()
+	}
+
+	start := r.clock.Now()
+	for i, step := range r.scenario.Steps {
+		if err := r.sleep(ctx, time.Duration(start.Add(step.At)-r.clock.Now())); err != nil {
+			return err
+		}
+		log.Info("Running scenario step", "step", i, "at", step.At, "action", step.Action)
+		if err := step.Action.Run(ctx, r); err != nil {
+			return fmt.Errorf("step %d (%v at %v): %w", i, step.Action, step.At, err)
+		}
+	}
+	return nil
+}
+
+// Close shuts down the simulated network.
+func (r *Runner) Close() {
+	r.net.Shutdown()
+}
+
+// Network returns the simulated network.
+func (r *Runner) Network() *simulations.Network {
+	return r.net
+}
+
+// Node returns a node by name.
+func (r *Runner) Node(name string) (*simulations.Node, error) {
+	n := r.nodes[name]
+	if n == nil {
+		return nil, fmt.Errorf("unknown node %q", name)
+	}
+	return n, nil
+}
+
+// Eth returns the eth service of a running node.
+func (r *Runner) Eth(name string) (*eth.Ethereum, error) {
+	n, err := r.Node(name)
+	if err != nil {
+		return nil, err
+	}
+	if sn, ok := n.Node.(*adapters.SimNode); ok && n.Up() {
+		if e, ok := sn.Service("eth").(*eth.Ethereum); ok {
+			return e, nil
+		}
+	}
+	return nil, fmt.Errorf("node %q is not running", name)
+}
+
+// Link returns the link between two nodes.
+func (r *Runner) Link(a, b string) (*pipes.Link, error) {
+	na, nb, err := r.nodePair(a, b)
+	if err != nil {
+		return nil, err
+	}
+	return r.adapter.Link(na.ID(), nb.ID()), nil
+}
+
+// Tx returns a transaction created by SendTx.
+func (r *Runner) Tx(label string) (*types.Transaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tx := r.txs[label]
+	if tx == nil {
+		return nil, fmt.Errorf("unknown transaction %q", label)
+	}
+	return tx, nil
+}
+
+func (r *Runner) addTx(label string, tx *types.Transaction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.txs[label] != nil {
+		return fmt.Errorf("duplicate transaction label %q", label)
+	}
+	r.txs[label] = tx
+	return nil
+}
+
+func (r *Runner) nodePair(a, b string) (*simulations.Node, *simulations.Node, error) {
+	na, err := r.Node(a)
+	if err != nil {
+		return nil, nil, err
+	}
+	nb, err := r.Node(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if na == nb {
+		return nil, nil, errors.New("nodes must be different")
+	}
+	return na, nb, nil
+}
+
+// sleep waits for the given duration, or until ctx is canceled.
+func (r *Runner) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := r.clock.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
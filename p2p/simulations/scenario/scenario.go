@@ -0,0 +1,286 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package scenario runs scripted simulations of eth networks.
+//
+// A scenario names the nodes of the network and lists the steps to perform. Steps are
+// scheduled relative to the start of the scenario and can change the topology, inject
+// faults into the links between nodes, mine blocks, submit transactions and check
+// conditions on chain heads, transaction pools and peer counts.
+//
+// All nodes run the full eth protocol in-process, using the SimAdapter of package
+// adapters. Every pair of nodes is connected by a pipes.Link, so link latency, packet
+// loss and partitions apply to all traffic between them.
+package scenario
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+)
+
+// pollInterval is the interval at which conditions are checked.
+const pollInterval = 100 * time.Millisecond
+
+// Scenario describes a simulation.
+type Scenario struct {
+	Nodes    []string // node names, at most 32 bytes long
+	Accounts int      // number of funded accounts available to SendTx
+	Steps    []Step
+
+	// Clock schedules the steps and delays writes on the links between nodes.
+	// The system clock is used if it is nil. A simulated clock must be advanced
+	// by the caller while the scenario runs.
+	Clock mclock.Clock
+}
+
+// Step is an action scheduled at a point in scenario time.
+type Step struct {
+	At     time.Duration // time since the start of the scenario
+	Action Action
+}
+
+// Action is something a scenario step does.
+type Action interface {
+	Run(ctx context.Context, r *Runner) error
+	String() string
+}
+
+type actionFunc struct {
+	name string
+	fn   func(context.Context, *Runner) error
+}
+
+func (a *actionFunc) Run(ctx context.Context, r *Runner) error { return a.fn(ctx, r) }
+func (a *actionFunc) String() string                           { return a.name }
+
+// Func creates an action which calls fn.
+func Func(name string, fn func(ctx context.Context, r *Runner) error) Action {
+	return &actionFunc{name, fn}
+}
+
+// Connect makes node a dial node b. The connection is established asynchronously.
+func Connect(a, b string) Action {
+	return Func(fmt.Sprintf("connect %s -> %s", a, b), func(ctx context.Context, r *Runner) error {
+		na, nb, err := r.nodePair(a, b)
+		if err != nil {
+			return err
+		}
+		return r.net.Connect(na.ID(), nb.ID())
+	})
+}
+
+// Disconnect removes the connection between nodes a and b.
+func Disconnect(a, b string) Action {
+	return Func(fmt.Sprintf("disconnect %s -> %s", a, b), func(ctx context.Context, r *Runner) error {
+		na, nb, err := r.nodePair(a, b)
+		if err != nil {
+			return err
+		}
+		return r.net.Disconnect(na.ID(), nb.ID())
+	})
+}
+
+// SetLink changes the conditions of the link between nodes a and b.
+func SetLink(a, b string, c pipes.Conditions) Action {
+	name := fmt.Sprintf("set link %s <-> %s latency=%v loss=%v", a, b, c.Latency, c.LossRate)
+	return Func(name, func(ctx context.Context, r *Runner) error {
+		link, err := r.Link(a, b)
+		if err != nil {
+			return err
+		}
+		link.SetConditions(c)
+		return nil
+	})
+}
+
+// Partition splits the network into the given groups by taking down all links between
+// nodes of different groups. Nodes which aren't in any group are cut off from all other
+// nodes. Connections survive short partitions: data sent while the links are down is
+// delivered when the network heals.
+func Partition(groups ...[]string) Action {
+	desc := make([]string, len(groups))
+	for i, g := range groups {
+		desc[i] = "[" + strings.Join(g, " ") + "]"
+	}
+	return Func("partition "+strings.Join(desc, " "), func(ctx context.Context, r *Runner) error {
+		group := make(map[string]int)
+		for i, g := range groups {
+			for _, name := range g {
+				if _, err := r.Node(name); err != nil {
+					return err
+				}
+				if _, ok := group[name]; ok {
+					return fmt.Errorf("node %q is in more than one group", name)
+				}
+				group[name] = i
+			}
+		}
+		for i, a := range r.scenario.Nodes {
+			for _, b := range r.scenario.Nodes[i+1:] {
+				ga, oka := group[a]
+				gb, okb := group[b]
+				link, _ := r.Link(a, b)
+				if oka && okb && ga == gb {
+					link.Up()
+				} else {
+					link.Down()
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Heal brings all links back up.
+func Heal() Action {
+	return Func("heal", func(ctx context.Context, r *Runner) error {
+		for i, a := range r.scenario.Nodes {
+			for _, b := range r.scenario.Nodes[i+1:] {
+				link, _ := r.Link(a, b)
+				link.Up()
+			}
+		}
+		return nil
+	})
+}
+
+// StopNode shuts down a node. Stopped nodes can't be restarted.
+func StopNode(name string) Action {
+	return Func("stop "+name, func(ctx context.Context, r *Runner) error {
+		n, err := r.Node(name)
+		if err != nil {
+			return err
+		}
+		return r.net.Stop(n.ID())
+	})
+}
+
+// Mine creates blocks on top of the head of a node and announces them to its peers.
+// Executable transactions in the pool of the node are included in the blocks.
+func Mine(name string, blocks int) Action {
+	return Func(fmt.Sprintf("mine %d blocks on %s", blocks, name), func(ctx context.Context, r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		_, err = r.chain.mine(e, name, blocks)
+		return err
+	})
+}
+
+// SendTx submits a value transfer between two test accounts to the pool of a node. The
+// transaction can be referred to by its label in conditions.
+func SendTx(label, name string, from, to int, value *big.Int) Action {
+	desc := fmt.Sprintf("send tx %q to %s: %d -> %d value=%v", label, name, from, to, value)
+	return Func(desc, func(ctx context.Context, r *Runner) error {
+		e, err := r.Eth(name)
+		if err != nil {
+			return err
+		}
+		key, err := r.chain.account(from)
+		if err != nil {
+			return err
+		}
+		recipient, err := r.Account(to)
+		if err != nil {
+			return err
+		}
+		var (
+			pool   = e.TxPool()
+			sender = crypto.PubkeyToAddress(key.PublicKey)
+			signer = types.LatestSigner(r.chain.genesis.Config)
+			gp     = new(big.Int).SetUint64(txGasPrice)
+		)
+		tx, err := types.SignNewTx(key, signer, &types.LegacyTx{
+			Nonce:    pool.Nonce(sender),
+			To:       &recipient,
+			Value:    value,
+			Gas:      21000,
+			GasPrice: gp,
+		})
+		if err != nil {
+			return err
+		}
+		if err := r.addTx(label, tx); err != nil {
+			return err
+		}
+		return pool.Add([]*txpool.Transaction{{Tx: tx}}, true, true)[0]
+	})
+}
+
+// Expect checks that a condition holds.
+func Expect(c Condition) Action {
+	return Func("expect "+c.String(), func(ctx context.Context, r *Runner) error {
+		return c.Check(r)
+	})
+}
+
+// Eventually waits until a condition holds. It fails if the condition doesn't hold
+// within the given time.
+func Eventually(c Condition, within time.Duration) Action {
+	return Func(fmt.Sprintf("eventually %v within %v", c, within), func(ctx context.Context, r *Runner) error {
+		deadline := r.clock.Now().Add(within)
+		for {
+			err := c.Check(r)
+			if err == nil {
+				return nil
+			}
+			if r.clock.Now() >= deadline {
+				return err
+			}
+			if err := r.sleep(ctx, pollInterval); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Always checks that a condition holds during the given time.
+func Always(c Condition, d time.Duration) Action {
+	return Func(fmt.Sprintf("always %v for %v", c, d), func(ctx context.Context, r *Runner) error {
+		end := r.clock.Now().Add(d)
+		for {
+			if err := c.Check(r); err != nil {
+				return err
+			}
+			if r.clock.Now() >= end {
+				return nil
+			}
+			if err := r.sleep(ctx, pollInterval); err != nil {
+				return err
+			}
+		}
+	})
+}
+
+// Account returns the address of a test account.
+func (r *Runner) Account(i int) (common.Address, error) {
+	key, err := r.chain.account(i)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(key.PublicKey), nil
+}
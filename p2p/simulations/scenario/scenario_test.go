@@ -0,0 +1,158 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package scenario
+
+import (
+	"context"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+	"github.com/ethereum/go-ethereum/p2p/simulations/pipes"
+)
+
+func runScenario(t *testing.T, s *Scenario) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	if err := Run(ctx, s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// This test checks that nodes converge on the heavier fork after a partition.
+func TestPartitionForkChoice(t *testing.T) {
+	runScenario(t, &Scenario{
+		Nodes: []string{"a", "b", "c", "d"},
+		Steps: []Step{
+			{0, Connect("a", "b")},
+			{0, Connect("a", "c")},
+			{0, Connect("a", "d")},
+			{0, Connect("b", "c")},
+			{0, Connect("b", "d")},
+			{0, Connect("c", "d")},
+			{0, Eventually(PeerCount("a", 3), 10*time.Second)},
+			{0, Eventually(PeerCount("d", 3), 10*time.Second)},
+			{0, Mine("a", 2)},
+			{0, Eventually(SameHead(), 10*time.Second)},
+			{0, Expect(HeadNumber("d", 2))},
+
+			// Both sides of the partition extend the chain, c builds the longer fork.
+			{0, Partition([]string{"a", "b"}, []string{"c", "d"})},
+			{0, Mine("a", 1)},
+			{0, Mine("c", 3)},
+			{0, Eventually(HeadNumber("b", 3), 10*time.Second)},
+			{0, Eventually(HeadNumber("d", 5), 10*time.Second)},
+			{0, Always(HeadMinedBy("b", "a"), time.Second)},
+
+			// After healing, all nodes switch to the fork of c without reconnecting.
+			{0, Heal()},
+			{0, Eventually(SameHead(), 30*time.Second)},
+			{0, Expect(HeadMinedBy("a", "c"))},
+			{0, Expect(HeadNumber("b", 5))},
+			{0, Expect(PeerCount("a", 3))},
+		},
+	})
+}
+
+// This test checks that transactions propagate across the network and are included.
+func TestTxPropagation(t *testing.T) {
+	runScenario(t, &Scenario{
+		Nodes:    []string{"a", "b", "c"},
+		Accounts: 2,
+		Steps: []Step{
+			{0, Connect("a", "b")},
+			{0, Connect("b", "c")},
+			{0, Eventually(PeerCount("b", 2), 10*time.Second)},
+			{0, SendTx("tx1", "a", 0, 1, big.NewInt(1))},
+			{0, SendTx("tx2", "a", 0, 1, big.NewInt(2))},
+			{0, Eventually(TxInPool("c", "tx2"), 10*time.Second)},
+			{0, Expect(TxPoolSize("c", 2, 0))},
+
+			// c includes the transactions, they disappear from all pools.
+			{0, Mine("c", 1)},
+			{0, Eventually(TxIncluded("a", "tx1"), 10*time.Second)},
+			{0, Expect(TxIncluded("a", "tx2"))},
+			{0, Eventually(TxPoolSize("a", 0, 0), 10*time.Second)},
+			{0, Expect(Not(TxInPool("b", "tx1")))},
+		},
+	})
+}
+
+// This test checks that the network keeps working over a slow, lossy link.
+func TestLinkConditions(t *testing.T) {
+	slow := pipes.Conditions{Latency: 100 * time.Millisecond, LossRate: 0.1, RetransmitTimeout: 50 * time.Millisecond}
+	runScenario(t, &Scenario{
+		Nodes: []string{"a", "b"},
+		Steps: []Step{
+			{0, SetLink("a", "b", slow)},
+			{0, Connect("a", "b")},
+			{0, Eventually(PeerCount("a", 1), 20*time.Second)},
+			{0, Mine("a", 3)},
+			{0, Eventually(HeadNumber("b", 3), 20*time.Second)},
+
+			// Blocks don't arrive while the link is down.
+			{0, Partition([]string{"a"}, []string{"b"})},
+			{0, Mine("a", 1)},
+			{0, Always(HeadNumber("b", 3), time.Second)},
+			{0, Heal()},
+			{0, Eventually(HeadNumber("b", 4), 20*time.Second)},
+		},
+	})
+}
+
+// This test checks that steps are scheduled on the clock of the scenario.
+func TestSimulatedClock(t *testing.T) {
+	clock := new(mclock.Simulated)
+	go func() {
+		clock.WaitForTimers(1)
+		clock.Run(time.Hour)
+	}()
+	runScenario(t, &Scenario{
+		Nodes: []string{"a"},
+		Clock: clock,
+		Steps: []Step{
+			{0, Expect(HeadNumber("a", 0))},
+			{time.Hour, Expect(HeadNumber("a", 0))},
+		},
+	})
+	if elapsed := time.Duration(clock.Now()); elapsed != time.Hour {
+		t.Fatalf("wrong simulated time %v, want 1h", elapsed)
+	}
+}
+
+func TestStepError(t *testing.T) {
+	err := Run(context.Background(), &Scenario{
+		Nodes: []string{"a"},
+		Steps: []Step{
+			{0, Expect(HeadNumber("a", 0))},
+			{100 * time.Millisecond, Eventually(HeadNumber("a", 1), 200*time.Millisecond)},
+		},
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(err.Error(), "step 1") || !strings.Contains(err.Error(), "head of a is #0") {
+		t.Fatalf("wrong error: %v", err)
+	}
+
+	if _, err := NewRunner(&Scenario{Nodes: []string{"a", "a"}}); err == nil {
+		t.Fatal("duplicate node name accepted")
+	}
+}
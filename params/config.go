@@ -17,8 +17,11 @@
 package params
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -300,6 +303,10 @@ type ChainConfig struct {
 	// even without having seen the TTD locally (safer long term).
 	TerminalTotalDifficultyPassed bool `json:"terminalTotalDifficultyPassed,omitempty"`
 
+	// Precompiles binds custom precompiled contracts, registered by name within
+	// the EVM, to addresses. It is meant for private and development networks.
+	Precompiles map[common.Address]*PrecompileConfig `json:"precompiles,omitempty"`
+
 	// Various consensus engines
 	Ethash    *EthashConfig `json:"ethash,omitempty"`
 	Clique    *CliqueConfig `json:"clique,omitempty"`
@@ -325,6 +332,79 @@ func (c *CliqueConfig) String() string {
 	return "clique"
 }
 
+// PrecompileConfig is the configuration of a custom precompiled contract.
+type PrecompileConfig struct {
+	Name string `json:"name"`           // Name the contract implementation was registered with
+	Fork string `json:"fork,omitempty"` // Fork activating the contract, active from genesis if empty
+}
+
+// precompileForks maps the fork names accepted in precompile configurations to
+// their activation checks.
+var precompileForks = map[string]func(Rules) bool{
+	"":               func(Rules) bool { return true },
+	"homestead":      func(r Rules) bool { return r.IsHomestead },
+	"byzantium":      func(r Rules) bool { return r.IsByzantium },
+	"constantinople": func(r Rules) bool { return r.IsConstantinople },
+	"istanbul":       func(r Rules) bool { return r.IsIstanbul },
+	"berlin":         func(r Rules) bool { return r.IsBerlin },
+	"london":         func(r Rules) bool { return r.IsLondon },
+	"merge":          func(r Rules) bool { return r.IsMerge },
+	"shanghai":       func(r Rules) bool { return r.IsShanghai },
+	"cancun":         func(r Rules) bool { return r.IsCancun },
+	"prague":         func(r Rules) bool { return r.IsPrague },
+}
+
+// Validate checks that the activation fork of the precompile is known.
+func (c *PrecompileConfig) Validate() error {
+	if c.Name == "" {
+		return errors.New("missing precompile name")
+	}
+	if _, ok := precompileForks[c.Fork]; !ok {
+		return fmt.Errorf("unknown precompile activation fork %q", c.Fork)
+	}
+	return nil
+}
+
+// Active returns whether the precompile is enabled under the given rules.
+func (c *PrecompileConfig) Active(rules Rules) bool {
+	active, ok := precompileForks[c.Fork]
+	return ok && active(rules)
+}
+
+// precompileActivation returns the block number or timestamp from which a
+// precompile activated by the given fork is enabled. The merge has no fixed block
+// unless a netsplit block is configured, it is treated as active from genesis.
+func (c *ChainConfig) precompileActivation(fork string) (*big.Int, *uint64) {
+	switch fork {
+	case "":
+		return big.NewInt(0), nil
+	case "homestead":
+		return c.HomesteadBlock, nil
+	case "byzantium":
+		return c.ByzantiumBlock, nil
+	case "constantinople":
+		return c.ConstantinopleBlock, nil
+	case "istanbul":
+		return c.IstanbulBlock, nil
+	case "berlin":
+		return c.BerlinBlock, nil
+	case "london":
+		return c.LondonBlock, nil
+	case "merge":
+		if c.MergeNetsplitBlock != nil {
+			return c.MergeNetsplitBlock, nil
+		}
+		return big.NewInt(0), nil
+	case "shanghai":
+		return nil, c.ShanghaiTime
+	case "cancun":
+		return nil, c.CancunTime
+	case "prague":
+		return nil, c.PragueTime
+	}
+	return nil, nil
+}
+
 // Description returns a human-readable description of ChainConfig.
 func (c *ChainConfig) Description() string {
 	var banner string
@@ -414,6 +494,24 @@ func (c *ChainConfig) Description() string {
 	if c.VerkleTime != nil {
 		banner += fmt.Sprintf(" - Verkle:                      @%-10v\n", *c.VerkleTime)
 	}
+	if len(c.Precompiles) > 0 {
+		banner += "\n"
+		banner += "Custom precompiles:\n"
+
+		addrs := make([]common.Address, 0, len(c.Precompiles))
+		for addr := range c.Precompiles {
+			addrs = append(addrs, addr)
+		}
+		sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+		for _, addr := range addrs {
+			p := c.Precompiles[addr]
+			fork := p.Fork
+			if fork == "" {
+				fork = "genesis"
+			}
+			banner += fmt.Sprintf(" - %-20s %v (from %s)\n", p.Name+":", addr, fork)
+		}
+	}
 	return banner
 }
 
@@ -678,6 +776,46 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkTimestampIncompatible(c.VerkleTime, newcfg.VerkleTime, headTimestamp) {
 		return newTimestampCompatError("Verkle fork timestamp", c.VerkleTime, newcfg.VerkleTime)
 	}
+	return c.checkPrecompilesCompatible(newcfg, headNumber, headTimestamp)
+}
+
+// checkPrecompilesCompatible checks that no custom precompile active at the given
+// head was added, removed or bound to a different contract or fork.
+func (c *ChainConfig) checkPrecompilesCompatible(newcfg *ChainConfig, headNumber *big.Int, headTimestamp uint64) *ConfigCompatError {
+	var addrs []common.Address
+	for addr := range c.Precompiles {
+		addrs = append(addrs, addr)
+	}
+	for addr := range newcfg.Precompiles {
+		if _, ok := c.Precompiles[addr]; !ok {
+			addrs = append(addrs, addr)
+		}
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	for _, addr := range addrs {
+		stored, next := c.Precompiles[addr], newcfg.Precompiles[addr]
+		if stored != nil && next != nil && *stored == *next {
+			continue
+		}
+		var (
+			storedBlock, newBlock *big.Int
+			storedTime, newTime   *uint64
+		)
+		if stored != nil {
+			storedBlock, storedTime = c.precompileActivation(stored.Fork)
+		}
+		if next != nil {
+			newBlock, newTime = newcfg.precompileActivation(next.Fork)
+		}
+		what := fmt.Sprintf("precompile %v", addr)
+		if isBlockForked(storedBlock, headNumber) || isBlockForked(newBlock, headNumber) {
+			return newBlockCompatError(what+" block", storedBlock, newBlock)
+		}
+		if isTimestampForked(storedTime, headTimestamp) || isTimestampForked(newTime, headTimestamp) {
+			return newTimestampCompatError(what+" timestamp", storedTime, newTime)
+		}
+	}
 	return nil
 }
 
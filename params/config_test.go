@@ -22,6 +22,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/math"
 )
 
@@ -109,6 +110,63 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToTime: 9,
 			},
 		},
+		{
+			stored: &ChainConfig{
+				BerlinBlock: big.NewInt(10),
+				Precompiles: map[common.Address]*PrecompileConfig{{0x10}: {Name: "a", Fork: "berlin"}},
+			},
+			new:       &ChainConfig{BerlinBlock: big.NewInt(10)},
+			headBlock: 9,
+			wantErr:   nil,
+		},
+		{
+			stored: &ChainConfig{
+				BerlinBlock: big.NewInt(10),
+				Precompiles: map[common.Address]*PrecompileConfig{{0x10}: {Name: "a", Fork: "berlin"}},
+			},
+			new:       &ChainConfig{BerlinBlock: big.NewInt(10)},
+			headBlock: 20,
+			wantErr: &ConfigCompatError{
+				What:          "precompile 0x1000000000000000000000000000000000000000 block",
+				StoredBlock:   big.NewInt(10),
+				NewBlock:      nil,
+				RewindToBlock: 9,
+			},
+		},
+		{
+			stored: &ChainConfig{
+				LondonBlock: big.NewInt(5),
+				Precompiles: map[common.Address]*PrecompileConfig{{0x10}: {Name: "a", Fork: "homestead"}},
+			},
+			new: &ChainConfig{
+				LondonBlock: big.NewInt(5),
+				Precompiles: map[common.Address]*PrecompileConfig{{0x10}: {Name: "b", Fork: "london"}},
+			},
+			headBlock: 20,
+			wantErr: &ConfigCompatError{
+				What:          "precompile 0x1000000000000000000000000000000000000000 block",
+				StoredBlock:   nil,
+				NewBlock:      big.NewInt(5),
+				RewindToBlock: 4,
+			},
+		},
+		{
+			stored: &ChainConfig{
+				ShanghaiTime: newUint64(10),
+				Precompiles:  map[common.Address]*PrecompileConfig{{0x10}: {Name: "a", Fork: "shanghai"}},
+			},
+			new: &ChainConfig{
+				ShanghaiTime: newUint64(10),
+				Precompiles:  map[common.Address]*PrecompileConfig{{0x10}: {Name: "b", Fork: "shanghai"}},
+			},
+			headTimestamp: 25,
+			wantErr: &ConfigCompatError{
+				What:         "precompile 0x1000000000000000000000000000000000000000 timestamp",
+				StoredTime:   newUint64(10),
+				NewTime:      newUint64(10),
+				RewindToTime: 9,
+			},
+		},
 	}
 
 	for _, test := range tests {